@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Output formats accepted by --output-format.
+const (
+	outputFormatText   = "text"
+	outputFormatAction = "action"
+)
+
+var (
+	outputFormat string
+	actionFlag   bool
+	// actionMode is set once setupActionMode found the command running as
+	// a GitHub Action step.
+	actionMode bool
+)
+
+// actionOutput is one step output written to $GITHUB_OUTPUT.
+type actionOutput struct {
+	Name  string
+	Value string
+}
+
+// addActionFlags registers --output-format and --action on cmd.
+func addActionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&outputFormat, "output-format", outputFormatText, "Output format: text, or action to run as a GitHub Action step (inputs from INPUT_*, outputs to $GITHUB_OUTPUT)")
+	cmd.Flags().BoolVar(&actionFlag, "action", false, "Use --output-format action when running in GitHub Actions")
+}
+
+// setupActionMode switches cmd to action mode when --output-format action is
+// given, or when --action is given in GitHub Actions. In action mode nothing
+// is treated as a terminal, and every flag not given on the command line is
+// read from the matching INPUT_* variable the runner sets from the step's
+// with: block, e.g. INPUT_BASE_REF or INPUT_BASE-REF for --base-ref. An
+// empty variable leaves the flag unset; repeatable flags take one value per
+// line.
+func setupActionMode(cmd *cobra.Command) error {
+	switch outputFormat {
+	case outputFormatText, outputFormatAction:
+	default:
+		return fmt.Errorf("invalid --output-format %q: must be %s or %s", outputFormat, outputFormatText, outputFormatAction)
+	}
+	if actionFlag && os.Getenv("GITHUB_ACTIONS") != "true" {
+		return fmt.Errorf("--action only works in GitHub Actions (GITHUB_ACTIONS=true); use --output-format %s elsewhere", outputFormatAction)
+	}
+	if !actionFlag && outputFormat != outputFormatAction {
+		return nil
+	}
+
+	actionMode = true
+	ui.SetHeadless()
+
+	var err error
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed {
+			return
+		}
+		switch flag.Name {
+		case "help", "action", "output-format":
+			return
+		}
+		value := actionInput(flag.Name)
+		if value == "" {
+			return
+		}
+		values := []string{value}
+		if _, ok := flag.Value.(pflag.SliceValue); ok {
+			values = nil
+			for _, line := range strings.Split(value, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					values = append(values, line)
+				}
+			}
+		}
+		for _, v := range values {
+			if setErr := cmd.Flags().Set(flag.Name, v); setErr != nil {
+				err = fmt.Errorf("invalid input %s: %w", strings.ReplaceAll(flag.Name, "-", "_"), setErr)
+				return
+			}
+		}
+	})
+	return err
+}
+
+// actionInput returns the INPUT_* variable for the flag name. The runner
+// upper-cases input names but keeps their hyphens, so both spellings are
+// looked up.
+func actionInput(name string) string {
+	upper := strings.ToUpper(name)
+	if value := strings.TrimSpace(os.Getenv("INPUT_" + strings.ReplaceAll(upper, "-", "_"))); value != "" {
+		return value
+	}
+	return strings.TrimSpace(os.Getenv("INPUT_" + upper))
+}
+
+// actionGroup starts a collapsible section of the step log in action mode
+// and returns the function that ends it. Ending it more than once, e.g.
+// both explicitly and deferred, is fine. Outside action mode both are
+// no-ops.
+func actionGroup(cmd *cobra.Command, title string) func() {
+	if !actionMode {
+		return func() {}
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "::group::%s\n", escapeActionData(title))
+	ended := false
+	return func() {
+		if !ended {
+			ended = true
+			fmt.Fprintln(cmd.OutOrStdout(), "::endgroup::")
+		}
+	}
+}
+
+// setActionOutputs appends outputs to the $GITHUB_OUTPUT file in action
+// mode. Without the variable, e.g. with --output-format action outside
+// GitHub Actions, the outputs are skipped with a warning.
+func setActionOutputs(cmd *cobra.Command, outputs []actionOutput) error {
+	if !actionMode {
+		return nil
+	}
+	path := os.Getenv("GITHUB_OUTPUT")
+	if path == "" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings([]string{"GITHUB_OUTPUT is not set; skipping the step outputs"}))
+		return nil
+	}
+
+	var b strings.Builder
+	for _, output := range outputs {
+		if !strings.ContainsAny(output.Value, "\r\n") {
+			fmt.Fprintf(&b, "%s=%s\n", output.Name, output.Value)
+			continue
+		}
+		delimiter, err := outputDelimiter(output.Value)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s<<%s\n%s\n%s\n", output.Name, delimiter, output.Value, delimiter)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write step outputs: %w", err)
+	}
+	_, err = file.WriteString(b.String())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write step outputs: %w", err)
+	}
+	return nil
+}
+
+// outputDelimiter returns a heredoc delimiter for a multiline output that
+// does not occur in value, so a generated body cannot end the value early.
+func outputDelimiter(value string) (string, error) {
+	for {
+		random := make([]byte, 8)
+		if _, err := rand.Read(random); err != nil {
+			return "", fmt.Errorf("failed to write step outputs: %w", err)
+		}
+		delimiter := "gelf_" + hex.EncodeToString(random)
+		if !strings.Contains(value, delimiter) {
+			return delimiter, nil
+		}
+	}
+}
+
+// printActionError annotates the step with err so that the failure shows
+// in the run summary, not only in the log.
+func printActionError(err error) {
+	fmt.Fprintf(os.Stdout, "::error::%s\n", escapeActionData(err.Error()))
+}
+
+// escapeActionData escapes text for the message part of a workflow command.
+func escapeActionData(text string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(text)
+}
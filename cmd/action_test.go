@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// withClosedStdin runs the test with stdin at end of file, as in a workflow
+// step.
+func withClosedStdin(t *testing.T) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		r.Close()
+	})
+}
+
+// resetActionMode restores the action mode globals after the test.
+func resetActionMode(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		outputFormat, actionFlag, actionMode = outputFormatText, false, false
+	})
+}
+
+// newActionTestCommand returns a command with the action flags and a few
+// flags of each kind, parsed from args.
+func newActionTestCommand(t *testing.T, args ...string) (*cobra.Command, *string, *[]string, *bool) {
+	t.Helper()
+	cmd := &cobra.Command{Use: "test"}
+	baseRef := cmd.Flags().String("base-ref", "", "")
+	labels := cmd.Flags().StringArray("label", nil, "")
+	draft := cmd.Flags().Bool("draft", false, "")
+	addActionFlags(cmd)
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatal(err)
+	}
+	return cmd, baseRef, labels, draft
+}
+
+func TestSetupActionModeInputs(t *testing.T) {
+	resetActionMode(t)
+	withClosedStdin(t)
+	t.Setenv("INPUT_BASE_REF", " main ")
+	t.Setenv("INPUT_LABEL", "bug\n\n  size/S \n")
+	t.Setenv("INPUT_DRAFT", "true")
+	// help, action and output-format are never read from inputs.
+	t.Setenv("INPUT_OUTPUT_FORMAT", "text")
+
+	cmd, baseRef, labels, draft := newActionTestCommand(t, "--output-format", "action")
+	if err := setupActionMode(cmd); err != nil {
+		t.Fatalf("setupActionMode() error: %v", err)
+	}
+	if !actionMode {
+		t.Fatal("actionMode is not set")
+	}
+	if *baseRef != "main" {
+		t.Errorf("--base-ref = %q, want %q", *baseRef, "main")
+	}
+	if want := []string{"bug", "size/S"}; !reflect.DeepEqual(*labels, want) {
+		t.Errorf("--label = %q, want %q", *labels, want)
+	}
+	if !*draft {
+		t.Error("--draft is not set")
+	}
+}
+
+func TestSetupActionModeHyphenatedInput(t *testing.T) {
+	resetActionMode(t)
+	withClosedStdin(t)
+	t.Setenv("INPUT_BASE-REF", "develop")
+
+	cmd, baseRef, _, _ := newActionTestCommand(t, "--output-format", "action")
+	if err := setupActionMode(cmd); err != nil {
+		t.Fatalf("setupActionMode() error: %v", err)
+	}
+	if *baseRef != "develop" {
+		t.Errorf("--base-ref = %q, want %q", *baseRef, "develop")
+	}
+}
+
+func TestSetupActionModeFlagsWin(t *testing.T) {
+	resetActionMode(t)
+	withClosedStdin(t)
+	t.Setenv("INPUT_BASE_REF", "main")
+
+	cmd, baseRef, _, _ := newActionTestCommand(t, "--output-format", "action", "--base-ref", "release")
+	if err := setupActionMode(cmd); err != nil {
+		t.Fatalf("setupActionMode() error: %v", err)
+	}
+	if *baseRef != "release" {
+		t.Errorf("--base-ref = %q, want the command line's %q", *baseRef, "release")
+	}
+}
+
+func TestSetupActionModeInvalidInput(t *testing.T) {
+	resetActionMode(t)
+	withClosedStdin(t)
+	t.Setenv("INPUT_DRAFT", "maybe")
+
+	cmd, _, _, _ := newActionTestCommand(t, "--output-format", "action")
+	err := setupActionMode(cmd)
+	if err == nil || !strings.Contains(err.Error(), "invalid input draft") {
+		t.Errorf("setupActionMode() error = %v, want an invalid input draft error", err)
+	}
+}
+
+func TestSetupActionModeOutsideActions(t *testing.T) {
+	resetActionMode(t)
+	withClosedStdin(t)
+	t.Setenv("GITHUB_ACTIONS", "")
+	t.Setenv("INPUT_BASE_REF", "main")
+
+	cmd, baseRef, _, _ := newActionTestCommand(t, "--action")
+	err := setupActionMode(cmd)
+	if err == nil || !strings.Contains(err.Error(), "--action only works in GitHub Actions") {
+		t.Fatalf("setupActionMode() error = %v, want the --action refusal", err)
+	}
+	if actionMode || *baseRef != "" {
+		t.Errorf("inputs were read although --action was refused")
+	}
+
+	// Without either flag the inputs are ignored.
+	cmd, baseRef, _, _ = newActionTestCommand(t)
+	if err := setupActionMode(cmd); err != nil {
+		t.Fatalf("setupActionMode() error: %v", err)
+	}
+	if actionMode || *baseRef != "" {
+		t.Errorf("inputs were read outside action mode")
+	}
+}
+
+func TestSetupActionModeInGitHubActions(t *testing.T) {
+	resetActionMode(t)
+	withClosedStdin(t)
+	t.Setenv("GITHUB_ACTIONS", "true")
+
+	cmd, _, _, _ := newActionTestCommand(t, "--action")
+	if err := setupActionMode(cmd); err != nil {
+		t.Fatalf("setupActionMode() error: %v", err)
+	}
+	if !actionMode {
+		t.Error("actionMode is not set")
+	}
+}
+
+func TestSetActionOutputs(t *testing.T) {
+	resetActionMode(t)
+	actionMode = true
+	path := filepath.Join(t.TempDir(), "output")
+	t.Setenv("GITHUB_OUTPUT", path)
+
+	body := "## Summary\nEOF\nends the heredoc of a naive writer\r\n"
+	err := setActionOutputs(&cobra.Command{}, []actionOutput{
+		{Name: "number", Value: "42"},
+		{Name: "body", Value: body},
+	})
+	if err != nil {
+		t.Fatalf("setActionOutputs() error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := string(data)
+
+	first, rest, _ := strings.Cut(output, "\n")
+	if first != "number=42" {
+		t.Errorf("first output line = %q, want %q", first, "number=42")
+	}
+	header, rest, _ := strings.Cut(rest, "\n")
+	delimiter, ok := strings.CutPrefix(header, "body<<")
+	if !ok || delimiter == "" {
+		t.Fatalf("multiline output header = %q, want body<<DELIMITER", header)
+	}
+	if strings.Contains(body, delimiter) {
+		t.Errorf("delimiter %q occurs in the value", delimiter)
+	}
+	if want := body + "\n" + delimiter + "\n"; rest != want {
+		t.Errorf("multiline output = %q, want %q", rest, want)
+	}
+}
+
+func TestSetActionOutputsAppends(t *testing.T) {
+	resetActionMode(t)
+	actionMode = true
+	path := filepath.Join(t.TempDir(), "output")
+	if err := os.WriteFile(path, []byte("earlier=1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_OUTPUT", path)
+
+	if err := setActionOutputs(&cobra.Command{}, []actionOutput{{Name: "url", Value: "https://github.com/o/r/pull/1"}}); err != nil {
+		t.Fatalf("setActionOutputs() error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if want := "earlier=1\nurl=https://github.com/o/r/pull/1\n"; string(data) != want {
+		t.Errorf("output file = %q, want %q", data, want)
+	}
+}
+
+func TestSetActionOutputsWithoutGitHubOutput(t *testing.T) {
+	resetActionMode(t)
+	actionMode = true
+	t.Setenv("GITHUB_OUTPUT", "")
+
+	var stderr strings.Builder
+	cmd := &cobra.Command{}
+	cmd.SetErr(&stderr)
+	if err := setActionOutputs(cmd, []actionOutput{{Name: "number", Value: "42"}}); err != nil {
+		t.Fatalf("setActionOutputs() error: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "GITHUB_OUTPUT is not set") {
+		t.Errorf("stderr = %q, want a GITHUB_OUTPUT warning", stderr.String())
+	}
+}
+
+func TestPRCreateActionModeRequiresYes(t *testing.T) {
+	resetActionMode(t)
+	withClosedStdin(t)
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("INPUT_YES", "")
+	t.Chdir(t.TempDir())
+
+	rootCmd.SetArgs([]string{"pr", "create", "--action"})
+	rootCmd.SetOut(&strings.Builder{})
+	rootCmd.SetErr(&strings.Builder{})
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		prCreateCmd.Flags().Set("action", "false")
+	})
+
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "nobody can confirm the pull request in action mode") {
+		t.Errorf("pr create --action error = %v, want the --yes requirement", err)
+	}
+}
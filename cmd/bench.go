@@ -0,0 +1,419 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/ai"
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/diffreport"
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/github"
+	"github.com/EkeMinusYou/gelf/internal/progress"
+	"github.com/EkeMinusYou/gelf/internal/redact"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Compare models on the same commit message and PR generation input",
+	Long: `Generates a commit message and a pull request description for a commit range
+with each of the listed models, several times, and compares latency, token
+use and the outputs. Outputs are not scored.
+
+A table is printed and a markdown report with every output side by side is
+written. Run i of every model sends seed --seed+i-1, so models that support
+seeds answer the same way when the bench is run again.`,
+	Args: cobra.NoArgs,
+	RunE: runBench,
+}
+
+var (
+	benchRef          string
+	benchModels       []string
+	benchTasks        []string
+	benchRuns         int
+	benchConcurrency  int
+	benchSeed         int32
+	benchOutput       string
+	benchAllowSecrets bool
+)
+
+// Generation tasks gelf bench can run.
+const (
+	benchTaskCommit = "commit"
+	benchTaskPR     = "pr"
+)
+
+func init() {
+	benchCmd.Flags().StringVar(&benchRef, "ref", "", "Commit range to generate for, e.g. origin/main..HEAD (default: the base branch..HEAD)")
+	benchCmd.Flags().StringSliceVar(&benchModels, "models", []string{"flash", "pro"}, "Comma-separated models to compare (flash, pro or model names)")
+	benchCmd.Flags().StringSliceVar(&benchTasks, "tasks", []string{benchTaskCommit, benchTaskPR}, "Comma-separated generations to run: commit, pr")
+	benchCmd.Flags().IntVar(&benchRuns, "runs", 3, "Generations per model and task")
+	benchCmd.Flags().IntVar(&benchConcurrency, "concurrency", 4, "Most generations running at once")
+	benchCmd.Flags().Int32Var(&benchSeed, "seed", 1, "Seed of the first run; run i uses seed+i-1")
+	benchCmd.Flags().StringVar(&benchOutput, "output", "", "Markdown report path (default: gelf-bench-<time>.md)")
+	benchCmd.Flags().BoolVar(&benchAllowSecrets, "allow-secrets", false, "Send the diff even if it appears to contain secrets")
+
+	rootCmd.AddCommand(benchCmd)
+}
+
+// benchJob is one generation: task with model, run number run.
+type benchJob struct {
+	Task  string
+	Model string
+	Run   int
+	Seed  int32
+}
+
+// benchResult is the outcome of a benchJob. Output is the commit message,
+// or the pull request title and body separated by a blank line.
+type benchResult struct {
+	benchJob
+	Latency      time.Duration
+	InputTokens  int
+	OutputTokens int
+	Output       string
+	Err          error
+}
+
+// benchInput is what every model is given.
+type benchInput struct {
+	Range  string
+	Commit ai.CommitInput
+	PR     ai.PullRequestInput
+}
+
+func runBench(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := applyFlagDefaults(cmd, "bench", cfg.Defaults["bench"]); err != nil {
+		return err
+	}
+	if !cfg.UseColor() {
+		ui.DisableColor()
+	}
+
+	if benchRuns < 1 {
+		return fmt.Errorf("--runs must be at least 1")
+	}
+	if benchConcurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	for _, task := range benchTasks {
+		if task != benchTaskCommit && task != benchTaskPR {
+			return fmt.Errorf("invalid --tasks entry %q (expected commit or pr)", task)
+		}
+	}
+	var models []string
+	for _, name := range benchModels {
+		if name = strings.TrimSpace(name); name != "" && !slices.Contains(models, cfg.ResolveModel(name)) {
+			models = append(models, cfg.ResolveModel(name))
+		}
+	}
+	if len(models) == 0 {
+		return fmt.Errorf("--models needs at least one model")
+	}
+
+	input, err := collectBenchInput(ctx, cmd, cfg)
+	if err != nil {
+		return err
+	}
+	if err := checkSecrets(cmd, cfg, input.Commit.Diff, benchAllowSecrets, false); err != nil {
+		return err
+	}
+
+	client, err := ai.NewProvider(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create AI client: %w", err)
+	}
+
+	var jobs []benchJob
+	for _, task := range benchTasks {
+		for _, model := range models {
+			for run := 1; run <= benchRuns; run++ {
+				jobs = append(jobs, benchJob{Task: task, Model: model, Run: run, Seed: benchSeed + int32(run-1)})
+			}
+		}
+	}
+	results := runBenchJobs(ctx, cmd, client, input, jobs)
+
+	fmt.Fprint(cmd.OutOrStdout(), ui.RenderTable(
+		[]string{"TASK", "MODEL", "OK", "AVG LATENCY", "MIN", "MAX", "AVG IN TOKENS", "AVG OUT TOKENS"},
+		benchSummaryRows(results, benchTasks, models),
+	))
+
+	path := benchOutput
+	if path == "" {
+		path = fmt.Sprintf("gelf-bench-%s.md", time.Now().Format("20060102-150405"))
+	}
+	if err := os.WriteFile(path, []byte(benchReport(input, results, benchTasks, models)), 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "\nReport written to %s\n", path)
+
+	for _, result := range results {
+		if result.Err != nil {
+			return exitWithCode(cmd, ExitError, nil)
+		}
+	}
+	return nil
+}
+
+// collectBenchInput builds the commit and pull request inputs for --ref the
+// way gelf commit and gelf pr create would, with the repository's own pull
+// request template only.
+func collectBenchInput(ctx context.Context, cmd *cobra.Command, cfg *config.Config) (*benchInput, error) {
+	baseRef, headRef, found := strings.Cut(benchRef, "..")
+	if benchRef == "" {
+		source, _, err := detectPRSource()
+		if err != nil {
+			return nil, err
+		}
+		baseRef = source.Base
+	}
+	if !found || headRef == "" {
+		headRef = "HEAD"
+	}
+	if strings.HasPrefix(headRef, ".") {
+		return nil, fmt.Errorf("invalid --ref %q: symmetric ranges (a...b) are not supported", benchRef)
+	}
+	for _, ref := range []string{baseRef, headRef} {
+		if _, err := git.ResolveCommit(ref); err != nil || strings.HasPrefix(ref, "-") {
+			return nil, fmt.Errorf("invalid --ref %q: %s is not a commit in this repository", benchRef, ref)
+		}
+	}
+
+	commitLog, err := git.GetCommitLog(baseRef, headRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit log: %w", err)
+	}
+	diffStat, err := git.GetCommittedDiffStat(baseRef, headRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diff stat: %w", err)
+	}
+	diff, err := git.GetCommittedDiff(baseRef, headRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diff: %w", err)
+	}
+	if diff == "" {
+		return nil, exitWithCode(cmd, ExitNothingToDo, fmt.Errorf("no changes between %s and %s", baseRef, headRef))
+	}
+
+	redactor, err := redact.New(cfg.RedactRules)
+	if err != nil {
+		return nil, err
+	}
+	classification := git.ClassifyDiff(diff)
+	scope := commitScope(cfg, diff)
+	diff, report := diffreport.Filter(diff, redactor, prDiffOptions(cfg))
+	printDiffReport(cmd, report)
+
+	templateContent := ""
+	if repoRoot, err := git.GetRepoRoot(); err == nil {
+		template, _, err := github.FindPullRequestTemplateWithMerge(ctx, repoRoot, "", "", github.TemplateOptions{
+			Mode:    cfg.PRTemplateMerge,
+			SkipOrg: true,
+		})
+		if err == nil && template != nil {
+			templateContent = redactor.Apply(template.Content)
+		}
+	}
+
+	instructions := loadInstructions(cmd)
+	return &benchInput{
+		Range: baseRef + ".." + headRef,
+		Commit: ai.CommitInput{
+			Diff:            diff,
+			Language:        cfg.CommitLanguage,
+			SubjectLanguage: cfg.CommitSubjectLanguage,
+			BodyLanguage:    cfg.CommitBodyLanguage,
+			Scope:           scope,
+			Instructions:    instructions,
+			FileOrder:       cfg.PromptFileOrder,
+		},
+		PR: ai.PullRequestInput{
+			BaseBranch:       baseRef,
+			HeadBranch:       headRef,
+			CommitLog:        redactor.Apply(commitLog),
+			DiffStat:         redactor.Apply(diffStat),
+			Diff:             diff,
+			Template:         templateContent,
+			Language:         cfg.PRLanguage,
+			TitleLanguage:    cfg.PRTitleLanguage,
+			BodyLanguage:     cfg.PRBodyLanguage,
+			FileCategories:   classification.String(),
+			DominantCategory: string(classification.Dominant),
+			DocsOnly:         classification.Only(git.CategoryDocs),
+			Scope:            scope,
+			Instructions:     instructions,
+			FileOrder:        cfg.PromptFileOrder,
+		},
+	}, nil
+}
+
+// runBenchJobs runs jobs with at most --concurrency at once and returns
+// their results in the order of jobs.
+func runBenchJobs(ctx context.Context, cmd *cobra.Command, client ai.Provider, input *benchInput, jobs []benchJob) []benchResult {
+	reporter := ui.NewReporter(cmd.ErrOrStderr())
+	message := fmt.Sprintf("Running %d generations...", len(jobs))
+	reporter.Report(progress.Event{Kind: progress.PhaseStart, Phase: "bench", Message: message})
+
+	results := make([]benchResult, len(jobs))
+	slots := make(chan struct{}, benchConcurrency)
+	var mu sync.Mutex
+	done := 0
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			results[i] = runBenchJob(ctx, client, input, job)
+
+			mu.Lock()
+			done++
+			reporter.Report(progress.Event{Kind: progress.Item, Phase: "bench", Current: done, Total: len(jobs)})
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	reporter.Report(progress.Event{Kind: progress.PhaseEnd, Phase: "bench"})
+	return results
+}
+
+func runBenchJob(ctx context.Context, client ai.Provider, input *benchInput, job benchJob) benchResult {
+	result := benchResult{benchJob: job}
+	// A pull request can take two calls when its JSON needs fixing, so
+	// tokens are summed.
+	var mu sync.Mutex
+	client = client.WithModel(job.Model).WithSeed(job.Seed).WithReporter(progress.ReporterFunc(func(event progress.Event) {
+		if event.Kind == progress.Tokens {
+			mu.Lock()
+			result.InputTokens += event.InputTokens
+			result.OutputTokens += event.OutputTokens
+			mu.Unlock()
+		}
+	}))
+
+	start := time.Now()
+	switch job.Task {
+	case benchTaskCommit:
+		result.Output, result.Err = client.GenerateCommitMessage(ctx, input.Commit)
+		result.Output = strings.TrimSpace(result.Output)
+	case benchTaskPR:
+		var content *ai.PullRequestContent
+		content, result.Err = client.GeneratePullRequestContent(ctx, input.PR)
+		if content != nil {
+			result.Output = strings.TrimSpace(content.Title + "\n\n" + content.Body)
+		}
+	}
+	result.Latency = time.Since(start)
+	return result
+}
+
+// benchSummaryRows aggregates results per task and model, in flag order.
+func benchSummaryRows(results []benchResult, tasks, models []string) [][]string {
+	var rows [][]string
+	for _, task := range tasks {
+		for _, model := range models {
+			var ok, runs, inTokens, outTokens int
+			var total, fastest, slowest time.Duration
+			for _, result := range results {
+				if result.Task != task || result.Model != model {
+					continue
+				}
+				runs++
+				if result.Err != nil {
+					continue
+				}
+				ok++
+				total += result.Latency
+				inTokens += result.InputTokens
+				outTokens += result.OutputTokens
+				if fastest == 0 || result.Latency < fastest {
+					fastest = result.Latency
+				}
+				slowest = max(slowest, result.Latency)
+			}
+			row := []string{task, model, fmt.Sprintf("%d/%d", ok, runs), "-", "-", "-", "-", "-"}
+			if ok > 0 {
+				row[3] = benchDuration(total / time.Duration(ok))
+				row[4] = benchDuration(fastest)
+				row[5] = benchDuration(slowest)
+				row[6] = fmt.Sprint(inTokens / ok)
+				row[7] = fmt.Sprint(outTokens / ok)
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func benchDuration(d time.Duration) string {
+	return d.Round(10 * time.Millisecond).String()
+}
+
+// benchReport renders the markdown report: the summary table, then per task
+// a table with a row per run and a column per model.
+func benchReport(input *benchInput, results []benchResult, tasks, models []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# gelf bench\n\n")
+	fmt.Fprintf(&b, "- Range: `%s`\n- Date: %s\n- Runs: %d (seeds %d to %d)\n\n", input.Range, time.Now().Format("2006-01-02 15:04"), benchRuns, benchSeed, benchSeed+int32(benchRuns-1))
+
+	headers := []string{"Task", "Model", "OK", "Avg latency", "Min", "Max", "Avg input tokens", "Avg output tokens"}
+	b.WriteString(markdownRow(headers))
+	b.WriteString(markdownRow(slices.Repeat([]string{"---"}, len(headers))))
+	for _, row := range benchSummaryRows(results, tasks, models) {
+		b.WriteString(markdownRow(row))
+	}
+
+	titles := map[string]string{benchTaskCommit: "Commit messages", benchTaskPR: "Pull requests"}
+	for _, task := range tasks {
+		fmt.Fprintf(&b, "\n## %s\n\n", titles[task])
+		b.WriteString(markdownRow(append([]string{"Run"}, models...)))
+		b.WriteString(markdownRow(slices.Repeat([]string{"---"}, len(models)+1)))
+		for run := 1; run <= benchRuns; run++ {
+			row := []string{fmt.Sprintf("%d (seed %d)", run, benchSeed+int32(run-1))}
+			for _, model := range models {
+				for _, result := range results {
+					if result.Task == task && result.Model == model && result.Run == run {
+						row = append(row, benchCell(result))
+					}
+				}
+			}
+			b.WriteString(markdownRow(row))
+		}
+	}
+	return b.String()
+}
+
+// benchCell renders a result for a markdown table cell, where line breaks
+// and pipes have to be escaped.
+func benchCell(result benchResult) string {
+	text := result.Output
+	if result.Err != nil {
+		text = "**error:** " + result.Err.Error()
+	} else {
+		text += fmt.Sprintf("\n\n_%s, %d in / %d out tokens_", benchDuration(result.Latency), result.InputTokens, result.OutputTokens)
+	}
+	text = strings.ReplaceAll(text, "|", `\|`)
+	return strings.ReplaceAll(text, "\n", "<br>")
+}
+
+func markdownRow(cells []string) string {
+	return "| " + strings.Join(cells, " | ") + " |\n"
+}
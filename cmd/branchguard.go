@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/ai"
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	"github.com/EkeMinusYou/gelf/internal/redact"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// guardBaseBranch handles pr create being run on the base branch itself or on
+// a detached HEAD. Interactively it offers to move the commits to a new
+// branch and returns that branch; with --yes it fails instead, and with
+// --dry-run it only warns.
+func guardBaseBranch(ctx context.Context, cmd *cobra.Command, cfg *config.Config, headBranch, baseBranch string) (string, error) {
+	detached := headBranch == "HEAD"
+	if !detached && headBranch != baseBranch {
+		return headBranch, nil
+	}
+
+	baseRef := prSource(baseBranch).Base
+	commitLog, err := git.GetCommitLog(baseRef, "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to get commit log: %w", err)
+	}
+	if commitLog == "" {
+		// Nothing to move; the regular "no commits" handling applies.
+		return headBranch, nil
+	}
+
+	// Each message has a variant for a detached HEAD, which has no branch
+	// name to show.
+	message := func(id string, args ...any) string {
+		if detached {
+			return i18n.T("pr.on_detached_"+id, args...)
+		}
+		return i18n.T("pr.on_base_"+id, append([]any{baseBranch}, args...)...)
+	}
+
+	if prDryRun {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings([]string{message("dry_run")}))
+		return headBranch, nil
+	}
+	if prYes || !ui.IsInteractive() {
+		return "", errors.New(message("refused"))
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "%s\n", ui.FormatWarnings([]string{message("commits", baseRef)}))
+	for _, line := range strings.Split(commitLog, "\n") {
+		fmt.Fprintf(out, "  %s\n", line)
+	}
+
+	branch, err := suggestBranchName(ctx, cmd, cfg, commitLog)
+	if err != nil {
+		return "", err
+	}
+	for {
+		fmt.Fprintf(out, "\n%s\n", i18n.T("pr.move_commands", branch))
+		for _, args := range git.ExtractCommands(branch, baseRef, detached) {
+			fmt.Fprintf(out, "  %s\n", shellJoin(args))
+		}
+		if !detached {
+			fmt.Fprintf(out, "%s\n", ui.FormatWarnings([]string{
+				i18n.T("pr.move_reset_note", baseBranch, baseRef, branch),
+			}))
+		}
+
+		choice, err := ui.PromptChoiceStyledWithWriter(i18n.T("pr.move_confirm"), []string{"y", "e", "n"}, out)
+		if err != nil {
+			return "", err
+		}
+
+		switch choice {
+		case "y":
+			release, err := acquireRepoLock(cmd, prWait)
+			if err != nil {
+				return "", err
+			}
+			err = git.ExtractCommits(branch, baseRef, detached)
+			release()
+			if err != nil {
+				return "", fmt.Errorf("failed to move commits: %w", err)
+			}
+			fmt.Fprintf(out, "%s\n", ui.RenderSuccessHeader(i18n.T("pr.moved", branch)))
+			return branch, nil
+		case "e":
+			edited, err := ui.EditText(branch+"\n", "gelf-branch-*.txt")
+			if err != nil {
+				return "", err
+			}
+			name := strings.TrimSpace(edited)
+			if name == "" {
+				continue
+			}
+			if err := git.ValidateBranchName(name); err != nil {
+				fmt.Fprintf(out, "%s\n", ui.FormatWarnings([]string{i18n.T("pr.invalid_branch", name)}))
+				continue
+			}
+			branch = name
+		default:
+			return "", exitWithCode(cmd, ExitDeclined, nil)
+		}
+	}
+}
+
+// headBaseProblem explains why HEAD has nothing to propose against
+// origin/<baseBranch>: the branch is the base branch itself, points at the
+// same commit, or only has commits that are already in the base. It returns
+// "" when HEAD has commits of its own or the base cannot be resolved, in
+// which case the regular handling applies.
+func headBaseProblem(headBranch, baseBranch string) string {
+	baseRef := prSource(baseBranch).Base
+	baseSHA, err := git.ResolveCommit(baseRef)
+	if err != nil {
+		return ""
+	}
+	headSHA, err := git.ResolveCommit("HEAD")
+	if err != nil {
+		return ""
+	}
+	mergeBase, err := git.MergeBase("HEAD", baseRef)
+	if err != nil || mergeBase != headSHA {
+		return ""
+	}
+
+	short := baseSHA
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	switch {
+	case headBranch == baseBranch:
+		return i18n.T("pr.base_no_commits", baseBranch, baseRef, short)
+	case headSHA == baseSHA:
+		return i18n.T("pr.same_commit_as_base", headBranch, baseRef, short, headBranch)
+	default:
+		return i18n.T("pr.already_in_base", headBranch, baseRef, short, baseRef, baseRef)
+	}
+}
+
+// warnBaseRefMismatch warns when --base-ref is not where HEAD leaves
+// baseRef, the base branch: the description then covers other commits than
+// the pull request will show.
+func warnBaseRefMismatch(cmd *cobra.Command, ref, baseRef string) {
+	refSHA, err := git.ResolveCommit(ref)
+	if err != nil {
+		return
+	}
+	forkPoint, err := git.MergeBase("HEAD", baseRef)
+	if err != nil || forkPoint == refSHA {
+		return
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings([]string{
+		i18n.T("pr.base_ref_mismatch", ref, refSHA[:7], baseRef, forkPoint[:7]),
+	}))
+}
+
+// offerCommitFirst handles pr create being run before the work was
+// committed: HEAD has nothing the base does not, but there are uncommitted
+// changes. With staged changes and a terminal it offers to run the commit
+// flow, and reports whether a commit was made so pr create can go on.
+// Otherwise it only says what to do.
+func offerCommitFirst(cmd *cobra.Command, headBranch, baseBranch string) (bool, error) {
+	staged, err := git.GetStagedDiff()
+	if err != nil {
+		return false, nil
+	}
+	clean, err := git.IsWorktreeClean()
+	if err != nil {
+		return false, nil
+	}
+
+	out := cmd.ErrOrStderr()
+	switch {
+	case clean || headBranch == baseBranch:
+		// On the base branch the work needs a branch first, which
+		// headBaseProblem already says.
+		return false, nil
+	case staged == "":
+		fmt.Fprintln(out, i18n.T("pr.not_committed"))
+		return false, nil
+	case prYes || prDryRun || prJSON || !ui.IsInteractive():
+		fmt.Fprintln(out, i18n.T("pr.staged_not_committed"))
+		return false, nil
+	}
+
+	fmt.Fprintln(out)
+	confirmed, err := ui.PromptYesNoStyledWithWriter(i18n.T("pr.commit_first"), out)
+	if err != nil || !confirmed {
+		return false, err
+	}
+	fmt.Fprintln(out)
+
+	before, err := git.ResolveCommit("HEAD")
+	if err != nil {
+		return false, err
+	}
+	commitWait = prWait
+	if err := runCommit(commitCmd, nil); err != nil {
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			return false, exitWithCode(cmd, exitErr.code, exitErr.err)
+		}
+		return false, err
+	}
+	after, err := git.ResolveCommit("HEAD")
+	if err != nil {
+		return false, err
+	}
+	if after != before {
+		fmt.Fprintln(out)
+	}
+	return after != before, nil
+}
+
+// suggestBranchName asks the model for a branch name, falling back to a slug
+// of the first commit subject. The commit log is redacted before it is sent,
+// as pr create does.
+func suggestBranchName(ctx context.Context, cmd *cobra.Command, cfg *config.Config, commitLog string) (string, error) {
+	redactor, err := redact.New(cfg.RedactRules)
+	if err != nil {
+		return "", err
+	}
+	commitLog = redactor.Apply(commitLog)
+
+	aiClient, err := ai.NewProvider(ctx, cfg)
+	if err == nil {
+		aiClient.SetReporter(ui.NewReporter(cmd.ErrOrStderr()))
+		if name, err := aiClient.GenerateBranchName(ctx, commitLog, loadInstructions(cmd)); err == nil {
+			if slug := git.Slugify(name); slug != "" {
+				return slug, nil
+			}
+		}
+	}
+
+	firstLine := strings.SplitN(commitLog, "\n", 2)[0]
+	if _, subject, found := strings.Cut(firstLine, " "); found {
+		firstLine = subject
+	}
+	if slug := git.Slugify(firstLine); slug != "" {
+		return slug, nil
+	}
+	return "gelf/pull-request", nil
+}
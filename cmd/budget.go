@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/ai"
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/state"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// overrideBudget lets model calls go ahead past the configured budgets. They
+// are still counted.
+var overrideBudget bool
+
+// budgetTracker counts the model calls of one command in the state directory
+// and refuses calls once a budget is used up.
+type budgetTracker struct {
+	command string
+	global  config.Budget
+	own     config.Budget
+
+	// mu keeps the calls of concurrent requests (gelf serve) from losing
+	// each other's counts.
+	mu     sync.Mutex
+	warned bool
+}
+
+// usageTally is the model use counted in a period.
+type usageTally struct {
+	Calls        int
+	InputTokens  int
+	OutputTokens int
+}
+
+func (t *usageTally) add(entry state.UsageEntry) {
+	t.Calls += entry.Calls
+	t.InputTokens += entry.InputTokens
+	t.OutputTokens += entry.OutputTokens
+}
+
+// commandName names cmd for usage and per-command budgets: the words of its
+// path after "gelf", joined with "-" ("commit", "pr-create").
+func commandName(cmd *cobra.Command) string {
+	path := strings.Fields(cmd.CommandPath())
+	if len(path) <= 1 {
+		return path[0]
+	}
+	return strings.Join(path[1:], "-")
+}
+
+// setUsageTracker has every model call made by cmd counted, and held to the
+// budgets in cfg unless --override-budget is set.
+func setUsageTracker(cmd *cobra.Command, cfg *config.Config) {
+	tracker := &budgetTracker{command: commandName(cmd)}
+	if !overrideBudget {
+		tracker.global = cfg.Budget
+		tracker.own = cfg.CommandBudgets[tracker.command]
+	}
+	ai.SetUsageTracker(tracker)
+}
+
+func (b *budgetTracker) Allow(model string) error {
+	if b.global.IsZero() && b.own.IsZero() {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entries, err := state.LoadUsage()
+	if err != nil {
+		// A usage file gelf cannot read must not lock the user out.
+		b.warn(err)
+		return nil
+	}
+	now := time.Now()
+	if err := checkBudget(entries, now, b.global, "", "across all commands"); err != nil {
+		return err
+	}
+	return checkBudget(entries, now, b.own, b.command, "for "+b.command)
+}
+
+func (b *budgetTracker) Record(provider, model string, inputTokens, outputTokens int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := state.RecordUsage(time.Now(), b.command, provider, model, inputTokens, outputTokens); err != nil {
+		b.warn(err)
+	}
+}
+
+// warn reports a usage file problem once per run.
+func (b *budgetTracker) warn(err error) {
+	if b.warned {
+		return
+	}
+	b.warned = true
+	fmt.Fprintf(os.Stderr, "%s\n", ui.FormatWarnings([]string{fmt.Sprintf("model usage is not being counted: %v", err)}))
+}
+
+// checkBudget returns an error when the use by command ("" for all commands)
+// has reached budget today or this week.
+func checkBudget(entries []state.UsageEntry, now time.Time, budget config.Budget, command, scope string) error {
+	for _, period := range budgetPeriods(now, budget) {
+		tally := tallyUsage(entries, period.start, command)
+		var reached string
+		switch {
+		case period.limit.Calls > 0 && tally.Calls >= period.limit.Calls:
+			reached = fmt.Sprintf("%d of %d calls", tally.Calls, period.limit.Calls)
+		case period.limit.InputTokens > 0 && tally.InputTokens >= period.limit.InputTokens:
+			reached = fmt.Sprintf("%d of %d input tokens", tally.InputTokens, period.limit.InputTokens)
+		default:
+			continue
+		}
+		return fmt.Errorf("%s model budget %s used up: %s (resets %s). Run with --override-budget to go ahead anyway",
+			period.name, scope, reached, period.reset.Format("Mon Jan 2 15:04"))
+	}
+	return nil
+}
+
+// budgetPeriod is a period a budget limits, running from start up to reset.
+type budgetPeriod struct {
+	name  string
+	limit config.BudgetLimit
+	start time.Time
+	reset time.Time
+}
+
+// budgetPeriods returns the periods around now that budget sets a limit for.
+func budgetPeriods(now time.Time, budget config.Budget) []budgetPeriod {
+	day, week := startOfDay(now), startOfWeek(now)
+	var periods []budgetPeriod
+	for _, period := range []budgetPeriod{
+		{"daily", budget.Daily, day, day.AddDate(0, 0, 1)},
+		{"weekly", budget.Weekly, week, week.AddDate(0, 0, 7)},
+	} {
+		if period.limit != (config.BudgetLimit{}) {
+			periods = append(periods, period)
+		}
+	}
+	return periods
+}
+
+// tallyUsage adds up the use by command ("" for all commands) on or after
+// since.
+func tallyUsage(entries []state.UsageEntry, since time.Time, command string) usageTally {
+	var tally usageTally
+	for _, entry := range entries {
+		if entry.Date().Before(since) || (command != "" && entry.Command != command) {
+			continue
+		}
+		tally.add(entry)
+	}
+	return tally
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek returns the Monday midnight starting t's week.
+func startOfWeek(t time.Time) time.Time {
+	days := (int(t.Weekday()) + 6) % 7
+	return startOfDay(t).AddDate(0, 0, -days)
+}
@@ -3,12 +3,22 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/EkeMinusYou/gelf/internal/ai"
+	"github.com/EkeMinusYou/gelf/internal/commitmsg"
 	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/conventional"
+	"github.com/EkeMinusYou/gelf/internal/diffreport"
 	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	"github.com/EkeMinusYou/gelf/internal/redact"
+	"github.com/EkeMinusYou/gelf/internal/state"
+	"github.com/EkeMinusYou/gelf/internal/timing"
 	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/EkeMinusYou/gelf/pkg/gelf"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
 )
@@ -21,11 +31,23 @@ var commitCmd = &cobra.Command{
 }
 
 var (
-	dryRun         bool
-	quiet          bool
-	model          string
-	commitLanguage string
-	yesFlag        bool
+	dryRun           bool
+	quiet            bool
+	model            string
+	commitLanguage   string
+	yesFlag          bool
+	allowSecrets     bool
+	showPrompt       bool
+	retryLast        bool
+	commitWait       bool
+	commitOnly       []string
+	commitTrailers   []string
+	detectFormatting bool
+	noMergeDetect    bool
+	commitJJ         bool
+
+	commitSubjectLanguage string
+	commitBodyLanguage    string
 )
 
 var warningStyle = lipgloss.NewStyle().
@@ -36,18 +58,35 @@ func init() {
 	commitCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Generate message only without committing")
 	commitCmd.Flags().BoolVar(&quiet, "quiet", false, "Don't show diff output (only with --dry-run)")
 	commitCmd.Flags().StringVar(&model, "model", "", "Override default model for this generation")
+	commitCmd.RegisterFlagCompletionFunc("model", completeWithin(completeModels))
 	commitCmd.Flags().StringVar(&commitLanguage, "language", "", "Language for commit message generation (e.g., english, japanese)")
+	commitCmd.Flags().StringVar(&commitSubjectLanguage, "subject-language", "", "Language for the subject line only (e.g., english)")
+	commitCmd.Flags().StringVar(&commitBodyLanguage, "body-language", "", "Language for the body only (e.g., japanese)")
 	commitCmd.Flags().BoolVar(&yesFlag, "yes", false, "Automatically approve commit message without interactive confirmation")
+	commitCmd.Flags().BoolVar(&showPrompt, "show-prompt", false, "Print the prompt that would be sent to the model and exit")
+	commitCmd.Flags().BoolVar(&allowSecrets, "allow-secrets", false, "Send the diff even if it appears to contain secrets")
+	commitCmd.Flags().BoolVar(&retryLast, "retry-last", false, "Reuse the message saved by the last failed commit instead of generating a new one")
+	commitCmd.Flags().StringArrayVar(&commitOnly, "only", nil, "Commit only staged changes matching this pathspec (repeatable)")
+	commitCmd.Flags().StringArrayVar(&commitTrailers, "trailer", nil, "Append a trailer such as \"Refs: #123\" to the message (repeatable)")
+	commitCmd.Flags().BoolVar(&detectFormatting, "detect-formatting", false, "Propose \"style: format code\" without AI when the staged changes only touch whitespace")
+	commitCmd.Flags().BoolVar(&noMergeDetect, "no-merge-detect", false, "Describe a merge in progress by its combined diff like any other commit")
+	commitCmd.Flags().BoolVar(&commitWait, "wait", false, "Wait for another gelf operation in this repository to finish instead of failing")
+	commitCmd.Flags().BoolVar(&commitJJ, "jj", false, "Describe the working-copy change with jj describe instead of creating a git commit (jj repositories colocated with git)")
 }
 
 func runCommit(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
+	defer timing.StartSpan("commit").End()
 
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
+	if err := applyFlagDefaults(cmd, "commit", cfg.Defaults["commit"]); err != nil {
+		return err
+	}
+
 	if !cfg.UseColor() {
 		warningStyle = lipgloss.NewStyle() // No color
 	}
@@ -58,29 +97,187 @@ func runCommit(cmd *cobra.Command, args []string) error {
 	// Note: cfg.FlashModel is already set to the configured commit model in config.Load()
 
 	if commitLanguage != "" {
-		cfg.CommitLanguage = commitLanguage
+		cfg.CommitLanguage, err = flagLanguage(cmd, cfg, "language", commitLanguage)
+		if err != nil {
+			return err
+		}
+		cfg.CommitSubjectLanguage = cfg.CommitLanguage
+		cfg.CommitBodyLanguage = cfg.CommitLanguage
+	}
+	if commitSubjectLanguage != "" {
+		cfg.CommitSubjectLanguage, err = flagLanguage(cmd, cfg, "subject-language", commitSubjectLanguage)
+		if err != nil {
+			return err
+		}
+	}
+	if commitBodyLanguage != "" {
+		cfg.CommitBodyLanguage, err = flagLanguage(cmd, cfg, "body-language", commitBodyLanguage)
+		if err != nil {
+			return err
+		}
 	}
 
-	diff, err := git.GetStagedDiff()
-	if err != nil {
-		return fmt.Errorf("failed to get staged changes: %w", err)
+	if err := git.CheckJujutsu(); err != nil {
+		return err
+	}
+	if commitJJ {
+		if !git.IsJujutsu() {
+			return fmt.Errorf("--jj needs a jj repository colocated with git")
+		}
+		if len(commitOnly) > 0 {
+			return fmt.Errorf("--only cannot be used with --jj: jj describe applies to the whole working-copy change")
+		}
+	}
+
+	var diff string
+	if commitJJ {
+		diff, err = git.GetJujutsuDiff()
+		if err != nil {
+			return err
+		}
+	} else {
+		diff, err = git.GetStagedDiff(commitOnly...)
+		if err != nil {
+			return fmt.Errorf("failed to get staged changes: %w", err)
+		}
+	}
+	if len(commitOnly) > 0 && !commitJJ {
+		// git commit -- <paths> commits the working tree of the paths, so
+		// unstaged edits would be committed without being described.
+		unstaged, err := git.UnstagedFiles(commitOnly...)
+		if err != nil {
+			return fmt.Errorf("failed to check unstaged changes: %w", err)
+		}
+		if len(unstaged) > 0 {
+			return fmt.Errorf("--only paths have unstaged changes that would be committed without being described: %s; stage or stash them first", strings.Join(unstaged, ", "))
+		}
 	}
 
-	if diff == "" {
-		message := warningStyle.Render("⚠ No staged changes found. Please stage some changes first with 'git add'.")
+	// A merge is described by the commits it brings in and its conflict
+	// resolutions, not by the combined diff. It can be committed even when
+	// the result equals HEAD.
+	var merge *git.MergeState
+	if !noMergeDetect && !commitJJ {
+		merge, err = git.GetMergeState()
+		if err != nil {
+			return fmt.Errorf("failed to inspect merge in progress: %w", err)
+		}
+	}
+
+	if diff == "" && len(commitOnly) > 0 {
+		return exitWithCode(cmd, ExitNothingToDo, fmt.Errorf("no staged changes match --only %s", strings.Join(commitOnly, " ")))
+	}
+	if diff == "" && merge == nil {
+		message := warningStyle.Render(i18n.T("commit.no_staged"))
+		if commitJJ {
+			message = warningStyle.Render(i18n.T("commit.jj_empty"))
+		}
 		if dryRun {
 			fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", message)
-			return fmt.Errorf("no staged changes")
+			return exitWithCode(cmd, ExitNothingToDo, fmt.Errorf("no staged changes"))
 		} else {
 			fmt.Print(message + "\n")
-			return nil
+			return exitWithCode(cmd, ExitNothingToDo, nil)
 		}
 	}
 
-	aiClient, err := ai.NewVertexAIClient(ctx, cfg)
+	redactor, err := redact.New(cfg.RedactRules)
+	if err != nil {
+		return err
+	}
+	summary := git.ParseDiffSummary(diff)
+	var changedFiles []string
+	for _, file := range summary.Files {
+		changedFiles = append(changedFiles, file.Name)
+	}
+	diff, report := diffreport.Filter(diff, redactor, diffreport.Options{})
+	printDiffReport(cmd, report)
+
+	_, commitTemplate, err := git.GetCommitTemplate()
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", warningStyle.Render(i18n.T("commit.template_ignored", err)))
+		commitTemplate = ""
+	}
+
+	scope := commitScope(cfg, diff)
+	commitInput := ai.CommitInput{
+		Diff:            diff,
+		Language:        cfg.CommitLanguage,
+		Template:        commitTemplate,
+		SubjectLanguage: cfg.CommitSubjectLanguage,
+		BodyLanguage:    cfg.CommitBodyLanguage,
+		Scope:           scope,
+		Instructions:    loadInstructions(cmd),
+		FileOrder:       cfg.PromptFileOrder,
+	}
+	if cfg.CommitPerPathStyle && merge == nil {
+		commitInput.PathHistory = pathHistory(ctx, summary, redactor)
+	}
+	if merge != nil {
+		scope = ""
+		commitInput.Scope = ""
+		commitInput.Merge = &ai.MergeInput{
+			Message:    merge.Message,
+			Commits:    redactor.Apply(merge.Commits),
+			Conflicts:  merge.Conflicts,
+			Resolution: redactor.Apply(merge.Resolution),
+		}
+	}
+
+	if showPrompt {
+		prompt := ai.CommitPrompt(commitInput)
+		fmt.Fprintln(cmd.OutOrStdout(), prompt.String())
+		printPromptBreakdown(ctx, cmd, cfg, prompt)
+		return nil
+	}
+
+	// Fail before the model call on what git commit would refuse at the end.
+	if !dryRun && !commitJJ {
+		if err := runPreflight(cmd, commitPreflight()); err != nil {
+			return err
+		}
+	}
+
+	repoRoot, err := git.GetRepoRoot()
+	if err != nil {
+		return fmt.Errorf("failed to get repository root: %w", err)
+	}
+
+	if retryLast {
+		savedMessage, err := loadSavedCommitMessage(cmd, repoRoot, diff)
+		if err != nil {
+			return err
+		}
+		if savedMessage != "" {
+			return commitSavedMessage(cmd, cfg, repoRoot, diff, savedMessage)
+		}
+	}
+
+	// Changes covered by a trivial rule get the rule's message without an
+	// AI call; it can still be edited or declined.
+	if rule, ok := trivialRule(cfg, changedFiles); ok && merge == nil {
+		note := i18n.T("commit.rule_note", rule.Name)
+		message := normalizeCommitMessage(cmd, cfg, scope, rule.Message, false)
+		return commitPreparedMessage(cmd, cfg, repoRoot, diff, message, "Commit message "+note, note)
+	}
+
+	if err := checkSecrets(cmd, cfg, diff, allowSecrets, yesFlag); err != nil {
+		return err
+	}
+	if verbose {
+		printPromptBreakdown(ctx, cmd, cfg, ai.CommitPrompt(commitInput))
+	}
+
+	generator, err := gelf.NewFromConfig(ctx, cfg, cfg.FlashModel, cfg.CommitLanguage)
 	if err != nil {
 		return fmt.Errorf("failed to create AI client: %w", err)
 	}
+	defer generator.Close()
+	generator = generator.WithInstructions(commitInput.Instructions)
+
+	if dryRun || yesFlag {
+		generator = generator.WithReporter(ui.NewReporter(cmd.ErrOrStderr()))
+	}
 
 	if dryRun {
 		if !quiet {
@@ -108,41 +305,334 @@ func runCommit(cmd *cobra.Command, args []string) error {
 			}
 		}
 
-		message, err := aiClient.GenerateCommitMessage(ctx, diff, cfg.CommitLanguage)
+		result, err := generator.CommitMessage(ctx, commitRequest(commitInput))
 		if err != nil {
 			return fmt.Errorf("failed to generate commit message: %w", err)
 		}
 
+		message, err := git.AddTrailers(normalizeCommitMessage(cmd, cfg, scope, result.Message, merge != nil), resolveTrailers(cfg))
+		if err != nil {
+			return err
+		}
 		fmt.Print(message)
 		return nil
 	}
 
 	// Handle --yes flag: automatically approve and commit
 	if yesFlag {
-		message, err := aiClient.GenerateCommitMessage(ctx, diff, cfg.CommitLanguage)
+		result, err := generator.CommitMessage(ctx, commitRequest(commitInput))
 		if err != nil {
 			return fmt.Errorf("failed to generate commit message: %w", err)
 		}
+		message := normalizeCommitMessage(cmd, cfg, scope, result.Message, merge != nil)
 
 		// Display the generated commit message
-		fmt.Printf("Generated commit message:\n%s\n\n", message)
+		fmt.Printf("%s\n%s\n\n", i18n.T("commit.generated_plain"), message)
+
+		return commitMessage(cmd, cfg, repoRoot, diff, message)
+	}
 
-		// Commit the changes
-		if err := git.CommitChanges(message); err != nil {
-			return fmt.Errorf("failed to commit changes: %w", err)
+	tui := ui.NewTUI(generator, commitRequest(commitInput), commitOptions(cfg))
+	tui.SetNormalizer(commitNormalizer(cfg, scope, merge != nil))
+	if commitInput.Merge == nil {
+		tui.SetFallback(conventional.Fallback(diff))
+	}
+	return runCommitTUI(cmd, cfg, tui, repoRoot, diff)
+}
+
+// commitRequest returns the request that generates the message input
+// prompts for. The instructions are the generator's.
+func commitRequest(input ai.CommitInput) gelf.CommitRequest {
+	request := gelf.CommitRequest{
+		Diff:            input.Diff,
+		Language:        input.Language,
+		Template:        input.Template,
+		Scope:           input.Scope,
+		SubjectLanguage: input.SubjectLanguage,
+		BodyLanguage:    input.BodyLanguage,
+	}
+	if input.Merge != nil {
+		request.Merge = &gelf.MergeRequest{
+			Message:    input.Merge.Message,
+			Commits:    input.Merge.Commits,
+			Conflicts:  input.Merge.Conflicts,
+			Resolution: input.Merge.Resolution,
 		}
+	}
+	for _, history := range input.PathHistory {
+		request.History = append(request.History, gelf.FileHistory{Path: history.Path, Subjects: history.Subjects})
+	}
+	return request
+}
 
-		fmt.Println("✅ Successfully committed changes!")
-		return nil
+// commitOptions returns how the flags and cfg make gelf commit commit.
+func commitOptions(cfg *config.Config) git.CommitOptions {
+	return git.CommitOptions{Paths: commitOnly, Trailers: resolveTrailers(cfg), Jujutsu: commitJJ}
+}
+
+// resolveTrailers returns the commit.trailers for the current branch
+// followed by the --trailer flags.
+func resolveTrailers(cfg *config.Config) []string {
+	branch, _ := git.GetCurrentBranch()
+	if branch == "HEAD" {
+		branch = ""
+	}
+	templates := append(append([]string{}, cfg.CommitTrailers...), commitTrailers...)
+	return commitmsg.ExpandTrailers(templates, branch)
+}
+
+// printDiffReport lists on stderr, with --verbose, what the filtering
+// pipeline did with each changed file.
+func printDiffReport(cmd *cobra.Command, report diffreport.Report) {
+	if verbose && len(report) > 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n\n", report)
+	}
+}
+
+// prDiffOptions returns the filtering options for the diffs pr create and
+// review send, which collapse minified and encoded hunks unless
+// diff.omit_unreadable is off. Commit diffs are sent whole.
+func prDiffOptions(cfg *config.Config) diffreport.Options {
+	return diffreport.Options{
+		OmitUnreadable: cfg.DiffOmitUnreadable,
+		Thresholds: diffreport.Thresholds{
+			AverageLineLength: cfg.DiffMaxLineLength,
+			LineKB:            cfg.DiffMaxLineKB,
+		},
+	}
+}
+
+// commitScope returns the scope monorepo.scopes assigns to the files changed
+// in diff, or "" when no scopes are configured or none match.
+func commitScope(cfg *config.Config, diff string) string {
+	if len(cfg.MonorepoScopes) == 0 {
+		return ""
+	}
+	var paths []string
+	for _, file := range git.ParseDiffSummary(diff).Files {
+		paths = append(paths, file.Name)
+	}
+	return commitmsg.ResolveScope(paths, cfg.MonorepoScopes, cfg.FallbackScope)
+}
+
+// commitNormalizer applies the commit.case and commit.allow_emoji settings
+// and the monorepo scope, and checks the languages of the subject and body.
+// merge leaves out the subject, which git writes.
+func commitNormalizer(cfg *config.Config, scope string, merge bool) func(string) (string, []string) {
+	opts := commitmsg.Options{
+		Case:            cfg.CommitCase,
+		AllowEmoji:      cfg.CommitEmoji,
+		Scope:           scope,
+		Wrap:            cfg.CommitWrap,
+		SubjectLanguage: cfg.CommitSubjectLanguage,
+		BodyLanguage:    cfg.CommitBodyLanguage,
+	}
+	if merge {
+		// The subject of a merge is git's own, in English.
+		opts.SubjectLanguage = ""
+	}
+	return func(message string) (string, []string) {
+		return commitmsg.Normalize(message, opts)
+	}
+}
+
+// normalizeCommitMessage normalizes message, printing warnings on stderr.
+func normalizeCommitMessage(cmd *cobra.Command, cfg *config.Config, scope, message string, merge bool) string {
+	message, warnings := commitNormalizer(cfg, scope, merge)(message)
+	if len(warnings) > 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings(warnings))
+	}
+	return message
+}
+
+// commitMessage commits message, saving it for --retry-last if git fails.
+func commitMessage(cmd *cobra.Command, cfg *config.Config, repoRoot, diff, message string) error {
+	release, err := acquireRepoLock(cmd, commitWait)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := git.CommitChanges(message, commitOptions(cfg)); err != nil {
+		saveFailedCommitMessage(cmd, repoRoot, diff, message)
+		return fmt.Errorf("failed to commit changes: %w", err)
+	}
+	_ = state.ClearCommitMessage(repoRoot)
+
+	fmt.Println(i18n.T("commit.committed"))
+	printStillStaged(cmd)
+	return nil
+}
+
+// printStillStaged lists the staged changes left out by --only.
+func printStillStaged(cmd *cobra.Command) {
+	if len(commitOnly) == 0 {
+		return
+	}
+	files, err := git.GetStagedFiles()
+	if err != nil || len(files) == 0 {
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "\n%s\n", i18n.T("commit.still_staged"))
+	for _, file := range files {
+		fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", file)
 	}
+}
+
+// commitTUI is the part of the commit TUI runCommitTUI depends on.
+type commitTUI interface {
+	Run() error
+	Err() error
+	Declined() bool
+	FailedMessage() string
+	SetCommitLock(lock func() (func(), error))
+}
 
+func runCommitTUI(cmd *cobra.Command, cfg *config.Config, tui commitTUI, repoRoot, diff string) error {
 	if !cfg.UseColor() {
 		ui.DisableColor()
 	}
-	tui := ui.NewTUI(aiClient, diff, cfg.CommitLanguage)
+	// The TUI commits once the message is approved. The lock is taken only
+	// around that commit, not while the message is reviewed.
+	tui.SetCommitLock(func() (func(), error) {
+		return acquireRepoLock(cmd, commitWait)
+	})
+
 	if err := tui.Run(); err != nil {
 		return fmt.Errorf("TUI error: %w", err)
 	}
+	if tui.Err() != nil {
+		if message := tui.FailedMessage(); message != "" {
+			saveFailedCommitMessage(cmd, repoRoot, diff, message)
+		}
+		// The TUI has already displayed the error.
+		return exitWithCode(cmd, ExitError, nil)
+	}
+	if tui.Declined() {
+		return exitWithCode(cmd, ExitDeclined, nil)
+	}
 
+	_ = state.ClearCommitMessage(repoRoot)
+	printStillStaged(cmd)
 	return nil
 }
+
+// commitSavedMessage goes straight to the confirm/commit step with a message
+// saved by a previous failed commit.
+func commitSavedMessage(cmd *cobra.Command, cfg *config.Config, repoRoot, diff, message string) error {
+	return commitPreparedMessage(cmd, cfg, repoRoot, diff, message, "Saved commit message", "")
+}
+
+// commitPreparedMessage goes straight to the confirm/commit step with a
+// message that was not generated now. note is shown next to the TUI header.
+func commitPreparedMessage(cmd *cobra.Command, cfg *config.Config, repoRoot, diff, message, label, note string) error {
+	if dryRun {
+		message, err := git.AddTrailers(message, resolveTrailers(cfg))
+		if err != nil {
+			return err
+		}
+		fmt.Print(message)
+		return nil
+	}
+
+	if yesFlag {
+		fmt.Printf("%s:\n%s\n\n", label, message)
+		return commitMessage(cmd, cfg, repoRoot, diff, message)
+	}
+
+	tui := ui.NewTUI(nil, gelf.CommitRequest{Diff: diff, Language: cfg.CommitLanguage}, commitOptions(cfg))
+	tui.SetNormalizer(commitNormalizer(cfg, commitScope(cfg, diff), false))
+	tui.SetNote(note)
+	tui.UseMessage(message)
+	return runCommitTUI(cmd, cfg, tui, repoRoot, diff)
+}
+
+// trivialRule returns the first commit.trivial_rules entry covering every
+// changed file or, with --detect-formatting, the built-in rule when the
+// staged changes only touch whitespace.
+func trivialRule(cfg *config.Config, files []string) (config.TrivialRule, bool) {
+	if rule, ok := commitmsg.MatchTrivialRule(cfg.TrivialRules, files); ok {
+		return rule, true
+	}
+	if detectFormatting && !commitJJ && git.IsWhitespaceOnlyStaged(commitOnly...) {
+		return commitmsg.FormattingRule, true
+	}
+	return config.TrivialRule{}, false
+}
+
+// Limits of commit.per_path_style, so that the prompt stays small and a
+// file with a long history behind many renames cannot stall the commit.
+const (
+	pathHistoryFiles    = 3
+	pathHistorySubjects = 5
+	pathHistoryTotal    = 12
+	pathHistoryTimeout  = 2 * time.Second
+)
+
+// pathHistory returns the recent commit subjects of the most changed files
+// in summary for commit.per_path_style. Files without history, such as new
+// ones, are skipped, and a subject already listed for another file is not
+// repeated. Lookups that fail or run out of time are left out.
+func pathHistory(ctx context.Context, summary git.DiffSummary, redactor *redact.Redactor) []ai.PathHistory {
+	files := slices.Clone(summary.Files)
+	slices.SortStableFunc(files, func(a, b git.FileDiff) int {
+		return (b.AddedLines + b.DeletedLines) - (a.AddedLines + a.DeletedLines)
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, pathHistoryTimeout)
+	defer cancel()
+	var histories []ai.PathHistory
+	seen := map[string]bool{}
+	total := 0
+	for _, file := range files[:min(len(files), pathHistoryFiles)] {
+		subjects, err := git.GetPathSubjects(ctx, file.Name, pathHistorySubjects)
+		if err != nil {
+			continue
+		}
+		history := ai.PathHistory{Path: file.Name}
+		for _, subject := range subjects {
+			if total == pathHistoryTotal {
+				break
+			}
+			if !seen[subject] {
+				seen[subject] = true
+				history.Subjects = append(history.Subjects, redactor.Apply(subject))
+				total++
+			}
+		}
+		if len(history.Subjects) > 0 {
+			histories = append(histories, history)
+		}
+	}
+	return histories
+}
+
+// loadSavedCommitMessage returns the message saved by the last failed commit
+// if it was generated from the currently staged diff. A stale message is
+// discarded and an empty string is returned so a new one is generated.
+func loadSavedCommitMessage(cmd *cobra.Command, repoRoot, diff string) (string, error) {
+	saved, err := state.LoadCommitMessage(repoRoot)
+	if err != nil {
+		return "", fmt.Errorf("failed to load saved commit message: %w", err)
+	}
+	if saved == nil {
+		return "", fmt.Errorf("no saved commit message for this repository")
+	}
+
+	if saved.DiffHash != state.Hash(diff) {
+		_ = state.ClearCommitMessage(repoRoot)
+		fmt.Fprintln(cmd.ErrOrStderr(), warningStyle.Render("⚠ Staged changes differ from the last failed commit; discarding the saved message and generating a new one."))
+		return "", nil
+	}
+
+	return saved.Message, nil
+}
+
+func saveFailedCommitMessage(cmd *cobra.Command, repoRoot, diff, message string) {
+	path, err := state.SaveCommitMessage(repoRoot, diff, message)
+	if err != nil {
+		fmt.Fprintln(cmd.ErrOrStderr(), warningStyle.Render(i18n.T("commit.save_failed", err)))
+		return
+	}
+	fmt.Fprintln(cmd.ErrOrStderr(), i18n.T("commit.saved", path))
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommitShowPromptIsRedacted(t *testing.T) {
+	newTestRepo(t)
+	dir := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "gelf")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config := `redact:
+  - pattern: '[a-z0-9]+\.corp\.example\.com'
+    replace: <HOST>
+  - pattern: 'ACME-\d+'
+    replace: <CUSTOMER>
+`
+	if err := os.WriteFile(filepath.Join(dir, "gelf.yml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("deploy.yml", []byte("host: db01.corp.example.com\ncustomer: ACME-48213\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runTestGit(t, "add", "deploy.yml")
+
+	var stdout strings.Builder
+	rootCmd.SetArgs([]string{"commit", "--show-prompt"})
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetErr(&strings.Builder{})
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		flag := commitCmd.Flags().Lookup("show-prompt")
+		flag.Value.Set(flag.DefValue)
+		flag.Changed = false
+	})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	prompt := stdout.String()
+	for _, secret := range []string{"db01.corp.example.com", "ACME-48213"} {
+		if strings.Contains(prompt, secret) {
+			t.Errorf("prompt contains %q:\n%s", secret, prompt)
+		}
+	}
+	for _, replacement := range []string{"+host: <HOST>", "+customer: <CUSTOMER>"} {
+		if !strings.Contains(prompt, replacement) {
+			t.Errorf("prompt does not contain %q:\n%s", replacement, prompt)
+		}
+	}
+}
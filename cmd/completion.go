@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/github"
+	"github.com/EkeMinusYou/gelf/internal/state"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// completionTimeout is how long a completion may take before it
+	// returns the candidates it has so far. Completion runs on every Tab,
+	// so it only reads local files and refs.
+	completionTimeout = 200 * time.Millisecond
+	// reviewerCacheTTL is how old the cached collaborators may get before
+	// a completion refreshes them in the background.
+	reviewerCacheTTL = 24 * time.Hour
+	// reviewerRefreshInterval is how long a started refresh is given before
+	// another one is started, e.g. when the first failed.
+	reviewerRefreshInterval = time.Minute
+	// reviewerRefreshTimeout bounds the background refresh.
+	reviewerRefreshTimeout = 30 * time.Second
+)
+
+// refreshReviewersCmd is started by --reviewer completion, detached, to
+// update the cached collaborators without making the completion wait.
+var refreshReviewersCmd = &cobra.Command{
+	Use:    "__refresh-reviewers",
+	Short:  "Refresh the collaborators --reviewer completes from",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE:   runRefreshReviewers,
+}
+
+func init() {
+	rootCmd.AddCommand(refreshReviewersCmd)
+}
+
+// completeWithin turns complete into a flag completion function that
+// returns after completionTimeout at the latest, with whatever complete
+// added by then. complete should stop once ctx is done, but the completion
+// does not wait for it. Candidates not starting with the typed text are
+// dropped, and files are never offered.
+func completeWithin(complete func(ctx context.Context, add func(candidates ...string))) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+		defer cancel()
+
+		var mu sync.Mutex
+		var completions []cobra.Completion
+		seen := map[string]bool{}
+		add := func(candidates ...string) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, candidate := range candidates {
+				if candidate != "" && !seen[candidate] && strings.HasPrefix(candidate, toComplete) {
+					seen[candidate] = true
+					completions = append(completions, candidate)
+				}
+			}
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			complete(ctx, add)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]cobra.Completion(nil), completions...), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeRefs offers the local branches, remote-tracking branches and tags.
+func completeRefs(ctx context.Context, add func(candidates ...string)) {
+	refs, _ := git.ListRefs(ctx)
+	add(refs...)
+}
+
+// completeModels offers the model aliases and the models they resolve to.
+func completeModels(ctx context.Context, add func(candidates ...string)) {
+	add("flash", "pro")
+	if cfg, err := config.Load(); err == nil {
+		add(cfg.ResolveModel("flash"), cfg.ResolveModel("pro"))
+	}
+}
+
+// completeReviewers offers the cached collaborators of the repository and
+// the reviewers learned from recent pull requests. It never runs gh: when
+// the cache is missing or older than reviewerCacheTTL, it starts gelf
+// __refresh-reviewers in the background, so a later Tab has them.
+func completeReviewers(ctx context.Context, add func(candidates ...string)) {
+	repoRoot, err := git.GetRepoRoot()
+	if err != nil {
+		return
+	}
+	if learned, err := state.LoadPRDefaults(repoRoot, learnedMaxAge); err == nil && learned != nil {
+		add(learned.Reviewers...)
+	}
+
+	candidates, err := state.LoadReviewerCandidates(repoRoot)
+	if err != nil {
+		return
+	}
+	if candidates == nil {
+		candidates = &state.ReviewerCandidates{}
+	}
+	add(candidates.Logins...)
+
+	if time.Since(candidates.At) < reviewerCacheTTL || time.Since(candidates.Attempted) < reviewerRefreshInterval {
+		return
+	}
+	candidates.Attempted = time.Now()
+	if err := state.SaveReviewerCandidates(repoRoot, *candidates); err != nil {
+		return
+	}
+	_ = startReviewerRefresh()
+}
+
+// startReviewerRefresh starts gelf __refresh-reviewers without waiting for
+// it. It gets no standard streams, so the shell reading the completion
+// does not wait for it either.
+func startReviewerRefresh() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	refresh := exec.Command(executable, refreshReviewersCmd.Name())
+	if err := refresh.Start(); err != nil {
+		return err
+	}
+	return refresh.Process.Release()
+}
+
+func runRefreshReviewers(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(cmd.Context(), reviewerRefreshTimeout)
+	defer cancel()
+
+	repoRoot, err := git.GetRepoRoot()
+	if err != nil {
+		return fmt.Errorf("failed to get repository root: %w", err)
+	}
+	// Reviewers are requested on the repository the pull request goes to,
+	// which is the parent of a fork.
+	repo, err := github.RepoInfoFromGH(ctx)
+	if err != nil {
+		return err
+	}
+	logins, err := github.RepoCollaborators(ctx, repo.Owner+"/"+repo.Name)
+	if err != nil {
+		return err
+	}
+
+	candidates := state.ReviewerCandidates{Logins: logins, At: time.Now()}
+	if cached, err := state.LoadReviewerCandidates(repoRoot); err == nil && cached != nil {
+		candidates.Attempted = cached.Attempted
+	}
+	return state.SaveReviewerCandidates(repoRoot, candidates)
+}
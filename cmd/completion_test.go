@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func complete(t *testing.T, fn func(ctx context.Context, add func(candidates ...string)), toComplete string) []cobra.Completion {
+	t.Helper()
+	completions, directive := completeWithin(fn)(prCreateCmd, nil, toComplete)
+	if directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("directive = %v, want ShellCompDirectiveNoFileComp", directive)
+	}
+	return completions
+}
+
+func TestCompleteWithinTimesOut(t *testing.T) {
+	start := time.Now()
+	got := complete(t, func(ctx context.Context, add func(candidates ...string)) {
+		add("main", "feature", "main")
+		<-ctx.Done()
+		time.Sleep(time.Second)
+		add("late")
+	}, "ma")
+	if elapsed := time.Since(start); elapsed > 5*completionTimeout {
+		t.Errorf("completion took %s, want about %s", elapsed, completionTimeout)
+	}
+	if want := []cobra.Completion{"main"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("completions = %q, want %q", got, want)
+	}
+}
+
+func TestCompleteRefs(t *testing.T) {
+	newTestRepo(t)
+	runTestGit(t, "branch", "feature/login")
+	runTestGit(t, "tag", "v1.0.0")
+
+	if got, want := complete(t, completeRefs, ""), []cobra.Completion{"feature/login", "main", "v1.0.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("completions = %q, want %q", got, want)
+	}
+	if got, want := complete(t, completeRefs, "fe"), []cobra.Completion{"feature/login"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("completions of fe = %q, want %q", got, want)
+	}
+}
+
+func TestCompleteModels(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	got := complete(t, completeModels, "")
+	if len(got) < 2 || got[0] != "flash" || got[1] != "pro" {
+		t.Errorf("completions = %q, want the flash and pro aliases first", got)
+	}
+}
+
+func TestCompleteReviewersColdCache(t *testing.T) {
+	repoRoot := newTestRepo(t)
+	ghLog := stubGH(t, "exit 1")
+	refreshLog := filepath.Join(t.TempDir(), "refresh.log")
+	t.Setenv(refreshLogEnv, refreshLog)
+
+	if got := complete(t, completeReviewers, ""); len(got) != 0 {
+		t.Errorf("completions = %q, want none on a cold cache", got)
+	}
+	// The refresh is started in the background instead.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(refreshLog); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the background refresh was not started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := os.Stat(ghLog); !os.IsNotExist(err) {
+		data, _ := os.ReadFile(ghLog)
+		t.Errorf("gh was run by the completion: %s", data)
+	}
+
+	// A second Tab right after does not start another refresh.
+	candidates, err := state.LoadReviewerCandidates(repoRoot)
+	if err != nil || candidates == nil || candidates.Attempted.IsZero() {
+		t.Fatalf("LoadReviewerCandidates() = %+v, %v, want the attempt recorded", candidates, err)
+	}
+	attempted := candidates.Attempted
+	complete(t, completeReviewers, "")
+	candidates, _ = state.LoadReviewerCandidates(repoRoot)
+	if !candidates.Attempted.Equal(attempted) {
+		t.Errorf("a second refresh was started at %s", candidates.Attempted)
+	}
+}
+
+func TestCompleteReviewersWarmCache(t *testing.T) {
+	repoRoot := newTestRepo(t)
+	ghLog := stubGH(t, "exit 1")
+	t.Setenv(refreshLogEnv, filepath.Join(t.TempDir(), "refresh.log"))
+
+	err := state.SaveReviewerCandidates(repoRoot, state.ReviewerCandidates{Logins: []string{"alice", "bob", "alex"}, At: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := complete(t, completeReviewers, "al"), []cobra.Completion{"alice", "alex"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("completions = %q, want %q", got, want)
+	}
+	candidates, _ := state.LoadReviewerCandidates(repoRoot)
+	if !candidates.Attempted.IsZero() {
+		t.Error("a refresh was started for a fresh cache")
+	}
+	if _, err := os.Stat(ghLog); !os.IsNotExist(err) {
+		t.Error("gh was run by the completion")
+	}
+}
@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/EkeMinusYou/gelf/internal/ai"
 	"github.com/EkeMinusYou/gelf/internal/config"
 	"github.com/spf13/cobra"
 )
@@ -21,9 +24,24 @@ var configListCmd = &cobra.Command{
 	RunE:  runConfigList,
 }
 
+var configDocsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Print a reference of every configuration key",
+	Long: `Prints every key gelf.yml accepts, with its type, default, overriding
+environment variables, accepted values and description, as a markdown
+table. --json prints the same reference for tools.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigDocs,
+}
+
+var configDocsJSON bool
+
 func init() {
+	configDocsCmd.Flags().BoolVar(&configDocsJSON, "json", false, "Print the reference as JSON")
+
 	rootCmd.AddCommand(configCmd)
 	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configDocsCmd)
 }
 
 func runConfigList(cmd *cobra.Command, args []string) error {
@@ -34,14 +52,27 @@ func runConfigList(cmd *cobra.Command, args []string) error {
 
 	fmt.Println("Current Configuration:")
 	fmt.Println("======================")
+	provider := cfg.Provider
+	if provider == "" {
+		provider = ai.DefaultProvider
+	}
+	fmt.Printf("Provider:          %s\n", provider)
 	fmt.Printf("Project ID:        %s\n", cfg.ProjectID)
 	fmt.Printf("Location:          %s\n", cfg.Location)
+	if provider == ai.BedrockProvider {
+		fmt.Printf("Bedrock Region:    %s\n", cfg.BedrockRegion)
+		fmt.Printf("Bedrock Profile:   %s\n", cfg.BedrockProfile)
+	}
+	if provider == ai.OpenAICompatibleProvider {
+		fmt.Printf("Base URL:          %s\n", cfg.OpenAICompatibleBaseURL)
+	}
 	fmt.Printf("Flash Model:       %s\n", cfg.FlashModel)
 	fmt.Printf("Pro Model:         %s\n", cfg.ProModel)
 	fmt.Printf("Commit Model:      %s\n", cfg.CommitModel)
 	fmt.Printf("Commit Language:   %s\n", cfg.CommitLanguage)
 	fmt.Printf("PR Model:          %s\n", cfg.PRModel)
 	fmt.Printf("PR Language:       %s\n", cfg.PRLanguage)
+	fmt.Printf("PR Template Merge: %s\n", cfg.PRTemplateMerge)
 
 	fmt.Println("\nEnvironment Variables:")
 	fmt.Println("======================")
@@ -50,6 +81,10 @@ func runConfigList(cmd *cobra.Command, args []string) error {
 	printEnvVar("VERTEXAI_LOCATION")
 	printEnvVar("GELF_CREDENTIALS")
 	printEnvVar("GOOGLE_APPLICATION_CREDENTIALS")
+	printEnvVar("OPENAI_BASE_URL")
+	printEnvVar("OPENAI_COMPATIBLE_BASE_URL")
+	printEnvVar("AWS_REGION")
+	printEnvVar("AWS_PROFILE")
 
 	return nil
 }
@@ -62,3 +97,68 @@ func printEnvVar(name string) {
 		fmt.Printf("%-30s (not set)\n", name+":")
 	}
 }
+
+func runConfigDocs(cmd *cobra.Command, args []string) error {
+	if configDocsJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(config.Schema)
+	}
+	fmt.Fprint(cmd.OutOrStdout(), renderConfigDocs(config.Schema))
+	return nil
+}
+
+// renderConfigDocs renders keys as a markdown table. The Since column is
+// only shown once a key has a version.
+func renderConfigDocs(keys []config.Key) string {
+	since := false
+	for _, key := range keys {
+		since = since || key.Since != ""
+	}
+
+	headers := []string{"Key", "Type", "Default", "Environment", "Description"}
+	if since {
+		headers = append(headers, "Since")
+	}
+	var b strings.Builder
+	b.WriteString("| " + strings.Join(headers, " | ") + " |\n")
+	b.WriteString(strings.Repeat("|---", len(headers)) + "|\n")
+	for _, key := range keys {
+		description := key.Description
+		if len(key.Values) > 0 {
+			description += " (one of " + markdownCode(key.Values) + ")"
+		}
+		row := []string{
+			"`" + key.Name + "`",
+			key.TypeName(),
+			markdownCode(nonEmpty(key.Default)),
+			markdownCode(key.Env),
+			description,
+		}
+		if since {
+			row = append(row, key.Since)
+		}
+		for i, cell := range row {
+			row[i] = strings.ReplaceAll(cell, "|", `\|`)
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	}
+	return b.String()
+}
+
+// markdownCode formats values as code spans separated by commas.
+func markdownCode(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = "`" + value + "`"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// nonEmpty returns value as a list of one, or no values when it is empty.
+func nonEmpty(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// applyFlagDefaults sets flag values from the config's defaults section for
+// flags that were not given on the command line, so explicit flags still win.
+func applyFlagDefaults(cmd *cobra.Command, section string, defaults map[string]string) error {
+	keys := make([]string, 0, len(defaults))
+	for key := range defaults {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		flag := cmd.Flags().Lookup(key)
+		if flag == nil || flag.Name == "help" {
+			message := fmt.Sprintf("⚠ Unknown key %q in defaults.%s (valid keys: %s)", key, section, strings.Join(flagNames(cmd), ", "))
+			fmt.Fprintln(cmd.ErrOrStderr(), warningStyle.Render(message))
+			continue
+		}
+		if flag.Changed {
+			continue
+		}
+		if err := flag.Value.Set(defaults[key]); err != nil {
+			return fmt.Errorf("invalid value %q for defaults.%s.%s: %w", defaults[key], section, key, err)
+		}
+	}
+
+	return nil
+}
+
+func flagNames(cmd *cobra.Command) []string {
+	var names []string
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Name != "help" {
+			names = append(names, flag.Name)
+		}
+	})
+	sort.Strings(names)
+	return names
+}
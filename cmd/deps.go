@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/deps"
+	"github.com/EkeMinusYou/gelf/internal/github"
+)
+
+const (
+	// releaseNotesTimeout bounds fetching release notes for all bumps.
+	releaseNotesTimeout = 10 * time.Second
+	// maxReleaseNotes is the number of characters kept per release.
+	maxReleaseNotes = 1500
+)
+
+// describeDependencyBumps lists the bumps for the PR prompt together with the
+// release notes published between the old and new versions. Release notes
+// are best effort: modules not hosted on GitHub or failed requests are noted
+// and skipped.
+func describeDependencyBumps(ctx context.Context, token string, bumps []deps.Bump) string {
+	ctx, cancel := context.WithTimeout(ctx, releaseNotesTimeout)
+	defer cancel()
+
+	var builder strings.Builder
+	for _, bump := range bumps {
+		fmt.Fprintf(&builder, "- %s (%s): %s -> %s", bump.Module, bump.Ecosystem, bump.From, bump.To)
+		if bump.Breaking() {
+			builder.WriteString(" [major version change]")
+		}
+		builder.WriteString("\n")
+
+		notes := releaseNotes(ctx, token, bump)
+		if notes == "" {
+			builder.WriteString("  RELEASE NOTES: not available\n")
+			continue
+		}
+		builder.WriteString("  RELEASE NOTES:\n")
+		for _, line := range strings.Split(notes, "\n") {
+			builder.WriteString("    " + line + "\n")
+		}
+	}
+	return strings.TrimRight(builder.String(), "\n")
+}
+
+// releaseNotes returns the notes of the GitHub releases after bump.From up
+// to and including bump.To, oldest first.
+func releaseNotes(ctx context.Context, token string, bump deps.Bump) string {
+	owner, repo, ok := bump.GitHubRepo()
+	if !ok {
+		return ""
+	}
+	releases, err := github.ListReleases(ctx, token, owner, repo, 30)
+	if err != nil {
+		return ""
+	}
+
+	var notes []string
+	for i := len(releases) - 1; i >= 0; i-- {
+		release := releases[i]
+		// Repositories with several modules tag each one under its own
+		// directory.
+		if release.TagName[:strings.LastIndex(release.TagName, "/")+1] != bump.TagPrefix() {
+			continue
+		}
+		if deps.CompareVersions(release.TagName, bump.From) <= 0 || deps.CompareVersions(release.TagName, bump.To) > 0 {
+			continue
+		}
+		body := strings.TrimSpace(release.Body)
+		if len([]rune(body)) > maxReleaseNotes {
+			body = string([]rune(body)[:maxReleaseNotes]) + "…"
+		}
+		notes = append(notes, fmt.Sprintf("%s:\n%s", release.TagName, body))
+	}
+	return strings.Join(notes, "\n\n")
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/EkeMinusYou/gelf/internal/git"
+)
+
+// diffSource is where a command takes the changes it works on from: the
+// staged changes, or the commits on HEAD since a base ref. pr create and
+// review both select their changes through it, so gelf review --pr-range
+// sees exactly what gelf pr create would send.
+type diffSource struct {
+	// Base is the ref a range starts from, e.g. origin/main; it is empty
+	// for the staged changes.
+	Base string
+}
+
+// prSource returns the range a pull request targeting baseBranch would
+// contain.
+func prSource(baseBranch string) diffSource {
+	return diffSource{Base: "origin/" + baseBranch}
+}
+
+// detectPRSource returns the range a pull request from the current branch
+// would contain, with the base branch found the way pr create finds it.
+func detectPRSource() (diffSource, string, error) {
+	baseBranch, err := git.GetDefaultBaseBranch()
+	if err != nil {
+		return diffSource{}, "", fmt.Errorf("failed to determine base branch: %w", err)
+	}
+	return prSource(baseBranch), baseBranch, nil
+}
+
+// String names the changes, e.g. "origin/main...HEAD".
+func (s diffSource) String() string {
+	if s.Base == "" {
+		return "staged changes"
+	}
+	return s.Base + "...HEAD"
+}
+
+// Diff returns the changes. A range is diffed three-dot: what HEAD added
+// since it forked from Base, not what Base gained since.
+func (s diffSource) Diff() (string, error) {
+	if s.Base == "" {
+		diff, err := git.GetStagedDiff()
+		if err != nil {
+			return "", fmt.Errorf("failed to get staged changes: %w", err)
+		}
+		return diff, nil
+	}
+	diff, err := git.GetCommittedDiff(s.Base, "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to get changes against %s: %w", s.Base, err)
+	}
+	return diff, nil
+}
+
+// CommitLog returns the commits of a range, oldest first, one
+// "<sha> <subject>" per line. The staged changes have none.
+func (s diffSource) CommitLog() (string, error) {
+	if s.Base == "" {
+		return "", nil
+	}
+	commitLog, err := git.GetCommitLog(s.Base, "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to get commit log: %w", err)
+	}
+	return commitLog, nil
+}
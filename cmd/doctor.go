@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/github"
+	"github.com/EkeMinusYou/gelf/internal/instructions"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the environment gelf runs in",
+	Long: `Check that the tools gelf calls are installed, what the installed gh supports, and which instruction files are loaded.
+
+With --check only the preflight checks that gelf commit and gelf pr create run
+before generating are run, and the exit code is 1 when one of them fails.`,
+	RunE: runDoctor,
+}
+
+var doctorCheck bool
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorCheck, "check", false, "Only run the commit and pull request preflight checks; exit with 1 when one fails")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
+
+	// The checks gelf commit and gelf pr create run before generating.
+	remote := ""
+	if _, err := git.GetGitDir(); err == nil {
+		remote = pushRemote()
+	}
+	checks := append(commitPreflight(), prPreflight(context.Background(), remote)...)
+	if doctorCheck {
+		fmt.Fprint(out, renderPreflight(checks))
+		for _, check := range checks {
+			if check.Problem != "" && !check.Warning {
+				return exitWithCode(cmd, ExitError, nil)
+			}
+		}
+		return nil
+	}
+
+	fmt.Fprintln(out, "Tools:")
+	fmt.Fprintln(out, "======")
+	for _, tool := range []string{"git", "gh"} {
+		if path, err := exec.LookPath(tool); err == nil {
+			fmt.Fprintf(out, "%-12s %s\n", tool+":", path)
+		} else {
+			fmt.Fprintf(out, "%-12s (not found)\n", tool+":")
+		}
+	}
+
+	fmt.Fprintln(out, "\nGitHub CLI:")
+	fmt.Fprintln(out, "===========")
+	if caps, err := github.DetectCapabilities(context.Background()); err != nil {
+		fmt.Fprintf(out, "(unavailable: %v)\n", err)
+	} else {
+		version := caps.Version
+		if version == "" {
+			version = "(unknown)"
+		}
+		fmt.Fprintf(out, "%-12s %s\n", "version:", version)
+		degraded := caps.Degraded()
+		if len(degraded) == 0 {
+			fmt.Fprintf(out, "%-12s %s\n", "degraded:", "(none)")
+		}
+		for _, note := range degraded {
+			fmt.Fprintf(out, "%-12s %s\n", "degraded:", note)
+		}
+	}
+
+	repoRoot, err := git.GetRepoRoot()
+	fmt.Fprintln(out, "\nRepository:")
+	fmt.Fprintln(out, "===========")
+	if err != nil {
+		fmt.Fprintln(out, "(not a git repository)")
+		repoRoot = ""
+	} else {
+		fmt.Fprintln(out, repoRoot)
+	}
+
+	fmt.Fprintln(out, "\nPreflight:")
+	fmt.Fprintln(out, "==========")
+	fmt.Fprint(out, renderPreflight(checks))
+
+	fmt.Fprintln(out, "\nInstructions:")
+	fmt.Fprintln(out, "=============")
+	files, err := instructions.Load(repoRoot)
+	if err != nil {
+		fmt.Fprintf(out, "(failed to load: %v)\n", err)
+		return nil
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(out, "(none)")
+	}
+	for _, file := range files {
+		note := ""
+		if file.Truncated {
+			note = fmt.Sprintf(" (truncated to %d bytes)", instructions.MaxFileSize)
+		}
+		fmt.Fprintf(out, "%-12s %s%s\n", file.Scope+":", file.Path, note)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes returned by gelf so that scripts can tell outcomes apart.
+const (
+	ExitOK          = 0 // created, updated or committed
+	ExitError       = 1 // any failure
+	ExitSkipped     = 3 // an existing pull request was left untouched
+	ExitDeclined    = 4 // the user answered "no" at a confirmation prompt
+	ExitNothingToDo = 5 // there were no changes to work with
+	ExitFindings    = 6 // review --fail-on found a finding of that severity or higher
+)
+
+// exitError carries a specific process exit code out of a command.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// exitWithCode makes the command exit with the given code. When err is nil the
+// exit is silent; otherwise err is reported like any other command error.
+func exitWithCode(cmd *cobra.Command, code int, err error) error {
+	cmd.SilenceUsage = true
+	if err == nil {
+		cmd.SilenceErrors = true
+	}
+	return &exitError{code: code, err: err}
+}
+
+// ExitCode maps an error returned by Execute to a process exit code.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return ExitError
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/EkeMinusYou/gelf/internal/ai"
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/explain"
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	"github.com/EkeMinusYou/gelf/internal/redact"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "Summarize a directory or package",
+	Long: `Gathers the tracked files under a path, the exported identifiers of its Go
+packages (or excerpts of other files) and the recent commits touching it, and
+asks the model for an overview. With --onboarding the overview is written for
+new contributors.`,
+	RunE: runExplain,
+}
+
+var (
+	explainPath       string
+	explainOnboarding bool
+	explainModel      string
+	explainLanguage   string
+	explainOutput     string
+	explainRender     bool
+	explainNoRender   bool
+	explainShowPrompt bool
+)
+
+func init() {
+	explainCmd.Flags().StringVar(&explainPath, "path", ".", "Directory or package to explain")
+	explainCmd.Flags().BoolVar(&explainOnboarding, "onboarding", false, "Write the overview for new contributors")
+	explainCmd.Flags().StringVar(&explainModel, "model", "", "Override default model for this generation")
+	explainCmd.RegisterFlagCompletionFunc("model", completeWithin(completeModels))
+	explainCmd.Flags().StringVar(&explainLanguage, "language", "", "Language for the overview (e.g., english, japanese)")
+	explainCmd.Flags().StringVar(&explainOutput, "output", "", "Write the overview as markdown to this file")
+	explainCmd.Flags().BoolVar(&explainRender, "render", true, "Render the overview as markdown")
+	explainCmd.Flags().BoolVar(&explainNoRender, "no-render", false, "Stream the raw markdown instead of rendering it")
+	explainCmd.Flags().BoolVar(&explainShowPrompt, "show-prompt", false, "Print the prompt that would be sent to the model and exit")
+
+	rootCmd.AddCommand(explainCmd)
+}
+
+func runExplain(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if err := applyFlagDefaults(cmd, "explain", cfg.Defaults["explain"]); err != nil {
+		return err
+	}
+
+	if explainNoRender {
+		explainRender = false
+	}
+
+	modelToUse := cfg.PRModel
+	if explainModel != "" {
+		modelToUse = explainModel
+	}
+	cfg.FlashModel = cfg.ResolveModel(modelToUse)
+
+	language := cfg.PRLanguage
+	if explainLanguage != "" {
+		language, err = flagLanguage(cmd, cfg, "language", explainLanguage)
+		if err != nil {
+			return err
+		}
+	}
+
+	gathered, err := explain.Gather(explainPath)
+	if err != nil {
+		return err
+	}
+
+	redactor, err := redact.New(cfg.RedactRules)
+	if err != nil {
+		return err
+	}
+
+	input := ai.ExplainInput{
+		Path:         explainPath,
+		Files:        gathered.Files,
+		Exports:      redactor.Apply(gathered.Exports),
+		Excerpts:     redactor.Apply(gathered.Excerpts),
+		CommitLog:    redactor.Apply(gathered.CommitLog),
+		Language:     language,
+		Onboarding:   explainOnboarding,
+		Instructions: loadInstructions(cmd),
+	}
+
+	if explainShowPrompt {
+		fmt.Fprintln(cmd.OutOrStdout(), ai.BuildExplainPrompt(input))
+		return nil
+	}
+
+	aiClient, err := ai.NewProvider(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create AI client: %w", err)
+	}
+
+	if explainOutput == "" && !explainRender {
+		out := cmd.OutOrStdout()
+		if _, err := aiClient.GenerateExplanation(ctx, input, func(chunk string) {
+			fmt.Fprint(out, chunk)
+		}); err != nil {
+			return err
+		}
+		fmt.Fprintln(out)
+		return nil
+	}
+
+	aiClient.SetReporter(ui.NewReporter(cmd.ErrOrStderr()))
+	overview, err := aiClient.GenerateExplanation(ctx, input, nil)
+	if err != nil {
+		return err
+	}
+
+	if explainOutput != "" {
+		if err := os.WriteFile(explainOutput, []byte(overview+"\n"), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", explainOutput, err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s\n", ui.RenderSuccessHeader(i18n.T("explain.wrote", explainOutput)))
+		return nil
+	}
+
+	rendered, err := ui.RenderMarkdown(overview, cfg.UseColor())
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Failed to render markdown: %v\n", err)
+		fmt.Fprintln(cmd.OutOrStdout(), overview)
+		return nil
+	}
+	fmt.Fprint(cmd.OutOrStdout(), rendered)
+	return nil
+}
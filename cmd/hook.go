@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/spf13/cobra"
+)
+
+// hookName is the git hook gelf integrates with.
+const hookName = "prepare-commit-msg"
+
+// hookShim fills in the commit message when git commit is run without a
+// message source (no -m, merge or amend) or with commit.template. With a
+// template only its comment lines are kept; gelf follows the template itself.
+const hookShim = `# Generated by gelf: fill in the commit message from staged changes.
+if { [ -z "$2" ] || [ "$2" = "template" ]; } && command -v gelf >/dev/null 2>&1; then
+  gelf_message=$(gelf commit --dry-run --quiet 2>/dev/null)
+  if [ -n "$gelf_message" ]; then
+    if [ "$2" = "template" ]; then
+      { printf '%s\n\n' "$gelf_message"; grep '^#' "$1" || true; } > "$1.gelf" && mv "$1.gelf" "$1"
+    else
+      { printf '%s\n' "$gelf_message"; cat "$1"; } > "$1.gelf" && mv "$1.gelf" "$1"
+    fi
+  fi
+fi`
+
+var hookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "Manage the gelf git hook",
+}
+
+var hookInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the prepare-commit-msg hook",
+	Long: `Installs a prepare-commit-msg hook that fills in the commit message with gelf.
+The hooks directory honors core.hooksPath, and existing hook scripts (husky,
+lefthook, hand-written) are kept: the gelf block is appended between
+"# gelf start" and "# gelf end" markers.`,
+	RunE: runHookInstall,
+}
+
+var hookUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the gelf block from the prepare-commit-msg hook",
+	RunE:  runHookUninstall,
+}
+
+var hookPrint bool
+
+func init() {
+	hookInstallCmd.Flags().BoolVar(&hookPrint, "print", false, "Print the hook snippet for manual integration instead of installing it")
+
+	hookCmd.AddCommand(hookInstallCmd)
+	hookCmd.AddCommand(hookUninstallCmd)
+	rootCmd.AddCommand(hookCmd)
+}
+
+func runHookInstall(cmd *cobra.Command, args []string) error {
+	if hookPrint {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s\n%s\n%s\n", git.HookBlockStart, hookShim, git.HookBlockEnd)
+		return nil
+	}
+
+	hooksDir, err := git.GetHooksDir()
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(hooksDir, hookName)
+	if err := git.InstallHookBlock(path, hookShim); err != nil {
+		if errors.Is(err, git.ErrHookIncompatible) {
+			return fmt.Errorf("%w\nrun 'gelf hook install --print' and add the snippet to the hook yourself", err)
+		}
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "✓ Installed gelf hook in %s\n", path)
+	return nil
+}
+
+func runHookUninstall(cmd *cobra.Command, args []string) error {
+	hooksDir, err := git.GetHooksDir()
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(hooksDir, hookName)
+	removed, err := git.RemoveHookBlock(path)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		fmt.Fprintf(cmd.OutOrStdout(), "No gelf hook found in %s\n", path)
+		return nil
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "✓ Removed gelf hook from %s\n", path)
+	return nil
+}
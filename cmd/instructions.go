@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	"github.com/EkeMinusYou/gelf/internal/instructions"
+	"github.com/spf13/cobra"
+)
+
+// loadInstructions returns the standing instructions from the global and
+// repository instruction files, formatted for a prompt. Problems reading them
+// are warnings; generation goes ahead without them.
+func loadInstructions(cmd *cobra.Command) string {
+	repoRoot, _ := git.GetRepoRoot()
+	files, err := instructions.Load(repoRoot)
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", warningStyle.Render(i18n.T("instructions.ignored", err)))
+		return ""
+	}
+	for _, file := range files {
+		if file.Truncated {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", warningStyle.Render(i18n.T("instructions.truncated", file.Path, instructions.MaxFileSize)))
+		}
+	}
+	return instructions.Format(files)
+}
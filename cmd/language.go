@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/language"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// flagLanguage returns the canonical name of the language given with --flag.
+// A value kept as written is reported on stderr.
+func flagLanguage(cmd *cobra.Command, cfg *config.Config, flag, value string) (string, error) {
+	name, warning, err := language.Normalize(value, "--"+flag, cfg.StrictLanguage)
+	if err != nil {
+		return "", err
+	}
+	if warning != "" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings([]string{warning}))
+	}
+	return name, nil
+}
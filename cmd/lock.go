@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	"github.com/EkeMinusYou/gelf/internal/lock"
+	"github.com/spf13/cobra"
+)
+
+// lockFileName is the advisory lock taken in the git directory shared by all
+// worktrees around mutating operations (committing, pushing, gh pr
+// create/edit), since worktrees share refs and remotes.
+const lockFileName = "gelf.lock"
+
+// acquireRepoLock takes the repository lock. With wait it blocks until a
+// concurrent gelf operation finishes; otherwise that operation is reported.
+// Read-only operations (dry runs, review) must not call it.
+func acquireRepoLock(cmd *cobra.Command, wait bool) (func(), error) {
+	paths, err := git.GetRepoPaths()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(paths.CommonDir, lockFileName)
+
+	var l *lock.Lock
+	if wait {
+		l, err = lock.Wait(path, func(held *lock.HeldError) {
+			fmt.Fprintln(cmd.ErrOrStderr(), i18n.T("lock.waiting", held))
+		})
+	} else {
+		l, err = lock.Acquire(path)
+	}
+	if err != nil {
+		var held *lock.HeldError
+		if errors.As(err, &held) {
+			return nil, exitWithCode(cmd, ExitError, fmt.Errorf("%w; retry when it finishes or pass --wait", held))
+		}
+		return nil, err
+	}
+
+	return func() { _ = l.Release() }, nil
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/spf13/cobra"
+)
+
+func TestRepoLockSharedByWorktrees(t *testing.T) {
+	root := newTestRepo(t)
+	worktree := filepath.Join(t.TempDir(), "feature")
+	runTestGit(t, "worktree", "add", "-q", "-b", "feature", worktree)
+
+	release, err := acquireRepoLock(&cobra.Command{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, ".git", lockFileName)); err != nil {
+		t.Fatalf("lock is not in the common git directory: %v", err)
+	}
+
+	t.Chdir(worktree)
+	git.ForgetRepoPaths()
+	_, err = acquireRepoLock(&cobra.Command{}, false)
+	if err == nil || !strings.Contains(err.Error(), "another gelf operation is in progress") {
+		t.Fatalf("lock from a linked worktree = %v, want it held by the main worktree", err)
+	}
+
+	release()
+	release, err = acquireRepoLock(&cobra.Command{}, false)
+	if err != nil {
+		t.Fatalf("lock after release: %v", err)
+	}
+	release()
+}
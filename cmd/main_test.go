@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/EkeMinusYou/gelf/internal/git"
+)
+
+// refreshLogEnv names a file the test binary appends to instead of running
+// gelf __refresh-reviewers, when a completion starts it.
+const refreshLogEnv = "GELF_TEST_REFRESH_LOG"
+
+func TestMain(m *testing.M) {
+	// Commands that gelf starts as os.Executable() land here in tests.
+	if len(os.Args) > 1 && os.Args[1] == refreshReviewersCmd.Name() {
+		if path := os.Getenv(refreshLogEnv); path != "" {
+			file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+			if err == nil {
+				file.WriteString(os.Args[1] + "\n")
+				file.Close()
+			}
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestRepo creates a git repository with one commit and makes it the
+// working directory for the test.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	git.ForgetRepoPaths()
+	t.Cleanup(git.ForgetRepoPaths)
+	runTestGit(t, "init", "-q", "-b", "main")
+	runTestGit(t, "-c", "user.name=gelf", "-c", "user.email=gelf@example.com", "commit", "-q", "--allow-empty", "-m", "initial")
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func runTestGit(t *testing.T, args ...string) {
+	t.Helper()
+	if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, output)
+	}
+}
+
+// stubGH puts a gh on PATH that runs script, a POSIX shell script body,
+// and returns the file each of its invocations is logged to as one line of
+// arguments.
+func stubGH(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the gh stub is a shell script")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "gh.log")
+	stub := "#!/bin/sh\necho \"$@\" >> '" + log + "'\n" + script + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "gh"), []byte(stub), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
@@ -3,19 +3,34 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
-	"net/url"
+	"os"
 	"os/exec"
-	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/EkeMinusYou/gelf/internal/ai"
+	"github.com/EkeMinusYou/gelf/internal/commitmsg"
 	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/deps"
+	"github.com/EkeMinusYou/gelf/internal/diffreport"
 	"github.com/EkeMinusYou/gelf/internal/git"
 	"github.com/EkeMinusYou/gelf/internal/github"
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	"github.com/EkeMinusYou/gelf/internal/language"
+	"github.com/EkeMinusYou/gelf/internal/mdwrap"
+	"github.com/EkeMinusYou/gelf/internal/postcreate"
+	"github.com/EkeMinusYou/gelf/internal/prbody"
+	"github.com/EkeMinusYou/gelf/internal/redact"
+	"github.com/EkeMinusYou/gelf/internal/state"
+	"github.com/EkeMinusYou/gelf/internal/timing"
 	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/EkeMinusYou/gelf/pkg/gelf"
 	"github.com/spf13/cobra"
 )
 
@@ -41,12 +56,122 @@ var (
 	prNoRender      bool
 	prYes           bool
 	prUpdate        bool
+	prJSON          bool
+	prEditPrompt    bool
+	prAllowSecrets  bool
+	prShowPrompt    bool
+	prPregenerate   bool
+	prNoPush        bool
+	prForce         bool
+	prLabelFromDiff bool
+	prContext       string
+	prForceGenerate bool
+	prWIP           bool
+	prWait          bool
+	prSelectCommits bool
+	prNoPost        bool
+	prAppendUpdate  bool
+	prFullContext   bool
+	prRegenTitle    bool
+	prBaseRef       string
+	prResume        bool
+	prLabels        []string
+	prReviewers     []string
+	prIfExists      string
+
+	prReviewerFromHistory bool
+
+	prSplit         bool
+	prSplitPlanOnly bool
+)
+
+// What --if-exists does when the branch already has a pull request.
+const (
+	prIfExistsUpdate = "update"
+	prIfExistsSkip   = "skip"
+	prIfExistsFail   = "fail"
+)
+
+// Actions reported in the --json result of pr create.
+const (
+	prActionCreated     = "created"
+	prActionUpdated     = "updated"
+	prActionSkipped     = "skipped"
+	prActionExists      = "exists"
+	prActionDeclined    = "declined"
+	prActionNothingToDo = "nothing-to-do"
+	prActionDryRun      = "dry-run"
 )
 
+// prCreateResult is the structured outcome of pr create, printed with --json.
+type prCreateResult struct {
+	Action      string            `json:"action"`
+	Number      int               `json:"number,omitempty"`
+	URL         string            `json:"url,omitempty"`
+	Title       string            `json:"title,omitempty"`
+	TitleSource string            `json:"title_source,omitempty"`
+	Body        string            `json:"body,omitempty"`
+	Draft       bool              `json:"draft,omitempty"`
+	Summary     *prSummary        `json:"summary,omitempty"`
+	Plan        *prPlan           `json:"plan,omitempty"`
+	Files       diffreport.Report `json:"files,omitempty"`
+	ExitCode    int               `json:"exit_code"`
+}
+
+// Where the title in prCreateResult came from.
+const (
+	prTitleFromModel  = "model"
+	prTitleFromCommit = "commit"
+)
+
+// prSummary is the stats footer printed after a pull request is created or
+// updated (pr.success_summary).
+type prSummary struct {
+	Commits   int    `json:"commits"`
+	Files     int    `json:"files"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Template  string `json:"template,omitempty"`
+	Model     string `json:"model"`
+}
+
+func newPRSummary(diff string, commits int, template, model string) *prSummary {
+	summary := &prSummary{Commits: commits, Template: template, Model: model}
+	for _, file := range git.ParseDiffSummary(diff).Files {
+		summary.Files++
+		summary.Additions += file.AddedLines
+		summary.Deletions += file.DeletedLines
+	}
+	return summary
+}
+
+// String renders the summary as one line, e.g.
+// "3 commits · 5 files changed · +120 -34 · model gemini-3.1-pro-preview".
+func (s *prSummary) String() string {
+	parts := []string{
+		plural(s.Commits, "commit"),
+		plural(s.Files, "file") + " changed",
+		fmt.Sprintf("+%d -%d", s.Additions, s.Deletions),
+	}
+	if s.Template != "" {
+		parts = append(parts, "using "+s.Template)
+	}
+	parts = append(parts, "model "+s.Model)
+	return strings.Join(parts, " · ")
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
 func init() {
 	prCreateCmd.Flags().BoolVar(&prDraft, "draft", false, "Create the pull request as a draft")
 	prCreateCmd.Flags().BoolVar(&prDryRun, "dry-run", false, "Print the generated title and body without creating a pull request")
 	prCreateCmd.Flags().StringVar(&prModel, "model", "", "Override default model for PR generation")
+	prCreateCmd.RegisterFlagCompletionFunc("model", completeWithin(completeModels))
 	prCreateCmd.Flags().StringVar(&prLanguage, "language", "", "Language for PR generation (e.g., english, japanese)")
 	prCreateCmd.Flags().StringVar(&prTitleLanguage, "title-language", "", "Language for PR title (e.g., english, japanese)")
 	prCreateCmd.Flags().StringVar(&prBodyLanguage, "body-language", "", "Language for PR body (e.g., english, japanese)")
@@ -54,19 +179,143 @@ func init() {
 	prCreateCmd.Flags().BoolVar(&prNoRender, "no-render", false, "Disable markdown rendering in dry-run output")
 	prCreateCmd.Flags().BoolVar(&prYes, "yes", false, "Automatically approve PR creation without confirmation")
 	prCreateCmd.Flags().BoolVar(&prUpdate, "update", false, "Update existing pull request when one already exists")
+	prCreateCmd.Flags().StringVar(&prIfExists, "if-exists", "", "What to do when the branch already has a pull request: update, skip or fail (default fail with --yes or without a terminal, skip otherwise)")
+	prCreateCmd.Flags().BoolVar(&prJSON, "json", false, "Print the result as JSON (requires --yes or --dry-run)")
+	prCreateCmd.Flags().BoolVar(&prEditPrompt, "edit-prompt", false, "Edit the full prompt in $EDITOR before generation")
+	prCreateCmd.Flags().BoolVar(&prForce, "force", false, "Create the pull request even if required checkboxes are unchecked")
+	prCreateCmd.Flags().BoolVar(&prForceGenerate, "force-generate", false, "Generate even when the pull request looks impossible to create (archived repository, no push access)")
+	prCreateCmd.Flags().BoolVar(&prNoPush, "no-push", false, "Never push the branch; fail if it is not pushed")
+	prCreateCmd.Flags().BoolVar(&prPregenerate, "pregenerate", false, "Start generating while the push prompt is shown")
+	prCreateCmd.Flags().BoolVar(&prShowPrompt, "show-prompt", false, "Print the prompt that would be sent to the model and exit")
+	prCreateCmd.Flags().BoolVar(&prWIP, "wip", false, "Also describe uncommitted changes as upcoming work and create a draft")
+	prCreateCmd.Flags().BoolVar(&prAllowSecrets, "allow-secrets", false, "Send the diff even if it appears to contain secrets")
+	prCreateCmd.Flags().BoolVar(&prAppendUpdate, "append-update", false, "Append a dated section describing only the commits since the last update (implies --update)")
+	prCreateCmd.Flags().BoolVar(&prFullContext, "full-context", false, "List every changed file and commit before the confirmation prompt")
+	prCreateCmd.Flags().StringVar(&prContext, "context", "", "Extra background for the description, such as related pull requests")
+	prCreateCmd.Flags().BoolVar(&prRegenTitle, "regen-title", false, "Generate the title even when the branch has a single commit")
+	prCreateCmd.Flags().StringVar(&prBaseRef, "base-ref", "", "Describe the changes since this commit, tag or other committish; the pull request still targets the base branch")
+	prCreateCmd.RegisterFlagCompletionFunc("base-ref", completeWithin(completeRefs))
+	prCreateCmd.MarkFlagsMutuallyExclusive("base-ref", "append-update")
+	prCreateCmd.Flags().BoolVar(&prResume, "resume", false, "Go back to the confirmation with the content saved when the last run was declined or gh failed")
+	prCreateCmd.MarkFlagsMutuallyExclusive("resume", "dry-run")
+	prCreateCmd.MarkFlagsMutuallyExclusive("resume", "show-prompt")
+	prCreateCmd.MarkFlagsMutuallyExclusive("resume", "edit-prompt")
+	prCreateCmd.Flags().StringArrayVar(&prLabels, "label", nil, "Add a label to the new pull request (repeatable)")
+	prCreateCmd.Flags().BoolVar(&prLabelFromDiff, "label-from-diff", false, "Add a size label (size/S, size/M, ...) from the number of changed lines")
+	prCreateCmd.Flags().StringArrayVar(&prReviewers, "reviewer", nil, "Request a review from a user or org/team (repeatable)")
+	prCreateCmd.RegisterFlagCompletionFunc("reviewer", completeWithin(completeReviewers))
+	prCreateCmd.Flags().BoolVar(&prReviewerFromHistory, "reviewer-from-history", false, "Suggest the reviewers who approved your recently merged pull requests")
+	prCreateCmd.Flags().BoolVar(&prNoPost, "no-post", false, "Skip the pr.post_create commands")
+	prCreateCmd.Flags().BoolVar(&prSelectCommits, "select-commits", false, "Choose which commits inform the description")
+	prCreateCmd.MarkFlagsMutuallyExclusive("select-commits", "yes")
+	prCreateCmd.Flags().BoolVar(&prWait, "wait", false, "Wait for another gelf operation in this repository to finish instead of failing")
+	prCreateCmd.Flags().BoolVar(&prSplit, "split", false, "Propose splitting the branch into stacked draft pull requests and create them")
+	prCreateCmd.Flags().BoolVar(&prSplitPlanOnly, "plan-only", false, "Print the proposed split without creating anything (implies --split)")
+	for _, flag := range splitConflicts {
+		prCreateCmd.MarkFlagsMutuallyExclusive("split", flag)
+		prCreateCmd.MarkFlagsMutuallyExclusive("plan-only", flag)
+	}
+	addActionFlags(prCreateCmd)
 
 	prCmd.AddCommand(prCreateCmd)
 }
 
 func runPRCreate(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
+	defer timing.StartSpan("pr create").End()
+
+	if err := setupActionMode(cmd); err != nil {
+		return err
+	}
 
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
+	if err := applyFlagDefaults(cmd, "pr", cfg.Defaults["pr"]); err != nil {
+		return err
+	}
+	if err := git.CheckJujutsu(); err != nil {
+		return err
+	}
+
+	if prJSON && !prYes && !prDryRun {
+		return fmt.Errorf("--json requires --yes or --dry-run")
+	}
+	if actionMode && !prYes && !prDryRun && !prShowPrompt {
+		return fmt.Errorf("nobody can confirm the pull request in action mode; use --yes or --dry-run")
+	}
+	if prEditPrompt && (prYes || !ui.IsInteractive()) {
+		// $EDITOR would wait for someone to save the prompt.
+		return fmt.Errorf("--edit-prompt opens $EDITOR and needs a terminal; it cannot be used with --yes or without one")
+	}
+	if prAppendUpdate {
+		prUpdate = true
+	}
+	if prSplitPlanOnly {
+		prSplit = true
+	}
+	switch prIfExists {
+	case "":
+		prIfExists = prIfExistsSkip
+		if prUpdate {
+			prIfExists = prIfExistsUpdate
+		} else if prYes || !ui.IsInteractive() {
+			// A script must not mistake the existing pull request for one
+			// it just created.
+			prIfExists = prIfExistsFail
+		}
+	case prIfExistsUpdate:
+		prUpdate = true
+	case prIfExistsSkip, prIfExistsFail:
+		if prUpdate {
+			flag := "--update"
+			if prAppendUpdate {
+				flag = "--append-update"
+			}
+			return fmt.Errorf("--if-exists %s conflicts with %s", prIfExists, flag)
+		}
+	default:
+		return fmt.Errorf("invalid --if-exists %q: must be %s, %s or %s", prIfExists, prIfExistsUpdate, prIfExistsSkip, prIfExistsFail)
+	}
+	if prBaseRef != "" {
+		if _, err := git.ResolveCommit(prBaseRef); err != nil || strings.HasPrefix(prBaseRef, "-") {
+			return fmt.Errorf("invalid --base-ref %q: not a commit in this repository", prBaseRef)
+		}
+	}
+	// A dry run or --show-prompt with --base-ref previews the description of
+	// an arbitrary range, e.g. v1.2..HEAD on the base branch itself, so the
+	// checks that HEAD has something to propose against the base branch do
+	// not apply.
+	previewRange := (prDryRun || prShowPrompt) && prBaseRef != ""
+
+	// --resume goes back to the confirmation with the content the last
+	// declined or failed run saved instead of generating.
+	var resumed *ai.PullRequestContent
+	if prResume {
+		resumed, err = loadResumeContent(cmd)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Override language settings from command line flags
+	for _, flag := range []struct {
+		name  string
+		value *string
+	}{
+		{"language", &prLanguage},
+		{"title-language", &prTitleLanguage},
+		{"body-language", &prBodyLanguage},
+	} {
+		if *flag.value == "" {
+			continue
+		}
+		if *flag.value, err = flagLanguage(cmd, cfg, flag.name, *flag.value); err != nil {
+			return err
+		}
+	}
 	if prLanguage != "" {
 		cfg.PRLanguage = prLanguage
 		cfg.PRTitleLanguage = prLanguage
@@ -93,6 +342,28 @@ func runPRCreate(cmd *cobra.Command, args []string) error {
 	}
 	cfg.FlashModel = cfg.ResolveModel(modelToUse)
 
+	contextSpan := timing.StartSpan("pr context")
+	defer contextSpan.End()
+	endContextGroup := actionGroup(cmd, "Collect context")
+	defer endContextGroup()
+
+	// Fail before the model call on what gh or the push would refuse at
+	// the end. The remote only matters when the branch has to be pushed.
+	remote := ""
+	if !prDryRun && !prShowPrompt {
+		if branch, err := git.GetCurrentBranch(); err == nil {
+			if status, err := git.GetPushStatus(branch); err == nil && !status.HeadPushed {
+				remote = status.RemoteName
+			}
+		}
+	}
+	if err := runPreflight(cmd, prPreflight(ctx, remote)); err != nil {
+		return err
+	}
+
+	if err := requireGHJSON(ctx); err != nil {
+		return err
+	}
 	currentRepo, parentRepo, err := github.RepoInfoFromGHWithParent(ctx)
 	if err != nil {
 		return err
@@ -103,6 +374,30 @@ func runPRCreate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to determine current branch: %w", err)
 	}
 
+	_, baseBranch, err := detectPRSource()
+	if err != nil {
+		return err
+	}
+
+	if !previewRange {
+		headBranch, err = guardBaseBranch(ctx, cmd, cfg, headBranch, baseBranch)
+		if err != nil {
+			return err
+		}
+	}
+	// Refuse before any lookup when there is nothing to propose, instead of
+	// reaching the "no commits" check late in the flow.
+	if problem := headBaseProblem(headBranch, baseBranch); problem != "" && !previewRange {
+		fmt.Fprintln(cmd.ErrOrStderr(), problem)
+		committed, err := offerCommitFirst(cmd, headBranch, baseBranch)
+		if err != nil {
+			return err
+		}
+		if !committed || headBaseProblem(headBranch, baseBranch) != "" {
+			return finishPRCreate(cmd, prCreateResult{Action: prActionNothingToDo}, ExitNothingToDo)
+		}
+	}
+
 	baseRepo := currentRepo
 	if parentRepo != nil {
 		baseRepo = parentRepo
@@ -120,9 +415,13 @@ func runPRCreate(cmd *cobra.Command, args []string) error {
 		remoteName = "origin"
 	}
 
+	forgeHost := ""
+	pushRepo := ""
 	if remoteURL, err := git.GetRemoteURL(remoteName); err == nil {
+		forgeHost = github.HostFromRemoteURL(remoteURL)
 		if remoteRepoInfo, err := github.RepoInfoFromRemoteURL(remoteURL); err == nil && remoteRepoInfo != nil {
 			headOwners = append(headOwners, remoteRepoInfo.Owner)
+			pushRepo = fmt.Sprintf("%s/%s", remoteRepoInfo.Owner, remoteRepoInfo.Name)
 		}
 	}
 	if currentRepo.Owner != "" {
@@ -161,8 +460,27 @@ func runPRCreate(cmd *cobra.Command, args []string) error {
 		if existingPR.IsDraft {
 			stateLabel = "DRAFT"
 		}
-		fmt.Fprintf(cmd.ErrOrStderr(), "Pull request already exists for branch %s (%s): #%d %s (%s)\n", headBranch, stateLabel, existingPR.Number, existingPR.Title, existingPR.URL)
-		return nil
+		fmt.Fprintln(cmd.ErrOrStderr(), i18n.T("pr.exists", headBranch, stateLabel, existingPR.Number, existingPR.Title, existingPR.URL))
+		result := prCreateResult{
+			Action: prActionSkipped,
+			Number: existingPR.Number,
+			URL:    existingPR.URL,
+			Title:  existingPR.Title,
+			Draft:  existingPR.IsDraft,
+		}
+		if prIfExists == prIfExistsFail {
+			fmt.Fprintln(cmd.ErrOrStderr(), i18n.T("pr.exists_hint"))
+			result.Action = prActionExists
+			return finishPRCreate(cmd, result, ExitError)
+		}
+		return finishPRCreate(cmd, result, ExitSkipped)
+	}
+
+	// Spend no model call on a pull request gh will refuse.
+	if !prForceGenerate && !prShowPrompt {
+		if err := checkPRViability(ctx, repoFullName, pushRepo, !status.HeadPushed); err != nil {
+			return err
+		}
 	}
 
 	token, err := github.AuthToken(ctx)
@@ -175,15 +493,9 @@ func runPRCreate(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	template, err := github.FindPullRequestTemplate(ctx, repoRoot, token, baseRepo.Owner)
-	if err != nil {
-		return fmt.Errorf("failed to resolve pull request template: %w", err)
-	}
-
-	baseBranch, err := git.GetDefaultBaseBranch()
-	if err != nil {
-		return fmt.Errorf("failed to determine base branch: %w", err)
-	}
+	// The org template lookup can take a dozen requests; it runs while the
+	// commits and diff are collected.
+	templates := startTemplateLookup(ctx, cfg, repoRoot, token, baseRepo)
 
 	// When updating, use the existing PR's base branch to avoid including
 	// unrelated commits from a non-default base branch in the diff.
@@ -191,23 +503,55 @@ func runPRCreate(cmd *cobra.Command, args []string) error {
 		baseBranch = existingPR.Base
 	}
 
-	if !prDryRun {
-		shouldContinue, err := ensureBranchPushed(cmd, headBranch)
+	source := prSource(baseBranch)
+	if prBaseRef != "" {
+		warnBaseRefMismatch(cmd, prBaseRef, source.Base)
+		source.Base = prBaseRef
+	}
+
+	// With --append-update, only the commits after the one the body last
+	// covered are described, and the addendum is appended to the body.
+	var previous *ai.PullRequestContent
+	if prAppendUpdate && updateExisting {
+		previous, err = previousDescription(ctx, cmd, repoFullName, existingPR)
 		if err != nil {
 			return err
 		}
-		if !shouldContinue {
-			return nil
+		if previous != nil {
+			source.Base = prbody.HeadMarker(previous.Body)
+			// The footer is added again after generation; the model must
+			// not see it, or it ends up copying it into the addendum.
+			previous.Body = prbody.StripAttribution(prbody.StripHeadMarker(previous.Body))
 		}
 	}
+	if updateExisting && previous == nil && cfg.PRAttribution {
+		warnHandWritten(ctx, cmd, repoFullName, existingPR)
+	}
 
-	baseRef := "origin/" + baseBranch
-	commitLog, err := git.GetCommitLog(baseRef, "HEAD")
+	baseRef := source.Base
+	commitLog, err := source.CommitLog()
 	if err != nil {
-		return fmt.Errorf("failed to get commit log: %w", err)
+		return err
 	}
 	if commitLog == "" {
-		return fmt.Errorf("no commits found between %s and %s", baseRef, headBranch)
+		if previous != nil {
+			fmt.Fprintln(cmd.ErrOrStderr(), i18n.T("pr.no_new_commits", baseRef))
+		} else {
+			fmt.Fprintln(cmd.ErrOrStderr(), i18n.T("pr.no_commits", baseRef, headBranch))
+		}
+		return finishPRCreate(cmd, prCreateResult{Action: prActionNothingToDo}, ExitNothingToDo)
+	}
+
+	totalCommits := strings.Count(commitLog, "\n") + 1
+	if prSelectCommits {
+		var declined bool
+		commitLog, declined, err = selectCommits(cmd, commitLog)
+		if err != nil {
+			return err
+		}
+		if declined {
+			return finishPRCreate(cmd, prCreateResult{Action: prActionDeclined}, ExitDeclined)
+		}
 	}
 
 	diffStat, err := git.GetCommittedDiffStat(baseRef, "HEAD")
@@ -215,112 +559,377 @@ func runPRCreate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get diff stat: %w", err)
 	}
 
-	diff, err := git.GetCommittedDiff(baseRef, "HEAD")
+	diff, err := source.Diff()
 	if err != nil {
-		return fmt.Errorf("failed to get diff: %w", err)
+		return err
 	}
 	if diff == "" {
-		return fmt.Errorf("no committed changes found between %s and %s", baseRef, headBranch)
+		fmt.Fprintln(cmd.ErrOrStderr(), i18n.T("pr.no_changes", baseRef, headBranch))
+		return finishPRCreate(cmd, prCreateResult{Action: prActionNothingToDo}, ExitNothingToDo)
+	}
+	if !prSplit && !updateExisting {
+		prSplit, err = offerSplit(cmd, cfg, diff, totalCommits)
+		if err != nil {
+			return err
+		}
 	}
 
-	aiClient, err := ai.NewVertexAIClient(ctx, cfg)
-	if err != nil {
-		return fmt.Errorf("failed to create AI client: %w", err)
+	uncommittedDiff := ""
+	if prWIP {
+		// Uncommitted work only informs the description; it is never
+		// committed or pushed, so the pull request is created as a draft.
+		prDraft = true
+		uncommittedDiff, err = git.GetUncommittedDiff()
+		if err != nil {
+			return fmt.Errorf("failed to get uncommitted changes: %w", err)
+		}
+		if uncommittedDiff == "" {
+			fmt.Fprintln(cmd.ErrOrStderr(), i18n.T("pr.wip_no_changes"))
+		}
+	}
+
+	classification := git.ClassifyDiff(diff)
+	scope := commitScope(cfg, diff)
+
+	// Dependency-only branches get the bumps and their release notes; mixed
+	// branches use the normal flow.
+	dependencyUpdates := ""
+	dependencySection := ""
+	if classification.Only(git.CategoryDependency) {
+		if bumps := deps.Parse(diff); len(bumps) > 0 {
+			dependencyUpdates = describeDependencyBumps(ctx, token, bumps)
+			dependencySection = deps.RenderSection(bumps)
+		}
 	}
 
+	template, err := templates.wait(cmd, cfg)
+	if err != nil {
+		return err
+	}
 	templateContent := ""
-	templatePath := ""
-	templateSource := ""
+	templateDescription := ""
 	if template != nil {
 		templateContent = template.Content
-		templatePath = template.Path
-		templateSource = template.Source
+		templateDescription = template.Describe()
 	}
 
-	if prDryRun {
-		prContent, err := aiClient.GeneratePullRequestContent(ctx, ai.PullRequestInput{
-			BaseBranch:    baseBranch,
-			HeadBranch:    headBranch,
-			CommitLog:     commitLog,
-			DiffStat:      diffStat,
-			Diff:          diff,
-			Template:      templateContent,
-			Language:      cfg.PRLanguage,
-			TitleLanguage: cfg.PRTitleLanguage,
-			BodyLanguage:  cfg.PRBodyLanguage,
+	var summary *prSummary
+	if cfg.PRSuccessStats {
+		summary = newPRSummary(diff, totalCommits, templateDescription, cfg.FlashModel)
+	}
+	var size prSize
+	if prLabelFromDiff {
+		size = sizeOf(cfg.PRSizeLabels, diff)
+	}
+
+	redactor, err := redact.New(cfg.RedactRules)
+	if err != nil {
+		return err
+	}
+	diff, report := diffreport.Filter(diff, redactor, prDiffOptions(cfg))
+	printDiffReport(cmd, report)
+	diffStat = redactor.Apply(diffStat)
+	commitLog = redactor.Apply(commitLog)
+	templateContent = redactor.Apply(templateContent)
+	uncommittedDiff = redactor.Apply(uncommittedDiff)
+	dependencyUpdates = redactor.Apply(dependencyUpdates)
+
+	prInput := ai.PullRequestInput{
+		BaseBranch:    baseBranch,
+		HeadBranch:    headBranch,
+		CommitLog:     commitLog,
+		DiffStat:      diffStat,
+		Diff:          diff,
+		Template:      templateContent,
+		Language:      cfg.PRLanguage,
+		TitleLanguage: cfg.PRTitleLanguage,
+		BodyLanguage:  cfg.PRBodyLanguage,
+
+		FileCategories:   classification.String(),
+		DominantCategory: string(classification.Dominant),
+		DocsOnly:         classification.Only(git.CategoryDocs),
+		UncommittedDiff:  uncommittedDiff,
+
+		DependencyUpdates: dependencyUpdates,
+		DependencySection: dependencySection,
+		Scope:             scope,
+
+		Instructions: loadInstructions(cmd),
+		Context:      redactor.Apply(prContext),
+		FileOrder:    cfg.PromptFileOrder,
+	}
+	if previous != nil {
+		prInput.Previous = previous
+		prInput.UpdateHeading = fmt.Sprintf("Update (%s)", time.Now().Format("Jan 2, 2006"))
+	}
+	if previous == nil && !prWIP && !prRegenTitle && totalCommits == 1 {
+		prInput.Title = singleCommitTitle(cfg, commitLog, scope)
+	}
+	titleSource := prTitleFromModel
+	if prInput.Title != "" {
+		titleSource = prTitleFromCommit
+	}
+	contextSpan.SetAttr("diff_bytes", len(diff))
+	contextSpan.SetAttr("commits", strings.Count(commitLog, "\n")+1)
+	contextSpan.End()
+
+	if prEditPrompt {
+		edited, err := ui.EditText(ai.BuildPRPrompt(prInput), "gelf-pr-prompt-*.txt")
+		if err != nil {
+			return fmt.Errorf("failed to edit prompt: %w", err)
+		}
+		if strings.TrimSpace(edited) == "" {
+			return fmt.Errorf("edited prompt is empty; aborting")
+		}
+		prInput.Prompt = edited
+	}
+
+	if prShowPrompt {
+		prompt := prPromptParts(prInput)
+		fmt.Fprintln(cmd.OutOrStdout(), prompt.String())
+		printPromptBreakdown(ctx, cmd, cfg, prompt)
+		return nil
+	}
+
+	endContextGroup()
+
+	if err := checkSecrets(cmd, cfg, strings.TrimSpace(diff+"\n"+uncommittedDiff), prAllowSecrets, prYes); err != nil {
+		return err
+	}
+	if verbose {
+		printPromptBreakdown(ctx, cmd, cfg, prPromptParts(prInput))
+	}
+
+	generator, err := gelf.NewFromConfig(ctx, cfg, cfg.FlashModel, cfg.PRLanguage)
+	if err != nil {
+		return fmt.Errorf("failed to create AI client: %w", err)
+	}
+	defer generator.Close()
+	generator = generator.WithInstructions(prInput.Instructions)
+
+	plan := &prPlan{
+		BaseRepo:   repoFullName,
+		BaseBranch: baseBranch,
+		BaseRef:    prBaseRef,
+		HeadRef:    headRef(headBranch, headOwners, baseRepo.Owner),
+		Draft:      prDraft,
+		Template:   templateDescription,
+		Labels:     prLabels,
+		Reviewers:  prReviewers,
+		SizeLabel:  size.Label,
+	}
+	if updateExisting {
+		plan.Update = existingPR.Number
+		plan.Draft = existingPR.IsDraft
+	} else if cfg.PRLearnDefaults {
+		declined, err := applyLearnedDefaults(ctx, cmd, cfg, repoRoot, plan)
+		if err != nil {
+			return err
+		}
+		if declined {
+			return finishPRCreate(cmd, prCreateResult{Action: prActionDeclined}, ExitDeclined)
+		}
+		prDraft = plan.Draft
+	}
+	if !updateExisting && (prReviewerFromHistory || cfg.PRReviewerHistory) {
+		declined, err := suggestHistoryReviewers(ctx, cmd, cfg, repoRoot, plan)
+		if err != nil {
+			return err
+		}
+		if declined {
+			return finishPRCreate(cmd, prCreateResult{Action: prActionDeclined}, ExitDeclined)
+		}
+	}
+
+	if prSplit {
+		return runPRSplit(ctx, cmd, prSplitRun{
+			cfg:        cfg,
+			generator:  generator,
+			input:      prInput,
+			plan:       plan,
+			redactor:   redactor,
+			baseRef:    baseRef,
+			headBranch: headBranch,
+			headOwners: headOwners,
+			baseOwner:  baseRepo.Owner,
+			remote:     remoteName,
+			forgeHost:  forgeHost,
 		})
+	}
+
+	if prDryRun {
+		endGenerateGroup := actionGroup(cmd, "Generate")
+		prContent, err := generatePRContent(ctx, generator.WithReporter(ui.NewReporter(cmd.ErrOrStderr())), prInput)
+		endGenerateGroup()
 		if err != nil {
 			return err
 		}
 
-		if templateContent != "" {
-			fmt.Fprintf(cmd.ErrOrStderr(), "Using %s template: %s\n", templateSource, templatePath)
+		prContent.Body = mdwrap.Wrap(prContent.Body, cfg.PRWrap)
+		attributeContent(cfg, prContent, prInput)
+		fitWarnings := fitPRContent(prContent)
+		if err := markCoveredHead(prContent, "HEAD"); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n\n", plan.Render(prContent.Title))
+		if warnings := append(fitWarnings, prBodyWarnings(cfg, scope, prContent)...); len(warnings) > 0 {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings(warnings))
+		}
+		result := prCreateResult{
+			Action:      prActionDryRun,
+			Title:       prContent.Title,
+			TitleSource: titleSource,
+			Body:        prContent.Body,
+			Draft:       prDraft,
+			Plan:        plan,
+			Files:       report,
+		}
+		if prJSON {
+			return finishPRCreate(cmd, result, ExitOK)
+		}
+		printPRContent(cmd, prContent, titleSource, cfg.UseColor())
+		return setActionOutputs(cmd, prActionOutputs(result))
+	}
+
+	// With --pregenerate, generation starts from the local state while the
+	// push prompt is shown. The diff only depends on local refs, so pushing
+	// does not change what the model sees.
+	var pending *prGeneration
+	if prPregenerate && resumed == nil {
+		pending = startPRGeneration(ctx, generator, prInput)
+		defer pending.cancel()
+	}
+
+	// Pushing and gh pr create/edit must not interleave with another gelf
+	// process working on the same repository. The lock is taken only around
+	// them, not while the description is generated and reviewed.
+	release, err := acquireRepoLock(cmd, prWait)
+	if err != nil {
+		return err
+	}
+	pushedHead, shouldContinue, err := ensureBranchPushed(cmd, headBranch, baseRef, prYes)
+	release()
+	if err != nil {
+		return err
+	}
+	if shouldContinue && pushedHead != "" {
+		// The push was rejected and the user chose to go on with what the
+		// remote already has, so describe only that.
+		if pending != nil {
+			pending.cancel()
+			pending = nil
+		}
+		resumed = nil
+		editedPrompt := prInput.Prompt != ""
+		pushedDiff, err := narrowPRInput(cfg, &prInput, redactor, baseRef, pushedHead)
+		if err != nil {
+			return err
 		}
-		fmt.Fprintf(cmd.OutOrStdout(), "Title:\n%s\n\n", prContent.Title)
-		if prRender {
-			fmt.Fprintf(cmd.OutOrStdout(), "Body:\n")
-			rendered, err := ui.RenderMarkdown(prContent.Body, cfg.UseColor())
-			if err != nil {
-				fmt.Fprintf(cmd.ErrOrStderr(), "Failed to render markdown: %v\n", err)
-				fmt.Fprintf(cmd.OutOrStdout(), "%s\n", prContent.Body)
-				return nil
+		if editedPrompt {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", warningStyle.Render(i18n.T("pr.edited_prompt_dropped")))
+		}
+		if prLabelFromDiff {
+			size = sizeOf(cfg.PRSizeLabels, pushedDiff)
+			plan.SizeLabel = size.Label
+		}
+		if summary != nil {
+			summary = newPRSummary(pushedDiff, strings.Count(prInput.CommitLog, "\n")+1, summary.Template, summary.Model)
+		}
+		titleSource = prTitleFromModel
+		if prInput.Title != "" {
+			titleSource = prTitleFromCommit
+		}
+	}
+	if !shouldContinue {
+		if pending != nil && !prJSON {
+			// Show what was already generated so the work is not wasted.
+			if content, err := pending.wait(); err == nil {
+				printPRContent(cmd, content, titleSource, cfg.UseColor())
+				savePRContent(cmd, headBranch, content)
 			}
-			fmt.Fprintf(cmd.OutOrStdout(), "%s\n", rendered)
-		} else {
-			fmt.Fprintf(cmd.OutOrStdout(), "Body:\n%s\n", prContent.Body)
 		}
-		return nil
+		return finishPRCreate(cmd, prCreateResult{Action: prActionDeclined}, ExitDeclined)
 	}
 
 	var prContent *ai.PullRequestContent
 	if prYes {
-		prContent, err = aiClient.GeneratePullRequestContent(ctx, ai.PullRequestInput{
-			BaseBranch:    baseBranch,
-			HeadBranch:    headBranch,
-			CommitLog:     commitLog,
-			DiffStat:      diffStat,
-			Diff:          diff,
-			Template:      templateContent,
-			Language:      cfg.PRLanguage,
-			TitleLanguage: cfg.PRTitleLanguage,
-			BodyLanguage:  cfg.PRBodyLanguage,
-		})
+		switch {
+		case resumed != nil:
+			prContent = resumed
+		case pending != nil:
+			prContent, err = pending.wait()
+		default:
+			endGenerateGroup := actionGroup(cmd, "Generate")
+			prContent, err = generatePRContent(ctx, generator.WithReporter(ui.NewReporter(cmd.ErrOrStderr())), prInput)
+			endGenerateGroup()
+		}
 		if err != nil {
 			return err
 		}
 	} else {
-		confirmPrompt := "Create this pull request? (y)es / (n)o"
+		confirmPrompt := i18n.T("pr.confirm_create")
 		if updateExisting {
-			confirmPrompt = "Update this pull request? (y)es / (n)o"
-		}
-		prTUI := ui.NewPRTUI(aiClient, ai.PullRequestInput{
-			BaseBranch:    baseBranch,
-			HeadBranch:    headBranch,
-			CommitLog:     commitLog,
-			DiffStat:      diffStat,
-			Diff:          diff,
-			Template:      templateContent,
-			Language:      cfg.PRLanguage,
-			TitleLanguage: cfg.PRTitleLanguage,
-			BodyLanguage:  cfg.PRBodyLanguage,
-		}, prRender, cfg.UseColor(), confirmPrompt)
+			confirmPrompt = i18n.T("pr.confirm_update")
+		}
+		prTUI := ui.NewPRTUI(generator, prRequest(prInput), prRender, cfg.UseColor(), confirmPrompt)
+		prTUI.SetFullContext(prFullContext)
+		if size.Label != "" {
+			prTUI.SetSize(size.String())
+		}
+		if prSelectCommits {
+			prTUI.SetCommitSelection(strings.Count(commitLog, "\n")+1, totalCommits)
+		}
+		if resumed != nil {
+			prTUI.UsePending(func() (*ai.PullRequestContent, error) { return resumed, nil })
+		} else if pending != nil {
+			prTUI.UsePending(pending.wait)
+		}
+		prTUI.SetFit(fitPRContent)
+		prTUI.SetWarnings(func(content *ai.PullRequestContent) []string {
+			return prBodyWarnings(cfg, scope, content)
+		})
 
 		content, confirmed, err := prTUI.Run()
 		if err != nil {
 			return err
 		}
 		if !confirmed {
-			return nil
+			if content != nil {
+				savePRContent(cmd, headBranch, content)
+			}
+			return finishPRCreate(cmd, prCreateResult{Action: prActionDeclined}, ExitDeclined)
 		}
 		prContent = content
 	}
 
-	if updateExisting {
-		ghArgs := []string{"pr", "edit", fmt.Sprintf("%d", existingPR.Number), "--title", prContent.Title, "--body-file", "-"}
+	// What gh gets is post-processed below; a failed run saves the content
+	// as confirmed, so --resume processes it again.
+	confirmed := *prContent
 
-		ghCmd := exec.Command("gh", ghArgs...)
-		ghCmd.Stdin = strings.NewReader(prContent.Body)
-		ghOut, ghErr, err := runCommandWithSpinnerCapture(ghCmd, "Updating pull request...", cmd.ErrOrStderr())
+	prContent.Body = mdwrap.Wrap(prContent.Body, cfg.PRWrap)
+	attributeContent(cfg, prContent, prInput)
+	// The review screen already showed the content fitted; wrapping and
+	// attribution can still push it over a limit. With --yes nothing was
+	// shown, so a cut description is printed as it will be posted.
+	if warnings := fitPRContent(prContent); len(warnings) > 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings(warnings))
+		if prYes && !prJSON {
+			printPRContent(cmd, prContent, titleSource, cfg.UseColor())
+		}
+	}
+	if err := markCoveredHead(prContent, coveredHead(pushedHead)); err != nil {
+		return err
+	}
+
+	if missing := prbody.MissingRequired(prbody.Lint(prContent.Body, cfg.PRRequiredBoxes)); len(missing) > 0 && !prForce {
+		// The content was already confirmed; keep it so that ticking the
+		// boxes does not cost another generation.
+		savePRContent(cmd, headBranch, &confirmed)
+		return fmt.Errorf("required checkboxes are not checked: %s (use --force to continue anyway)", strings.Join(missing, "; "))
+	}
+
+	if updateExisting {
+		ghOut, ghErr, err := runGHPRCommand(ctx, cmd, plan, prContent, i18n.T("pr.updating"))
 		if err != nil {
 			if strings.TrimSpace(ghOut) != "" {
 				fmt.Fprint(cmd.OutOrStdout(), ghOut)
@@ -328,28 +937,37 @@ func runPRCreate(cmd *cobra.Command, args []string) error {
 			if strings.TrimSpace(ghErr) != "" {
 				fmt.Fprint(cmd.ErrOrStderr(), ghErr)
 			}
+			savePRContent(cmd, headBranch, &confirmed)
 			return fmt.Errorf("failed to update pull request: %w", err)
 		}
-		successHeader := "✓ Pull request updated"
-		if existingPR.Number > 0 {
-			successHeader = fmt.Sprintf("✓ Pull request updated (#%d)", existingPR.Number)
+		if size.Label != "" {
+			applySizeLabel(ctx, cmd, cfg, repoFullName, existingPR.Number, size)
 		}
-		fmt.Fprintf(cmd.OutOrStdout(), "%s\n", ui.RenderSuccessHeader(successHeader))
-		fmt.Fprintf(cmd.OutOrStdout(), "%s\n", ui.RenderSuccessMessage(prContent.Title))
-		if existingPR.URL != "" {
-			fmt.Fprintf(cmd.OutOrStdout(), "%s\n", existingPR.URL)
+		if !prJSON {
+			successHeader := i18n.T("pr.updated")
+			if existingPR.Number > 0 {
+				successHeader = i18n.T("pr.updated_number", existingPR.Number)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s\n", ui.RenderSuccessHeader(successHeader))
+			fmt.Fprintf(cmd.OutOrStdout(), "%s\n", ui.RenderSuccessMessage(prContent.Title))
+			if existingPR.URL != "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\n", existingPR.URL)
+			}
+			printPRSummary(cmd, summary)
 		}
-		return nil
-	}
-
-	ghArgs := []string{"pr", "create", "--title", prContent.Title, "--body-file", "-", "--base", baseBranch}
-	if prDraft {
-		ghArgs = append(ghArgs, "--draft")
+		return finishPRCreate(cmd, prCreateResult{
+			Action:      prActionUpdated,
+			Number:      existingPR.Number,
+			URL:         existingPR.URL,
+			Title:       prContent.Title,
+			TitleSource: titleSource,
+			Body:        prContent.Body,
+			Draft:       existingPR.IsDraft,
+			Summary:     summary,
+		}, ExitOK)
 	}
 
-	ghCmd := exec.Command("gh", ghArgs...)
-	ghCmd.Stdin = strings.NewReader(prContent.Body)
-	ghOut, ghErr, err := runCommandWithSpinnerCapture(ghCmd, "Creating pull request...", cmd.ErrOrStderr())
+	ghOut, ghErr, err := runGHPRCommand(ctx, cmd, plan, prContent, i18n.T("pr.creating"))
 	if err != nil {
 		if strings.TrimSpace(ghOut) != "" {
 			fmt.Fprint(cmd.OutOrStdout(), ghOut)
@@ -357,21 +975,37 @@ func runPRCreate(cmd *cobra.Command, args []string) error {
 		if strings.TrimSpace(ghErr) != "" {
 			fmt.Fprint(cmd.ErrOrStderr(), ghErr)
 		}
+		savePRContent(cmd, headBranch, &confirmed)
 		return fmt.Errorf("failed to create pull request: %w", err)
 	}
 
 	ghOutTrim := strings.TrimSpace(ghOut)
 	ghErrTrim := strings.TrimSpace(ghErr)
 	combinedOutput := strings.TrimSpace(strings.Join([]string{ghOutTrim, ghErrTrim}, "\n"))
-	prURL := extractFirstURL(combinedOutput)
+	prURL, prNumber := github.FindPullRequestURL(combinedOutput, forgeHost)
+	if prURL == "" {
+		// gh printed no recognizable URL; ask for the pull request by branch.
+		if found, err := github.FindPullRequest(ctx, repoFullName, headBranch, headOwners); err == nil && found != nil {
+			prURL, prNumber = found.URL, found.Number
+		}
+	}
+	created := prCreateResult{
+		Action:      prActionCreated,
+		Title:       prContent.Title,
+		TitleSource: titleSource,
+		Body:        prContent.Body,
+		Draft:       prDraft,
+		Summary:     summary,
+	}
 	if prURL == "" {
 		if ghOutTrim != "" {
-			fmt.Fprint(cmd.OutOrStdout(), ghOut)
+			fmt.Fprint(prStatusWriter(cmd), ghOut)
 		}
 		if ghErrTrim != "" {
 			fmt.Fprint(cmd.ErrOrStderr(), ghErr)
 		}
-		return nil
+		runPostCreate(cmd, cfg, created, headBranch)
+		return finishPRCreate(cmd, created, ExitOK)
 	}
 
 	if ghErrTrim != "" {
@@ -381,28 +1015,407 @@ func runPRCreate(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	prNumber := pullNumberFromURL(prURL)
-	successHeader := "✓ Pull request created"
-	if prNumber != "" {
-		successHeader = fmt.Sprintf("✓ Pull request created (#%s)", prNumber)
+	created.URL = prURL
+	created.Number = prNumber
+	if size.Label != "" {
+		applySizeLabel(ctx, cmd, cfg, repoFullName, prNumber, size)
 	}
-	if prDraft {
-		successHeader = fmt.Sprintf("%s (draft)", successHeader)
+	if !prJSON {
+		successHeader := i18n.T("pr.created")
+		if prNumber > 0 {
+			successHeader = i18n.T("pr.created_number", prNumber)
+		}
+		if prDraft {
+			successHeader = i18n.T("pr.draft", successHeader)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s\n", ui.RenderSuccessHeader(successHeader))
+		fmt.Fprintf(cmd.OutOrStdout(), "%s\n", ui.RenderSuccessMessage(prContent.Title))
+		fmt.Fprintf(cmd.OutOrStdout(), "%s\n", prURL)
+		printPRSummary(cmd, summary)
+	}
+
+	runPostCreate(cmd, cfg, created, headBranch)
+	return finishPRCreate(cmd, created, ExitOK)
+}
+
+// prRequest returns the request that generates the description input
+// prompts for. The instructions are the generator's, and the kinds of files
+// changed are worked out from the diff again.
+func prRequest(input ai.PullRequestInput) gelf.PRRequest {
+	request := gelf.PRRequest{
+		BaseBranch:        input.BaseBranch,
+		HeadBranch:        input.HeadBranch,
+		CommitLog:         input.CommitLog,
+		DiffStat:          input.DiffStat,
+		Diff:              input.Diff,
+		Template:          input.Template,
+		Language:          input.Language,
+		TitleLanguage:     input.TitleLanguage,
+		BodyLanguage:      input.BodyLanguage,
+		Scope:             input.Scope,
+		Context:           input.Context,
+		UncommittedDiff:   input.UncommittedDiff,
+		DependencyUpdates: input.DependencyUpdates,
+		DependencySection: input.DependencySection,
+		Title:             input.Title,
+		UpdateHeading:     input.UpdateHeading,
+		Prompt:            input.Prompt,
+	}
+	if input.Previous != nil {
+		request.Previous = &gelf.Description{Title: input.Previous.Title, Body: input.Previous.Body}
+	}
+	return request
+}
+
+// generatePRContent generates the description input prompts for.
+func generatePRContent(ctx context.Context, generator *gelf.Generator, input ai.PullRequestInput) (*ai.PullRequestContent, error) {
+	result, err := generator.PullRequest(ctx, prRequest(input))
+	if err != nil {
+		return nil, err
+	}
+	return &ai.PullRequestContent{Title: result.Title, Body: result.Body}, nil
+}
+
+// runGHPRCommand runs the gh command that carries out plan with content
+// under the repository lock, showing message while it runs, and returns
+// what gh printed.
+func runGHPRCommand(ctx context.Context, cmd *cobra.Command, plan *prPlan, content *ai.PullRequestContent, message string) (string, string, error) {
+	release, err := acquireRepoLock(cmd, prWait)
+	if err != nil {
+		return "", "", err
+	}
+	defer release()
+
+	ghCmd, cleanup, err := ghPRCommand(ctx, plan, content)
+	if err != nil {
+		return "", "", err
+	}
+	defer cleanup()
+	return runCommandWithSpinnerCapture(ghCmd, message, cmd.ErrOrStderr())
+}
+
+// ghPRCommand returns the gh command that carries out plan with content,
+// and a function that removes the body file once it has run. The body goes
+// in a temporary file only the user can read, or in an argument when gh has
+// no --body-file. gh gets no stdin and is told not to prompt: a question it
+// asks (such as whether to fork) would otherwise wait for an answer nobody
+// sees behind the spinner.
+func ghPRCommand(ctx context.Context, plan *prPlan, content *ai.PullRequestContent) (*exec.Cmd, func(), error) {
+	ghCmd := exec.Command("gh")
+	ghCmd.Env = append(os.Environ(), "GH_PROMPT_DISABLED=1")
+
+	if caps, err := github.DetectCapabilities(ctx); err == nil && !caps.BodyFile {
+		args := plan.GHArgs(content.Title, "")
+		i := slices.Index(args, "--body-file")
+		args[i], args[i+1] = "--body", content.Body
+		ghCmd.Args = append(ghCmd.Args, args...)
+		return ghCmd, func() {}, nil
 	}
-	fmt.Fprintf(cmd.OutOrStdout(), "%s\n", ui.RenderSuccessHeader(successHeader))
-	fmt.Fprintf(cmd.OutOrStdout(), "%s\n", ui.RenderSuccessMessage(prContent.Title))
-	fmt.Fprintf(cmd.OutOrStdout(), "%s\n", prURL)
 
+	// os.CreateTemp creates the file with mode 0600.
+	file, err := os.CreateTemp("", "gelf-pr-body-*.md")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to write pull request body: %w", err)
+	}
+	cleanup := func() { _ = os.Remove(file.Name()) }
+	_, err = file.WriteString(content.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to write pull request body: %w", err)
+	}
+
+	ghCmd.Args = append(ghCmd.Args, plan.GHArgs(content.Title, file.Name())...)
+	return ghCmd, cleanup, nil
+}
+
+// requireGHJSON fails before any work is done when gh is too old to print
+// pull requests as JSON. A gh that cannot be run is left to fail later with
+// its own error.
+func requireGHJSON(ctx context.Context) error {
+	caps, err := github.DetectCapabilities(ctx)
+	if err != nil {
+		return nil
+	}
+	return caps.RequireJSON()
+}
+
+// runPostCreate runs the pr.post_create commands for a newly created pull
+// request. Their failures are only reported; the pull request exists either
+// way, so they do not change the exit code.
+func runPostCreate(cmd *cobra.Command, cfg *config.Config, created prCreateResult, branch string) {
+	if prNoPost || len(cfg.PRPostCreate) == 0 {
+		return
+	}
+	errs := postcreate.Run(cfg.PRPostCreate, postcreate.PullRequest{
+		URL:    created.URL,
+		Number: created.Number,
+		Title:  created.Title,
+		Branch: branch,
+	}, prStatusWriter(cmd))
+	if len(errs) == 0 {
+		return
+	}
+	warnings := make([]string, 0, len(errs))
+	for _, err := range errs {
+		warnings = append(warnings, err.Error())
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings(warnings))
+}
+
+// printPRSummary prints the stats footer below the success block.
+func printPRSummary(cmd *cobra.Command, summary *prSummary) {
+	if summary == nil {
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s\n", ui.RenderSuccessStats(summary.String()))
+}
+
+// finishPRCreate prints the --json result when requested and converts a
+// non-zero exit code into the matching command error.
+func finishPRCreate(cmd *cobra.Command, result prCreateResult, code int) error {
+	result.ExitCode = code
+	if result.Action == prActionCreated || result.Action == prActionUpdated {
+		// Neither is worth failing over: history only feeds pr list, and
+		// the saved content is stale once the pull request went through.
+		if repoRoot, err := git.GetRepoRoot(); err == nil {
+			_ = state.ClearPullRequestContent(repoRoot)
+			if result.Number > 0 {
+				_ = state.RecordPullRequest(repoRoot, result.Number, result.Action)
+			}
+		}
+	}
+	if prJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("failed to encode result: %w", err)
+		}
+	}
+	if err := setActionOutputs(cmd, prActionOutputs(result)); err != nil {
+		return err
+	}
+	if code == ExitOK {
+		return nil
+	}
+	return exitWithCode(cmd, code, nil)
+}
+
+// prActionOutputs returns the step outputs for result in action mode.
+func prActionOutputs(result prCreateResult) []actionOutput {
+	number := ""
+	if result.Number > 0 {
+		number = strconv.Itoa(result.Number)
+	}
+	return []actionOutput{
+		{"action", result.Action},
+		{"number", number},
+		{"url", result.URL},
+		{"title", result.Title},
+		{"body", result.Body},
+		{"draft", strconv.FormatBool(result.Draft)},
+	}
+}
+
+// prStatusWriter returns where progress output goes. With --json, stdout is
+// reserved for the result so everything else is written to stderr.
+func prStatusWriter(cmd *cobra.Command) io.Writer {
+	if prJSON {
+		return cmd.ErrOrStderr()
+	}
+	return cmd.OutOrStdout()
+}
+
+// prBodyWarnings lists template boilerplate the generated body left
+// untouched, a title missing the monorepo scope prefix, and a title or body
+// not in pr.title_language or pr.body_language.
+func prBodyWarnings(cfg *config.Config, scope string, content *ai.PullRequestContent) []string {
+	var warnings []string
+	for _, warning := range prbody.Lint(content.Body, cfg.PRRequiredBoxes) {
+		warnings = append(warnings, warning.String())
+	}
+	_, scopeWarnings := commitmsg.ApplyScope(content.Title, scope)
+	for _, warning := range scopeWarnings {
+		warnings = append(warnings, i18n.T("pr.title_warning", warning))
+	}
+	if !language.Matches(commitmsg.Description(content.Title), cfg.PRTitleLanguage) {
+		warnings = append(warnings, i18n.T("pr.title_language", cfg.PRTitleLanguage))
+	}
+	if !language.Matches(prbody.StripAttribution(content.Body), cfg.PRBodyLanguage) {
+		warnings = append(warnings, i18n.T("pr.body_language", cfg.PRBodyLanguage))
+	}
+	return warnings
+}
+
+// fitPRContent clamps the title and trims the body to GitHub's size limits
+// and returns a warning for each that had to be cut.
+func fitPRContent(content *ai.PullRequestContent) []string {
+	var clamped, trimmed bool
+	content.Title, content.Body, clamped = prbody.ClampTitle(content.Title, content.Body)
+	content.Body, trimmed = prbody.FitBody(content.Body, prbody.MaxBodyLength)
+
+	var warnings []string
+	if clamped {
+		warnings = append(warnings, i18n.T("pr.title_clamped", prbody.MaxTitleLength))
+	}
+	if trimmed {
+		warnings = append(warnings, i18n.T("pr.body_trimmed", prbody.MaxBodyLength))
+	}
+	return warnings
+}
+
+// previousDescription returns the title and body of existingPR when its body
+// carries a gelf:head marker for a commit on this branch. Otherwise it
+// returns nil and the whole description is regenerated.
+func previousDescription(ctx context.Context, cmd *cobra.Command, repoFullName string, existingPR *github.PullRequestInfo) (*ai.PullRequestContent, error) {
+	body, err := github.PullRequestBody(ctx, repoFullName, existingPR.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	covered := prbody.HeadMarker(body)
+	if covered == "" {
+		fmt.Fprintln(cmd.ErrOrStderr(), i18n.T("pr.no_head_marker", existingPR.Number))
+		return nil, nil
+	}
+	if !git.IsAncestor(covered, "HEAD") {
+		fmt.Fprintln(cmd.ErrOrStderr(), i18n.T("pr.head_marker_not_on_branch", covered))
+		return nil, nil
+	}
+	return &ai.PullRequestContent{Title: existingPR.Title, Body: body}, nil
+}
+
+// warnHandWritten warns before --update replaces a description that gelf did
+// not generate, recognized by the prompt marker pr.attribution leaves.
+func warnHandWritten(ctx context.Context, cmd *cobra.Command, repoFullName string, existingPR *github.PullRequestInfo) {
+	body, err := github.PullRequestBody(ctx, repoFullName, existingPR.Number)
+	if err != nil || strings.TrimSpace(body) == "" || prbody.PromptMarker(body) != "" {
+		return
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings([]string{
+		i18n.T("pr.hand_written", existingPR.Number),
+	}))
+}
+
+// attributeContent appends the pr.attribution footer naming the gelf
+// version, the model and the hash of the prompt the content came from.
+func attributeContent(cfg *config.Config, content *ai.PullRequestContent, input ai.PullRequestInput) {
+	if !cfg.PRAttribution {
+		return
+	}
+	prompt := input.Prompt
+	if prompt == "" {
+		prompt = ai.BuildPRPrompt(input)
+	}
+	content.Body = prbody.SetAttribution(content.Body, version, cfg.FlashModel, prbody.HashPrompt(prompt))
+}
+
+// markCoveredHead records the full SHA of head in the body with
+// --append-update, so the next --append-update knows which commits are
+// already described. It runs after fitPRContent and trims the body further
+// when the marker would push it over GitHub's limit.
+func markCoveredHead(content *ai.PullRequestContent, head string) error {
+	if !prAppendUpdate {
+		return nil
+	}
+	sha, err := git.ResolveCommit(head)
+	if err != nil {
+		return err
+	}
+	marked := prbody.SetHeadMarker(content.Body, sha)
+	if overflow := utf8.RuneCountInString(marked) - prbody.MaxBodyLength; overflow > 0 {
+		body, _ := prbody.FitBody(content.Body, utf8.RuneCountInString(content.Body)-overflow)
+		marked = prbody.SetHeadMarker(body, sha)
+	}
+	content.Body = marked
 	return nil
 }
 
-func ensureBranchPushed(cmd *cobra.Command, branch string) (bool, error) {
+// coveredHead is the commit the pull request shows: HEAD, or the commit the
+// remote already had when the push was rejected.
+func coveredHead(pushedHead string) string {
+	if pushedHead != "" {
+		return pushedHead
+	}
+	return "HEAD"
+}
+
+// singleCommitTitle returns the subject of the only commit in commitLog
+// ("<sha> <subject>"), normalized the way commit messages are, for use as
+// the pull request title. A model call rarely improves on it.
+func singleCommitTitle(cfg *config.Config, commitLog, scope string) string {
+	line := strings.TrimSpace(commitLog)
+	if _, subject, ok := strings.Cut(line, " "); ok {
+		line = subject
+	}
+	title, _ := commitmsg.Normalize(line, commitmsg.Options{
+		Case:       cfg.CommitCase,
+		AllowEmoji: cfg.CommitEmoji,
+		Scope:      scope,
+	})
+	return strings.TrimSpace(title)
+}
+
+// printPRContent prints a generated title and body the way --dry-run does.
+func printPRContent(cmd *cobra.Command, content *ai.PullRequestContent, titleSource string, useColor bool) {
+	titleLabel := i18n.T("pr.dry_run_title")
+	if titleSource == prTitleFromCommit {
+		titleLabel = i18n.T("pr.dry_run_title_from_commit")
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s\n%s\n\n", titleLabel, content.Title)
+	if !prRender {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s\n%s\n", i18n.T("pr.dry_run_body"), content.Body)
+		return
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), i18n.T("pr.dry_run_body"))
+	rendered, err := ui.RenderMarkdown(content.Body, useColor)
+	if err != nil {
+		fmt.Fprintln(cmd.ErrOrStderr(), i18n.T("pr.render_failed", err))
+		fmt.Fprintf(cmd.OutOrStdout(), "%s\n", content.Body)
+		return
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s\n", rendered)
+}
+
+// selectCommits lets the user uncheck commits (one "<sha> <subject>" per
+// line) that should not inform the description. The diff is not affected.
+func selectCommits(cmd *cobra.Command, commitLog string) (string, bool, error) {
+	lines := strings.Split(commitLog, "\n")
+	checked, confirmed, err := ui.PromptMultiSelectWithWriter(i18n.T("pr.select_commits"), lines, cmd.ErrOrStderr())
+	if err != nil {
+		return "", false, fmt.Errorf("--select-commits: %w", err)
+	}
+	if !confirmed {
+		return "", true, nil
+	}
+
+	var selected []string
+	for i, line := range lines {
+		if checked[i] {
+			selected = append(selected, line)
+		}
+	}
+	if len(selected) == 0 {
+		return "", false, fmt.Errorf("no commits selected")
+	}
+	return strings.Join(selected, "\n"), false, nil
+}
+
+// ensureBranchPushed makes sure the branch is on the remote, asking before
+// pushing. With autoPush the branch is pushed without asking, unless
+// --no-push was given, in which case an unpushed branch is an error. When the
+// push is rejected and the user goes on with what the remote branch already
+// has, the commit it points at is returned; otherwise the commit is "".
+func ensureBranchPushed(cmd *cobra.Command, branch, baseRef string, autoPush bool) (string, bool, error) {
 	status, err := git.GetPushStatus(branch)
 	if err != nil {
-		return false, fmt.Errorf("failed to check if branch is pushed: %w", err)
+		return "", false, fmt.Errorf("failed to check if branch is pushed: %w", err)
 	}
 	if status.HeadPushed {
-		return true, nil
+		return "", true, nil
 	}
 
 	remoteName := status.RemoteName
@@ -410,38 +1423,49 @@ func ensureBranchPushed(cmd *cobra.Command, branch string) (bool, error) {
 		remoteName = "origin"
 	}
 
-	prompt := fmt.Sprintf("Current branch is not pushed to %s. Push now? (y)es / (n)o", remoteName)
-	confirmed, err := ui.PromptYesNoStyledWithWriter(prompt, cmd.ErrOrStderr())
-	if err != nil {
-		return false, err
+	if prNoPush {
+		return "", false, fmt.Errorf("branch %s is not pushed to %s and --no-push was given", branch, remoteName)
 	}
-	if !confirmed {
-		return false, nil
+
+	if !autoPush {
+		prompt := i18n.T("pr.push_confirm", remoteName)
+		confirmed, err := ui.PromptYesNoStyledWithWriter(prompt, cmd.ErrOrStderr())
+		if err != nil {
+			return "", false, err
+		}
+		if !confirmed {
+			return "", false, nil
+		}
 	}
 
 	args := []string{"push"}
 	if !status.HasUpstream {
-		args = []string{"push", "-u", remoteName, branch}
+		// A full refspec keeps a tag with the same name from being pushed
+		// instead of the branch.
+		args = []string{"push", "-u", remoteName, "refs/heads/" + branch + ":refs/heads/" + branch}
 	}
 
 	pushCmd := exec.Command("git", args...)
 	var pushOutput bytes.Buffer
 	pushCmd.Stdout = &pushOutput
 	pushCmd.Stderr = &pushOutput
-	stopSpinner := ui.StartSpinnerInline("Pushing branch...", cmd.ErrOrStderr())
-	if err := pushCmd.Run(); err != nil {
+	stopSpinner := ui.StartSpinnerInline(i18n.T("pr.pushing"), cmd.ErrOrStderr())
+	if err := timing.Run(pushCmd); err != nil {
 		stopSpinner()
 		trimmed := strings.TrimSpace(pushOutput.String())
+		if reason := git.PushRejection(trimmed); reason != "" {
+			return pushRejected(cmd, status, remoteName, baseRef, reason, trimmed, autoPush)
+		}
 		if trimmed == "" {
-			return false, fmt.Errorf("failed to push branch: %w", err)
+			return "", false, fmt.Errorf("failed to push branch: %w", err)
 		}
-		return false, fmt.Errorf("failed to push branch: %w\n%s", err, trimmed)
+		return "", false, fmt.Errorf("failed to push branch: %w\n%s", err, trimmed)
 	}
 	stopSpinner()
 
-	fmt.Fprintf(cmd.OutOrStdout(), "%s\n\n", ui.RenderSuccessHeader("✓ Push succeeded"))
+	fmt.Fprintf(prStatusWriter(cmd), "%s\n\n", ui.RenderSuccessHeader(i18n.T("pr.push_succeeded")))
 
-	return true, nil
+	return "", true, nil
 }
 
 func runCommandWithSpinner(cmd *exec.Cmd, message string, stdout, stderr io.Writer) error {
@@ -458,7 +1482,7 @@ func runCommandWithSpinner(cmd *exec.Cmd, message string, stdout, stderr io.Writ
 	cmd.Stderr = &errBuf
 
 	stopSpinner := ui.StartSpinner(message, stderr)
-	err := cmd.Run()
+	err := timing.Run(cmd)
 	stopSpinner()
 
 	if outBuf.Len() > 0 {
@@ -482,29 +1506,8 @@ func runCommandWithSpinnerCapture(cmd *exec.Cmd, message string, stderr io.Write
 	cmd.Stderr = &errBuf
 
 	stopSpinner := ui.StartSpinner(message, stderr)
-	err := cmd.Run()
+	err := timing.Run(cmd)
 	stopSpinner()
 
 	return outBuf.String(), errBuf.String(), err
 }
-
-func extractFirstURL(output string) string {
-	re := regexp.MustCompile(`https?://\S+`)
-	return re.FindString(output)
-}
-
-func pullNumberFromURL(prURL string) string {
-	parsed, err := url.Parse(prURL)
-	if err != nil {
-		return ""
-	}
-	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
-	for i := 0; i+1 < len(segments); i++ {
-		if segments[i] == "pull" || segments[i] == "pulls" {
-			if _, err := strconv.Atoi(segments[i+1]); err == nil {
-				return segments[i+1]
-			}
-		}
-	}
-	return ""
-}
@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/EkeMinusYou/gelf/internal/ai"
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/prbody"
+	"github.com/EkeMinusYou/gelf/internal/redact"
+)
+
+// ghPRStub answers the capability probes like a current gh and reports how
+// pr create was run to $GELF_TEST_REPORT.
+const ghPRStub = `case "$1 $2" in
+"--version ") echo "gh version 2.60.0 (2024-10-01)" ;;
+"pr list") printf 'Specify one or more comma-separated fields for ` + "`--json`" + `:\n  number\n  url\n'; exit 1 ;;
+"pr create")
+	if [ "$3" = "--help" ]; then echo "  -F, --body-file file   Read body text from file"; exit 0; fi
+	while [ $# -gt 0 ]; do
+		if [ "$1" = "--body-file" ]; then body="$2"; fi
+		shift
+	done
+	{
+		echo "prompt=$GH_PROMPT_DISABLED"
+		echo "stdin=$(cat)"
+		echo "mode=$(ls -l "$body" | cut -c1-10)"
+		echo "body=$(cat "$body")"
+		echo "file=$body"
+	} > "$GELF_TEST_REPORT"
+	echo "https://github.com/o/r/pull/7"
+	;;
+esac`
+
+func TestGHPRCommandBodyFile(t *testing.T) {
+	stubGH(t, ghPRStub)
+	report := filepath.Join(t.TempDir(), "report")
+	t.Setenv("GELF_TEST_REPORT", report)
+	t.Setenv("GH_PROMPT_DISABLED", "")
+
+	// gh must not read what gelf was given on stdin.
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString("y\n")
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		r.Close()
+	})
+
+	plan := &prPlan{BaseBranch: "main", HeadRef: "feature"}
+	content := &ai.PullRequestContent{Title: "Add retries", Body: "## Summary\nRetries model calls."}
+	ghCmd, cleanup, err := ghPRCommand(context.Background(), plan, content)
+	if err != nil {
+		t.Fatalf("ghPRCommand() error: %v", err)
+	}
+	output, err := ghCmd.Output()
+	if err != nil {
+		t.Fatalf("gh failed: %v", err)
+	}
+	if strings.TrimSpace(string(output)) != "https://github.com/o/r/pull/7" {
+		t.Errorf("gh output = %q", output)
+	}
+
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatalf("gh pr create did not run: %v", err)
+	}
+	fields := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if key, value, ok := strings.Cut(line, "="); ok {
+			fields[key] = value
+		} else {
+			fields["body"] += "\n" + line
+		}
+	}
+	if fields["prompt"] != "1" {
+		t.Errorf("GH_PROMPT_DISABLED = %q, want 1", fields["prompt"])
+	}
+	if fields["stdin"] != "" {
+		t.Errorf("gh read %q from stdin, want nothing", fields["stdin"])
+	}
+	if fields["mode"] != "-rw-------" {
+		t.Errorf("body file mode = %s, want -rw-------", fields["mode"])
+	}
+	if fields["body"] != content.Body {
+		t.Errorf("body file = %q, want %q", fields["body"], content.Body)
+	}
+
+	if _, err := os.Stat(fields["file"]); err != nil {
+		t.Fatalf("body file is gone before cleanup: %v", err)
+	}
+	cleanup()
+	if _, err := os.Stat(fields["file"]); !os.IsNotExist(err) {
+		t.Errorf("body file %s was not removed: %v", fields["file"], err)
+	}
+}
+
+func TestMarkCoveredHeadFullSHAWithinLimit(t *testing.T) {
+	newTestRepo(t)
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sha := strings.TrimSpace(string(out))
+
+	old := prAppendUpdate
+	prAppendUpdate = true
+	t.Cleanup(func() { prAppendUpdate = old })
+
+	section := "## Changes\n\n" + strings.Repeat("a", prbody.MaxBodyLength/2) + "\n\n"
+	content := &ai.PullRequestContent{Title: "Title"}
+	content.Body, _ = prbody.FitBody(section+strings.Replace(section, "Changes", "Details", 1), prbody.MaxBodyLength)
+	if err := markCoveredHead(content, "HEAD"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := prbody.HeadMarker(content.Body); got != sha {
+		t.Errorf("HeadMarker() = %q, want the full SHA %q", got, sha)
+	}
+	if n := utf8.RuneCountInString(content.Body); n > prbody.MaxBodyLength {
+		t.Errorf("body is %d characters, over the limit of %d", n, prbody.MaxBodyLength)
+	}
+}
+
+func TestFitPRContent(t *testing.T) {
+	content := &ai.PullRequestContent{
+		Title: strings.Repeat("t", prbody.MaxTitleLength+10),
+		Body:  strings.Repeat("b", prbody.MaxBodyLength+10),
+	}
+	if warnings := fitPRContent(content); len(warnings) != 2 {
+		t.Errorf("fitPRContent() = %q, want a warning for the title and one for the body", warnings)
+	}
+	if n := utf8.RuneCountInString(content.Title); n > prbody.MaxTitleLength {
+		t.Errorf("title is %d characters", n)
+	}
+	if n := utf8.RuneCountInString(content.Body); n > prbody.MaxBodyLength {
+		t.Errorf("body is %d characters", n)
+	}
+
+	// Content that was fitted before confirmation is left alone.
+	fitted := *content
+	if warnings := fitPRContent(content); len(warnings) != 0 || *content != fitted {
+		t.Errorf("fitting again = %q and changed the content", warnings)
+	}
+}
+
+// ghExistingPRStub answers pr create's lookups for o/r, where the feature
+// branch already has pull request #7.
+const ghExistingPRStub = `case "$1 $2" in
+"--version ") echo "gh version 2.60.0 (2024-10-01)" ;;
+"auth token") echo "token" ;;
+"repo view") echo '{"owner":{"login":"o"},"name":"r","parent":null}' ;;
+"pr list")
+	if [ -z "$4" ]; then printf 'Specify one or more comma-separated fields for ` + "`--json`" + `:\n  number\n  url\n  headRepositoryOwner\n'; exit 1; fi
+	echo '[{"number":7,"title":"Add retries","url":"https://github.com/o/r/pull/7","state":"OPEN","isDraft":false,"headRefName":"feature","baseRefName":"main","headRepositoryOwner":{"login":"o"}}]'
+	;;
+*) echo "unexpected gh $*" >&2; exit 1 ;;
+esac`
+
+// newPushedFeatureRepo makes a repository whose feature branch has one
+// commit over main and is pushed to a local origin.
+func newPushedFeatureRepo(t *testing.T) {
+	t.Helper()
+	newTestRepo(t)
+	origin := t.TempDir()
+	runTestGit(t, "init", "-q", "--bare", origin)
+	runTestGit(t, "remote", "add", "origin", origin)
+	runTestGit(t, "push", "-q", "origin", "main")
+	runTestGit(t, "remote", "set-head", "origin", "main")
+	runTestGit(t, "switch", "-q", "-c", "feature")
+	if err := os.WriteFile("retry.go", []byte("package retry\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runTestGit(t, "add", "retry.go")
+	runTestGit(t, "-c", "user.name=gelf", "-c", "user.email=gelf@example.com", "commit", "-q", "-m", "Add retries")
+	runTestGit(t, "push", "-q", "-u", "origin", "feature")
+}
+
+// executePRCreate runs gelf pr create with args and fails the test when it
+// does not return within a few seconds, e.g. because it waits for input.
+func executePRCreate(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var stderr strings.Builder
+	rootCmd.SetArgs(append([]string{"pr", "create"}, args...))
+	rootCmd.SetOut(&strings.Builder{})
+	rootCmd.SetErr(&stderr)
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		for _, name := range []string{"yes", "edit-prompt", "if-exists"} {
+			flag := prCreateCmd.Flags().Lookup(name)
+			flag.Value.Set(flag.DefValue)
+			flag.Changed = false
+		}
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- rootCmd.Execute() }()
+	select {
+	case err := <-done:
+		return stderr.String(), err
+	case <-time.After(30 * time.Second):
+		t.Fatal("pr create did not return; it is waiting on something")
+		return "", nil
+	}
+}
+
+func TestPRCreateYesWithClosedStdin(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		// wantErr and wantStderr are text the error and stderr must show.
+		wantErr    string
+		wantStderr string
+	}{
+		{
+			name:       "existing pull request fails by default",
+			args:       []string{"--yes"},
+			wantCode:   ExitError,
+			wantStderr: "#7",
+		},
+		{
+			name:       "existing pull request is skipped",
+			args:       []string{"--yes", "--if-exists", "skip"},
+			wantCode:   ExitSkipped,
+			wantStderr: "#7",
+		},
+		{
+			name:     "edit-prompt is refused",
+			args:     []string{"--yes", "--edit-prompt"},
+			wantCode: ExitError,
+			wantErr:  "--edit-prompt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newPushedFeatureRepo(t)
+			stubGH(t, ghExistingPRStub)
+			withClosedStdin(t)
+			t.Setenv("EDITOR", "false")
+			t.Setenv("GITHUB_ACTIONS", "")
+
+			stderr, err := executePRCreate(t, tt.args...)
+			if code := ExitCode(err); code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d (error: %v)\n%s", code, tt.wantCode, err, stderr)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.wantErr)
+			}
+			if !strings.Contains(stderr, tt.wantStderr) {
+				t.Errorf("stderr = %q, want it to mention %q", stderr, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestNarrowPRInputClearsEditedPrompt(t *testing.T) {
+	newPushedFeatureRepo(t)
+	if err := os.WriteFile("backoff.go", []byte("package retry\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runTestGit(t, "add", "backoff.go")
+	runTestGit(t, "-c", "user.name=gelf", "-c", "user.email=gelf@example.com", "commit", "-q", "-m", "Add backoff")
+
+	redactor, err := redact.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := ai.PullRequestInput{Prompt: "Describe the retries and the backoff.", CommitLog: "Add retries\nAdd backoff"}
+	if _, err := narrowPRInput(&config.Config{}, &input, redactor, "main", "origin/feature"); err != nil {
+		t.Fatal(err)
+	}
+	if input.Prompt != "" {
+		t.Errorf("Prompt = %q, want it cleared", input.Prompt)
+	}
+	if strings.Contains(input.CommitLog, "backoff") || strings.Contains(input.Diff, "backoff.go") {
+		t.Errorf("input still holds the unpushed commit: %q", input.CommitLog)
+	}
+}
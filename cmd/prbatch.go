@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var prBatchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "Run a pull request command in several repositories",
+}
+
+// prBatchCreateCmd forwards its flags to gelf pr create, so they are parsed
+// by hand instead of being declared twice.
+var prBatchCreateCmd = &cobra.Command{
+	Use:                "create --repos <dir,...> [--context <text>] (--yes | --dry-run) [pr create flags]",
+	Short:              "Run gelf pr create in each repository, one after another",
+	DisableFlagParsing: true,
+	RunE:               runPRBatchCreate,
+}
+
+func init() {
+	prBatchCmd.PersistentFlags().StringSlice("repos", nil, "Comma-separated repository directories, in the order they are processed")
+	prBatchCmd.PersistentFlags().String("context", "", "Background shared by every description; companion pull request URLs are added to it")
+
+	prBatchCmd.AddCommand(prBatchCreateCmd)
+	prCmd.AddCommand(prBatchCmd)
+}
+
+// prBatchResult is the outcome of pr create in one repository of a batch.
+type prBatchResult struct {
+	Repo   string          `json:"repo"`
+	Dir    string          `json:"dir"`
+	Result *prCreateResult `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// prBatchArgs is the command line of pr batch create, split into the batch's
+// own flags and those passed on to pr create.
+type prBatchArgs struct {
+	repos   []string
+	context string
+	json    bool
+	forward []string
+}
+
+func parsePRBatchArgs(args []string) (prBatchArgs, error) {
+	var parsed prBatchArgs
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(arg, "=")
+		switch name {
+		case "--repos", "--context":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return parsed, fmt.Errorf("flag needs an argument: %s", name)
+				}
+				i++
+				value = args[i]
+			}
+			if name == "--context" {
+				parsed.context = value
+				continue
+			}
+			for _, repo := range strings.Split(value, ",") {
+				if repo = strings.TrimSpace(repo); repo != "" {
+					parsed.repos = append(parsed.repos, repo)
+				}
+			}
+		case "--json":
+			parsed.json = true
+		default:
+			parsed.forward = append(parsed.forward, arg)
+		}
+	}
+
+	if len(parsed.repos) == 0 {
+		return parsed, fmt.Errorf("--repos is required")
+	}
+	if !slices.Contains(parsed.forward, "--yes") && !slices.Contains(parsed.forward, "--dry-run") {
+		return parsed, fmt.Errorf("pr batch create requires --yes or --dry-run")
+	}
+	return parsed, nil
+}
+
+func runPRBatchCreate(cmd *cobra.Command, args []string) error {
+	if slices.Contains(args, "-h") || slices.Contains(args, "--help") {
+		return cmd.Help()
+	}
+	batch, err := parsePRBatchArgs(args)
+	if err != nil {
+		return err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the gelf executable: %w", err)
+	}
+
+	var results []prBatchResult
+	var companions []string
+	failed := 0
+	for _, dir := range batch.repos {
+		result := runPRBatchRepo(cmd, self, dir, batch, companions)
+		if result.Error != "" {
+			failed++
+		} else if result.Result.URL != "" {
+			companions = append(companions, fmt.Sprintf("Companion PR in %s: %s", result.Repo, result.Result.URL))
+		}
+		results = append(results, result)
+	}
+
+	if batch.json {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		printPRBatchSummary(cmd, results)
+	}
+
+	if failed > 0 {
+		return exitWithCode(cmd, ExitError, fmt.Errorf("pr create failed in %d of %d repositories", failed, len(results)))
+	}
+	return nil
+}
+
+// runPRBatchRepo runs gelf pr create --json in dir. Its stderr is shown as
+// it runs; the pull requests already created in the batch are appended to
+// the shared context so the description can point at them.
+func runPRBatchRepo(cmd *cobra.Command, self, dir string, batch prBatchArgs, companions []string) prBatchResult {
+	result := prBatchResult{Repo: filepath.Base(dir), Dir: dir}
+	if abs, err := filepath.Abs(dir); err == nil {
+		result.Repo = filepath.Base(abs)
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "\n==> %s (%s)\n", result.Repo, dir)
+
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		result.Error = "not a directory"
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings([]string{result.Error}))
+		return result
+	}
+
+	context := strings.TrimSpace(strings.Join(append([]string{batch.context}, companions...), "\n"))
+	args := append([]string{"pr", "create", "--json"}, batch.forward...)
+	if context != "" {
+		args = append(args, "--context", context)
+	}
+
+	var stdout, stderr bytes.Buffer
+	child := exec.Command(self, args...)
+	child.Dir = dir
+	child.Stdin = os.Stdin
+	child.Stdout = &stdout
+	child.Stderr = io.MultiWriter(cmd.ErrOrStderr(), &stderr)
+	runErr := child.Run()
+
+	var created prCreateResult
+	if err := json.Unmarshal(stdout.Bytes(), &created); err == nil && created.Action != "" {
+		result.Result = &created
+		if created.ExitCode != ExitError {
+			return result
+		}
+	}
+	result.Error = errorLine(stderr.String())
+	if result.Error == "" {
+		var exitErr *exec.ExitError
+		if errors.As(runErr, &exitErr) {
+			result.Error = exitErr.Error()
+		} else if runErr != nil {
+			result.Error = runErr.Error()
+		} else {
+			result.Error = "no result from gelf pr create"
+		}
+	}
+	return result
+}
+
+// errorLine picks the error gelf reported in stderr, which is followed by
+// the usage text, falling back to the last line.
+func errorLine(stderr string) string {
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if message, ok := strings.CutPrefix(lines[i], "Error: "); ok {
+			return strings.TrimSpace(message)
+		}
+	}
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// printPRBatchSummary prints one row per repository: its outcome and the
+// pull request URL, or why it failed.
+func printPRBatchSummary(cmd *cobra.Command, results []prBatchResult) {
+	rows := make([][]string, 0, len(results))
+	for _, result := range results {
+		outcome, detail := "failed", result.Error
+		if result.Error == "" {
+			outcome, detail = result.Result.Action, result.Result.URL
+		}
+		rows = append(rows, []string{result.Repo, outcome, detail})
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "\n%s", ui.RenderTable([]string{"REPO", "RESULT", "URL"}, rows))
+}
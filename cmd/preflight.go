@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/github"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// remoteCheckTimeout bounds how long the preflight waits for the remote.
+const remoteCheckTimeout = 10 * time.Second
+
+// preflightCheck is the outcome of one check run before a command spends a
+// model call on work git or gh would refuse at the end.
+type preflightCheck struct {
+	Name string
+	// Problem says what is wrong; it is empty when the check passed.
+	Problem string
+	// Fix says how to resolve Problem.
+	Fix string
+	// Warning marks a problem that does not stop the command.
+	Warning bool
+}
+
+// commitPreflight checks that git commit will go through: the identity
+// resolves, no operation is stopped midway, signing works when it is
+// required, and hooks will run. A merge whose conflicts are resolved is
+// fine: committing concludes it, and gelf commit describes it as a merge.
+func commitPreflight() []preflightCheck {
+	if _, err := git.GetGitDir(); err != nil {
+		return []preflightCheck{{Name: "repository", Problem: "not a git repository", Fix: "run gelf inside a git repository"}}
+	}
+
+	identity := preflightCheck{Name: "identity"}
+	if err := git.CheckIdentity(); err != nil {
+		var missing []string
+		for _, key := range []string{"user.name", "user.email"} {
+			if git.GetConfig(key) == "" {
+				missing = append(missing, key)
+			}
+		}
+		identity.Problem = err.Error()
+		if len(missing) > 0 {
+			identity.Problem = fmt.Sprintf("%s (%s not set)", err, strings.Join(missing, " and "))
+		}
+		identity.Fix = `run git config --global user.name "Your Name" and git config --global user.email you@example.com`
+	}
+
+	state := preflightCheck{Name: "repository state"}
+	operation, err := git.GetOperationInProgress()
+	if err != nil {
+		state.Problem = err.Error()
+	}
+	unmerged, err := git.GetUnmergedFiles()
+	if err != nil {
+		state.Problem = err.Error()
+	}
+	switch {
+	case state.Problem != "":
+	case len(unmerged) > 0:
+		state.Problem = fmt.Sprintf("unresolved conflicts in %s", strings.Join(unmerged, ", "))
+		state.Fix = "resolve the conflicts and git add the files"
+	case operation != "" && operation != git.OperationMerge:
+		state.Problem = fmt.Sprintf("a %s is in progress", operation)
+		state.Fix = fmt.Sprintf("finish it with git %[1]s --continue or abandon it with git %[1]s --abort", operation)
+	}
+
+	signing := preflightCheck{Name: "signing"}
+	if err := git.CheckSigning(); err != nil {
+		signing.Problem = err.Error()
+		signing.Fix = "fix the signing setup (git config user.signingkey, gpg.format, gpg.program) or disable it with git config commit.gpgsign false"
+	}
+
+	hooks := preflightCheck{Name: "hooks", Warning: true}
+	if path := git.GetMissingHooksPath(); path != "" {
+		hooks.Problem = fmt.Sprintf("core.hooksPath points to %s, which does not exist, so no hooks will run", path)
+		hooks.Fix = "create the directory, or git config --unset core.hooksPath"
+		if filepath.Base(filepath.Dir(path)) == ".husky" {
+			hooks.Fix = "run npx husky (or your package manager's install) to regenerate the husky shims"
+		}
+	}
+
+	return []preflightCheck{identity, state, signing, hooks}
+}
+
+// prPreflight checks that gh is logged in and, when remote is not empty,
+// that the remote the branch is pushed to can be reached.
+func prPreflight(ctx context.Context, remote string) []preflightCheck {
+	auth := preflightCheck{Name: "gh auth"}
+	if _, err := github.AuthToken(ctx); err != nil {
+		auth.Problem = "gh is not logged in or not installed"
+		auth.Fix = "run gh auth login"
+	}
+	checks := []preflightCheck{auth}
+
+	if remote != "" {
+		reachable := preflightCheck{Name: "remote"}
+		if err := git.CheckRemote(remote, remoteCheckTimeout); err != nil {
+			reachable.Problem = fmt.Sprintf("cannot reach %s: %v", remote, err)
+			reachable.Fix = fmt.Sprintf("check the network and your credentials for %s (git ls-remote %s)", remote, remote)
+		}
+		checks = append(checks, reachable)
+	}
+	return checks
+}
+
+// pushRemote returns the remote the current branch is pushed to.
+func pushRemote() string {
+	branch, err := git.GetCurrentBranch()
+	if err != nil {
+		return "origin"
+	}
+	status, err := git.GetPushStatus(branch)
+	if err != nil || status.RemoteName == "" {
+		return "origin"
+	}
+	return status.RemoteName
+}
+
+// runPreflight prints the warnings among checks and fails with every
+// problem and its fix when any check that is not a warning failed.
+func runPreflight(cmd *cobra.Command, checks []preflightCheck) error {
+	var warnings, problems []string
+	for _, check := range checks {
+		if check.Problem == "" {
+			continue
+		}
+		line := fmt.Sprintf("%s: %s", check.Name, check.Problem)
+		if check.Fix != "" {
+			line += "\n  → " + check.Fix
+		}
+		if check.Warning {
+			warnings = append(warnings, line)
+		} else {
+			problems = append(problems, line)
+		}
+	}
+
+	if len(warnings) > 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings(warnings))
+	}
+	if len(problems) > 0 {
+		return exitWithCode(cmd, ExitError, fmt.Errorf("preflight checks failed:\n%s", strings.Join(problems, "\n")))
+	}
+	return nil
+}
+
+// renderPreflight lists checks for gelf doctor, passed ones included.
+func renderPreflight(checks []preflightCheck) string {
+	var b strings.Builder
+	for _, check := range checks {
+		switch {
+		case check.Problem == "":
+			fmt.Fprintf(&b, "✓ %s\n", check.Name)
+		case check.Warning:
+			fmt.Fprintf(&b, "⚠ %s: %s\n", check.Name, check.Problem)
+		default:
+			fmt.Fprintf(&b, "✗ %s: %s\n", check.Name, check.Problem)
+		}
+		if check.Problem != "" && check.Fix != "" {
+			fmt.Fprintf(&b, "  → %s\n", check.Fix)
+		}
+	}
+	return b.String()
+}
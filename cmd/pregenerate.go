@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/EkeMinusYou/gelf/internal/ai"
+	"github.com/EkeMinusYou/gelf/pkg/gelf"
+)
+
+// prGeneration runs pull request generation in the background so that it can
+// overlap with interactive steps such as the push prompt.
+type prGeneration struct {
+	cancel  context.CancelFunc
+	done    chan struct{}
+	content *ai.PullRequestContent
+	err     error
+}
+
+func startPRGeneration(ctx context.Context, generator *gelf.Generator, input ai.PullRequestInput) *prGeneration {
+	ctx, cancel := context.WithCancel(ctx)
+	generation := &prGeneration{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(generation.done)
+		generation.content, generation.err = generatePRContent(ctx, generator, input)
+	}()
+
+	return generation
+}
+
+// wait blocks until generation has finished and returns its result.
+func (g *prGeneration) wait() (*ai.PullRequestContent, error) {
+	<-g.done
+	return g.content, g.err
+}
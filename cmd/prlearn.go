@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/github"
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	"github.com/EkeMinusYou/gelf/internal/state"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// Learned defaults are what the last learnedWindow of the user's pull
+// requests have in common, looked up at most once per learnedMaxAge.
+const (
+	learnedWindow = 3
+	learnedMaxAge = 24 * time.Hour
+)
+
+// learnedItem is one learned default the user can remove before it is
+// applied.
+type learnedItem struct {
+	label string
+	apply func(*prPlan)
+}
+
+// applyLearnedDefaults adds the draft state, labels and reviewers of the
+// user's recent pull requests to plan (pr.learn_defaults). Anything set by a
+// flag or by the defaults section is left alone. Interactively the defaults
+// are listed checked and can be removed one by one; it reports true when
+// that list was cancelled.
+func applyLearnedDefaults(ctx context.Context, cmd *cobra.Command, cfg *config.Config, repoRoot string, plan *prPlan) (bool, error) {
+	learned, err := learnPRDefaults(ctx, repoRoot, plan.BaseRepo)
+	if err != nil {
+		fmt.Fprintln(cmd.ErrOrStderr(), warningStyle.Render(fmt.Sprintf("⚠ Ignoring pr.learn_defaults: %v", err)))
+		return false, nil
+	}
+
+	var items []learnedItem
+	if learned.Draft && !plan.Draft && !flagConfigured(cmd, cfg, "draft") {
+		items = append(items, learnedItem{label: "draft", apply: func(p *prPlan) { p.Draft = true }})
+	}
+	if !flagConfigured(cmd, cfg, "label") {
+		for _, label := range learned.Labels {
+			items = append(items, learnedItem{label: "label " + label, apply: func(p *prPlan) { p.Labels = append(p.Labels, label) }})
+		}
+	}
+	if !flagConfigured(cmd, cfg, "reviewer") {
+		for _, reviewer := range learned.Reviewers {
+			items = append(items, learnedItem{label: "reviewer " + reviewer, apply: func(p *prPlan) { p.Reviewers = append(p.Reviewers, reviewer) }})
+		}
+	}
+	if len(items) == 0 {
+		return false, nil
+	}
+
+	labels := make([]string, len(items))
+	for i, item := range items {
+		labels[i] = item.label
+	}
+	checked := make([]bool, len(items))
+	for i := range checked {
+		checked[i] = true
+	}
+	if prYes || prDryRun {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s %s\n", i18n.T("pr.learned_defaults"), strings.Join(labels, ", "))
+	} else {
+		var confirmed bool
+		checked, confirmed, err = ui.PromptMultiSelectWithWriter(i18n.T("pr.learned_defaults"), labels, cmd.ErrOrStderr())
+		if err != nil {
+			return false, fmt.Errorf("pr.learn_defaults: %w", err)
+		}
+		if !confirmed {
+			return true, nil
+		}
+	}
+
+	for i, item := range items {
+		if checked[i] {
+			item.apply(plan)
+		}
+	}
+	return false, nil
+}
+
+// flagConfigured reports whether flag was given on the command line or in
+// defaults.pr, which both override learned defaults.
+func flagConfigured(cmd *cobra.Command, cfg *config.Config, flag string) bool {
+	if cmd.Flags().Changed(flag) {
+		return true
+	}
+	_, ok := cfg.Defaults["pr"][flag]
+	return ok
+}
+
+// learnPRDefaults returns the cached learned defaults, asking gh for the
+// user's recent pull requests when the cache is missing or stale.
+func learnPRDefaults(ctx context.Context, repoRoot, repoFullName string) (*state.PRDefaults, error) {
+	if cached, err := state.LoadPRDefaults(repoRoot, learnedMaxAge); err == nil && cached != nil {
+		return cached, nil
+	}
+
+	prs, err := github.RecentPullRequests(ctx, repoFullName, 10)
+	if err != nil {
+		return nil, err
+	}
+	learned := commonPRDefaults(prs)
+	_ = state.SavePRDefaults(repoRoot, learned)
+	return &learned, nil
+}
+
+// commonPRDefaults returns what the newest learnedWindow pull requests all
+// share. Fewer pull requests than that are not enough to learn from.
+func commonPRDefaults(prs []github.RecentPullRequest) state.PRDefaults {
+	if len(prs) < learnedWindow {
+		return state.PRDefaults{}
+	}
+	prs = prs[:learnedWindow]
+
+	defaults := state.PRDefaults{Draft: true}
+	for _, pr := range prs {
+		defaults.Draft = defaults.Draft && pr.IsDraft
+	}
+	defaults.Labels = common(prs, func(pr github.RecentPullRequest) []string { return pr.Labels })
+	defaults.Reviewers = common(prs, func(pr github.RecentPullRequest) []string { return pr.Reviewers })
+	return defaults
+}
+
+// common returns the values every pull request has, in the order of the
+// newest one.
+func common(prs []github.RecentPullRequest, values func(github.RecentPullRequest) []string) []string {
+	var shared []string
+	for _, value := range values(prs[0]) {
+		everywhere := true
+		for _, pr := range prs[1:] {
+			found := false
+			for _, other := range values(pr) {
+				if other == value {
+					found = true
+					break
+				}
+			}
+			if !found {
+				everywhere = false
+				break
+			}
+		}
+		if everywhere {
+			shared = append(shared, value)
+		}
+	}
+	return shared
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/github"
+	"github.com/EkeMinusYou/gelf/internal/state"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var prListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List pull requests of the current repository",
+	Long: `List pull requests of the current repository.
+
+The list is read-only. To regenerate the description of a pull request,
+check out its branch (gh pr checkout <number>) and run gelf pr create --update.`,
+	RunE: runPRList,
+}
+
+var (
+	prListState string
+	prListMine  bool
+	prListLimit int
+	prListJSON  bool
+)
+
+// prListItem is a pull request as printed by pr list --json.
+type prListItem struct {
+	github.PullRequestSummary
+	// Gelf is the last action gelf took on the pull request, if any.
+	Gelf string `json:"gelf,omitempty"`
+}
+
+func init() {
+	prListCmd.Flags().StringVar(&prListState, "state", "open", "Filter by state: open, closed, merged or all")
+	prListCmd.Flags().BoolVar(&prListMine, "mine", false, "Only list your own pull requests")
+	prListCmd.Flags().IntVar(&prListLimit, "limit", 30, "Maximum number of pull requests to list")
+	prListCmd.Flags().BoolVar(&prListJSON, "json", false, "Print the pull requests as JSON")
+
+	prCmd.AddCommand(prListCmd)
+}
+
+func runPRList(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if err := applyFlagDefaults(cmd, "pr-list", cfg.Defaults["pr-list"]); err != nil {
+		return err
+	}
+
+	switch prListState {
+	case "open", "closed", "merged", "all":
+	default:
+		return fmt.Errorf("invalid --state %q (expected open, closed, merged or all)", prListState)
+	}
+	if prListLimit < 1 {
+		return fmt.Errorf("--limit must be at least 1")
+	}
+
+	if !cfg.UseColor() {
+		ui.DisableColor()
+	}
+
+	if err := requireGHJSON(ctx); err != nil {
+		return err
+	}
+	repo, err := github.RepoInfoFromGH(ctx)
+	if err != nil {
+		return err
+	}
+
+	opts := github.ListOptions{State: prListState, Limit: prListLimit}
+	if prListMine {
+		opts.Author = "@me"
+	}
+	prs, err := github.ListPullRequests(ctx, fmt.Sprintf("%s/%s", repo.Owner, repo.Name), opts)
+	if err != nil {
+		return err
+	}
+
+	history := map[int]state.PullRequestRecord{}
+	if repoRoot, err := git.GetRepoRoot(); err == nil {
+		if loaded, err := state.LoadPullRequestHistory(repoRoot); err == nil {
+			history = loaded
+		}
+	}
+
+	items := make([]prListItem, 0, len(prs))
+	for _, pr := range prs {
+		items = append(items, prListItem{PullRequestSummary: pr, Gelf: history[pr.Number].Action})
+	}
+
+	if prListJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(items)
+	}
+
+	if len(items) == 0 {
+		fmt.Fprintln(cmd.ErrOrStderr(), "No pull requests found")
+		return nil
+	}
+
+	rows := make([][]string, 0, len(items))
+	for _, item := range items {
+		draft := ""
+		if item.IsDraft {
+			draft = "yes"
+		}
+		rows = append(rows, []string{
+			fmt.Sprintf("#%d", item.Number),
+			ui.Truncate(item.Title, 60),
+			ui.RenderPRState(item.State),
+			draft,
+			item.Head,
+			item.Gelf,
+		})
+	}
+	fmt.Fprint(cmd.OutOrStdout(), ui.RenderTable([]string{"#", "TITLE", "STATE", "DRAFT", "HEAD", "GELF"}, rows))
+	return nil
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/ai"
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/diffreport"
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/state"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// promptBreakdownFiles is how many of the largest files of the diff get a
+// row of their own in the breakdown.
+const promptBreakdownFiles = 5
+
+// tokenCountTimeout bounds asking the model's tokenizer; past it the
+// breakdown falls back to estimates.
+const tokenCountTimeout = 10 * time.Second
+
+// promptFile is one file of the diff in a prompt.
+type promptFile struct {
+	name string
+	text string
+}
+
+// printPromptBreakdown prints on stderr how many tokens each part of prompt
+// takes, with the largest files of the diff on rows of their own, and how
+// the whole prompt compares to what is left of the input-token budgets of
+// cmd.
+func printPromptBreakdown(ctx context.Context, cmd *cobra.Command, cfg *config.Config, prompt ai.Prompt) {
+	labels := prompt.Labels()
+	texts := []string{prompt.String()}
+	for _, label := range labels {
+		texts = append(texts, prompt.Text(label))
+	}
+	files, others := largestFiles(prompt.Text(ai.PartDiff))
+	for _, file := range files {
+		texts = append(texts, file.text)
+	}
+
+	counts, model := countPromptTokens(ctx, cfg, texts)
+	total := counts[0]
+	share := func(tokens int) string {
+		if total == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f%%", 100*float64(tokens)/float64(total))
+	}
+
+	var rows [][]string
+	for i, label := range labels {
+		tokens := counts[1+i]
+		rows = append(rows, []string{label, strconv.Itoa(tokens), share(tokens)})
+		if label != ai.PartDiff || len(files) == 0 {
+			continue
+		}
+		listed := 0
+		for j, file := range files {
+			fileTokens := counts[1+len(labels)+j]
+			listed += fileTokens
+			rows = append(rows, []string{"  " + file.name, strconv.Itoa(fileTokens), share(fileTokens)})
+		}
+		if others > 0 {
+			rest := max(tokens-listed, 0)
+			rows = append(rows, []string{"  " + plural(others, "other file"), strconv.Itoa(rest), share(rest)})
+		}
+	}
+	rows = append(rows, []string{"total", strconv.Itoa(total), share(total)})
+
+	out := cmd.ErrOrStderr()
+	heading := "Prompt tokens (estimated, about 4 characters per token):"
+	if model != "" {
+		heading = fmt.Sprintf("Prompt tokens (counted by %s):", model)
+	}
+	fmt.Fprintln(out, heading)
+	fmt.Fprint(out, ui.RenderTable([]string{"PART", "TOKENS", "SHARE"}, rows))
+	printPromptHeadroom(cmd, cfg, total)
+}
+
+// largestFiles cuts diff into its files and returns the promptBreakdownFiles
+// largest, by estimated tokens, with how many files are left out.
+func largestFiles(diff string) ([]promptFile, int) {
+	if diff == "" {
+		return nil, 0
+	}
+	var files []promptFile
+	for _, chunk := range diffreport.Split(diff) {
+		summary := git.ParseDiffSummary(chunk)
+		if len(summary.Files) == 0 {
+			continue
+		}
+		files = append(files, promptFile{name: summary.Files[0].Name, text: chunk})
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return ai.EstimateTokens(files[i].text) > ai.EstimateTokens(files[j].text)
+	})
+	if len(files) <= promptBreakdownFiles {
+		return files, 0
+	}
+	return files[:promptBreakdownFiles], len(files) - promptBreakdownFiles
+}
+
+// countPromptTokens counts the tokens of each of texts with the tokenizer of
+// the configured model and returns the model's name. When the model cannot
+// be reached it estimates them instead and returns no name.
+func countPromptTokens(ctx context.Context, cfg *config.Config, texts []string) ([]int, string) {
+	ctx, cancel := context.WithTimeout(ctx, tokenCountTimeout)
+	defer cancel()
+	if client, err := ai.NewProvider(ctx, cfg); err == nil {
+		counts, err := client.CountTokens(ctx, texts)
+		client.Close()
+		if err == nil {
+			return counts, client.Model()
+		}
+	}
+
+	counts := make([]int, len(texts))
+	for i, text := range texts {
+		counts[i] = ai.EstimateTokens(text)
+	}
+	return counts, ""
+}
+
+// printPromptHeadroom prints, for each input-token budget that applies to
+// cmd, how much of it is used and how much of what is left a prompt of
+// tokens would take.
+func printPromptHeadroom(cmd *cobra.Command, cfg *config.Config, tokens int) {
+	out := cmd.ErrOrStderr()
+	command := commandName(cmd)
+	type scopedBudget struct {
+		scope   string
+		command string
+		budget  config.Budget
+	}
+	budgets := []scopedBudget{{"all commands", "", cfg.Budget}}
+	if budget, ok := cfg.CommandBudgets[command]; ok {
+		budgets = append(budgets, scopedBudget{command, command, budget})
+	}
+
+	entries, err := state.LoadUsage()
+	if err != nil {
+		fmt.Fprintf(out, "%s\n", ui.FormatWarnings([]string{fmt.Sprintf("failed to load usage: %v", err)}))
+		return
+	}
+	now := time.Now()
+	var lines, warnings []string
+	for _, scoped := range budgets {
+		for _, period := range budgetPeriods(now, scoped.budget) {
+			limit := period.limit.InputTokens
+			if limit == 0 {
+				continue
+			}
+			used := tallyUsage(entries, period.start, scoped.command).InputTokens
+			left := max(limit-used, 0)
+			line := fmt.Sprintf("  %s input tokens (%s): %s used, %d left", period.name, scoped.scope, usedOf(used, limit), left)
+			if left > 0 {
+				line += fmt.Sprintf(", this prompt takes %.1f%%", 100*float64(tokens)/float64(left))
+			}
+			lines = append(lines, line)
+			if tokens > left {
+				warnings = append(warnings, fmt.Sprintf("this prompt of %d tokens is over the %d left of the %s input-token budget (%s)", tokens, left, period.name, scoped.scope))
+			}
+		}
+	}
+
+	if len(lines) == 0 {
+		fmt.Fprintln(out, "No input-token budget configured (budget.daily.input_tokens, budget.weekly.input_tokens)")
+		return
+	}
+	fmt.Fprintln(out, "Budget headroom:")
+	for _, line := range lines {
+		fmt.Fprintln(out, line)
+	}
+	if len(warnings) > 0 {
+		fmt.Fprintf(out, "%s\n", ui.FormatWarnings(warnings))
+	}
+}
+
+// prPromptParts returns the prompt pr create sends for input: the one edited
+// with --edit-prompt, as a single part, or the one built from input.
+func prPromptParts(input ai.PullRequestInput) ai.Prompt {
+	if input.Prompt != "" {
+		return ai.Prompt{{Label: ai.PartEdited, Text: input.Prompt}}
+	}
+	return ai.PRPrompt(input)
+}
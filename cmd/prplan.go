@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// prPlan is what pr create resolved before running gh: where the pull
+// request goes and whether an existing one is edited. Dry runs print it
+// instead of executing it.
+type prPlan struct {
+	BaseRepo   string   `json:"base_repo"`
+	BaseBranch string   `json:"base_branch"`
+	BaseRef    string   `json:"base_ref,omitempty"`
+	HeadRef    string   `json:"head_ref"`
+	Draft      bool     `json:"draft"`
+	Template   string   `json:"template,omitempty"`
+	Labels     []string `json:"labels,omitempty"`
+	Reviewers  []string `json:"reviewers,omitempty"`
+	// SizeLabel is added after gh ran (--label-from-diff).
+	SizeLabel string `json:"size_label,omitempty"`
+	// Update is the number of the pull request that is edited, or 0 when a
+	// new one is created.
+	Update int `json:"update,omitempty"`
+}
+
+// headRef names the head branch the way GitHub does, prefixed with the fork
+// owner when the branch lives in a different repository than the base.
+// GitHub owner names are case-insensitive, so a remote URL spelled
+// differently from the API's name still counts as the same owner.
+func headRef(branch string, headOwners []string, baseOwner string) string {
+	if len(headOwners) > 0 && !strings.EqualFold(headOwners[0], baseOwner) {
+		return headOwners[0] + ":" + branch
+	}
+	return branch
+}
+
+// bodyFilePlaceholder stands for the body file in the command a dry run
+// prints.
+const bodyFilePlaceholder = "<body-file>"
+
+// GHArgs returns the gh arguments that create or edit the pull request with
+// the body read from bodyFile. The head is always given, so gh never asks
+// where to push.
+func (p *prPlan) GHArgs(title, bodyFile string) []string {
+	if p.Update > 0 {
+		return []string{"pr", "edit", fmt.Sprintf("%d", p.Update), "--title", title, "--body-file", bodyFile}
+	}
+	args := []string{"pr", "create", "--title", title, "--body-file", bodyFile, "--base", p.BaseBranch, "--head", p.HeadRef}
+	if p.Draft {
+		args = append(args, "--draft")
+	}
+	for _, label := range p.Labels {
+		args = append(args, "--label", label)
+	}
+	for _, reviewer := range p.Reviewers {
+		args = append(args, "--reviewer", reviewer)
+	}
+	return args
+}
+
+// Render describes the plan, ending with the exact gh command for title.
+func (p *prPlan) Render(title string) string {
+	action := "create a new pull request"
+	if p.Update > 0 {
+		action = fmt.Sprintf("update #%d", p.Update)
+	}
+	draft := "no"
+	if p.Draft {
+		draft = "yes"
+	}
+	template := p.Template
+	if template == "" {
+		template = "(none)"
+	}
+
+	lines := []string{
+		"Plan:",
+		fmt.Sprintf("  base repo:   %s", p.BaseRepo),
+		fmt.Sprintf("  base branch: %s", p.BaseBranch),
+	}
+	// --base-ref only changes what is described, not where the pull
+	// request goes.
+	if p.BaseRef != "" {
+		lines = append(lines, fmt.Sprintf("  described:   %s..HEAD", p.BaseRef))
+	}
+	lines = append(lines,
+		fmt.Sprintf("  head ref:    %s", p.HeadRef),
+		fmt.Sprintf("  draft:       %s", draft),
+		fmt.Sprintf("  template:    %s", template),
+		fmt.Sprintf("  labels:      %s", listOrNone(p.Labels)),
+		fmt.Sprintf("  reviewers:   %s", listOrNone(p.Reviewers)),
+	)
+	if p.SizeLabel != "" {
+		lines = append(lines, fmt.Sprintf("  size label:  %s", p.SizeLabel))
+	}
+	lines = append(lines,
+		fmt.Sprintf("  action:      %s", action),
+		fmt.Sprintf("  command:     %s", shellJoin(append([]string{"gh"}, p.GHArgs(title, bodyFilePlaceholder)...))),
+		"               (body written to a temporary file)",
+	)
+	return strings.Join(lines, "\n")
+}
+
+func listOrNone(values []string) string {
+	if len(values) == 0 {
+		return "(none)"
+	}
+	return strings.Join(values, ", ")
+}
+
+// shellJoin joins args into a command line that can be pasted into a POSIX
+// shell, single-quoting the arguments that need it.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@,+") == "" {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import "testing"
+
+func TestHeadRef(t *testing.T) {
+	tests := []struct {
+		name       string
+		headOwners []string
+		baseOwner  string
+		want       string
+	}{
+		{"same repository", []string{"octo"}, "octo", "feature/x"},
+		{"owner spelled in another case", []string{"Octo"}, "octo", "feature/x"},
+		{"fork", []string{"someone"}, "octo", "someone:feature/x"},
+		{"unknown head owner", nil, "octo", "feature/x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := headRef("feature/x", tt.headOwners, tt.baseOwner); got != tt.want {
+				t.Errorf("headRef(%q, %q) = %q, want %q", tt.headOwners, tt.baseOwner, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellJoin(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"plain", []string{"git", "switch", "feat/search"}, "git switch feat/search"},
+		{"hash", []string{"git", "branch", "feat/#123-fix"}, "git branch 'feat/#123-fix'"},
+		{"non-ascii", []string{"git", "branch", "feat/fïx"}, "git branch 'feat/fïx'"},
+		{"quote", []string{"gh", "pr", "create", "--title", "don't"}, `gh pr create --title 'don'\''t'`},
+		{"empty", []string{"git", "commit", "-m", ""}, "git commit -m ''"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shellJoin(tt.args); got != tt.want {
+				t.Errorf("shellJoin(%q) = %s, want %s", tt.args, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/EkeMinusYou/gelf/internal/ai"
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	"github.com/EkeMinusYou/gelf/internal/state"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// savePRContent keeps content, as it was before the head marker, wrapping
+// and attribution were added, for --resume and says where it went. Failing
+// to save is only reported.
+func savePRContent(cmd *cobra.Command, branch string, content *ai.PullRequestContent) {
+	repoRoot, err := git.GetRepoRoot()
+	if err != nil {
+		return
+	}
+	head, _ := git.ResolveCommit("HEAD")
+	path, err := state.SavePullRequestContent(repoRoot, state.SavedPullRequest{
+		Branch:  branch,
+		HeadSHA: head,
+		Title:   content.Title,
+		Body:    content.Body,
+	})
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings([]string{i18n.T("pr.save_failed", err)}))
+		return
+	}
+	fmt.Fprintln(cmd.ErrOrStderr(), i18n.T("pr.saved", path))
+}
+
+// loadResumeContent returns the content saved by the last declined or
+// failed pr create. When the branch or its head moved since, the user can
+// have new content generated instead, in which case nil is returned.
+func loadResumeContent(cmd *cobra.Command) (*ai.PullRequestContent, error) {
+	repoRoot, err := git.GetRepoRoot()
+	if err != nil {
+		return nil, err
+	}
+	saved, err := state.LoadPullRequestContent(repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load saved pull request content: %w", err)
+	}
+	if saved == nil {
+		return nil, fmt.Errorf("no saved pull request content for this repository")
+	}
+
+	branch, err := git.GetCurrentBranch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine current branch: %w", err)
+	}
+	head, err := git.ResolveCommit("HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if saved.Branch == branch && saved.HeadSHA == head {
+		return &ai.PullRequestContent{Title: saved.Title, Body: saved.Body}, nil
+	}
+
+	if prYes || !ui.IsInteractive() {
+		return nil, fmt.Errorf("the saved pull request content was generated for %s at %s, but HEAD is now %s at %s; rerun without --resume to generate new content",
+			saved.Branch, shortSHA(saved.HeadSHA), branch, shortSHA(head))
+	}
+	out := cmd.ErrOrStderr()
+	regenerate, err := ui.PromptYesNoStyledWithWriter(i18n.T("pr.resume_stale", saved.Branch, shortSHA(saved.HeadSHA), branch, shortSHA(head)), out)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(out)
+	if !regenerate {
+		return nil, finishPRCreate(cmd, prCreateResult{Action: prActionDeclined}, ExitDeclined)
+	}
+	return nil, nil
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
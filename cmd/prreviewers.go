@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/github"
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	"github.com/EkeMinusYou/gelf/internal/state"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// Reviewers are suggested from who approved the user's last
+// reviewerHistoryWindow merged pull requests, looked up at most once per
+// reviewerHistoryMaxAge. At most reviewerHistoryTop are suggested, leaving
+// out anyone already asked to review reviewerHistoryBusy of the user's open
+// pull requests.
+const (
+	reviewerHistoryWindow = 20
+	reviewerHistoryTop    = 3
+	reviewerHistoryBusy   = 3
+	reviewerHistoryMaxAge = 24 * time.Hour
+)
+
+// suggestHistoryReviewers offers the reviewers who approved the user's
+// recent pull requests (--reviewer-from-history) as optional additions to
+// plan. Interactively they are listed unchecked and only those the user
+// checks are requested; it reports true when that list was cancelled. With
+// --yes or --dry-run they are requested only when pr.reviewer_from_history
+// opts in, and printed either way.
+func suggestHistoryReviewers(ctx context.Context, cmd *cobra.Command, cfg *config.Config, repoRoot string, plan *prPlan) (bool, error) {
+	history, err := loadReviewerHistory(ctx, repoRoot, plan.BaseRepo)
+	if err != nil {
+		fmt.Fprintln(cmd.ErrOrStderr(), warningStyle.Render(fmt.Sprintf("⚠ Ignoring --reviewer-from-history: %v", err)))
+		return false, nil
+	}
+
+	var reviewers []string
+	for _, reviewer := range history.Reviewers {
+		if len(reviewers) == reviewerHistoryTop {
+			break
+		}
+		if !containsLogin(plan.Reviewers, reviewer) {
+			reviewers = append(reviewers, reviewer)
+		}
+	}
+	if len(reviewers) == 0 {
+		return false, nil
+	}
+
+	checked := make([]bool, len(reviewers))
+	if prYes || prDryRun {
+		if !cfg.PRReviewerHistory {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s %s\n", i18n.T("pr.history_reviewers_not_requested"), strings.Join(reviewers, ", "))
+			return false, nil
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s %s\n", i18n.T("pr.history_reviewers"), strings.Join(reviewers, ", "))
+		for i := range checked {
+			checked[i] = true
+		}
+	} else {
+		var confirmed bool
+		checked, confirmed, err = ui.PromptChecklistWithWriter(i18n.T("pr.history_reviewers"), reviewers, checked, cmd.ErrOrStderr())
+		if err != nil {
+			return false, fmt.Errorf("--reviewer-from-history: %w", err)
+		}
+		if !confirmed {
+			return true, nil
+		}
+	}
+
+	for i, reviewer := range reviewers {
+		if checked[i] {
+			plan.Reviewers = append(plan.Reviewers, reviewer)
+		}
+	}
+	return false, nil
+}
+
+// loadReviewerHistory returns the cached reviewer history, asking gh for the
+// user's merged and open pull requests when the cache is missing or stale.
+func loadReviewerHistory(ctx context.Context, repoRoot, repoFullName string) (*state.ReviewerHistory, error) {
+	if cached, err := state.LoadReviewerHistory(repoRoot, reviewerHistoryMaxAge); err == nil && cached != nil {
+		return cached, nil
+	}
+
+	approvals, err := github.MergedPullRequestApprovers(ctx, repoFullName, reviewerHistoryWindow)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := github.PendingReviewRequests(ctx, repoFullName)
+	if err != nil {
+		return nil, err
+	}
+
+	history := state.ReviewerHistory{}
+	for _, reviewer := range rankApprovers(approvals) {
+		if pending[reviewer] < reviewerHistoryBusy {
+			history.Reviewers = append(history.Reviewers, reviewer)
+		}
+	}
+	_ = state.SaveReviewerHistory(repoRoot, history)
+	return &history, nil
+}
+
+// rankApprovers orders the users who approved the pull requests in
+// approvals, newest first, by how often and how recently they approved: an
+// approval of the newest pull request counts 1 and one of the oldest
+// 1/len(approvals). Ties go to the more recent approval, then by login.
+// Bots are left out.
+func rankApprovers(approvals [][]string) []string {
+	scores := map[string]float64{}
+	latest := map[string]int{}
+	for i, logins := range approvals {
+		weight := float64(len(approvals)-i) / float64(len(approvals))
+		for _, login := range logins {
+			if strings.HasSuffix(login, "[bot]") {
+				continue
+			}
+			if _, ok := scores[login]; !ok {
+				latest[login] = i
+			}
+			scores[login] += weight
+		}
+	}
+
+	ranked := make([]string, 0, len(scores))
+	for login := range scores {
+		ranked = append(ranked, login)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		if latest[a] != latest[b] {
+			return latest[a] < latest[b]
+		}
+		return a < b
+	})
+	return ranked
+}
+
+// containsLogin reports whether logins has login, ignoring case as GitHub
+// does.
+func containsLogin(logins []string, login string) bool {
+	for _, candidate := range logins {
+		if strings.EqualFold(candidate, login) {
+			return true
+		}
+	}
+	return false
+}
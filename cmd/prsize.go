@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/github"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// sizeLabelPrefix starts every size label; a diff bigger than the largest
+// pr.size_labels threshold gets sizeLabelPrefix + "XL".
+const sizeLabelPrefix = "size/"
+
+// prSize is the size label a diff falls into (--label-from-diff).
+type prSize struct {
+	Label   string
+	Changed int
+}
+
+// sizeOf picks the size label for diff: the smallest pr.size_labels bucket
+// whose threshold is at least the number of added and deleted lines.
+func sizeOf(thresholds map[string]int, diff string) prSize {
+	changed := 0
+	for _, file := range git.ParseDiffSummary(diff).Files {
+		changed += file.AddedLines + file.DeletedLines
+	}
+
+	names := sizeNames(thresholds)
+	for _, name := range names {
+		if changed <= thresholds[name] {
+			return prSize{Label: sizeLabelPrefix + name, Changed: changed}
+		}
+	}
+	return prSize{Label: sizeLabelPrefix + "XL", Changed: changed}
+}
+
+// sizeNames returns the bucket names from the smallest threshold up.
+func sizeNames(thresholds map[string]int) []string {
+	names := make([]string, 0, len(thresholds))
+	for name := range thresholds {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if thresholds[names[i]] != thresholds[names[j]] {
+			return thresholds[names[i]] < thresholds[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+func (s prSize) String() string {
+	return fmt.Sprintf("%s (%s changed)", s.Label, plural(s.Changed, "line"))
+}
+
+// applySizeLabel adds size.Label to pull request number and removes the
+// other size labels, so an updated pull request does not keep a stale one.
+// A label missing from the repository is not created; like the other steps
+// after gh succeeded, problems are only warned about.
+func applySizeLabel(ctx context.Context, cmd *cobra.Command, cfg *config.Config, repoFullName string, number int, size prSize) {
+	if number <= 0 {
+		return
+	}
+	warn := func(message string) {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings([]string{message}))
+	}
+
+	existing, err := github.RepoLabels(ctx, repoFullName)
+	if err != nil {
+		warn(fmt.Sprintf("size label not applied: %v", err))
+		return
+	}
+	if !slices.Contains(existing, size.Label) {
+		warn(fmt.Sprintf("size label %s does not exist in %s; not applied", size.Label, repoFullName))
+		return
+	}
+
+	var stale []string
+	for _, name := range append(sizeNames(cfg.PRSizeLabels), "XL") {
+		label := sizeLabelPrefix + name
+		if label != size.Label && slices.Contains(existing, label) && !slices.Contains(stale, label) {
+			stale = append(stale, label)
+		}
+	}
+	if err := github.EditLabels(ctx, repoFullName, number, []string{size.Label}, stale); err != nil {
+		warn(fmt.Sprintf("size label not applied: %v", err))
+	}
+}
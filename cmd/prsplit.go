@@ -0,0 +1,488 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/ai"
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/diffreport"
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/github"
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	"github.com/EkeMinusYou/gelf/internal/mdwrap"
+	"github.com/EkeMinusYou/gelf/internal/redact"
+	"github.com/EkeMinusYou/gelf/internal/state"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/EkeMinusYou/gelf/pkg/gelf"
+	"github.com/spf13/cobra"
+)
+
+// splitProposalAttempts is how many proposals are asked for before a model
+// that keeps leaving out or inventing commits is given up on.
+const splitProposalAttempts = 2
+
+// splitConflicts are the pr create flags --split cannot be combined with:
+// they describe or edit a single pull request.
+var splitConflicts = []string{"update", "append-update", "if-exists", "resume", "show-prompt", "edit-prompt", "select-commits", "wip", "json", "base-ref"}
+
+// prSplitRun is what pr create resolved for the whole branch. Every part of
+// a split inherits it.
+type prSplitRun struct {
+	cfg *config.Config
+	// generator writes the description of each part; splitter proposes the
+	// parts.
+	generator *gelf.Generator
+	splitter  ai.Provider
+	// input describes the whole branch; each part gets a copy with its own
+	// commits and diff.
+	input ai.PullRequestInput
+	// plan is where the pull requests go and the labels and reviewers each
+	// of them gets.
+	plan       *prPlan
+	redactor   *redact.Redactor
+	baseRef    string
+	headBranch string
+	headOwners []string
+	baseOwner  string
+	remote     string
+	forgeHost  string
+}
+
+// splitCommit is one commit of the branch being split.
+type splitCommit struct {
+	SHA     string
+	Subject string
+}
+
+// splitPart is one pull request of a split.
+type splitPart struct {
+	Title   string
+	Branch  string
+	Commits []splitCommit
+	// Body, Number and URL are set once the pull request is created.
+	Body   string
+	Number int
+	URL    string
+}
+
+// splitReason describes how diff goes over pr.split.max_files or
+// pr.split.max_lines, or returns "" when it does not.
+func splitReason(cfg *config.Config, diff string) string {
+	files := git.ParseDiffSummary(diff).Files
+	changed := 0
+	for _, file := range files {
+		changed += file.AddedLines + file.DeletedLines
+	}
+
+	var reasons []string
+	if cfg.PRSplitMaxFiles > 0 && len(files) > cfg.PRSplitMaxFiles {
+		reasons = append(reasons, fmt.Sprintf("%s changed, over pr.split.max_files %d", plural(len(files), "file"), cfg.PRSplitMaxFiles))
+	}
+	if cfg.PRSplitMaxLines > 0 && changed > cfg.PRSplitMaxLines {
+		reasons = append(reasons, fmt.Sprintf("%s changed, over pr.split.max_lines %d", plural(changed, "line"), cfg.PRSplitMaxLines))
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// offerSplit suggests --split when diff is over the pr.split limits and the
+// branch has commits to split. Interactively it asks whether to split
+// instead; otherwise it only prints the suggestion.
+func offerSplit(cmd *cobra.Command, cfg *config.Config, diff string, commits int) (bool, error) {
+	reason := splitReason(cfg, diff)
+	if reason == "" || commits < 2 {
+		return false, nil
+	}
+	for _, flag := range splitConflicts {
+		if cmd.Flags().Changed(flag) {
+			return false, nil
+		}
+	}
+
+	if prYes || prDryRun || actionMode || !ui.IsInteractive() {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings([]string{
+			fmt.Sprintf("this branch is hard to review as one pull request (%s); gelf pr create --split proposes stacked pull requests instead", reason),
+		}))
+		return false, nil
+	}
+	return ui.PromptYesNoStyledWithWriter(i18n.T("pr.split_offer", reason), cmd.ErrOrStderr())
+}
+
+// runPRSplit asks the model to partition the commits of the branch into
+// stacked pull requests, shows the proposal and, once confirmed, creates a
+// branch per part by cherry-picking its commits, pushes them and opens a
+// draft pull request for each, based on the one before. With --plan-only or
+// --dry-run it stops after printing the proposal.
+func runPRSplit(ctx context.Context, cmd *cobra.Command, run prSplitRun) error {
+	planOnly := prSplitPlanOnly || prDryRun
+	if git.IsJujutsu() {
+		return fmt.Errorf("--split is not supported in jj repositories")
+	}
+	if !planOnly {
+		if prNoPush {
+			return fmt.Errorf("--split pushes a branch per pull request and cannot be combined with --no-push")
+		}
+		if headRef(run.headBranch, run.headOwners, run.baseOwner) != run.headBranch {
+			return fmt.Errorf("--split stacks pull requests on each other's branches, which must be in %s; push the branch there instead of to a fork", run.plan.BaseRepo)
+		}
+	}
+
+	commits := parseSplitCommits(run.input.CommitLog)
+	if len(commits) < 2 {
+		fmt.Fprintln(cmd.ErrOrStderr(), "The branch has a single commit; there is nothing to split")
+		return finishPRCreate(cmd, prCreateResult{Action: prActionNothingToDo}, ExitNothingToDo)
+	}
+	files, err := git.GetCommitFiles(run.baseRef, "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to list the files of each commit: %w", err)
+	}
+	splitInput := ai.SplitInput{
+		BaseBranch:   run.plan.BaseBranch,
+		HeadBranch:   run.headBranch,
+		Commits:      run.redactor.Apply(describeSplitCommits(commits, files)),
+		DiffStat:     run.input.DiffStat,
+		Language:     run.cfg.PRTitleLanguage,
+		Instructions: run.input.Instructions,
+		Context:      run.input.Context,
+	}
+	reporter := ui.NewReporter(cmd.ErrOrStderr())
+	run.generator = run.generator.WithReporter(reporter)
+	run.splitter, err = ai.NewProvider(ctx, run.cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create AI client: %w", err)
+	}
+	defer run.splitter.Close()
+	run.splitter.SetReporter(reporter)
+
+	var parts []*splitPart
+	for {
+		parts, err = proposeSplit(ctx, cmd, run, splitInput, commits)
+		if err != nil {
+			return err
+		}
+		if len(parts) < 2 {
+			fmt.Fprintln(cmd.ErrOrStderr(), "The model found no useful split; the branch is best reviewed as one pull request")
+			return finishPRCreate(cmd, prCreateResult{Action: prActionNothingToDo}, ExitNothingToDo)
+		}
+
+		if planOnly {
+			fmt.Fprintln(cmd.OutOrStdout(), renderSplitPlan(run, parts))
+			return nil
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n\n", renderSplitPlan(run, parts))
+		if prYes {
+			break
+		}
+
+		choice, confirmed, err := ui.PromptSelectWithWriter(i18n.T("pr.split_prompt"), []string{
+			i18n.T("pr.split_create", len(parts)),
+			i18n.T("pr.split_retry"),
+			i18n.T("pr.split_cancel"),
+		}, cmd.ErrOrStderr())
+		if err != nil {
+			return err
+		}
+		if !confirmed || choice == 2 {
+			return finishPRCreate(cmd, prCreateResult{Action: prActionDeclined}, ExitDeclined)
+		}
+		if choice == 0 {
+			break
+		}
+	}
+
+	// Creating branches, pushing and gh pr create must not interleave with
+	// another gelf process working on the same repository.
+	release, err := acquireRepoLock(cmd, prWait)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	stack := make([]git.StackPart, len(parts))
+	branches := make([]string, len(parts))
+	for i, part := range parts {
+		stack[i] = git.StackPart{Branch: part.Branch}
+		for _, commit := range part.Commits {
+			stack[i].Commits = append(stack[i].Commits, commit.SHA)
+		}
+		branches[i] = part.Branch
+	}
+	stopSpinner := ui.StartSpinner("Cherry-picking commits onto the split branches...", cmd.ErrOrStderr())
+	err = git.CreateStack(run.baseRef, stack, run.headBranch)
+	stopSpinner()
+	if err != nil {
+		return err
+	}
+	if !git.SameTree(branches[len(branches)-1], run.headBranch) {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings([]string{
+			fmt.Sprintf("%s does not end up with the same content as %s; the reordered commits changed the result", branches[len(branches)-1], run.headBranch),
+		}))
+	}
+
+	stopSpinner = ui.StartSpinner(i18n.T("pr.pushing_branches"), cmd.ErrOrStderr())
+	err = git.PushBranches(run.remote, branches)
+	stopSpinner()
+	if err != nil {
+		return err
+	}
+
+	for i := range parts {
+		if err := createSplitPart(ctx, cmd, run, parts, i); err != nil {
+			return fmt.Errorf("%w; the pull requests created so far are kept", err)
+		}
+	}
+	// The later parts had no numbers when the earlier ones were created.
+	for i, part := range parts {
+		if part.Number == 0 {
+			continue
+		}
+		content := &ai.PullRequestContent{Title: part.Title, Body: stackSection(run.headBranch, parts, i) + "\n\n" + part.Body}
+		ghCmd, cleanup, err := ghPRCommand(ctx, &prPlan{Update: part.Number}, content)
+		if err != nil {
+			return err
+		}
+		_, ghErr, err := runCommandWithSpinnerCapture(ghCmd, i18n.T("pr.linking", part.Number), cmd.ErrOrStderr())
+		cleanup()
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings([]string{
+				fmt.Sprintf("failed to list the stack in #%d: %v %s", part.Number, err, strings.TrimSpace(ghErr)),
+			}))
+		}
+	}
+
+	repoRoot, _ := git.GetRepoRoot()
+	rows := make([][]string, 0, len(parts))
+	for i, part := range parts {
+		rows = append(rows, []string{fmt.Sprintf("%d", i+1), part.Branch, part.URL})
+		if repoRoot != "" && part.Number > 0 {
+			_ = state.RecordPullRequest(repoRoot, part.Number, prActionCreated)
+		}
+		runPostCreate(cmd, run.cfg, prCreateResult{Action: prActionCreated, Number: part.Number, URL: part.URL, Title: part.Title, Draft: true}, part.Branch)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s\n", ui.RenderSuccessHeader(i18n.T("pr.split_created", len(parts))))
+	fmt.Fprint(cmd.OutOrStdout(), ui.RenderTable([]string{"PART", "BRANCH", "PULL REQUEST"}, rows))
+	return nil
+}
+
+// proposeSplit asks the model for a split and maps it onto commits, asking
+// again when the proposal does not use every commit exactly once.
+func proposeSplit(ctx context.Context, cmd *cobra.Command, run prSplitRun, input ai.SplitInput, commits []splitCommit) ([]*splitPart, error) {
+	var err error
+	for attempt := 1; attempt <= splitProposalAttempts; attempt++ {
+		var groups []ai.SplitGroup
+		groups, err = run.splitter.ProposeSplit(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		var parts []*splitPart
+		parts, err = splitParts(groups, commits, run.headBranch)
+		if err == nil {
+			return parts, nil
+		}
+		if attempt < splitProposalAttempts {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings([]string{"ignoring the proposed split: " + err.Error()}))
+		}
+	}
+	return nil, fmt.Errorf("the model did not propose a usable split: %w", err)
+}
+
+// parseSplitCommits reads a commit log of "<sha> <subject>" lines.
+func parseSplitCommits(commitLog string) []splitCommit {
+	var commits []splitCommit
+	for _, line := range strings.Split(strings.TrimSpace(commitLog), "\n") {
+		sha, subject, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if sha != "" {
+			commits = append(commits, splitCommit{SHA: sha, Subject: subject})
+		}
+	}
+	return commits
+}
+
+// describeSplitCommits lists the commits for the split prompt, each with the
+// files it changes.
+func describeSplitCommits(commits []splitCommit, files map[string][]string) string {
+	var lines []string
+	for _, commit := range commits {
+		lines = append(lines, commit.SHA+" "+commit.Subject)
+		for _, file := range files[commit.SHA] {
+			lines = append(lines, "    "+file)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splitParts turns the proposed groups into parts named after headBranch.
+// Every commit must be in exactly one group; SHAs may be longer or shorter
+// than the ones given. Commits keep their original order within a part.
+func splitParts(groups []ai.SplitGroup, commits []splitCommit, headBranch string) ([]*splitPart, error) {
+	position := map[string]int{}
+	var parts []*splitPart
+	for _, group := range groups {
+		part := &splitPart{Title: group.Title}
+		for _, sha := range group.Commits {
+			index := matchCommit(commits, strings.TrimSpace(sha))
+			if index < 0 {
+				return nil, fmt.Errorf("unknown commit %q", sha)
+			}
+			if _, seen := position[commits[index].SHA]; seen {
+				return nil, fmt.Errorf("commit %s is in more than one group", commits[index].SHA)
+			}
+			position[commits[index].SHA] = index
+			part.Commits = append(part.Commits, commits[index])
+		}
+		if len(part.Commits) == 0 {
+			continue
+		}
+		sort.Slice(part.Commits, func(i, j int) bool {
+			return position[part.Commits[i].SHA] < position[part.Commits[j].SHA]
+		})
+		parts = append(parts, part)
+	}
+	for _, commit := range commits {
+		if _, seen := position[commit.SHA]; !seen {
+			return nil, fmt.Errorf("commit %s %s is in no group", commit.SHA, commit.Subject)
+		}
+	}
+
+	for i, part := range parts {
+		part.Branch = fmt.Sprintf("%s-part-%d", headBranch, i+1)
+	}
+	return parts, nil
+}
+
+// matchCommit returns the index of the commit sha abbreviates or extends,
+// or -1 when there is none or more than one.
+func matchCommit(commits []splitCommit, sha string) int {
+	if len(sha) < 4 {
+		return -1
+	}
+	match := -1
+	for i, commit := range commits {
+		if strings.HasPrefix(commit.SHA, sha) || strings.HasPrefix(sha, commit.SHA) {
+			if match >= 0 {
+				return -1
+			}
+			match = i
+		}
+	}
+	return match
+}
+
+// renderSplitPlan describes the proposed stack: each part with its branch,
+// what it is based on, its title and its commits.
+func renderSplitPlan(run prSplitRun, parts []*splitPart) string {
+	lines := []string{fmt.Sprintf("Proposed split of %s into %d stacked draft pull requests:", run.headBranch, len(parts))}
+	base := run.plan.BaseBranch
+	for i, part := range parts {
+		lines = append(lines,
+			"",
+			fmt.Sprintf("  %d. %s", i+1, part.Title),
+			fmt.Sprintf("     %s → %s", part.Branch, base),
+		)
+		for _, commit := range part.Commits {
+			lines = append(lines, fmt.Sprintf("       %s %s", commit.SHA, commit.Subject))
+		}
+		base = part.Branch
+	}
+	return strings.Join(lines, "\n")
+}
+
+// createSplitPart generates the description of parts[index] from its own
+// commits and opens it as a draft based on the part before it, or on the
+// base branch for the first part.
+func createSplitPart(ctx context.Context, cmd *cobra.Command, run prSplitRun, parts []*splitPart, index int) error {
+	part := parts[index]
+	baseBranch, baseRef := run.plan.BaseBranch, run.baseRef
+	if index > 0 {
+		baseBranch, baseRef = parts[index-1].Branch, parts[index-1].Branch
+	}
+
+	commitLog, err := git.GetCommitLog(baseRef, part.Branch)
+	if err != nil {
+		return fmt.Errorf("failed to get commit log of %s: %w", part.Branch, err)
+	}
+	diffStat, err := git.GetCommittedDiffStat(baseRef, part.Branch)
+	if err != nil {
+		return fmt.Errorf("failed to get diff stat of %s: %w", part.Branch, err)
+	}
+	diff, err := git.GetCommittedDiff(baseRef, part.Branch)
+	if err != nil {
+		return fmt.Errorf("failed to get changes of %s: %w", part.Branch, err)
+	}
+	classification := git.ClassifyDiff(diff)
+	diff, _ = diffreport.Filter(diff, run.redactor, prDiffOptions(run.cfg))
+
+	input := run.input
+	input.BaseBranch = baseBranch
+	input.HeadBranch = part.Branch
+	input.CommitLog = run.redactor.Apply(commitLog)
+	input.DiffStat = run.redactor.Apply(diffStat)
+	input.Diff = diff
+	input.FileCategories = classification.String()
+	input.DominantCategory = string(classification.Dominant)
+	input.DocsOnly = classification.Only(git.CategoryDocs)
+	input.DependencyUpdates = ""
+	input.DependencySection = ""
+	input.Title = part.Title
+	input.Prompt = ""
+
+	content, err := generatePRContent(ctx, run.generator, input)
+	if err != nil {
+		return err
+	}
+	content.Body = mdwrap.Wrap(content.Body, run.cfg.PRWrap)
+	attributeContent(run.cfg, content, input)
+	if warnings := fitPRContent(content); len(warnings) > 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings(warnings))
+	}
+	part.Body = content.Body
+	content.Body = stackSection(run.headBranch, parts, index) + "\n\n" + part.Body
+
+	plan := *run.plan
+	plan.BaseBranch = baseBranch
+	plan.BaseRef = ""
+	plan.HeadRef = part.Branch
+	plan.Draft = true
+	plan.SizeLabel = ""
+	plan.Update = 0
+	ghCmd, cleanup, err := ghPRCommand(ctx, &plan, content)
+	if err != nil {
+		return err
+	}
+	ghOut, ghErr, err := runCommandWithSpinnerCapture(ghCmd, i18n.T("pr.creating_part", index+1, len(parts)), cmd.ErrOrStderr())
+	cleanup()
+	if err != nil {
+		if strings.TrimSpace(ghErr) != "" {
+			fmt.Fprint(cmd.ErrOrStderr(), ghErr)
+		}
+		return fmt.Errorf("failed to create the pull request for %s: %w", part.Branch, err)
+	}
+
+	part.URL, part.Number = github.FindPullRequestURL(strings.TrimSpace(ghOut+"\n"+ghErr), run.forgeHost)
+	if part.URL == "" {
+		if found, err := github.FindPullRequest(ctx, run.plan.BaseRepo, part.Branch, run.headOwners); err == nil && found != nil {
+			part.URL, part.Number = found.URL, found.Number
+		}
+	}
+	return nil
+}
+
+// stackSection lists the pull requests of a split in merge order, pointing
+// at the one the section is added to. Parts without a number yet are named
+// by title.
+func stackSection(headBranch string, parts []*splitPart, current int) string {
+	lines := []string{fmt.Sprintf("**Stack** (split from `%s`, merge in order):", headBranch), ""}
+	for i, part := range parts {
+		entry := part.Title
+		if part.Number > 0 {
+			entry = fmt.Sprintf("#%d", part.Number)
+		}
+		if i == current {
+			entry += " ← this pull request"
+		}
+		lines = append(lines, fmt.Sprintf("%d. %s", i+1, entry))
+	}
+	return strings.Join(lines, "\n")
+}
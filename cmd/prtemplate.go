@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/github"
+	"github.com/EkeMinusYou/gelf/internal/state"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// templateTimeoutMemory is how long a timed out org template lookup is
+// skipped on later runs.
+const templateTimeoutMemory = time.Hour
+
+// defaultBranchTemplateMemory is how long the template found (or not) on the
+// base repository's default branch is reused.
+const defaultBranchTemplateMemory = 24 * time.Hour
+
+// templateLookup resolves the pull request template in the background, so
+// that the org lookup overlaps with collecting the diff.
+type templateLookup struct {
+	done     chan struct{}
+	template *github.PullRequestTemplate
+	// timedOut is set when the org lookup ran out of template.lookup_timeout
+	// now, skipped when it did so on a recent run and was not tried.
+	timedOut bool
+	skipped  bool
+	err      error
+	// defaultBranchErr is why the lookup on the base repository's default
+	// branch failed; that lookup is a last resort, so it does not fail the
+	// whole lookup.
+	defaultBranchErr error
+}
+
+func startTemplateLookup(ctx context.Context, cfg *config.Config, repoRoot, token string, repo *github.RepoInfo) *templateLookup {
+	lookup := &templateLookup{done: make(chan struct{})}
+	lookup.skipped, _ = state.TemplateTimedOut(repoRoot, repo.Owner, templateTimeoutMemory)
+
+	go func() {
+		defer close(lookup.done)
+		lookup.template, lookup.timedOut, lookup.err = github.FindPullRequestTemplateWithMerge(ctx, repoRoot, token, repo.Owner, github.TemplateOptions{
+			Mode:       cfg.PRTemplateMerge,
+			OrgTimeout: cfg.TemplateTimeout,
+			SkipOrg:    lookup.skipped,
+		})
+		if lookup.timedOut {
+			_ = state.SaveTemplateTimeout(repoRoot, repo.Owner)
+		}
+		if lookup.err == nil && lookup.template == nil {
+			lookup.template, lookup.defaultBranchErr = findDefaultBranchTemplate(ctx, cfg, repoRoot, token, repo)
+		}
+	}()
+
+	return lookup
+}
+
+// findDefaultBranchTemplate looks for the template on the default branch of
+// repo when neither the checkout nor the org has one: the local branch may
+// predate the template, or the clone may be shallow or partial. The result,
+// including finding nothing, is cached per repository.
+func findDefaultBranchTemplate(ctx context.Context, cfg *config.Config, repoRoot, token string, repo *github.RepoInfo) (*github.PullRequestTemplate, error) {
+	if token == "" || repo.Owner == "" || repo.Name == "" {
+		return nil, nil
+	}
+	fullName := repo.Owner + "/" + repo.Name
+	if cached, _ := state.LoadRemoteTemplate(repoRoot, fullName, defaultBranchTemplateMemory); cached != nil {
+		if cached.Path == "" {
+			return nil, nil
+		}
+		return &github.PullRequestTemplate{Source: "default branch", Path: cached.Path, Content: cached.Content}, nil
+	}
+
+	lookupCtx := ctx
+	if cfg.TemplateTimeout > 0 {
+		var cancel context.CancelFunc
+		lookupCtx, cancel = context.WithTimeout(ctx, cfg.TemplateTimeout)
+		defer cancel()
+	}
+	template, err := github.FindDefaultBranchPullRequestTemplate(lookupCtx, token, repo.Owner, repo.Name)
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(lookupCtx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("timed out after %s", cfg.TemplateTimeout)
+		}
+		return nil, err
+	}
+
+	cached := state.RemoteTemplate{Repo: fullName}
+	if template != nil {
+		cached.Path = template.Path
+		cached.Content = template.Content
+	}
+	_ = state.SaveRemoteTemplate(repoRoot, cached)
+	return template, nil
+}
+
+// wait blocks until the lookup has finished and returns the template. When
+// the org template was left out because of a timeout it says so on stderr.
+func (l *templateLookup) wait(cmd *cobra.Command, cfg *config.Config) (*github.PullRequestTemplate, error) {
+	<-l.done
+	if l.err != nil {
+		return nil, fmt.Errorf("failed to resolve pull request template: %w", l.err)
+	}
+
+	if l.defaultBranchErr != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings([]string{
+			fmt.Sprintf("pull request template lookup on the default branch failed: %v; continuing without a template", l.defaultBranchErr),
+		}))
+	}
+
+	note := ""
+	switch {
+	case l.timedOut:
+		note = fmt.Sprintf("org pull request template lookup timed out after %s", cfg.TemplateTimeout)
+	case l.skipped && (l.template == nil || cfg.PRTemplateMerge != github.TemplateMergeRepo):
+		// In repo mode a repository template means the org lookup would
+		// not have run anyway.
+		note = "org pull request template lookup skipped; it timed out within the last hour"
+	}
+	if note == "" {
+		return l.template, nil
+	}
+	if l.template != nil {
+		note += "; using " + l.template.Describe()
+	} else {
+		note += "; continuing without a template"
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings([]string{note}))
+	return l.template, nil
+}
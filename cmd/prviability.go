@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/github"
+)
+
+// checkPRViability fails before anything is generated when gh is bound to
+// refuse the pull request: the base repository is archived, or the branch
+// still has to be pushed to a repository the user cannot push to. Lookups
+// that fail are not treated as problems, so the check never blocks on its
+// own. --force-generate skips it.
+func checkPRViability(ctx context.Context, baseRepo, pushRepo string, needsPush bool) error {
+	if access, err := github.GetRepoAccess(ctx, baseRepo); err == nil && access.Archived {
+		return fmt.Errorf("%s is archived, so pull requests cannot be opened or edited there (use --force-generate to generate anyway)", baseRepo)
+	}
+
+	if !needsPush || pushRepo == "" {
+		return nil
+	}
+	access, err := github.GetRepoAccess(ctx, pushRepo)
+	if err != nil || access.CanPush() {
+		return nil
+	}
+	permission := "no"
+	if access.Permission != "" {
+		permission = strings.ToLower(access.Permission)
+	}
+	if access.Archived {
+		return fmt.Errorf("the branch has to be pushed to %s, which is archived (use --force-generate to generate anyway)", pushRepo)
+	}
+	return fmt.Errorf("the branch has to be pushed to %s, where you have %s access; fork the repository and push the branch there (use --force-generate to generate anyway)", pushRepo, permission)
+}
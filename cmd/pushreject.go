@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/ai"
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/diffreport"
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	"github.com/EkeMinusYou/gelf/internal/redact"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// pushRejected explains a push the remote rejected. When the remote branch
+// already holds some of the branch's commits, it offers to create the pull
+// request from those instead of failing, and returns the pushed commit if
+// the user agrees. With autoPush nothing is asked and the push error stands.
+func pushRejected(cmd *cobra.Command, status git.PushStatus, remoteName, baseRef, reason, output string, autoPush bool) (string, bool, error) {
+	hint := pushRejectionHint(reason, remoteName)
+
+	// A branch that is behind its remote cannot be described from the
+	// remote's state, since that includes commits we do not have.
+	pushed := ""
+	if reason != git.PushNonFastForward {
+		pushed = pushedCommit(status.RemoteRef, baseRef)
+	}
+	if pushed == "" {
+		return "", false, fmt.Errorf("failed to push branch: %s\n%s", hint, output)
+	}
+
+	if autoPush {
+		return "", false, fmt.Errorf("failed to push branch: %s\n%s\n\nRun without --yes to create the pull request from what is already on %s", hint, output, status.RemoteRef)
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings([]string{"push rejected: " + hint}))
+	prompt := i18n.T("pr.push_rejected_continue", status.RemoteRef, commitCount(baseRef, pushed), commitCount(baseRef, "HEAD"), pushed[:7])
+	confirmed, err := ui.PromptYesNoStyledWithWriter(prompt, cmd.ErrOrStderr())
+	if err != nil {
+		return "", false, err
+	}
+	if !confirmed {
+		return "", false, nil
+	}
+	return pushed, true, nil
+}
+
+// pushRejectionHint says what to do about a push rejected for reason.
+func pushRejectionHint(reason, remoteName string) string {
+	switch reason {
+	case git.PushProtected:
+		return fmt.Sprintf("the branch on %s is protected (required status checks, linear history or other rules). Rebase away merge commits, or push to a branch the rules do not cover", remoteName)
+	case git.PushNonFastForward:
+		return fmt.Sprintf("the branch on %s has commits that are not in your branch. Pull or rebase, then run gelf pr create again", remoteName)
+	default:
+		return fmt.Sprintf("you are not allowed to push to %s. Check your credentials, or push the branch to a fork", remoteName)
+	}
+}
+
+// pushedCommit returns the commit remoteRef points at when it holds part of
+// the local branch: it is an ancestor of HEAD and has commits baseRef does
+// not. Otherwise it returns "".
+func pushedCommit(remoteRef, baseRef string) string {
+	sha, err := git.ResolveCommit(remoteRef)
+	if err != nil || !git.IsAncestor(sha, "HEAD") || git.IsAncestor(sha, baseRef) {
+		return ""
+	}
+	return sha
+}
+
+func commitCount(baseRef, head string) int {
+	log, err := git.GetCommitLog(baseRef, head)
+	if err != nil || log == "" {
+		return 0
+	}
+	return strings.Count(log, "\n") + 1
+}
+
+// narrowPRInput points input at the commits in baseRef..head, redacted like
+// the rest of the input, after the branch could only partly be pushed. It
+// returns the unredacted diff for the stats footer. A prompt edited with
+// --edit-prompt still describes the dropped commits, so it is cleared.
+func narrowPRInput(cfg *config.Config, input *ai.PullRequestInput, redactor *redact.Redactor, baseRef, head string) (string, error) {
+	commitLog, err := git.GetCommitLog(baseRef, head)
+	if err != nil {
+		return "", fmt.Errorf("failed to get commit log: %w", err)
+	}
+	diffStat, err := git.GetCommittedDiffStat(baseRef, head)
+	if err != nil {
+		return "", fmt.Errorf("failed to get diff stat: %w", err)
+	}
+	diff, err := git.GetCommittedDiff(baseRef, head)
+	if err != nil {
+		return "", fmt.Errorf("failed to get diff: %w", err)
+	}
+
+	classification := git.ClassifyDiff(diff)
+	input.Scope = commitScope(cfg, diff)
+	input.Diff, _ = diffreport.Filter(diff, redactor, prDiffOptions(cfg))
+	input.DiffStat = redactor.Apply(diffStat)
+	input.CommitLog = redactor.Apply(commitLog)
+	input.FileCategories = classification.String()
+	input.DominantCategory = string(classification.Dominant)
+	input.DocsOnly = classification.Only(git.CategoryDocs)
+	if !classification.Only(git.CategoryDependency) {
+		input.DependencyUpdates = ""
+		input.DependencySection = ""
+	}
+
+	input.Prompt = ""
+	input.Title = ""
+	if input.Previous == nil && !prWIP && !prRegenTitle && !strings.Contains(commitLog, "\n") {
+		input.Title = singleCommitTitle(cfg, input.CommitLog, input.Scope)
+	}
+	return diff, nil
+}
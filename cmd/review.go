@@ -0,0 +1,578 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/ai"
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/diffreport"
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/github"
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	"github.com/EkeMinusYou/gelf/internal/redact"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/EkeMinusYou/gelf/pkg/gelf"
+	"github.com/spf13/cobra"
+)
+
+var reviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "Review changes with AI",
+	Long: `Asks the model to review a diff and lists its findings by severity.
+
+On a branch with an upstream, the review covers what a pull request from the
+branch would contain: the commits since it forked from the base branch, found
+the way gelf pr create finds it (--pr-range). Otherwise, or with --staged,
+the staged changes are reviewed. --ref reviews the current branch against a
+base ref, and --commit reviews individual commits: each commit is reviewed
+on its own, with its message as context, under its own heading. --commit can
+be repeated and accepts ranges such as main..HEAD.
+
+--against-template also checks the change against the repository's pull
+request template, found the way gelf pr create finds it, and ends the review
+with the template's requirements the change does not meet yet. Without a
+template the review is unchanged.
+
+--compare takes two branches implementing the same change and compares
+their approaches instead: each branch's diff against the commit both fork
+from is sent, and the model weighs correctness, complexity and risk, naming
+the branch every observation is about.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if reviewCompare {
+			return cobra.ExactArgs(2)(cmd, args)
+		}
+		return cobra.NoArgs(cmd, args)
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if !reviewCompare || len(args) >= 2 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeWithin(completeRefs)(cmd, args, toComplete)
+	},
+	RunE: runReview,
+}
+
+var (
+	reviewStaged       bool
+	reviewRef          string
+	reviewCommits      []string
+	reviewModel        string
+	reviewLanguage     string
+	reviewRender       bool
+	reviewNoRender     bool
+	reviewShowPrompt   bool
+	reviewAllowSecrets bool
+	reviewTemplate     bool
+	reviewPRRange      bool
+	reviewPager        bool
+	reviewFailOn       string
+
+	reviewCompare bool
+)
+
+func init() {
+	reviewCmd.Flags().BoolVar(&reviewStaged, "staged", false, "Review the staged changes (default without an upstream)")
+	reviewCmd.Flags().BoolVar(&reviewPRRange, "pr-range", false, "Review what a pull request from this branch would contain (default with an upstream)")
+	reviewCmd.Flags().StringVar(&reviewRef, "ref", "", "Review the current branch against this base ref")
+	reviewCmd.RegisterFlagCompletionFunc("ref", completeWithin(completeRefs))
+	reviewCmd.Flags().StringArrayVar(&reviewCommits, "commit", nil, "Review a single commit or a range (repeatable)")
+	reviewCmd.Flags().StringVar(&reviewModel, "model", "", "Override default model for this generation")
+	reviewCmd.RegisterFlagCompletionFunc("model", completeWithin(completeModels))
+	reviewCmd.Flags().StringVar(&reviewLanguage, "language", "", "Language for the review (e.g., english, japanese)")
+	reviewCmd.Flags().BoolVar(&reviewRender, "render", true, "Render the review as markdown")
+	reviewCmd.Flags().BoolVar(&reviewNoRender, "no-render", false, "Stream the raw markdown instead of rendering it")
+	reviewCmd.Flags().BoolVar(&reviewPager, "pager", false, "Show the rendered review in a scrollable view as it streams in")
+	reviewCmd.Flags().BoolVar(&reviewShowPrompt, "show-prompt", false, "Print the prompt that would be sent to the model and exit")
+	reviewCmd.Flags().BoolVar(&reviewAllowSecrets, "allow-secrets", false, "Send the diff even if possible secrets are detected")
+	reviewCmd.Flags().BoolVar(&reviewTemplate, "against-template", false, "Also list what the pull request template requires that the change does not provide")
+	reviewCmd.Flags().BoolVar(&reviewCompare, "compare", false, "Compare the approaches of two branches given as arguments")
+	reviewCmd.Flags().StringVar(&reviewFailOn, "fail-on", "", "Exit with code 6 when a finding has this severity or higher: low, medium or high")
+	reviewCmd.RegisterFlagCompletionFunc("fail-on", cobra.FixedCompletions(reviewSeverities, cobra.ShellCompDirectiveNoFileComp))
+	reviewCmd.MarkFlagsMutuallyExclusive("commit", "staged", "ref", "pr-range", "compare")
+	// Template readiness is about the pull request as a whole, not about
+	// each of its commits.
+	reviewCmd.MarkFlagsMutuallyExclusive("commit", "against-template")
+	reviewCmd.MarkFlagsMutuallyExclusive("compare", "against-template")
+	addActionFlags(reviewCmd)
+
+	rootCmd.AddCommand(reviewCmd)
+}
+
+// reviewSeverities are the severities the model labels findings with,
+// lowest first.
+var reviewSeverities = []string{"low", "medium", "high"}
+
+// severityLabelRegex matches a bold severity label on a list item, such as
+// "- **high** cmd/pr.go:12: ...".
+var severityLabelRegex = regexp.MustCompile(`(?im)^[ \t]*(?:[-*+]|\d{1,9}[.)])[ \t].*?\*\*(high|medium|low)\*\*`)
+
+// highestSeverity returns the index in reviewSeverities of the most severe
+// finding labelled in review, or -1 when no finding is labelled.
+func highestSeverity(review string) int {
+	highest := -1
+	for _, match := range severityLabelRegex.FindAllStringSubmatch(review, -1) {
+		highest = max(highest, slices.Index(reviewSeverities, strings.ToLower(match[1])))
+	}
+	return highest
+}
+
+// checkFailOn fails the command with ExitFindings when review has a finding
+// at or above the --fail-on severity.
+func checkFailOn(cmd *cobra.Command, review string) error {
+	if reviewFailOn == "" {
+		return nil
+	}
+	highest := highestSeverity(review)
+	if highest < slices.Index(reviewSeverities, reviewFailOn) {
+		return nil
+	}
+	fmt.Fprintln(cmd.ErrOrStderr(), warningStyle.Render(i18n.T("review.fail_on", reviewSeverities[highest], reviewFailOn)))
+	return exitWithCode(cmd, ExitFindings, nil)
+}
+
+// errNoReviewChanges is returned by collectReviewTargets when the range to
+// review has no changes.
+var errNoReviewChanges = errors.New("no changes")
+
+// reviewTarget is one diff to review. Title is the heading printed above its
+// findings; it is empty when a single diff is reviewed.
+type reviewTarget struct {
+	Title string
+	Input ai.ReviewInput
+	// Compare is compared instead of Input being reviewed with --compare.
+	Compare *ai.CompareInput
+}
+
+// request converts the target for the gelf API; Input is kept for
+// --show-prompt.
+func (t reviewTarget) request() gelf.ReviewRequest {
+	return gelf.ReviewRequest{
+		Diff:          t.Input.Diff,
+		CommitMessage: t.Input.CommitMessage,
+		Language:      t.Input.Language,
+		Template:      t.Input.Template,
+	}
+}
+
+// prompt returns the prompt the target is sent with.
+func (t reviewTarget) prompt() string {
+	if t.Compare != nil {
+		return ai.BuildComparePrompt(*t.Compare)
+	}
+	return ai.BuildReviewPrompt(t.Input)
+}
+
+// generate reviews or compares the target, streaming the markdown to stream.
+func (t reviewTarget) generate(ctx context.Context, generator *gelf.Generator, stream func(chunk string)) error {
+	if t.Compare != nil {
+		_, err := generator.Compare(ctx, gelf.CompareRequest{
+			BranchA:   t.Compare.BranchA,
+			BranchB:   t.Compare.BranchB,
+			DiffA:     t.Compare.DiffA,
+			DiffB:     t.Compare.DiffB,
+			MergeBase: t.Compare.MergeBase,
+			Language:  t.Compare.Language,
+		}, stream)
+		return err
+	}
+	_, err := generator.Review(ctx, t.request(), stream)
+	return err
+}
+
+func runReview(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if err := setupActionMode(cmd); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if err := applyFlagDefaults(cmd, "review", cfg.Defaults["review"]); err != nil {
+		return err
+	}
+	if reviewFailOn != "" && !slices.Contains(reviewSeverities, reviewFailOn) {
+		return fmt.Errorf("invalid --fail-on %q: must be low, medium or high", reviewFailOn)
+	}
+
+	// The step log shows raw text, and the review output should be the
+	// markdown a later step can post as a comment.
+	if reviewNoRender || actionMode {
+		reviewRender = false
+	}
+
+	modelToUse := cfg.PRModel
+	if reviewModel != "" {
+		modelToUse = reviewModel
+	}
+	cfg.FlashModel = cfg.ResolveModel(modelToUse)
+
+	language := cfg.PRLanguage
+	if reviewLanguage != "" {
+		language, err = flagLanguage(cmd, cfg, "language", reviewLanguage)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Resolve everything to review before any AI call so that bad SHAs and
+	// refs fail fast.
+	endContextGroup := actionGroup(cmd, "Collect context")
+	defer endContextGroup()
+	targets, err := collectReviewTargets(args)
+	if errors.Is(err, errNoReviewChanges) {
+		return exitWithCode(cmd, ExitNothingToDo, err)
+	}
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		fmt.Fprintln(cmd.ErrOrStderr(), warningStyle.Render(i18n.T("commit.no_staged")))
+		return exitWithCode(cmd, ExitNothingToDo, nil)
+	}
+
+	redactor, err := redact.New(cfg.RedactRules)
+	if err != nil {
+		return err
+	}
+	instructions := loadInstructions(cmd)
+	template := ""
+	if reviewTemplate {
+		template = redactor.Apply(reviewPRTemplate(ctx, cmd, cfg))
+	}
+	var diffs []string
+	for i := range targets {
+		// Both diffs of a comparison go through the same filtering as a
+		// single one, so together they stay within what a review sends.
+		if compare := targets[i].Compare; compare != nil {
+			compare.DiffA = filterCompareDiff(cmd, cfg, redactor, compare.BranchA, compare.DiffA)
+			compare.DiffB = filterCompareDiff(cmd, cfg, redactor, compare.BranchB, compare.DiffB)
+			compare.Language = language
+			compare.Instructions = instructions
+			compare.FileOrder = cfg.PromptFileOrder
+			diffs = append(diffs, compare.DiffA, compare.DiffB)
+			continue
+		}
+		var report diffreport.Report
+		targets[i].Input.Diff, report = diffreport.Filter(targets[i].Input.Diff, redactor, prDiffOptions(cfg))
+		printDiffReport(cmd, report)
+		targets[i].Input.CommitMessage = redactor.Apply(targets[i].Input.CommitMessage)
+		targets[i].Input.Language = language
+		targets[i].Input.Instructions = instructions
+		targets[i].Input.Template = template
+		targets[i].Input.FileOrder = cfg.PromptFileOrder
+		diffs = append(diffs, targets[i].Input.Diff)
+	}
+	endContextGroup()
+
+	if reviewShowPrompt {
+		for i, target := range targets {
+			if i > 0 {
+				fmt.Fprintln(cmd.OutOrStdout())
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), target.prompt())
+		}
+		return nil
+	}
+
+	if err := checkSecrets(cmd, cfg, strings.Join(diffs, "\n"), reviewAllowSecrets, false); err != nil {
+		return err
+	}
+
+	generator, err := gelf.NewFromConfig(ctx, cfg, cfg.FlashModel, language)
+	if err != nil {
+		return fmt.Errorf("failed to create AI client: %w", err)
+	}
+	defer generator.Close()
+	generator = generator.WithInstructions(instructions)
+
+	// The review is also collected for --fail-on and the action's step
+	// output.
+	var review strings.Builder
+	out := cmd.OutOrStdout()
+	if !reviewRender {
+		out = io.MultiWriter(out, &review)
+		for i, target := range targets {
+			if i > 0 {
+				fmt.Fprintln(out)
+			}
+			if target.Title != "" {
+				fmt.Fprintf(out, "## %s\n\n", target.Title)
+			}
+			if err := target.generate(ctx, generator, func(chunk string) {
+				fmt.Fprint(out, chunk)
+			}); err != nil {
+				return err
+			}
+			fmt.Fprintln(out)
+		}
+		if err := setActionOutputs(cmd, []actionOutput{{"review", strings.TrimSpace(review.String())}}); err != nil {
+			return err
+		}
+		return checkFailOn(cmd, review.String())
+	}
+
+	// Completed blocks are rendered as they stream in, so long reviews
+	// appear progressively without re-rendering what was already shown.
+	// With --pager they go to a scrollable view instead; closing it early
+	// stops the review.
+	show := func(rendered string) { fmt.Fprint(out, rendered) }
+	var pager *ui.Pager
+	if reviewPager && ui.IsInteractive() {
+		pager, err = ui.StartPager(i18n.T("review.pager_title"))
+		if err != nil {
+			return err
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			<-pager.Done()
+			cancel()
+		}()
+		show = pager.Append
+	}
+	for _, target := range targets {
+		stream, err := ui.NewMarkdownStream(cfg.UseColor())
+		if err != nil {
+			return fmt.Errorf("failed to render markdown: %w", err)
+		}
+		var renderErr error
+		write := func(chunk string) {
+			review.WriteString(chunk)
+			if renderErr != nil {
+				return
+			}
+			rendered, err := stream.Write(chunk)
+			if err != nil {
+				renderErr = err
+				return
+			}
+			show(rendered)
+		}
+
+		if target.Title != "" {
+			write(fmt.Sprintf("## %s\n\n", target.Title))
+		}
+		if err := target.generate(ctx, generator, write); err != nil {
+			if pager != nil {
+				if ctx.Err() != nil {
+					// The pager was closed before the review was done.
+					return nil
+				}
+				pager.Close()
+			}
+			return err
+		}
+		rendered, err := stream.Flush()
+		if renderErr == nil {
+			renderErr = err
+		}
+		if renderErr != nil {
+			if pager != nil {
+				pager.Close()
+			}
+			return fmt.Errorf("failed to render markdown: %w", renderErr)
+		}
+		show(rendered)
+	}
+	if pager != nil {
+		if err := pager.Wait(); err != nil {
+			return err
+		}
+	}
+	return checkFailOn(cmd, review.String())
+}
+
+// collectReviewTargets returns the diffs selected by --compare, --commit,
+// --ref, --pr-range or --staged. It returns no targets when the staged
+// changes are reviewed and there are none, and errNoReviewChanges when a
+// range has none.
+func collectReviewTargets(args []string) ([]reviewTarget, error) {
+	if reviewCompare {
+		target, err := compareTarget(args[0], args[1])
+		if err != nil {
+			return nil, err
+		}
+		return []reviewTarget{target}, nil
+	}
+
+	if len(reviewCommits) > 0 {
+		shas, err := git.ResolveCommits(reviewCommits)
+		if err != nil {
+			return nil, err
+		}
+		targets := make([]reviewTarget, 0, len(shas))
+		for _, sha := range shas {
+			patch, err := git.GetCommitPatch(sha)
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, reviewTarget{
+				Title: fmt.Sprintf("%s %s", patch.SHA, patch.Subject),
+				Input: ai.ReviewInput{Diff: patch.Diff, CommitMessage: patch.Message},
+			})
+		}
+		return targets, nil
+	}
+
+	if reviewRef != "" {
+		diff, err := diffSource{Base: reviewRef}.Diff()
+		if err != nil {
+			return nil, err
+		}
+		if diff == "" {
+			return nil, fmt.Errorf("%w between %s and HEAD", errNoReviewChanges, reviewRef)
+		}
+		return []reviewTarget{{Input: ai.ReviewInput{Diff: diff}}}, nil
+	}
+
+	source, err := reviewSource()
+	if err != nil {
+		return nil, err
+	}
+	diff, err := source.Diff()
+	if err != nil {
+		return nil, err
+	}
+	if source.Base == "" {
+		if diff == "" {
+			return nil, nil
+		}
+		return []reviewTarget{{Input: ai.ReviewInput{Diff: diff}}}, nil
+	}
+
+	commitLog, err := source.CommitLog()
+	if err != nil {
+		return nil, err
+	}
+	if diff == "" || commitLog == "" {
+		return nil, fmt.Errorf("%w between %s and HEAD", errNoReviewChanges, source.Base)
+	}
+	return []reviewTarget{{
+		Title: fmt.Sprintf("%s (%s)", source, plural(strings.Count(commitLog, "\n")+1, "commit")),
+		Input: ai.ReviewInput{Diff: diff},
+	}}, nil
+}
+
+// reviewSource returns what is reviewed without --commit or --ref: with
+// --pr-range, or by default on a branch with an upstream, the range a pull
+// request from the branch would contain; otherwise the staged changes. The
+// default falls back to the staged changes when the base branch cannot be
+// detected or the branch has no commits of its own yet.
+func reviewSource() (diffSource, error) {
+	if reviewStaged {
+		return diffSource{}, nil
+	}
+	if reviewPRRange {
+		source, _, err := detectPRSource()
+		return source, err
+	}
+
+	branch, err := git.GetCurrentBranch()
+	if err != nil || branch == "HEAD" {
+		return diffSource{}, nil
+	}
+	if status, err := git.GetPushStatus(branch); err != nil || !status.HasUpstream {
+		return diffSource{}, nil
+	}
+	source, baseBranch, err := detectPRSource()
+	if err != nil || baseBranch == branch {
+		return diffSource{}, nil
+	}
+	if commitLog, err := source.CommitLog(); err != nil || commitLog == "" {
+		return diffSource{}, nil
+	}
+	return source, nil
+}
+
+// reviewPRTemplate returns the pull request template gelf pr create would
+// use, or "" when there is none. Without gh only the repository's own
+// template is looked for; a failed lookup is a warning, since the review is
+// still useful without it.
+func reviewPRTemplate(ctx context.Context, cmd *cobra.Command, cfg *config.Config) string {
+	repoRoot, err := git.GetRepoRoot()
+	if err != nil {
+		return ""
+	}
+
+	var template *github.PullRequestTemplate
+	repo, repoErr := github.RepoInfoFromGH(ctx)
+	token, tokenErr := github.AuthToken(ctx)
+	if repoErr == nil && tokenErr == nil {
+		template, err = startTemplateLookup(ctx, cfg, repoRoot, token, repo).wait(cmd, cfg)
+	} else {
+		template, _, err = github.FindPullRequestTemplateWithMerge(ctx, repoRoot, "", "", github.TemplateOptions{
+			Mode:    cfg.PRTemplateMerge,
+			SkipOrg: true,
+		})
+	}
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings([]string{fmt.Sprintf("reviewing without the pull request template: %v", err)}))
+		return ""
+	}
+	if template == nil {
+		return ""
+	}
+	return template.Content
+}
+
+// compareTarget returns the comparison of branches a and b: the diff of each
+// against the commit both fork from.
+func compareTarget(a, b string) (reviewTarget, error) {
+	mergeBase, err := git.MergeBase(a, b)
+	if err != nil {
+		return reviewTarget{}, err
+	}
+	diffA, err := git.GetCommittedDiff(mergeBase, a)
+	if err != nil {
+		return reviewTarget{}, fmt.Errorf("failed to get changes of %s: %w", a, err)
+	}
+	diffB, err := git.GetCommittedDiff(mergeBase, b)
+	if err != nil {
+		return reviewTarget{}, fmt.Errorf("failed to get changes of %s: %w", b, err)
+	}
+	for _, side := range []struct{ branch, other, diff string }{{a, b, diffA}, {b, a, diffB}} {
+		if side.diff == "" {
+			return reviewTarget{}, fmt.Errorf("%s has no changes since it forked from %s; there is nothing to compare", side.branch, side.other)
+		}
+	}
+	if diffA == diffB {
+		return reviewTarget{}, fmt.Errorf("%s and %s make the same changes; there is nothing to compare", a, b)
+	}
+
+	short := mergeBase
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	return reviewTarget{
+		Title: fmt.Sprintf("%s vs %s (since %s)", a, b, short),
+		Compare: &ai.CompareInput{
+			BranchA:   a,
+			DiffA:     diffA,
+			BranchB:   b,
+			DiffB:     diffB,
+			MergeBase: short,
+		},
+	}, nil
+}
+
+// filterCompareDiff runs the diff of branch through the filtering pipeline
+// and, with --verbose, reports what it did under the branch's name.
+func filterCompareDiff(cmd *cobra.Command, cfg *config.Config, redactor *redact.Redactor, branch, diff string) string {
+	diff, report := diffreport.Filter(diff, redactor, prDiffOptions(cfg))
+	if verbose && len(report) > 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s:\n", branch)
+	}
+	printDiffReport(cmd, report)
+	return diff
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHighestSeverity(t *testing.T) {
+	tests := []struct {
+		name   string
+		review string
+		want   int
+	}{
+		{"no findings", "Nothing worth changing.", -1},
+		{"one finding", "- **low** cmd/pr.go:12: unused variable", 0},
+		{"highest of several", "- **low** a\n* **HIGH** b\n1. **medium** c\n", 2},
+		{"label after the location", "- cmd/pr.go:12 (**medium**): error ignored", 1},
+		{"indented sub-item", "- cmd/pr.go\n  - **high** nil dereference\n", 2},
+		{"prose is not a finding", "There are no **high** severity findings.\n- **low** typo", 0},
+		{"unknown label", "- **critical** panic", -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highestSeverity(tt.review); got != tt.want {
+				t.Errorf("highestSeverity() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// withReviewModel points gelf at an OpenAI-compatible server that streams
+// review as the answer to any prompt.
+func withReviewModel(t *testing.T, review string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, line := range strings.SplitAfter(review, "\n") {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", line)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+
+	dir := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "gelf")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "gelf.yml"), []byte("provider: openai\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	t.Setenv("OPENAI_BASE_URL", server.URL)
+}
+
+// executeReview runs gelf review with args and returns its exit code.
+func executeReview(t *testing.T, args ...string) int {
+	t.Helper()
+	rootCmd.SetArgs(append([]string{"review"}, args...))
+	rootCmd.SetOut(&strings.Builder{})
+	rootCmd.SetErr(&strings.Builder{})
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		for _, name := range []string{"fail-on", "no-render", "ref"} {
+			flag := reviewCmd.Flags().Lookup(name)
+			flag.Value.Set(flag.DefValue)
+			flag.Changed = false
+		}
+		reviewRender = true
+	})
+	return ExitCode(rootCmd.Execute())
+}
+
+func TestReviewExitCodes(t *testing.T) {
+	const review = "- **medium** retry.go:1: the package has no tests\n- **low** retry.go:1: missing doc comment\n"
+	tests := []struct {
+		name   string
+		staged bool
+		args   []string
+		want   int
+	}{
+		{"nothing staged", false, nil, ExitNothingToDo},
+		{"empty range", false, []string{"--ref", "HEAD"}, ExitNothingToDo},
+		{"invalid severity", true, []string{"--fail-on", "severe"}, ExitError},
+		{"findings without --fail-on", true, nil, ExitOK},
+		{"below the threshold", true, []string{"--fail-on", "high"}, ExitOK},
+		{"at the threshold", true, []string{"--fail-on", "medium"}, ExitFindings},
+		{"above the threshold", true, []string{"--fail-on", "low"}, ExitFindings},
+		{"raw markdown", true, []string{"--no-render", "--fail-on", "medium"}, ExitFindings},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestRepo(t)
+			withReviewModel(t, review)
+			if tt.staged {
+				if err := os.WriteFile("retry.go", []byte("package retry\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				runTestGit(t, "add", "retry.go")
+			}
+			if got := executeReview(t, tt.args...); got != tt.want {
+				t.Errorf("exit code = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
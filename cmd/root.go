@@ -1,11 +1,16 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
 
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	"github.com/EkeMinusYou/gelf/internal/timing"
+	"github.com/EkeMinusYou/gelf/internal/ui"
 	"github.com/spf13/cobra"
 )
 
@@ -17,6 +22,19 @@ var rootCmd = &cobra.Command{
 	Short: "AI-powered Git commit message generator using Vertex AI (Gemini)",
 	Long: `gelf is a CLI tool that generates Git commit messages using Vertex AI (Gemini).
 It analyzes staged changes and creates appropriate commit messages through an interactive TUI.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Commands report configuration errors themselves; here a broken
+		// file only means the interface stays in the locale's language.
+		uiLanguage := ""
+		if cfg, err := config.Load(); err == nil {
+			uiLanguage = cfg.UILanguage
+			setUsageTracker(cmd, cfg)
+			if len(cfg.Warnings) > 0 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", ui.FormatWarnings(cfg.Warnings))
+			}
+		}
+		i18n.SetLanguage(i18n.Resolve(uiLanguage))
+	},
 }
 
 var versionCmd = &cobra.Command{
@@ -45,11 +63,35 @@ func getGitCommitHash() string {
 	return strings.TrimSpace(string(output))
 }
 
+// verbose prints a breakdown of the time spent in git, gh and model calls.
+var verbose bool
+
 func Execute() error {
-	return rootCmd.Execute()
+	err := rootCmd.Execute()
+	var panicErr *ui.PanicError
+	if errors.As(err, &panicErr) {
+		// The error itself was printed by cobra; the stack is what a bug
+		// report needs.
+		fmt.Fprintf(os.Stderr, "\n%s", panicErr.Stack)
+	}
+	var exitErr *exitError
+	if actionMode && err != nil && !(errors.As(err, &exitErr) && exitErr.err == nil) {
+		printActionError(err)
+	}
+	if verbose {
+		timing.Write(os.Stderr)
+	}
+	if traceErr := timing.WriteTrace(); traceErr != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", traceErr)
+	}
+	return err
 }
 
 func init() {
+	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Print how long git, gh and model calls took")
+	rootCmd.PersistentFlags().BoolVar(&verbose, "timings", false, "Alias for --verbose")
+	rootCmd.PersistentFlags().BoolVar(&overrideBudget, "override-budget", false, "Make model calls even when a configured budget is used up")
+
 	rootCmd.AddCommand(commitCmd)
 	rootCmd.AddCommand(prCmd)
 	rootCmd.AddCommand(versionCmd)
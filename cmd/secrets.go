@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	"github.com/EkeMinusYou/gelf/internal/secrets"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// checkSecrets scans diff for secrets before it is sent to the AI. When
+// something is found the masked lines are shown and the user has to confirm,
+// unless allow is set. With noPrompt (e.g. --yes) the command fails instead
+// of asking.
+func checkSecrets(cmd *cobra.Command, cfg *config.Config, diff string, allow bool, noPrompt bool) error {
+	scanner, err := secrets.NewScanner(cfg.SecretPatterns, cfg.SecretEntropy)
+	if err != nil {
+		return err
+	}
+
+	findings := scanner.Scan(diff)
+	if len(findings) == 0 {
+		return nil
+	}
+
+	out := cmd.ErrOrStderr()
+	fmt.Fprintln(out, warningStyle.Render(i18n.T("secrets.found", len(findings))))
+	for _, finding := range findings {
+		fmt.Fprintf(out, "  %s [%s]: %s\n", finding.File, finding.Rule, finding.Masked())
+	}
+	fmt.Fprintln(out)
+
+	if allow {
+		return nil
+	}
+	if noPrompt || !ui.IsInteractive() {
+		return fmt.Errorf("refusing to send a diff containing possible secrets to the AI; use --allow-secrets to override")
+	}
+
+	confirmed, err := ui.PromptYesNoStyledWithWriter(i18n.T("secrets.confirm"), out)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out)
+	if !confirmed {
+		return exitWithCode(cmd, ExitDeclined, nil)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/redact"
+	"github.com/EkeMinusYou/gelf/internal/server"
+	"github.com/EkeMinusYou/gelf/pkg/gelf"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve generation over a local HTTP API for editor integrations",
+	Long: `Starts a JSON HTTP API on a loopback address so that editor plugins can
+reuse gelf's configuration and prompts without starting gelf for every
+request:
+
+  POST /v1/commit-message  {"diff"} -> {"message", "usage"}
+  POST /v1/pr-content      {"diff", "commit_log", ...} -> {"title", "body", "usage"}
+  POST /v1/review          {"diff"} -> server-sent "chunk" events, then "done"
+
+The first line on stdout is {"url": ..., "token": ...}. Every request must
+send "Authorization: Bearer <token>". Ctrl+C stops the server after the
+requests in flight finish.`,
+	RunE: runServe,
+}
+
+var serveListen string
+
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:0", "Loopback address to listen on (port 0 picks a free port)")
+
+	rootCmd.AddCommand(serveCmd)
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := applyFlagDefaults(cmd, "serve", cfg.Defaults["serve"]); err != nil {
+		return err
+	}
+
+	if err := checkLoopback(serveListen); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	instructions := loadInstructions(cmd)
+	commitGenerator, err := gelf.NewFromConfig(ctx, cfg, cfg.ResolveModel(cfg.CommitModel), cfg.CommitLanguage)
+	if err != nil {
+		return fmt.Errorf("failed to create AI client: %w", err)
+	}
+	defer commitGenerator.Close()
+	commitGenerator = commitGenerator.WithInstructions(instructions)
+	prGenerator, err := gelf.NewFromConfig(ctx, cfg, cfg.ResolveModel(cfg.PRModel), cfg.PRLanguage)
+	if err != nil {
+		return fmt.Errorf("failed to create AI client: %w", err)
+	}
+	defer prGenerator.Close()
+	prGenerator = prGenerator.WithInstructions(instructions)
+
+	redactor, err := redact.New(cfg.RedactRules)
+	if err != nil {
+		return err
+	}
+
+	token, err := newServeToken()
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", serveListen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", serveListen, err)
+	}
+
+	httpServer := &http.Server{
+		Handler: server.New(server.Options{
+			Commit: commitGenerator,
+			PR:     prGenerator,
+			Token:  token,
+			Redact: redactor.Apply,
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	encoded, err := json.Marshal(struct {
+		URL   string `json:"url"`
+		Token string `json:"token"`
+	}{URL: "http://" + listener.Addr().String(), Token: token})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), string(encoded))
+	fmt.Fprintf(cmd.ErrOrStderr(), "Listening on http://%s (Ctrl+C to stop)\n", listener.Addr())
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- httpServer.Serve(listener) }()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintln(cmd.ErrOrStderr(), "Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to shut down: %w", err)
+	}
+	return nil
+}
+
+// checkLoopback refuses listen addresses other than loopback ones, so the
+// API is never reachable from other machines.
+func checkLoopback(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid --listen address %q: %w", address, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("--listen must be a loopback address such as 127.0.0.1:0, got %q", address)
+}
+
+func newServeToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
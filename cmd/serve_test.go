@@ -0,0 +1,29 @@
+package cmd
+
+import "testing"
+
+func TestCheckLoopback(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{"127.0.0.1:0", false},
+		{"127.0.0.2:8080", false},
+		{"[::1]:0", false},
+		{"localhost:0", false},
+		{"0.0.0.0:0", true},
+		{"[::]:0", true},
+		{":0", true},
+		{"192.168.1.10:0", true},
+		{"[fe80::1]:0", true},
+		{"example.com:0", true},
+		{"127.0.0.1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			if err := checkLoopback(tt.address); (err != nil) != tt.wantErr {
+				t.Errorf("checkLoopback(%q) = %v, want error %v", tt.address, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/ai"
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/diffreport"
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	"github.com/EkeMinusYou/gelf/internal/redact"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var stashCmd = &cobra.Command{
+	Use:   "stash",
+	Short: "Stash changes under a generated description and browse stashes",
+}
+
+var stashPushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Stash the working tree changes with an AI-generated description",
+	Long: `Describes the uncommitted changes, staged or not, in one line and runs
+git stash push -m with it. With --include-untracked untracked files are
+described and stashed too.`,
+	Args: cobra.NoArgs,
+	RunE: runStashPush,
+}
+
+var stashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Browse stashes and apply or pop one",
+	Long: `Lists the stashes with their age, the files and lines they change and their
+description. In a terminal a stash can be picked from the list and applied or
+popped after confirmation; otherwise the list is printed as a table.`,
+	Args: cobra.NoArgs,
+	RunE: runStashList,
+}
+
+var (
+	stashUntracked    bool
+	stashModel        string
+	stashLanguage     string
+	stashDryRun       bool
+	stashShowPrompt   bool
+	stashAllowSecrets bool
+	stashYes          bool
+	stashWait         bool
+)
+
+func init() {
+	stashPushCmd.Flags().BoolVarP(&stashUntracked, "include-untracked", "u", false, "Describe and stash untracked files too")
+	stashPushCmd.Flags().StringVar(&stashModel, "model", "", "Override default model for this generation")
+	stashPushCmd.RegisterFlagCompletionFunc("model", completeWithin(completeModels))
+	stashPushCmd.Flags().StringVar(&stashLanguage, "language", "", "Language for the stash description (e.g., english, japanese)")
+	stashPushCmd.Flags().BoolVar(&stashDryRun, "dry-run", false, "Print the generated description without stashing")
+	stashPushCmd.Flags().BoolVar(&stashShowPrompt, "show-prompt", false, "Print the prompt that would be sent to the model and exit")
+	stashPushCmd.Flags().BoolVar(&stashAllowSecrets, "allow-secrets", false, "Send the diff even if it appears to contain secrets")
+	stashPushCmd.Flags().BoolVar(&stashYes, "yes", false, "Stash with the generated description without confirmation")
+	stashPushCmd.Flags().BoolVar(&stashWait, "wait", false, "Wait for another gelf operation in this repository to finish instead of failing")
+	stashListCmd.Flags().BoolVar(&stashWait, "wait", false, "Wait for another gelf operation in this repository to finish instead of failing")
+
+	stashCmd.AddCommand(stashPushCmd)
+	stashCmd.AddCommand(stashListCmd)
+	rootCmd.AddCommand(stashCmd)
+}
+
+func runStashPush(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if err := applyFlagDefaults(cmd, "stash-push", cfg.Defaults["stash-push"]); err != nil {
+		return err
+	}
+
+	if stashModel != "" {
+		cfg.FlashModel = cfg.ResolveModel(stashModel)
+	}
+	if stashLanguage != "" {
+		cfg.CommitLanguage, err = flagLanguage(cmd, cfg, "language", stashLanguage)
+		if err != nil {
+			return err
+		}
+	}
+
+	diff, err := git.GetUncommittedDiff()
+	if err != nil {
+		return fmt.Errorf("failed to get uncommitted changes: %w", err)
+	}
+	untracked, err := git.GetUntrackedFiles()
+	if err != nil {
+		return err
+	}
+	if stashUntracked && len(untracked) > 0 {
+		untrackedDiff, err := git.GetUntrackedDiff(untracked)
+		if err != nil {
+			return err
+		}
+		diff += untrackedDiff
+	}
+
+	if strings.TrimSpace(diff) == "" {
+		if len(untracked) > 0 {
+			return exitWithCode(cmd, ExitNothingToDo, fmt.Errorf("no tracked changes to stash; use --include-untracked to stash the %d untracked file(s)", len(untracked)))
+		}
+		return exitWithCode(cmd, ExitNothingToDo, fmt.Errorf("no local changes to stash"))
+	}
+
+	redactor, err := redact.New(cfg.RedactRules)
+	if err != nil {
+		return err
+	}
+	diff, report := diffreport.Filter(diff, redactor, diffreport.Options{})
+	printDiffReport(cmd, report)
+
+	input := ai.CommitInput{
+		Diff:         diff,
+		Language:     cfg.CommitLanguage,
+		Instructions: loadInstructions(cmd),
+		Stash:        true,
+		FileOrder:    cfg.PromptFileOrder,
+	}
+	if stashShowPrompt {
+		fmt.Fprintln(cmd.OutOrStdout(), ai.BuildCommitPrompt(input))
+		return nil
+	}
+
+	if err := checkSecrets(cmd, cfg, diff, stashAllowSecrets, stashYes); err != nil {
+		return err
+	}
+	if !stashDryRun && !stashYes && !ui.IsInteractive() {
+		return fmt.Errorf("confirming the stash description requires an interactive terminal; use --yes to stash without confirmation")
+	}
+
+	aiClient, err := ai.NewProvider(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create AI client: %w", err)
+	}
+	aiClient.SetReporter(ui.NewReporter(cmd.ErrOrStderr()))
+
+	description, err := aiClient.GenerateStashDescription(ctx, input)
+	if err != nil {
+		return err
+	}
+	if stashDryRun {
+		fmt.Fprintln(cmd.OutOrStdout(), description)
+		return nil
+	}
+
+	out := cmd.OutOrStdout()
+	if !stashYes {
+		if !cfg.UseColor() {
+			ui.DisableColor()
+		}
+		for {
+			fmt.Fprintf(out, "%s\n  %s\n\n", ui.RenderSuccessHeader(i18n.T("stash.generated")), description)
+			choice, err := ui.PromptChoiceStyledWithWriter(i18n.T("stash.confirm"), []string{"y", "e", "n"}, out)
+			if err != nil {
+				return err
+			}
+			if choice == "y" {
+				break
+			}
+			if choice != "e" {
+				return exitWithCode(cmd, ExitDeclined, nil)
+			}
+			edited, err := ui.EditText(description+"\n", "gelf-stash-*.txt")
+			if err != nil {
+				return err
+			}
+			if line := firstLine(edited); line != "" {
+				description = line
+			}
+		}
+	}
+
+	release, err := acquireRepoLock(cmd, stashWait)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := git.StashPush(description, stashUntracked); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "✅ Stashed changes: %s\n", description)
+	return nil
+}
+
+// firstLine returns the first non-empty line of text, trimmed.
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+func runStashList(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if !cfg.UseColor() {
+		ui.DisableColor()
+	}
+
+	stashes, err := git.ListStashes()
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if len(stashes) == 0 {
+		fmt.Fprintln(out, i18n.T("stash.none"))
+		return nil
+	}
+
+	now := time.Now()
+	rows := make([][]string, 0, len(stashes))
+	for _, stash := range stashes {
+		summary := "?"
+		if stat, err := git.GetStashStat(stash.Ref); err == nil {
+			summary = fmt.Sprintf("%d file(s), +%d -%d", stat.Files, stat.AddedLines, stat.DeletedLines)
+		}
+		rows = append(rows, []string{stash.Ref, stashAge(now, stash.Time), summary, stash.Message})
+	}
+	table := ui.RenderTable([]string{"STASH", "AGE", "CHANGES", "DESCRIPTION"}, rows)
+	if !ui.IsInteractive() {
+		fmt.Fprint(out, table)
+		return nil
+	}
+
+	// The rows as RenderTable aligned them, without the header.
+	items := strings.Split(strings.TrimSuffix(table, "\n"), "\n")[1:]
+	index, ok, err := ui.PromptSelectWithWriter(i18n.T("stash.pick"), items, out)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return exitWithCode(cmd, ExitDeclined, nil)
+	}
+	stash := stashes[index]
+
+	fmt.Fprintf(out, "%s  %s\n", stash.Ref, stash.Message)
+	choice, err := ui.PromptChoiceStyledWithWriter(i18n.T("stash.action", stash.Ref), []string{"a", "p", "n"}, out)
+	if err != nil {
+		return err
+	}
+	if choice != "a" && choice != "p" {
+		return exitWithCode(cmd, ExitDeclined, nil)
+	}
+
+	release, err := acquireRepoLock(cmd, stashWait)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	pop := choice == "p"
+	if err := git.StashApply(stash.Ref, pop); err != nil {
+		return err
+	}
+	if pop {
+		fmt.Fprintf(out, "✅ Popped %s: %s\n", stash.Ref, stash.Message)
+	} else {
+		fmt.Fprintf(out, "✅ Applied %s: %s\n", stash.Ref, stash.Message)
+	}
+	return nil
+}
+
+// stashAge says how long ago a stash was made, e.g. "3 hours ago". Stashes
+// older than a month show their date.
+func stashAge(now, t time.Time) string {
+	if t.IsZero() {
+		return "?"
+	}
+	age := now.Sub(t)
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return plural(int(age/time.Minute), "minute")
+	case age < 24*time.Hour:
+		return plural(int(age/time.Hour), "hour")
+	case age < 30*24*time.Hour:
+		return plural(int(age/(24*time.Hour)), "day")
+	default:
+		return t.Format("2006-01-02")
+	}
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/state"
+	"github.com/EkeMinusYou/gelf/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show model use today and this week",
+	Long: `Show the model calls and tokens gelf used today and this week, per
+command and model, and how much of each configured budget is left.
+
+Use is counted in gelf's state directory on this machine; weeks start on
+Monday.`,
+	Args: cobra.NoArgs,
+	RunE: runUsage,
+}
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+}
+
+func runUsage(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	entries, err := state.LoadUsage()
+	if err != nil {
+		return fmt.Errorf("failed to read usage: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	now := time.Now()
+	fmt.Fprintf(out, "Today (%s):\n", now.Format("Mon Jan 2"))
+	fmt.Fprint(out, usageTable(entries, startOfDay(now)))
+	fmt.Fprintf(out, "\nThis week (since %s):\n", startOfWeek(now).Format("Mon Jan 2"))
+	fmt.Fprint(out, usageTable(entries, startOfWeek(now)))
+
+	rows := budgetRows(entries, now, cfg.Budget, "", "all commands")
+	commands := make([]string, 0, len(cfg.CommandBudgets))
+	for command := range cfg.CommandBudgets {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	for _, command := range commands {
+		rows = append(rows, budgetRows(entries, now, cfg.CommandBudgets[command], command, command)...)
+	}
+	fmt.Fprintln(out, "\nBudgets:")
+	if len(rows) == 0 {
+		fmt.Fprintln(out, "(none configured)")
+		return nil
+	}
+	fmt.Fprint(out, ui.RenderTable([]string{"SCOPE", "PERIOD", "CALLS", "INPUT TOKENS", "RESETS"}, rows))
+	return nil
+}
+
+// usageTable renders the use on or after since per command, provider and
+// model.
+func usageTable(entries []state.UsageEntry, since time.Time) string {
+	type key struct{ command, provider, model string }
+	tallies := map[key]*usageTally{}
+	var keys []key
+	var total usageTally
+	for _, entry := range entries {
+		if entry.Date().Before(since) {
+			continue
+		}
+		k := key{entry.Command, entry.Provider, entry.Model}
+		if tallies[k] == nil {
+			tallies[k] = &usageTally{}
+			keys = append(keys, k)
+		}
+		tallies[k].add(entry)
+		total.add(entry)
+	}
+	if len(keys) == 0 {
+		return "(no model calls)\n"
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].command != keys[j].command {
+			return keys[i].command < keys[j].command
+		}
+		if keys[i].provider != keys[j].provider {
+			return keys[i].provider < keys[j].provider
+		}
+		return keys[i].model < keys[j].model
+	})
+
+	rows := make([][]string, 0, len(keys)+1)
+	for _, k := range keys {
+		rows = append(rows, tallyRow(k.command, k.provider, k.model, *tallies[k]))
+	}
+	rows = append(rows, tallyRow("total", "", "", total))
+	return ui.RenderTable([]string{"COMMAND", "PROVIDER", "MODEL", "CALLS", "INPUT TOKENS", "OUTPUT TOKENS"}, rows)
+}
+
+func tallyRow(command, provider, model string, tally usageTally) []string {
+	return []string{command, provider, model, strconv.Itoa(tally.Calls), strconv.Itoa(tally.InputTokens), strconv.Itoa(tally.OutputTokens)}
+}
+
+// budgetRows describes how much of budget the use by command ("" for all
+// commands) has taken, one row per limited period.
+func budgetRows(entries []state.UsageEntry, now time.Time, budget config.Budget, command, scope string) [][]string {
+	var rows [][]string
+	for _, period := range budgetPeriods(now, budget) {
+		tally := tallyUsage(entries, period.start, command)
+		rows = append(rows, []string{
+			scope,
+			period.name,
+			usedOf(tally.Calls, period.limit.Calls),
+			usedOf(tally.InputTokens, period.limit.InputTokens),
+			period.reset.Format("Mon Jan 2 15:04"),
+		})
+	}
+	return rows
+}
+
+// usedOf formats used against limit, which is 0 when there is none.
+func usedOf(used, limit int) string {
+	if limit == 0 {
+		return strconv.Itoa(used)
+	}
+	return fmt.Sprintf("%d/%d", used, limit)
+}
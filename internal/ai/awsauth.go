@@ -0,0 +1,201 @@
+package ai
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// awsCredentials signs requests to AWS.
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is set for temporary credentials.
+	SessionToken string
+}
+
+// loadAWSCredentials reads the credentials of AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, or else those of profile in
+// the shared credentials file, ~/.aws/credentials unless
+// AWS_SHARED_CREDENTIALS_FILE names another. Profiles that get their
+// credentials from SSO, a role or a credential_process are not supported.
+func loadAWSCredentials(profile string) (awsCredentials, error) {
+	if accessKeyID := os.Getenv("AWS_ACCESS_KEY_ID"); accessKeyID != "" {
+		secretAccessKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+		if secretAccessKey == "" {
+			return awsCredentials{}, fmt.Errorf("AWS_ACCESS_KEY_ID is set but AWS_SECRET_ACCESS_KEY is not")
+		}
+		return awsCredentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: secretAccessKey,
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return awsCredentials{}, fmt.Errorf("no AWS credentials: AWS_ACCESS_KEY_ID is not set and the home directory is unknown: %w", err)
+		}
+		path = filepath.Join(homeDir, ".aws", "credentials")
+	}
+	values, err := readAWSProfile(path, profile)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("no AWS credentials: AWS_ACCESS_KEY_ID is not set and %w", err)
+	}
+	credentials := awsCredentials{
+		AccessKeyID:     values["aws_access_key_id"],
+		SecretAccessKey: values["aws_secret_access_key"],
+		SessionToken:    values["aws_session_token"],
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return awsCredentials{}, fmt.Errorf("profile %q in %s has no aws_access_key_id and aws_secret_access_key (SSO, role and credential_process profiles are not supported)", profile, path)
+	}
+	return credentials, nil
+}
+
+// readAWSProfile returns the keys of the [profile] section of the INI file
+// at path.
+func readAWSProfile(path, profile string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var values map[string]string
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if section == profile && values == nil {
+				values = map[string]string{}
+			}
+		case section == profile:
+			if key, value, ok := strings.Cut(line, "="); ok {
+				values[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if values == nil {
+		return nil, fmt.Errorf("%s has no profile %q", path, profile)
+	}
+	return values, nil
+}
+
+// signAWSRequest signs req, whose body is body, with AWS Signature Version 4
+// for service in region.
+func signAWSRequest(req *http.Request, body []byte, credentials awsCredentials, region, service string, now time.Time) {
+	req.Header.Set("X-Amz-Date", now.UTC().Format("20060102T150405Z"))
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+	req.Header.Set("Authorization", awsSign(req, body, credentials, region, service).Authorization)
+}
+
+// awsSignature is the outcome of signing a request, with the intermediate
+// strings AWS documents for checking a signer.
+type awsSignature struct {
+	CanonicalRequest string
+	StringToSign     string
+	Authorization    string
+}
+
+// awsSign signs req, whose X-Amz-Date header is already set, and every other
+// header it has but a previous Authorization.
+func awsSign(req *http.Request, body []byte, credentials awsCredentials, region, service string) awsSignature {
+	amzDate := req.Header.Get("X-Amz-Date")
+	date := amzDate[:min(8, len(amzDate))]
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		if name = strings.ToLower(name); name != "authorization" {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	// Services other than S3 escape each segment of the path a second time.
+	segments := strings.Split(req.URL.EscapedPath(), "/")
+	for i, segment := range segments {
+		segments[i] = awsEscape(segment)
+	}
+	canonicalURI := strings.Join(segments, "/")
+	if canonicalURI == "" {
+		canonicalURI = "/"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	for _, part := range []string{region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	return awsSignature{
+		CanonicalRequest: canonicalRequest,
+		StringToSign:     stringToSign,
+		Authorization: fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+			credentials.AccessKeyID, scope, signedHeaders, signature),
+	}
+}
+
+// awsEscape percent-encodes every byte of s but the unreserved characters,
+// as AWS signatures expect.
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
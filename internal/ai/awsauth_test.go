@@ -0,0 +1,183 @@
+package ai
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// awsTestCredentials are the credentials of AWS's Signature Version 4 test
+// suite.
+var awsTestCredentials = awsCredentials{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+// awsTestTime is the request time of the test suite, 20150830T123600Z.
+var awsTestTime = time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+// TestSignAWSRequestTestSuite checks the signer against the get-vanilla and
+// post-vanilla cases of AWS's published Signature Version 4 test suite.
+func TestSignAWSRequestTestSuite(t *testing.T) {
+	tests := []struct {
+		method        string
+		canonical     string
+		stringToSign  string
+		authorization string
+	}{
+		{
+			method:        http.MethodGet,
+			canonical:     "GET\n/\n\nhost:example.amazonaws.com\nx-amz-date:20150830T123600Z\n\nhost;x-amz-date\ne3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			stringToSign:  "AWS4-HMAC-SHA256\n20150830T123600Z\n20150830/us-east-1/service/aws4_request\nbb579772317eb040ac9ed261061d46c1f17a8133879d6129b6e1c25292927e63",
+			authorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			method:        http.MethodPost,
+			canonical:     "POST\n/\n\nhost:example.amazonaws.com\nx-amz-date:20150830T123600Z\n\nhost;x-amz-date\ne3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			stringToSign:  "AWS4-HMAC-SHA256\n20150830T123600Z\n20150830/us-east-1/service/aws4_request\n553f88c9e4d10fc9e109e2aeb65f030801b70c2f6468faca261d401ae622fc87",
+			authorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, "https://example.amazonaws.com/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signAWSRequest(req, nil, awsTestCredentials, "us-east-1", "service", awsTestTime)
+
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("X-Amz-Date = %q", got)
+			}
+			if got := req.Header.Get("Authorization"); got != tt.authorization {
+				t.Errorf("Authorization = %q, want %q", got, tt.authorization)
+			}
+
+			// Signing again must not sign the Authorization just added.
+			signature := awsSign(req, nil, awsTestCredentials, "us-east-1", "service")
+			if signature.CanonicalRequest != tt.canonical {
+				t.Errorf("canonical request = %q, want %q", signature.CanonicalRequest, tt.canonical)
+			}
+			if signature.StringToSign != tt.stringToSign {
+				t.Errorf("string to sign = %q, want %q", signature.StringToSign, tt.stringToSign)
+			}
+			if signature.Authorization != tt.authorization {
+				t.Errorf("Authorization = %q, want %q", signature.Authorization, tt.authorization)
+			}
+		})
+	}
+}
+
+func TestSignAWSRequestBedrockPath(t *testing.T) {
+	credentials := awsTestCredentials
+	credentials.SessionToken = "session"
+	url := "https://bedrock-runtime.us-east-1.amazonaws.com/model/" + awsEscape("anthropic.claude-3-5-sonnet-20240620-v1:0") + "/converse"
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	signAWSRequest(req, []byte("{}"), credentials, "us-east-1", "bedrock", awsTestTime)
+
+	if got := req.Header.Get("X-Amz-Security-Token"); got != "session" {
+		t.Errorf("X-Amz-Security-Token = %q, want the session token", got)
+	}
+	canonical := awsSign(req, []byte("{}"), credentials, "us-east-1", "bedrock").CanonicalRequest
+	lines := strings.Split(canonical, "\n")
+	// The escaped colon in the path is escaped a second time.
+	if want := "/model/anthropic.claude-3-5-sonnet-20240620-v1%253A0/converse"; lines[1] != want {
+		t.Errorf("canonical URI = %q, want %q", lines[1], want)
+	}
+	if want := "content-type;host;x-amz-date;x-amz-security-token"; lines[len(lines)-2] != want {
+		t.Errorf("signed headers = %q, want %q", lines[len(lines)-2], want)
+	}
+	if !strings.Contains(req.Header.Get("Authorization"), "Credential=AKIDEXAMPLE/20150830/us-east-1/bedrock/aws4_request") {
+		t.Errorf("Authorization = %q, want the bedrock scope", req.Header.Get("Authorization"))
+	}
+}
+
+const awsTestCredentialsFile = `# comment
+[default]
+aws_access_key_id = AKIDDEFAULT
+aws_secret_access_key = default-secret
+
+[work]
+; comment
+AWS_ACCESS_KEY_ID=AKIDWORK
+aws_secret_access_key= work-secret
+aws_session_token =work-token
+
+[sso]
+sso_session = corp
+`
+
+func TestReadAWSProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(path, []byte(awsTestCredentialsFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		profile string
+		want    map[string]string
+	}{
+		{"default", map[string]string{"aws_access_key_id": "AKIDDEFAULT", "aws_secret_access_key": "default-secret"}},
+		{"work", map[string]string{"aws_access_key_id": "AKIDWORK", "aws_secret_access_key": "work-secret", "aws_session_token": "work-token"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			got, err := readAWSProfile(path, tt.profile)
+			if err != nil {
+				t.Fatalf("readAWSProfile() error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("readAWSProfile() = %v, want %v", got, tt.want)
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Errorf("%s = %q, want %q", key, got[key], value)
+				}
+			}
+		})
+	}
+
+	if _, err := readAWSProfile(path, "missing"); err == nil || !strings.Contains(err.Error(), `no profile "missing"`) {
+		t.Errorf("readAWSProfile(missing) error = %v, want a missing profile error", err)
+	}
+}
+
+func TestLoadAWSCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(path, []byte(awsTestCredentialsFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", path)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+
+	got, err := loadAWSCredentials("work")
+	if err != nil {
+		t.Fatalf("loadAWSCredentials(work) error: %v", err)
+	}
+	if want := (awsCredentials{AccessKeyID: "AKIDWORK", SecretAccessKey: "work-secret", SessionToken: "work-token"}); got != want {
+		t.Errorf("loadAWSCredentials(work) = %+v, want %+v", got, want)
+	}
+	if _, err := loadAWSCredentials("sso"); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("loadAWSCredentials(sso) error = %v, want an unsupported profile error", err)
+	}
+
+	// The environment wins over the file.
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDENV")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+	got, err = loadAWSCredentials("default")
+	if err != nil {
+		t.Fatalf("loadAWSCredentials() error: %v", err)
+	}
+	if got.AccessKeyID != "AKIDENV" || got.SecretAccessKey != "env-secret" {
+		t.Errorf("loadAWSCredentials() = %+v, want the environment credentials", got)
+	}
+}
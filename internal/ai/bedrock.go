@@ -0,0 +1,347 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/progress"
+)
+
+// BedrockProvider is the name of the Amazon Bedrock provider in gelf.yml.
+const BedrockProvider = "bedrock"
+
+func init() {
+	RegisterProvider(BedrockProvider, func(ctx context.Context, cfg *config.Config) (Provider, error) {
+		client, err := NewBedrockClient(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	})
+}
+
+// NewBedrockClient connects to the Amazon Bedrock Converse API, the bedrock
+// provider, which serves Claude, Titan and the other Bedrock models alike.
+// The region is cfg.BedrockRegion; the credentials come from the
+// environment or from the cfg.BedrockProfile profile of the shared
+// credentials file. AWS_ENDPOINT_URL_BEDROCK_RUNTIME points it at another
+// endpoint.
+func NewBedrockClient(ctx context.Context, cfg *config.Config) (*Client, error) {
+	// Fail before any work is done for a request that could not be sent.
+	if err := allowCall(cfg.FlashModel); err != nil {
+		return nil, err
+	}
+
+	if cfg.BedrockRegion == "" {
+		return nil, fmt.Errorf("no AWS region for the bedrock provider; set bedrock.region or AWS_REGION")
+	}
+	credentials, err := loadAWSCredentials(cfg.BedrockProfile)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_BEDROCK_RUNTIME")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", cfg.BedrockRegion)
+	}
+
+	return &Client{
+		backend: bedrockBackend{
+			endpoint:    strings.TrimSuffix(endpoint, "/"),
+			region:      cfg.BedrockRegion,
+			credentials: credentials,
+			http:        http.DefaultClient,
+		},
+		flashModel: cfg.FlashModel,
+		proModel:   cfg.ProModel,
+		reporter:   progress.Nop(),
+		retry:      newRetryPolicy(cfg),
+	}, nil
+}
+
+// bedrockBackend sends prompts to the Amazon Bedrock Converse API.
+type bedrockBackend struct {
+	endpoint    string
+	region      string
+	credentials awsCredentials
+	http        *http.Client
+}
+
+type bedrockContent struct {
+	Text string `json:"text"`
+}
+
+type bedrockMessage struct {
+	Role    string           `json:"role"`
+	Content []bedrockContent `json:"content"`
+}
+
+// bedrockRequest is a Converse request. The Converse API takes no seed, so
+// a Seed set on the request is not sent.
+type bedrockRequest struct {
+	Messages        []bedrockMessage `json:"messages"`
+	InferenceConfig struct {
+		Temperature float32 `json:"temperature"`
+	} `json:"inferenceConfig"`
+}
+
+type bedrockUsage struct {
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
+}
+
+type bedrockResponse struct {
+	Output struct {
+		Message bedrockMessage `json:"message"`
+	} `json:"output"`
+	Usage bedrockUsage `json:"usage"`
+}
+
+func (b bedrockBackend) name() string {
+	return "bedrock"
+}
+
+// post sends a signed Converse request for request to the action of its
+// model, converse or converse-stream, and returns the response body, or
+// the API's error message for a failed request.
+func (b bedrockBackend) post(ctx context.Context, request modelRequest, action string) (io.ReadCloser, error) {
+	var converse bedrockRequest
+	converse.Messages = []bedrockMessage{{Role: "user", Content: []bedrockContent{{Text: request.Prompt}}}}
+	converse.InferenceConfig.Temperature = request.Temperature
+	body, err := json.Marshal(converse)
+	if err != nil {
+		return nil, err
+	}
+
+	// Model IDs hold colons, which must reach the API escaped.
+	url := b.endpoint + "/model/" + awsEscape(request.Model) + "/" + action
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	signAWSRequest(req, body, b.credentials, b.region, "bedrock", time.Now())
+
+	resp, err := b.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		var apiError struct {
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		message := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiError) == nil && apiError.Message != "" {
+			message = apiError.Message
+		}
+		return nil, &statusError{provider: "bedrock", status: resp.StatusCode, message: message}
+	}
+	return resp.Body, nil
+}
+
+func (b bedrockBackend) generate(ctx context.Context, request modelRequest) (modelResponse, error) {
+	body, err := b.post(ctx, request, "converse")
+	if err != nil {
+		return modelResponse{}, err
+	}
+	defer body.Close()
+
+	var converse bedrockResponse
+	if err := json.NewDecoder(body).Decode(&converse); err != nil {
+		return modelResponse{}, fmt.Errorf("bedrock: failed to decode the response: %w", err)
+	}
+	var text strings.Builder
+	for _, content := range converse.Output.Message.Content {
+		text.WriteString(content.Text)
+	}
+	return modelResponse{
+		Text:         text.String(),
+		InputTokens:  converse.Usage.InputTokens,
+		OutputTokens: converse.Usage.OutputTokens,
+	}, nil
+}
+
+// generateStream reads the events of a streamed Converse response: the text
+// arrives in contentBlockDelta events and the usage in a metadata event.
+func (b bedrockBackend) generateStream(ctx context.Context, request modelRequest, onChunk func(string)) (modelResponse, error) {
+	body, err := b.post(ctx, request, "converse-stream")
+	if err != nil {
+		return modelResponse{}, err
+	}
+	defer body.Close()
+
+	var builder strings.Builder
+	var response modelResponse
+	for {
+		event, err := readBedrockEvent(body)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return modelResponse{}, fmt.Errorf("bedrock: failed to read the stream: %w", err)
+		}
+		if event.headers[":message-type"] == "exception" {
+			var apiError struct {
+				Message string `json:"message"`
+			}
+			if err := json.Unmarshal(event.payload, &apiError); err != nil || apiError.Message == "" {
+				apiError.Message = strings.TrimSpace(string(event.payload))
+			}
+			return modelResponse{}, &bedrockException{exceptionType: event.headers[":exception-type"], message: apiError.Message}
+		}
+		switch event.headers[":event-type"] {
+		case "contentBlockDelta":
+			var delta struct {
+				Delta struct {
+					Text string `json:"text"`
+				} `json:"delta"`
+			}
+			if err := json.Unmarshal(event.payload, &delta); err != nil {
+				return modelResponse{}, fmt.Errorf("bedrock: failed to decode a streamed chunk: %w", err)
+			}
+			if delta.Delta.Text == "" {
+				continue
+			}
+			builder.WriteString(delta.Delta.Text)
+			onChunk(delta.Delta.Text)
+		case "metadata":
+			var metadata struct {
+				Usage bedrockUsage `json:"usage"`
+			}
+			if err := json.Unmarshal(event.payload, &metadata); err == nil {
+				response.InputTokens = metadata.Usage.InputTokens
+				response.OutputTokens = metadata.Usage.OutputTokens
+			}
+		}
+	}
+	response.Text = builder.String()
+	return response, nil
+}
+
+// retryable reports whether err is a rate limit or a server error, answered
+// with an HTTP status or as an exception in a stream.
+func (b bedrockBackend) retryable(err error) bool {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return retryableStatus(statusErr.status)
+	}
+	var exception *bedrockException
+	if errors.As(err, &exception) {
+		switch exception.exceptionType {
+		case "throttlingException", "serviceUnavailableException", "internalServerException", "modelNotReadyException":
+			return true
+		}
+	}
+	return false
+}
+
+// countTokens fails: the Converse API cannot count the tokens of every
+// model, so callers fall back to estimates.
+func (b bedrockBackend) countTokens(ctx context.Context, model, text string) (int, error) {
+	return 0, fmt.Errorf("the bedrock provider cannot count tokens")
+}
+
+func (b bedrockBackend) close() error {
+	return nil
+}
+
+// bedrockException is an exception a stream ended with.
+type bedrockException struct {
+	exceptionType string
+	message       string
+}
+
+func (e *bedrockException) Error() string {
+	return fmt.Sprintf("bedrock: %s (%s)", e.message, e.exceptionType)
+}
+
+// bedrockEvent is one message of an AWS event stream.
+type bedrockEvent struct {
+	// headers holds the string headers, such as :event-type.
+	headers map[string]string
+	payload []byte
+}
+
+// readBedrockEvent reads the next message of an AWS event stream: a prelude
+// of the total and header lengths with its CRC, the headers, the payload
+// and the CRC of the whole message. It returns io.EOF at the end of r.
+func readBedrockEvent(r io.Reader) (bedrockEvent, error) {
+	var prelude [12]byte
+	if _, err := io.ReadFull(r, prelude[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return bedrockEvent{}, err
+		}
+		return bedrockEvent{}, io.EOF
+	}
+	totalLength := binary.BigEndian.Uint32(prelude[0:4])
+	headersLength := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return bedrockEvent{}, fmt.Errorf("bad prelude checksum")
+	}
+	if totalLength < 16+headersLength || totalLength > 16*1024*1024 {
+		return bedrockEvent{}, fmt.Errorf("bad message length %d", totalLength)
+	}
+	message := make([]byte, totalLength)
+	copy(message, prelude[:])
+	if _, err := io.ReadFull(r, message[12:]); err != nil {
+		return bedrockEvent{}, err
+	}
+	if crc32.ChecksumIEEE(message[:totalLength-4]) != binary.BigEndian.Uint32(message[totalLength-4:]) {
+		return bedrockEvent{}, fmt.Errorf("bad message checksum")
+	}
+
+	headers, err := parseBedrockHeaders(message[12 : 12+headersLength])
+	if err != nil {
+		return bedrockEvent{}, err
+	}
+	return bedrockEvent{headers: headers, payload: message[12+headersLength : totalLength-4]}, nil
+}
+
+// parseBedrockHeaders returns the string headers of an event stream
+// message, skipping headers of other types.
+func parseBedrockHeaders(data []byte) (map[string]string, error) {
+	// Sizes of the values of the fixed-size header types, by type.
+	fixedSizes := map[byte]int{0: 0, 1: 0, 2: 1, 3: 2, 4: 4, 5: 8, 8: 8, 9: 16}
+	headers := map[string]string{}
+	for len(data) > 0 {
+		nameLength := int(data[0])
+		if len(data) < 2+nameLength {
+			return nil, fmt.Errorf("truncated header")
+		}
+		name := string(data[1 : 1+nameLength])
+		valueType := data[1+nameLength]
+		data = data[2+nameLength:]
+		if size, ok := fixedSizes[valueType]; ok {
+			if len(data) < size {
+				return nil, fmt.Errorf("truncated header %s", name)
+			}
+			data = data[size:]
+			continue
+		}
+		// Byte arrays (6) and strings (7) hold their length first.
+		if (valueType != 6 && valueType != 7) || len(data) < 2 {
+			return nil, fmt.Errorf("bad header %s", name)
+		}
+		valueLength := int(binary.BigEndian.Uint16(data))
+		if len(data) < 2+valueLength {
+			return nil, fmt.Errorf("truncated header %s", name)
+		}
+		if valueType == 7 {
+			headers[name] = string(data[2 : 2+valueLength])
+		}
+		data = data[2+valueLength:]
+	}
+	return headers, nil
+}
@@ -0,0 +1,260 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// bedrockTestHeader is an event stream header; a nil value is sent as an
+// int32 header, which the reader skips.
+type bedrockTestHeader struct {
+	name  string
+	value *string
+}
+
+func stringHeader(name, value string) bedrockTestHeader {
+	return bedrockTestHeader{name: name, value: &value}
+}
+
+// encodeBedrockEvent frames payload with headers as an AWS event stream
+// message.
+func encodeBedrockEvent(headers []bedrockTestHeader, payload string) []byte {
+	var encoded bytes.Buffer
+	for _, header := range headers {
+		encoded.WriteByte(byte(len(header.name)))
+		encoded.WriteString(header.name)
+		if header.value == nil {
+			encoded.WriteByte(4)
+			binary.Write(&encoded, binary.BigEndian, int32(42))
+			continue
+		}
+		encoded.WriteByte(7)
+		binary.Write(&encoded, binary.BigEndian, uint16(len(*header.value)))
+		encoded.WriteString(*header.value)
+	}
+
+	var message bytes.Buffer
+	binary.Write(&message, binary.BigEndian, uint32(16+encoded.Len()+len(payload)))
+	binary.Write(&message, binary.BigEndian, uint32(encoded.Len()))
+	binary.Write(&message, binary.BigEndian, crc32.ChecksumIEEE(message.Bytes()))
+	message.Write(encoded.Bytes())
+	message.WriteString(payload)
+	binary.Write(&message, binary.BigEndian, crc32.ChecksumIEEE(message.Bytes()))
+	return message.Bytes()
+}
+
+// bedrockTestEvent frames payload as an event of eventType.
+func bedrockTestEvent(eventType, payload string) []byte {
+	return encodeBedrockEvent([]bedrockTestHeader{
+		stringHeader(":event-type", eventType),
+		stringHeader(":content-type", "application/json"),
+		stringHeader(":message-type", "event"),
+	}, payload)
+}
+
+// newBedrockTestBackend returns a backend for an httptest server answering
+// with handler.
+func newBedrockTestBackend(t *testing.T, handler http.HandlerFunc) bedrockBackend {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	credentials := awsTestCredentials
+	credentials.SessionToken = "session-token"
+	return bedrockBackend{endpoint: server.URL, region: "us-west-2", credentials: credentials, http: server.Client()}
+}
+
+// checkBedrockRequest checks the path, signature headers and body of a
+// Converse request for model and action.
+func checkBedrockRequest(t *testing.T, r *http.Request, model, action string) {
+	t.Helper()
+	if want := "/model/" + model + "/" + action; r.Method != http.MethodPost || r.URL.EscapedPath() != want {
+		t.Errorf("request = %s %s, want POST %s", r.Method, r.URL.EscapedPath(), want)
+	}
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") ||
+		!strings.Contains(authorization, "/us-west-2/bedrock/aws4_request, SignedHeaders=") ||
+		!strings.Contains(authorization, "x-amz-security-token") {
+		t.Errorf("Authorization = %q", authorization)
+	}
+	if r.Header.Get("X-Amz-Date") == "" || r.Header.Get("X-Amz-Security-Token") != "session-token" {
+		t.Errorf("X-Amz-Date = %q, X-Amz-Security-Token = %q", r.Header.Get("X-Amz-Date"), r.Header.Get("X-Amz-Security-Token"))
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The seed of the request is not sent.
+	want := `{"messages":[{"role":"user","content":[{"text":"describe"}]}],"inferenceConfig":{"temperature":0.2}}`
+	if string(body) != want {
+		t.Errorf("request body = %s, want %s", body, want)
+	}
+}
+
+func TestBedrockGenerate(t *testing.T) {
+	backend := newBedrockTestBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		checkBedrockRequest(t, r, "anthropic.claude-3-haiku-20240307-v1%3A0", "converse")
+		io.WriteString(w, `{"output":{"message":{"role":"assistant","content":[{"text":"feat: "},{"text":"add retries"}]}},"stopReason":"end_turn","usage":{"inputTokens":130,"outputTokens":6,"totalTokens":136}}`)
+	})
+
+	seed := int32(7)
+	resp, err := backend.generate(context.Background(), modelRequest{Model: "anthropic.claude-3-haiku-20240307-v1:0", Prompt: "describe", Temperature: 0.2, Seed: &seed})
+	if err != nil {
+		t.Fatalf("generate() error: %v", err)
+	}
+	if resp != (modelResponse{Text: "feat: add retries", InputTokens: 130, OutputTokens: 6}) {
+		t.Errorf("generate() = %+v", resp)
+	}
+}
+
+func TestBedrockGenerateStream(t *testing.T) {
+	backend := newBedrockTestBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		checkBedrockRequest(t, r, "amazon.titan-text-express-v1", "converse-stream")
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+		w.Write(bedrockTestEvent("messageStart", `{"role":"assistant"}`))
+		w.Write(bedrockTestEvent("contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"feat: "}}`))
+		w.Write(encodeBedrockEvent([]bedrockTestHeader{
+			{name: ":sequence"},
+			stringHeader(":event-type", "contentBlockDelta"),
+			stringHeader(":message-type", "event"),
+		}, `{"contentBlockIndex":0,"delta":{"text":"add retries"}}`))
+		w.Write(bedrockTestEvent("contentBlockStop", `{"contentBlockIndex":0}`))
+		w.Write(bedrockTestEvent("messageStop", `{"stopReason":"end_turn"}`))
+		w.Write(bedrockTestEvent("metadata", `{"usage":{"inputTokens":88,"outputTokens":5,"totalTokens":93},"metrics":{"latencyMs":412}}`))
+	})
+
+	var chunks []string
+	resp, err := backend.generateStream(context.Background(), modelRequest{Model: "amazon.titan-text-express-v1", Prompt: "describe", Temperature: 0.2}, func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("generateStream() error: %v", err)
+	}
+	if resp != (modelResponse{Text: "feat: add retries", InputTokens: 88, OutputTokens: 5}) {
+		t.Errorf("generateStream() = %+v", resp)
+	}
+	if strings.Join(chunks, "|") != "feat: |add retries" {
+		t.Errorf("chunks = %q", chunks)
+	}
+}
+
+func TestBedrockStreamException(t *testing.T) {
+	backend := newBedrockTestBackend(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bedrockTestEvent("contentBlockDelta", `{"delta":{"text":"feat"}}`))
+		w.Write(encodeBedrockEvent([]bedrockTestHeader{
+			stringHeader(":exception-type", "throttlingException"),
+			stringHeader(":message-type", "exception"),
+		}, `{"message":"Too many requests, please wait before trying again."}`))
+	})
+
+	_, err := backend.generateStream(context.Background(), modelRequest{Model: "amazon.titan-text-express-v1"}, func(string) {})
+	var exception *bedrockException
+	if !errors.As(err, &exception) || exception.exceptionType != "throttlingException" || exception.message != "Too many requests, please wait before trying again." {
+		t.Fatalf("generateStream() error = %v, want a throttlingException", err)
+	}
+	if !backend.retryable(err) {
+		t.Error("retryable() = false for a throttlingException")
+	}
+}
+
+func TestBedrockStreamCorrupted(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func([]byte) []byte
+		want    string
+	}{
+		{"prelude checksum", func(event []byte) []byte { event[9] ^= 0xff; return event }, "bad prelude checksum"},
+		{"message checksum", func(event []byte) []byte { event[len(event)-5] ^= 0xff; return event }, "bad message checksum"},
+		{"truncated", func(event []byte) []byte { return event[:len(event)-3] }, "unexpected EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newBedrockTestBackend(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write(tt.corrupt(bedrockTestEvent("contentBlockDelta", `{"delta":{"text":"feat"}}`)))
+			})
+			_, err := backend.generateStream(context.Background(), modelRequest{Model: "amazon.titan-text-express-v1"}, func(string) {})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("generateStream() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBedrockErrorStatus(t *testing.T) {
+	tests := []struct {
+		status    int
+		body      string
+		message   string
+		retryable bool
+	}{
+		{http.StatusTooManyRequests, `{"message":"Too many requests, please wait before trying again."}`, "Too many requests, please wait before trying again.", true},
+		{http.StatusInternalServerError, `{"message":"internal error"}`, "internal error", true},
+		{http.StatusServiceUnavailable, "service unavailable", "service unavailable", true},
+		{http.StatusBadRequest, `{"message":"The provided model identifier is invalid."}`, "The provided model identifier is invalid.", false},
+		{http.StatusForbidden, `{"message":"The security token included in the request is invalid."}`, "The security token included in the request is invalid.", false},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			backend := newBedrockTestBackend(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			})
+			_, err := backend.generate(context.Background(), modelRequest{Model: "amazon.titan-text-express-v1"})
+			var statusErr *statusError
+			if !errors.As(err, &statusErr) || statusErr.status != tt.status || statusErr.message != tt.message {
+				t.Fatalf("generate() error = %v, want status %d with %q", err, tt.status, tt.message)
+			}
+			if got := backend.retryable(err); got != tt.retryable {
+				t.Errorf("retryable() = %v, want %v", got, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestBedrockRetryableExceptions(t *testing.T) {
+	tests := map[string]bool{
+		"throttlingException":         true,
+		"serviceUnavailableException": true,
+		"internalServerException":     true,
+		"modelNotReadyException":      true,
+		"validationException":         false,
+		"modelStreamErrorException":   false,
+		"accessDeniedException":       false,
+	}
+	var backend bedrockBackend
+	for exceptionType, want := range tests {
+		err := &bedrockException{exceptionType: exceptionType, message: "failed"}
+		if got := backend.retryable(err); got != want {
+			t.Errorf("retryable(%s) = %v, want %v", exceptionType, got, want)
+		}
+	}
+	if backend.retryable(errors.New("connection reset")) {
+		t.Error("retryable() is true for an error without a status")
+	}
+}
+
+func TestParseBedrockHeaders(t *testing.T) {
+	event, err := readBedrockEvent(bytes.NewReader(encodeBedrockEvent([]bedrockTestHeader{
+		{name: ":sequence"},
+		stringHeader(":event-type", "metadata"),
+	}, "{}")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := json.Marshal(event.headers); string(got) != `{":event-type":"metadata"}` {
+		t.Errorf("headers = %s", got)
+	}
+
+	if _, err := parseBedrockHeaders([]byte{10, ':', 'e'}); err == nil {
+		t.Error("parseBedrockHeaders() accepted a truncated header")
+	}
+}
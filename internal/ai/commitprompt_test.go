@@ -0,0 +1,70 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildCommitPromptLanguages(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   CommitInput
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "one language",
+			input:   CommitInput{Language: "Japanese"},
+			want:    []string{"1. Use Japanese language"},
+			notWant: []string{"the body in", "\n10. "},
+		},
+		{
+			name:    "split languages",
+			input:   CommitInput{Language: "English", SubjectLanguage: "English", BodyLanguage: "Japanese"},
+			want:    []string{"1. Write the description on the first line in English and the body in Japanese", "\n10. After the first line, add a blank line and a short body in Japanese"},
+			notWant: []string{"1. Use English language"},
+		},
+		{
+			name:  "subject falls back to language",
+			input: CommitInput{Language: "English", BodyLanguage: "Japanese"},
+			want:  []string{"first line in English and the body in Japanese"},
+		},
+		{
+			name:  "body falls back to language",
+			input: CommitInput{Language: "Japanese", SubjectLanguage: "English"},
+			want:  []string{"first line in English and the body in Japanese"},
+		},
+		{
+			name:    "same subject and body language",
+			input:   CommitInput{Language: "English", SubjectLanguage: "Japanese", BodyLanguage: "Japanese"},
+			want:    []string{"1. Use Japanese language"},
+			notWant: []string{"the body in"},
+		},
+		{
+			name:  "split languages with a scope",
+			input: CommitInput{Language: "English", BodyLanguage: "Japanese", Scope: "api"},
+			want:  []string{"9. The scope MUST be exactly (api)", "\n10. After the first line"},
+		},
+		{
+			name:    "merge body language",
+			input:   CommitInput{Language: "English", BodyLanguage: "Japanese", Merge: &MergeInput{Message: "Merge branch 'feature'"}},
+			want:    []string{"Use Japanese language for the body"},
+			notWant: []string{"first line in English"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt := BuildCommitPrompt(tt.input)
+			for _, want := range tt.want {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("prompt does not contain %q:\n%s", want, prompt)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(prompt, notWant) {
+					t.Errorf("prompt contains %q:\n%s", notWant, prompt)
+				}
+			}
+		})
+	}
+}
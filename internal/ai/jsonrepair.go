@@ -0,0 +1,238 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/prbody"
+)
+
+// How parsePullRequestContent got a valid object, logged with --verbose.
+const (
+	parseStrict   = "strict"
+	parseRepaired = "repaired"
+	parseMarkdown = "markdown"
+	parseRetried  = "retried"
+)
+
+// parsePullRequestContent decodes the model's {"title","body"} response. When
+// strict decoding fails, common model mistakes are repaired and decoding is
+// tried once more. A response that is markdown instead of JSON, e.g.
+// "## Title\n...\n## Body\n...", is read as a last resort. It returns which
+// path succeeded.
+func parsePullRequestContent(text string) (*PullRequestContent, string, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```json") {
+		text = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(text, "```json"), "```"))
+	}
+	result, err := decodePullRequestContent(text)
+	if err == nil {
+		return result, parseStrict, nil
+	}
+
+	repaired, repairErr := decodePullRequestContent(repairJSON(text))
+	if repairErr == nil {
+		return repaired, parseRepaired, nil
+	}
+	if markdown, ok := parseMarkdownPullRequest(text); ok {
+		return markdown, parseMarkdown, nil
+	}
+	return nil, "", err
+}
+
+var (
+	// markdownLabelRegex matches a line naming the part that follows: a
+	// heading ("## Title"), bold text ("**Body:**") or a plain label with a
+	// colon ("Title: ..."). Group 3, the rest of the line, is only set after
+	// a colon.
+	markdownLabelRegex = regexp.MustCompile(`(?i)^(#{1,6}\s+|\*\*|__)?\s*(pr title|pull request title|title|pr body|pull request body|body)\s*(?:\*\*|__)?\s*(?::\s*(?:\*\*|__)?\s*(.*)|$)`)
+	headingRegex       = regexp.MustCompile(`^#{1,6}\s+`)
+	markdownFenceRegex = regexp.MustCompile("(?s)^```(?:markdown|md)?\\s*\n(.*?)\n?```$")
+)
+
+// markdownLabel reports whether line labels the title or the body, and
+// returns the text following the label on the same line.
+func markdownLabel(line string) (isTitle bool, rest string, ok bool) {
+	line = strings.TrimSpace(line)
+	match := markdownLabelRegex.FindStringSubmatchIndex(line)
+	if match == nil {
+		return false, "", false
+	}
+	hasMarker := match[2] >= 0
+	hasColon := match[6] >= 0
+	if !hasMarker && !hasColon {
+		return false, "", false
+	}
+	if hasColon {
+		rest = strings.TrimSpace(line[match[6]:match[7]])
+	}
+	return strings.HasSuffix(strings.ToLower(line[match[4]:match[5]]), "title"), rest, true
+}
+
+// parseMarkdownPullRequest reads a response written as markdown instead of
+// JSON. Parts labelled Title and Body are used when present; otherwise the
+// first line, without heading marks, is the title and the rest is the body.
+// Text that looks like JSON is left to the JSON paths.
+func parseMarkdownPullRequest(text string) (*PullRequestContent, bool) {
+	text = strings.TrimSpace(text)
+	if match := markdownFenceRegex.FindStringSubmatch(text); match != nil {
+		text = strings.TrimSpace(match[1])
+	}
+	if text == "" || strings.HasPrefix(text, "{") || strings.HasPrefix(text, "```") {
+		return nil, false
+	}
+
+	lines := strings.Split(text, "\n")
+	var title string
+	var body []string
+	titleLabel, bodyLabel := -1, -1
+	for i, line := range lines {
+		isTitle, rest, ok := markdownLabel(line)
+		if !ok {
+			continue
+		}
+		if isTitle && titleLabel < 0 {
+			titleLabel = i
+			title = rest
+		} else if !isTitle && titleLabel >= 0 {
+			bodyLabel = i
+			if rest != "" {
+				body = append(body, rest)
+			}
+			body = append(body, lines[i+1:]...)
+			break
+		}
+	}
+
+	switch {
+	case titleLabel >= 0:
+		rest := lines[titleLabel+1:]
+		if bodyLabel >= 0 {
+			rest = lines[titleLabel+1 : bodyLabel]
+		}
+		// A heading label puts the title on the next non-empty line.
+		for len(rest) > 0 && title == "" {
+			title = strings.TrimSpace(rest[0])
+			rest = rest[1:]
+		}
+		if bodyLabel < 0 {
+			body = rest
+		}
+	default:
+		// Skip a lead-in such as "Here is the pull request:".
+		if len(lines) > 1 && strings.HasSuffix(strings.TrimSpace(lines[0]), ":") {
+			lines = lines[1:]
+		}
+		for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+			lines = lines[1:]
+		}
+		if len(lines) == 0 {
+			return nil, false
+		}
+		title = headingRegex.ReplaceAllString(strings.TrimSpace(lines[0]), "")
+		body = lines[1:]
+	}
+
+	title = strings.Trim(strings.TrimSpace(title), "*_`\"'")
+	content := &PullRequestContent{
+		Title: prbody.SanitizeTitle(title),
+		Body:  strings.TrimSpace(prbody.SanitizeBody(strings.Join(body, "\n"))),
+	}
+	if content.Title == "" || content.Body == "" {
+		return nil, false
+	}
+	return content, true
+}
+
+// decodePullRequestContent decodes text strictly, sanitizes both fields and
+// checks that they are present.
+func decodePullRequestContent(text string) (*PullRequestContent, error) {
+	var result PullRequestContent
+	if err := json.Unmarshal([]byte(text), &result); err != nil {
+		return nil, err
+	}
+	result.Title = prbody.SanitizeTitle(result.Title)
+	result.Body = strings.TrimSpace(prbody.SanitizeBody(result.Body))
+	if result.Title == "" {
+		return nil, fmt.Errorf("generated PR title is empty")
+	}
+	if result.Body == "" {
+		return nil, fmt.Errorf("generated PR body is empty")
+	}
+	return &result, nil
+}
+
+// repairJSON fixes the mistakes models make most often in JSON output: text
+// or code fences around the object, raw newlines and tabs inside strings,
+// invalid escapes such as \' and trailing commas.
+func repairJSON(text string) string {
+	if start := strings.Index(text, "{"); start >= 0 {
+		if end := strings.LastIndex(text, "}"); end > start {
+			text = text[start : end+1]
+		}
+	}
+
+	var out strings.Builder
+	inString := false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch {
+			case c == '\\' && i+1 < len(text):
+				if strings.IndexByte(`"\/bfnrtu`, text[i+1]) >= 0 {
+					out.WriteByte(c)
+					out.WriteByte(text[i+1])
+					i++
+				} else if text[i+1] == '\'' {
+					out.WriteByte('\'')
+					i++
+				} else {
+					out.WriteString(`\\`)
+				}
+			case c == '"':
+				inString = false
+				out.WriteByte(c)
+			case c == '\n':
+				out.WriteString(`\n`)
+			case c == '\r':
+				out.WriteString(`\r`)
+			case c == '\t':
+				out.WriteString(`\t`)
+			case c < 0x20:
+				fmt.Fprintf(&out, `\u%04x`, c)
+			default:
+				out.WriteByte(c)
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case ',':
+			// Drop a comma that only precedes whitespace and a closing
+			// bracket.
+			rest := strings.TrimLeft(text[i+1:], " \t\r\n")
+			if strings.HasPrefix(rest, "}") || strings.HasPrefix(rest, "]") {
+				continue
+			}
+		}
+		out.WriteByte(c)
+	}
+	return out.String()
+}
+
+// buildJSONFixPrompt asks the model to correct a response that could not be
+// parsed, given the parse error.
+func buildJSONFixPrompt(response string, parseErr error) string {
+	return fmt.Sprintf(`Your previous response could not be parsed as JSON: %v
+
+Return the same content as a single valid JSON object matching the schema {"title":"...", "body":"..."}.
+- Escape newlines in strings as \n and quotes as \".
+- No markdown fences or extra text.
+
+PREVIOUS RESPONSE:
+%s`, parseErr, response)
+}
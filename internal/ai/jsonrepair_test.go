@@ -0,0 +1,241 @@
+package ai
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParsePullRequestContent(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		wantTitle string
+		wantBody  string
+		wantPath  string
+	}{
+		{
+			name:      "strict",
+			text:      `{"title":"Add retries","body":"Retries model calls."}`,
+			wantTitle: "Add retries",
+			wantBody:  "Retries model calls.",
+			wantPath:  parseStrict,
+		},
+		{
+			name:      "json fence",
+			text:      "```json\n{\"title\":\"Add retries\",\"body\":\"Retries model calls.\"}\n```",
+			wantTitle: "Add retries",
+			wantBody:  "Retries model calls.",
+			wantPath:  parseStrict,
+		},
+		{
+			name:      "plain fence",
+			text:      "```\n{\"title\":\"Add retries\",\"body\":\"Retries model calls.\"}\n```",
+			wantTitle: "Add retries",
+			wantBody:  "Retries model calls.",
+			wantPath:  parseRepaired,
+		},
+		{
+			name:      "text around the object",
+			text:      "Here is the pull request:\n{\"title\":\"Add retries\",\"body\":\"Retries model calls.\"}\nLet me know if you need changes.",
+			wantTitle: "Add retries",
+			wantBody:  "Retries model calls.",
+			wantPath:  parseRepaired,
+		},
+		{
+			name:      "trailing comma",
+			text:      "{\"title\":\"Add retries\",\"body\":\"Retries model calls.\",\n}",
+			wantTitle: "Add retries",
+			wantBody:  "Retries model calls.",
+			wantPath:  parseRepaired,
+		},
+		{
+			name:      "unescaped newlines and tabs",
+			text:      "{\"title\":\"Add retries\",\"body\":\"## Summary\n\n- Retry 429\n\t- and 503\"}",
+			wantTitle: "Add retries",
+			wantBody:  "## Summary\n\n- Retry 429\n\t- and 503",
+			wantPath:  parseRepaired,
+		},
+		{
+			name:      "invalid escapes",
+			text:      `{"title":"Don\'t retry 400","body":"Matches C:\temp and C:\data paths."}`,
+			wantTitle: "Don't retry 400",
+			wantBody:  "Matches C:\temp and C:\\data paths.",
+			wantPath:  parseRepaired,
+		},
+		{
+			name:      "repair wins over markdown labels in the strings",
+			text:      "{\"title\":\"Title: Add retries\",\"body\":\"## Body\nRetries model calls.\",}",
+			wantTitle: "Title: Add retries",
+			wantBody:  "## Body\nRetries model calls.",
+			wantPath:  parseRepaired,
+		},
+		{
+			name:      "markdown instead of json",
+			text:      "## Title\nAdd retries\n\n## Body\nRetries model calls.",
+			wantTitle: "Add retries",
+			wantBody:  "Retries model calls.",
+			wantPath:  parseMarkdown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, path, err := parsePullRequestContent(tt.text)
+			if err != nil {
+				t.Fatalf("parsePullRequestContent() error: %v", err)
+			}
+			if got.Title != tt.wantTitle || got.Body != tt.wantBody {
+				t.Errorf("parsePullRequestContent() = %q, %q, want %q, %q", got.Title, got.Body, tt.wantTitle, tt.wantBody)
+			}
+			if path != tt.wantPath {
+				t.Errorf("parsePullRequestContent() path = %q, want %q", path, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestParsePullRequestContentFails(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		// A truncated response is left to the retry rather than accepted
+		// with its body cut short.
+		{"truncated object", `{"title":"Add retries","body":"Retries model`},
+		{"truncated after a key", `{"title":"Add retries",`},
+		{"empty body", `{"title":"Add retries","body":""}`},
+		{"missing title", `{"body":"Retries model calls."}`},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, path, err := parsePullRequestContent(tt.text)
+			if err == nil {
+				t.Errorf("parsePullRequestContent() = %+v via %s, want an error", got, path)
+			}
+		})
+	}
+}
+
+func TestRepairJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"valid", `{"a":"b"}`, `{"a":"b"}`},
+		{"trailing comma in array", `{"a":[1,2,]}`, `{"a":[1,2]}`},
+		{"comma inside a string", `{"a":"x, }"}`, `{"a":"x, }"}`},
+		{"raw newline", "{\"a\":\"x\ny\"}", `{"a":"x\ny"}`},
+		{"carriage return", "{\"a\":\"x\r\ny\"}", `{"a":"x\r\ny"}`},
+		{"control character", "{\"a\":\"x\x01y\"}", `{"a":"x\u0001y"}`},
+		{"escaped quote", `{"a":"say \"hi\""}`, `{"a":"say \"hi\""}`},
+		{"unknown escape", `{"a":"\d+"}`, `{"a":"\\d+"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := repairJSON(tt.text)
+			if got != tt.want {
+				t.Errorf("repairJSON(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			if !json.Valid([]byte(got)) {
+				t.Errorf("repairJSON(%q) = %q is not valid JSON", tt.text, got)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownPullRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		wantTitle string
+		wantBody  string
+	}{
+		{
+			name:      "heading labels",
+			text:      "## Title\nfeat: add retries\n\n## Body\n## Summary\n- Retries 429 and 5xx",
+			wantTitle: "feat: add retries",
+			wantBody:  "## Summary\n- Retries 429 and 5xx",
+		},
+		{
+			name:      "bold labels with colons",
+			text:      "**Title:** feat: add retries\n\n**Body:**\nRetries model calls on rate limits.",
+			wantTitle: "feat: add retries",
+			wantBody:  "Retries model calls on rate limits.",
+		},
+		{
+			name:      "bold labels with the colon inside",
+			text:      "**PR Title:**\nfeat: add retries\n\n**PR Body:**\nRetries model calls on rate limits.",
+			wantTitle: "feat: add retries",
+			wantBody:  "Retries model calls on rate limits.",
+		},
+		{
+			name:      "plain labels",
+			text:      "Title: feat: add retries\nBody: Retries model calls on rate limits.\n\nAlso documents retry.max_attempts.",
+			wantTitle: "feat: add retries",
+			wantBody:  "Retries model calls on rate limits.\n\nAlso documents retry.max_attempts.",
+		},
+		{
+			name:      "title label without a body label",
+			text:      "Title: feat: add retries\n\nRetries model calls on rate limits.",
+			wantTitle: "feat: add retries",
+			wantBody:  "Retries model calls on rate limits.",
+		},
+		{
+			name:      "quoted title",
+			text:      "## Pull Request Title\n\"feat: add retries\"\n\n## Pull Request Body\nRetries model calls.",
+			wantTitle: "feat: add retries",
+			wantBody:  "Retries model calls.",
+		},
+		{
+			name:      "lead-in before an unlabelled heading",
+			text:      "Here is the pull request description:\n\n# feat: add retries\n\nRetries model calls on rate limits.",
+			wantTitle: "feat: add retries",
+			wantBody:  "Retries model calls on rate limits.",
+		},
+		{
+			name:      "markdown fence",
+			text:      "```markdown\n# feat: add retries\n\nRetries model calls on rate limits.\n```",
+			wantTitle: "feat: add retries",
+			wantBody:  "Retries model calls on rate limits.",
+		},
+		{
+			name:      "body heading named like a label is kept",
+			text:      "# feat: add retries\n\n## Summary\nRetries model calls.\n\n## Testing\nRan go test.",
+			wantTitle: "feat: add retries",
+			wantBody:  "## Summary\nRetries model calls.\n\n## Testing\nRan go test.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseMarkdownPullRequest(tt.text)
+			if !ok {
+				t.Fatalf("parseMarkdownPullRequest(%q) failed", tt.text)
+			}
+			if got.Title != tt.wantTitle || got.Body != tt.wantBody {
+				t.Errorf("parseMarkdownPullRequest() = %q, %q, want %q, %q", got.Title, got.Body, tt.wantTitle, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownPullRequestRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"json", `{"title":"feat: add retries","body":`},
+		{"json fence", "```json\n{\"title\":\"x\"\n```"},
+		{"title only", "## Title\nfeat: add retries"},
+		{"single line", "feat: add retries"},
+		{"lead-in only", "Here is the pull request:"},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, ok := parseMarkdownPullRequest(tt.text); ok {
+				t.Errorf("parseMarkdownPullRequest(%q) = %+v, want failure", tt.text, got)
+			}
+		})
+	}
+}
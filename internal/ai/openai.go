@@ -0,0 +1,280 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/progress"
+)
+
+// OpenAIProvider is the name of the OpenAI provider in gelf.yml.
+const OpenAIProvider = "openai"
+
+// OpenAICompatibleProvider is the name in gelf.yml of the provider for other
+// servers speaking the OpenAI API, such as LiteLLM, vLLM and LM Studio.
+const OpenAICompatibleProvider = "openai-compatible"
+
+// defaultOpenAIBaseURL is where the OpenAI API is served unless
+// OPENAI_BASE_URL points at a compatible server.
+const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+func init() {
+	RegisterProvider(OpenAIProvider, func(ctx context.Context, cfg *config.Config) (Provider, error) {
+		client, err := NewOpenAIClient(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	})
+	RegisterProvider(OpenAICompatibleProvider, func(ctx context.Context, cfg *config.Config) (Provider, error) {
+		client, err := NewOpenAICompatibleClient(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	})
+}
+
+// NewOpenAIClient connects to the OpenAI chat completions API, the openai
+// provider. The API key is read from OPENAI_API_KEY; OPENAI_BASE_URL points
+// it at another server speaking the same API.
+func NewOpenAIClient(ctx context.Context, cfg *config.Config) (*Client, error) {
+	// Fail before any work is done for a request that could not be sent.
+	if err := allowCall(cfg.FlashModel); err != nil {
+		return nil, err
+	}
+
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		return nil, fmt.Errorf("OPENAI_API_KEY is not set; the openai provider needs an API key")
+	}
+	baseURL := os.Getenv("OPENAI_BASE_URL")
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	return newOpenAIClient(cfg, OpenAIProvider, baseURL, apiKey), nil
+}
+
+// NewOpenAICompatibleClient connects to the server at
+// cfg.OpenAICompatibleBaseURL, which speaks the OpenAI chat completions API,
+// the openai-compatible provider. Such servers have no default models, and
+// the API key, read from OPENAI_COMPATIBLE_API_KEY, is only sent when set,
+// as local servers take none.
+func NewOpenAICompatibleClient(ctx context.Context, cfg *config.Config) (*Client, error) {
+	if err := allowCall(cfg.FlashModel); err != nil {
+		return nil, err
+	}
+
+	if cfg.OpenAICompatibleBaseURL == "" {
+		return nil, fmt.Errorf("no server for the openai-compatible provider; set openai_compatible.base_url or OPENAI_COMPATIBLE_BASE_URL")
+	}
+	if cfg.FlashModel == "" || cfg.ProModel == "" {
+		return nil, fmt.Errorf("the openai-compatible provider has no default models; set model.flash and model.pro to models the server serves")
+	}
+	apiKey := os.Getenv("OPENAI_COMPATIBLE_API_KEY")
+	return newOpenAIClient(cfg, OpenAICompatibleProvider, cfg.OpenAICompatibleBaseURL, apiKey), nil
+}
+
+func newOpenAIClient(cfg *config.Config, provider, baseURL, apiKey string) *Client {
+	return &Client{
+		backend: openAIBackend{
+			provider: provider,
+			baseURL:  strings.TrimSuffix(baseURL, "/"),
+			apiKey:   apiKey,
+			http:     http.DefaultClient,
+		},
+		flashModel: cfg.FlashModel,
+		proModel:   cfg.ProModel,
+		reporter:   progress.Nop(),
+		retry:      newRetryPolicy(cfg),
+	}
+}
+
+// openAIBackend sends prompts to the chat completions API of OpenAI or of a
+// server speaking it.
+type openAIBackend struct {
+	// provider names the provider in timings and errors.
+	provider string
+	baseURL  string
+	// apiKey is sent as a bearer token when set.
+	apiKey string
+	http   *http.Client
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIRequest struct {
+	Model         string               `json:"model"`
+	Messages      []openAIMessage      `json:"messages"`
+	Temperature   float32              `json:"temperature"`
+	Seed          *int32               `json:"seed,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// openAIResponse is a chat completion, or one chunk of a streamed one.
+type openAIResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+		Delta   openAIMessage `json:"delta"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+func (b openAIBackend) name() string {
+	return b.provider
+}
+
+// post sends a chat completion request and returns the response body, or
+// the API's error message for a failed request.
+func (b openAIBackend) post(ctx context.Context, request openAIRequest) (io.ReadCloser, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if b.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+b.apiKey)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		var apiError struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		message := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiError) == nil && apiError.Error.Message != "" {
+			message = apiError.Error.Message
+		}
+		return nil, &statusError{provider: b.provider, status: resp.StatusCode, message: message}
+	}
+	return resp.Body, nil
+}
+
+func (b openAIBackend) request(request modelRequest, stream bool) openAIRequest {
+	r := openAIRequest{
+		Model:       request.Model,
+		Messages:    []openAIMessage{{Role: "user", Content: request.Prompt}},
+		Temperature: request.Temperature,
+		Seed:        request.Seed,
+		Stream:      stream,
+	}
+	if stream {
+		r.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+	return r
+}
+
+func (b openAIBackend) generate(ctx context.Context, request modelRequest) (modelResponse, error) {
+	body, err := b.post(ctx, b.request(request, false))
+	if err != nil {
+		return modelResponse{}, err
+	}
+	defer body.Close()
+
+	var completion openAIResponse
+	if err := json.NewDecoder(body).Decode(&completion); err != nil {
+		return modelResponse{}, fmt.Errorf("%s: failed to decode the response: %w", b.provider, err)
+	}
+	var response modelResponse
+	if completion.Usage != nil {
+		response.InputTokens = completion.Usage.PromptTokens
+		response.OutputTokens = completion.Usage.CompletionTokens
+	}
+	if len(completion.Choices) > 0 {
+		response.Text = completion.Choices[0].Message.Content
+	}
+	return response, nil
+}
+
+// generateStream reads the server-sent events of a streamed completion:
+// "data: <chunk>" lines ending with "data: [DONE]". With include_usage the
+// last chunk holds the usage and no choices.
+func (b openAIBackend) generateStream(ctx context.Context, request modelRequest, onChunk func(string)) (modelResponse, error) {
+	body, err := b.post(ctx, b.request(request, true))
+	if err != nil {
+		return modelResponse{}, err
+	}
+	defer body.Close()
+
+	var builder strings.Builder
+	var response modelResponse
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		var chunk openAIResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return modelResponse{}, fmt.Errorf("%s: failed to decode a streamed chunk: %w", b.provider, err)
+		}
+		if chunk.Usage != nil {
+			response.InputTokens = chunk.Usage.PromptTokens
+			response.OutputTokens = chunk.Usage.CompletionTokens
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+		builder.WriteString(chunk.Choices[0].Delta.Content)
+		onChunk(chunk.Choices[0].Delta.Content)
+	}
+	if err := scanner.Err(); err != nil {
+		return modelResponse{}, err
+	}
+	response.Text = builder.String()
+	return response, nil
+}
+
+// retryable reports whether err is a rate limit or a server error.
+func (b openAIBackend) retryable(err error) bool {
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && retryableStatus(statusErr.status)
+}
+
+// countTokens fails: the OpenAI API has no endpoint for counting tokens, so
+// callers fall back to estimates.
+func (b openAIBackend) countTokens(ctx context.Context, model, text string) (int, error) {
+	return 0, fmt.Errorf("the %s provider cannot count tokens", b.provider)
+}
+
+func (b openAIBackend) close() error {
+	return nil
+}
@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newOpenAITestBackend returns a backend for an httptest server answering
+// with handler.
+func newOpenAITestBackend(t *testing.T, apiKey string, handler http.HandlerFunc) openAIBackend {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return openAIBackend{provider: OpenAIProvider, baseURL: server.URL + "/v1", apiKey: apiKey, http: server.Client()}
+}
+
+// decodeOpenAIRequest checks the request line and headers of a chat
+// completion and returns its body.
+func decodeOpenAIRequest(t *testing.T, r *http.Request, apiKey string) map[string]any {
+	t.Helper()
+	if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+		t.Errorf("request = %s %s, want POST /v1/chat/completions", r.Method, r.URL.Path)
+	}
+	wantAuth := ""
+	if apiKey != "" {
+		wantAuth = "Bearer " + apiKey
+	}
+	if got := r.Header.Get("Authorization"); got != wantAuth {
+		t.Errorf("Authorization = %q, want %q", got, wantAuth)
+	}
+	if got := r.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		t.Errorf("request body: %v", err)
+	}
+	return body
+}
+
+func TestOpenAIGenerate(t *testing.T) {
+	var body map[string]any
+	backend := newOpenAITestBackend(t, "sk-test", func(w http.ResponseWriter, r *http.Request) {
+		body = decodeOpenAIRequest(t, r, "sk-test")
+		io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"feat: add retries"}}],"usage":{"prompt_tokens":120,"completion_tokens":8}}`)
+	})
+
+	seed := int32(7)
+	resp, err := backend.generate(context.Background(), modelRequest{Model: "gpt-4o-mini", Prompt: "describe", Temperature: 0.2, Seed: &seed})
+	if err != nil {
+		t.Fatalf("generate() error: %v", err)
+	}
+	if resp != (modelResponse{Text: "feat: add retries", InputTokens: 120, OutputTokens: 8}) {
+		t.Errorf("generate() = %+v", resp)
+	}
+
+	want := `{"messages":[{"content":"describe","role":"user"}],"model":"gpt-4o-mini","seed":7,"temperature":0.2}`
+	if got, _ := json.Marshal(body); string(got) != want {
+		t.Errorf("request body = %s, want %s", got, want)
+	}
+}
+
+func TestOpenAIGenerateStream(t *testing.T) {
+	var body map[string]any
+	backend := newOpenAITestBackend(t, "", func(w http.ResponseWriter, r *http.Request) {
+		body = decodeOpenAIRequest(t, r, "")
+		for _, event := range []string{
+			`{"choices":[{"delta":{"role":"assistant","content":""}}]}`,
+			`{"choices":[{"delta":{"content":"feat: "}}]}`,
+			`{"choices":[{"delta":{"content":"add retries"}}]}`,
+			`{"choices":[],"usage":{"prompt_tokens":95,"completion_tokens":4}}`,
+			`[DONE]`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+	})
+
+	var chunks []string
+	resp, err := backend.generateStream(context.Background(), modelRequest{Model: "local", Prompt: "describe"}, func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("generateStream() error: %v", err)
+	}
+	if resp != (modelResponse{Text: "feat: add retries", InputTokens: 95, OutputTokens: 4}) {
+		t.Errorf("generateStream() = %+v", resp)
+	}
+	if strings.Join(chunks, "|") != "feat: |add retries" {
+		t.Errorf("chunks = %q", chunks)
+	}
+
+	want := `{"messages":[{"content":"describe","role":"user"}],"model":"local","stream":true,"stream_options":{"include_usage":true},"temperature":0}`
+	if got, _ := json.Marshal(body); string(got) != want {
+		t.Errorf("request body = %s, want %s", got, want)
+	}
+}
+
+func TestOpenAIStreamBadChunk(t *testing.T) {
+	backend := newOpenAITestBackend(t, "", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"feat\"}}]}\n\ndata: {not json\n\n")
+	})
+	_, err := backend.generateStream(context.Background(), modelRequest{Model: "local"}, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "failed to decode a streamed chunk") {
+		t.Errorf("generateStream() error = %v, want a decode error", err)
+	}
+}
+
+func TestOpenAIErrorStatus(t *testing.T) {
+	tests := []struct {
+		status    int
+		body      string
+		message   string
+		retryable bool
+	}{
+		{http.StatusTooManyRequests, `{"error":{"message":"Rate limit reached"}}`, "Rate limit reached", true},
+		{http.StatusInternalServerError, `{"error":{"message":"The server had an error"}}`, "The server had an error", true},
+		{http.StatusBadGateway, "bad gateway", "bad gateway", true},
+		{http.StatusServiceUnavailable, `{"error":{"message":"overloaded"}}`, "overloaded", true},
+		{http.StatusBadRequest, `{"error":{"message":"context_length_exceeded"}}`, "context_length_exceeded", false},
+		{http.StatusUnauthorized, `{"error":{"message":"Incorrect API key"}}`, "Incorrect API key", false},
+		{http.StatusNotFound, `{"error":{"message":"model not found"}}`, "model not found", false},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			backend := newOpenAITestBackend(t, "sk-test", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			})
+			_, err := backend.generate(context.Background(), modelRequest{Model: "gpt-4o-mini"})
+			var statusErr *statusError
+			if !errors.As(err, &statusErr) || statusErr.status != tt.status || statusErr.message != tt.message {
+				t.Fatalf("generate() error = %v, want status %d with %q", err, tt.status, tt.message)
+			}
+			if got := backend.retryable(err); got != tt.retryable {
+				t.Errorf("retryable() = %v, want %v", got, tt.retryable)
+			}
+		})
+	}
+
+	backend := openAIBackend{provider: OpenAIProvider}
+	if backend.retryable(errors.New("connection reset")) || backend.retryable(context.Canceled) {
+		t.Error("retryable() is true for an error without a status")
+	}
+}
@@ -0,0 +1,68 @@
+package ai
+
+import "strings"
+
+// Labels of the parts of a prompt, naming where their text comes from.
+const (
+	// PartTask is gelf's own text: the task, the requirements and the
+	// headings around the other parts.
+	PartTask         = "task"
+	PartTemplate     = "template"
+	PartCommitLog    = "commit log"
+	PartDiffStat     = "diff stat"
+	PartDiff         = "diff"
+	PartUncommitted  = "uncommitted diff"
+	PartDependencies = "dependency notes"
+	PartFileHistory  = "file history"
+	PartDescription  = "current description"
+	PartMerge        = "merge"
+	PartContext      = "context"
+	PartInstructions = "project instructions"
+	// PartEdited is a whole prompt edited with --edit-prompt.
+	PartEdited = "edited prompt"
+)
+
+// PromptPart is one labelled piece of a prompt.
+type PromptPart struct {
+	Label string
+	Text  string
+}
+
+// Prompt is a prompt assembled from labelled parts, so that its size can be
+// broken down by where the text comes from. A label may occur more than
+// once.
+type Prompt []PromptPart
+
+// String returns the text sent to the model: the parts in order.
+func (p Prompt) String() string {
+	var b strings.Builder
+	for _, part := range p {
+		b.WriteString(part.Text)
+	}
+	return b.String()
+}
+
+// Text returns the text of the parts labelled label, in order.
+func (p Prompt) Text(label string) string {
+	var b strings.Builder
+	for _, part := range p {
+		if part.Label == label {
+			b.WriteString(part.Text)
+		}
+	}
+	return b.String()
+}
+
+// Labels returns the labels of the parts with text, each once, in the order
+// they first occur.
+func (p Prompt) Labels() []string {
+	var labels []string
+	seen := map[string]bool{}
+	for _, part := range p {
+		if part.Text != "" && !seen[part.Label] {
+			seen[part.Label] = true
+			labels = append(labels, part.Label)
+		}
+	}
+	return labels
+}
@@ -0,0 +1,130 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/progress"
+)
+
+// Provider generates gelf's text with one model provider. The commands
+// depend only on Provider; NewProvider picks the implementation the
+// configuration names. The providers of this package are Clients, which
+// share gelf's prompts and the parsing of the answers and differ only in
+// the backend the prompts are sent through.
+type Provider interface {
+	GenerateCommitMessage(ctx context.Context, input CommitInput) (string, error)
+	// GenerateCommitMessageStream streams the response, reporting
+	// progress.Status events as it arrives.
+	GenerateCommitMessageStream(ctx context.Context, input CommitInput) (string, error)
+	GenerateStashDescription(ctx context.Context, input CommitInput) (string, error)
+	GeneratePullRequestContent(ctx context.Context, input PullRequestInput) (*PullRequestContent, error)
+	GenerateBranchName(ctx context.Context, commitLog string, instructions string) (string, error)
+	ProposeSplit(ctx context.Context, input SplitInput) ([]SplitGroup, error)
+	// GenerateExplanation, GenerateReview and GenerateComparison stream
+	// the response to onChunk when it is not nil.
+	GenerateExplanation(ctx context.Context, input ExplainInput, onChunk func(string)) (string, error)
+	GenerateReview(ctx context.Context, input ReviewInput, onChunk func(string)) (string, error)
+	GenerateComparison(ctx context.Context, input CompareInput, onChunk func(string)) (string, error)
+
+	// CountTokens counts the tokens of each of texts with the model's
+	// tokenizer, without counting as a model call.
+	CountTokens(ctx context.Context, texts []string) ([]int, error)
+	// Model returns the model the provider generates with.
+	Model() string
+
+	// SetReporter sets where generation progress events are sent.
+	SetReporter(reporter progress.Reporter)
+	// WithModel, WithSeed and WithReporter return a copy sharing the
+	// connection, so that copies can be used concurrently.
+	WithModel(model string) Provider
+	WithSeed(seed int32) Provider
+	WithReporter(reporter progress.Reporter) Provider
+	Close() error
+}
+
+// DefaultProvider is the provider used when the configuration names none.
+const DefaultProvider = "vertex_ai"
+
+// ProviderFactory connects to a provider with the configuration.
+type ProviderFactory func(ctx context.Context, cfg *config.Config) (Provider, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]ProviderFactory{}
+)
+
+// RegisterProvider makes a provider available under name, the value of the
+// provider key in gelf.yml. Registering a name twice panics.
+func RegisterProvider(name string, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if _, ok := providers[name]; ok {
+		panic(fmt.Sprintf("ai: provider %q registered twice", name))
+	}
+	providers[name] = factory
+}
+
+// Providers returns the names of the registered providers, sorted.
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewProvider connects to the provider cfg.Provider names, or to
+// DefaultProvider when it names none.
+func NewProvider(ctx context.Context, cfg *config.Config) (Provider, error) {
+	name := cfg.Provider
+	if name == "" {
+		name = DefaultProvider
+	}
+	providersMu.RLock()
+	factory, ok := providers[name]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q (available: %s)", name, strings.Join(Providers(), ", "))
+	}
+	return factory(ctx, cfg)
+}
+
+// backend sends prompts to the models of one provider.
+type backend interface {
+	// name labels the provider's calls in timings, such as "vertex".
+	name() string
+	// generate returns the text of the answer, "" when there is none.
+	generate(ctx context.Context, request modelRequest) (modelResponse, error)
+	// generateStream is generate with the answer passed to onChunk as it
+	// arrives.
+	generateStream(ctx context.Context, request modelRequest, onChunk func(string)) (modelResponse, error)
+	// retryable reports whether a call that failed with err may succeed
+	// when tried again shortly.
+	retryable(err error) bool
+	countTokens(ctx context.Context, model, text string) (int, error)
+	close() error
+}
+
+// modelRequest is one prompt for a model.
+type modelRequest struct {
+	Model       string
+	Prompt      string
+	Temperature float32
+	// Seed, when set, asks for the same answer to the same prompt.
+	Seed *int32
+}
+
+// modelResponse is a model's answer with the tokens the call used.
+type modelResponse struct {
+	Text         string
+	InputTokens  int
+	OutputTokens int
+}
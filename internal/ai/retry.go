@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/config"
+)
+
+// maxRetryDelay caps the wait between two attempts of a model call.
+const maxRetryDelay = 30 * time.Second
+
+// retryPolicy is how a Client retries model calls that failed with a rate
+// limit or a server error.
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	// sleep waits d and reports whether ctx is still live; nil waits on a
+	// timer.
+	sleep func(ctx context.Context, d time.Duration) bool
+}
+
+// newRetryPolicy returns the retry policy of cfg, with the defaults of
+// config for values it leaves unset.
+func newRetryPolicy(cfg *config.Config) retryPolicy {
+	policy := retryPolicy{maxAttempts: cfg.RetryMaxAttempts, baseDelay: cfg.RetryBaseDelay}
+	if policy.maxAttempts <= 0 {
+		policy.maxAttempts = config.DefaultRetryMaxAttempts
+	}
+	if policy.baseDelay <= 0 {
+		policy.baseDelay = config.DefaultRetryBaseDelay
+	}
+	return policy
+}
+
+// delay returns the wait before retry n, counting from 1: the base delay
+// doubled for each earlier retry, capped at maxRetryDelay, of which a random
+// part in the upper half is waited so that clients failing together do not
+// retry together.
+func (p retryPolicy) delay(n int) time.Duration {
+	d := maxRetryDelay
+	if n-1 < 16 {
+		d = min(p.baseDelay<<(n-1), maxRetryDelay)
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// wait waits d, or less when ctx ends first, and reports whether ctx is
+// still live.
+func (p retryPolicy) wait(ctx context.Context, d time.Duration) bool {
+	if p.sleep != nil {
+		return p.sleep(ctx, d)
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// retryableStatus reports whether a request that failed with the HTTP status
+// is worth retrying: rate limits and server errors usually pass in seconds.
+func retryableStatus(status int) bool {
+	switch status {
+	case 429, 500, 502, 503, 504:
+		return true
+	}
+	return false
+}
+
+// statusError is a request a provider's API answered with an error status.
+type statusError struct {
+	provider string
+	status   int
+	message  string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s: %s (HTTP %d)", e.provider, e.message, e.status)
+}
+
+// withRetry calls call until it succeeds, fails with an error retryable
+// rejects, ctx ends or the attempts of the policy are used up, and returns
+// how many attempts were made with the error of the last one.
+func (v *Client) withRetry(ctx context.Context, retryable func(error) bool, call func() error) (int, error) {
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil || !retryable(err) {
+			return attempt, err
+		}
+		if attempt >= v.retry.maxAttempts {
+			if attempt > 1 {
+				err = fmt.Errorf("%w (gave up after %d attempts)", err, attempt)
+			}
+			return attempt, err
+		}
+
+		if !v.retry.wait(ctx, v.retry.delay(attempt)) {
+			return attempt, err
+		}
+	}
+}
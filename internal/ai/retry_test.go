@@ -0,0 +1,265 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/progress"
+)
+
+// fakeCall is the outcome of one call to a fakeBackend: the chunks it
+// streams before failing with err, or returning the chunks as its text.
+type fakeCall struct {
+	chunks []string
+	err    error
+}
+
+// fakeBackend answers calls from a script and counts them.
+type fakeBackend struct {
+	calls []fakeCall
+	made  int
+}
+
+func (b *fakeBackend) name() string {
+	return "fake"
+}
+
+func (b *fakeBackend) next() fakeCall {
+	call := b.calls[min(b.made, len(b.calls)-1)]
+	b.made++
+	return call
+}
+
+func (b *fakeBackend) generate(ctx context.Context, request modelRequest) (modelResponse, error) {
+	call := b.next()
+	if call.err != nil {
+		return modelResponse{}, call.err
+	}
+	return modelResponse{Text: strings.Join(call.chunks, "")}, nil
+}
+
+func (b *fakeBackend) generateStream(ctx context.Context, request modelRequest, onChunk func(string)) (modelResponse, error) {
+	call := b.next()
+	for _, chunk := range call.chunks {
+		onChunk(chunk)
+	}
+	if call.err != nil {
+		return modelResponse{}, call.err
+	}
+	return modelResponse{Text: strings.Join(call.chunks, "")}, nil
+}
+
+func (b *fakeBackend) retryable(err error) bool {
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && retryableStatus(statusErr.status)
+}
+
+func (b *fakeBackend) countTokens(ctx context.Context, model, text string) (int, error) {
+	return 0, errors.New("not supported")
+}
+
+func (b *fakeBackend) close() error {
+	return nil
+}
+
+var (
+	errRateLimited = &statusError{provider: "fake", status: 429, message: "rate limited"}
+	errBadRequest  = &statusError{provider: "fake", status: 400, message: "bad request"}
+)
+
+// newFakeClient returns a client of backend that retries up to maxAttempts
+// times and records its waits instead of sleeping.
+func newFakeClient(backend *fakeBackend, maxAttempts int, waits *[]time.Duration) *Client {
+	return &Client{
+		backend:    backend,
+		flashModel: "fake-model",
+		reporter:   progress.Nop(),
+		retry: retryPolicy{
+			maxAttempts: maxAttempts,
+			baseDelay:   time.Second,
+			sleep: func(ctx context.Context, d time.Duration) bool {
+				*waits = append(*waits, d)
+				return ctx.Err() == nil
+			},
+		},
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := retryPolicy{baseDelay: time.Second}
+	tests := []struct {
+		retry int
+		max   time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{5, 16 * time.Second},
+		{6, maxRetryDelay},
+		{20, maxRetryDelay},
+		{100, maxRetryDelay},
+	}
+	for _, tt := range tests {
+		for range 200 {
+			if d := policy.delay(tt.retry); d < tt.max/2 || d > tt.max {
+				t.Fatalf("delay(%d) = %v, want within [%v, %v]", tt.retry, d, tt.max/2, tt.max)
+			}
+		}
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		calls    []fakeCall
+		wantText string
+		wantErr  string
+		attempts int
+	}{
+		{
+			name:     "first attempt succeeds",
+			calls:    []fakeCall{{chunks: []string{"feat: add retries"}}},
+			wantText: "feat: add retries",
+			attempts: 1,
+		},
+		{
+			name:     "rate limit then success",
+			calls:    []fakeCall{{err: errRateLimited}, {err: errRateLimited}, {chunks: []string{"feat: add retries"}}},
+			wantText: "feat: add retries",
+			attempts: 3,
+		},
+		{
+			name:     "not retryable",
+			calls:    []fakeCall{{err: errBadRequest}, {chunks: []string{"unreachable"}}},
+			wantErr:  "fake: bad request (HTTP 400)",
+			attempts: 1,
+		},
+		{
+			name:     "attempts used up",
+			calls:    []fakeCall{{err: errRateLimited}},
+			wantErr:  "fake: rate limited (HTTP 429) (gave up after 4 attempts)",
+			attempts: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeBackend{calls: tt.calls}
+			var waits []time.Duration
+			client := newFakeClient(backend, 4, &waits)
+
+			text, err := client.callModel(context.Background(), "describe", 0)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("callModel() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil || text != tt.wantText {
+				t.Errorf("callModel() = %q, %v, want %q", text, err, tt.wantText)
+			}
+			if backend.made != tt.attempts {
+				t.Errorf("made %d calls, want %d", backend.made, tt.attempts)
+			}
+			if len(waits) != tt.attempts-1 {
+				t.Fatalf("waited %d times, want %d", len(waits), tt.attempts-1)
+			}
+			for i, d := range waits {
+				if limit := time.Second << i; d < limit/2 || d > limit {
+					t.Errorf("wait %d = %v, want within [%v, %v]", i+1, d, limit/2, limit)
+				}
+			}
+		})
+	}
+}
+
+func TestWithRetryOneAttempt(t *testing.T) {
+	backend := &fakeBackend{calls: []fakeCall{{err: errRateLimited}}}
+	var waits []time.Duration
+	_, err := newFakeClient(backend, 1, &waits).callModel(context.Background(), "describe", 0)
+	if !errors.Is(err, errRateLimited) || strings.Contains(err.Error(), "gave up") {
+		t.Errorf("callModel() error = %v, want the rate limit alone", err)
+	}
+	if backend.made != 1 || len(waits) != 0 {
+		t.Errorf("made %d calls and %d waits, want 1 and 0", backend.made, len(waits))
+	}
+}
+
+func TestWithRetryStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	backend := &fakeBackend{calls: []fakeCall{{err: errRateLimited}}}
+	var waits []time.Duration
+	client := newFakeClient(backend, 5, &waits)
+	sleep := client.retry.sleep
+	client.retry.sleep = func(ctx context.Context, d time.Duration) bool {
+		cancel()
+		return sleep(ctx, d)
+	}
+
+	_, err := client.callModel(ctx, "describe", 0)
+	if !errors.Is(err, errRateLimited) {
+		t.Errorf("callModel() error = %v, want the rate limit", err)
+	}
+	if backend.made != 1 || len(waits) != 1 {
+		t.Errorf("made %d calls and %d waits, want 1 and 1", backend.made, len(waits))
+	}
+}
+
+func TestRetryPolicyWaitTimer(t *testing.T) {
+	var policy retryPolicy
+	if !policy.wait(context.Background(), time.Millisecond) {
+		t.Error("wait() = false with a live context")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if policy.wait(ctx, time.Hour) {
+		t.Error("wait() = true with an ended context")
+	}
+	if time.Since(start) > time.Second {
+		t.Error("wait() did not return when the context ended")
+	}
+}
+
+func TestCallModelStreamRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		calls    []fakeCall
+		want     string
+		wantErr  error
+		attempts int
+	}{
+		{
+			name:     "failure before the first chunk is retried",
+			calls:    []fakeCall{{err: errRateLimited}, {chunks: []string{"feat: ", "add retries"}}},
+			want:     "feat: |add retries",
+			attempts: 2,
+		},
+		{
+			name:     "failure after a chunk is not retried",
+			calls:    []fakeCall{{chunks: []string{"feat: "}, err: errRateLimited}, {chunks: []string{"feat: ", "add retries"}}},
+			want:     "feat: ",
+			wantErr:  errRateLimited,
+			attempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeBackend{calls: tt.calls}
+			var waits []time.Duration
+			var chunks []string
+			_, err := newFakeClient(backend, 4, &waits).callModelStream(context.Background(), "describe", 0, func(chunk string) {
+				chunks = append(chunks, chunk)
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("callModelStream() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := strings.Join(chunks, "|"); got != tt.want {
+				t.Errorf("chunks = %q, want %q", got, tt.want)
+			}
+			if backend.made != tt.attempts {
+				t.Errorf("made %d calls, want %d", backend.made, tt.attempts)
+			}
+		})
+	}
+}
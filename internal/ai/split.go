@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/prbody"
+)
+
+// SplitInput describes a branch too large to review as one pull request.
+type SplitInput struct {
+	BaseBranch string
+	HeadBranch string
+	// Commits lists the commits oldest first, one "<sha> <subject>" line
+	// each, followed by the files the commit changes, indented.
+	Commits  string
+	DiffStat string
+	Language string
+	// Instructions holds the standing project instructions, if any.
+	Instructions string
+	// Context is extra background given with --context.
+	Context string
+}
+
+// SplitGroup is one proposed pull request of a split: its title and the
+// abbreviated SHAs of its commits.
+type SplitGroup struct {
+	Title   string   `json:"title"`
+	Commits []string `json:"commits"`
+}
+
+// BuildSplitPrompt builds the prompt that asks for a branch to be
+// partitioned into stacked pull requests.
+func BuildSplitPrompt(input SplitInput) string {
+	return fmt.Sprintf(`You are an experienced reviewer splitting a branch that is too large to review into a stack of smaller pull requests.
+
+OUTPUT FORMAT:
+- Respond with ONLY a valid JSON object.
+- No markdown fences or extra text.
+- JSON schema: {"groups":[{"title":"...", "commits":["<sha>", ...]}, ...]}
+
+REQUIREMENTS:
+- Partition the COMMITS into groups; every commit belongs to exactly one group. Use the SHAs exactly as given.
+- Each group becomes one pull request, stacked on the previous group in the order you return them, so a group may only depend on commits in itself or in earlier groups.
+- Prefer keeping commits in their original order; move a commit only when it clearly belongs with another group and does not depend on the commits it jumps over.
+- Make each group coherent (one purpose a reviewer can follow) and roughly similar in size.
+- Use as few groups as give reviewable pull requests; a single group means the branch should not be split.
+- Write each title in %s, in imperative mood, under 72 characters.
+
+BASE BRANCH: %s
+HEAD BRANCH: %s
+
+COMMITS (oldest to newest, each with the files it changes):
+%s
+
+DIFF STAT:
+%s`, input.Language, input.BaseBranch, input.HeadBranch, input.Commits, input.DiffStat) + contextSection(input.Context) + instructionsSection(input.Instructions)
+}
+
+// ProposeSplit asks the model how to partition the commits of a branch into
+// stacked pull requests. The groups are returned in stack order and are not
+// checked against the commits; that is up to the caller.
+func (v *Client) ProposeSplit(ctx context.Context, input SplitInput) ([]SplitGroup, error) {
+	responseText, err := v.generateText(ctx, BuildSplitPrompt(input), 0.2, "Proposing a split...")
+	if err != nil {
+		return nil, fmt.Errorf("failed to propose a split: %w", err)
+	}
+
+	groups, err := parseSplitGroups(responseText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the proposed split: %w", err)
+	}
+	return groups, nil
+}
+
+// parseSplitGroups decodes the {"groups":[...]} response, repairing it like
+// a pull request response when strict decoding fails.
+func parseSplitGroups(text string) ([]SplitGroup, error) {
+	text = strings.TrimSpace(text)
+	var result struct {
+		Groups []SplitGroup `json:"groups"`
+	}
+	err := json.Unmarshal([]byte(text), &result)
+	if err != nil {
+		if repairErr := json.Unmarshal([]byte(repairJSON(text)), &result); repairErr != nil {
+			return nil, err
+		}
+	}
+	if len(result.Groups) == 0 {
+		return nil, fmt.Errorf("no groups in the response")
+	}
+
+	for i := range result.Groups {
+		result.Groups[i].Title = prbody.SanitizeTitle(result.Groups[i].Title)
+		if result.Groups[i].Title == "" {
+			return nil, fmt.Errorf("group %d has no title", i+1)
+		}
+	}
+	return result.Groups, nil
+}
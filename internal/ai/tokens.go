@@ -0,0 +1,45 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+)
+
+// EstimateTokens estimates the tokens text takes without asking the model:
+// one per four ASCII characters and one per other character, which is close
+// to the Gemini tokenizer for English, code and CJK text alike.
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// Model returns the model the client generates with.
+func (v *Client) Model() string {
+	return v.flashModel
+}
+
+// CountTokens counts the tokens of each of texts with the tokenizer of the
+// client's model. Counting is not a model call: it is neither held to nor
+// counted against a budget.
+func (v *Client) CountTokens(ctx context.Context, texts []string) ([]int, error) {
+	counts := make([]int, len(texts))
+	for i, text := range texts {
+		if text == "" {
+			continue
+		}
+		count, err := v.backend.countTokens(ctx, v.flashModel, text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count tokens: %w", err)
+		}
+		counts[i] = count
+	}
+	return counts, nil
+}
@@ -0,0 +1,43 @@
+package ai
+
+import "sync"
+
+// UsageTracker is told about every model call, so that use can be counted
+// and held to a budget.
+type UsageTracker interface {
+	// Allow returns an error when another call to model would go over
+	// budget.
+	Allow(model string) error
+	// Record counts a call to model through provider that succeeded.
+	Record(provider, model string, inputTokens, outputTokens int)
+}
+
+var (
+	usageMu      sync.RWMutex
+	usageTracker UsageTracker
+)
+
+// SetUsageTracker sets the tracker every client reports to. nil turns
+// tracking off.
+func SetUsageTracker(tracker UsageTracker) {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	usageTracker = tracker
+}
+
+func allowCall(model string) error {
+	usageMu.RLock()
+	defer usageMu.RUnlock()
+	if usageTracker == nil {
+		return nil
+	}
+	return usageTracker.Allow(model)
+}
+
+func recordCall(provider, model string, inputTokens, outputTokens int) {
+	usageMu.RLock()
+	defer usageMu.RUnlock()
+	if usageTracker != nil {
+		usageTracker.Record(provider, model, inputTokens, outputTokens)
+	}
+}
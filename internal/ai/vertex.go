@@ -2,12 +2,18 @@ package ai
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 
+	"github.com/EkeMinusYou/gelf/internal/commitmsg"
 	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	"github.com/EkeMinusYou/gelf/internal/prbody"
+	"github.com/EkeMinusYou/gelf/internal/progress"
+	"github.com/EkeMinusYou/gelf/internal/timing"
 	"google.golang.org/genai"
 )
 
@@ -21,20 +27,165 @@ type PullRequestInput struct {
 	Language      string
 	TitleLanguage string
 	BodyLanguage  string
+	// FileCategories summarizes the changed files by category, e.g.
+	// "source: 3, test: 2", and DominantCategory names the category with
+	// the most changed lines. DocsOnly is set when only docs changed.
+	FileCategories   string
+	DominantCategory string
+	DocsOnly         bool
+	// UncommittedDiff holds work that is not committed yet (--wip). It is
+	// described as upcoming work, not as part of the pull request.
+	UncommittedDiff string
+	// DependencyUpdates lists the bumped dependencies with release notes of
+	// a dependency-only branch; DependencySection is appended to the body.
+	DependencyUpdates string
+	DependencySection string
+	// Scope, when set, is the Conventional Commits scope the title must use
+	// (from monorepo.scopes).
+	Scope string
+	// Instructions holds the standing project instructions, if any.
+	Instructions string
+	// Context is extra background given with --context, such as companion
+	// pull requests in other repositories.
+	Context string
+	// Previous, when set, is the existing pull request being extended
+	// (--append-update): only an addendum describing CommitLog and Diff is
+	// generated and appended to its body under UpdateHeading.
+	Previous      *PullRequestContent
+	UpdateHeading string
+	// Title, when set, is used as the title and only the body is generated.
+	Title string
+	// FileOrder is the order of the files of Diff and UncommittedDiff in
+	// the prompt (prompt.file_order); "" keeps git's order.
+	FileOrder string
+	// Prompt, when set, is sent as-is instead of the prompt built from the
+	// fields above.
+	Prompt string
 }
 
+// WIPNote is prepended to bodies generated with uncommitted changes.
+const WIPNote = "> 🚧 Work in progress: description generated from uncommitted changes."
+
 type PullRequestContent struct {
 	Title string `json:"title"`
 	Body  string `json:"body"`
 }
 
-type VertexAIClient struct {
-	client     *genai.Client
+// ExplainInput describes a directory to explain.
+type ExplainInput struct {
+	Path         string
+	Files        []string
+	Exports      string
+	Excerpts     string
+	CommitLog    string
+	Language     string
+	Onboarding   bool
+	Instructions string
+}
+
+// CommitInput describes the staged changes to write a commit message for.
+type CommitInput struct {
+	Diff     string
+	Language string
+	// SubjectLanguage and BodyLanguage override Language for the subject
+	// line and the body.
+	SubjectLanguage string
+	BodyLanguage    string
+	// Template is the repository's commit.template content, if any.
+	Template string
+	// Scope, when set, is the Conventional Commits scope the message must
+	// use (from monorepo.scopes).
+	Scope string
+	// Instructions holds the standing project instructions, if any.
+	Instructions string
+	// Merge is set when a merge is being committed.
+	Merge *MergeInput
+	// Stash asks for a stash description instead of a commit message.
+	Stash bool
+	// PathHistory holds recent commit subjects of the most changed files
+	// (commit.per_path_style).
+	PathHistory []PathHistory
+	// FileOrder is the order of the files of Diff in the prompt
+	// (prompt.file_order); "" keeps git's order.
+	FileOrder string
+}
+
+// PathHistory is the recent commit subjects of one file, newest first.
+type PathHistory struct {
+	Path     string
+	Subjects []string
+}
+
+// MergeInput describes a merge in progress: git's prepared message, the
+// commits it brings in and how conflicts were resolved.
+type MergeInput struct {
+	Message    string
+	Commits    string
+	Conflicts  []string
+	Resolution string
+}
+
+// ReviewInput describes a change to review. CommitMessage is set when a
+// single commit is reviewed.
+type ReviewInput struct {
+	Diff          string
+	CommitMessage string
+	Language      string
+	Instructions  string
+	// Template is the pull request template the change will be opened
+	// with; the review then says which of its requirements are unmet.
+	Template string
+	// FileOrder is the order of the files of Diff in the prompt
+	// (prompt.file_order); "" keeps git's order.
+	FileOrder string
+}
+
+// CompareInput describes two branches implementing the same thing, each
+// with its diff against the commit they fork from.
+type CompareInput struct {
+	BranchA string
+	DiffA   string
+	BranchB string
+	DiffB   string
+	// MergeBase is the commit both diffs are against.
+	MergeBase    string
+	Language     string
+	Instructions string
+	FileOrder    string
+}
+
+// Client is a Provider that builds gelf's prompts, sends them through a
+// backend and parses the answers. The providers differ only in the backend.
+type Client struct {
+	backend    backend
 	flashModel string
 	proModel   string
+	reporter   progress.Reporter
+	// seed, when set, is sent with every request (WithSeed).
+	seed *int32
+	// retry is how calls failing with a rate limit or a server error are
+	// retried.
+	retry retryPolicy
+}
+
+func init() {
+	RegisterProvider(DefaultProvider, func(ctx context.Context, cfg *config.Config) (Provider, error) {
+		client, err := NewVertexAIClient(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	})
 }
 
-func NewVertexAIClient(ctx context.Context, cfg *config.Config) (*VertexAIClient, error) {
+// NewVertexAIClient connects to Gemini models on Vertex AI, the vertex_ai
+// provider.
+func NewVertexAIClient(ctx context.Context, cfg *config.Config) (*Client, error) {
+	// Fail before any work is done for a request that could not be sent.
+	if err := allowCall(cfg.FlashModel); err != nil {
+		return nil, err
+	}
+
 	// Check for GELF_CREDENTIALS first, then fall back to GOOGLE_APPLICATION_CREDENTIALS
 	credentialsPath := os.Getenv("GELF_CREDENTIALS")
 	if credentialsPath == "" {
@@ -66,15 +217,259 @@ func NewVertexAIClient(ctx context.Context, cfg *config.Config) (*VertexAIClient
 		return nil, fmt.Errorf("failed to create Vertex AI client: %w", err)
 	}
 
-	return &VertexAIClient{
-		client:     client,
+	return &Client{
+		backend:    vertexBackend{client: client},
 		flashModel: cfg.FlashModel,
 		proModel:   cfg.ProModel,
+		reporter:   progress.Nop(),
+		retry:      newRetryPolicy(cfg),
 	}, nil
 }
 
-func (v *VertexAIClient) GenerateCommitMessage(ctx context.Context, diff string, language string) (string, error) {
-	prompt := fmt.Sprintf(`Analyze the following git diff and generate a precise commit message following the Conventional Commits specification.
+// SetReporter sets where generation progress events are sent.
+func (v *Client) SetReporter(reporter progress.Reporter) {
+	if reporter == nil {
+		reporter = progress.Nop()
+	}
+	v.reporter = reporter
+}
+
+// WithModel returns a client sharing v's connection that generates with
+// model. v itself is not changed, so both can be used concurrently.
+func (v *Client) WithModel(model string) Provider {
+	clone := *v
+	clone.flashModel = model
+	return &clone
+}
+
+// WithSeed returns a copy of v that sends seed with every request, so that
+// models supporting it answer the same prompt the same way across runs.
+func (v *Client) WithSeed(seed int32) Provider {
+	clone := *v
+	clone.seed = &seed
+	return &clone
+}
+
+// WithReporter is SetReporter on a copy of v, for callers that share one
+// client between concurrent requests.
+func (v *Client) WithReporter(reporter progress.Reporter) Provider {
+	clone := *v
+	clone.SetReporter(reporter)
+	return &clone
+}
+
+// generateText sends prompt to the model and returns the text of the first
+// candidate, reporting the "generate" phase and token usage.
+func (v *Client) generateText(ctx context.Context, prompt string, temperature float32, message string) (string, error) {
+	v.reporter.Report(progress.Event{Kind: progress.PhaseStart, Phase: "generate", Message: message})
+	text, err := v.callModel(ctx, prompt, temperature)
+	v.reporter.Report(progress.Event{Kind: progress.PhaseEnd, Phase: "generate", Err: err})
+	return text, err
+}
+
+func (v *Client) callModel(ctx context.Context, prompt string, temperature float32) (string, error) {
+	if err := allowCall(v.flashModel); err != nil {
+		return "", err
+	}
+	span := v.startModelSpan(temperature)
+	defer span.End()
+
+	var resp modelResponse
+	attempts, err := v.withRetry(ctx, v.backend.retryable, func() (err error) {
+		resp, err = v.backend.generate(ctx, v.request(prompt, temperature))
+		return err
+	})
+	if attempts > 1 {
+		span.SetAttr("attempts", attempts)
+	}
+	if err != nil {
+		return "", err
+	}
+	v.recordUsage(span, resp)
+
+	if resp.Text == "" {
+		return "", fmt.Errorf("empty response")
+	}
+	return resp.Text, nil
+}
+
+// callModelStream is callModel with the response streamed to onChunk as it
+// arrives. A stream is only retried when nothing of it reached onChunk, so
+// that no text is passed twice.
+func (v *Client) callModelStream(ctx context.Context, prompt string, temperature float32, onChunk func(string)) (string, error) {
+	if err := allowCall(v.flashModel); err != nil {
+		return "", err
+	}
+	span := v.startModelSpan(temperature)
+	span.SetAttr("stream", true)
+	defer span.End()
+
+	var resp modelResponse
+	streamed := false
+	retryable := func(err error) bool {
+		return !streamed && v.backend.retryable(err)
+	}
+	attempts, err := v.withRetry(ctx, retryable, func() (err error) {
+		resp, err = v.backend.generateStream(ctx, v.request(prompt, temperature), func(chunk string) {
+			streamed = true
+			onChunk(chunk)
+		})
+		return err
+	})
+	if attempts > 1 {
+		span.SetAttr("attempts", attempts)
+	}
+	if err != nil {
+		return "", err
+	}
+	v.recordUsage(span, resp)
+
+	if resp.Text == "" {
+		return "", fmt.Errorf("empty response")
+	}
+	return resp.Text, nil
+}
+
+// request describes a call to the client's model with prompt.
+func (v *Client) request(prompt string, temperature float32) modelRequest {
+	return modelRequest{Model: v.flashModel, Prompt: prompt, Temperature: temperature, Seed: v.seed}
+}
+
+// recordUsage reports and counts the tokens of a call that succeeded.
+func (v *Client) recordUsage(span *timing.Span, resp modelResponse) {
+	if resp.InputTokens > 0 || resp.OutputTokens > 0 {
+		span.SetAttr("input_tokens", resp.InputTokens)
+		span.SetAttr("output_tokens", resp.OutputTokens)
+		v.reporter.Report(progress.Event{
+			Kind:         progress.Tokens,
+			Phase:        "generate",
+			InputTokens:  resp.InputTokens,
+			OutputTokens: resp.OutputTokens,
+		})
+	}
+	recordCall(v.backend.name(), v.flashModel, resp.InputTokens, resp.OutputTokens)
+}
+
+// startModelSpan starts the timed "<backend> generate" call for a request.
+func (v *Client) startModelSpan(temperature float32) *timing.Span {
+	span := timing.StartCall(v.backend.name() + " generate")
+	span.SetAttr("model", v.flashModel)
+	span.SetAttr("temperature", temperature)
+	return span
+}
+
+// promptDiff returns diff with its files in order, a prompt.file_order
+// value; "" keeps git's order.
+func promptDiff(diff, order string) string {
+	if order == config.FileOrderSignificance {
+		return git.OrderDiffBySignificance(diff)
+	}
+	return diff
+}
+
+// instructionsSection appends the standing project instructions to a prompt.
+// They come from files the repository owner controls, so they are sent as-is,
+// only delimited from the rest of the prompt.
+func instructionsSection(instructions string) string {
+	if strings.TrimSpace(instructions) == "" {
+		return ""
+	}
+	return fmt.Sprintf(`
+
+PROJECT INSTRUCTIONS:
+Standing instructions for this project, between the BEGIN and END markers. Follow them unless they contradict the output format required above. Repo instructions take precedence over global ones.
+
+%s
+`, instructions)
+}
+
+// contextSection adds the --context text of a pull request, if any.
+func contextSection(context string) string {
+	if strings.TrimSpace(context) == "" {
+		return ""
+	}
+	return fmt.Sprintf(`
+
+ADDITIONAL CONTEXT:
+Background from the author, between the BEGIN and END markers. Use it to explain why the change is made and mention related pull requests it names, for example as "Companion PR in <repo>: <url>".
+
+BEGIN CONTEXT
+%s
+END CONTEXT
+`, strings.TrimSpace(context))
+}
+
+// languages returns the languages of the subject line and the body, which
+// default to Language.
+func (input CommitInput) languages() (string, string) {
+	subject, body := input.SubjectLanguage, input.BodyLanguage
+	if subject == "" {
+		subject = input.Language
+	}
+	if body == "" {
+		body = input.Language
+	}
+	return subject, body
+}
+
+// BuildCommitPrompt builds the prompt used to generate a commit message.
+func BuildCommitPrompt(input CommitInput) string {
+	return CommitPrompt(input).String()
+}
+
+// CommitPrompt is BuildCommitPrompt in labelled parts.
+func CommitPrompt(input CommitInput) Prompt {
+	if input.Merge != nil {
+		return mergePrompt(input)
+	}
+	if input.Stash {
+		return stashPrompt(input)
+	}
+
+	templateSection := ""
+	if strings.TrimSpace(input.Template) != "" {
+		templateSection = fmt.Sprintf(`
+COMMIT TEMPLATE:
+The repository configures the commit message template below. Use it as the base:
+- Keep its structure and fill each section with details from the diff.
+- Lines starting with "#" are instructions; follow them but omit them from the output.
+- The first line still follows the requirements above; a body is allowed.
+
+%s
+`, input.Template)
+	}
+
+	historySection := ""
+	if len(input.PathHistory) > 0 {
+		var b strings.Builder
+		for _, history := range input.PathHistory {
+			fmt.Fprintf(&b, "\n%s:\n", history.Path)
+			for _, subject := range history.Subjects {
+				fmt.Fprintf(&b, "- %s\n", subject)
+			}
+		}
+		historySection = fmt.Sprintf(`
+FILE HISTORY:
+Recent commit subjects of the files this change touches most, newest first. Where the subjects of a file share a convention (type, scope, wording), follow it for this commit. The requirements above still apply.
+%s`, b.String())
+	}
+
+	scopeRule := "9. Use scope when it helps clarify the area of change (e.g., auth, api, ui)"
+	if input.Scope != "" {
+		scopeRule = fmt.Sprintf("9. The scope MUST be exactly (%s), i.e. <type>(%s): <description>", input.Scope, input.Scope)
+	}
+
+	// A body in another language than the subject is a convention of its
+	// own, so the message gets one.
+	subjectLanguage, bodyLanguage := input.languages()
+	languageRule := fmt.Sprintf("1. Use %s language", subjectLanguage)
+	if bodyLanguage != subjectLanguage {
+		languageRule = fmt.Sprintf("1. Write the description on the first line in %s and the body in %s", subjectLanguage, bodyLanguage)
+		scopeRule += fmt.Sprintf("\n10. After the first line, add a blank line and a short body in %s explaining what changed and why, with lines under 72 columns", bodyLanguage)
+	}
+
+	return Prompt{
+		{PartTask, fmt.Sprintf(`Analyze the following git diff and generate a precise commit message following the Conventional Commits specification.
 
 DIFF ANALYSIS GUIDE:
 1. Look at file paths to understand what parts of the codebase are affected
@@ -84,15 +479,15 @@ DIFF ANALYSIS GUIDE:
 5. Identify the primary purpose: new feature, bug fix, refactoring, etc.
 
 COMMIT MESSAGE REQUIREMENTS:
-1. Use %s language
-2. Follow format: <type>[optional scope]: <description>
+%s
+2. Follow format: <type>[optional scope]: <description>, writing the prefix in ASCII (e.g. "feat(ui): ", never "feat（ui）：") even when the description is not in English
 3. Valid types: feat, fix, docs, style, refactor, test, chore, perf, ci, build, revert
-4. Keep under 72 characters total
+4. Keep under 72 columns total; full-width characters such as Japanese count as two
 5. Use imperative mood ("add" not "added")
 6. Start description with lowercase letter
 7. No period at the end
 8. If multiple changes, focus on the most significant one
-9. Use scope when it helps clarify the area of change (e.g., auth, api, ui)
+%s
 
 EXAMPLES:
 - feat(auth): add JWT token validation
@@ -102,38 +497,152 @@ EXAMPLES:
 - chore(deps): update react to version 18.2.0
 
 Git diff:
-%s
+`, languageRule, scopeRule)},
+		{PartDiff, promptDiff(input.Diff, input.FileOrder)},
+		{PartTask, "\n"},
+		{PartTemplate, templateSection},
+		{PartFileHistory, historySection},
+		{PartTask, "\nRespond with only the commit message, no additional text or formatting."},
+		{PartInstructions, instructionsSection(input.Instructions)},
+	}
+}
 
-Respond with only the commit message, no additional text or formatting.`, language, diff)
+// mergePrompt asks for a merge commit message that summarizes the merged
+// commits and the conflict resolutions rather than the combined diff.
+func mergePrompt(input CommitInput) Prompt {
+	orNone := func(text string) string {
+		if strings.TrimSpace(text) == "" {
+			return "NONE"
+		}
+		return text
+	}
+	_, bodyLanguage := input.languages()
+	conflicts := "NONE"
+	if len(input.Merge.Conflicts) > 0 {
+		conflicts = strings.Join(input.Merge.Conflicts, "\n")
+	}
 
-	resp, err := v.client.Models.GenerateContent(ctx, v.flashModel,
-		[]*genai.Content{
-			genai.NewContentFromText(prompt, genai.RoleUser),
-		},
-		&genai.GenerateContentConfig{
-			Temperature: genai.Ptr(float32(0.3)),
-		})
+	return Prompt{
+		{PartTask, fmt.Sprintf(`Write the commit message for a merge commit.
+
+REQUIREMENTS:
+1. Use %s language for the body
+2. First line: keep the subject of GIT MERGE MESSAGE (e.g. "Merge branch 'feature' into main") as it is
+3. Then a blank line and a short bullet list of what the MERGED COMMITS bring in, grouping related commits; do not list every commit when there are many
+4. If CONFLICTED FILES is not NONE, add a "Conflicts resolved:" section with one bullet per file explaining how the conflict was resolved, based on CONFLICT RESOLUTION
+5. Do not describe the merge as new work of its own
+6. Keep lines under 72 columns; full-width characters such as Japanese count as two
+
+GIT MERGE MESSAGE:
+`, bodyLanguage)},
+		{PartMerge, orNone(input.Merge.Message)},
+		{PartTask, "\n\nMERGED COMMITS (oldest to newest):\n"},
+		{PartCommitLog, orNone(input.Merge.Commits)},
+		{PartTask, "\n\nCONFLICTED FILES:\n"},
+		{PartMerge, conflicts},
+		{PartTask, "\n\nCONFLICT RESOLUTION (diff of the conflicted files against the automatic merge with its conflict markers, or a combined diff against both sides):\n"},
+		{PartDiff, orNone(input.Merge.Resolution)},
+		{PartTask, "\n\nRespond with only the commit message, no additional text or formatting."},
+		{PartInstructions, instructionsSection(input.Instructions)},
+	}
+}
+
+// stashPrompt asks for the one-line description git stash push -m stores,
+// written so the stash can be told apart in a list later.
+func stashPrompt(input CommitInput) Prompt {
+	subjectLanguage, _ := input.languages()
+	return Prompt{
+		{PartTask, fmt.Sprintf(`Analyze the following git diff of uncommitted work and write a one-line description for stashing it.
+
+REQUIREMENTS:
+1. Use %s language
+2. Describe the work in progress so it can be recognized later in a list of stashes, naming the area it touches
+3. Keep under 72 columns; full-width characters such as Japanese count as two
+4. No Conventional Commits type prefix, no quotes and no period at the end
+5. If multiple changes, focus on the most significant one
+
+EXAMPLES:
+- WIP retry logic for the upload client
+- Experiment with caching parsed templates
+- Half-done rename of config loader options
+
+Git diff:
+`, subjectLanguage)},
+		{PartDiff, promptDiff(input.Diff, input.FileOrder)},
+		{PartTask, "\n\nRespond with only the description, no additional text or formatting."},
+		{PartInstructions, instructionsSection(input.Instructions)},
+	}
+}
+
+// GenerateStashDescription generates the message for git stash push from
+// input.Diff. Only the first non-empty line of the response is kept.
+func (v *Client) GenerateStashDescription(ctx context.Context, input CommitInput) (string, error) {
+	input.Stash = true
+	prompt := BuildCommitPrompt(input)
+
+	text, err := v.generateText(ctx, prompt, 0.3, "Generating stash description...")
 	if err != nil {
-		return "", fmt.Errorf("failed to generate commit message: %w", err)
+		return "", fmt.Errorf("failed to generate stash description: %w", err)
 	}
 
-	if len(resp.Candidates) == 0 {
-		return "", fmt.Errorf("no candidates in response")
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.Trim(strings.TrimSpace(line), "`\"'"); line != "" {
+			return line, nil
+		}
 	}
+	return "", fmt.Errorf("the model returned an empty stash description")
+}
+
+func (v *Client) GenerateCommitMessage(ctx context.Context, input CommitInput) (string, error) {
+	prompt := BuildCommitPrompt(input)
 
-	if len(resp.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("no content parts in response")
+	text, err := v.generateText(ctx, prompt, 0.3, i18n.T("commit.generating"))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate commit message: %w", err)
 	}
 
-	part := resp.Candidates[0].Content.Parts[0]
-	if part.Text == "" {
-		return "", fmt.Errorf("empty text in response part")
+	return text, nil
+}
+
+// GenerateCommitMessageStream is GenerateCommitMessage with the response
+// streamed, reporting progress.Status events as it arrives so a caller can
+// show that the model is working on a large diff. When the stream fails
+// before anything arrived, it falls back to a single request.
+func (v *Client) GenerateCommitMessageStream(ctx context.Context, input CommitInput) (string, error) {
+	prompt := BuildCommitPrompt(input)
+
+	v.reporter.Report(progress.Event{Kind: progress.PhaseStart, Phase: "generate", Message: i18n.T("commit.generating")})
+	received := 0
+	text, err := v.callModelStream(ctx, prompt, 0.3, func(chunk string) {
+		message := "receiving"
+		if received == 0 {
+			message = "first candidate ready"
+		}
+		received += len([]rune(chunk))
+		v.reporter.Report(progress.Event{Kind: progress.Status, Phase: "generate", Message: message, Current: received})
+	})
+	if err != nil && received == 0 && ctx.Err() == nil {
+		text, err = v.callModel(ctx, prompt, 0.3)
+	}
+	v.reporter.Report(progress.Event{Kind: progress.PhaseEnd, Phase: "generate", Err: err})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate commit message: %w", err)
 	}
 
-	return part.Text, nil
+	return text, nil
 }
 
-func (v *VertexAIClient) GeneratePullRequestContent(ctx context.Context, input PullRequestInput) (*PullRequestContent, error) {
+// BuildPRPrompt builds the prompt used to generate pull request content.
+func BuildPRPrompt(input PullRequestInput) string {
+	return PRPrompt(input).String()
+}
+
+// PRPrompt is BuildPRPrompt in labelled parts.
+func PRPrompt(input PullRequestInput) Prompt {
+	if input.Previous != nil {
+		return prUpdatePrompt(input)
+	}
+
 	template := input.Template
 	if strings.TrimSpace(template) == "" {
 		template = "NONE"
@@ -149,7 +658,56 @@ func (v *VertexAIClient) GeneratePullRequestContent(ctx context.Context, input P
 		bodyLanguage = input.Language
 	}
 
-	prompt := fmt.Sprintf(`You are an expert software engineer writing a GitHub pull request title and description.
+	changeKind := ""
+	if input.FileCategories != "" {
+		changeKind = fmt.Sprintf("\nCHANGED FILES BY CATEGORY: %s (dominant: %s)\n", input.FileCategories, input.DominantCategory)
+		if hint := categoryHint(input); hint != "" {
+			changeKind += "- " + hint + "\n"
+		}
+	}
+
+	wip := ""
+	if strings.TrimSpace(input.UncommittedDiff) != "" {
+		wip = fmt.Sprintf(`
+WORK IN PROGRESS:
+- UNCOMMITTED CHANGES below are not part of this pull request yet.
+- Describe DIFF under a heading "Already in this PR" and UNCOMMITTED CHANGES under a heading "Coming next".
+- Base the title on the overall goal of both.
+
+UNCOMMITTED CHANGES:
+%s
+`, promptDiff(input.UncommittedDiff, input.FileOrder))
+	}
+
+	dependencies := ""
+	if strings.TrimSpace(input.DependencyUpdates) != "" {
+		dependencies = fmt.Sprintf(`
+DEPENDENCY UPDATE:
+- This branch only updates dependencies. A table of the bumps is appended to the body automatically; do not repeat it.
+- Title: "Bump <package> from <old> to <new>" for a single bump, otherwise "Bump <n> dependencies".
+- In the body, summarize the notable changes of each bump from its RELEASE NOTES, one bullet per package.
+- Call out breaking changes and required code or configuration changes explicitly; say when release notes were not available.
+
+DEPENDENCY BUMPS AND RELEASE NOTES:
+%s
+`, input.DependencyUpdates)
+	}
+
+	titleScope := ""
+	if input.Scope != "" {
+		titleScope = fmt.Sprintf("\n- Format the title as <type>(%s): <description>, with a Conventional Commits type such as feat or fix.", input.Scope)
+	}
+	titleRequirements := `TITLE REQUIREMENTS:
+- Concise and specific.
+- Use imperative mood.
+- Keep it under 72 characters if possible.` + titleScope
+	if input.Title != "" {
+		titleRequirements = fmt.Sprintf(`TITLE:
+- The title is already decided: %q. Return it unchanged as "title" and only write the body.`, input.Title)
+	}
+
+	return Prompt{
+		{PartTask, fmt.Sprintf(`You are an expert software engineer writing a GitHub pull request title and description.
 
 OUTPUT FORMAT:
 - Respond with ONLY a valid JSON object.
@@ -160,10 +718,7 @@ LANGUAGE:
 - Write the title in %s.
 - Write the body in %s.
 
-TITLE REQUIREMENTS:
-- Concise and specific.
-- Use imperative mood.
-- Keep it under 72 characters if possible.
+%s
 
 BODY REQUIREMENTS:
 - If PR_TEMPLATE is not "NONE", use it as the base text.
@@ -175,68 +730,405 @@ BODY REQUIREMENTS:
 
 BASE BRANCH: %s
 HEAD BRANCH: %s
+%s
+COMMITS (oldest to newest):
+`, titleLanguage, bodyLanguage, titleRequirements, input.BaseBranch, input.HeadBranch, changeKind)},
+		{PartCommitLog, input.CommitLog},
+		{PartTask, "\n\nDIFF STAT:\n"},
+		{PartDiffStat, input.DiffStat},
+		{PartTask, "\n\nDIFF:\n"},
+		{PartDiff, promptDiff(input.Diff, input.FileOrder)},
+		{PartTask, "\n\nPR_TEMPLATE:\n"},
+		{PartTemplate, template},
+		{PartTask, "\n"},
+		{PartUncommitted, wip},
+		{PartDependencies, dependencies},
+		{PartContext, contextSection(input.Context)},
+		{PartInstructions, instructionsSection(input.Instructions)},
+	}
+}
+
+// categoryHint steers the body towards what matters for the kind of change.
+func categoryHint(input PullRequestInput) string {
+	if input.DocsOnly {
+		return "This is a documentation-only change: keep the body to one short paragraph (still keep PR_TEMPLATE headings if a template is given)."
+	}
+	switch input.DominantCategory {
+	case "test":
+		return "Most changes are tests: write a detailed Testing section listing what the tests cover."
+	case "config":
+		return "Most changes are configuration or infrastructure: include deployment notes (rollout steps, required configuration, risks)."
+	case "dependency":
+		return "Most changes are dependency updates: list the updated dependencies and any upgrade risks."
+	}
+	return ""
+}
+
+func (v *Client) GeneratePullRequestContent(ctx context.Context, input PullRequestInput) (*PullRequestContent, error) {
+	span := timing.StartSpan("pr generate")
+	span.SetAttr("model", v.flashModel)
+	defer span.End()
+
+	prompt := input.Prompt
+	if strings.TrimSpace(prompt) == "" {
+		prompt = BuildPRPrompt(input)
+	}
+
+	responseText, err := v.generateText(ctx, prompt, 0.2, i18n.T("pr.generating"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate pull request content: %w", err)
+	}
+
+	if input.Previous != nil {
+		addendum := strings.TrimSpace(prbody.SanitizeBody(responseText))
+		if addendum == "" {
+			return nil, fmt.Errorf("generated update is empty")
+		}
+		return &PullRequestContent{
+			Title: input.Previous.Title,
+			Body:  fmt.Sprintf("%s\n\n## %s\n\n%s", strings.TrimSpace(input.Previous.Body), input.UpdateHeading, addendum),
+		}, nil
+	}
+
+	// A response that still fails after repair gets one retry in which the
+	// model fixes its own output.
+	result, path, err := parsePullRequestContent(responseText)
+	if err != nil {
+		fixed, genErr := v.generateText(ctx, buildJSONFixPrompt(responseText, err), 0, "Fixing pull request response...")
+		if genErr != nil {
+			return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+		}
+		result, _, err = parsePullRequestContent(fixed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JSON response after retry: %w", err)
+		}
+		path = parseRetried
+	}
+	span.SetAttr("json_parse", path)
+	timing.Note("pr response parsed: %s", path)
+
+	// A title without a type prefix is left as is and reported by the caller.
+	if input.Title != "" {
+		result.Title = input.Title
+	} else {
+		result.Title, _ = commitmsg.ApplyScope(result.Title, input.Scope)
+	}
+	if strings.TrimSpace(input.UncommittedDiff) != "" && !strings.HasPrefix(result.Body, WIPNote) {
+		result.Body = WIPNote + "\n\n" + result.Body
+	}
+	if input.DependencySection != "" && !strings.Contains(result.Body, input.DependencySection) {
+		result.Body += "\n\n" + input.DependencySection
+	}
+
+	return result, nil
+}
+
+// prUpdatePrompt asks for an addendum to an existing description that
+// covers only the commits pushed since it was last written.
+func prUpdatePrompt(input PullRequestInput) Prompt {
+	bodyLanguage := input.BodyLanguage
+	if bodyLanguage == "" {
+		bodyLanguage = input.Language
+	}
+
+	return Prompt{
+		{PartTask, fmt.Sprintf(`Write an addendum to the description of an existing pull request. The description below already covers the earlier commits; describe only the NEW COMMITS and their diff.
+
+REQUIREMENTS:
+- Write in %s
+- Use markdown bullet points; no heading (one is added for you)
+- Keep it short: what changed since the description was written and why
+- Do not repeat what the current description already says
+
+PULL REQUEST: %s
+
+CURRENT DESCRIPTION:
+`, bodyLanguage, input.Previous.Title)},
+		{PartDescription, input.Previous.Body},
+		{PartTask, "\n\nNEW COMMITS (oldest to newest):\n"},
+		{PartCommitLog, input.CommitLog},
+		{PartTask, "\n\nDIFF STAT:\n"},
+		{PartDiffStat, input.DiffStat},
+		{PartTask, "\n\nDIFF:\n"},
+		{PartDiff, promptDiff(input.Diff, input.FileOrder)},
+		{PartTask, "\n\nRespond with only the addendum in markdown, no additional text or code fences."},
+		{PartContext, contextSection(input.Context)},
+		{PartInstructions, instructionsSection(input.Instructions)},
+	}
+}
+
+// GenerateBranchName suggests a short branch name for the given commit
+// subjects. The result is not sanitized.
+func (v *Client) GenerateBranchName(ctx context.Context, commitLog string, instructions string) (string, error) {
+	prompt := fmt.Sprintf(`Suggest a git branch name for a pull request containing the following commits.
+
+REQUIREMENTS:
+- Format: <type>/<short-description>, e.g. feat/add-login-timeout or fix/null-user-id
+- Use lowercase English words separated by hyphens
+- Keep it under 40 characters
 
 COMMITS (oldest to newest):
 %s
 
-DIFF STAT:
+Respond with only the branch name, no additional text or formatting.`, commitLog) + instructionsSection(instructions)
+
+	text, err := v.generateText(ctx, prompt, 0.2, "Suggesting branch name...")
+	if err != nil {
+		return "", fmt.Errorf("failed to generate branch name: %w", err)
+	}
+
+	return strings.Trim(strings.TrimSpace(text), "`"), nil
+}
+
+// BuildExplainPrompt builds the prompt used to explain a directory.
+func BuildExplainPrompt(input ExplainInput) string {
+	audience := `AUDIENCE:
+- Experienced engineers who want a concise technical summary.`
+	sections := "Purpose, Key components, How it fits the repository"
+	if input.Onboarding {
+		audience = `AUDIENCE:
+- New contributors who have never seen this code.
+- Explain concepts they need before reading the code and where to start reading.`
+		sections = "Purpose, Key components, How it fits the repository, Where to start, Recent activity"
+	}
+
+	orNone := func(text string) string {
+		if strings.TrimSpace(text) == "" {
+			return "NONE"
+		}
+		return text
+	}
+
+	return fmt.Sprintf(`You are an expert software engineer explaining part of a code base.
+
+%s
+
+OUTPUT REQUIREMENTS:
+- Write in %s.
+- Use markdown with the sections: %s.
+- Base every statement on the material below; do not invent APIs.
+- Mention files and identifiers by name.
+
+PATH: %s
+
+FILES:
 %s
 
-DIFF:
+EXPORTED GO IDENTIFIERS:
 %s
 
-PR_TEMPLATE:
+FILE EXCERPTS:
 %s
-`, titleLanguage, bodyLanguage, input.BaseBranch, input.HeadBranch, input.CommitLog, input.DiffStat, input.Diff, template)
 
-	resp, err := v.client.Models.GenerateContent(ctx, v.flashModel,
-		[]*genai.Content{
-			genai.NewContentFromText(prompt, genai.RoleUser),
-		},
-		&genai.GenerateContentConfig{
-			Temperature: genai.Ptr(float32(0.2)),
-		})
+RECENT COMMITS (newest first):
+%s
+`, audience, input.Language, sections, input.Path, strings.Join(input.Files, "\n"), orNone(input.Exports), orNone(input.Excerpts), orNone(input.CommitLog)) + instructionsSection(input.Instructions)
+}
+
+// GenerateExplanation explains a directory in markdown. When onChunk is not
+// nil the response is streamed to it as it is generated.
+func (v *Client) GenerateExplanation(ctx context.Context, input ExplainInput, onChunk func(string)) (string, error) {
+	prompt := BuildExplainPrompt(input)
+
+	var text string
+	var err error
+	if onChunk != nil {
+		text, err = v.callModelStream(ctx, prompt, 0.3, onChunk)
+	} else {
+		text, err = v.generateText(ctx, prompt, 0.3, "Generating explanation...")
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate pull request content: %w", err)
+		return "", fmt.Errorf("failed to generate explanation: %w", err)
 	}
 
-	if len(resp.Candidates) == 0 {
-		return nil, fmt.Errorf("no candidates in response")
+	return strings.TrimSpace(text), nil
+}
+
+// BuildReviewPrompt builds the prompt used to review a diff.
+func BuildReviewPrompt(input ReviewInput) string {
+	commitSection := ""
+	if strings.TrimSpace(input.CommitMessage) != "" {
+		commitSection = fmt.Sprintf(`
+COMMIT MESSAGE (the author's stated intent; point out where the diff does not match it):
+%s
+`, input.CommitMessage)
 	}
 
-	if len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no content parts in response")
+	templateSection := ""
+	if strings.TrimSpace(input.Template) != "" {
+		templateSection = fmt.Sprintf(`
+PULL REQUEST TEMPLATE (the change will be opened as a pull request with this template):
+%s
+
+After the findings, add a "### Template readiness" subsection. List each thing the template requires (tests run, screenshots, checklist items, linked issues, migration notes) that this diff does not provide, with the reason, e.g. "template requires screenshots for UI changes; the diff touches internal/ui but provides none". If every requirement appears to be met, say so in one sentence.
+`, strings.TrimSpace(input.Template))
 	}
 
-	part := resp.Candidates[0].Content.Parts[0]
-	if part.Text == "" {
-		return nil, fmt.Errorf("empty text in response part")
+	return fmt.Sprintf(`You are an experienced code reviewer. Review the following git diff.
+
+REVIEW REQUIREMENTS:
+- Write in %s.
+- Use markdown. List findings as bullets, most important first, each naming the file and line or identifier.
+- Focus on bugs, missing error handling, security problems and confusing code; skip pure style nits.
+- Start each finding with its severity in bold: **high**, **medium** or **low**. Keep these labels in English whatever language the review is in.
+- If there is nothing worth changing, say so in one sentence.
+%s%s
+Git diff:
+%s
+`, input.Language, commitSection, templateSection, promptDiff(input.Diff, input.FileOrder)) + instructionsSection(input.Instructions)
+}
+
+// GenerateReview reviews a diff in markdown. When onChunk is not nil the
+// response is streamed to it as it is generated.
+func (v *Client) GenerateReview(ctx context.Context, input ReviewInput, onChunk func(string)) (string, error) {
+	prompt := BuildReviewPrompt(input)
+
+	var text string
+	var err error
+	if onChunk != nil {
+		text, err = v.callModelStream(ctx, prompt, 0.2, onChunk)
+	} else {
+		text, err = v.generateText(ctx, prompt, 0.2, "Reviewing changes...")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to generate review: %w", err)
 	}
 
-	text := strings.TrimSpace(part.Text)
-	if strings.HasPrefix(text, "```json") {
-		text = strings.TrimPrefix(text, "```json")
-		text = strings.TrimSuffix(text, "```")
-		text = strings.TrimSpace(text)
+	return strings.TrimSpace(text), nil
+}
+
+// BuildComparePrompt builds the prompt used to compare two branches'
+// approaches to the same change.
+func BuildComparePrompt(input CompareInput) string {
+	base := "their common merge base"
+	if input.MergeBase != "" {
+		base += " " + input.MergeBase
 	}
 
-	var result PullRequestContent
-	if err := json.Unmarshal([]byte(text), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	return fmt.Sprintf(`You are an experienced code reviewer. Two branches implement the same change in different ways. Compare their approaches.
+
+COMPARISON REQUIREMENTS:
+- Write in %[1]s.
+- Use markdown with the sections "### Approaches", "### Correctness", "### Complexity", "### Risk" and "### Recommendation".
+- Under "### Approaches", describe in one or two sentences how each branch goes about the change.
+- Under the other sections, list observations as bullets, most important first. Start every bullet with the branch it is about in bold: **%[2]s**, **%[3]s**, or **both**. Name the file and line or identifier.
+- Correctness covers bugs, missing error handling and cases one branch handles and the other does not. Complexity covers the amount and clarity of code and new abstractions. Risk covers security, compatibility, migrations and what could break elsewhere.
+- Label each correctness and risk observation with a severity: **high**, **medium** or **low**.
+- Under "### Recommendation", say which branch to prefer, or what to take from each, and why, in a few sentences.
+- Skip pure style nits, and do not invent differences: say so where the branches agree.
+
+Both diffs are against %[4]s.
+
+BRANCH %[2]s:
+%[5]s
+
+BRANCH %[3]s:
+%[6]s
+`, input.Language, input.BranchA, input.BranchB, base, promptDiff(input.DiffA, input.FileOrder), promptDiff(input.DiffB, input.FileOrder)) + instructionsSection(input.Instructions)
+}
+
+// GenerateComparison compares two branches in markdown. When onChunk is not
+// nil the response is streamed to it as it is generated.
+func (v *Client) GenerateComparison(ctx context.Context, input CompareInput, onChunk func(string)) (string, error) {
+	prompt := BuildComparePrompt(input)
+
+	var text string
+	var err error
+	if onChunk != nil {
+		text, err = v.callModelStream(ctx, prompt, 0.2, onChunk)
+	} else {
+		text, err = v.generateText(ctx, prompt, 0.2, "Comparing branches...")
 	}
+	if err != nil {
+		return "", fmt.Errorf("failed to generate comparison: %w", err)
+	}
+
+	return strings.TrimSpace(text), nil
+}
 
-	result.Title = strings.TrimSpace(result.Title)
-	result.Body = strings.TrimSpace(result.Body)
-	if result.Title == "" {
-		return nil, fmt.Errorf("generated PR title is empty")
+func (v *Client) Close() error {
+	return v.backend.close()
+}
+
+// vertexBackend sends prompts to Gemini models on Vertex AI.
+type vertexBackend struct {
+	client *genai.Client
+}
+
+func (b vertexBackend) name() string {
+	return "vertex"
+}
+
+func (b vertexBackend) config(request modelRequest) *genai.GenerateContentConfig {
+	return &genai.GenerateContentConfig{
+		Temperature: genai.Ptr(request.Temperature),
+		Seed:        request.Seed,
 	}
-	if result.Body == "" {
-		return nil, fmt.Errorf("generated PR body is empty")
+}
+
+func (b vertexBackend) generate(ctx context.Context, request modelRequest) (modelResponse, error) {
+	resp, err := b.client.Models.GenerateContent(ctx, request.Model,
+		[]*genai.Content{
+			genai.NewContentFromText(request.Prompt, genai.RoleUser),
+		},
+		b.config(request))
+	if err != nil {
+		return modelResponse{}, err
+	}
+
+	var response modelResponse
+	if resp.UsageMetadata != nil {
+		response.InputTokens = int(resp.UsageMetadata.PromptTokenCount)
+		response.OutputTokens = int(resp.UsageMetadata.CandidatesTokenCount)
+	}
+	if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil && len(resp.Candidates[0].Content.Parts) > 0 {
+		response.Text = resp.Candidates[0].Content.Parts[0].Text
+	}
+	return response, nil
+}
+
+func (b vertexBackend) generateStream(ctx context.Context, request modelRequest, onChunk func(string)) (modelResponse, error) {
+	var builder strings.Builder
+	var response modelResponse
+	for resp, err := range b.client.Models.GenerateContentStream(ctx, request.Model,
+		[]*genai.Content{
+			genai.NewContentFromText(request.Prompt, genai.RoleUser),
+		},
+		b.config(request)) {
+		if err != nil {
+			return modelResponse{}, err
+		}
+		if resp.UsageMetadata != nil {
+			response.InputTokens = int(resp.UsageMetadata.PromptTokenCount)
+			response.OutputTokens = int(resp.UsageMetadata.CandidatesTokenCount)
+		}
+		chunk := resp.Text()
+		if chunk == "" {
+			continue
+		}
+		builder.WriteString(chunk)
+		onChunk(chunk)
 	}
+	response.Text = builder.String()
+	return response, nil
+}
 
-	return &result, nil
+// retryable reports whether err is a rate limit or a server error.
+func (b vertexBackend) retryable(err error) bool {
+	var apiErr genai.APIError
+	return errors.As(err, &apiErr) && retryableStatus(apiErr.Code)
+}
+
+func (b vertexBackend) countTokens(ctx context.Context, model, text string) (int, error) {
+	resp, err := b.client.Models.CountTokens(ctx, model, []*genai.Content{
+		genai.NewContentFromText(text, genai.RoleUser),
+	}, nil)
+	if err != nil {
+		return 0, err
+	}
+	return int(resp.TotalTokens), nil
 }
 
-func (v *VertexAIClient) Close() error {
+func (b vertexBackend) close() error {
 	return nil
 }
@@ -0,0 +1,287 @@
+package commitmsg
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/EkeMinusYou/gelf/internal/language"
+	"github.com/EkeMinusYou/gelf/internal/mdwrap"
+	"github.com/EkeMinusYou/gelf/internal/textwidth"
+)
+
+// MaxSubjectWidth is the width in columns a subject should stay within;
+// full-width characters take two.
+const MaxSubjectWidth = 72
+
+// Supported values for commit.case.
+const (
+	CaseLower = "lower"
+	CaseTitle = "title"
+)
+
+// Options controls how Normalize rewrites a commit message.
+type Options struct {
+	// Case is applied to the Conventional Commits type and scope. Empty keeps
+	// them as they are.
+	Case string
+	// AllowEmoji keeps emoji and :shortcode: emoji in the message.
+	AllowEmoji bool
+	// Scope, when set, replaces the scope of the subject (monorepo.scopes).
+	Scope string
+	// Wrap is the column the body is wrapped at (commit.wrap). 0 leaves it
+	// as it is.
+	Wrap int
+	// SubjectLanguage and BodyLanguage, when set, are the languages the
+	// description of the subject and the body are expected in; a part that
+	// does not look written in its language gets a warning.
+	SubjectLanguage string
+	BodyLanguage    string
+}
+
+var (
+	prefixRegex    = regexp.MustCompile(`^([A-Za-z]+)(\(([^)]*)\))?(!?):`)
+	shortcodeRegex = regexp.MustCompile(`:[a-z0-9_+\-]+:`)
+	// trailerLineRegex matches the first line of a git trailer.
+	trailerLineRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]*:[ \t]`)
+	// widePrefixRegex matches a type prefix written with full-width letters
+	// or punctuation, as Japanese input methods produce, e.g. "feat（ui）：".
+	widePrefixRegex = regexp.MustCompile(`^[ \x{3000}]*([A-Za-zＡ-Ｚａ-ｚ]+)[ \x{3000}]*([(（][^)）]*[)）])?[ \x{3000}]*([!！]?)[ \x{3000}]*[:：][ \x{3000}]*`)
+)
+
+// Normalize applies opts to message and returns the result together with
+// warnings for problems it could not fix.
+func Normalize(message string, opts Options) (string, []string) {
+	var warnings []string
+
+	subject, body, hasBody := strings.Cut(message, "\n")
+	subject = narrowPrefix(subject)
+
+	if !opts.AllowEmoji {
+		stripped := stripEmoji(subject)
+		if isEmptySubject(stripped) {
+			warnings = append(warnings, "subject would be empty without emoji; emoji kept")
+		} else {
+			subject = stripped
+		}
+		body = stripEmoji(body)
+	}
+
+	subject = applyCase(subject, opts.Case)
+
+	subject, scopeWarnings := ApplyScope(subject, opts.Scope)
+	warnings = append(warnings, scopeWarnings...)
+
+	if width := textwidth.Width(subject); width > MaxSubjectWidth {
+		warnings = append(warnings, fmt.Sprintf("subject is %d columns wide (over %d)", width, MaxSubjectWidth))
+	}
+	warnings = append(warnings, languageWarnings(subject, body, opts)...)
+
+	if hasBody {
+		prose, trailers := splitTrailers(body)
+		return subject + "\n" + mdwrap.Wrap(prose, opts.Wrap) + trailers, warnings
+	}
+	return subject, warnings
+}
+
+// splitTrailers splits body before its trailing paragraph when that
+// paragraph is made of git trailers ("Signed-off-by: ..."), which are not
+// wrapped: git interpret-trailers reads a trailer folded onto a line that
+// does not start with whitespace as the end of the block.
+func splitTrailers(body string) (prose, trailers string) {
+	trimmed := strings.TrimRight(body, "\n")
+	// body starts after the subject's line break, so a leading "\n" is the
+	// blank line that separates the first paragraph.
+	blank := strings.LastIndex("\n"+trimmed, "\n\n")
+	if blank == -1 {
+		return body, ""
+	}
+	start := blank + 1
+	paragraph := trimmed[start:]
+	if paragraph == "" {
+		return body, ""
+	}
+	for i, line := range strings.Split(paragraph, "\n") {
+		continued := i > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t"))
+		if !continued && !trailerLineRegex.MatchString(line) {
+			return body, ""
+		}
+	}
+	return body[:start], body[start:]
+}
+
+// languageWarnings warns about a subject or body that does not look written
+// in the language opts asks for. The type prefix of the subject and comment
+// lines of the body are left out, as they are English whatever the language.
+func languageWarnings(subject, body string, opts Options) []string {
+	var warnings []string
+	if opts.SubjectLanguage != "" && !language.Matches(Description(subject), opts.SubjectLanguage) {
+		warnings = append(warnings, fmt.Sprintf("subject does not look like %s", opts.SubjectLanguage))
+	}
+
+	var prose []string
+	for _, line := range strings.Split(body, "\n") {
+		if !strings.HasPrefix(line, "#") {
+			prose = append(prose, line)
+		}
+	}
+	if opts.BodyLanguage != "" && !language.Matches(strings.Join(prose, "\n"), opts.BodyLanguage) {
+		warnings = append(warnings, fmt.Sprintf("body does not look like %s", opts.BodyLanguage))
+	}
+	return warnings
+}
+
+// Description returns subject without its Conventional Commits type prefix.
+func Description(subject string) string {
+	if match := prefixRegex.FindStringIndex(subject); match != nil {
+		return strings.TrimSpace(subject[match[1]:])
+	}
+	return subject
+}
+
+// narrowPrefix rewrites a type prefix containing full-width characters to
+// its ASCII form, e.g. "feat（ui）：説明" to "feat(ui): 説明", so that
+// Conventional Commits tooling recognizes it. A scope keeps its other
+// characters, and the description is left as it is.
+func narrowPrefix(subject string) string {
+	match := widePrefixRegex.FindStringSubmatchIndex(subject)
+	if match == nil || !hasWide(subject[:match[1]]) {
+		return subject
+	}
+
+	prefix := narrow(subject[match[2]:match[3]])
+	if match[4] != -1 {
+		scope := []rune(narrow(subject[match[4]:match[5]]))
+		prefix += "(" + strings.TrimSpace(string(scope[1:len(scope)-1])) + ")"
+	}
+	prefix += narrow(subject[match[6]:match[7]]) + ": "
+	return prefix + subject[match[1]:]
+}
+
+// hasWide reports whether text contains a full-width form of an ASCII
+// character or the ideographic space.
+func hasWide(text string) bool {
+	return strings.ContainsFunc(text, func(r rune) bool {
+		return r >= 0xFF01 && r <= 0xFF5E || r == 0x3000
+	})
+}
+
+// narrow replaces full-width forms of ASCII characters and the ideographic
+// space with their ASCII counterparts.
+func narrow(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 0xFF01 && r <= 0xFF5E:
+			return r - 0xFEE0
+		case r == 0x3000:
+			return ' '
+		}
+		return r
+	}, text)
+}
+
+func applyCase(subject, mode string) string {
+	match := prefixRegex.FindStringSubmatchIndex(subject)
+	if match == nil || mode == "" {
+		return subject
+	}
+
+	convert := strings.ToLower
+	if mode == CaseTitle {
+		convert = titleWord
+	}
+
+	result := convert(subject[match[2]:match[3]])
+	if match[4] != -1 {
+		result += "(" + convert(subject[match[6]:match[7]]) + ")"
+	}
+	return result + subject[match[8]:]
+}
+
+func titleWord(word string) string {
+	lower := []rune(strings.ToLower(word))
+	if len(lower) == 0 {
+		return ""
+	}
+	lower[0] = unicode.ToUpper(lower[0])
+	return string(lower)
+}
+
+// stripEmoji removes emoji and the :shortcode: emoji of emojiShortcodes from
+// each line of text. Only the whitespace left where one was removed is
+// collapsed; spacing elsewhere on the line is kept.
+func stripEmoji(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		spans := emojiSpans(line)
+		for j := len(spans) - 1; j >= 0; j-- {
+			before, after := line[:spans[j][0]], line[spans[j][1]:]
+			switch {
+			case strings.TrimSpace(before) == "":
+				after = strings.TrimLeft(after, " \t")
+			case strings.TrimSpace(after) == "":
+				before, after = strings.TrimRight(before, " \t"), ""
+			case strings.HasSuffix(before, " ") || strings.HasSuffix(before, "\t"):
+				after = strings.TrimLeft(after, " \t")
+			}
+			line = before + after
+		}
+		if strings.TrimSpace(line) == "" {
+			line = ""
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// emojiSpans returns the byte ranges of line holding emoji or a shortcode
+// of emojiShortcodes that stands apart from the words around it.
+func emojiSpans(line string) [][2]int {
+	var spans [][2]int
+	for _, loc := range shortcodeRegex.FindAllStringIndex(line, -1) {
+		start, end := loc[0], loc[1]
+		if !emojiShortcodes[line[start+1:end-1]] {
+			continue
+		}
+		if (start > 0 && line[start-1] != ' ' && line[start-1] != '\t') || (end < len(line) && line[end] != ' ' && line[end] != '\t') {
+			continue
+		}
+		spans = append(spans, [2]int{start, end})
+	}
+	for i, r := range line {
+		if !isEmoji(r) {
+			continue
+		}
+		end := i + utf8.RuneLen(r)
+		if n := len(spans); n > 0 && spans[n-1][1] == i {
+			spans[n-1][1] = end
+		} else {
+			spans = append(spans, [2]int{i, end})
+		}
+	}
+	slices.SortFunc(spans, func(a, b [2]int) int { return a[0] - b[0] })
+	return spans
+}
+
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF, // pictographs, emoticons, transport, flags
+		r >= 0x2600 && r <= 0x27BF, // miscellaneous symbols and dingbats
+		r >= 0x2B00 && r <= 0x2BFF, // arrows and stars such as ⭐
+		r == 0xFE0F, r == 0x200D:   // variation selector and zero width joiner
+		return true
+	}
+	return false
+}
+
+// isEmptySubject reports whether subject has no description left, either
+// because it is blank or because only the type prefix remains.
+func isEmptySubject(subject string) bool {
+	if loc := prefixRegex.FindStringIndex(subject); loc != nil {
+		subject = subject[loc[1]:]
+	}
+	return strings.TrimSpace(subject) == ""
+}
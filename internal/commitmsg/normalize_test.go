@@ -0,0 +1,153 @@
+package commitmsg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeWidePrefix(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"full-width colon", "feat：検索を追加", "feat: 検索を追加"},
+		{"full-width scope and colon", "feat（ui）：検索を追加", "feat(ui): 検索を追加"},
+		{"full-width letters", "ｆｉｘ：空の差分を扱う", "fix: 空の差分を扱う"},
+		{"full-width breaking mark", "feat（api）！：旧エンドポイントを削除", "feat(api)!: 旧エンドポイントを削除"},
+		{"ideographic spaces", "feat　（ui）　：　検索を追加", "feat(ui): 検索を追加"},
+		{"scope is narrowed too", "fix（検索ＵＩ）：表示崩れ", "fix(検索UI): 表示崩れ"},
+		{"description keeps full-width text", "fix：ＡＰＩの（一部）を修正", "fix: ＡＰＩの（一部）を修正"},
+		{"ascii prefix untouched", "fix(ui): 検索：修正", "fix(ui): 検索：修正"},
+		{"no prefix", "検索を追加：画面", "検索を追加：画面"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := Normalize(tt.message, Options{AllowEmoji: true})
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeSubjectWidth(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		warn    bool
+	}{
+		{"ascii at the limit", "fix: " + strings.Repeat("a", MaxSubjectWidth-5), false},
+		{"ascii over the limit", "fix: " + strings.Repeat("a", MaxSubjectWidth-4), true},
+		// 33 kanji take 66 columns: 71 in all, under the limit in runes
+		// and in columns.
+		{"cjk under the limit", "fix: " + strings.Repeat("漢", 33), false},
+		// 34 kanji are only 39 runes but 73 columns.
+		{"cjk over the limit", "fix: " + strings.Repeat("漢", 34), true},
+		{"combining marks take no columns", "fix: " + strings.Repeat("é", MaxSubjectWidth-5), false},
+		{"zwj sequences take two columns", "fix: " + strings.Repeat("👩‍💻", 34), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, warnings := Normalize(tt.subject, Options{AllowEmoji: true})
+			warned := false
+			for _, warning := range warnings {
+				if strings.Contains(warning, "columns wide") {
+					warned = true
+				}
+			}
+			if warned != tt.warn {
+				t.Errorf("Normalize(%q) warnings = %q, want a width warning: %v", tt.subject, warnings, tt.warn)
+			}
+		})
+	}
+}
+
+func TestNormalizeWrapKeepsTrailers(t *testing.T) {
+	long := "Retry model calls that fail with a rate limit or a server error."
+	coAuthor := "Co-authored-by: Somebody With A Long Name <somebody.with.a.long.name@example.com>"
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name:    "trailers after the body",
+			message: "fix: retry\n\n" + long + "\n\n" + coAuthor + "\nSigned-off-by: Somebody With A Long Name <somebody@example.com>",
+			want:    "fix: retry\n\nRetry model calls that fail with a rate\nlimit or a server error.\n\n" + coAuthor + "\nSigned-off-by: Somebody With A Long Name <somebody@example.com>",
+		},
+		{
+			name:    "trailers only",
+			message: "fix: retry\n\n" + coAuthor,
+			want:    "fix: retry\n\n" + coAuthor,
+		},
+		{
+			name:    "folded trailer",
+			message: "fix: retry\n\n" + long + "\n\nRefs: a reference that goes on for a while\n and is folded",
+			want:    "fix: retry\n\nRetry model calls that fail with a rate\nlimit or a server error.\n\nRefs: a reference that goes on for a while\n and is folded",
+		},
+		{
+			name:    "last paragraph is prose",
+			message: "fix: retry\n\nNote: this is prose\n" + long,
+			want:    "fix: retry\n\nNote: this is prose\nRetry model calls that fail with a rate\nlimit or a server error.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := Normalize(tt.message, Options{AllowEmoji: true, Wrap: 40})
+			if got != tt.want {
+				t.Errorf("Normalize() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripEmoji(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"leading emoji", "✨ feat: add search", "feat: add search"},
+		{"leading shortcode", ":sparkles: feat: add search", "feat: add search"},
+		{"emoji in the middle", "feat: add 🔍 search", "feat: add search"},
+		{"trailing emoji", "feat: add search 🎉", "feat: add search"},
+		{"adjacent emoji", "🎉✨ feat: add search", "feat: add search"},
+		{"emoji and shortcode", "🎉 :sparkles: feat: add search", "feat: add search"},
+		{"zwj sequence", "feat: pair 👩‍💻 on search", "feat: pair on search"},
+		{"unknown shortcode kept", "fix: open :memory: databases", "fix: open :memory: databases"},
+		{"shortcode inside a word kept", "fix: parse a:bug:b", "fix: parse a:bug:b"},
+		{"spacing elsewhere kept", "- name:  value 🎉\n- other: value", "- name:  value\n- other: value"},
+		{"indentation kept", "  ✨ nested item", "  nested item"},
+		{"line of emoji", "body\n🎉🎉\nmore", "body\n\nmore"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripEmoji(tt.text); got != tt.want {
+				t.Errorf("stripEmoji(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeLanguageWarnings(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    []string
+	}{
+		{"both match", "feat(auth): add token refresh\n\nトークンの期限切れ前に更新する。", nil},
+		{"japanese subject", "feat(auth): トークンを更新する\n\nトークンの期限切れ前に更新する。", []string{"subject does not look like English"}},
+		{"english body", "feat(auth): add token refresh\n\nRefresh tokens before they expire.", []string{"body does not look like Japanese"}},
+		{"comment lines ignored", "feat(auth): add token refresh\n\nトークンの期限切れ前に更新する。\n# Please enter the commit message for your changes.", nil},
+		{"no body", "feat(auth): add token refresh", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, warnings := Normalize(tt.message, Options{AllowEmoji: true, SubjectLanguage: "English", BodyLanguage: "Japanese"})
+			if strings.Join(warnings, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Normalize(%q) warnings = %q, want %q", tt.message, warnings, tt.want)
+			}
+		})
+	}
+}
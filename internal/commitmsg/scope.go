@@ -0,0 +1,79 @@
+package commitmsg
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ResolveScope maps changed paths to the scopes configured in
+// monorepo.scopes (path prefix -> scope). The longest matching prefix wins
+// and paths outside every prefix are ignored. When the changes span several
+// scopes, fallback is returned if set; otherwise the scopes are joined with
+// commas, the one with the most changed files first. It returns "" when no
+// path matches.
+func ResolveScope(paths []string, scopes map[string]string, fallback string) string {
+	prefixes := make(map[string]string, len(scopes))
+	for prefix, scope := range scopes {
+		prefixes[strings.TrimSuffix(path.Clean(prefix), "/")] = scope
+	}
+
+	counts := map[string]int{}
+	for _, changed := range paths {
+		best := ""
+		for prefix := range prefixes {
+			if (changed == prefix || strings.HasPrefix(changed, prefix+"/")) && len(prefix) > len(best) {
+				best = prefix
+			}
+		}
+		if best != "" {
+			counts[prefixes[best]]++
+		}
+	}
+
+	if len(counts) == 0 {
+		return ""
+	}
+	if len(counts) > 1 && fallback != "" {
+		return fallback
+	}
+
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return strings.Join(names, ",")
+}
+
+// ApplyScope makes the Conventional Commits prefix of subject use scope,
+// replacing a missing or different scope. A subject without a <type>: prefix
+// is returned unchanged with a warning.
+func ApplyScope(subject, scope string) (string, []string) {
+	if scope == "" {
+		return subject, nil
+	}
+
+	match := prefixRegex.FindStringSubmatchIndex(subject)
+	if match == nil {
+		return subject, []string{fmt.Sprintf("subject has no <type>(%s): prefix", scope)}
+	}
+
+	var warnings []string
+	if match[4] != -1 {
+		current := subject[match[6]:match[7]]
+		if current == scope {
+			return subject, nil
+		}
+		if current != "" {
+			warnings = append(warnings, fmt.Sprintf("scope %q replaced with %q from monorepo.scopes", current, scope))
+		}
+	}
+	return subject[match[2]:match[3]] + "(" + scope + ")" + subject[match[8]:], warnings
+}
@@ -0,0 +1,98 @@
+package commitmsg
+
+// emojiShortcodes are the :shortcode: names stripEmoji removes: those of
+// gitmoji and a few common others. Other words between colons, such as
+// SQLite's ":memory:", are left alone.
+var emojiShortcodes = map[string]bool{
+	"adhesive_bandage":          true,
+	"airplane":                  true,
+	"alembic":                   true,
+	"alien":                     true,
+	"ambulance":                 true,
+	"apple":                     true,
+	"arrow_down":                true,
+	"arrow_up":                  true,
+	"art":                       true,
+	"beers":                     true,
+	"bento":                     true,
+	"bookmark":                  true,
+	"books":                     true,
+	"boom":                      true,
+	"bricks":                    true,
+	"bug":                       true,
+	"building_construction":     true,
+	"bulb":                      true,
+	"busts_in_silhouette":       true,
+	"camera_flash":              true,
+	"card_file_box":             true,
+	"chart_with_upwards_trend":  true,
+	"checkered_flag":            true,
+	"children_crossing":         true,
+	"closed_lock_with_key":      true,
+	"clown_face":                true,
+	"coffin":                    true,
+	"construction":              true,
+	"construction_worker":       true,
+	"dizzy":                     true,
+	"egg":                       true,
+	"fire":                      true,
+	"gear":                      true,
+	"globe_with_meridians":      true,
+	"goal_net":                  true,
+	"green_apple":               true,
+	"green_heart":               true,
+	"hammer":                    true,
+	"heavy_check_mark":          true,
+	"heavy_minus_sign":          true,
+	"heavy_plus_sign":           true,
+	"iphone":                    true,
+	"label":                     true,
+	"lipstick":                  true,
+	"lock":                      true,
+	"loud_sound":                true,
+	"mag":                       true,
+	"memo":                      true,
+	"money_with_wings":          true,
+	"monocle_face":              true,
+	"mute":                      true,
+	"necktie":                   true,
+	"package":                   true,
+	"page_facing_up":            true,
+	"passport_control":          true,
+	"pencil":                    true,
+	"pencil2":                   true,
+	"penguin":                   true,
+	"poop":                      true,
+	"pushpin":                   true,
+	"racehorse":                 true,
+	"recycle":                   true,
+	"rewind":                    true,
+	"robot":                     true,
+	"rocket":                    true,
+	"rotating_light":            true,
+	"safety_vest":               true,
+	"see_no_evil":               true,
+	"seedling":                  true,
+	"shirt":                     true,
+	"smile":                     true,
+	"sparkles":                  true,
+	"speech_balloon":            true,
+	"stethoscope":               true,
+	"tada":                      true,
+	"technologist":              true,
+	"test_tube":                 true,
+	"thread":                    true,
+	"thumbsup":                  true,
+	"triangular_flag_on_post":   true,
+	"truck":                     true,
+	"twisted_rightwards_arrows": true,
+	"warning":                   true,
+	"wastebasket":               true,
+	"wheelchair":                true,
+	"white_check_mark":          true,
+	"wrench":                    true,
+	"x":                         true,
+	"zap":                       true,
+	"+1":                        true,
+	"-1":                        true,
+}
@@ -0,0 +1,82 @@
+package commitmsg
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ticketPattern matches an issue key such as ABC-123 as a component of a
+// branch name segment, between the segment's ends, "-" and "_".
+var ticketPattern = regexp.MustCompile(`(?:^|[_-])([A-Z][A-Z0-9]+-[0-9]+)(?:[_-]|$)`)
+
+// issuePattern matches an issue number written as #123, issue-123 or
+// gh-123 as a component of a branch name segment. Bare numbers are not
+// taken, since they are as often versions and dates (release/2024-10).
+var issuePattern = regexp.MustCompile(`(?i)(?:^|[_-])(?:#|issue-|gh-)([0-9]+)(?:[_-]|$)`)
+
+// notTicketKeys are prefixes of names that look like issue keys but name
+// standards and versions, such as UTF-8 and SHA-256.
+var notTicketKeys = map[string]bool{
+	"AES": true, "CVE": true, "ES": true, "HTTP": true, "IPV": true, "ISO": true,
+	"MD": true, "RFC": true, "RSA": true, "SHA": true, "SSL": true, "TLS": true,
+	"UCS": true, "UTF": true,
+}
+
+// TicketFromBranch returns the issue key (ABC-123) or number (#123) named by
+// branch, or "" if there is none. Each "/"-separated segment is searched on
+// its own, so a ticket never spans two; a key anywhere in the branch wins
+// over a number, and the first segment holding one wins over later ones.
+func TicketFromBranch(branch string) string {
+	segments := strings.Split(branch, "/")
+	for _, segment := range segments {
+		for _, match := range findOverlapping(ticketPattern, segment) {
+			if key, _, _ := strings.Cut(match, "-"); !notTicketKeys[key] {
+				return match
+			}
+		}
+	}
+	for _, segment := range segments {
+		if numbers := findOverlapping(issuePattern, segment); len(numbers) > 0 {
+			return "#" + numbers[0]
+		}
+	}
+	return ""
+}
+
+// findOverlapping returns the first submatch of every match of pattern in
+// text, letting a match begin on the separator that ended the previous one.
+func findOverlapping(pattern *regexp.Regexp, text string) []string {
+	var found []string
+	for offset := 0; offset < len(text); {
+		loc := pattern.FindStringSubmatchIndex(text[offset:])
+		if loc == nil {
+			break
+		}
+		found = append(found, text[offset+loc[2]:offset+loc[3]])
+		offset += loc[3]
+	}
+	return found
+}
+
+// ExpandTrailers fills in the {{branch}} and {{ticket}} placeholders of
+// commit.trailers. Trailers that use {{ticket}} are dropped when branch names
+// no ticket, and exact duplicates are dropped so --trailer can repeat a
+// configured one.
+func ExpandTrailers(templates []string, branch string) []string {
+	ticket := TicketFromBranch(branch)
+	seen := map[string]bool{}
+	var trailers []string
+	for _, template := range templates {
+		if strings.Contains(template, "{{ticket}}") && ticket == "" {
+			continue
+		}
+		trailer := strings.NewReplacer("{{branch}}", branch, "{{ticket}}", ticket).Replace(template)
+		trailer = strings.TrimSpace(trailer)
+		if trailer == "" || seen[trailer] {
+			continue
+		}
+		seen[trailer] = true
+		trailers = append(trailers, trailer)
+	}
+	return trailers
+}
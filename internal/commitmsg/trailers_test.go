@@ -0,0 +1,68 @@
+package commitmsg
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTicketFromBranch(t *testing.T) {
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{"feature/ABC-123-add-search", "ABC-123"},
+		{"ABC-123", "ABC-123"},
+		{"user/fix_ABC-123_crash", "ABC-123"},
+		{"fix/#123-crash", "#123"},
+		{"fix/issue-45-crash", "#45"},
+		{"fix/Issue-45", "#45"},
+		{"gh-7", "#7"},
+		{"fix/123-crash", ""},
+		{"release/2024-10", ""},
+		{"fix/UTF-8", ""},
+		{"fix/UTF-8-ABC-12", "ABC-12"},
+		{"fix/SHA-256-digests", ""},
+		{"feat/XABC-12x", ""},
+		{"feat/abc-123", ""},
+		{"fix/issue-45/ABC-123", "ABC-123"},
+		{"ABC-1/DEF-2", "ABC-1"},
+		{"fix/#1-and-#2", "#1"},
+		{"gh-7/#8", "#7"},
+		{"main", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.branch, func(t *testing.T) {
+			if got := TicketFromBranch(tt.branch); got != tt.want {
+				t.Errorf("TicketFromBranch(%q) = %q, want %q", tt.branch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandTrailers(t *testing.T) {
+	templates := []string{"Refs: {{ticket}}", "Branch: {{branch}}", "Reviewed-by: Someone <someone@example.com>", "Branch: {{branch}}"}
+	tests := []struct {
+		name   string
+		branch string
+		want   []string
+	}{
+		{
+			name:   "ticket",
+			branch: "feature/ABC-123-search",
+			want:   []string{"Refs: ABC-123", "Branch: feature/ABC-123-search", "Reviewed-by: Someone <someone@example.com>"},
+		},
+		{
+			name:   "no ticket",
+			branch: "release/2024-10",
+			want:   []string{"Branch: release/2024-10", "Reviewed-by: Someone <someone@example.com>"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExpandTrailers(templates, tt.branch); !slices.Equal(got, tt.want) {
+				t.Errorf("ExpandTrailers(%q) = %q, want %q", tt.branch, got, tt.want)
+			}
+		})
+	}
+}
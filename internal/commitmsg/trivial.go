@@ -0,0 +1,81 @@
+package commitmsg
+
+import (
+	"path"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/config"
+)
+
+// FormattingRule is the built-in rule used when the staged changes only
+// touch whitespace (gelf commit --detect-formatting).
+var FormattingRule = config.TrivialRule{Name: "formatting", Message: "style: format code"}
+
+// MatchTrivialRule returns the first rule whose paths cover every file, with
+// {{files}} in its message replaced by the files joined with ", ".
+func MatchTrivialRule(rules []config.TrivialRule, files []string) (config.TrivialRule, bool) {
+	if len(files) == 0 {
+		return config.TrivialRule{}, false
+	}
+	for _, rule := range rules {
+		if matchesAll(rule.Paths, files) {
+			rule.Message = strings.ReplaceAll(rule.Message, "{{files}}", strings.Join(files, ", "))
+			return rule, true
+		}
+	}
+	return config.TrivialRule{}, false
+}
+
+func matchesAll(patterns, files []string) bool {
+	for _, file := range files {
+		matched := false
+		for _, pattern := range patterns {
+			if matchPath(pattern, file) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPath matches file against a glob. A pattern without a slash matches
+// the file name in any directory, and a pattern ending in a slash matches
+// everything under that directory. A "**" segment matches any number of
+// directories, including none, as in docs/**/*.md.
+func matchPath(pattern, file string) bool {
+	if dir, ok := strings.CutSuffix(pattern, "/"); ok {
+		return strings.HasPrefix(file, dir+"/")
+	}
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(file))
+		return matched
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(file, "/"))
+}
+
+// matchSegments matches the segments of a file path against those of a
+// pattern, each with path.Match except "**", which takes zero or more.
+func matchSegments(patterns, segments []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			for skip := 0; skip <= len(segments); skip++ {
+				if matchSegments(patterns[1:], segments[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(patterns[0], segments[0]); !matched {
+			return false
+		}
+		patterns, segments = patterns[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
@@ -0,0 +1,66 @@
+package commitmsg
+
+import (
+	"testing"
+
+	"github.com/EkeMinusYou/gelf/internal/config"
+)
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		file    string
+		want    bool
+	}{
+		{"*.md", "README.md", true},
+		{"*.md", "docs/guide/usage.md", true},
+		{"docs/", "docs/guide/usage.md", true},
+		{"docs/", "docsite/index.md", false},
+		{"docs/*.md", "docs/usage.md", true},
+		{"docs/*.md", "docs/guide/usage.md", false},
+		{"docs/**/*.md", "docs/usage.md", true},
+		{"docs/**/*.md", "docs/guide/deep/usage.md", true},
+		{"docs/**/*.md", "docs/guide/usage.txt", false},
+		{"docs/**/*.md", "src/docs/usage.md", false},
+		{"**/testdata/*", "internal/prbody/testdata/body.md", true},
+		{"**/testdata/*", "testdata/body.md", true},
+		{"**/testdata/*", "internal/testdata/nested/body.md", false},
+		{"locales/**", "locales/en.json", true},
+		{"locales/**", "locales/ja/common.json", true},
+		{"locales/**", "src/locales/en.json", false},
+		{"**", "any/file.go", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.file, func(t *testing.T) {
+			if got := matchPath(tt.pattern, tt.file); got != tt.want {
+				t.Errorf("matchPath(%q, %q) = %v, want %v", tt.pattern, tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchTrivialRule(t *testing.T) {
+	rules := []config.TrivialRule{
+		{Name: "lockfiles", Paths: []string{"go.sum", "**/package-lock.json"}, Message: "chore(deps): update {{files}}"},
+		{Name: "docs", Paths: []string{"*.md", "docs/"}, Message: "docs: update documentation"},
+	}
+	tests := []struct {
+		name     string
+		files    []string
+		wantName string
+		wantMsg  string
+	}{
+		{"first rule", []string{"go.sum", "web/app/package-lock.json"}, "lockfiles", "chore(deps): update go.sum, web/app/package-lock.json"},
+		{"second rule", []string{"README.md", "docs/img/logo.png"}, "docs", "docs: update documentation"},
+		{"mixed", []string{"go.sum", "README.md"}, "", ""},
+		{"no files", nil, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, ok := MatchTrivialRule(rules, tt.files)
+			if ok != (tt.wantName != "") || rule.Name != tt.wantName || rule.Message != tt.wantMsg {
+				t.Errorf("MatchTrivialRule(%q) = %+v, %v, want %q with %q", tt.files, rule, ok, tt.wantName, tt.wantMsg)
+			}
+		})
+	}
+}
@@ -1,26 +1,155 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/language"
 	"gopkg.in/yaml.v3"
 )
 
+// Orders of the files in the diffs sent to the model (prompt.file_order).
+const (
+	// FileOrderSignificance puts source files before tests, docs and
+	// dependency files, and larger changes before smaller ones.
+	FileOrderSignificance = "significance"
+	// FileOrderPath keeps git's order, which is by path.
+	FileOrderPath = "path"
+)
+
 type Config struct {
-	ProjectID       string
-	Location        string
-	FlashModel      string
-	ProModel        string
-	BaseFlashModel  string
-	BaseProModel    string
-	CommitLanguage  string
-	CommitModel     string
+	ProjectID      string
+	Location       string
+	FlashModel     string
+	ProModel       string
+	BaseFlashModel string
+	BaseProModel   string
+	CommitLanguage string
+	CommitModel    string
+	CommitCase     string
+	CommitEmoji    bool
+	CommitTrailers []string
+	// CommitWrap is the column commit bodies are wrapped at; 0 disables it.
+	CommitWrap      int
+	TrivialRules    []TrivialRule
 	PRLanguage      string
 	PRTitleLanguage string
 	PRBodyLanguage  string
 	PRModel         string
+	PRTemplateMerge string
+	// PRRequiredBoxes lists checkbox labels that must be checked before a
+	// pull request is created.
+	PRRequiredBoxes []string
+	PRSuccessStats  bool
+	PRPostCreate    []string
+	PRLearnDefaults bool
+	PRAttribution   bool
+	// PRWrap is the column pull request bodies are wrapped at; 0 disables it.
+	PRWrap int
+	// PRSizeLabels maps size label suffixes (size/S) to the most changed
+	// lines they cover (--label-from-diff).
+	PRSizeLabels map[string]int
+	// PRReviewerHistory suggests the reviewers of the user's recently
+	// merged pull requests on every pr create, and lets --yes request
+	// them (pr.reviewer_from_history).
+	PRReviewerHistory bool
+	// PRSplitMaxFiles and PRSplitMaxLines are how many changed files and
+	// lines a branch may have before pr create suggests --split; 0 turns
+	// either check off (pr.split).
+	PRSplitMaxFiles int
+	PRSplitMaxLines int
+	// TemplateTimeout bounds the org pull request template lookup.
+	TemplateTimeout time.Duration
 	Color           string
+	UILanguage      string
+	SecretPatterns  map[string]string
+	SecretEntropy   bool
+	RedactRules     []RedactRule
+	MonorepoScopes  map[string]string
+	FallbackScope   string
+	// Defaults holds flag defaults per command section ("commit", "pr").
+	Defaults map[string]map[string]string
+	// Budget caps model use across all commands; CommandBudgets caps it per
+	// command ("commit", "pr-create", ...).
+	Budget         Budget
+	CommandBudgets map[string]Budget
+	// StrictLanguage rejects language values that are not known spellings
+	// instead of passing them on with a warning.
+	StrictLanguage bool
+	// Warnings lists problems in the file that did not stop it from
+	// loading.
+	Warnings []string
+	// DiffOmitUnreadable collapses minified or encoded hunks in the diffs
+	// pr create and review send. A hunk is unreadable when its changed
+	// lines average more than DiffMaxLineLength characters or one of them
+	// is longer than DiffMaxLineKB kilobytes.
+	DiffOmitUnreadable bool
+	DiffMaxLineLength  int
+	DiffMaxLineKB      int
+	// CommitPerPathStyle shows the model recent commit subjects of the
+	// most changed files, so messages follow a file's own conventions.
+	CommitPerPathStyle bool
+	// CommitSubjectLanguage and CommitBodyLanguage are the languages of the
+	// subject line and of the body of commit messages; both default to
+	// CommitLanguage.
+	CommitSubjectLanguage string
+	CommitBodyLanguage    string
+	// PromptFileOrder is the order of the files in the diffs sent to the
+	// model: FileOrderSignificance or FileOrderPath.
+	PromptFileOrder string
+	// Provider names the registered model provider to generate with; ""
+	// uses the default.
+	Provider string
+	// BedrockRegion and BedrockProfile are the AWS region and the profile of
+	// the shared credentials file the bedrock provider uses.
+	BedrockRegion  string
+	BedrockProfile string
+	// OpenAICompatibleBaseURL is the server of the openai-compatible
+	// provider, such as http://localhost:4000/v1.
+	OpenAICompatibleBaseURL string
+	// RetryMaxAttempts is how many times a model call failing with a rate
+	// limit or a server error is tried, 1 disabling retries. RetryBaseDelay
+	// is the wait before the first retry, doubled for each further one.
+	RetryMaxAttempts int
+	RetryBaseDelay   time.Duration
+}
+
+// Budget limits model use per local day and per week (starting Monday).
+type Budget struct {
+	Daily  BudgetLimit `yaml:"daily"`
+	Weekly BudgetLimit `yaml:"weekly"`
+}
+
+// BudgetLimit is the most model calls and input tokens allowed in a period.
+// Zero means no limit.
+type BudgetLimit struct {
+	Calls       int `yaml:"calls"`
+	InputTokens int `yaml:"input_tokens"`
+}
+
+// IsZero reports whether b limits nothing.
+func (b Budget) IsZero() bool {
+	return b == Budget{}
+}
+
+// RedactRule masks every match of Pattern with Replace before content is
+// sent to the model.
+type RedactRule struct {
+	Pattern string `yaml:"pattern"`
+	Replace string `yaml:"replace"`
+}
+
+// TrivialRule proposes Message without asking the model when every staged
+// file matches one of Paths.
+type TrivialRule struct {
+	Name    string   `yaml:"name"`
+	Paths   []string `yaml:"paths"`
+	Message string   `yaml:"message"`
 }
 
 type FileConfig struct {
@@ -32,26 +161,97 @@ type FileConfig struct {
 		Flash string `yaml:"flash"`
 		Pro   string `yaml:"pro"`
 	} `yaml:"model"`
-	Language string `yaml:"language"`
-	Color    string `yaml:"color"`
-	Commit   struct {
-		Model    string `yaml:"model"`
-		Language string `yaml:"language"`
+	Language       string `yaml:"language"`
+	StrictLanguage bool   `yaml:"strict_language"`
+	UILanguage     string `yaml:"ui_language"`
+	Color          string `yaml:"color"`
+	Commit         struct {
+		Model        string        `yaml:"model"`
+		Language     string        `yaml:"language"`
+		Case         string        `yaml:"case"`
+		AllowEmoji   *bool         `yaml:"allow_emoji"`
+		Trailers     []string      `yaml:"trailers"`
+		Wrap         *int          `yaml:"wrap"`
+		TrivialRules []TrivialRule `yaml:"trivial_rules"`
+		PerPathStyle bool          `yaml:"per_path_style"`
+		// SubjectLanguage and BodyLanguage override Language for one part
+		// of the message.
+		SubjectLanguage string `yaml:"subject_language"`
+		BodyLanguage    string `yaml:"body_language"`
 	} `yaml:"commit"`
 	PR struct {
-		Model         string `yaml:"model"`
-		Language      string `yaml:"language"`
-		TitleLanguage string `yaml:"title_language"`
-		BodyLanguage  string `yaml:"body_language"`
+		Model              string         `yaml:"model"`
+		Language           string         `yaml:"language"`
+		TitleLanguage      string         `yaml:"title_language"`
+		BodyLanguage       string         `yaml:"body_language"`
+		TemplateMerge      string         `yaml:"template_merge"`
+		RequiredCheckboxes []string       `yaml:"required_checkboxes"`
+		SuccessSummary     *bool          `yaml:"success_summary"`
+		PostCreate         []string       `yaml:"post_create"`
+		LearnDefaults      bool           `yaml:"learn_defaults"`
+		ReviewerHistory    bool           `yaml:"reviewer_from_history"`
+		Attribution        bool           `yaml:"attribution"`
+		Wrap               int            `yaml:"wrap"`
+		SizeLabels         map[string]int `yaml:"size_labels"`
+		Split              struct {
+			MaxFiles *int `yaml:"max_files"`
+			MaxLines *int `yaml:"max_lines"`
+		} `yaml:"split"`
 	} `yaml:"pr"`
+	Template struct {
+		LookupTimeout string `yaml:"lookup_timeout"`
+	} `yaml:"template"`
+	Secrets struct {
+		Entropy  *bool             `yaml:"entropy"`
+		Patterns map[string]string `yaml:"patterns"`
+	} `yaml:"secrets"`
+	Monorepo struct {
+		Scopes        map[string]string `yaml:"scopes"`
+		FallbackScope string            `yaml:"fallback_scope"`
+	} `yaml:"monorepo"`
+	Budget struct {
+		Budget   `yaml:",inline"`
+		Commands map[string]Budget `yaml:"commands"`
+	} `yaml:"budget"`
+	Diff struct {
+		OmitUnreadable *bool `yaml:"omit_unreadable"`
+		MaxLineLength  int   `yaml:"max_line_length"`
+		MaxLineKB      int   `yaml:"max_line_kb"`
+	} `yaml:"diff"`
+	Prompt struct {
+		FileOrder string `yaml:"file_order"`
+	} `yaml:"prompt"`
+	Redact   []RedactRule              `yaml:"redact"`
+	Defaults map[string]map[string]any `yaml:"defaults"`
+
+	// Provider names the model provider; the vertex_ai section configures
+	// the default one.
+	Provider string `yaml:"provider"`
+	Bedrock  struct {
+		Region  string `yaml:"region"`
+		Profile string `yaml:"profile"`
+	} `yaml:"bedrock"`
+	OpenAICompatible struct {
+		BaseURL string `yaml:"base_url"`
+	} `yaml:"openai_compatible"`
+	Retry struct {
+		MaxAttempts *int   `yaml:"max_attempts"`
+		BaseDelay   string `yaml:"base_delay"`
+	} `yaml:"retry"`
 }
 
 func Load() (*Config, error) {
 	// Load from file first (lowest priority)
-	fileConfig, err := loadFromFile()
-	if err != nil {
-		// File not found or invalid is not an error - use defaults
-		fileConfig = &FileConfig{}
+	fileConfig := &FileConfig{}
+	var warnings []string
+	// File not found or not YAML is not an error - use defaults
+	if node, err := loadFromFile(); err == nil {
+		if warnings, err = validateFile(node); err != nil {
+			return nil, err
+		}
+		if err := node.Decode(fileConfig); err != nil {
+			return nil, fmt.Errorf("invalid configuration: %w", err)
+		}
 	}
 
 	// Environment variables override file config
@@ -71,21 +271,69 @@ func Load() (*Config, error) {
 		location = "global"
 	}
 
-	// Define model names
+	bedrockRegion := os.Getenv("AWS_REGION")
+	if bedrockRegion == "" {
+		bedrockRegion = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if bedrockRegion == "" {
+		bedrockRegion = fileConfig.Bedrock.Region
+	}
+
+	bedrockProfile := os.Getenv("AWS_PROFILE")
+	if bedrockProfile == "" {
+		bedrockProfile = fileConfig.Bedrock.Profile
+	}
+	if bedrockProfile == "" {
+		bedrockProfile = "default"
+	}
+
+	openAICompatibleBaseURL := os.Getenv("OPENAI_COMPATIBLE_BASE_URL")
+	if openAICompatibleBaseURL == "" {
+		openAICompatibleBaseURL = fileConfig.OpenAICompatible.BaseURL
+	}
+
+	// Define model names, by default the provider's
+	defaultFlashModel, defaultProModel := DefaultModels(fileConfig.Provider)
 	flashModel := fileConfig.Model.Flash
 	if flashModel == "" {
-		flashModel = "gemini-3-flash-preview"
+		flashModel = defaultFlashModel
 	}
 
 	proModel := fileConfig.Model.Pro
 	if proModel == "" {
-		proModel = "gemini-3.1-pro-preview"
+		proModel = defaultProModel
+	}
+
+	// Languages of generated text, in their canonical names
+	for _, setting := range []struct {
+		key   string
+		value *string
+	}{
+		{"language", &fileConfig.Language},
+		{"commit.language", &fileConfig.Commit.Language},
+		{"commit.subject_language", &fileConfig.Commit.SubjectLanguage},
+		{"commit.body_language", &fileConfig.Commit.BodyLanguage},
+		{"pr.language", &fileConfig.PR.Language},
+		{"pr.title_language", &fileConfig.PR.TitleLanguage},
+		{"pr.body_language", &fileConfig.PR.BodyLanguage},
+	} {
+		if *setting.value == "" {
+			continue
+		}
+		name, warning, err := language.Normalize(*setting.value, setting.key, fileConfig.StrictLanguage)
+		if err != nil {
+			return nil, err
+		}
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+		*setting.value = name
 	}
 
 	// Default language
 	defaultLanguage := fileConfig.Language
 	if defaultLanguage == "" {
-		defaultLanguage = "english"
+		defaultLanguage = "English"
 	}
 
 	// Commit settings
@@ -99,6 +347,38 @@ func Load() (*Config, error) {
 		commitLanguage = defaultLanguage
 	}
 
+	// Subject and body languages (default to commit.language)
+	commitSubjectLanguage := fileConfig.Commit.SubjectLanguage
+	if commitSubjectLanguage == "" {
+		commitSubjectLanguage = commitLanguage
+	}
+	commitBodyLanguage := fileConfig.Commit.BodyLanguage
+	if commitBodyLanguage == "" {
+		commitBodyLanguage = commitLanguage
+	}
+
+	for i, rule := range fileConfig.Commit.TrivialRules {
+		if rule.Name == "" || len(rule.Paths) == 0 || strings.TrimSpace(rule.Message) == "" {
+			return nil, fmt.Errorf("commit.trivial_rules[%d]: name, paths and message are required", i)
+		}
+		for _, pattern := range rule.Paths {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("commit.trivial_rules %q: invalid path %q: %w", rule.Name, pattern, err)
+			}
+		}
+	}
+
+	commitEmoji := true
+	if fileConfig.Commit.AllowEmoji != nil {
+		commitEmoji = *fileConfig.Commit.AllowEmoji
+	}
+
+	// Commit body wrap column
+	commitWrap := 72
+	if fileConfig.Commit.Wrap != nil {
+		commitWrap = *fileConfig.Commit.Wrap
+	}
+
 	// PR settings
 	prModel := fileConfig.PR.Model
 	if prModel == "" {
@@ -122,6 +402,89 @@ func Load() (*Config, error) {
 		prBodyLanguage = prLanguage
 	}
 
+	// PR template merge mode (repo, org or both)
+	prTemplateMerge := fileConfig.PR.TemplateMerge
+	if prTemplateMerge == "" {
+		prTemplateMerge = "repo"
+	}
+
+	// Size label thresholds
+	prSizeLabels := fileConfig.PR.SizeLabels
+	if len(prSizeLabels) == 0 {
+		prSizeLabels = map[string]int{"S": 50, "M": 250, "L": 1000}
+	}
+	for name, threshold := range prSizeLabels {
+		if name == "" || threshold <= 0 {
+			return nil, fmt.Errorf("invalid pr.size_labels entry %q: %d (expected a name and a positive line count)", name, threshold)
+		}
+	}
+
+	// Reviewability limits beyond which --split is suggested
+	prSplitMaxFiles, prSplitMaxLines := 30, 1000
+	if fileConfig.PR.Split.MaxFiles != nil {
+		prSplitMaxFiles = *fileConfig.PR.Split.MaxFiles
+	}
+	if fileConfig.PR.Split.MaxLines != nil {
+		prSplitMaxLines = *fileConfig.PR.Split.MaxLines
+	}
+
+	// Org template lookup timeout
+	templateTimeout := 3 * time.Second
+	if timeout, err := time.ParseDuration(fileConfig.Template.LookupTimeout); err == nil {
+		templateTimeout = timeout
+	}
+
+	// Retries of model calls failing with a rate limit or a server error
+	retryMaxAttempts := DefaultRetryMaxAttempts
+	if fileConfig.Retry.MaxAttempts != nil {
+		retryMaxAttempts = max(*fileConfig.Retry.MaxAttempts, 1)
+	}
+	retryBaseDelay := DefaultRetryBaseDelay
+	if delay, err := time.ParseDuration(fileConfig.Retry.BaseDelay); err == nil {
+		retryBaseDelay = delay
+	}
+
+	// Stats footer after pull request creation
+	prSuccessStats := true
+	if fileConfig.PR.SuccessSummary != nil {
+		prSuccessStats = *fileConfig.PR.SuccessSummary
+	}
+
+	// Secret scanning settings
+	secretEntropy := true
+	if fileConfig.Secrets.Entropy != nil {
+		secretEntropy = *fileConfig.Secrets.Entropy
+	}
+
+	// Minified and encoded content in diffs
+	diffOmitUnreadable := true
+	if fileConfig.Diff.OmitUnreadable != nil {
+		diffOmitUnreadable = *fileConfig.Diff.OmitUnreadable
+	}
+	diffMaxLineLength := 300
+	if fileConfig.Diff.MaxLineLength > 0 {
+		diffMaxLineLength = fileConfig.Diff.MaxLineLength
+	}
+	diffMaxLineKB := 10
+	if fileConfig.Diff.MaxLineKB > 0 {
+		diffMaxLineKB = fileConfig.Diff.MaxLineKB
+	}
+
+	// Order of the files in prompts
+	promptFileOrder := fileConfig.Prompt.FileOrder
+	if promptFileOrder == "" {
+		promptFileOrder = FileOrderSignificance
+	}
+
+	// Flag defaults per command
+	defaults := make(map[string]map[string]string, len(fileConfig.Defaults))
+	for section, values := range fileConfig.Defaults {
+		defaults[section] = make(map[string]string, len(values))
+		for key, value := range values {
+			defaults[section][key] = fmt.Sprint(value)
+		}
+	}
+
 	// Color settings
 	color := fileConfig.Color
 	if color == "" {
@@ -148,21 +511,81 @@ func Load() (*Config, error) {
 		BaseProModel:    proModel,
 		CommitLanguage:  commitLanguage,
 		CommitModel:     commitModel,
+		CommitCase:      fileConfig.Commit.Case,
+		CommitEmoji:     commitEmoji,
+		CommitTrailers:  fileConfig.Commit.Trailers,
+		CommitWrap:      commitWrap,
+		TrivialRules:    fileConfig.Commit.TrivialRules,
 		PRLanguage:      prLanguage,
 		PRTitleLanguage: prTitleLanguage,
 		PRBodyLanguage:  prBodyLanguage,
 		PRModel:         prModel,
+		PRTemplateMerge: prTemplateMerge,
+		PRRequiredBoxes: fileConfig.PR.RequiredCheckboxes,
+		PRSuccessStats:  prSuccessStats,
+		PRPostCreate:    fileConfig.PR.PostCreate,
+		PRLearnDefaults: fileConfig.PR.LearnDefaults,
+		PRAttribution:   fileConfig.PR.Attribution,
+		PRWrap:          fileConfig.PR.Wrap,
+		PRSizeLabels:    prSizeLabels,
+		TemplateTimeout: templateTimeout,
 		Color:           color,
+		UILanguage:      fileConfig.UILanguage,
+		SecretPatterns:  fileConfig.Secrets.Patterns,
+		SecretEntropy:   secretEntropy,
+		RedactRules:     fileConfig.Redact,
+		MonorepoScopes:  fileConfig.Monorepo.Scopes,
+		FallbackScope:   fileConfig.Monorepo.FallbackScope,
+		Defaults:        defaults,
+		Budget:          fileConfig.Budget.Budget,
+		CommandBudgets:  fileConfig.Budget.Commands,
+		StrictLanguage:  fileConfig.StrictLanguage,
+		Warnings:        warnings,
+
+		DiffOmitUnreadable: diffOmitUnreadable,
+		DiffMaxLineLength:  diffMaxLineLength,
+		DiffMaxLineKB:      diffMaxLineKB,
+
+		CommitPerPathStyle: fileConfig.Commit.PerPathStyle,
+
+		CommitSubjectLanguage: commitSubjectLanguage,
+		CommitBodyLanguage:    commitBodyLanguage,
+
+		PRReviewerHistory: fileConfig.PR.ReviewerHistory,
+		PRSplitMaxFiles:   prSplitMaxFiles,
+		PRSplitMaxLines:   prSplitMaxLines,
+
+		PromptFileOrder: promptFileOrder,
+
+		Provider:       fileConfig.Provider,
+		BedrockRegion:  bedrockRegion,
+		BedrockProfile: bedrockProfile,
+
+		OpenAICompatibleBaseURL: openAICompatibleBaseURL,
+
+		RetryMaxAttempts: retryMaxAttempts,
+		RetryBaseDelay:   retryBaseDelay,
 	}, nil
 }
 
-func loadFromFile() (*FileConfig, error) {
-	// Try to find gelf.yml in current directory, XDG config, or home directory
+func loadFromFile() (*yaml.Node, error) {
+	// Try to find gelf.yml in current directory, the repository root, XDG
+	// config, or home directory
 	configPaths := []string{
 		"gelf.yml",
 		"gelf.yaml",
 	}
 
+	// Add the repository root, so a project configuration applies in its
+	// subdirectories and where GIT_WORK_TREE points outside the current
+	// directory
+	if paths, err := git.GetRepoPaths(); err == nil {
+		configPaths = append(configPaths,
+			filepath.Join(paths.Root, "gelf.yml"),
+			filepath.Join(paths.Root, "gelf.yaml"),
+		)
+	}
+
 	// Add XDG config directory paths
 	if xdgConfigHome := os.Getenv("XDG_CONFIG_HOME"); xdgConfigHome != "" {
 		configPaths = append(configPaths,
@@ -185,22 +608,46 @@ func loadFromFile() (*FileConfig, error) {
 		)
 	}
 
-	var config FileConfig
 	for _, path := range configPaths {
 		data, err := os.ReadFile(path)
 		if err != nil {
 			continue // Try next path
 		}
 
-		if err := yaml.Unmarshal(data, &config); err != nil {
+		// The file is checked against Schema before it is decoded into a
+		// FileConfig.
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
 			return nil, err
 		}
-		return &config, nil
+		return &node, nil
 	}
 
 	return nil, os.ErrNotExist
 }
 
+// DefaultRetryMaxAttempts and DefaultRetryBaseDelay are how model calls are
+// retried when retry.max_attempts and retry.base_delay are not set.
+const (
+	DefaultRetryMaxAttempts = 3
+	DefaultRetryBaseDelay   = time.Second
+)
+
+// DefaultModels returns the models the flash and pro aliases resolve to
+// with provider when model.flash and model.pro are not set; none for
+// openai-compatible, whose models depend on the server.
+func DefaultModels(provider string) (flash, pro string) {
+	switch provider {
+	case "openai-compatible":
+		return "", ""
+	case "openai":
+		return "gpt-4o-mini", "gpt-4o"
+	case "bedrock":
+		return "anthropic.claude-3-5-haiku-20241022-v1:0", "anthropic.claude-3-5-sonnet-20241022-v2:0"
+	}
+	return "gemini-3-flash-preview", "gemini-3.1-pro-preview"
+}
+
 func (c *Config) UseColor() bool {
 	switch c.Color {
 	case "never":
@@ -0,0 +1,297 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Types of configuration values. Lists and maps are only checked to be
+// lists and maps; their entries are checked by Load.
+const (
+	TypeString   = "string"
+	TypeBool     = "bool"
+	TypeInt      = "int"
+	TypeDuration = "duration"
+	TypeList     = "list"
+	TypeMap      = "map"
+	// TypeAny accepts any value.
+	TypeAny = "any"
+)
+
+// Key describes one key of gelf.yml.
+type Key struct {
+	// Name is the dotted path of the key. A segment in angle brackets,
+	// such as <command>, stands for any name.
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Elem describes the entries of a list or map, such as "string" or
+	// "{pattern, replace}".
+	Elem    string `json:"elem,omitempty"`
+	Default string `json:"default,omitempty"`
+	// Env lists the environment variables that override the key, in order
+	// of priority.
+	Env []string `json:"env,omitempty"`
+	// Values lists the accepted values of a string key; empty accepts any.
+	Values      []string `json:"values,omitempty"`
+	Since       string   `json:"since,omitempty"`
+	Description string   `json:"description"`
+}
+
+// TypeName describes the type of the key's values, such as "list of string".
+func (k Key) TypeName() string {
+	switch {
+	case k.Elem != "" && k.Type == TypeList:
+		return "list of " + k.Elem
+	case k.Elem != "" && k.Type == TypeMap:
+		return "map of " + k.Elem
+	}
+	return k.Type
+}
+
+// Schema lists every key gelf.yml accepts. Load checks the file against it,
+// and gelf config docs renders it, so a key is added here and nowhere else
+// to be documented and checked. Keys without a version in Since predate
+// the schema.
+var Schema = []Key{
+	{Name: "provider", Type: TypeString, Default: "vertex_ai", Description: "Model provider to generate with: vertex_ai, configured by the vertex_ai section, openai, which reads its API key from OPENAI_API_KEY and an optional server from OPENAI_BASE_URL, bedrock, configured by the bedrock section, or openai-compatible, configured by the openai_compatible section"},
+	{Name: "vertex_ai.project_id", Type: TypeString, Env: []string{"VERTEXAI_PROJECT", "GOOGLE_CLOUD_PROJECT"}, Description: "Google Cloud project ID used for Vertex AI"},
+	{Name: "vertex_ai.location", Type: TypeString, Default: "global", Env: []string{"VERTEXAI_LOCATION"}, Description: "Vertex AI location"},
+	{Name: "bedrock.region", Type: TypeString, Env: []string{"AWS_REGION", "AWS_DEFAULT_REGION"}, Description: "AWS region of Amazon Bedrock"},
+	{Name: "bedrock.profile", Type: TypeString, Default: "default", Env: []string{"AWS_PROFILE"}, Description: "Profile of the AWS shared credentials file used when AWS_ACCESS_KEY_ID is not set"},
+	{Name: "openai_compatible.base_url", Type: TypeString, Env: []string{"OPENAI_COMPATIBLE_BASE_URL"}, Description: "Server speaking the OpenAI API, such as LiteLLM, vLLM or LM Studio, up to /v1; the API key, if any, is read from OPENAI_COMPATIBLE_API_KEY"},
+	{Name: "model.flash", Type: TypeString, Default: "gemini-3-flash-preview", Description: "Model the flash alias resolves to; gpt-4o-mini with the openai provider and Claude 3.5 Haiku with bedrock; required with openai-compatible"},
+	{Name: "model.pro", Type: TypeString, Default: "gemini-3.1-pro-preview", Description: "Model the pro alias resolves to; gpt-4o with the openai provider and Claude 3.5 Sonnet with bedrock; required with openai-compatible"},
+	{Name: "language", Type: TypeString, Default: "English", Description: "Default language of generated text; codes and native names are accepted"},
+	{Name: "strict_language", Type: TypeBool, Default: "false", Description: "Reject language values that are not known names or codes instead of warning"},
+	{Name: "ui_language", Type: TypeString, Description: "Language of gelf's own interface: en or ja; unset takes it from LC_ALL, LC_MESSAGES or LANG, else en"},
+	{Name: "color", Type: TypeString, Default: "always", Values: []string{"auto", "always", "never"}, Description: "Color output; auto behaves like always"},
+
+	{Name: "commit.model", Type: TypeString, Default: "flash", Description: "Model for commit messages: flash, pro or a model name"},
+	{Name: "commit.language", Type: TypeString, Default: "language", Description: "Language of commit messages"},
+	{Name: "commit.subject_language", Type: TypeString, Default: "commit.language", Description: "Language of the subject line only"},
+	{Name: "commit.body_language", Type: TypeString, Default: "commit.language", Description: "Language of the body only"},
+	{Name: "commit.case", Type: TypeString, Values: []string{"lower", "title"}, Description: "Casing of the Conventional Commits type and scope; unset keeps the model's casing"},
+	{Name: "commit.allow_emoji", Type: TypeBool, Default: "true", Description: "Keep emoji and :shortcode: emoji in commit messages"},
+	{Name: "commit.wrap", Type: TypeInt, Default: "72", Description: "Column long body lines are broken at; 0 disables"},
+	{Name: "commit.trailers", Type: TypeList, Elem: "string", Description: "Trailers appended to every commit; {{branch}} and {{ticket}} are filled in from the branch"},
+	{Name: "commit.trivial_rules", Type: TypeList, Elem: "{name, paths, message}", Description: "Messages proposed without the model when every staged file matches one of paths"},
+	{Name: "commit.per_path_style", Type: TypeBool, Default: "false", Description: "Show the model recent commit subjects of the most changed files"},
+
+	{Name: "pr.model", Type: TypeString, Default: "pro", Description: "Model for pull requests: flash, pro or a model name"},
+	{Name: "pr.language", Type: TypeString, Default: "language", Description: "Language of pull request titles and descriptions"},
+	{Name: "pr.title_language", Type: TypeString, Default: "pr.language", Description: "Language of the title only"},
+	{Name: "pr.body_language", Type: TypeString, Default: "pr.language", Description: "Language of the description only"},
+	{Name: "pr.template_merge", Type: TypeString, Default: "repo", Values: []string{"repo", "org", "both"}, Description: "Template source: repo (then org), org (then repo), or both merged"},
+	{Name: "pr.required_checkboxes", Type: TypeList, Elem: "string", Description: "Checkbox labels that must be checked before creation"},
+	{Name: "pr.success_summary", Type: TypeBool, Default: "true", Description: "Print a stats footer after creating or updating"},
+	{Name: "pr.post_create", Type: TypeList, Elem: "string", Description: "Shell commands run after a pull request is created; {{url}}, {{number}}, {{title}} and {{branch}} are filled in"},
+	{Name: "pr.size_labels", Type: TypeMap, Elem: "int", Default: "{S: 50, M: 250, L: 1000}", Description: "Most changed lines per size/<name> label for --label-from-diff"},
+	{Name: "pr.learn_defaults", Type: TypeBool, Default: "false", Description: "Pre-fill draft, labels and reviewers shared by your last three pull requests"},
+	{Name: "pr.reviewer_from_history", Type: TypeBool, Default: "false", Description: "Suggest the reviewers of your recently merged pull requests"},
+	{Name: "pr.attribution", Type: TypeBool, Default: "false", Description: "End generated descriptions with a Generated by gelf footer"},
+	{Name: "pr.wrap", Type: TypeInt, Default: "0", Description: "Column long description lines are broken at; 0 disables"},
+	{Name: "pr.split.max_files", Type: TypeInt, Default: "30", Description: "Offer --split above this many changed files; 0 disables"},
+	{Name: "pr.split.max_lines", Type: TypeInt, Default: "1000", Description: "Offer --split above this many added and deleted lines; 0 disables"},
+
+	{Name: "monorepo.scopes", Type: TypeMap, Elem: "string", Description: "Path prefix to the scope used in commit messages and pull request titles"},
+	{Name: "monorepo.fallback_scope", Type: TypeString, Description: "Scope for changes spanning several scopes; unset joins the scopes with commas"},
+	{Name: "retry.max_attempts", Type: TypeInt, Default: "3", Description: "Times a model call failing with a rate limit (429) or a server error (5xx) is tried; 1 disables retries"},
+	{Name: "retry.base_delay", Type: TypeDuration, Default: "1s", Description: "Wait before the first retry, doubled for each further one up to 30s, with random jitter"},
+	{Name: "template.lookup_timeout", Type: TypeDuration, Default: "3s", Description: "Time limit for finding the org and base branch pull request templates"},
+	{Name: "secrets.entropy", Type: TypeBool, Default: "true", Description: "Flag long random-looking strings as possible secrets"},
+	{Name: "secrets.patterns", Type: TypeMap, Elem: "string", Description: "Extra secret patterns: rule name to regular expression"},
+	{Name: "redact", Type: TypeList, Elem: "{pattern, replace}", Description: "Regular expressions replaced in everything sent to the model"},
+
+	{Name: "diff.omit_unreadable", Type: TypeBool, Default: "true", Description: "Collapse minified and encoded hunks in pr create and review diffs"},
+	{Name: "diff.max_line_length", Type: TypeInt, Default: "300", Description: "Average changed-line length above which a hunk counts as minified"},
+	{Name: "diff.max_line_kb", Type: TypeInt, Default: "10", Description: "Size of a single changed line, in KB, above which a hunk counts as minified"},
+	{Name: "prompt.file_order", Type: TypeString, Default: FileOrderSignificance, Values: []string{FileOrderSignificance, FileOrderPath}, Description: "Order of the files in the diffs sent to the model"},
+
+	{Name: "budget.daily.calls", Type: TypeInt, Description: "Model calls per local day across all commands; 0 means no limit"},
+	{Name: "budget.daily.input_tokens", Type: TypeInt, Description: "Input tokens per local day across all commands; 0 means no limit"},
+	{Name: "budget.weekly.calls", Type: TypeInt, Description: "Model calls per week, starting Monday, across all commands; 0 means no limit"},
+	{Name: "budget.weekly.input_tokens", Type: TypeInt, Description: "Input tokens per week, starting Monday, across all commands; 0 means no limit"},
+	{Name: "budget.commands.<command>.daily.calls", Type: TypeInt, Description: "Model calls per local day for one command"},
+	{Name: "budget.commands.<command>.daily.input_tokens", Type: TypeInt, Description: "Input tokens per local day for one command"},
+	{Name: "budget.commands.<command>.weekly.calls", Type: TypeInt, Description: "Model calls per week for one command"},
+	{Name: "budget.commands.<command>.weekly.input_tokens", Type: TypeInt, Description: "Input tokens per week for one command"},
+
+	{Name: "defaults.<command>.<flag>", Type: TypeAny, Description: "Default of a command's flag, such as defaults.commit.yes"},
+}
+
+// lookupKey returns the key path names, or whether path is a section
+// holding keys.
+func lookupKey(path []string) (*Key, bool) {
+	section := false
+	for i := range Schema {
+		segments := strings.Split(Schema[i].Name, ".")
+		if len(segments) < len(path) || !matchSegments(segments[:len(path)], path) {
+			continue
+		}
+		if len(segments) == len(path) {
+			return &Schema[i], false
+		}
+		section = true
+	}
+	return nil, section
+}
+
+func matchSegments(pattern, path []string) bool {
+	for i, segment := range pattern {
+		if !strings.HasPrefix(segment, "<") && segment != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// validateFile checks the keys and values of a parsed gelf.yml against
+// Schema. Bad values are errors; unknown keys only warnings, with the
+// closest known key when there is one.
+func validateFile(node *yaml.Node) ([]string, error) {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	var warnings []string
+	err := validateSection(node, nil, &warnings)
+	return warnings, err
+}
+
+func validateSection(node *yaml.Node, path []string, warnings *[]string) error {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyPath := append(append([]string{}, path...), node.Content[i].Value)
+		value := node.Content[i+1]
+		key, section := lookupKey(keyPath)
+		switch {
+		case section && value.Tag == "!!null":
+		case key != nil:
+			if err := validateValue(*key, strings.Join(keyPath, "."), value); err != nil {
+				return err
+			}
+		case section && value.Kind == yaml.MappingNode:
+			if err := validateSection(value, keyPath, warnings); err != nil {
+				return err
+			}
+		case section:
+			return fmt.Errorf("invalid %s (expected a mapping of keys)", strings.Join(keyPath, "."))
+		default:
+			*warnings = append(*warnings, unknownKeyWarning(keyPath))
+		}
+	}
+	return nil
+}
+
+// validateValue checks value against the type and accepted values of key,
+// which is named name in messages.
+func validateValue(key Key, name string, value *yaml.Node) error {
+	if value.Tag == "!!null" {
+		return nil
+	}
+	switch key.Type {
+	case TypeString:
+		if value.Kind != yaml.ScalarNode {
+			return fmt.Errorf("invalid %s (expected a string)", name)
+		}
+		if len(key.Values) > 0 && value.Value != "" && !contains(key.Values, value.Value) {
+			return fmt.Errorf("invalid %s %q (expected %s)", name, value.Value, orList(key.Values))
+		}
+	case TypeBool:
+		if value.Kind != yaml.ScalarNode || value.Tag != "!!bool" {
+			return fmt.Errorf("invalid %s %q (expected true or false)", name, value.Value)
+		}
+	case TypeInt:
+		n, err := strconv.Atoi(value.Value)
+		if value.Kind != yaml.ScalarNode || value.Tag != "!!int" || err != nil {
+			return fmt.Errorf("invalid %s %q (expected a whole number)", name, value.Value)
+		}
+		if n < 0 {
+			return fmt.Errorf("invalid %s %d (expected 0 or more)", name, n)
+		}
+	case TypeDuration:
+		if d, err := time.ParseDuration(value.Value); value.Kind != yaml.ScalarNode || err != nil || d <= 0 {
+			return fmt.Errorf("invalid %s %q (expected a duration such as 3s)", name, value.Value)
+		}
+	case TypeList:
+		if value.Kind != yaml.SequenceNode {
+			return fmt.Errorf("invalid %s (expected a list)", name)
+		}
+	case TypeMap:
+		if value.Kind != yaml.MappingNode {
+			return fmt.Errorf("invalid %s (expected a mapping)", name)
+		}
+	}
+	return nil
+}
+
+// unknownKeyWarning reports the unknown key at path, suggesting the known
+// key in the same section that is closest in spelling.
+func unknownKeyWarning(path []string) string {
+	name := strings.Join(path, ".")
+	last := path[len(path)-1]
+	best, bestDistance := "", len(last)/2+1
+	seen := map[string]bool{}
+	for _, key := range Schema {
+		segments := strings.Split(key.Name, ".")
+		if len(segments) < len(path) || !matchSegments(segments[:len(path)-1], path[:len(path)-1]) {
+			continue
+		}
+		candidate := segments[len(path)-1]
+		if seen[candidate] || strings.HasPrefix(candidate, "<") {
+			continue
+		}
+		seen[candidate] = true
+		if distance := editDistance(last, candidate); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	if best == "" {
+		return fmt.Sprintf("unknown configuration key %s is ignored", name)
+	}
+	suggestion := strings.Join(append(append([]string{}, path[:len(path)-1]...), best), ".")
+	return fmt.Sprintf("unknown configuration key %s is ignored (did you mean %s?)", name, suggestion)
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// orList joins values as "a, b or c".
+func orList(values []string) string {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return strings.Join(values[:len(values)-1], ", ") + " or " + values[len(values)-1]
+}
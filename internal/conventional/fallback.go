@@ -0,0 +1,133 @@
+// Package conventional makes Conventional Commits messages without the
+// model, from the shape of a diff alone.
+package conventional
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/textwidth"
+)
+
+var fallbackTypes = map[git.FileCategory]string{
+	git.CategoryDocs:       "docs",
+	git.CategoryTest:       "test",
+	git.CategoryDependency: "build",
+	git.CategoryConfig:     "chore",
+	git.CategoryAsset:      "chore",
+}
+
+// maxFallbackWidth is the widest subject Fallback returns, in columns.
+const maxFallbackWidth = 72
+
+// Fallback returns a commit message made from the shape of diff alone, for
+// when the model cannot be reached: "feat(cmd): add lint.go (+12/-0)" for a
+// single file, "chore: update 3 files in internal/ui (+120/-45)" otherwise.
+// The type comes from the file categories; source changes are "feat" when
+// they only add files and "chore" otherwise. Scope, file name and directory
+// are left out as needed to keep the subject within 72 columns. It returns
+// "" for an empty diff.
+func Fallback(diff string) string {
+	files := git.ParseDiffSummary(diff).Files
+	if len(files) == 0 {
+		return ""
+	}
+
+	verb := fallbackVerb(diff)
+	classification := git.ClassifyDiff(diff)
+	commitType := "chore"
+	if len(classification.Counts) == 1 {
+		if t, ok := fallbackTypes[classification.Dominant]; ok {
+			commitType = t
+		} else if verb == "add" {
+			commitType = "feat"
+		}
+	}
+
+	added, deleted := 0, 0
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		added += file.AddedLines
+		deleted += file.DeletedLines
+		names = append(names, file.Name)
+	}
+	stats := fmt.Sprintf("(+%d/-%d)", added, deleted)
+
+	// Candidates from the most to the least specific; the last always fits.
+	var candidates []string
+	if len(files) == 1 {
+		name := renamedTo(diff, files[0].Name)
+		if dir, _, ok := strings.Cut(name, "/"); ok {
+			candidates = append(candidates, fmt.Sprintf("%s(%s): %s %s %s", commitType, dir, verb, path.Base(name), stats))
+		}
+		candidates = append(candidates,
+			fmt.Sprintf("%s: %s %s %s", commitType, verb, path.Base(name), stats),
+			fmt.Sprintf("%s: %s 1 file %s", commitType, verb, stats))
+	} else {
+		if dir := commonDir(names); dir != "" {
+			candidates = append(candidates, fmt.Sprintf("%s: %s %d files in %s %s", commitType, verb, len(files), dir, stats))
+		}
+		candidates = append(candidates, fmt.Sprintf("%s: %s %d files %s", commitType, verb, len(files), stats))
+	}
+	for _, candidate := range candidates[:len(candidates)-1] {
+		if textwidth.Width(candidate) <= maxFallbackWidth {
+			return candidate
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// renamedTo returns the new path of name when diff renames it.
+func renamedTo(diff, name string) string {
+	for _, line := range strings.Split(diff, "\n") {
+		if to, ok := strings.CutPrefix(line, "rename to "); ok {
+			return to
+		}
+	}
+	return name
+}
+
+// fallbackVerb is "add" when diff only creates files, "remove" when it only
+// deletes them, "rename" when it only renames them and "update" otherwise.
+func fallbackVerb(diff string) string {
+	files, created, removed, renamed := 0, 0, 0, 0
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			files++
+		case strings.HasPrefix(line, "new file mode"):
+			created++
+		case strings.HasPrefix(line, "deleted file mode"):
+			removed++
+		case strings.HasPrefix(line, "rename to "):
+			renamed++
+		}
+	}
+	switch {
+	case files > 0 && created == files:
+		return "add"
+	case files > 0 && removed == files:
+		return "remove"
+	case files > 0 && renamed == files:
+		return "rename"
+	default:
+		return "update"
+	}
+}
+
+// commonDir returns the deepest directory containing every file, or "" when
+// they only share the repository root.
+func commonDir(files []string) string {
+	dir := path.Dir(files[0])
+	for _, file := range files[1:] {
+		for dir != "." && !strings.HasPrefix(file, dir+"/") {
+			dir = path.Dir(dir)
+		}
+	}
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
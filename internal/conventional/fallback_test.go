@@ -0,0 +1,104 @@
+package conventional
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/EkeMinusYou/gelf/internal/textwidth"
+)
+
+// fileDiff returns the diff of one file: header lines such as "new file
+// mode 100644", then a hunk adding added lines and deleting deleted ones.
+func fileDiff(name string, header []string, added, deleted int) string {
+	lines := []string{"diff --git a/" + name + " b/" + name}
+	lines = append(lines, header...)
+	if added+deleted > 0 {
+		lines = append(lines, "--- a/"+name, "+++ b/"+name, "@@ -1 +1 @@")
+		for range deleted {
+			lines = append(lines, "-old line")
+		}
+		for range added {
+			lines = append(lines, "+new line")
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+var fallbackStatsRegex = regexp.MustCompile(`\(\+\d+/-\d+\)$`)
+
+var newFile = []string{"new file mode 100644", "index 0000000..1234567"}
+
+func TestFallback(t *testing.T) {
+	longDir := "internal/" + strings.Repeat("deeply/nested/", 5) + "package"
+	tests := []struct {
+		name string
+		diff string
+		want string
+	}{
+		{
+			name: "one new source file",
+			diff: fileDiff("cmd/lint.go", newFile, 12, 0),
+			want: "feat(cmd): add lint.go (+12/-0)",
+		},
+		{
+			name: "one file at the root",
+			diff: fileDiff("main.go", []string{"index 1234567..89abcde 100644"}, 2, 1),
+			want: "chore: update main.go (+2/-1)",
+		},
+		{
+			name: "several files in one directory",
+			diff: fileDiff("internal/ui/tui.go", nil, 100, 40) + fileDiff("internal/ui/pr.go", nil, 20, 5) + fileDiff("internal/ui/styles.go", nil, 0, 0),
+			want: "chore: update 3 files in internal/ui (+120/-45)",
+		},
+		{
+			name: "files across top directories",
+			diff: fileDiff("cmd/pr.go", nil, 3, 1) + fileDiff("internal/ai/openai.go", nil, 4, 2),
+			want: "chore: update 2 files (+7/-3)",
+		},
+		{
+			name: "delete only",
+			diff: fileDiff("internal/legacy/a.go", []string{"deleted file mode 100644"}, 0, 30) + fileDiff("internal/legacy/b.go", []string{"deleted file mode 100644"}, 0, 12),
+			want: "chore: remove 2 files in internal/legacy (+0/-42)",
+		},
+		{
+			name: "rename",
+			diff: "diff --git a/internal/ai/client.go b/internal/ai/gemini.go\nsimilarity index 100%\nrename from internal/ai/client.go\nrename to internal/ai/gemini.go\n",
+			want: "chore(internal): rename gemini.go (+0/-0)",
+		},
+		{
+			name: "binary file",
+			diff: "diff --git a/assets/logo.png b/assets/logo.png\nnew file mode 100644\nindex 0000000..1234567\nBinary files /dev/null and b/assets/logo.png differ\n",
+			want: "chore(assets): add logo.png (+0/-0)",
+		},
+		{
+			name: "long directory is left out",
+			diff: fileDiff(longDir+"/a.go", nil, 1, 1) + fileDiff(longDir+"/b.go", nil, 1, 1),
+			want: "chore: update 2 files (+2/-2)",
+		},
+		{
+			name: "long file name is left out",
+			diff: fileDiff("cmd/"+strings.Repeat("very_long_name_", 6)+".go", newFile, 5, 0),
+			want: "feat: add 1 file (+5/-0)",
+		},
+		{
+			name: "empty diff",
+			diff: "",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Fallback(tt.diff)
+			if got != tt.want {
+				t.Errorf("Fallback() = %q, want %q", got, tt.want)
+			}
+			if width := textwidth.Width(got); width > maxFallbackWidth {
+				t.Errorf("Fallback() is %d columns, over %d", width, maxFallbackWidth)
+			}
+			if got != "" && !fallbackStatsRegex.MatchString(got) {
+				t.Errorf("Fallback() = %q, want the (+N/-M) stats", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+// Package deps extracts dependency version bumps from go.mod and
+// package-lock.json diffs.
+package deps
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Ecosystems a bump can come from.
+const (
+	EcosystemGo  = "go"
+	EcosystemNPM = "npm"
+)
+
+// Bump is a dependency whose version changed.
+type Bump struct {
+	Ecosystem string
+	Module    string
+	From      string
+	To        string
+}
+
+// Breaking reports whether the bump crosses a major version, which is the
+// usual signal for breaking changes.
+func (b Bump) Breaking() bool {
+	from, to := majorVersion(b.From), majorVersion(b.To)
+	if from == "0" && to == "0" {
+		// In 0.x every minor release may break.
+		return minorVersion(b.From) != minorVersion(b.To)
+	}
+	return from != to
+}
+
+// Parse returns the bumps found in the go.mod and package-lock.json files of
+// a unified diff, in the order they appear. Other files are ignored.
+func Parse(diff string) []Bump {
+	var bumps []Bump
+	for _, file := range splitFiles(diff) {
+		switch path.Base(file.name) {
+		case "go.mod":
+			bumps = append(bumps, parseGoMod(file.lines)...)
+		case "package-lock.json":
+			bumps = append(bumps, parsePackageLock(file.lines)...)
+		}
+	}
+	return bumps
+}
+
+type fileDiff struct {
+	name  string
+	lines []string
+}
+
+var diffHeaderRegex = regexp.MustCompile(`^diff --git a/(.+) b/(.+)$`)
+
+func splitFiles(diff string) []fileDiff {
+	var files []fileDiff
+	for _, line := range strings.Split(diff, "\n") {
+		if matches := diffHeaderRegex.FindStringSubmatch(line); matches != nil {
+			files = append(files, fileDiff{name: matches[2]})
+			continue
+		}
+		if len(files) > 0 {
+			files[len(files)-1].lines = append(files[len(files)-1].lines, line)
+		}
+	}
+	return files
+}
+
+// goRequireRegex matches a requirement inside or outside a require block:
+// "\tgithub.com/a/b v1.2.3 // indirect" or "require github.com/a/b v1.2.3".
+var goRequireRegex = regexp.MustCompile(`^\s*(?:require\s+)?([^\s()]+)\s+(v[^\s]+)`)
+
+// majorSuffixRegex matches the major version suffix of a module path, as in
+// github.com/a/b/v2.
+var majorSuffixRegex = regexp.MustCompile(`/v[0-9]+$`)
+
+// parseGoMod pairs removed and added requirements by module path without its
+// major version suffix, so that a move from github.com/a/b to
+// github.com/a/b/v2 is a bump of the new path.
+func parseGoMod(lines []string) []Bump {
+	removed := map[string]string{}
+	added := map[string]string{}
+	paths := map[string]string{}
+	var order []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") || line == "" {
+			continue
+		}
+		sign := line[0]
+		if sign != '-' && sign != '+' {
+			continue
+		}
+		matches := goRequireRegex.FindStringSubmatch(line[1:])
+		if matches == nil || matches[1] == "go" || matches[1] == "toolchain" {
+			continue
+		}
+		module, version := majorSuffixRegex.ReplaceAllString(matches[1], ""), matches[2]
+		if sign == '-' {
+			removed[module] = version
+		} else {
+			if _, seen := added[module]; !seen {
+				order = append(order, module)
+			}
+			added[module] = version
+			paths[module] = matches[1]
+		}
+	}
+
+	var bumps []Bump
+	for _, module := range order {
+		from, ok := removed[module]
+		if !ok || from == added[module] {
+			continue
+		}
+		bumps = append(bumps, Bump{Ecosystem: EcosystemGo, Module: paths[module], From: from, To: added[module]})
+	}
+	return bumps
+}
+
+var (
+	lockPackageRegex = regexp.MustCompile(`^\s*"node_modules/([^"]+)":\s*\{`)
+	lockVersionRegex = regexp.MustCompile(`^\s*"version":\s*"([^"]+)"`)
+)
+
+// parsePackageLock reads lockfile v2/v3 diffs, where each package is a
+// "node_modules/<name>" object whose "version" line changes.
+func parsePackageLock(lines []string) []Bump {
+	var bumps []Bump
+	current := ""
+	from := ""
+	seen := map[string]bool{}
+	for _, line := range lines {
+		if line == "" || strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "@@") {
+			if strings.HasPrefix(line, "@@") {
+				current, from = "", ""
+			}
+			continue
+		}
+		sign, text := line[0], line[1:]
+		if matches := lockPackageRegex.FindStringSubmatch(text); matches != nil {
+			current, from = matches[1], ""
+			continue
+		}
+		matches := lockVersionRegex.FindStringSubmatch(text)
+		if matches == nil || current == "" || strings.Contains(current, "/node_modules/") {
+			// Nested copies are transitive duplicates of a top-level bump.
+			continue
+		}
+		switch sign {
+		case '-':
+			from = matches[1]
+		case '+':
+			if from != "" && from != matches[1] && !seen[current] {
+				seen[current] = true
+				bumps = append(bumps, Bump{Ecosystem: EcosystemNPM, Module: current, From: from, To: matches[1]})
+			}
+			from = ""
+		}
+	}
+	return bumps
+}
+
+// GitHubRepo returns the GitHub repository a Go module is hosted in, e.g.
+// github.com/spf13/cobra/v2 -> spf13, cobra.
+func (b Bump) GitHubRepo() (string, string, bool) {
+	if b.Ecosystem != EcosystemGo {
+		return "", "", false
+	}
+	parts := strings.Split(b.Module, "/")
+	if len(parts) < 3 || parts[0] != "github.com" {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// TagPrefix returns the prefix of the release tags of a Go module in a
+// subdirectory of its repository, e.g. github.com/a/b/cmd/v2 -> "cmd/". It
+// is empty for a module at the root.
+func (b Bump) TagPrefix() string {
+	parts := strings.Split(majorSuffixRegex.ReplaceAllString(b.Module, ""), "/")
+	if b.Ecosystem != EcosystemGo || len(parts) <= 3 {
+		return ""
+	}
+	return strings.Join(parts[3:], "/") + "/"
+}
@@ -0,0 +1,80 @@
+package deps
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGoMod(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want []Bump
+	}{
+		{
+			name: "minor bump",
+			diff: "diff --git a/go.mod b/go.mod\n--- a/go.mod\n+++ b/go.mod\n@@ -3,3 +3,3 @@\n require (\n-\tgithub.com/spf13/cobra v1.9.1\n+\tgithub.com/spf13/cobra v1.10.2\n )\n",
+			want: []Bump{{Ecosystem: EcosystemGo, Module: "github.com/spf13/cobra", From: "v1.9.1", To: "v1.10.2"}},
+		},
+		{
+			name: "major bump to a /v2 path",
+			diff: "diff --git a/go.mod b/go.mod\n--- a/go.mod\n+++ b/go.mod\n@@ -3,3 +3,3 @@\n require (\n-\tgithub.com/a/b v1.4.0\n+\tgithub.com/a/b/v2 v2.0.0\n )\n",
+			want: []Bump{{Ecosystem: EcosystemGo, Module: "github.com/a/b/v2", From: "v1.4.0", To: "v2.0.0"}},
+		},
+		{
+			name: "major bump between suffixes",
+			diff: "diff --git a/go.mod b/go.mod\n--- a/go.mod\n+++ b/go.mod\n@@ -1 +1 @@\n-require github.com/a/b/v2 v2.3.0\n+require github.com/a/b/v3 v3.0.1\n",
+			want: []Bump{{Ecosystem: EcosystemGo, Module: "github.com/a/b/v3", From: "v2.3.0", To: "v3.0.1"}},
+		},
+		{
+			name: "added module",
+			diff: "diff --git a/go.mod b/go.mod\n--- a/go.mod\n+++ b/go.mod\n@@ -3,2 +3,3 @@\n require (\n+\tgithub.com/a/b v1.0.0\n )\n",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.diff); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "v1.2.3", 0},
+		{"v1.2.3", "v1.10.0", -1},
+		{"v2.0.0", "v1.99.99", 1},
+		{"v1.2.3-rc.1", "v1.2.3", 0},
+		{"cmd/v1.2.3", "v1.2.3", 0},
+		{"cmd/v1.3.0", "v1.2.3", 1},
+		{"tools/cmd/v0.9.0", "v1.0.0", -1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestTagPrefix(t *testing.T) {
+	tests := []struct {
+		module string
+		want   string
+	}{
+		{"github.com/a/b", ""},
+		{"github.com/a/b/v2", ""},
+		{"github.com/a/b/cmd", "cmd/"},
+		{"github.com/a/b/tools/cmd/v3", "tools/cmd/"},
+	}
+	for _, tt := range tests {
+		bump := Bump{Ecosystem: EcosystemGo, Module: tt.module}
+		if got := bump.TagPrefix(); got != tt.want {
+			t.Errorf("TagPrefix(%q) = %q, want %q", tt.module, got, tt.want)
+		}
+	}
+}
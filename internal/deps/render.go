@@ -0,0 +1,39 @@
+package deps
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RenderSection renders the bumps as a markdown section for a pull request
+// body, with a warning callout for bumps that cross a major version.
+func RenderSection(bumps []Bump) string {
+	if len(bumps) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString("## Dependency updates\n\n")
+
+	var breaking []string
+	for _, bump := range bumps {
+		if bump.Breaking() {
+			breaking = append(breaking, fmt.Sprintf("`%s` %s → %s", bump.Module, bump.From, bump.To))
+		}
+	}
+	if len(breaking) > 0 {
+		builder.WriteString("> [!WARNING]\n")
+		builder.WriteString("> Possibly breaking (major version change):\n")
+		for _, line := range breaking {
+			fmt.Fprintf(&builder, "> - %s\n", line)
+		}
+		builder.WriteString("\n")
+	}
+
+	builder.WriteString("| Package | From | To |\n")
+	builder.WriteString("|---------|------|----|\n")
+	for _, bump := range bumps {
+		fmt.Fprintf(&builder, "| `%s` | %s | %s |\n", bump.Module, bump.From, bump.To)
+	}
+	return strings.TrimRight(builder.String(), "\n")
+}
@@ -0,0 +1,52 @@
+package deps
+
+import (
+	"strconv"
+	"strings"
+)
+
+// versionParts splits "v1.2.3-rc.1+meta" into its numeric release parts.
+// The path prefix of a tag such as "cmd/v1.2.3" is dropped.
+func versionParts(version string) []string {
+	version = version[strings.LastIndex(version, "/")+1:]
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	return strings.Split(version, ".")
+}
+
+func majorVersion(version string) string {
+	return versionParts(version)[0]
+}
+
+func minorVersion(version string) string {
+	parts := versionParts(version)
+	if len(parts) < 2 {
+		return "0"
+	}
+	return parts[1]
+}
+
+// CompareVersions compares the release parts of two versions numerically,
+// returning -1, 0 or 1. Pre-release and build suffixes and tag path prefixes
+// are ignored.
+func CompareVersions(a, b string) int {
+	aParts, bParts := versionParts(a), versionParts(b)
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var x, y int
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
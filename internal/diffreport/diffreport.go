@@ -0,0 +1,112 @@
+// Package diffreport records what happened to each changed file on its way
+// to the model, so --verbose and --json can show what the model saw.
+package diffreport
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/redact"
+)
+
+// Status is what the filtering pipeline did with a file's diff.
+type Status string
+
+const (
+	// Included means the file's diff was sent unchanged.
+	Included Status = "included"
+	// Redacted means redaction rules replaced part of the file's diff.
+	Redacted Status = "redacted"
+	// Omitted means hunks that looked minified or encoded were replaced
+	// with a stat line.
+	Omitted Status = "omitted"
+)
+
+// Entry is the outcome for one changed file.
+type Entry struct {
+	Path   string `json:"path"`
+	Status Status `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Report lists the changed files in diff order.
+type Report []Entry
+
+var fileHeader = regexp.MustCompile(`^diff --git a/(.*) b/(.*)$`)
+
+// Options control the optional steps of the filtering pipeline.
+type Options struct {
+	// OmitUnreadable collapses hunks that look minified or encoded, as
+	// decided by Thresholds, to a stat line.
+	OmitUnreadable bool
+	Thresholds     Thresholds
+}
+
+// Filter runs diff through the filtering pipeline (unreadable hunks, then
+// the redaction rules) file by file and returns the filtered diff with its
+// report.
+func Filter(diff string, redactor *redact.Redactor, opts Options) (string, Report) {
+	var report Report
+	chunks := Split(diff)
+	for i, chunk := range chunks {
+		filtered, omitted, hunks := chunk, 0, 0
+		if opts.OmitUnreadable {
+			filtered, omitted, hunks = omitUnreadable(chunk, opts.Thresholds)
+		}
+		redacted := redactor.Apply(filtered)
+		match := fileHeader.FindStringSubmatch(strings.SplitN(chunk, "\n", 2)[0])
+		if match != nil {
+			entry := Entry{Path: match[2], Status: Included}
+			switch {
+			case omitted > 0:
+				entry.Status = Omitted
+				entry.Reason = "minified/encoded content"
+				if omitted < hunks {
+					entry.Reason += fmt.Sprintf(", %d of %d hunks", omitted, hunks)
+				}
+			case redacted != filtered:
+				entry.Status = Redacted
+				entry.Reason = "matched redact rules"
+			}
+			report = append(report, entry)
+		}
+		chunks[i] = redacted
+	}
+	return strings.Join(chunks, "\n"), report
+}
+
+// Split cuts diff into one chunk per file, before every "diff --git" line.
+// Joining the chunks with newlines gives diff back.
+func Split(diff string) []string {
+	var chunks []string
+	var current []string
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") && len(current) > 0 {
+			chunks = append(chunks, strings.Join(current, "\n"))
+			current = nil
+		}
+		current = append(current, line)
+	}
+	return append(chunks, strings.Join(current, "\n"))
+}
+
+// String renders the report as one aligned line per file.
+func (r Report) String() string {
+	if len(r) == 0 {
+		return ""
+	}
+	width := 0
+	for _, entry := range r {
+		width = max(width, len(entry.Status))
+	}
+	lines := []string{"Files sent to the model:"}
+	for _, entry := range r {
+		line := fmt.Sprintf("  %-*s  %s", width, entry.Status, entry.Path)
+		if entry.Reason != "" {
+			line += " (" + entry.Reason + ")"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,118 @@
+package diffreport
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Thresholds decide when a hunk is treated as minified or encoded content,
+// such as a bundled script, a sourcemap or a base64 blob in a fixture, that
+// git does not detect as binary but that is of no use to the model.
+type Thresholds struct {
+	// AverageLineLength is the average length, in characters, of a hunk's
+	// changed lines above which the hunk is unreadable. 0 disables the
+	// check.
+	AverageLineLength int
+	// LineKB is the length, in kilobytes, above which a single changed line
+	// makes its hunk unreadable. 0 disables the check.
+	LineKB int
+}
+
+const (
+	// minUnreadableBytes keeps small hunks: collapsing them saves little,
+	// and a few long lines are often still worth reading.
+	minUnreadableBytes = 1024
+	// base64Ratio is the share of a hunk's changed text in base64 runs at
+	// which the hunk is treated as an encoded blob.
+	base64Ratio = 0.5
+)
+
+var (
+	// base64Run matches base64 text too long to be an identifier or a
+	// checksum such as a package-lock.json integrity hash.
+	base64Run = regexp.MustCompile(`[A-Za-z0-9+/]{100,}={0,2}`)
+	// base64Line matches a line of base64 wrapped at the usual 64 or 76
+	// columns, as in PEM and MIME fixtures.
+	base64Line = regexp.MustCompile(`^[A-Za-z0-9+/]{60,}={0,2}$`)
+)
+
+// omitUnreadable replaces the hunks of one file's diff that look minified or
+// encoded with a line saying what was left out, keeping their @@ headers. It
+// returns the result, the number of hunks omitted and the number of hunks.
+func omitUnreadable(chunk string, t Thresholds) (string, int, int) {
+	lines := strings.Split(chunk, "\n")
+	out := make([]string, 0, len(lines))
+	omitted, hunks := 0, 0
+	for start := 0; start < len(lines); {
+		if !strings.HasPrefix(lines[start], "@@") {
+			out = append(out, lines[start])
+			start++
+			continue
+		}
+
+		// Lines inside a hunk start with "+", "-", " " or "\", so the next
+		// "@@" starts the next hunk.
+		end := start + 1
+		for end < len(lines) && !strings.HasPrefix(lines[end], "@@") {
+			end++
+		}
+		hunks++
+		if summary, ok := unreadableHunk(lines[start+1:end], t); ok {
+			omitted++
+			out = append(out, lines[start], summary)
+			// Keep the chunk's trailing newline.
+			if end == len(lines) && lines[end-1] == "" {
+				out = append(out, "")
+			}
+		} else {
+			out = append(out, lines[start:end]...)
+		}
+		start = end
+	}
+	if omitted == 0 {
+		return chunk, 0, hunks
+	}
+	return strings.Join(out, "\n"), omitted, hunks
+}
+
+// unreadableHunk reports whether the hunk body lines look minified or
+// encoded and, if so, returns the line that replaces them.
+func unreadableHunk(lines []string, t Thresholds) (string, bool) {
+	added, deleted, size, longest, encoded := 0, 0, 0, 0, 0
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		switch line[0] {
+		case '+':
+			added++
+		case '-':
+			deleted++
+		default:
+			continue
+		}
+		text := line[1:]
+		size += len(text)
+		longest = max(longest, len(text))
+		if base64Line.MatchString(strings.TrimSpace(text)) {
+			encoded += len(text)
+			continue
+		}
+		for _, run := range base64Run.FindAllStringIndex(text, -1) {
+			encoded += run[1] - run[0]
+		}
+	}
+
+	changed := added + deleted
+	if changed == 0 || size < minUnreadableBytes {
+		return "", false
+	}
+	unreadable := (t.LineKB > 0 && longest > t.LineKB*1024) ||
+		(t.AverageLineLength > 0 && size/changed > t.AverageLineLength) ||
+		float64(encoded) >= base64Ratio*float64(size)
+	if !unreadable {
+		return "", false
+	}
+	return fmt.Sprintf("(minified/encoded content omitted: +%d -%d lines, %.1f KB)", added, deleted, float64(size)/1024), true
+}
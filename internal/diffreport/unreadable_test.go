@@ -0,0 +1,83 @@
+package diffreport
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// defaultThresholds are the diff.max_line_length and diff.max_line_kb
+// defaults.
+var defaultThresholds = Thresholds{AverageLineLength: 300, LineKB: 10}
+
+func TestOmitUnreadable(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		wantOmitted int
+		wantSummary string
+	}{
+		{"minified.diff", 1, "(minified/encoded content omitted: +1 -1 lines, 27.8 KB)"},
+		{"base64-data-uri.diff", 1, "(minified/encoded content omitted: +4 -0 lines, 4.0 KB)"},
+		{"base64-wrapped.diff", 1, "(minified/encoded content omitted: +56 -0 lines, 4.0 KB)"},
+		// Integrity hashes are base64 too, but a lockfile bump is worth
+		// reading and must not collapse.
+		{"package-lock.diff", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			chunk := string(data)
+
+			got, omitted, hunks := omitUnreadable(chunk, defaultThresholds)
+			if omitted != tt.wantOmitted || hunks != 1 {
+				t.Fatalf("omitUnreadable() omitted %d of %d hunks, want %d of 1", omitted, hunks, tt.wantOmitted)
+			}
+			if tt.wantOmitted == 0 {
+				if got != chunk {
+					t.Errorf("omitUnreadable() changed a readable diff")
+				}
+				return
+			}
+
+			header, _, _ := strings.Cut(chunk, "\n@@")
+			hunkHeader := "@@" + strings.SplitN(strings.SplitN(chunk, "\n@@", 2)[1], "\n", 2)[0]
+			if want := header + "\n" + hunkHeader + "\n" + tt.wantSummary + "\n"; got != want {
+				t.Errorf("omitUnreadable() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestOmitUnreadableKeepsReadableHunks(t *testing.T) {
+	minified, err := os.ReadFile(filepath.Join("testdata", "minified.diff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, minifiedHunk, _ := strings.Cut(string(minified), "\n@@ -1 +1 @@\n")
+	readable := "@@ -10,3 +10,3 @@\n context\n-old line\n+new line\n"
+	chunk := "diff --git a/dist/app.min.js b/dist/app.min.js\n--- a/dist/app.min.js\n+++ b/dist/app.min.js\n" +
+		readable + "@@ -40 +40 @@\n" + minifiedHunk
+
+	got, omitted, hunks := omitUnreadable(chunk, defaultThresholds)
+	if omitted != 1 || hunks != 2 {
+		t.Fatalf("omitUnreadable() omitted %d of %d hunks, want 1 of 2", omitted, hunks)
+	}
+	if !strings.Contains(got, readable) {
+		t.Errorf("omitUnreadable() dropped the readable hunk:\n%s", got)
+	}
+	if !strings.Contains(got, "@@ -40 +40 @@\n(minified/encoded content omitted:") {
+		t.Errorf("omitUnreadable() kept the minified hunk:\n%s", got)
+	}
+}
+
+func TestOmitUnreadableSmallHunks(t *testing.T) {
+	// A short base64 line, such as a test key, stays.
+	chunk := "diff --git a/key.txt b/key.txt\n--- a/key.txt\n+++ b/key.txt\n@@ -0,0 +1 @@\n+" + strings.Repeat("QUJD", 50) + "\n"
+	if got, omitted, _ := omitUnreadable(chunk, defaultThresholds); omitted != 0 || got != chunk {
+		t.Errorf("omitUnreadable() omitted a %d-byte hunk", len(chunk))
+	}
+}
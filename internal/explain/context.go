@@ -0,0 +1,225 @@
+package explain
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/git"
+)
+
+// Limits on how much of a directory is sent to the model.
+const (
+	maxFiles        = 500
+	maxExportsBytes = 32 * 1024
+	maxExcerptBytes = 48 * 1024
+	excerptLines    = 40
+	commitLimit     = 20
+)
+
+// Context is what the model is told about a directory.
+type Context struct {
+	Path      string
+	Files     []string
+	Exports   string
+	Excerpts  string
+	CommitLog string
+}
+
+// Gather collects the tracked files under path, the exported identifiers of
+// its Go packages, excerpts of its other files and the recent commit
+// subjects touching it.
+func Gather(path string) (*Context, error) {
+	files, err := git.ListFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no tracked files under %s", path)
+	}
+
+	commitLog, err := git.GetPathLog(path, commitLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit log: %w", err)
+	}
+
+	listed := files
+	if len(listed) > maxFiles {
+		listed = listed[:maxFiles]
+	}
+
+	var goFiles, otherFiles []string
+	for _, file := range files {
+		switch {
+		case strings.HasSuffix(file, "_test.go"):
+		case strings.HasSuffix(file, ".go"):
+			goFiles = append(goFiles, file)
+		default:
+			otherFiles = append(otherFiles, file)
+		}
+	}
+
+	return &Context{
+		Path:      path,
+		Files:     listed,
+		Exports:   goExports(goFiles),
+		Excerpts:  excerpts(otherFiles),
+		CommitLog: commitLog,
+	}, nil
+}
+
+// goExports lists the exported declarations of goFiles grouped by package
+// directory, with the first sentence of their doc comments.
+func goExports(goFiles []string) string {
+	byDir := make(map[string][]string)
+	for _, file := range goFiles {
+		dir := filepath.Dir(file)
+		byDir[dir] = append(byDir[dir], file)
+	}
+	dirs := make([]string, 0, len(byDir))
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var builder strings.Builder
+	fset := token.NewFileSet()
+	for _, dir := range dirs {
+		var lines []string
+		pkgName := ""
+		for _, file := range byDir[dir] {
+			parsed, err := parser.ParseFile(fset, file, nil, parser.ParseComments|parser.SkipObjectResolution)
+			if err != nil {
+				continue
+			}
+			pkgName = parsed.Name.Name
+			lines = append(lines, exportedDecls(fset, parsed)...)
+		}
+		if pkgName == "" {
+			continue
+		}
+
+		fmt.Fprintf(&builder, "%s (package %s):\n", dir, pkgName)
+		for _, line := range lines {
+			fmt.Fprintf(&builder, "  %s\n", line)
+		}
+		if builder.Len() > maxExportsBytes {
+			builder.WriteString("  ...\n")
+			break
+		}
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+func exportedDecls(fset *token.FileSet, file *ast.File) []string {
+	var lines []string
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if !decl.Name.IsExported() || !receiverExported(decl.Recv) {
+				continue
+			}
+			signature := &ast.FuncDecl{Recv: decl.Recv, Name: decl.Name, Type: decl.Type}
+			var buf bytes.Buffer
+			if err := printer.Fprint(&buf, fset, signature); err != nil {
+				continue
+			}
+			lines = append(lines, withDoc(buf.String(), decl.Doc))
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if spec.Name.IsExported() {
+						doc := spec.Doc
+						if doc == nil {
+							doc = decl.Doc
+						}
+						lines = append(lines, withDoc("type "+spec.Name.Name, doc))
+					}
+				case *ast.ValueSpec:
+					doc := spec.Doc
+					if doc == nil && !decl.Lparen.IsValid() {
+						doc = decl.Doc
+					}
+					for _, name := range spec.Names {
+						if name.IsExported() {
+							lines = append(lines, withDoc(decl.Tok.String()+" "+name.Name, doc))
+						}
+					}
+				}
+			}
+		}
+	}
+	return lines
+}
+
+// receiverExported reports whether a method's receiver type is exported.
+// Functions without a receiver count as exported.
+func receiverExported(recv *ast.FieldList) bool {
+	if recv == nil || len(recv.List) == 0 {
+		return true
+	}
+	expr := recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.IsExported()
+	case *ast.IndexExpr:
+		if ident, ok := expr.X.(*ast.Ident); ok {
+			return ident.IsExported()
+		}
+	case *ast.IndexListExpr:
+		if ident, ok := expr.X.(*ast.Ident); ok {
+			return ident.IsExported()
+		}
+	}
+	return false
+}
+
+func withDoc(decl string, doc *ast.CommentGroup) string {
+	if doc == nil {
+		return decl
+	}
+	text := strings.Join(strings.Fields(doc.Text()), " ")
+	if idx := strings.Index(text, ". "); idx != -1 {
+		text = text[:idx+1]
+	}
+	if text == "" {
+		return decl
+	}
+	return decl + " // " + text
+}
+
+// excerpts returns the first lines of each file, for languages gelf cannot
+// parse, until maxExcerptBytes is reached.
+func excerpts(files []string) string {
+	var builder strings.Builder
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil || bytes.IndexByte(content, 0) != -1 {
+			// Skip unreadable and binary files.
+			continue
+		}
+
+		lines := strings.SplitN(string(content), "\n", excerptLines+1)
+		if len(lines) > excerptLines {
+			lines = lines[:excerptLines]
+		}
+		excerpt := fmt.Sprintf("--- %s ---\n%s\n", file, strings.Join(lines, "\n"))
+		if builder.Len()+len(excerpt) > maxExcerptBytes {
+			builder.WriteString("...\n")
+			break
+		}
+		builder.WriteString(excerpt)
+	}
+	return strings.TrimSpace(builder.String())
+}
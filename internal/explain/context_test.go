@@ -0,0 +1,176 @@
+package explain
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/EkeMinusYou/gelf/internal/git"
+)
+
+// newTestRepo makes a repository in a temporary directory, commits files
+// (keyed by path) one commit per entry of commits, and changes into it.
+func newTestRepo(t *testing.T, commits ...map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "gelf")
+	t.Setenv("GIT_AUTHOR_EMAIL", "gelf@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "gelf")
+	t.Setenv("GIT_COMMITTER_EMAIL", "gelf@example.com")
+	git.ForgetRepoPaths()
+	t.Cleanup(git.ForgetRepoPaths)
+	runTestGit(t, "init", "-q", "-b", "main")
+	for i, files := range commits {
+		for name, content := range files {
+			if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			runTestGit(t, "add", name)
+		}
+		runTestGit(t, "commit", "-q", "-m", "commit "+string(rune('A'+i)))
+	}
+}
+
+func runTestGit(t *testing.T, args ...string) {
+	t.Helper()
+	if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, output)
+	}
+}
+
+const retryGo = `// Package retry retries calls.
+package retry
+
+// Policy says how often to retry. It is safe to copy.
+type Policy struct{}
+
+// Do calls f until it succeeds.
+func Do(f func() error) error { return nil }
+
+// Wait waits before the next attempt.
+func (p *Policy) Wait() {}
+
+func (b *backoff) Next() {}
+
+type backoff struct{}
+
+// MaxAttempts is the default number of attempts.
+const MaxAttempts = 3
+
+var (
+	// Default is the policy used by Do.
+	Default Policy
+	verbose bool
+)
+`
+
+func TestGatherGoPackage(t *testing.T) {
+	newTestRepo(t,
+		map[string]string{
+			"internal/retry/retry.go":      retryGo,
+			"internal/retry/retry_test.go": "package retry\n\nfunc TestHidden() {}\n",
+			"internal/retry/README.md":     "# retry\n\nRetries calls.\n",
+			"internal/other/other.go":      "package other\n\nfunc Outside() {}\n",
+		},
+		map[string]string{"internal/retry/jitter/jitter.go": "package jitter\n\n// Add adds jitter.\nfunc Add() {}\n"},
+		map[string]string{"internal/other/more.go": "package other\n"},
+	)
+
+	got, err := Gather("internal/retry")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantFiles := "internal/retry/README.md|internal/retry/jitter/jitter.go|internal/retry/retry.go|internal/retry/retry_test.go"
+	if files := strings.Join(got.Files, "|"); files != wantFiles {
+		t.Errorf("Files = %q, want %q", files, wantFiles)
+	}
+
+	wantExports := `internal/retry (package retry):
+  type Policy // Policy says how often to retry.
+  func Do(f func() error) error // Do calls f until it succeeds.
+  func (p *Policy) Wait() // Wait waits before the next attempt.
+  const MaxAttempts // MaxAttempts is the default number of attempts.
+  var Default // Default is the policy used by Do.
+internal/retry/jitter (package jitter):
+  func Add() // Add adds jitter.`
+	if got.Exports != wantExports {
+		t.Errorf("Exports =\n%s\nwant\n%s", got.Exports, wantExports)
+	}
+
+	if got.Excerpts != "--- internal/retry/README.md ---\n# retry\n\nRetries calls." {
+		t.Errorf("Excerpts = %q, want the README only", got.Excerpts)
+	}
+
+	// Only the commits touching the path, newest first.
+	var subjects []string
+	for _, line := range strings.Split(got.CommitLog, "\n") {
+		_, subject, _ := strings.Cut(line, " ")
+		subjects = append(subjects, subject)
+	}
+	if strings.Join(subjects, "|") != "commit B|commit A" {
+		t.Errorf("CommitLog = %q, want commits B and A", got.CommitLog)
+	}
+}
+
+func TestGatherOtherLanguages(t *testing.T) {
+	long := strings.Repeat("line\n", excerptLines+10)
+	newTestRepo(t, map[string]string{
+		"web/app.ts":    "export function start() {}\n",
+		"web/long.py":   long,
+		"web/logo.png":  "\x89PNG\x00\x00binary",
+		"web/broken.go": "package web\n\nfunc Broken( {\n",
+	})
+
+	got, err := Gather("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Exports != "" {
+		t.Errorf("Exports = %q, want none for a file that does not parse", got.Exports)
+	}
+	if !strings.Contains(got.Excerpts, "--- web/app.ts ---\nexport function start() {}") {
+		t.Errorf("Excerpts = %q, want app.ts", got.Excerpts)
+	}
+	if strings.Contains(got.Excerpts, "logo.png") {
+		t.Errorf("Excerpts = %q, want binary files skipped", got.Excerpts)
+	}
+	if _, python, _ := strings.Cut(got.Excerpts, "--- web/long.py ---\n"); strings.Count(python, "line") != excerptLines {
+		t.Errorf("long.py excerpt has %d lines, want %d", strings.Count(python, "line"), excerptLines)
+	}
+}
+
+func TestGatherNoFiles(t *testing.T) {
+	newTestRepo(t, map[string]string{"main.go": "package main\n"})
+	if _, err := Gather("missing"); err == nil || !strings.Contains(err.Error(), "no tracked files under missing") {
+		t.Errorf("Gather() error = %v, want no tracked files", err)
+	}
+}
+
+func TestExcerptsCap(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i := range 10 {
+		name := filepath.Join(dir, string(rune('a'+i))+".txt")
+		if err := os.WriteFile(name, []byte(strings.Repeat("x", maxExcerptBytes/4)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, name)
+	}
+
+	got := excerpts(files)
+	if len(got) > maxExcerptBytes {
+		t.Errorf("excerpts are %d bytes, over the cap of %d", len(got), maxExcerptBytes)
+	}
+	if !strings.HasSuffix(got, "...") || strings.Count(got, "--- ") != 3 {
+		t.Errorf("excerpts = %d files, want 3 and a ... marker", strings.Count(got, "--- "))
+	}
+}
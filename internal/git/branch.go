@@ -2,34 +2,54 @@ package git
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os/exec"
 	"strings"
 )
 
 func GetRepoRoot() (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
-	output, err := cmd.Output()
+	paths, err := GetRepoPaths()
 	if err != nil {
 		return "", fmt.Errorf("failed to get repository root: %w", err)
 	}
+	return paths.Root, nil
+}
 
-	root := strings.TrimSpace(string(output))
-	if root == "" {
-		return "", fmt.Errorf("repository root is empty")
+// GetGitDir returns the absolute path of the .git directory of the current
+// worktree.
+func GetGitDir() (string, error) {
+	paths, err := GetRepoPaths()
+	if err != nil {
+		return "", fmt.Errorf("failed to get git directory: %w", err)
 	}
-
-	return root, nil
+	return paths.GitDir, nil
 }
 
+// GetCurrentBranch returns the checked out branch verbatim, or "HEAD" when
+// HEAD is detached. rev-parse --abbrev-ref is not used because it shortens
+// to "heads/<name>" when a tag has the same name. In a repository colocated
+// with jj, where HEAD is always detached, the current bookmark is the
+// branch.
 func GetCurrentBranch() (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-	output, err := cmd.Output()
+	output, err := runGit("symbolic-ref", "--quiet", "HEAD")
 	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			if IsJujutsu() {
+				bookmark, err := GetJujutsuBookmark()
+				if err != nil {
+					return "", err
+				}
+				if bookmark != "" {
+					return bookmark, nil
+				}
+			}
+			return "HEAD", nil
+		}
 		return "", fmt.Errorf("failed to get current branch: %w", err)
 	}
 
-	branch := strings.TrimSpace(string(output))
+	branch := strings.TrimPrefix(strings.TrimSpace(string(output)), "refs/heads/")
 	if branch == "" {
 		return "", fmt.Errorf("current branch is empty")
 	}
@@ -55,8 +75,7 @@ func GetDefaultBaseBranch() (string, error) {
 }
 
 func originHeadBranch() (string, error) {
-	cmd := exec.Command("git", "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD")
-	output, err := cmd.Output()
+	output, err := runGit("symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD")
 	if err != nil {
 		return "", err
 	}
@@ -74,8 +93,7 @@ func originHeadBranch() (string, error) {
 }
 
 func remoteShowHeadBranch() (string, error) {
-	cmd := exec.Command("git", "remote", "show", "origin")
-	output, err := cmd.Output()
+	output, err := runGit("remote", "show", "origin")
 	if err != nil {
 		return "", fmt.Errorf("failed to inspect origin remote: %w", err)
 	}
@@ -97,32 +115,89 @@ func remoteShowHeadBranch() (string, error) {
 }
 
 func GetCommittedDiff(baseRef, headRef string) (string, error) {
-	cmd := exec.Command("git", "--no-pager", "diff", "-U5", fmt.Sprintf("%s...%s", baseRef, headRef))
-	output, err := cmd.Output()
+	output, err := runGit("diff", "--no-color", "--no-ext-diff", "-U5", fmt.Sprintf("%s...%s", baseRef, headRef))
 	if err != nil {
 		return "", err
 	}
 
-	return strings.TrimSpace(string(output)), nil
+	return strings.TrimSpace(stripANSI(string(output))), nil
 }
 
 func GetCommittedDiffStat(baseRef, headRef string) (string, error) {
-	cmd := exec.Command("git", "--no-pager", "diff", "--stat", fmt.Sprintf("%s...%s", baseRef, headRef))
-	output, err := cmd.Output()
+	output, err := runGit("diff", "--no-color", "--no-ext-diff", "--stat", fmt.Sprintf("%s...%s", baseRef, headRef))
 	if err != nil {
 		return "", err
 	}
 
-	return strings.TrimSpace(string(output)), nil
+	return strings.TrimSpace(stripANSI(string(output))), nil
 }
 
 func GetCommitLog(baseRef, headRef string) (string, error) {
 	rangeSpec := fmt.Sprintf("%s..%s", baseRef, headRef)
-	cmd := exec.Command("git", "log", "--reverse", "--format=%h %s", rangeSpec)
-	output, err := cmd.Output()
+	output, err := runGit("log", "--reverse", "--format=%h %s", rangeSpec)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(stripANSI(string(output))), nil
+}
+
+// GetHeadSHA returns the abbreviated SHA of HEAD.
+func GetHeadSHA() (string, error) {
+	output, err := runGit("rev-parse", "--short", "HEAD")
 	if err != nil {
 		return "", err
 	}
 
 	return strings.TrimSpace(string(output)), nil
 }
+
+// IsAncestor reports whether ancestor is reachable from ref. It is false
+// when ancestor does not exist, e.g. after a rebase dropped it.
+func IsAncestor(ancestor, ref string) bool {
+	_, err := runGit("merge-base", "--is-ancestor", ancestor, ref)
+	return err == nil
+}
+
+// ResolveCommit returns the full SHA of the commit ref points at.
+func ResolveCommit(ref string) (string, error) {
+	output, err := runGit("rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// MergeBase returns the full SHA of the best common ancestor of a and b.
+func MergeBase(a, b string) (string, error) {
+	output, err := runGit("merge-base", a, b)
+	if err != nil {
+		return "", fmt.Errorf("failed to find merge base of %s and %s: %w", a, b, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ListRefs returns the short names of the local branches, remote-tracking
+// branches and tags, reading only the local repository. When ctx is done
+// first, it returns the names read so far together with ctx.Err().
+func ListRefs(ctx context.Context) ([]string, error) {
+	// Symbolic refs such as origin/HEAD print as empty lines: they point at
+	// a branch that is listed anyway.
+	output, err := runGitContext(ctx, "for-each-ref", "--format=%(if)%(symref)%(then)%(else)%(refname:short)%(end)", "refs/heads", "refs/remotes", "refs/tags")
+	if err != nil && ctx.Err() == nil {
+		return nil, fmt.Errorf("failed to list refs: %w", err)
+	}
+
+	text := string(output)
+	if err != nil {
+		// The last line may have been cut off mid-name.
+		text = text[:strings.LastIndex(text, "\n")+1]
+	}
+	var refs []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			refs = append(refs, line)
+		}
+	}
+	return refs, ctx.Err()
+}
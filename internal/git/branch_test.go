@@ -0,0 +1,55 @@
+package git
+
+import "testing"
+
+func TestGetCurrentBranch(t *testing.T) {
+	tests := []struct {
+		name   string
+		branch string
+		setup  func(t *testing.T)
+		want   string
+	}{
+		{name: "slashes and hash", branch: "feat/user/#123-fïx", want: "feat/user/#123-fïx"},
+		{
+			name:   "tag with the same name",
+			branch: "release",
+			setup:  func(t *testing.T) { runTestGit(t, "tag", "release") },
+			want:   "release",
+		},
+		{
+			name:  "detached",
+			setup: func(t *testing.T) { runTestGit(t, "checkout", "-q", "--detach") },
+			want:  "HEAD",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestRepo(t)
+			if tt.branch != "" {
+				runTestGit(t, "checkout", "-q", "-b", tt.branch)
+			}
+			if tt.setup != nil {
+				tt.setup(t)
+			}
+			got, err := GetCurrentBranch()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("GetCurrentBranch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpstreamRemoteNameWithSlash(t *testing.T) {
+	dir := newTestRepo(t)
+	runTestGit(t, "remote", "add", "up/stream", dir)
+	runTestGit(t, "fetch", "-q", "up/stream")
+	runTestGit(t, "checkout", "-q", "-b", "feat/search")
+	runTestGit(t, "branch", "-q", "--set-upstream-to=up/stream/main")
+
+	if got := upstreamRemoteName("feat/search", "up/stream/main"); got != "up/stream" {
+		t.Errorf("upstreamRemoteName() = %q, want %q", got, "up/stream")
+	}
+}
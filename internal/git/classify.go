@@ -0,0 +1,245 @@
+package git
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// FileCategory is the kind of content a changed file holds.
+type FileCategory string
+
+const (
+	CategorySource     FileCategory = "source"
+	CategoryTest       FileCategory = "test"
+	CategoryDocs       FileCategory = "docs"
+	CategoryConfig     FileCategory = "config"
+	CategoryDependency FileCategory = "dependency"
+	CategoryAsset      FileCategory = "asset"
+)
+
+var dependencyFiles = map[string]bool{
+	"go.mod": true, "go.sum": true,
+	"package.json": true, "package-lock.json": true, "yarn.lock": true, "pnpm-lock.yaml": true,
+	"Cargo.toml": true, "Cargo.lock": true,
+	"Gemfile": true, "Gemfile.lock": true,
+	"Pipfile": true, "Pipfile.lock": true, "poetry.lock": true, "requirements.txt": true,
+	"composer.json": true, "composer.lock": true,
+}
+
+var testDirs = map[string]bool{"test": true, "tests": true, "__tests__": true, "spec": true, "testdata": true}
+
+var docsDirs = map[string]bool{"docs": true, "doc": true}
+
+var infraDirs = map[string]bool{
+	".github": true, ".circleci": true, "deploy": true, "deployments": true,
+	"infra": true, "k8s": true, "helm": true, "charts": true, "terraform": true,
+}
+
+var docsExtensions = map[string]bool{".md": true, ".mdx": true, ".rst": true, ".adoc": true, ".txt": true}
+
+var assetExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".ico": true, ".webp": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".otf": true,
+	".mp3": true, ".mp4": true, ".wav": true, ".pdf": true,
+}
+
+var configExtensions = map[string]bool{
+	".yml": true, ".yaml": true, ".toml": true, ".ini": true, ".json": true,
+	".tf": true, ".hcl": true, ".cfg": true, ".conf": true,
+}
+
+// ClassifyFile tags a repository path with its category based on its name,
+// extension and directories.
+func ClassifyFile(filePath string) FileCategory {
+	base := path.Base(filePath)
+	lowerBase := strings.ToLower(base)
+	ext := path.Ext(lowerBase)
+	if ext == ".example" || ext == ".sample" || ext == ".dist" {
+		// gelf.yml.example is classified like gelf.yml.
+		ext = path.Ext(strings.TrimSuffix(lowerBase, ext))
+	}
+	dirs := strings.Split(path.Dir(filePath), "/")
+
+	switch {
+	case dependencyFiles[base] || (strings.HasPrefix(lowerBase, "requirements") && ext == ".txt"):
+		return CategoryDependency
+	case isTestFile(lowerBase) || anyDir(dirs, testDirs):
+		return CategoryTest
+	case docsExtensions[ext] || anyDir(dirs, docsDirs) ||
+		strings.HasPrefix(lowerBase, "license") || strings.HasPrefix(lowerBase, "changelog"):
+		return CategoryDocs
+	case assetExtensions[ext]:
+		return CategoryAsset
+	case configExtensions[ext] || anyDir(dirs, infraDirs) || isInfraFile(lowerBase):
+		return CategoryConfig
+	default:
+		return CategorySource
+	}
+}
+
+func isTestFile(lowerBase string) bool {
+	return strings.HasSuffix(lowerBase, "_test.go") ||
+		strings.Contains(lowerBase, ".test.") ||
+		strings.Contains(lowerBase, ".spec.") ||
+		(strings.HasPrefix(lowerBase, "test_") && strings.HasSuffix(lowerBase, ".py")) ||
+		strings.HasSuffix(lowerBase, "_test.py")
+}
+
+func isInfraFile(lowerBase string) bool {
+	return strings.HasPrefix(lowerBase, "dockerfile") ||
+		strings.HasPrefix(lowerBase, "docker-compose") ||
+		strings.HasPrefix(lowerBase, ".env") ||
+		lowerBase == "makefile" ||
+		lowerBase == "jenkinsfile" ||
+		lowerBase == ".gitlab-ci.yml"
+}
+
+func anyDir(dirs []string, set map[string]bool) bool {
+	for _, dir := range dirs {
+		if set[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// FileClassification summarizes the categories of the files in a diff.
+type FileClassification struct {
+	Counts map[FileCategory]int
+	// Dominant is the category with the most changed lines.
+	Dominant FileCategory
+}
+
+// ClassifyDiff classifies the files changed by diff.
+func ClassifyDiff(diff string) FileClassification {
+	classification := FileClassification{Counts: make(map[FileCategory]int)}
+	lines := make(map[FileCategory]int)
+	for _, file := range ParseDiffSummary(diff).Files {
+		category := ClassifyFile(file.Name)
+		classification.Counts[category]++
+		lines[category] += file.AddedLines + file.DeletedLines
+	}
+
+	best := -1
+	for _, category := range classification.categories() {
+		if lines[category] > best {
+			best = lines[category]
+			classification.Dominant = category
+		}
+	}
+	return classification
+}
+
+// Only reports whether every changed file is in category.
+func (c FileClassification) Only(category FileCategory) bool {
+	return len(c.Counts) == 1 && c.Counts[category] > 0
+}
+
+// String formats the counts, e.g. "source: 3, test: 2".
+func (c FileClassification) String() string {
+	var parts []string
+	for _, category := range c.categories() {
+		parts = append(parts, fmt.Sprintf("%s: %d", category, c.Counts[category]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// categories returns the categories present, most files first.
+func (c FileClassification) categories() []FileCategory {
+	categories := make([]FileCategory, 0, len(c.Counts))
+	for category := range c.Counts {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if c.Counts[categories[i]] != c.Counts[categories[j]] {
+			return c.Counts[categories[i]] > c.Counts[categories[j]]
+		}
+		return categories[i] < categories[j]
+	})
+	return categories
+}
+
+// significanceRank orders file categories for OrderDiffBySignificance: the
+// code a change is about first, then what supports or follows from it.
+var significanceRank = map[FileCategory]int{
+	CategorySource:     0,
+	CategoryConfig:     1,
+	CategoryTest:       2,
+	CategoryDocs:       3,
+	CategoryDependency: 4,
+	CategoryAsset:      5,
+}
+
+// OrderDiffBySignificance reorders the per-file sections of diff so that
+// the most significant come first: source files before config, tests, docs,
+// dependency files and assets, and within a category the files with the
+// most changed lines first. Files that tie keep git's order. Sections are
+// moved whole, so the result is a valid diff of the same changes. A diff
+// that is not made of "diff --git" sections, such as a merge's combined
+// diff, is returned unchanged.
+func OrderDiffBySignificance(diff string) string {
+	if !strings.HasPrefix(diff, "diff --git ") {
+		return diff
+	}
+
+	// Lines inside a hunk start with "+", "-", " " or "\", so every line
+	// starting with "diff --git " starts a file.
+	var starts []int
+	for offset := 0; offset < len(diff); {
+		if strings.HasPrefix(diff[offset:], "diff --git ") {
+			starts = append(starts, offset)
+		}
+		next := strings.IndexByte(diff[offset:], '\n')
+		if next < 0 {
+			break
+		}
+		offset += next + 1
+	}
+	if len(starts) < 2 {
+		return diff
+	}
+
+	type section struct {
+		text  string
+		rank  int
+		lines int
+	}
+	sections := make([]section, len(starts))
+	for i, start := range starts {
+		end := len(diff)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		sections[i].text = diff[start:end]
+		if files := ParseDiffSummary(sections[i].text).Files; len(files) > 0 {
+			sections[i].rank = significanceRank[ClassifyFile(files[0].Name)]
+			sections[i].lines = files[0].AddedLines + files[0].DeletedLines
+		}
+	}
+	// The last section may lack the final newline, which it needs once
+	// another section follows it.
+	trimmed := !strings.HasSuffix(diff, "\n")
+	if trimmed {
+		sections[len(sections)-1].text += "\n"
+	}
+
+	sort.SliceStable(sections, func(i, j int) bool {
+		if sections[i].rank != sections[j].rank {
+			return sections[i].rank < sections[j].rank
+		}
+		return sections[i].lines > sections[j].lines
+	})
+
+	var b strings.Builder
+	b.Grow(len(diff) + 1)
+	for _, s := range sections {
+		b.WriteString(s.text)
+	}
+	ordered := b.String()
+	if trimmed {
+		ordered = strings.TrimSuffix(ordered, "\n")
+	}
+	return ordered
+}
@@ -0,0 +1,139 @@
+package git
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassifyFile(t *testing.T) {
+	tests := []struct {
+		path string
+		want FileCategory
+	}{
+		{"main.go", CategorySource},
+		{"internal/git/diff.go", CategorySource},
+		{"src/app.tsx", CategorySource},
+		{"internal/git/diff_test.go", CategoryTest},
+		{"src/app.test.ts", CategoryTest},
+		{"src/app.spec.js", CategoryTest},
+		{"test_parser.py", CategoryTest},
+		{"parser_test.py", CategoryTest},
+		{"tests/fixtures/input.json", CategoryTest},
+		{"internal/prbody/testdata/body.md", CategoryTest},
+		{"__tests__/app.js", CategoryTest},
+		{"README.md", CategoryDocs},
+		{"docs/architecture.go", CategoryDocs},
+		{"LICENSE", CategoryDocs},
+		{"CHANGELOG", CategoryDocs},
+		{"notes.txt", CategoryDocs},
+		{"go.mod", CategoryDependency},
+		{"go.sum", CategoryDependency},
+		{"web/package-lock.json", CategoryDependency},
+		{"requirements-dev.txt", CategoryDependency},
+		{"assets/logo.PNG", CategoryAsset},
+		{"fonts/inter.woff2", CategoryAsset},
+		{"gelf.yml", CategoryConfig},
+		{"gelf.yml.example", CategoryConfig},
+		{"config/settings.json", CategoryConfig},
+		{".github/workflows/ci.yml", CategoryConfig},
+		{".github/scripts/release.sh", CategoryConfig},
+		{"Dockerfile", CategoryConfig},
+		{"Dockerfile.dev", CategoryConfig},
+		{"Makefile", CategoryConfig},
+		{".env.local", CategoryConfig},
+		{"infra/main.tf", CategoryConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := ClassifyFile(tt.path); got != tt.want {
+				t.Errorf("ClassifyFile(%q) = %s, want %s", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+// diffOf returns a diff that adds added lines to each named file.
+func diffOf(files map[string]int, order ...string) string {
+	var b strings.Builder
+	for _, name := range order {
+		b.WriteString("diff --git a/" + name + " b/" + name + "\n")
+		b.WriteString("--- a/" + name + "\n+++ b/" + name + "\n@@ -0,0 +1 @@\n")
+		for range files[name] {
+			b.WriteString("+line\n")
+		}
+	}
+	return b.String()
+}
+
+func TestClassifyDiff(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]int
+		order        []string
+		wantDominant FileCategory
+		wantString   string
+		onlyDocs     bool
+	}{
+		{
+			name:         "most lines win",
+			files:        map[string]int{"a.go": 30, "a_test.go": 5, "b_test.go": 5},
+			order:        []string{"a.go", "a_test.go", "b_test.go"},
+			wantDominant: CategorySource,
+			wantString:   "test: 2, source: 1",
+		},
+		{
+			name:         "docs only",
+			files:        map[string]int{"README.md": 3, "docs/usage.md": 2},
+			order:        []string{"README.md", "docs/usage.md"},
+			wantDominant: CategoryDocs,
+			wantString:   "docs: 2",
+			onlyDocs:     true,
+		},
+		{
+			name:         "ties by name",
+			files:        map[string]int{"go.mod": 2, "main.go": 2},
+			order:        []string{"go.mod", "main.go"},
+			wantDominant: CategoryDependency,
+			wantString:   "dependency: 1, source: 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyDiff(diffOf(tt.files, tt.order...))
+			if got.Dominant != tt.wantDominant {
+				t.Errorf("Dominant = %s, want %s", got.Dominant, tt.wantDominant)
+			}
+			if got.String() != tt.wantString {
+				t.Errorf("String() = %q, want %q", got.String(), tt.wantString)
+			}
+			if got.Only(CategoryDocs) != tt.onlyDocs {
+				t.Errorf("Only(docs) = %v, want %v", got.Only(CategoryDocs), tt.onlyDocs)
+			}
+		})
+	}
+}
+
+func TestOrderDiffBySignificance(t *testing.T) {
+	files := map[string]int{"README.md": 1, "a_test.go": 1, "small.go": 1, "big.go": 3, "gelf.yml": 1}
+	diff := diffOf(files, "README.md", "a_test.go", "small.go", "big.go", "gelf.yml")
+
+	got := OrderDiffBySignificance(diff)
+	var order []string
+	for _, line := range strings.Split(got, "\n") {
+		if name, ok := strings.CutPrefix(line, "diff --git a/"); ok {
+			order = append(order, strings.Fields(name)[0])
+		}
+	}
+	want := "big.go small.go gelf.yml a_test.go README.md"
+	if strings.Join(order, " ") != want {
+		t.Errorf("order = %q, want %q", order, want)
+	}
+	if len(got) != len(diff) {
+		t.Errorf("reordered diff is %d bytes, want %d", len(got), len(diff))
+	}
+
+	combined := "diff --cc file.go\nindex 1,2..3\n"
+	if got := OrderDiffBySignificance(combined); got != combined {
+		t.Errorf("combined diff changed: %q", got)
+	}
+}
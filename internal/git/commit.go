@@ -0,0 +1,103 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CommitPatch is a single commit with its message and patch.
+type CommitPatch struct {
+	SHA     string
+	Subject string
+	Message string
+	Diff    string
+}
+
+// ResolveCommits expands specs into commit SHAs, oldest first. A spec is a
+// single revision or a range (a..b, a...b); every spec must resolve to at
+// least one reachable commit. Ranges leave out merge commits, which have no
+// patch of their own. A spec starting with "-" is rejected, so that it
+// cannot pass an option to git.
+func ResolveCommits(specs []string) ([]string, error) {
+	var shas []string
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		if strings.HasPrefix(spec, "-") {
+			return nil, fmt.Errorf("invalid commit %q", spec)
+		}
+		var resolved []string
+		if strings.Contains(spec, "..") {
+			output, err := runGit("rev-list", "--reverse", "--no-merges", spec, "--")
+			if err != nil {
+				return nil, fmt.Errorf("invalid commit range %q", spec)
+			}
+			resolved = strings.Fields(string(output))
+			if len(resolved) == 0 {
+				return nil, fmt.Errorf("commit range %q contains no commits", spec)
+			}
+		} else {
+			output, err := runGit("rev-parse", "--verify", "--quiet", spec+"^{commit}")
+			if err != nil {
+				return nil, fmt.Errorf("invalid commit %q", spec)
+			}
+			resolved = []string{strings.TrimSpace(string(output))}
+		}
+
+		for _, sha := range resolved {
+			if !seen[sha] {
+				seen[sha] = true
+				shas = append(shas, sha)
+			}
+		}
+	}
+	return shas, nil
+}
+
+// GetCommitPatch returns the message and patch of the commit sha.
+func GetCommitPatch(sha string) (*CommitPatch, error) {
+	message, err := runGit("show", "-s", "--format=%B", sha)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", sha, err)
+	}
+	short, err := runGit("rev-parse", "--short", sha)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", sha, err)
+	}
+	diff, err := runGit("show", "--no-color", "--no-ext-diff", "--format=", "--patch", "-U5", sha)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patch of %s: %w", sha, err)
+	}
+
+	text := strings.TrimSpace(string(message))
+	subject, _, _ := strings.Cut(text, "\n")
+	return &CommitPatch{
+		SHA:     strings.TrimSpace(string(short)),
+		Subject: subject,
+		Message: text,
+		Diff:    strings.TrimSpace(stripANSI(string(diff))),
+	}, nil
+}
+
+// GetPathSubjects returns the subjects of the last limit commits that
+// touched path, newest first, following the file across renames. Merge
+// commits are skipped. A path without history returns no subjects. Rename
+// detection makes --follow slow in long histories, so callers bound it with
+// ctx, which kills git once done.
+func GetPathSubjects(ctx context.Context, path string, limit int) ([]string, error) {
+	output, err := runGitContext(ctx, "log", "--follow", "--no-merges", fmt.Sprintf("--max-count=%d", limit), "--format=%s", "--", path)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to get history of %s: %w", path, err)
+	}
+
+	var subjects []string
+	for _, line := range strings.Split(stripANSI(string(output)), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			subjects = append(subjects, line)
+		}
+	}
+	return subjects, nil
+}
@@ -0,0 +1,107 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestResolveCommits(t *testing.T) {
+	newTestRepo(t)
+	runTestGit(t, "commit", "-q", "--allow-empty", "-m", "base")
+	base := runTestGit(t, "rev-parse", "HEAD")
+	runTestGit(t, "checkout", "-q", "-b", "feature")
+	runTestGit(t, "commit", "-q", "--allow-empty", "-m", "feature")
+	feature := runTestGit(t, "rev-parse", "HEAD")
+	runTestGit(t, "checkout", "-q", "main")
+	runTestGit(t, "commit", "-q", "--allow-empty", "-m", "main")
+	main := runTestGit(t, "rev-parse", "HEAD")
+	runTestGit(t, "merge", "-q", "--no-ff", "-m", "merge feature", "feature")
+	merge := runTestGit(t, "rev-parse", "HEAD")
+
+	tests := []struct {
+		name    string
+		specs   []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "single commit", specs: []string{"HEAD~1"}, want: []string{main}},
+		{name: "single merge commit", specs: []string{"HEAD"}, want: []string{merge}},
+		{name: "duplicates once", specs: []string{"HEAD~1", "HEAD~1"}, want: []string{main}},
+		{name: "option", specs: []string{"--all"}, wantErr: true},
+		{name: "option range", specs: []string{"--output=x..y"}, wantErr: true},
+		{name: "unknown", specs: []string{"no-such-ref"}, wantErr: true},
+		{name: "empty range", specs: []string{"HEAD..HEAD"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveCommits(tt.specs)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ResolveCommits(%q) = %q, want an error", tt.specs, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ResolveCommits(%q) = %q, want %q", tt.specs, got, tt.want)
+			}
+		})
+	}
+
+	got, err := ResolveCommits([]string{base + "..HEAD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(got, merge) || len(got) != 2 || !slices.Contains(got, feature) || !slices.Contains(got, main) {
+		t.Errorf("range = %q, want the feature and main commits without the merge", got)
+	}
+}
+
+func TestGetPathSubjectsFollowsRenames(t *testing.T) {
+	newTestRepo(t)
+	writeTestFile(t, "client.go", "package ai\n\nfunc call() {}\n")
+	runTestGit(t, "add", "client.go")
+	runTestGit(t, "commit", "-q", "-m", "feat(ai): add the client")
+	writeTestFile(t, "client.go", "package ai\n\nfunc call() error { return nil }\n")
+	runTestGit(t, "commit", "-q", "-am", "fix(ai): return call errors")
+	runTestGit(t, "mv", "client.go", "gemini.go")
+	runTestGit(t, "commit", "-q", "-m", "refactor(ai): rename the client to gemini")
+	writeTestFile(t, "other.go", "package ai\n")
+	runTestGit(t, "add", "other.go")
+	runTestGit(t, "commit", "-q", "-m", "chore: unrelated file")
+
+	got, err := GetPathSubjects(context.Background(), "gemini.go", 10)
+	if err != nil {
+		t.Fatalf("GetPathSubjects() error: %v", err)
+	}
+	want := []string{"refactor(ai): rename the client to gemini", "fix(ai): return call errors", "feat(ai): add the client"}
+	if !slices.Equal(got, want) {
+		t.Errorf("GetPathSubjects() = %q, want %q", got, want)
+	}
+
+	got, err = GetPathSubjects(context.Background(), "gemini.go", 2)
+	if err != nil {
+		t.Fatalf("GetPathSubjects() error: %v", err)
+	}
+	if !slices.Equal(got, want[:2]) {
+		t.Errorf("GetPathSubjects() with a limit of 2 = %q, want %q", got, want[:2])
+	}
+
+	got, err = GetPathSubjects(context.Background(), "missing.go", 10)
+	if err != nil || len(got) != 0 {
+		t.Errorf("GetPathSubjects() for a path without history = %q, %v, want nothing", got, err)
+	}
+}
+
+func TestGetPathSubjectsStopsWithContext(t *testing.T) {
+	newTestRepo(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := GetPathSubjects(ctx, "file.txt", 10); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetPathSubjects() error = %v, want context.Canceled", err)
+	}
+}
@@ -1,34 +1,144 @@
 package git
 
 import (
-	"os/exec"
+	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 )
 
-func GetStagedDiff() (string, error) {
-	cmd := exec.Command("git", "--no-pager", "diff", "--staged", "-U5")
-	output, err := cmd.Output()
+// GetStagedDiff returns the staged changes, limited to pathspecs if any are
+// given.
+func GetStagedDiff(pathspecs ...string) (string, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--staged", "-U5"}
+	if len(pathspecs) > 0 {
+		args = append(append(args, "--"), pathspecs...)
+	}
+	output, err := runGit(args...)
 	if err != nil {
 		return "", err
 	}
 
-	return strings.TrimSpace(string(output)), nil
+	return strings.TrimSpace(stripANSI(string(output))), nil
 }
 
 func GetUnstagedDiff() (string, error) {
-	cmd := exec.Command("git", "--no-pager", "diff", "-U5")
-	output, err := cmd.Output()
+	output, err := runGit("diff", "--no-color", "--no-ext-diff", "-U5")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(stripANSI(string(output))), nil
+}
+
+// GetUncommittedDiff returns staged and unstaged changes to tracked files
+// relative to HEAD.
+func GetUncommittedDiff() (string, error) {
+	output, err := runGit("diff", "--no-color", "--no-ext-diff", "HEAD", "-U5")
 	if err != nil {
 		return "", err
 	}
 
-	return strings.TrimSpace(string(output)), nil
+	return strings.TrimSpace(stripANSI(string(output))), nil
 }
 
-func CommitChanges(message string) error {
-	cmd := exec.Command("git", "commit", "-m", message)
-	return cmd.Run()
+// IsWhitespaceOnlyStaged reports whether the staged changes (limited to
+// pathspecs) vanish when whitespace and blank lines are ignored, i.e. the
+// change only reformats code. Callers check that there are staged changes.
+func IsWhitespaceOnlyStaged(pathspecs ...string) bool {
+	args := []string{"diff", "--no-ext-diff", "--staged", "--quiet", "--ignore-all-space", "--ignore-blank-lines"}
+	if len(pathspecs) > 0 {
+		args = append(append(args, "--"), pathspecs...)
+	}
+	_, err := runGit(args...)
+	return err == nil
+}
+
+// UnstagedFiles returns the files matching pathspecs whose working tree
+// differs from the index. git commit -- <pathspec> commits their working
+// tree, so these changes would be committed without being staged.
+func UnstagedFiles(pathspecs ...string) ([]string, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--name-only"}
+	if len(pathspecs) > 0 {
+		args = append(append(args, "--"), pathspecs...)
+	}
+	output, err := runGit(args...)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// GetStagedFiles returns the paths of all staged changes.
+func GetStagedFiles() ([]string, error) {
+	output, err := runGit("diff", "--no-color", "--no-ext-diff", "--staged", "--name-only")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// CommitOptions adjust how CommitChanges commits.
+type CommitOptions struct {
+	// Paths limits the commit to the matching paths (git commit --
+	// <pathspec>); other staged changes stay staged.
+	Paths []string
+	// Trailers such as "Refs: #123" are appended with AddTrailers.
+	Trailers []string
+	// Jujutsu describes the working-copy change with jj describe instead
+	// of creating a git commit.
+	Jujutsu bool
+}
+
+// CommitChanges commits with message.
+func CommitChanges(message string, opts CommitOptions) error {
+	message, err := AddTrailers(message, opts.Trailers)
+	if err != nil {
+		return err
+	}
+	if opts.Jujutsu {
+		return DescribeJujutsu(message)
+	}
+	args := []string{"commit", "-m", message}
+	if len(opts.Paths) > 0 {
+		args = append(append(args, "--"), opts.Paths...)
+	}
+	_, err = runGit(args...)
+	return err
+}
+
+// AddTrailers appends trailers to message with git interpret-trailers, so
+// they accept the same "Key: value" / "key=value" forms as git commit
+// --trailer, join an existing trailer block or get a blank line before them,
+// and follow trailer.* configuration. A trailer already present with the same
+// value is not added again.
+func AddTrailers(message string, trailers []string) (string, error) {
+	if len(trailers) == 0 {
+		return message, nil
+	}
+	args := []string{"interpret-trailers", "--if-exists", "addIfDifferent"}
+	for _, trailer := range trailers {
+		args = append(args, "--trailer", trailer)
+	}
+	output, err := runGitInput(strings.TrimRight(message, "\n")+"\n", args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to add trailers: %w", err)
+	}
+	return strings.TrimRight(string(output), "\n"), nil
 }
 
 type DiffSummary struct {
@@ -76,3 +186,27 @@ func ParseDiffSummary(diff string) DiffSummary {
 
 	return summary
 }
+
+// GetCommitTemplate returns the path and content of the file configured as
+// commit.template. Both are empty when no template is configured; a
+// configured but missing file returns its path and an error.
+func GetCommitTemplate() (string, string, error) {
+	output, err := runGit("config", "--path", "commit.template")
+	path := strings.TrimSpace(string(output))
+	if err != nil || path == "" {
+		// git config exits with 1 when the key is not set.
+		return "", "", nil
+	}
+
+	if !filepath.IsAbs(path) {
+		if root, err := GetRepoRoot(); err == nil {
+			path = filepath.Join(root, path)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return path, "", fmt.Errorf("failed to read commit template %s: %w", path, err)
+	}
+	return path, string(content), nil
+}
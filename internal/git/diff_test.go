@@ -0,0 +1,60 @@
+package git
+
+import "testing"
+
+func TestAddTrailers(t *testing.T) {
+	newTestRepo(t)
+	tests := []struct {
+		name     string
+		message  string
+		trailers []string
+		want     string
+	}{
+		{
+			name:     "subject only",
+			message:  "fix: handle empty diffs",
+			trailers: []string{"Refs: ABC-123"},
+			want:     "fix: handle empty diffs\n\nRefs: ABC-123",
+		},
+		{
+			name:     "separated from the body",
+			message:  "fix: handle empty diffs\n\nThe diff can be empty after filtering.",
+			trailers: []string{"Refs: ABC-123", "Branch: fix/ABC-123"},
+			want:     "fix: handle empty diffs\n\nThe diff can be empty after filtering.\n\nRefs: ABC-123\nBranch: fix/ABC-123",
+		},
+		{
+			name:     "order kept",
+			message:  "fix: handle empty diffs",
+			trailers: []string{"Reviewed-by: B <b@example.com>", "Acked-by: A <a@example.com>"},
+			want:     "fix: handle empty diffs\n\nReviewed-by: B <b@example.com>\nAcked-by: A <a@example.com>",
+		},
+		{
+			name:     "joins an existing block",
+			message:  "fix: handle empty diffs\n\nBody.\n\nSigned-off-by: A <a@example.com>",
+			trailers: []string{"Refs: ABC-123"},
+			want:     "fix: handle empty diffs\n\nBody.\n\nSigned-off-by: A <a@example.com>\nRefs: ABC-123",
+		},
+		{
+			name:     "same trailer once",
+			message:  "fix: handle empty diffs\n\nRefs: ABC-123",
+			trailers: []string{"Refs: ABC-123", "Refs: ABC-124"},
+			want:     "fix: handle empty diffs\n\nRefs: ABC-123\nRefs: ABC-124",
+		},
+		{
+			name:    "none",
+			message: "fix: handle empty diffs\n",
+			want:    "fix: handle empty diffs\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AddTrailers(tt.message, tt.trailers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("AddTrailers(%q, %q) = %q, want %q", tt.message, tt.trailers, got, tt.want)
+			}
+		})
+	}
+}
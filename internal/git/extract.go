@@ -0,0 +1,99 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/timing"
+)
+
+var slugInvalidRegex = regexp.MustCompile(`[^a-z0-9/]+`)
+
+// Slugify turns text into a branch-name friendly slug such as "fix/login-timeout".
+func Slugify(text string) string {
+	slug := strings.ToLower(strings.TrimSpace(text))
+	slug = slugInvalidRegex.ReplaceAllString(slug, "-")
+	parts := strings.Split(slug, "/")
+	cleaned := parts[:0]
+	for _, part := range parts {
+		part = strings.Trim(part, "-")
+		if part != "" {
+			cleaned = append(cleaned, part)
+		}
+	}
+	slug = strings.Join(cleaned, "/")
+	if len(slug) > 60 {
+		slug = strings.TrimRight(slug[:60], "-/")
+	}
+	return slug
+}
+
+// IsWorktreeClean reports whether there are no staged, unstaged or untracked
+// changes.
+func IsWorktreeClean() (bool, error) {
+	output, err := runGit("status", "--porcelain")
+	if err != nil {
+		return false, fmt.Errorf("failed to get worktree status: %w", err)
+	}
+	return strings.TrimSpace(string(output)) == "", nil
+}
+
+// BranchExists reports whether a local branch named name exists.
+func BranchExists(name string) bool {
+	_, err := runGit("rev-parse", "--verify", "--quiet", "refs/heads/"+name)
+	return err == nil
+}
+
+// ValidateBranchName checks name with git check-ref-format.
+func ValidateBranchName(name string) error {
+	if _, err := runGit("check-ref-format", "--branch", name); err != nil {
+		return fmt.Errorf("invalid branch name %q", name)
+	}
+	return nil
+}
+
+// ExtractCommands returns the git commands that move the commits on the
+// current branch that are not in baseRef to a new branch. On a branch, the
+// current branch is reset to baseRef; on a detached HEAD the new branch is
+// simply created at HEAD.
+func ExtractCommands(branch, baseRef string, detached bool) [][]string {
+	if detached {
+		return [][]string{{"git", "switch", "-c", branch}}
+	}
+	return [][]string{
+		{"git", "branch", branch},
+		{"git", "reset", "--hard", baseRef},
+		{"git", "switch", branch},
+	}
+}
+
+// ExtractCommits runs ExtractCommands. It refuses to run when the worktree
+// has changes, since git reset --hard would discard them, or when branch
+// already exists. The new branch is created first, so the commits are never
+// lost even if a later step fails.
+func ExtractCommits(branch, baseRef string, detached bool) error {
+	if err := ValidateBranchName(branch); err != nil {
+		return err
+	}
+	if BranchExists(branch) {
+		return fmt.Errorf("branch %s already exists", branch)
+	}
+
+	clean, err := IsWorktreeClean()
+	if err != nil {
+		return err
+	}
+	if !clean {
+		return fmt.Errorf("worktree has uncommitted changes; commit or stash them first")
+	}
+
+	for _, args := range ExtractCommands(branch, baseRef, detached) {
+		cmd := exec.Command(args[0], args[1:]...)
+		if output, err := timing.CombinedOutput(cmd); err != nil {
+			return fmt.Errorf("%s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
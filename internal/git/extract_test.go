@@ -0,0 +1,68 @@
+package git
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"type and description", "feat/add-login-timeout", "feat/add-login-timeout"},
+		{"subject", "Fix login timeout", "fix-login-timeout"},
+		{"nested slashes", "feat/user/#123 fix", "feat/user/123-fix"},
+		{"empty segments", "feat//add///search/", "feat/add/search"},
+		{"punctuation", "fix: handle `nil` user (again)!", "fix-handle-nil-user-again"},
+		{"non-ascii", "fïx ログイン timeout", "f-x-timeout"},
+		{"only non-ascii", "ログイン", ""},
+		{"surrounding space", "  feat/search  \n", "feat/search"},
+		{"long", "feat/" + strings.Repeat("a", 70), "feat/" + strings.Repeat("a", 55)},
+		{"cut before a separator", "feat/" + strings.Repeat("a", 54) + "-xyz", "feat/" + strings.Repeat("a", 54)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Slugify(tt.text)
+			if got != tt.want {
+				t.Errorf("Slugify(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			if got != "" {
+				if err := ValidateBranchName(got); err != nil {
+					t.Errorf("Slugify(%q) = %q, which git rejects: %v", tt.text, got, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateBranchName(t *testing.T) {
+	tests := []struct {
+		name   string
+		branch string
+		valid  bool
+	}{
+		{"simple", "feature", true},
+		{"slashes", "feat/user/search", true},
+		{"hash", "feat/#123-fix", true},
+		{"non-ascii", "feat/fïx-ログイン", true},
+		{"space", "feat/add search", false},
+		{"double dot", "feat..search", false},
+		{"leading dash", "-feature", false},
+		{"trailing slash", "feat/", false},
+		{"lock suffix", "feat/search.lock", false},
+		{"at brace", "feat@{1}", false},
+		{"tilde", "feat~1", false},
+		{"colon", "feat:search", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBranchName(tt.branch)
+			if (err == nil) != tt.valid {
+				t.Errorf("ValidateBranchName(%q) = %v, want valid: %v", tt.branch, err, tt.valid)
+			}
+		})
+	}
+}
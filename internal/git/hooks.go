@@ -0,0 +1,176 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	HookBlockStart = "# gelf start"
+	HookBlockEnd   = "# gelf end"
+)
+
+// ErrHookIncompatible is returned by InstallHookBlock when an existing hook
+// would not run a shell block appended to it.
+var ErrHookIncompatible = errors.New("existing hook cannot run the gelf block")
+
+var (
+	// shellInterpreters are the interpreters that run the POSIX sh block.
+	shellInterpreters = map[string]bool{"sh": true, "bash": true, "dash": true, "ksh": true, "zsh": true}
+	// hookExitRegex matches a line that ends the script, after which an
+	// appended block never runs.
+	hookExitRegex = regexp.MustCompile(`^(?:exit|exec)(?:\s|;|$)`)
+)
+
+// GetHooksDir returns the directory git runs hooks from, honoring
+// core.hooksPath. Husky-managed directories (.husky/_) are regenerated by
+// husky, so the user-editable .husky directory is returned instead.
+func GetHooksDir() (string, error) {
+	paths, err := GetRepoPaths()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine hooks directory: %w", err)
+	}
+
+	dir := paths.HooksDir
+	if filepath.Base(dir) == "_" && filepath.Base(filepath.Dir(dir)) == ".husky" {
+		return filepath.Dir(dir), nil
+	}
+
+	return dir, nil
+}
+
+// InstallHookBlock adds block between the gelf markers to the hook script at
+// path. An existing script is kept, along with its mode, and a previously
+// installed block is replaced, so repeated installs are idempotent. A script
+// that would not run the block is left alone and ErrHookIncompatible is
+// returned.
+func InstallHookBlock(path, block string) error {
+	marked := HookBlockStart + "\n" + strings.TrimRight(block, "\n") + "\n" + HookBlockEnd + "\n"
+
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read hook %s: %w", path, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("failed to create hooks directory: %w", err)
+		}
+		return os.WriteFile(path, []byte("#!/bin/sh\n\n"+marked), 0o755)
+	}
+
+	content := string(existing)
+	if stripped, found := removeHookBlock(content); found {
+		content = stripped
+	}
+	if err := checkHookScript(content); err != nil {
+		return fmt.Errorf("%w: %s %v", ErrHookIncompatible, path, err)
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if strings.TrimSpace(content) != "" {
+		content += "\n"
+	}
+	content += marked
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write hook %s: %w", path, err)
+	}
+	return nil
+}
+
+// checkHookScript reports why the block cannot be appended to the hook
+// script content: it is run by an interpreter other than a POSIX shell, or
+// it ends with exit or exec. A script without a shebang is run with sh.
+func checkHookScript(content string) error {
+	lines := strings.Split(content, "\n")
+	if shebang, ok := strings.CutPrefix(lines[0], "#!"); ok {
+		fields := strings.Fields(shebang)
+		interpreter := ""
+		if len(fields) > 0 {
+			interpreter = filepath.Base(fields[0])
+		}
+		if interpreter == "env" {
+			interpreter = ""
+			for _, field := range fields[1:] {
+				if !strings.HasPrefix(field, "-") {
+					interpreter = field
+					break
+				}
+			}
+		}
+		if !shellInterpreters[interpreter] {
+			return fmt.Errorf("is run by %q, not sh", strings.TrimSpace(shebang))
+		}
+	}
+
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if hookExitRegex.MatchString(line) {
+			return fmt.Errorf("ends with %q, so nothing after it runs", line)
+		}
+		break
+	}
+	return nil
+}
+
+// RemoveHookBlock removes the gelf block from the hook script at path. The
+// script is deleted when nothing but a shebang remains. It reports whether a
+// block was found.
+func RemoveHookBlock(path string) (bool, error) {
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read hook %s: %w", path, err)
+	}
+
+	content, found := removeHookBlock(string(existing))
+	if !found {
+		return false, nil
+	}
+
+	remaining := strings.TrimSpace(content)
+	if remaining == "" || (strings.HasPrefix(remaining, "#!") && !strings.Contains(remaining, "\n")) {
+		return true, os.Remove(path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return true, err
+	}
+	return true, os.WriteFile(path, []byte(strings.TrimRight(content, "\n")+"\n"), info.Mode().Perm())
+}
+
+func removeHookBlock(content string) (string, bool) {
+	start := strings.Index(content, HookBlockStart)
+	if start == -1 {
+		return content, false
+	}
+	endOffset := strings.Index(content[start:], HookBlockEnd)
+	if endOffset == -1 {
+		return content, false
+	}
+	end := start + endOffset + len(HookBlockEnd)
+	if end < len(content) && content[end] == '\n' {
+		end++
+	}
+
+	before := strings.TrimRight(content[:start], "\n")
+	if before != "" {
+		before += "\n"
+	}
+	return before + content[end:], true
+}
@@ -0,0 +1,96 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstallHookBlockKeepsScriptAndMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prepare-commit-msg")
+	if err := os.WriteFile(path, []byte("#!/usr/bin/env bash\necho lint\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if err := InstallHookBlock(path, "gelf hook run"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "#!/usr/bin/env bash\necho lint\n\n" + HookBlockStart + "\ngelf hook run\n" + HookBlockEnd + "\n"
+	if string(data) != want {
+		t.Errorf("hook = %q, want %q", data, want)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o700 {
+		t.Errorf("mode = %o, want 700", info.Mode().Perm())
+	}
+}
+
+func TestInstallHookBlockRefusesIncompatibleScripts(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"node", "#!/usr/bin/env node\nconsole.log('hi')\n"},
+		{"python", "#!/usr/bin/python3\nprint('hi')\n"},
+		{"exec", "#!/bin/sh\nexec lefthook run prepare-commit-msg \"$@\"\n"},
+		{"exit", "#!/bin/sh\nnpx lint-staged\nexit 0\n\n# done\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "prepare-commit-msg")
+			if err := os.WriteFile(path, []byte(tt.content), 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			err := InstallHookBlock(path, "gelf hook run")
+			if !errors.Is(err, ErrHookIncompatible) {
+				t.Fatalf("InstallHookBlock() error = %v, want ErrHookIncompatible", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.content {
+				t.Errorf("hook changed to %q", data)
+			}
+		})
+	}
+}
+
+func TestInstallHookBlockAcceptsShellScripts(t *testing.T) {
+	tests := []string{
+		"",
+		"npx lint-staged\n",
+		"#!/bin/sh\n. \"$(dirname -- \"$0\")/_/husky.sh\"\n",
+		"#!/usr/bin/env -S zsh -e\nexit_code=0\n",
+	}
+	for _, content := range tests {
+		path := filepath.Join(t.TempDir(), "prepare-commit-msg")
+		if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := InstallHookBlock(path, "gelf hook run"); err != nil {
+			t.Errorf("InstallHookBlock(%q) error = %v", content, err)
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(string(data), HookBlockEnd+"\n") {
+			t.Errorf("hook for %q = %q, want the block appended", content, data)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/timing"
+)
+
+// ErrJujutsuNotColocated is returned by CheckJujutsu for a jj repository
+// whose git repository is not checked out next to it.
+var ErrJujutsuNotColocated = errors.New("this jj repository is not colocated with git; gelf only works with colocated repositories (jj git init --colocate)")
+
+// runJJ runs jj with args and returns its standard output. Colors and the
+// pager are turned off so the output can be parsed.
+func runJJ(args ...string) ([]byte, error) {
+	cmd := exec.Command("jj", append([]string{"--color=never", "--no-pager"}, args...)...)
+	output, err := timing.Output(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("jj %s: %w", args[0], gitFailure(err))
+	}
+	return output, nil
+}
+
+// IsJujutsu reports whether the current git repository is colocated with a
+// jj repository: its worktree root also holds a .jj directory.
+func IsJujutsu() bool {
+	root, err := GetRepoRoot()
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(root, ".jj"))
+	return err == nil && info.IsDir()
+}
+
+// CheckJujutsu returns ErrJujutsuNotColocated when the working directory is
+// inside a jj repository that git does not see at the same root, where git
+// commands would fail or act on an unrelated repository further up. It
+// returns nil outside jj repositories.
+func CheckJujutsu() error {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	for {
+		if info, err := os.Stat(filepath.Join(dir, ".jj")); err == nil && info.IsDir() {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+
+	root, err := GetRepoRoot()
+	if err != nil || !sameDir(root, dir) {
+		return ErrJujutsuNotColocated
+	}
+	return nil
+}
+
+// sameDir reports whether a and b are the same directory, following
+// symbolic links.
+func sameDir(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
+
+// GetJujutsuBookmark returns the bookmark closest to the working-copy change
+// among its ancestors, which names the branch in a jj repository, where HEAD
+// is always detached. It returns "" when no ancestor has a bookmark.
+func GetJujutsuBookmark() (string, error) {
+	output, err := runJJ("log", "--ignore-working-copy", "--no-graph",
+		"-r", "heads(::@ & bookmarks())",
+		"-T", `local_bookmarks.map(|b| b.name()).join("\n") ++ "\n"`)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the current bookmark: %w", err)
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line, nil
+		}
+	}
+	return "", nil
+}
+
+// GetJujutsuDiff returns the changes of the working-copy change (@) as a
+// git diff.
+func GetJujutsuDiff() (string, error) {
+	output, err := runJJ("diff", "--git", "--context", "5", "-r", "@")
+	if err != nil {
+		return "", fmt.Errorf("failed to get the working-copy change: %w", err)
+	}
+	return strings.TrimSpace(stripANSI(string(output))), nil
+}
+
+// DescribeJujutsu sets message as the description of the working-copy
+// change.
+func DescribeJujutsu(message string) error {
+	if _, err := runJJ("describe", "-r", "@", "-m", message); err != nil {
+		return fmt.Errorf("failed to describe the working-copy change: %w", err)
+	}
+	return nil
+}
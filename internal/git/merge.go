@@ -0,0 +1,122 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MergeState describes a merge in progress (MERGE_HEAD exists).
+type MergeState struct {
+	// Message is git's prepared MERGE_MSG without comment lines.
+	Message string
+	// Commits lists the merged commits that are not on HEAD as "<sha>
+	// <subject>", oldest first.
+	Commits string
+	// Conflicts are the paths that had conflicts.
+	Conflicts []string
+	// Resolution is how Conflicts were resolved: the staged diff against
+	// the automatic merge git recorded in AUTO_MERGE, conflict markers
+	// included, or with an older git a combined diff against both parents.
+	Resolution string
+}
+
+// GetMergeState returns the merge in progress, or nil when there is none.
+func GetMergeState() (*MergeState, error) {
+	if _, err := runGit("rev-parse", "--quiet", "--verify", "MERGE_HEAD"); err != nil {
+		return nil, nil
+	}
+
+	gitDir, err := GetGitDir()
+	if err != nil {
+		return nil, err
+	}
+	state := &MergeState{}
+
+	// git lists conflicted paths in MERGE_MSG under "# Conflicts:", which
+	// survives resolving and staging them.
+	seen := map[string]bool{}
+	addConflict := func(path string) {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			state.Conflicts = append(state.Conflicts, path)
+		}
+	}
+	if content, err := os.ReadFile(filepath.Join(gitDir, "MERGE_MSG")); err == nil {
+		var lines []string
+		inConflicts := false
+		for _, line := range strings.Split(string(content), "\n") {
+			if !strings.HasPrefix(line, "#") {
+				inConflicts = false
+				lines = append(lines, line)
+				continue
+			}
+			if strings.TrimSpace(strings.TrimPrefix(line, "#")) == "Conflicts:" {
+				inConflicts = true
+				continue
+			}
+			if inConflicts {
+				addConflict(strings.TrimSpace(strings.TrimPrefix(line, "#")))
+			}
+		}
+		state.Message = strings.TrimSpace(strings.Join(lines, "\n"))
+	}
+
+	// Paths that are still unmerged.
+	status, err := runGit("status", "--porcelain=v1", "--untracked-files=no")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		if len(line) < 4 {
+			continue
+		}
+		switch line[:2] {
+		case "DD", "AU", "UD", "UA", "DU", "AA", "UU":
+			addConflict(line[3:])
+		}
+	}
+
+	commits, err := runGit("log", "--reverse", "--format=%h %s", "HEAD..MERGE_HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merged commits: %w", err)
+	}
+	state.Commits = strings.TrimSpace(stripANSI(string(commits)))
+
+	if len(state.Conflicts) > 0 {
+		resolution, err := conflictResolution(state.Conflicts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get conflict resolution: %w", err)
+		}
+		state.Resolution = strings.TrimSpace(stripANSI(string(resolution)))
+	}
+
+	return state, nil
+}
+
+// conflictResolution returns the diff of how paths were resolved. A diff
+// against HEAD would also show everything the merged branch changed in
+// them, so git 2.42 and later diff the index against AUTO_MERGE, the
+// automatic merge with conflict markers. Older versions get a combined diff
+// of a throwaway commit of the index against HEAD and MERGE_HEAD, or of the
+// work tree while paths are still unmerged, which only shows lines that
+// differ from both sides.
+func conflictResolution(paths []string) ([]byte, error) {
+	pathArgs := append([]string{"--"}, paths...)
+	if _, err := runGit("rev-parse", "--quiet", "--verify", "AUTO_MERGE"); err == nil {
+		return runGit(append([]string{"diff", "--no-color", "--no-ext-diff", "--staged", "-U5", "AUTO_MERGE"}, pathArgs...)...)
+	}
+
+	tree, err := runGit("write-tree")
+	if err != nil {
+		// Unmerged paths remain, so the index cannot be written yet.
+		return runGit(append([]string{"diff", "--no-color", "--no-ext-diff", "--cc", "-U5"}, pathArgs...)...)
+	}
+	commit, err := runGit("-c", "user.name=gelf", "-c", "user.email=gelf@localhost",
+		"commit-tree", strings.TrimSpace(string(tree)), "-p", "HEAD", "-p", "MERGE_HEAD", "-m", "resolution")
+	if err != nil {
+		return nil, err
+	}
+	return runGit(append([]string{"show", "--no-color", "--no-ext-diff", "--cc", "-U5", "--format=", strings.TrimSpace(string(commit))}, pathArgs...)...)
+}
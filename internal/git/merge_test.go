@@ -0,0 +1,90 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// newTestRepo makes an empty repository with one commit in a temporary
+// directory and changes into it.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "gelf")
+	t.Setenv("GIT_AUTHOR_EMAIL", "gelf@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "gelf")
+	t.Setenv("GIT_COMMITTER_EMAIL", "gelf@example.com")
+	ForgetRepoPaths()
+	t.Cleanup(ForgetRepoPaths)
+	runTestGit(t, "init", "-q", "-b", "main")
+	runTestGit(t, "commit", "-q", "--allow-empty", "-m", "initial")
+	return dir
+}
+
+// runTestGit runs git with args and returns its trimmed output.
+func runTestGit(t *testing.T, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetMergeStateResolution(t *testing.T) {
+	newTestRepo(t)
+	lines := make([]string, 30)
+	for i := range lines {
+		lines[i] = "line " + string(rune('a'+i%26))
+	}
+	base := strings.Join(lines, "\n") + "\n"
+	writeTestFile(t, "file.txt", base)
+	runTestGit(t, "add", "file.txt")
+	runTestGit(t, "commit", "-q", "-m", "base")
+
+	runTestGit(t, "checkout", "-q", "-b", "feature")
+	feature := strings.Replace(base, "line b\n", "line b from feature\n", 1)
+	feature = strings.Replace(feature, "line z\n", "line z far away on feature\n", 1)
+	writeTestFile(t, "file.txt", feature)
+	runTestGit(t, "commit", "-q", "-am", "feature")
+
+	runTestGit(t, "checkout", "-q", "main")
+	writeTestFile(t, "file.txt", strings.Replace(base, "line b\n", "line b from main\n", 1))
+	runTestGit(t, "commit", "-q", "-am", "main")
+
+	if err := exec.Command("git", "merge", "-q", "feature").Run(); err == nil {
+		t.Fatal("merge did not conflict")
+	}
+	resolved := strings.Replace(feature, "line b from feature\n", "line b from both\n", 1)
+	writeTestFile(t, "file.txt", resolved)
+	runTestGit(t, "add", "file.txt")
+
+	state, err := GetMergeState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state == nil {
+		t.Fatal("GetMergeState() = nil during a merge")
+	}
+	if len(state.Conflicts) != 1 || state.Conflicts[0] != "file.txt" {
+		t.Errorf("Conflicts = %q, want file.txt", state.Conflicts)
+	}
+	if !strings.Contains(state.Resolution, "line b from both") {
+		t.Errorf("Resolution does not show the resolved line:\n%s", state.Resolution)
+	}
+	if strings.Contains(state.Resolution, "far away") {
+		t.Errorf("Resolution shows a change the merge took from feature without a conflict:\n%s", state.Resolution)
+	}
+}
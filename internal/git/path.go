@@ -0,0 +1,34 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ListFiles returns the tracked files under path, relative to the current
+// directory.
+func ListFiles(path string) ([]string, error) {
+	output, err := runGit("ls-files", "--", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	var files []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// GetPathLog returns the subjects of the most recent commits touching path,
+// newest first.
+func GetPathLog(path string, limit int) (string, error) {
+	output, err := runGit("log", fmt.Sprintf("-n%d", limit), "--format=%h %s", "--", path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
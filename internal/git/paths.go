@@ -0,0 +1,83 @@
+package git
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// RepoPaths are the directories of the current repository that gelf reads
+// and writes. git resolves them, so they are right from a subdirectory, from
+// a linked worktree, and in checkouts configured with GIT_DIR and
+// GIT_WORK_TREE, where the worktree need not contain the working directory.
+type RepoPaths struct {
+	// Root is the top of the worktree, which holds gelf.yml, the pull
+	// request templates and the repository instructions.
+	Root string
+	// GitDir is the git directory of the worktree, which holds its
+	// in-progress operations.
+	GitDir string
+	// CommonDir is the git directory shared by all worktrees of the
+	// repository, which holds the gelf lock; it is GitDir except in a
+	// linked worktree.
+	CommonDir string
+	// HooksDir is the directory git runs hooks from, honoring
+	// core.hooksPath.
+	HooksDir string
+}
+
+var (
+	repoPathsMu sync.Mutex
+	repoPaths   *RepoPaths
+)
+
+// GetRepoPaths returns the paths of the current repository, all absolute.
+// They are resolved with a single git call on first use and kept for the
+// rest of the process; a failure is not kept, so a later call retries.
+// Outside a worktree, e.g. in a bare repository, it fails with git's
+// message.
+func GetRepoPaths() (*RepoPaths, error) {
+	repoPathsMu.Lock()
+	defer repoPathsMu.Unlock()
+	if repoPaths != nil {
+		return repoPaths, nil
+	}
+
+	output, err := runGit("rev-parse", "--show-toplevel", "--absolute-git-dir", "--git-common-dir", "--git-path", "hooks")
+	if err != nil {
+		return nil, gitFailure(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 4 {
+		return nil, fmt.Errorf("unexpected output from git rev-parse: %q", strings.TrimSpace(string(output)))
+	}
+
+	// --git-common-dir and --git-path are relative to the working
+	// directory unless GIT_DIR is absolute.
+	resolved := make([]string, len(lines))
+	for i, line := range lines {
+		if line == "" {
+			return nil, fmt.Errorf("repository path is empty")
+		}
+		if resolved[i], err = filepath.Abs(line); err != nil {
+			return nil, err
+		}
+	}
+
+	repoPaths = &RepoPaths{
+		Root:      resolved[0],
+		GitDir:    resolved[1],
+		CommonDir: resolved[2],
+		HooksDir:  resolved[3],
+	}
+	return repoPaths, nil
+}
+
+// ForgetRepoPaths drops the paths GetRepoPaths keeps, so that the next call
+// resolves them again, e.g. after the working directory changed.
+func ForgetRepoPaths() {
+	repoPathsMu.Lock()
+	defer repoPathsMu.Unlock()
+	repoPaths = nil
+}
@@ -0,0 +1,95 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// checkRepoPaths compares GetRepoPaths, resolved afresh, with want.
+func checkRepoPaths(t *testing.T, want RepoPaths) {
+	t.Helper()
+	ForgetRepoPaths()
+	got, err := GetRepoPaths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != want {
+		t.Errorf("GetRepoPaths() = %+v, want %+v", *got, want)
+	}
+}
+
+func TestGetRepoPathsFromNestedDirectory(t *testing.T) {
+	root, err := filepath.EvalSymlinks(newTestRepo(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(nested)
+
+	gitDir := filepath.Join(root, ".git")
+	checkRepoPaths(t, RepoPaths{Root: root, GitDir: gitDir, CommonDir: gitDir, HooksDir: filepath.Join(gitDir, "hooks")})
+
+	// A relative core.hooksPath is relative to the top of the worktree,
+	// where git runs hooks.
+	runTestGit(t, "config", "core.hooksPath", "tools/hooks")
+	checkRepoPaths(t, RepoPaths{Root: root, GitDir: gitDir, CommonDir: gitDir, HooksDir: filepath.Join(root, "tools", "hooks")})
+}
+
+func TestGetRepoPathsWithGitDirAndWorkTree(t *testing.T) {
+	root, err := filepath.EvalSymlinks(newTestRepo(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	workTree, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	elsewhere, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	gitDir := filepath.Join(root, ".git")
+
+	// A relative GIT_DIR is resolved against the working directory, which
+	// here is outside the work tree.
+	t.Chdir(root)
+	t.Setenv("GIT_DIR", ".git")
+	t.Setenv("GIT_WORK_TREE", workTree)
+	checkRepoPaths(t, RepoPaths{Root: workTree, GitDir: gitDir, CommonDir: gitDir, HooksDir: filepath.Join(gitDir, "hooks")})
+
+	t.Chdir(elsewhere)
+	t.Setenv("GIT_DIR", gitDir)
+	checkRepoPaths(t, RepoPaths{Root: workTree, GitDir: gitDir, CommonDir: gitDir, HooksDir: filepath.Join(gitDir, "hooks")})
+}
+
+func TestGetRepoPathsInLinkedWorktree(t *testing.T) {
+	root, err := filepath.EvalSymlinks(newTestRepo(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	linked := filepath.Join(root, "linked")
+	runTestGit(t, "worktree", "add", "-q", "-b", "linked", linked)
+	t.Chdir(linked)
+
+	commonDir := filepath.Join(root, ".git")
+	checkRepoPaths(t, RepoPaths{
+		Root:      linked,
+		GitDir:    filepath.Join(commonDir, "worktrees", "linked"),
+		CommonDir: commonDir,
+		HooksDir:  filepath.Join(commonDir, "hooks"),
+	})
+}
+
+func TestGetRepoPathsOutsideRepository(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(t.TempDir()))
+	ForgetRepoPaths()
+	t.Cleanup(ForgetRepoPaths)
+	if paths, err := GetRepoPaths(); err == nil {
+		t.Errorf("GetRepoPaths() = %+v outside a repository, want an error", *paths)
+	}
+}
@@ -0,0 +1,215 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/timing"
+)
+
+// Operations that can be stopped midway, as returned by
+// GetOperationInProgress.
+const (
+	OperationMerge      = "merge"
+	OperationRebase     = "rebase"
+	OperationAm         = "am"
+	OperationCherryPick = "cherry-pick"
+	OperationRevert     = "revert"
+)
+
+// GetOperationInProgress returns the git operation that stopped midway in
+// the current worktree, e.g. on a conflict, or "" when there is none.
+func GetOperationInProgress() (string, error) {
+	gitDir, err := GetGitDir()
+	if err != nil {
+		return "", err
+	}
+
+	for _, marker := range []struct{ path, operation string }{
+		{"rebase-merge", OperationRebase},
+		{filepath.Join("rebase-apply", "applying"), OperationAm},
+		{"rebase-apply", OperationRebase},
+		{"CHERRY_PICK_HEAD", OperationCherryPick},
+		{"REVERT_HEAD", OperationRevert},
+		{"MERGE_HEAD", OperationMerge},
+	} {
+		if _, err := os.Stat(filepath.Join(gitDir, marker.path)); err == nil {
+			return marker.operation, nil
+		}
+	}
+	return "", nil
+}
+
+// GetUnmergedFiles returns the paths with unresolved conflicts.
+func GetUnmergedFiles() ([]string, error) {
+	output, err := runGit("diff", "--name-only", "--diff-filter=U", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unmerged files: %w", err)
+	}
+
+	var files []string
+	seen := map[string]bool{}
+	for _, file := range strings.Split(string(output), "\x00") {
+		if file != "" && !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// GetConfig returns the value of the git config key, or "" when it is not
+// set.
+func GetConfig(key string) string {
+	output, err := runGit("config", "--get", key)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// CheckIdentity returns an error when git cannot determine the author or
+// committer of a new commit, the way git commit would refuse at the end.
+func CheckIdentity() error {
+	for _, variable := range []string{"GIT_AUTHOR_IDENT", "GIT_COMMITTER_IDENT"} {
+		if _, err := runGit("var", variable); err != nil {
+			return gitFailure(err)
+		}
+	}
+	return nil
+}
+
+// CheckSigning returns an error when commit.gpgsign is set but git would
+// not be able to sign: the signing program is missing or has no key to sign
+// with. It does not sign anything, so it never asks for a passphrase.
+func CheckSigning() error {
+	if output, err := runGit("config", "--type=bool", "--get", "commit.gpgsign"); err != nil || strings.TrimSpace(string(output)) != "true" {
+		return nil
+	}
+
+	format := GetConfig("gpg.format")
+	if format == "" {
+		format = "openpgp"
+	}
+	program := GetConfig("gpg." + format + ".program")
+	if program == "" && format == "openpgp" {
+		program = GetConfig("gpg.program")
+	}
+	if program == "" {
+		program = map[string]string{"openpgp": "gpg", "x509": "gpgsm", "ssh": "ssh-keygen"}[format]
+	}
+	if program == "" {
+		return fmt.Errorf("unknown gpg.format %q", format)
+	}
+	if _, err := exec.LookPath(program); err != nil {
+		return fmt.Errorf("commit.gpgsign is set but the %s signing program %q is not installed", format, program)
+	}
+
+	key := GetConfig("user.signingkey")
+	switch format {
+	case "openpgp":
+		// Without user.signingkey gpg picks a key for the committer email.
+		if key == "" {
+			key = GetConfig("user.email")
+		}
+		args := []string{"--batch", "--list-secret-keys"}
+		if key != "" {
+			args = append(args, key)
+		}
+		if output, err := timing.Output(exec.Command(program, args...)); err != nil || strings.TrimSpace(string(output)) == "" {
+			if key == "" {
+				return fmt.Errorf("commit.gpgsign is set but %s has no secret key", program)
+			}
+			return fmt.Errorf("commit.gpgsign is set but %s has no secret key for %s", program, key)
+		}
+	case "ssh":
+		if key == "" {
+			if GetConfig("gpg.ssh.defaultKeyCommand") != "" {
+				return nil
+			}
+			return fmt.Errorf("commit.gpgsign is set with gpg.format=ssh but user.signingkey is not set")
+		}
+		// The key is a path unless it is given literally.
+		if strings.HasPrefix(key, "key::") || strings.HasPrefix(key, "ssh-") || strings.HasPrefix(key, "ecdsa-") || strings.HasPrefix(key, "sk-") {
+			return nil
+		}
+		if strings.HasPrefix(key, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				key = filepath.Join(home, key[2:])
+			}
+		}
+		if _, err := os.Stat(key); err != nil {
+			return fmt.Errorf("commit.gpgsign is set but the SSH signing key %s does not exist", key)
+		}
+	}
+	return nil
+}
+
+// GetMissingHooksPath returns core.hooksPath when it is set to a directory
+// that does not exist, in which case git silently runs no hooks; otherwise
+// it returns "".
+func GetMissingHooksPath() string {
+	output, err := runGit("config", "--path", "--get", "core.hooksPath")
+	if err != nil {
+		return ""
+	}
+	path := strings.TrimSpace(string(output))
+	if path == "" {
+		return ""
+	}
+	if !filepath.IsAbs(path) {
+		// A relative core.hooksPath is relative to where hooks run: the
+		// worktree root.
+		root, err := GetRepoRoot()
+		if err != nil {
+			return ""
+		}
+		path = filepath.Join(root, path)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return ""
+	}
+	return path
+}
+
+// CheckRemote returns an error when remote cannot be reached within
+// timeout, e.g. because the network is down or the credentials are
+// missing. It never prompts for credentials.
+func CheckRemote(remote string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", append(append([]string{}, globalArgs...), "ls-remote", "--heads", remote, "HEAD")...)
+	cmd.Env = append(os.Environ(), "GIT_PAGER=cat", "GIT_TERMINAL_PROMPT=0")
+	// Make the default ssh fail instead of asking for a passphrase or to
+	// confirm a host key. A custom ssh command is left alone.
+	if os.Getenv("GIT_SSH_COMMAND") == "" && os.Getenv("GIT_SSH") == "" && GetConfig("core.sshCommand") == "" {
+		cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+	}
+	if _, err := timing.Output(cmd); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("no answer from %s within %s", remote, timeout)
+		}
+		return gitFailure(err)
+	}
+	return nil
+}
+
+// gitFailure turns err from a git call into an error with the first line
+// git printed on stderr, which says what went wrong.
+func gitFailure(err error) error {
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		for _, line := range strings.Split(string(exitErr.Stderr), "\n") {
+			line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "fatal:"))
+			if line != "" && !strings.HasPrefix(line, "***") {
+				return fmt.Errorf("%s", line)
+			}
+		}
+	}
+	return err
+}
@@ -26,7 +26,7 @@ func GetPushStatus(branch string) (PushStatus, error) {
 		status.HasUpstream = true
 		status.UpstreamRef = upstreamRef
 		status.RemoteRef = upstreamRef
-		status.RemoteName = remoteNameFromRef(upstreamRef)
+		status.RemoteName = upstreamRemoteName(branch, upstreamRef)
 		pushed, err := isAncestor("HEAD", upstreamRef)
 		if err != nil {
 			return status, err
@@ -55,8 +55,7 @@ func GetPushStatus(branch string) (PushStatus, error) {
 }
 
 func getUpstreamRef() (string, bool, error) {
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
-	output, err := cmd.Output()
+	output, err := runGit("rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			if exitErr.ExitCode() == 128 {
@@ -75,8 +74,7 @@ func getUpstreamRef() (string, bool, error) {
 }
 
 func isAncestor(ancestorRef, descendantRef string) (bool, error) {
-	cmd := exec.Command("git", "merge-base", "--is-ancestor", ancestorRef, descendantRef)
-	if err := cmd.Run(); err != nil {
+	if _, err := runGit("merge-base", "--is-ancestor", ancestorRef, descendantRef); err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			if exitErr.ExitCode() == 1 {
 				return false, nil
@@ -90,8 +88,7 @@ func isAncestor(ancestorRef, descendantRef string) (bool, error) {
 
 func remoteBranchExists(remoteRef string) (bool, error) {
 	ref := fmt.Sprintf("refs/remotes/%s", remoteRef)
-	cmd := exec.Command("git", "show-ref", "--verify", "--quiet", ref)
-	if err := cmd.Run(); err != nil {
+	if _, err := runGit("show-ref", "--verify", "--quiet", ref); err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			if exitErr.ExitCode() == 1 {
 				return false, nil
@@ -103,6 +100,19 @@ func remoteBranchExists(remoteRef string) (bool, error) {
 	return true, nil
 }
 
+// upstreamRemoteName returns the remote of branch's upstream. Remote names
+// may contain slashes, so git is asked before falling back to the part of
+// ref before the first slash.
+func upstreamRemoteName(branch, ref string) string {
+	output, err := runGit("for-each-ref", "--format=%(upstream:remotename)", "refs/heads/"+branch)
+	if err == nil {
+		if name := strings.TrimSpace(string(output)); name != "" {
+			return name
+		}
+	}
+	return remoteNameFromRef(ref)
+}
+
 func remoteNameFromRef(ref string) string {
 	parts := strings.SplitN(ref, "/", 2)
 	if len(parts) == 0 || parts[0] == "" {
@@ -110,3 +120,56 @@ func remoteNameFromRef(ref string) string {
 	}
 	return parts[0]
 }
+
+// Reasons a push was rejected, as returned by PushRejection.
+const (
+	PushProtected        = "protected"
+	PushNonFastForward   = "non-fast-forward"
+	PushPermissionDenied = "permission-denied"
+)
+
+// pushRejections maps phrases from git and GitHub push errors to the reason
+// they stand for. Protection rules are checked first, because a rejected
+// protected branch is also reported as "[remote rejected]".
+var pushRejections = []struct {
+	reason  string
+	phrases []string
+}{
+	{PushProtected, []string{
+		"protected branch",
+		"gh006",
+		"gh013",
+		"repository rule violations",
+		"required status check",
+		"must not contain merge commits",
+		"linear history",
+		"pre-receive hook declined",
+	}},
+	{PushNonFastForward, []string{
+		"non-fast-forward",
+		"fetch first",
+		"tip of your current branch is behind",
+		"stale info",
+	}},
+	{PushPermissionDenied, []string{
+		"permission denied",
+		"permission to ",
+		"the requested url returned error: 403",
+		"authentication failed",
+		"could not read from remote repository",
+	}},
+}
+
+// PushRejection returns why a push failed, judged from the output of git
+// push, or "" when the output does not match a known reason.
+func PushRejection(output string) string {
+	lower := strings.ToLower(output)
+	for _, rejection := range pushRejections {
+		for _, phrase := range rejection.phrases {
+			if strings.Contains(lower, phrase) {
+				return rejection.reason
+			}
+		}
+	}
+	return ""
+}
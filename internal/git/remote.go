@@ -2,7 +2,6 @@ package git
 
 import (
 	"fmt"
-	"os/exec"
 	"strings"
 )
 
@@ -12,8 +11,7 @@ func GetRemoteURL(remoteName string) (string, error) {
 		return "", fmt.Errorf("remote name is empty")
 	}
 
-	cmd := exec.Command("git", "remote", "get-url", remoteName)
-	output, err := cmd.Output()
+	output, err := runGit("remote", "get-url", remoteName)
 	if err != nil {
 		return "", fmt.Errorf("failed to get remote URL for %s: %w", remoteName, err)
 	}
@@ -0,0 +1,48 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/timing"
+)
+
+// globalArgs keep user configuration from changing output that gelf parses
+// or sends to the model: pagers such as delta and forced colors would add
+// escape sequences to captured diffs.
+var globalArgs = []string{"--no-pager", "-c", "core.pager=cat", "-c", "color.ui=never"}
+
+// runGit runs git with args and returns its standard output. Every git call
+// goes through here so that it is timed.
+func runGit(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append(append([]string{}, globalArgs...), args...)...)
+	cmd.Env = append(os.Environ(), "GIT_PAGER=cat")
+	return timing.Output(cmd)
+}
+
+// runGitContext is runGit with the process killed once ctx is done.
+func runGitContext(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append(append([]string{}, globalArgs...), args...)...)
+	cmd.Env = append(os.Environ(), "GIT_PAGER=cat")
+	return timing.Output(cmd)
+}
+
+// runGitInput is runGit with input on standard input.
+func runGitInput(input string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append(append([]string{}, globalArgs...), args...)...)
+	cmd.Env = append(os.Environ(), "GIT_PAGER=cat")
+	cmd.Stdin = strings.NewReader(input)
+	return timing.Output(cmd)
+}
+
+// ansiPattern matches CSI sequences (colors) and OSC sequences (hyperlinks).
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// stripANSI removes terminal escape sequences that slipped into output
+// despite globalArgs, e.g. from a textconv filter.
+func stripANSI(text string) string {
+	return ansiPattern.ReplaceAllString(text, "")
+}
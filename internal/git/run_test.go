@@ -0,0 +1,61 @@
+package git
+
+import (
+	"strings"
+	"testing"
+)
+
+// ansiPager prints a colored banner before its input, as delta and similar
+// pagers add escape sequences.
+const ansiPager = `printf '\033[31mPAGED\033[0m\n'; cat`
+
+func TestOutputIgnoresPagersAndExternalDiff(t *testing.T) {
+	newTestRepo(t)
+	writeTestFile(t, "file.txt", "one\n")
+	runTestGit(t, "add", "file.txt")
+	runTestGit(t, "commit", "-q", "-m", "add file")
+	base := runTestGit(t, "rev-parse", "HEAD")
+
+	// diff.external=false would fail every diff that used it.
+	for _, setting := range [][2]string{
+		{"diff.external", "false"},
+		{"core.pager", ansiPager},
+		{"pager.diff", ansiPager},
+		{"pager.log", ansiPager},
+		{"color.ui", "always"},
+		{"color.diff", "always"},
+	} {
+		runTestGit(t, "config", setting[0], setting[1])
+	}
+	t.Setenv("GIT_PAGER", ansiPager)
+
+	writeTestFile(t, "file.txt", "one\ntwo\n")
+	runTestGit(t, "add", "file.txt")
+
+	staged, err := GetStagedDiff()
+	if err != nil {
+		t.Fatalf("GetStagedDiff() error: %v", err)
+	}
+	runTestGit(t, "commit", "-q", "-m", "add a second line")
+	committed, err := GetCommittedDiff(base, "HEAD")
+	if err != nil {
+		t.Fatalf("GetCommittedDiff() error: %v", err)
+	}
+	log, err := GetCommitLog(base, "HEAD")
+	if err != nil {
+		t.Fatalf("GetCommitLog() error: %v", err)
+	}
+
+	for name, output := range map[string]string{"GetStagedDiff": staged, "GetCommittedDiff": committed} {
+		if strings.ContainsAny(output, "\x1b") || strings.Contains(output, "PAGED") {
+			t.Errorf("%s() = %q, want no pager output or escape sequences", name, output)
+		}
+		if !strings.HasPrefix(output, "diff --git a/file.txt b/file.txt\n") || !strings.Contains(output, "\n@@ -1 +1,2 @@\n one\n+two") {
+			t.Errorf("%s() = %q, want a plain unified diff", name, output)
+		}
+	}
+	short := runTestGit(t, "rev-parse", "--short", "HEAD")
+	if want := short + " add a second line"; log != want {
+		t.Errorf("GetCommitLog() = %q, want %q", log, want)
+	}
+}
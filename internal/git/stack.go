@@ -0,0 +1,119 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StackPart is one branch of a stack of pull requests: it starts where the
+// part before it ends and gets Commits cherry-picked onto it in order.
+type StackPart struct {
+	Branch  string
+	Commits []string
+}
+
+// GetCommitFiles returns the files each commit in baseRef..headRef changes,
+// keyed by the abbreviated SHA GetCommitLog prints.
+func GetCommitFiles(baseRef, headRef string) (map[string][]string, error) {
+	output, err := runGit("log", "--reverse", "--no-renames", "--format=%x00%h", "--name-only", fmt.Sprintf("%s..%s", baseRef, headRef))
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]string{}
+	for _, entry := range strings.Split(string(output), "\x00") {
+		lines := strings.Split(strings.TrimSpace(entry), "\n")
+		if lines[0] == "" {
+			continue
+		}
+		for _, line := range lines[1:] {
+			if line = strings.TrimSpace(line); line != "" {
+				files[lines[0]] = append(files[lines[0]], line)
+			}
+		}
+	}
+	return files, nil
+}
+
+// CreateStack creates the branches of parts, the first from baseRef and each
+// later one from the part before it, by cherry-picking their commits, and
+// switches back to returnTo. It refuses to start when the worktree has
+// changes or a branch already exists. When a cherry-pick stops, e.g. on a
+// conflict, it is aborted, returnTo is checked out again and the branches
+// created so far are deleted, so the repository is left as it was.
+func CreateStack(baseRef string, parts []StackPart, returnTo string) error {
+	for _, part := range parts {
+		if err := ValidateBranchName(part.Branch); err != nil {
+			return err
+		}
+		if BranchExists(part.Branch) {
+			return fmt.Errorf("branch %s already exists", part.Branch)
+		}
+	}
+	clean, err := IsWorktreeClean()
+	if err != nil {
+		return err
+	}
+	if !clean {
+		return fmt.Errorf("worktree has uncommitted changes; commit or stash them first")
+	}
+
+	var created []string
+	undo := func() error {
+		_, _ = runGit("cherry-pick", "--abort")
+		if _, err := runGit("switch", returnTo); err != nil {
+			return fmt.Errorf("failed to switch back to %s: %w", returnTo, gitFailure(err))
+		}
+		for _, branch := range created {
+			_, _ = runGit("branch", "-D", branch)
+		}
+		return nil
+	}
+
+	start := baseRef
+	for _, part := range parts {
+		if _, err := runGit("switch", "-c", part.Branch, start); err != nil {
+			if undoErr := undo(); undoErr != nil {
+				return undoErr
+			}
+			return fmt.Errorf("failed to create %s: %w", part.Branch, gitFailure(err))
+		}
+		created = append(created, part.Branch)
+
+		for _, commit := range part.Commits {
+			if _, err := runGit("cherry-pick", "--allow-empty", commit); err != nil {
+				if undoErr := undo(); undoErr != nil {
+					return undoErr
+				}
+				return fmt.Errorf("cherry-picking %s onto %s failed (%v); the split was undone and %s is unchanged", commit, part.Branch, gitFailure(err), returnTo)
+			}
+		}
+		start = part.Branch
+	}
+
+	if _, err := runGit("switch", returnTo); err != nil {
+		return fmt.Errorf("failed to switch back to %s: %w", returnTo, gitFailure(err))
+	}
+	return nil
+}
+
+// SameTree reports whether a and b have the same content.
+func SameTree(a, b string) bool {
+	_, err := runGit("diff", "--quiet", a, b)
+	return err == nil
+}
+
+// PushBranches pushes branches to remote in one push and sets it as their
+// upstream.
+func PushBranches(remote string, branches []string) error {
+	args := []string{"push", "-u", remote}
+	for _, branch := range branches {
+		// A full refspec keeps a tag with the same name from being pushed
+		// instead of the branch.
+		args = append(args, "refs/heads/"+branch+":refs/heads/"+branch)
+	}
+	if _, err := runGit(args...); err != nil {
+		return fmt.Errorf("failed to push %s: %w", strings.Join(branches, ", "), gitFailure(err))
+	}
+	return nil
+}
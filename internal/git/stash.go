@@ -0,0 +1,145 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stash is an entry of the stash list.
+type Stash struct {
+	// Ref names the entry, e.g. "stash@{0}".
+	Ref     string
+	Message string
+	Time    time.Time
+}
+
+// StashStat summarizes the changes a stash holds.
+type StashStat struct {
+	Files        int
+	AddedLines   int
+	DeletedLines int
+}
+
+// ListStashes returns the stash list, newest first.
+func ListStashes() ([]Stash, error) {
+	output, err := runGit("stash", "list", "--format=%gd%x00%ct%x00%gs")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stashes: %w", err)
+	}
+
+	var stashes []Stash
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.SplitN(line, "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		stash := Stash{Ref: fields[0], Message: fields[2]}
+		if seconds, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			stash.Time = time.Unix(seconds, 0)
+		}
+		stashes = append(stashes, stash)
+	}
+	return stashes, nil
+}
+
+// GetStashStat counts the files and lines changed by the stash ref,
+// untracked files included when the stash has them.
+func GetStashStat(ref string) (StashStat, error) {
+	// --include-untracked needs git 2.32; older versions only count the
+	// tracked changes.
+	output, err := runGit("stash", "show", "--numstat", "--include-untracked", ref)
+	if err != nil {
+		output, err = runGit("stash", "show", "--numstat", ref)
+		if err != nil {
+			return StashStat{}, fmt.Errorf("failed to show %s: %w", ref, err)
+		}
+	}
+
+	var stat StashStat
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		stat.Files++
+		// Binary files show "-" for both counts.
+		added, _ := strconv.Atoi(fields[0])
+		deleted, _ := strconv.Atoi(fields[1])
+		stat.AddedLines += added
+		stat.DeletedLines += deleted
+	}
+	return stat, nil
+}
+
+// GetUntrackedFiles returns the untracked files that are not ignored.
+func GetUntrackedFiles() ([]string, error) {
+	output, err := runGit("ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list untracked files: %w", err)
+	}
+
+	var files []string
+	for _, file := range strings.Split(string(output), "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// GetUntrackedDiff returns files as a diff that adds them.
+func GetUntrackedDiff(files []string) (string, error) {
+	var b strings.Builder
+	for _, file := range files {
+		// diff --no-index exits with 1 when the files differ, which an
+		// added file always does.
+		output, err := runGit("diff", "--no-color", "--no-ext-diff", "--no-index", "-U5", "--", "/dev/null", file)
+		if err != nil {
+			if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+				return "", fmt.Errorf("failed to get diff of %s: %w", file, err)
+			}
+		}
+		b.Write(output)
+	}
+	return stripANSI(b.String()), nil
+}
+
+// StashPush stashes the working tree changes under message. With
+// includeUntracked the untracked files are stashed too.
+func StashPush(message string, includeUntracked bool) error {
+	args := []string{"stash", "push", "-m", message}
+	if includeUntracked {
+		args = append(args, "--include-untracked")
+	}
+	if _, err := runGit(args...); err != nil {
+		return stashError("push", err)
+	}
+	return nil
+}
+
+// StashApply applies the stash ref to the working tree. With pop the stash
+// is dropped once it applied cleanly.
+func StashApply(ref string, pop bool) error {
+	action := "apply"
+	if pop {
+		action = "pop"
+	}
+	if _, err := runGit("stash", action, ref); err != nil {
+		return stashError(action, err)
+	}
+	return nil
+}
+
+// stashError adds what git stash printed on stderr, such as the files that
+// conflicted, to err.
+func stashError(action string, err error) error {
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if stderr := strings.TrimSpace(string(exitErr.Stderr)); stderr != "" {
+			return fmt.Errorf("git stash %s failed: %s", action, stderr)
+		}
+	}
+	return fmt.Errorf("git stash %s failed: %w", action, err)
+}
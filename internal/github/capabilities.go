@@ -0,0 +1,108 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/EkeMinusYou/gelf/internal/timing"
+)
+
+// Capabilities is what the installed gh supports of the flags and fields
+// gelf uses. Older releases lack some of them.
+type Capabilities struct {
+	// Version is the gh version, e.g. "2.40.1", or "" if it could not be
+	// read.
+	Version string
+	// JSON reports whether pr commands accept --json.
+	JSON bool
+	// BodyFile reports whether pr create and pr edit accept --body-file.
+	BodyFile bool
+	// PRFields are the --json fields of pr list; nil when unknown.
+	PRFields map[string]bool
+}
+
+var (
+	ghVersionRegex = regexp.MustCompile(`gh version (\S+)`)
+
+	capabilitiesOnce sync.Once
+	capabilities     *Capabilities
+	capabilitiesErr  error
+)
+
+// DetectCapabilities runs gh --version and probes gh once per process; later
+// calls return the same result. The probes only parse flags, so they need
+// neither a repository nor the network.
+func DetectCapabilities(ctx context.Context) (*Capabilities, error) {
+	capabilitiesOnce.Do(func() {
+		capabilities, capabilitiesErr = detectCapabilities(ctx)
+	})
+	return capabilities, capabilitiesErr
+}
+
+func detectCapabilities(ctx context.Context) (*Capabilities, error) {
+	output, err := runGH(ctx, "--version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to run gh --version: %w", err)
+	}
+	caps := &Capabilities{}
+	if match := ghVersionRegex.FindStringSubmatch(string(output)); match != nil {
+		caps.Version = match[1]
+	}
+
+	// Without a field list, gh answers --json with the fields it knows.
+	// Releases without --json reject the flag instead.
+	probe, _ := timing.CombinedOutput(exec.CommandContext(ctx, "gh", "pr", "list", "--json"))
+	if _, fields, ok := strings.Cut(string(probe), "fields for `--json`:"); ok {
+		caps.JSON = true
+		caps.PRFields = map[string]bool{}
+		for _, field := range strings.Fields(fields) {
+			caps.PRFields[field] = true
+		}
+	}
+
+	help, _ := timing.CombinedOutput(exec.CommandContext(ctx, "gh", "pr", "create", "--help"))
+	caps.BodyFile = strings.Contains(string(help), "--body-file")
+	return caps, nil
+}
+
+// HasPRField reports whether gh pr list can return field. Unknown field
+// lists count as supported, so a failed probe does not drop fields.
+func (c *Capabilities) HasPRField(field string) bool {
+	return c == nil || c.PRFields == nil || c.PRFields[field]
+}
+
+// RequireJSON fails when gh cannot print pull requests as JSON, which every
+// gelf pr command needs.
+func (c *Capabilities) RequireJSON() error {
+	if c == nil || c.JSON {
+		return nil
+	}
+	version := c.Version
+	if version == "" {
+		version = "unknown"
+	}
+	return fmt.Errorf("gh >= 2.0 required for --json output (found %s); upgrade gh", version)
+}
+
+// Degraded describes what gelf does differently because gh lacks a flag or
+// field.
+func (c *Capabilities) Degraded() []string {
+	if c == nil {
+		return nil
+	}
+	var degraded []string
+	if !c.JSON {
+		degraded = append(degraded, "no --json output; gelf pr commands will fail")
+	}
+	if !c.BodyFile {
+		degraded = append(degraded, "no --body-file; descriptions are passed with --body")
+	}
+	if c.JSON && !c.HasPRField("headRepositoryOwner") {
+		degraded = append(degraded, "no headRepositoryOwner field; pull requests from forks are matched by branch only")
+	}
+	return degraded
+}
@@ -4,9 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/url"
 	"os/exec"
 	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/timing"
 )
 
 type RepoInfo struct {
@@ -37,9 +38,13 @@ type pullRequestListItem struct {
 	} `json:"headRepositoryOwner"`
 }
 
+// runGH runs gh with args and returns its standard output, timing the call.
+func runGH(ctx context.Context, args ...string) ([]byte, error) {
+	return timing.Output(exec.CommandContext(ctx, "gh", args...))
+}
+
 func AuthToken(ctx context.Context) (string, error) {
-	cmd := exec.CommandContext(ctx, "gh", "auth", "token")
-	output, err := cmd.Output()
+	output, err := runGH(ctx, "auth", "token")
 	if err != nil {
 		return "", fmt.Errorf("failed to get GitHub auth token: %w", err)
 	}
@@ -64,8 +69,7 @@ func RepoInfoFromGH(ctx context.Context) (*RepoInfo, error) {
 }
 
 func RepoInfoFromGHWithParent(ctx context.Context) (*RepoInfo, *RepoInfo, error) {
-	cmd := exec.CommandContext(ctx, "gh", "repo", "view", "--json", "owner,name,parent")
-	output, err := cmd.Output()
+	output, err := runGH(ctx, "repo", "view", "--json", "owner,name,parent")
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get repository info: %w", err)
 	}
@@ -115,26 +119,14 @@ func FindPullRequest(ctx context.Context, repoFullName, headBranch string, headO
 
 	owners := normalizeOwners(headOwners)
 
-	listByHead := func(head string, limit int) ([]pullRequestListItem, error) {
-		args := []string{"pr", "list", "--state", "all", "--json", "number,title,url,state,isDraft,headRefName,baseRefName,headRepositoryOwner", "--limit", fmt.Sprintf("%d", limit), "--head", head}
-		if strings.TrimSpace(repoFullName) != "" {
-			args = append(args, "--repo", repoFullName)
-		}
-
-		cmd := exec.CommandContext(ctx, "gh", args...)
-		output, err := cmd.Output()
-		if err != nil {
-			return nil, fmt.Errorf("failed to list pull requests: %w", err)
-		}
-
-		var prs []pullRequestListItem
-		if err := json.Unmarshal(output, &prs); err != nil {
-			return nil, fmt.Errorf("failed to parse pull request list: %w", err)
-		}
-		return prs, nil
+	// gh pr list --head matches the branch name exactly and does not
+	// support the <owner>:<branch> form, so the owner is matched here.
+	prs, err := listPullRequests(ctx, repoFullName, "--state", "all", "--limit", "30", "--head", headBranch)
+	if err != nil {
+		return nil, err
 	}
 
-	selectMatch := func(prs []pullRequestListItem, owner string) *PullRequestInfo {
+	selectMatch := func(owner string) *PullRequestInfo {
 		owner = strings.ToLower(strings.TrimSpace(owner))
 		for _, pr := range prs {
 			if strings.TrimSpace(pr.HeadRefName) != headBranch {
@@ -161,26 +153,110 @@ func FindPullRequest(ctx context.Context, repoFullName, headBranch string, headO
 		return nil
 	}
 
-	for owner := range owners {
-		head := fmt.Sprintf("%s:%s", owner, headBranch)
-		prs, err := listByHead(head, 5)
-		if err != nil {
-			return nil, err
-		}
-		if match := selectMatch(prs, owner); match != nil {
+	// Owners are tried in the order given, so the pushing remote's owner
+	// wins over the base repository's.
+	for _, owner := range headOwners {
+		if match := selectMatch(owner); match != nil {
 			return match, nil
 		}
 	}
+	return selectMatch(""), nil
+}
+
+// PullRequestBody returns the current body of pull request number.
+func PullRequestBody(ctx context.Context, repoFullName string, number int) (string, error) {
+	args := []string{"pr", "view", fmt.Sprintf("%d", number), "--json", "body", "--jq", ".body"}
+	if strings.TrimSpace(repoFullName) != "" {
+		args = append(args, "--repo", repoFullName)
+	}
 
-	prs, err := listByHead(headBranch, 20)
+	output, err := runGH(ctx, args...)
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("failed to get body of pull request #%d: %w", number, err)
 	}
-	if match := selectMatch(prs, ""); match != nil {
-		return match, nil
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// pullRequestListFields are the fields requested from gh pr list.
+const pullRequestListFields = "number,title,url,state,isDraft,headRefName,baseRefName,headRepositoryOwner"
+
+func listPullRequests(ctx context.Context, repoFullName string, filters ...string) ([]pullRequestListItem, error) {
+	fields := pullRequestListFields
+	if caps, err := DetectCapabilities(ctx); err == nil && !caps.HasPRField("headRepositoryOwner") {
+		// The owner is then left empty, which FindPullRequest tolerates.
+		fields = strings.TrimSuffix(fields, ",headRepositoryOwner")
+	}
+	args := append([]string{"pr", "list", "--json", fields}, filters...)
+	if strings.TrimSpace(repoFullName) != "" {
+		args = append(args, "--repo", repoFullName)
 	}
 
-	return nil, nil
+	output, err := runGH(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull requests: %w", err)
+	}
+
+	var prs []pullRequestListItem
+	if err := json.Unmarshal(output, &prs); err != nil {
+		return nil, fmt.Errorf("failed to parse pull request list: %w", err)
+	}
+	return prs, nil
+}
+
+// PullRequestSummary is a pull request as shown by pr list.
+type PullRequestSummary struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	State   string `json:"state"`
+	IsDraft bool   `json:"isDraft"`
+	Head    string `json:"head"`
+	Base    string `json:"base"`
+}
+
+// ListOptions filters ListPullRequests.
+type ListOptions struct {
+	// State is open, closed, merged or all.
+	State string
+	// Author limits the list to one author; "@me" is the current user.
+	Author string
+	// Limit is the maximum number of pull requests; gh pages through results
+	// as needed.
+	Limit int
+}
+
+// ListPullRequests lists the pull requests of repoFullName.
+func ListPullRequests(ctx context.Context, repoFullName string, opts ListOptions) ([]PullRequestSummary, error) {
+	state := opts.State
+	if state == "" {
+		state = "open"
+	}
+	filters := []string{"--state", state}
+	if opts.Limit > 0 {
+		filters = append(filters, "--limit", fmt.Sprintf("%d", opts.Limit))
+	}
+	if opts.Author != "" {
+		filters = append(filters, "--author", opts.Author)
+	}
+
+	items, err := listPullRequests(ctx, repoFullName, filters...)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]PullRequestSummary, 0, len(items))
+	for _, item := range items {
+		summaries = append(summaries, PullRequestSummary{
+			Number:  item.Number,
+			Title:   item.Title,
+			URL:     item.URL,
+			State:   item.State,
+			IsDraft: item.IsDraft,
+			Head:    item.HeadRefName,
+			Base:    item.BaseRefName,
+		})
+	}
+	return summaries, nil
 }
 
 func normalizeOwners(headOwners []string) map[string]struct{} {
@@ -195,42 +271,246 @@ func normalizeOwners(headOwners []string) map[string]struct{} {
 	return owners
 }
 
-func RepoInfoFromRemoteURL(remoteURL string) (*RepoInfo, error) {
-	remoteURL = strings.TrimSpace(remoteURL)
-	if remoteURL == "" {
-		return nil, nil
+// RecentPullRequest is how one of the user's pull requests was opened.
+type RecentPullRequest struct {
+	IsDraft   bool
+	Labels    []string
+	Reviewers []string
+}
+
+// RecentPullRequests returns the user's most recent pull requests, newest
+// first. Reviewers are the requested reviewers plus those who reviewed, since
+// GitHub drops a request once the review is submitted; teams are returned as
+// <owner>/<slug>.
+func RecentPullRequests(ctx context.Context, repoFullName string, limit int) ([]RecentPullRequest, error) {
+	args := []string{"pr", "list", "--author", "@me", "--state", "all", "--limit", fmt.Sprintf("%d", limit), "--json", "isDraft,labels,reviewRequests,latestReviews"}
+	if strings.TrimSpace(repoFullName) != "" {
+		args = append(args, "--repo", repoFullName)
+	}
+
+	output, err := runGH(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recent pull requests: %w", err)
+	}
+
+	var items []struct {
+		IsDraft bool `json:"isDraft"`
+		Labels  []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+		ReviewRequests []struct {
+			Login string `json:"login"`
+			Slug  string `json:"slug"`
+		} `json:"reviewRequests"`
+		LatestReviews []struct {
+			Author struct {
+				Login string `json:"login"`
+			} `json:"author"`
+		} `json:"latestReviews"`
+	}
+	if err := json.Unmarshal(output, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse recent pull requests: %w", err)
+	}
+
+	owner, _, _ := strings.Cut(repoFullName, "/")
+	prs := make([]RecentPullRequest, 0, len(items))
+	for _, item := range items {
+		pr := RecentPullRequest{IsDraft: item.IsDraft}
+		for _, label := range item.Labels {
+			pr.Labels = append(pr.Labels, label.Name)
+		}
+		seen := map[string]bool{}
+		addReviewer := func(reviewer string) {
+			if reviewer != "" && !seen[reviewer] {
+				seen[reviewer] = true
+				pr.Reviewers = append(pr.Reviewers, reviewer)
+			}
+		}
+		for _, request := range item.ReviewRequests {
+			if request.Login != "" {
+				addReviewer(request.Login)
+			} else if request.Slug != "" && owner != "" {
+				addReviewer(owner + "/" + request.Slug)
+			}
+		}
+		for _, review := range item.LatestReviews {
+			addReviewer(review.Author.Login)
+		}
+		prs = append(prs, pr)
+	}
+	return prs, nil
+}
+
+// MergedPullRequestApprovers returns, for each of the user's most recently
+// merged pull requests, newest first, the users whose latest review approved
+// it.
+func MergedPullRequestApprovers(ctx context.Context, repoFullName string, limit int) ([][]string, error) {
+	args := []string{"pr", "list", "--author", "@me", "--state", "merged", "--limit", fmt.Sprintf("%d", limit), "--json", "latestReviews"}
+	if strings.TrimSpace(repoFullName) != "" {
+		args = append(args, "--repo", repoFullName)
+	}
+
+	output, err := runGH(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list merged pull requests: %w", err)
+	}
+
+	var items []struct {
+		LatestReviews []struct {
+			Author struct {
+				Login string `json:"login"`
+			} `json:"author"`
+			State string `json:"state"`
+		} `json:"latestReviews"`
+	}
+	if err := json.Unmarshal(output, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse merged pull requests: %w", err)
 	}
 
-	if strings.Contains(remoteURL, "://") {
-		parsed, err := url.Parse(remoteURL)
-		if err != nil {
-			return nil, nil
+	approvers := make([][]string, 0, len(items))
+	for _, item := range items {
+		var logins []string
+		for _, review := range item.LatestReviews {
+			if review.State == "APPROVED" && review.Author.Login != "" {
+				logins = append(logins, review.Author.Login)
+			}
 		}
-		return repoInfoFromPath(parsed.Path), nil
+		approvers = append(approvers, logins)
 	}
+	return approvers, nil
+}
 
-	if idx := strings.Index(remoteURL, ":"); idx != -1 {
-		return repoInfoFromPath(remoteURL[idx+1:]), nil
+// PendingReviewRequests returns on how many of the user's open pull
+// requests each user has a review request that is still pending. Team
+// requests are not counted.
+func PendingReviewRequests(ctx context.Context, repoFullName string) (map[string]int, error) {
+	args := []string{"pr", "list", "--author", "@me", "--state", "open", "--limit", "100", "--json", "reviewRequests"}
+	if strings.TrimSpace(repoFullName) != "" {
+		args = append(args, "--repo", repoFullName)
 	}
 
-	return nil, nil
+	output, err := runGH(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open pull requests: %w", err)
+	}
+
+	var items []struct {
+		ReviewRequests []struct {
+			Login string `json:"login"`
+		} `json:"reviewRequests"`
+	}
+	if err := json.Unmarshal(output, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse open pull requests: %w", err)
+	}
+
+	pending := map[string]int{}
+	for _, item := range items {
+		for _, request := range item.ReviewRequests {
+			if request.Login != "" {
+				pending[request.Login]++
+			}
+		}
+	}
+	return pending, nil
 }
 
-func repoInfoFromPath(path string) *RepoInfo {
-	path = strings.TrimPrefix(path, "/")
-	path = strings.TrimSuffix(path, ".git")
-	parts := strings.Split(path, "/")
-	if len(parts) < 2 {
-		return nil
+// RepoLabels returns the names of the labels defined in the repository.
+func RepoLabels(ctx context.Context, repoFullName string) ([]string, error) {
+	args := []string{"label", "list", "--limit", "1000", "--json", "name"}
+	if strings.TrimSpace(repoFullName) != "" {
+		args = append(args, "--repo", repoFullName)
 	}
-	owner := strings.TrimSpace(parts[0])
-	repo := strings.TrimSpace(parts[1])
-	if owner == "" || repo == "" {
-		return nil
+
+	output, err := runGH(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list labels: %w", err)
 	}
 
-	return &RepoInfo{
-		Owner: owner,
-		Name:  repo,
+	var items []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(output, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse labels: %w", err)
+	}
+	labels := make([]string, 0, len(items))
+	for _, item := range items {
+		labels = append(labels, item.Name)
+	}
+	return labels, nil
+}
+
+// RepoCollaborators returns the logins of the users who can be requested to
+// review pull requests in repoFullName, or in the current repository when it
+// is empty.
+func RepoCollaborators(ctx context.Context, repoFullName string) ([]string, error) {
+	if strings.TrimSpace(repoFullName) == "" {
+		repoFullName = "{owner}/{repo}"
+	}
+
+	output, err := runGH(ctx, "api", "--paginate", "repos/"+repoFullName+"/collaborators", "--jq", ".[].login")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collaborators: %w", err)
+	}
+
+	var logins []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			logins = append(logins, line)
+		}
+	}
+	return logins, nil
+}
+
+// EditLabels adds and removes labels on an existing pull request.
+func EditLabels(ctx context.Context, repoFullName string, number int, add, remove []string) error {
+	args := []string{"pr", "edit", fmt.Sprintf("%d", number)}
+	if strings.TrimSpace(repoFullName) != "" {
+		args = append(args, "--repo", repoFullName)
+	}
+	for _, label := range add {
+		args = append(args, "--add-label", label)
+	}
+	for _, label := range remove {
+		args = append(args, "--remove-label", label)
+	}
+
+	if _, err := runGH(ctx, args...); err != nil {
+		return fmt.Errorf("failed to edit labels of #%d: %w", number, err)
+	}
+	return nil
+}
+
+// RepoAccess is what the current user may do in a repository.
+type RepoAccess struct {
+	// Permission is the viewer's role: ADMIN, MAINTAIN, WRITE, TRIAGE, READ
+	// or empty when the repository is not visible.
+	Permission string
+	Archived   bool
+}
+
+// CanPush reports whether the viewer may push branches.
+func (a *RepoAccess) CanPush() bool {
+	switch a.Permission {
+	case "ADMIN", "MAINTAIN", "WRITE":
+		return true
+	}
+	return false
+}
+
+// GetRepoAccess looks up the viewer's permission on repoFullName and whether
+// it is archived.
+func GetRepoAccess(ctx context.Context, repoFullName string) (*RepoAccess, error) {
+	output, err := runGH(ctx, "repo", "view", repoFullName, "--json", "viewerPermission,isArchived")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access to %s: %w", repoFullName, err)
+	}
+
+	var result struct {
+		ViewerPermission string `json:"viewerPermission"`
+		IsArchived       bool   `json:"isArchived"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse access to %s: %w", repoFullName, err)
 	}
+	return &RepoAccess{Permission: result.ViewerPermission, Archived: result.IsArchived}, nil
 }
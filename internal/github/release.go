@@ -0,0 +1,32 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Release is a published GitHub release.
+type Release struct {
+	TagName string `json:"tag_name"`
+	Name    string `json:"name"`
+	Body    string `json:"body"`
+}
+
+// ListReleases returns the most recent releases of owner/repo, newest first.
+func ListReleases(ctx context.Context, token, owner, repo string, limit int) ([]Release, error) {
+	body, status, err := fetchGitHubAPI(ctx, token, fmt.Sprintf("repos/%s/%s/releases?per_page=%d", owner, repo, limit), "github api releases")
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("failed to list releases of %s/%s: status %d", owner, repo, status)
+	}
+
+	var releases []Release
+	if err := json.Unmarshal(body, &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse releases of %s/%s: %w", owner, repo, err)
+	}
+	return releases, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,12 +12,36 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/timing"
 )
 
 type PullRequestTemplate struct {
 	Source  string
 	Path    string
 	Content string
+	// Parts lists the templates a merged template was built from.
+	Parts []*PullRequestTemplate
+}
+
+// Template merge modes accepted by FindPullRequestTemplateWithMerge.
+const (
+	TemplateMergeRepo = "repo"
+	TemplateMergeOrg  = "org"
+	TemplateMergeBoth = "both"
+)
+
+// Describe returns a human readable description of where the template came from.
+func (t *PullRequestTemplate) Describe() string {
+	if len(t.Parts) == 0 {
+		return fmt.Sprintf("%s template: %s", t.Source, t.Path)
+	}
+	descriptions := make([]string, 0, len(t.Parts))
+	for _, part := range t.Parts {
+		descriptions = append(descriptions, part.Describe())
+	}
+	return strings.Join(descriptions, " + ")
 }
 
 var templateFileCandidates = []string{
@@ -37,20 +62,192 @@ var templateDirCandidates = []string{
 	"docs/pull_request_template",
 }
 
+// FindPullRequestTemplate returns the repository template, falling back to
+// the org one, without a time limit on the org lookup.
 func FindPullRequestTemplate(ctx context.Context, repoRoot, token, owner string) (*PullRequestTemplate, error) {
-	localTemplate, err := findLocalPullRequestTemplate(repoRoot)
+	template, _, err := FindPullRequestTemplateWithMerge(ctx, repoRoot, token, owner, TemplateOptions{})
+	return template, err
+}
+
+// TemplateOptions control how FindPullRequestTemplateWithMerge resolves the
+// template.
+type TemplateOptions struct {
+	// Mode is the merge mode: "repo" (the default) prefers the repository
+	// template and falls back to the org one, "org" does the reverse, and
+	// "both" merges the org template followed by the repository template.
+	Mode string
+	// OrgTimeout bounds the lookup in the owner's .github repository, which
+	// takes up to a dozen requests. 0 means no limit.
+	OrgTimeout time.Duration
+	// SkipOrg leaves the org template out without asking GitHub.
+	SkipOrg bool
+}
+
+// FindPullRequestTemplateWithMerge resolves the template according to opts.
+// When the org lookup runs out of opts.OrgTimeout it continues as if there
+// were no org template and reports true.
+func FindPullRequestTemplateWithMerge(ctx context.Context, repoRoot, token, owner string, opts TemplateOptions) (*PullRequestTemplate, bool, error) {
+	switch opts.Mode {
+	case "", TemplateMergeRepo:
+		repoTemplate, err := findLocalPullRequestTemplate(repoRoot)
+		if err != nil || repoTemplate != nil {
+			return repoTemplate, false, err
+		}
+		return findOrgPullRequestTemplateWithin(ctx, token, owner, opts)
+	case TemplateMergeOrg, TemplateMergeBoth:
+	default:
+		return nil, false, fmt.Errorf("unknown template merge mode %q (expected repo, org or both)", opts.Mode)
+	}
+
+	orgTemplate, timedOut, err := findOrgPullRequestTemplateWithin(ctx, token, owner, opts)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	if localTemplate != nil {
-		return localTemplate, nil
+	if opts.Mode == TemplateMergeOrg && orgTemplate != nil {
+		return orgTemplate, false, nil
 	}
 
-	if owner == "" || token == "" {
-		return nil, nil
+	repoTemplate, err := findLocalPullRequestTemplate(repoRoot)
+	if err != nil {
+		return nil, false, err
 	}
 
-	return findOrgPullRequestTemplate(ctx, token, owner)
+	if orgTemplate == nil {
+		return repoTemplate, timedOut, nil
+	}
+	if repoTemplate == nil {
+		return orgTemplate, false, nil
+	}
+
+	return &PullRequestTemplate{
+		Source:  "org+repo",
+		Path:    orgTemplate.Path + ", " + repoTemplate.Path,
+		Content: MergeTemplates(orgTemplate.Content, repoTemplate.Content),
+		Parts:   []*PullRequestTemplate{orgTemplate, repoTemplate},
+	}, false, nil
+}
+
+// findOrgPullRequestTemplateWithin looks up the org template within
+// opts.OrgTimeout. A lookup that times out reports true instead of an error.
+func findOrgPullRequestTemplateWithin(ctx context.Context, token, owner string, opts TemplateOptions) (*PullRequestTemplate, bool, error) {
+	if owner == "" || token == "" || opts.SkipOrg {
+		return nil, false, nil
+	}
+
+	lookupCtx := ctx
+	if opts.OrgTimeout > 0 {
+		var cancel context.CancelFunc
+		lookupCtx, cancel = context.WithTimeout(ctx, opts.OrgTimeout)
+		defer cancel()
+	}
+	template, err := findOrgPullRequestTemplate(lookupCtx, token, owner)
+	if err != nil && ctx.Err() == nil && errors.Is(lookupCtx.Err(), context.DeadlineExceeded) {
+		return nil, true, nil
+	}
+	return template, false, err
+}
+
+type templateSection struct {
+	heading string
+	body    []string
+}
+
+// MergeTemplates concatenates first and second. Sections of second whose
+// heading already appears in first are folded into that section instead of
+// being repeated.
+func MergeTemplates(first, second string) string {
+	merged := splitTemplateSections(first)
+	for _, section := range splitTemplateSections(second) {
+		index := -1
+		for i, existing := range merged {
+			if sameHeading(existing.heading, section.heading) {
+				index = i
+				break
+			}
+		}
+		if index == -1 {
+			merged = append(merged, section)
+			continue
+		}
+
+		body := strings.TrimSpace(strings.Join(section.body, "\n"))
+		existingBody := strings.TrimSpace(strings.Join(merged[index].body, "\n"))
+		if body == "" || body == existingBody {
+			continue
+		}
+		if existingBody == "" {
+			merged[index].body = section.body
+			continue
+		}
+		merged[index].body = append(trimTrailingBlank(merged[index].body), append([]string{""}, section.body...)...)
+	}
+
+	var lines []string
+	for _, section := range merged {
+		body := trimTrailingBlank(section.body)
+		if section.heading == "" && len(body) == 0 {
+			continue
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		if section.heading != "" {
+			lines = append(lines, section.heading)
+		}
+		lines = append(lines, body...)
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func splitTemplateSections(content string) []templateSection {
+	sections := []templateSection{{}}
+	inFence := false
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if !inFence && isATXHeading(trimmed) {
+			sections = append(sections, templateSection{heading: trimmed})
+			continue
+		}
+		current := &sections[len(sections)-1]
+		if len(current.body) == 0 && trimmed == "" {
+			continue
+		}
+		current.body = append(current.body, line)
+	}
+	return sections
+}
+
+func isATXHeading(line string) bool {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return false
+	}
+	return level == len(line) || line[level] == ' ' || line[level] == '\t'
+}
+
+func sameHeading(a, b string) bool {
+	if a == "" || b == "" {
+		return a == b
+	}
+	normalize := func(heading string) string {
+		heading = strings.TrimRight(heading, "# \t")
+		return strings.ToLower(strings.Join(strings.Fields(heading), " "))
+	}
+	return normalize(a) == normalize(b)
+}
+
+func trimTrailingBlank(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
 }
 
 func findLocalPullRequestTemplate(repoRoot string) (*PullRequestTemplate, error) {
@@ -138,16 +335,31 @@ func isTemplateFile(name string) bool {
 }
 
 func findOrgPullRequestTemplate(ctx context.Context, token, owner string) (*PullRequestTemplate, error) {
-	orgRepo := ".github"
+	return findRemotePullRequestTemplate(ctx, token, owner, ".github", "org")
+}
 
+// FindDefaultBranchPullRequestTemplate looks for the repository template on
+// the default branch of owner/repo through the contents API. It finds
+// templates the local checkout lacks, e.g. because the branch predates them
+// or the clone is shallow or partial.
+func FindDefaultBranchPullRequestTemplate(ctx context.Context, token, owner, repo string) (*PullRequestTemplate, error) {
+	if owner == "" || repo == "" || token == "" {
+		return nil, nil
+	}
+	return findRemotePullRequestTemplate(ctx, token, owner, repo, "default branch")
+}
+
+// findRemotePullRequestTemplate checks the template candidates in owner/repo
+// on GitHub, on its default branch, in the same order as the local lookup.
+func findRemotePullRequestTemplate(ctx context.Context, token, owner, repo, source string) (*PullRequestTemplate, error) {
 	for _, relPath := range templateFileCandidates {
-		content, found, err := fetchGitHubFile(ctx, token, owner, orgRepo, relPath)
+		content, found, err := fetchGitHubFile(ctx, token, owner, repo, relPath)
 		if err != nil {
 			return nil, err
 		}
 		if found {
 			return &PullRequestTemplate{
-				Source:  "org",
+				Source:  source,
 				Path:    relPath,
 				Content: content,
 			}, nil
@@ -155,7 +367,7 @@ func findOrgPullRequestTemplate(ctx context.Context, token, owner string) (*Pull
 	}
 
 	for _, relDir := range templateDirCandidates {
-		selected, err := fetchGitHubDirTemplate(ctx, token, owner, orgRepo, relDir)
+		selected, err := fetchGitHubDirTemplate(ctx, token, owner, repo, relDir)
 		if err != nil {
 			return nil, err
 		}
@@ -164,7 +376,7 @@ func findOrgPullRequestTemplate(ctx context.Context, token, owner string) (*Pull
 		}
 
 		return &PullRequestTemplate{
-			Source:  "org",
+			Source:  source,
 			Path:    selected.Path,
 			Content: selected.Content,
 		}, nil
@@ -282,7 +494,13 @@ func fetchGitHubFile(ctx context.Context, token, owner, repo, path string) (stri
 }
 
 func fetchGitHubContent(ctx context.Context, token, owner, repo, path string) ([]byte, int, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/%s", owner, repo, path)
+	return fetchGitHubAPI(ctx, token, fmt.Sprintf("repos/%s/%s/contents/%s", owner, repo, path), "github api contents")
+}
+
+// fetchGitHubAPI GETs a REST API path and returns the body and status code.
+// The request is timed under name.
+func fetchGitHubAPI(ctx context.Context, token, apiPath, name string) ([]byte, int, error) {
+	url := "https://api.github.com/" + apiPath
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, 0, err
@@ -294,10 +512,13 @@ func fetchGitHubContent(ctx context.Context, token, owner, repo, path string) ([
 		req.Header.Set("Authorization", "token "+token)
 	}
 
+	stop := timing.Track(name)
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
+		stop()
 		return nil, 0, err
 	}
+	defer stop()
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
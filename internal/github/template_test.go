@@ -0,0 +1,213 @@
+package github
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// orgTemplateTransport serves the contents API of the owner's .github
+// repository from files, keyed by path, and 404 for everything else.
+type orgTemplateTransport struct {
+	files map[string]string
+}
+
+func (tr orgTemplateTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	respond := func(status int, body string) *http.Response {
+		return &http.Response{
+			StatusCode: status,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     http.Header{},
+			Request:    req,
+		}
+	}
+
+	path, found := strings.CutPrefix(req.URL.Path, "/repos/acme/.github/contents/")
+	if !found {
+		return respond(http.StatusNotFound, `{}`), nil
+	}
+	content, found := tr.files[path]
+	if !found {
+		return respond(http.StatusNotFound, `{}`), nil
+	}
+	body, err := json.Marshal(map[string]string{
+		"type":     "file",
+		"encoding": "base64",
+		"content":  base64.StdEncoding.EncodeToString([]byte(content)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return respond(http.StatusOK, string(body)), nil
+}
+
+// withOrgTemplates routes GitHub API requests to an org .github repository
+// holding files.
+func withOrgTemplates(t *testing.T, files map[string]string) {
+	t.Helper()
+	original := http.DefaultClient.Transport
+	http.DefaultClient.Transport = orgTemplateTransport{files: files}
+	t.Cleanup(func() { http.DefaultClient.Transport = original })
+}
+
+// writeRepoTemplate writes content as the repository template under root.
+func writeRepoTemplate(t *testing.T, root, content string) {
+	t.Helper()
+	path := filepath.Join(root, ".github", "pull_request_template.md")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergeTemplates(t *testing.T) {
+	tests := []struct {
+		name   string
+		first  string
+		second string
+		want   string
+	}{
+		{
+			name:   "distinct sections in order",
+			first:  "## Summary\n\n<!-- what -->\n",
+			second: "## Testing\n\n- [ ] tests\n",
+			want:   "## Summary\n<!-- what -->\n\n## Testing\n- [ ] tests\n",
+		},
+		{
+			name:   "duplicate heading with other case and spacing",
+			first:  "## Summary\n\nDescribe the change.\n",
+			second: "##   summary  \n\nLink the issue.\n",
+			want:   "## Summary\nDescribe the change.\n\nLink the issue.\n",
+		},
+		{
+			name:   "duplicate heading with closing hashes",
+			first:  "## Checklist ##\n- [ ] docs\n",
+			second: "## checklist\n- [ ] tests\n",
+			want:   "## Checklist ##\n- [ ] docs\n\n- [ ] tests\n",
+		},
+		{
+			name:   "identical section is kept once",
+			first:  "## Summary\n\nDescribe the change.\n",
+			second: "## SUMMARY\nDescribe the change.\n",
+			want:   "## Summary\nDescribe the change.\n",
+		},
+		{
+			name:   "empty section takes the other body",
+			first:  "## Notes\n",
+			second: "## notes\nAnything else?\n",
+			want:   "## Notes\nAnything else?\n",
+		},
+		{
+			name:   "headings inside fences are not sections",
+			first:  "## Summary\n```\n## Summary\n```\n",
+			second: "## Testing\n",
+			want:   "## Summary\n```\n## Summary\n```\n\n## Testing\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeTemplates(tt.first, tt.second); got != tt.want {
+				t.Errorf("MergeTemplates() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindPullRequestTemplateWithMergeRepoOnly(t *testing.T) {
+	const content = "## Summary\n\n<!-- what changed -->\n\n## Testing\n"
+	root := t.TempDir()
+	writeRepoTemplate(t, root, content)
+	withOrgTemplates(t, nil)
+
+	old, err := FindPullRequestTemplate(context.Background(), root, "token", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old == nil || old.Content != content {
+		t.Fatalf("FindPullRequestTemplate() = %+v, want the repository template", old)
+	}
+
+	for _, mode := range []string{"", TemplateMergeRepo, TemplateMergeOrg, TemplateMergeBoth} {
+		got, timedOut, err := FindPullRequestTemplateWithMerge(context.Background(), root, "token", "acme", TemplateOptions{Mode: mode})
+		if err != nil {
+			t.Fatalf("mode %q: %v", mode, err)
+		}
+		if timedOut {
+			t.Errorf("mode %q: timed out", mode)
+		}
+		if got == nil || got.Source != old.Source || got.Path != old.Path || got.Content != old.Content || len(got.Parts) != 0 {
+			t.Errorf("mode %q: got %+v, want %+v", mode, got, old)
+		}
+	}
+}
+
+func TestFindPullRequestTemplateWithMergeOrgOnly(t *testing.T) {
+	const content = "## Why\n\n## How\n"
+	withOrgTemplates(t, map[string]string{".github/PULL_REQUEST_TEMPLATE.md": content})
+
+	for _, mode := range []string{TemplateMergeRepo, TemplateMergeOrg, TemplateMergeBoth} {
+		got, _, err := FindPullRequestTemplateWithMerge(context.Background(), t.TempDir(), "token", "acme", TemplateOptions{Mode: mode})
+		if err != nil {
+			t.Fatalf("mode %q: %v", mode, err)
+		}
+		if got == nil || got.Source != "org" || got.Path != ".github/PULL_REQUEST_TEMPLATE.md" || got.Content != content {
+			t.Errorf("mode %q: got %+v, want the org template", mode, got)
+		}
+	}
+
+	got, _, err := FindPullRequestTemplateWithMerge(context.Background(), t.TempDir(), "token", "acme", TemplateOptions{Mode: TemplateMergeBoth, SkipOrg: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Errorf("SkipOrg: got %+v, want no template", got)
+	}
+}
+
+func TestFindPullRequestTemplateWithMergeBoth(t *testing.T) {
+	root := t.TempDir()
+	writeRepoTemplate(t, root, "## summary\n\nRepo notes.\n\n## Testing\n\n- [ ] unit tests\n")
+	withOrgTemplates(t, map[string]string{"PULL_REQUEST_TEMPLATE.md": "## Summary\n\nOrg notes.\n\n## Security\n"})
+
+	got, _, err := FindPullRequestTemplateWithMerge(context.Background(), root, "token", "acme", TemplateOptions{Mode: TemplateMergeBoth})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil {
+		t.Fatal("got no template")
+	}
+
+	want := "## Summary\nOrg notes.\n\nRepo notes.\n\n## Security\n\n## Testing\n- [ ] unit tests\n"
+	if got.Content != want {
+		t.Errorf("Content = %q, want %q", got.Content, want)
+	}
+	if got.Source != "org+repo" || len(got.Parts) != 2 || got.Parts[0].Source != "org" || got.Parts[1].Source != "repo" {
+		t.Errorf("got %+v, want the org part followed by the repo part", got)
+	}
+	if desc := got.Describe(); desc != "org template: PULL_REQUEST_TEMPLATE.md + repo template: .github/pull_request_template.md" {
+		t.Errorf("Describe() = %q", desc)
+	}
+
+	// In org mode the org template wins outright.
+	got, _, err = FindPullRequestTemplateWithMerge(context.Background(), root, "token", "acme", TemplateOptions{Mode: TemplateMergeOrg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.Source != "org" {
+		t.Errorf("org mode: got %+v, want the org template", got)
+	}
+}
+
+func TestFindPullRequestTemplateWithMergeUnknownMode(t *testing.T) {
+	if _, _, err := FindPullRequestTemplateWithMerge(context.Background(), t.TempDir(), "", "", TemplateOptions{Mode: "neither"}); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
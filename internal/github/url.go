@@ -0,0 +1,125 @@
+package github
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var urlRegex = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+`)
+
+// pullRequestPathRegex matches pull request paths of GitHub (/pull/N and the
+// API's /pulls/N) and GitLab (/-/merge_requests/N).
+var pullRequestPathRegex = regexp.MustCompile(`^/[^/]+/[^/]+(?:/[^/]+)*?/(?:-/)?(?:pull|pulls|merge_requests)/(\d+)/?$`)
+
+// FindPullRequestURL returns the pull request URL in command output such as
+// gh pr create's. URLs on host are preferred, and URLs that are not shaped
+// like a pull request (documentation links, fork guidance) are ignored. host
+// may be empty to accept any host.
+func FindPullRequestURL(output, host string) (string, int) {
+	var fallbackURL string
+	var fallbackNumber int
+	for _, candidate := range urlRegex.FindAllString(output, -1) {
+		candidate = strings.TrimRight(candidate, ".,;:!?)]}>")
+		number := PullNumberFromURL(candidate)
+		if number == 0 {
+			continue
+		}
+		parsed, _ := url.Parse(candidate)
+		if host == "" || strings.EqualFold(parsed.Hostname(), host) {
+			return candidate, number
+		}
+		if fallbackURL == "" {
+			fallbackURL, fallbackNumber = candidate, number
+		}
+	}
+	return fallbackURL, fallbackNumber
+}
+
+// PullNumberFromURL returns the number of a pull request or merge request
+// URL, or 0 if the URL does not point at one.
+func PullNumberFromURL(prURL string) int {
+	parsed, err := url.Parse(prURL)
+	if err != nil {
+		return 0
+	}
+	matches := pullRequestPathRegex.FindStringSubmatch(parsed.Path)
+	if matches == nil {
+		return 0
+	}
+	number, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0
+	}
+	return number
+}
+
+// HostFromRemoteURL returns the host of a git remote URL, or "" for a local
+// path.
+func HostFromRemoteURL(remoteURL string) string {
+	host, _, _ := splitRemoteURL(remoteURL)
+	return host
+}
+
+// RepoInfoFromRemoteURL returns the owner and name of the repository a git
+// remote URL points at, or nil when it does not name one. In nested paths
+// such as GitLab subgroups the owner is the whole group path.
+func RepoInfoFromRemoteURL(remoteURL string) (*RepoInfo, error) {
+	_, path, ok := splitRemoteURL(remoteURL)
+	if !ok {
+		return nil, nil
+	}
+	return repoInfoFromPath(path), nil
+}
+
+// splitRemoteURL returns the host and repository path of a remote in URL
+// form (https://, ssh://git@host:2222/, git://) or scp form
+// (git@host:owner/repo.git, host:owner/repo). As in git, a colon is only
+// the scp form when no slash comes before it; local paths and file:// URLs
+// have no host and report false.
+func splitRemoteURL(remoteURL string) (host, path string, ok bool) {
+	remoteURL = strings.TrimSpace(remoteURL)
+	if strings.Contains(remoteURL, "://") {
+		parsed, err := url.Parse(remoteURL)
+		if err != nil || parsed.Hostname() == "" {
+			return "", "", false
+		}
+		return parsed.Hostname(), parsed.Path, true
+	}
+
+	colon := strings.Index(remoteURL, ":")
+	if colon <= 0 || strings.Contains(remoteURL[:colon], "/") {
+		return "", "", false
+	}
+	host = remoteURL[:colon]
+	if at := strings.LastIndex(host, "@"); at != -1 {
+		host = host[at+1:]
+	}
+	if host == "" {
+		return "", "", false
+	}
+	return host, remoteURL[colon+1:], true
+}
+
+// repoInfoFromPath splits a repository path into owner and name: the name is
+// the last element without .git, the owner everything before it. Paths with
+// empty, "." or ".." elements name no repository.
+func repoInfoFromPath(path string) *RepoInfo {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	path = strings.TrimSuffix(path, ".git")
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 {
+		return nil
+	}
+	for _, part := range parts {
+		if part == "" || part == "." || part == ".." || strings.TrimSpace(part) != part {
+			return nil
+		}
+	}
+
+	return &RepoInfo{
+		Owner: strings.Join(parts[:len(parts)-1], "/"),
+		Name:  parts[len(parts)-1],
+	}
+}
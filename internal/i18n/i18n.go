@@ -0,0 +1,126 @@
+// Package i18n translates gelf's own interface text. Messages are looked up
+// by ID in catalogs embedded from locales/*.json; an ID missing from the
+// selected catalog falls back to English.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguage is the catalog every other catalog falls back to.
+const DefaultLanguage = "en"
+
+//go:embed locales/*.json
+var locales embed.FS
+
+var (
+	mu       sync.RWMutex
+	current  = DefaultLanguage
+	catalogs = mustLoadCatalogs()
+)
+
+// aliases maps language names accepted in configuration, in the spelling
+// used for the language setting, to catalog codes.
+var aliases = map[string]string{
+	"english":  "en",
+	"japanese": "ja",
+}
+
+func mustLoadCatalogs() map[string]map[string]string {
+	entries, err := locales.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	loaded := make(map[string]map[string]string, len(entries))
+	for _, entry := range entries {
+		data, err := locales.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var catalog map[string]string
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			panic(fmt.Sprintf("invalid catalog %s: %v", entry.Name(), err))
+		}
+		loaded[strings.TrimSuffix(entry.Name(), ".json")] = catalog
+	}
+	return loaded
+}
+
+// Resolve returns the catalog code for a configured language, falling back
+// to the locale environment (LC_ALL, LC_MESSAGES, LANG) when configured is
+// empty and to English when nothing matches a catalog.
+func Resolve(configured string) string {
+	candidates := []string{configured}
+	if strings.TrimSpace(configured) == "" {
+		candidates = []string{os.Getenv("LC_ALL"), os.Getenv("LC_MESSAGES"), os.Getenv("LANG")}
+	}
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if code := normalize(candidate); code != "" {
+			return code
+		}
+		// The first variable that is set decides, as in setlocale.
+		break
+	}
+	return DefaultLanguage
+}
+
+// normalize turns "ja", "ja_JP.UTF-8" or "Japanese" into a catalog code, or
+// returns "" if there is no catalog for it.
+func normalize(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if code, ok := aliases[language]; ok {
+		language = code
+	}
+	language, _, _ = strings.Cut(language, ".")
+	language, _, _ = strings.Cut(language, "_")
+	language, _, _ = strings.Cut(language, "-")
+	if _, ok := catalogs[language]; ok {
+		return language
+	}
+	return ""
+}
+
+// SetLanguage selects the catalog used by T. Unknown codes select English.
+func SetLanguage(code string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := catalogs[code]; !ok {
+		code = DefaultLanguage
+	}
+	current = code
+}
+
+// Language returns the selected catalog code.
+func Language() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// T returns the message id in the selected language, formatted with args
+// like fmt.Sprintf. Unknown IDs are returned as-is so a missing entry is
+// visible instead of blank.
+func T(id string, args ...any) string {
+	mu.RLock()
+	message, ok := catalogs[current][id]
+	mu.RUnlock()
+	if !ok {
+		message, ok = catalogs[DefaultLanguage][id]
+	}
+	if !ok {
+		message = id
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
@@ -0,0 +1,107 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// verbRegex matches a fmt verb such as %s, %d or %[2]v; %% is matched so
+// that it is not read as the start of another verb.
+var verbRegex = regexp.MustCompile(`%(?:%|(?:\[\d+\])?[-+# 0]*\d*(?:\.\d*)?[a-zA-Z])`)
+
+// verbs returns the fmt verbs of message in order, without %%.
+func verbs(message string) []string {
+	var found []string
+	for _, verb := range verbRegex.FindAllString(message, -1) {
+		if verb != "%%" {
+			found = append(found, verb)
+		}
+	}
+	return found
+}
+
+func TestCatalogsMatchEnglish(t *testing.T) {
+	english := catalogs[DefaultLanguage]
+	if len(english) == 0 {
+		t.Fatal("the English catalog is empty")
+	}
+	for code, catalog := range catalogs {
+		if code == DefaultLanguage {
+			continue
+		}
+		for id, message := range english {
+			translated, ok := catalog[id]
+			if !ok {
+				t.Errorf("%s: missing %q", code, id)
+				continue
+			}
+			if want, got := verbs(message), verbs(translated); !slices.Equal(got, want) {
+				t.Errorf("%s: %q has verbs %q, want %q like English", code, id, got, want)
+			}
+		}
+		for id := range catalog {
+			if _, ok := english[id]; !ok {
+				t.Errorf("%s: %q is not in the English catalog", code, id)
+			}
+		}
+	}
+}
+
+func TestVerbs(t *testing.T) {
+	tests := map[string]string{
+		"✗ Error: %v":            "%v",
+		"%s — lines %d-%d of %d": "%s %d %d %d",
+		"100%% done in %.1fs":    "%.1f",
+		"%[2]s then %[1]s":       "%[2]s %[1]s",
+		"no verbs":               "",
+		"(+%d/-%d) %-10s% 5d":    "%d %d %-10s % 5d",
+	}
+	for message, want := range tests {
+		if got := strings.Join(verbs(message), " "); got != want {
+			t.Errorf("verbs(%q) = %q, want %q", message, got, want)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	t.Cleanup(func() { SetLanguage(DefaultLanguage) })
+
+	SetLanguage("ja")
+	if got := T("error", "boom"); got != "✗ エラー: boom" {
+		t.Errorf("T(error) = %q", got)
+	}
+	if got := T("no.such.id"); got != "no.such.id" {
+		t.Errorf("unknown ID = %q, want the ID", got)
+	}
+
+	SetLanguage("fr")
+	if Language() != DefaultLanguage {
+		t.Errorf("Language() = %q after selecting a language without a catalog", Language())
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		configured string
+		lang       string
+		want       string
+	}{
+		{"japanese", "", "ja"},
+		{"Japanese", "", "ja"},
+		{"ja_JP.UTF-8", "", "ja"},
+		{"english", "ja_JP.UTF-8", "en"},
+		{"", "ja_JP.UTF-8", "ja"},
+		{"", "fr_FR.UTF-8", "en"},
+		{"", "", "en"},
+	}
+	for _, tt := range tests {
+		t.Setenv("LC_ALL", "")
+		t.Setenv("LC_MESSAGES", "")
+		t.Setenv("LANG", tt.lang)
+		if got := Resolve(tt.configured); got != tt.want {
+			t.Errorf("Resolve(%q) with LANG=%q = %q, want %q", tt.configured, tt.lang, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Package instructions loads the standing instructions that are added to
+// every prompt: a global file in the gelf config directory and a file in the
+// repository.
+package instructions
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxFileSize is the number of bytes kept from each instructions file.
+const MaxFileSize = 8 * 1024
+
+// RepoFiles are the repository instruction files, in order of preference;
+// only the first one found is used.
+var RepoFiles = []string{".gelf/instructions.md", "GELF.md"}
+
+// File is a loaded instructions file.
+type File struct {
+	Path      string
+	Scope     string // "global" or "repo"
+	Content   string
+	Truncated bool
+}
+
+// Load returns the global and repository instructions that exist, global
+// first. repoRoot may be empty outside a repository.
+func Load(repoRoot string) ([]File, error) {
+	var files []File
+
+	if dir := globalDir(); dir != "" {
+		file, err := readFile(filepath.Join(dir, "instructions.md"), "global")
+		if err != nil {
+			return nil, err
+		}
+		if file != nil {
+			files = append(files, *file)
+		}
+	}
+
+	if repoRoot != "" {
+		for _, name := range RepoFiles {
+			file, err := readFile(filepath.Join(repoRoot, name), "repo")
+			if err != nil {
+				return nil, err
+			}
+			if file != nil {
+				files = append(files, *file)
+				break
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// Format renders files as delimited blocks for a prompt, global first so
+// that repository instructions come last and take precedence. It returns ""
+// when there are no instructions.
+func Format(files []File) string {
+	var blocks []string
+	for _, file := range files {
+		if strings.TrimSpace(file.Content) == "" {
+			continue
+		}
+		blocks = append(blocks, fmt.Sprintf("<<<BEGIN %s INSTRUCTIONS>>>\n%s\n<<<END %s INSTRUCTIONS>>>",
+			strings.ToUpper(file.Scope), strings.TrimSpace(file.Content), strings.ToUpper(file.Scope)))
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+func globalDir() string {
+	if xdgConfigHome := os.Getenv("XDG_CONFIG_HOME"); xdgConfigHome != "" {
+		return filepath.Join(xdgConfigHome, "gelf")
+	}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(homeDir, ".config", "gelf")
+	}
+	return ""
+}
+
+func readFile(path, scope string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read instructions %s: %w", path, err)
+	}
+
+	file := &File{Path: path, Scope: scope}
+	if len(data) > MaxFileSize {
+		data = data[:MaxFileSize]
+		for !utf8.Valid(data) {
+			data = data[:len(data)-1]
+		}
+		file.Truncated = true
+	}
+	file.Content = string(data)
+	return file, nil
+}
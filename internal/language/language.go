@@ -0,0 +1,155 @@
+// Package language maps the language values gelf accepts for generated text
+// (--language, language: in gelf.yml) to canonical English names, so every
+// prompt names a language the same way.
+package language
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// known lists the canonical names with the other spellings accepted for
+// them: ISO 639-1 codes and the languages' own names. Matching ignores case.
+var known = []struct {
+	name    string
+	aliases []string
+}{
+	{"English", []string{"en", "eng"}},
+	{"Japanese", []string{"ja", "jp", "jpn", "日本語"}},
+	{"Chinese", []string{"zh", "zho", "中文", "chinese simplified", "simplified chinese", "zh-cn", "zh-hans"}},
+	{"Traditional Chinese", []string{"chinese traditional", "zh-tw", "zh-hk", "zh-hant", "繁體中文"}},
+	{"Korean", []string{"ko", "kor", "한국어"}},
+	{"Spanish", []string{"es", "spa", "español", "espanol"}},
+	{"French", []string{"fr", "fra", "français", "francais"}},
+	{"German", []string{"de", "deu", "ger", "deutsch"}},
+	{"Italian", []string{"it", "ita", "italiano"}},
+	{"Portuguese", []string{"pt", "por", "português", "portugues"}},
+	{"Brazilian Portuguese", []string{"pt-br", "portuguese (brazil)", "português do brasil"}},
+	{"Dutch", []string{"nl", "nld", "nederlands"}},
+	{"Russian", []string{"ru", "rus", "русский"}},
+	{"Ukrainian", []string{"uk", "ukr", "українська"}},
+	{"Polish", []string{"pl", "pol", "polski"}},
+	{"Czech", []string{"cs", "ces", "čeština"}},
+	{"Swedish", []string{"sv", "swe", "svenska"}},
+	{"Norwegian", []string{"no", "nb", "nor", "norsk"}},
+	{"Danish", []string{"da", "dan", "dansk"}},
+	{"Finnish", []string{"fi", "fin", "suomi"}},
+	{"Turkish", []string{"tr", "tur", "türkçe", "turkce"}},
+	{"Greek", []string{"el", "ell", "ελληνικά"}},
+	{"Hebrew", []string{"he", "heb", "עברית"}},
+	{"Arabic", []string{"ar", "ara", "العربية"}},
+	{"Hindi", []string{"hi", "hin", "हिन्दी"}},
+	{"Thai", []string{"th", "tha", "ไทย"}},
+	{"Vietnamese", []string{"vi", "vie", "tiếng việt"}},
+	{"Indonesian", []string{"id", "ind", "bahasa indonesia"}},
+	{"Malay", []string{"ms", "msa", "bahasa melayu"}},
+}
+
+// plausibleRegex matches values that could name a language: letters, with
+// spaces, hyphens, apostrophes and parentheses between words.
+var plausibleRegex = regexp.MustCompile(`^\p{L}[\p{L}\p{M} '()-]{0,39}$`)
+
+// Canonical returns the canonical name for value and whether value is one
+// of the known spellings. Locale names such as en_US.UTF-8 are matched by
+// their language code when the region is not a known spelling itself.
+func Canonical(value string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(value))
+	key = strings.ReplaceAll(key, "_", "-")
+	key, _, _ = strings.Cut(key, ".")
+	if name, ok := lookup(key); ok {
+		return name, true
+	}
+	if code, region, ok := strings.Cut(key, "-"); ok && region != "" {
+		return lookup(code)
+	}
+	return "", false
+}
+
+func lookup(key string) (string, bool) {
+	for _, language := range known {
+		if key == strings.ToLower(language.name) {
+			return language.name, true
+		}
+		for _, alias := range language.aliases {
+			if key == alias {
+				return language.name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Normalize returns the canonical name for value. A value that is not a
+// known spelling but could name a language is kept as given, with a warning
+// suggesting the closest known name; with strict it is an error instead.
+// Values that cannot name a language are always an error. setting names the
+// flag or key value came from, for the messages.
+func Normalize(value, setting string, strict bool) (string, string, error) {
+	if name, ok := Canonical(value); ok {
+		return name, "", nil
+	}
+
+	value = strings.TrimSpace(value)
+	problem := fmt.Sprintf("unknown language %q for %s", value, setting)
+	if suggestion := Suggest(value); suggestion != "" {
+		problem += fmt.Sprintf(" (did you mean %s?)", suggestion)
+	}
+	if strict || !plausibleRegex.MatchString(value) {
+		return "", "", fmt.Errorf("%s", problem)
+	}
+	return value, problem + "; passing it to the model as written", nil
+}
+
+// Suggest returns the known name closest to value, or "" when none is close
+// enough to be a likely typo.
+func Suggest(value string) string {
+	key := strings.ToLower(strings.TrimSpace(value))
+	best, bestDistance := "", 0
+	for _, language := range known {
+		for _, spelling := range append([]string{strings.ToLower(language.name)}, language.aliases...) {
+			// Two-letter codes are too short to tell a typo from another
+			// word.
+			if len([]rune(spelling)) < 4 {
+				continue
+			}
+			distance := editDistance(key, spelling)
+			if distance <= maxTypos(spelling) && (best == "" || distance < bestDistance) {
+				best, bestDistance = language.name, distance
+			}
+		}
+	}
+	return best
+}
+
+// maxTypos is how many edits apart a value may be from spelling to still be
+// taken for it.
+func maxTypos(spelling string) int {
+	if len([]rune(spelling)) <= 5 {
+		return 1
+	}
+	return 2
+}
+
+// editDistance returns the Levenshtein distance between a and b, counted in
+// runes.
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	previous := make([]int, len(br)+1)
+	current := make([]int, len(br)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		current[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(br)]
+}
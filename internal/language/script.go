@@ -0,0 +1,66 @@
+package language
+
+import (
+	"regexp"
+	"unicode"
+)
+
+// minScriptShare is the share of letters that must be in a language's
+// script for text to count as written in it. It is low because code
+// identifiers and product names in Latin letters are common in text in
+// any language.
+const minScriptShare = 0.2
+
+// scripts lists the scripts of the known languages not written in the Latin
+// alphabet.
+var scripts = map[string][]*unicode.RangeTable{
+	"Japanese":            {unicode.Hiragana, unicode.Katakana, unicode.Han},
+	"Chinese":             {unicode.Han},
+	"Traditional Chinese": {unicode.Han},
+	"Korean":              {unicode.Hangul},
+	"Russian":             {unicode.Cyrillic},
+	"Ukrainian":           {unicode.Cyrillic},
+	"Greek":               {unicode.Greek},
+	"Hebrew":              {unicode.Hebrew},
+	"Arabic":              {unicode.Arabic},
+	"Hindi":               {unicode.Devanagari},
+	"Thai":                {unicode.Thai},
+}
+
+// ignoredRegex matches what is not prose: code blocks, code spans, HTML
+// comments and URLs.
+var ignoredRegex = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`|<!--.*?-->|https?://\\S+")
+
+// Matches reports whether text looks written in the language name, judged
+// only by the script of its letters: at least a fifth of them must be in
+// the language's script, and for a language written in the Latin alphabet
+// at least half. For English only ASCII letters count. It cannot tell
+// languages sharing a script apart. Code, HTML comments and URLs are left
+// out; text without letters and languages it does not know always match.
+func Matches(text, name string) bool {
+	name, ok := Canonical(name)
+	if !ok {
+		return true
+	}
+
+	inScript := func(r rune) bool { return unicode.Is(unicode.Latin, r) }
+	share := 0.5
+	if tables, ok := scripts[name]; ok {
+		inScript = func(r rune) bool { return unicode.IsOneOf(tables, r) }
+		share = minScriptShare
+	} else if name == "English" {
+		inScript = func(r rune) bool { return r <= unicode.MaxASCII }
+	}
+
+	letters, matching := 0, 0
+	for _, r := range ignoredRegex.ReplaceAllString(text, " ") {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if inScript(r) {
+			matching++
+		}
+	}
+	return letters == 0 || float64(matching) >= share*float64(letters)
+}
@@ -0,0 +1,42 @@
+package language
+
+import "testing"
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		language string
+		want     bool
+	}{
+		{"japanese", "ログインのタイムアウトを修正", "Japanese", true},
+		{"japanese by code", "ログインのタイムアウトを修正", "ja", true},
+		{"japanese by locale", "ログインのタイムアウトを修正", "ja_JP.UTF-8", true},
+		{"japanese with identifiers", "GetRepoPaths と ForgetRepoPaths のキャッシュを修正", "Japanese", true},
+		{"english for japanese", "fix the login timeout", "Japanese", false},
+		{"japanese for english", "ログインのタイムアウトを修正", "English", false},
+		{"english", "fix the login timeout", "English", true},
+		{"english with a name", "credit Zoë for the login fix", "English", true},
+		// Languages sharing a script cannot be told apart.
+		{"french for english", "corrige le délai de connexion à l'écran", "English", true},
+		{"french", "corrige le délai de connexion à l'écran", "French", true},
+		{"japanese for french", "ログインのタイムアウトを修正", "French", false},
+		{"korean", "로그인 시간 초과 수정", "Korean", true},
+		{"russian", "исправить тайм-аут входа", "Russian", true},
+		{"russian for ukrainian", "исправить тайм-аут входа", "Ukrainian", true},
+		{"code block ignored", "修正しました\n\n```go\nfunc fixLoginTimeoutInTheSessionHandler() {}\n```", "Japanese", true},
+		{"code span ignored", "`fixLoginTimeoutInTheSessionHandler` を修正", "Japanese", true},
+		{"url ignored", "詳細 https://example.com/a/very/long/path/about/login/timeouts", "Japanese", true},
+		{"comment ignored", "<!-- generated by the release tooling for this project -->修正", "Japanese", true},
+		{"no letters", "1234 !?", "Japanese", true},
+		{"empty", "", "English", true},
+		{"unknown language", "fix the login timeout", "Klingon", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Matches(tt.text, tt.language); got != tt.want {
+				t.Errorf("Matches(%q, %q) = %v, want %v", tt.text, tt.language, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Package lock implements the advisory lock file that keeps concurrent gelf
+// processes from interleaving mutating git and gh operations in one
+// repository.
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// pollInterval is how often Wait retries a held lock.
+const pollInterval = 200 * time.Millisecond
+
+// errLocked is returned by tryLock when another process holds the lock.
+var errLocked = errors.New("locked")
+
+// Lock is a held lock file.
+type Lock struct {
+	file *os.File
+}
+
+// owner is the content of the lock file.
+type owner struct {
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+}
+
+// HeldError reports that another live process holds the lock.
+type HeldError struct {
+	PID     int
+	Started time.Time
+}
+
+func (e *HeldError) Error() string {
+	if e.PID <= 0 {
+		return "another gelf operation is in progress"
+	}
+	return fmt.Sprintf("another gelf operation is in progress (pid %d, started %s ago)", e.PID, time.Since(e.Started).Round(time.Second))
+}
+
+// Acquire takes the lock at path; a live holder is reported as *HeldError.
+// The lock is an OS file lock on the open file, so the system releases it
+// when its process exits and a lock left behind by a crash never needs to
+// be taken over. The file itself stays in place: removing it would let a
+// process lock a new file while another still holds the old one.
+func Acquire(path string) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", path, err)
+	}
+	if err := tryLock(file); err != nil {
+		current := readOwner(file)
+		file.Close()
+		if errors.Is(err, errLocked) {
+			return nil, &HeldError{PID: current.PID, Started: current.Started}
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	// The owner is only recorded for the message other processes show.
+	data, err := json.Marshal(owner{PID: os.Getpid(), Started: time.Now()})
+	if err == nil && file.Truncate(0) == nil {
+		_, _ = file.WriteAt(data, 0)
+	}
+	return &Lock{file: file}, nil
+}
+
+// Wait is like Acquire but blocks while the lock is held by another process.
+// onWait is called once, with the holder, before the first wait.
+func Wait(path string, onWait func(*HeldError)) (*Lock, error) {
+	notified := false
+	for {
+		l, err := Acquire(path)
+		var held *HeldError
+		if !errors.As(err, &held) {
+			return l, err
+		}
+		if !notified && onWait != nil {
+			onWait(held)
+			notified = true
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Release clears the owner and unlocks the lock file.
+func (l *Lock) Release() error {
+	_ = l.file.Truncate(0)
+	err := unlock(l.file)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to release lock file %s: %w", l.file.Name(), err)
+	}
+	return nil
+}
+
+// readOwner returns the recorded holder of file, or no holder when it has
+// not been written yet.
+func readOwner(file *os.File) owner {
+	data, err := io.ReadAll(io.NewSectionReader(file, 0, 4096))
+	if err != nil {
+		return owner{}
+	}
+	var current owner
+	if json.Unmarshal(data, &current) != nil {
+		return owner{}
+	}
+	return current
+}
@@ -0,0 +1,53 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcquireHeld(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gelf.lock")
+
+	first, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	_, err = Acquire(path)
+	var held *HeldError
+	if !errors.As(err, &held) {
+		t.Fatalf("second Acquire = %v, want *HeldError", err)
+	}
+	if held.PID != os.Getpid() {
+		t.Errorf("HeldError.PID = %d, want %d", held.PID, os.Getpid())
+	}
+
+	if err := first.Release(); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	// Release leaves the file in place; the lock is free again.
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("lock file after Release: %v", err)
+	}
+	second, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire after Release: %v", err)
+	}
+	if err := second.Release(); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+}
+
+func TestAcquireIgnoresLeftoverOwner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gelf.lock")
+	// A crashed holder leaves its record, but not its lock, behind.
+	if err := os.WriteFile(path, []byte(`{"pid":999999,"started":"2020-01-01T00:00:00Z"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	defer l.Release()
+}
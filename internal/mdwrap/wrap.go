@@ -0,0 +1,130 @@
+// Package mdwrap breaks long lines of generated markdown and commit bodies
+// at a column without changing what the markdown means.
+package mdwrap
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/textwidth"
+)
+
+var (
+	// listMarkerRegex matches a list item marker and an optional task box;
+	// continuation lines are indented to the end of the match.
+	listMarkerRegex = regexp.MustCompile(`^[ \t]*(?:[-*+]|\d{1,9}[.)])[ \t]+(?:\[[ xX]\][ \t]+)?`)
+	quoteRegex      = regexp.MustCompile(`^[ \t]*(?:>[ \t]?)+`)
+	linkDefRegex    = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:`)
+	blockStartRegex = regexp.MustCompile(`^(?:[-*+>=]+|#{1,6}|\d{1,9}[.)])$`)
+)
+
+// Wrap breaks lines of text wider than width columns at spaces; full-width
+// characters take two columns. Lines are only split, never joined, so
+// existing line breaks stay. Code fences, indented code, tables, headings,
+// link definitions and HTML lines are left as they are, and a word wider
+// than width, such as a URL, is kept whole on its own line. List items and
+// block quotes continue under their content. A width of 0 or less returns
+// text unchanged.
+func Wrap(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	fence := ""
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if closesFence(trimmed, fence) {
+				fence = ""
+			}
+			out = append(out, line)
+			continue
+		}
+		if marker := fenceMarker(trimmed); marker != "" {
+			fence = marker
+			out = append(out, line)
+			continue
+		}
+		if textwidth.Width(line) <= width || !wrappable(line, trimmed) {
+			out = append(out, line)
+			continue
+		}
+		out = append(out, wrapLine(line, width)...)
+	}
+	return strings.Join(out, "\n")
+}
+
+// fenceMarker returns the run of three or more backticks or tildes opening
+// a code fence on trimmed, or "".
+func fenceMarker(trimmed string) string {
+	if trimmed == "" || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return ""
+	}
+	n := len(trimmed) - len(strings.TrimLeft(trimmed, trimmed[:1]))
+	if n < 3 {
+		return ""
+	}
+	return trimmed[:n]
+}
+
+// closesFence reports whether trimmed closes the code fence opened with
+// fence: a run of the same character at least as long, and nothing else.
+func closesFence(trimmed, fence string) bool {
+	run := fenceMarker(trimmed)
+	return run != "" && run[0] == fence[0] && len(run) >= len(fence) && strings.TrimSpace(trimmed[len(run):]) == ""
+}
+
+// wrappable reports whether line is prose that can be split.
+func wrappable(line, trimmed string) bool {
+	switch {
+	case strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "    ") && !listMarkerRegex.MatchString(line):
+		return false // indented code
+	case strings.HasPrefix(trimmed, "|"), strings.HasPrefix(trimmed, "#"), strings.HasPrefix(trimmed, "<"):
+		return false
+	case linkDefRegex.MatchString(line):
+		return false
+	}
+	return true
+}
+
+// wrapLine splits a single long line into lines of at most width, prefixing
+// continuation lines so they stay in the same list item or quote.
+func wrapLine(line string, width int) []string {
+	prefix := quoteRegex.FindString(line)
+	rest := line[len(prefix):]
+	continuation := prefix
+	if marker := listMarkerRegex.FindString(rest); marker != "" {
+		prefix += marker
+		rest = rest[len(marker):]
+		continuation += strings.Repeat(" ", textwidth.Width(marker))
+	}
+
+	var lines []string
+	current := prefix
+	empty := true
+	for _, word := range strings.Fields(rest) {
+		if !empty && textwidth.Width(current)+1+textwidth.Width(word) > width && !startsBlock(word) {
+			lines = append(lines, current)
+			current = continuation
+			empty = true
+		}
+		if !empty {
+			current += " "
+		}
+		current += word
+		empty = false
+	}
+	// Keep a trailing hard line break ("  ") on the last piece.
+	if strings.HasSuffix(rest, "  ") {
+		current += "  "
+	}
+	return append(lines, current)
+}
+
+// startsBlock reports whether word would turn a continuation line into a
+// list item, quote, heading or setext underline, so no break goes before it.
+func startsBlock(word string) bool {
+	return blockStartRegex.MatchString(word)
+}
@@ -0,0 +1,131 @@
+package mdwrap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	long := "Retry model calls that fail with a rate limit or a server error before giving up."
+	url := "https://github.com/EkeMinusYou/gelf/blob/main/internal/ai/retry.go#L68-L92"
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  string
+	}{
+		{
+			name:  "prose",
+			text:  long,
+			width: 40,
+			want:  "Retry model calls that fail with a rate\nlimit or a server error before giving\nup.",
+		},
+		{
+			name:  "zero width",
+			text:  long,
+			width: 0,
+			want:  long,
+		},
+		{
+			name:  "long url alone",
+			text:  url,
+			width: 40,
+			want:  url,
+		},
+		{
+			name:  "long url in prose",
+			text:  "See " + url + " for the loop.",
+			width: 40,
+			want:  "See\n" + url + "\nfor the loop.",
+		},
+		{
+			name:  "markdown link",
+			text:  "- See [the retry loop](" + url + ")",
+			width: 40,
+			want:  "- See [the retry\n  loop](" + url + ")",
+		},
+		{
+			name:  "fenced code",
+			text:  "```sh\n" + long + "\n```\n" + long,
+			width: 40,
+			want:  "```sh\n" + long + "\n```\nRetry model calls that fail with a rate\nlimit or a server error before giving\nup.",
+		},
+		{
+			name:  "tilde fence holding backticks",
+			text:  "~~~md\n```\n" + long + "\n~~~",
+			width: 40,
+			want:  "~~~md\n```\n" + long + "\n~~~",
+		},
+		{
+			name:  "shorter run does not close a fence",
+			text:  "````md\n```go\n```\n" + long + "\n````\n" + long,
+			width: 40,
+			want:  "````md\n```go\n```\n" + long + "\n````\nRetry model calls that fail with a rate\nlimit or a server error before giving\nup.",
+		},
+		{
+			name:  "fence with an info string does not close",
+			text:  "```\n``` not a close\n" + long + "\n```",
+			width: 40,
+			want:  "```\n``` not a close\n" + long + "\n```",
+		},
+		{
+			name:  "list item",
+			text:  "- " + long,
+			width: 40,
+			want:  "- Retry model calls that fail with a\n  rate limit or a server error before\n  giving up.",
+		},
+		{
+			name:  "quote",
+			text:  "> " + long,
+			width: 40,
+			want:  "> Retry model calls that fail with a\n> rate limit or a server error before\n> giving up.",
+		},
+		{
+			name:  "heading and table",
+			text:  "## " + long + "\n| " + long + " |",
+			width: 40,
+			want:  "## " + long + "\n| " + long + " |",
+		},
+		{
+			name:  "indented code",
+			text:  "    " + long,
+			width: 40,
+			want:  "    " + long,
+		},
+		{
+			name:  "no break before a list marker",
+			text:  "Counts go from one to two - then stop at three",
+			width: 26,
+			want:  "Counts go from one to two -\nthen stop at three",
+		},
+		{
+			name:  "full-width text",
+			text:  "再試行 は レート制限 と サーバー エラー に 限る",
+			width: 20,
+			want:  "再試行 は レート制限\nと サーバー エラー\nに 限る",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Wrap(tt.text, tt.width)
+			if got != tt.want {
+				t.Errorf("Wrap() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if words(got) != words(tt.text) {
+				t.Errorf("Wrap() changed the words of the text")
+			}
+		})
+	}
+}
+
+// words returns the words of text without the quote markers that Wrap
+// repeats on continuation lines.
+func words(text string) string {
+	var words []string
+	for _, word := range strings.Fields(text) {
+		if word != ">" {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
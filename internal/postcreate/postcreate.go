@@ -0,0 +1,128 @@
+// Package postcreate runs the pr.post_create commands after a pull request is
+// created.
+package postcreate
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/timing"
+)
+
+// PullRequest holds the values available to post-create commands.
+type PullRequest struct {
+	URL    string
+	Number int
+	Title  string
+	Branch string
+}
+
+// placeholders maps each placeholder to the environment variable carrying its
+// value.
+var placeholders = []struct {
+	Name string
+	Env  string
+}{
+	{"{{url}}", "GELF_PR_URL"},
+	{"{{number}}", "GELF_PR_NUMBER"},
+	{"{{title}}", "GELF_PR_TITLE"},
+	{"{{branch}}", "GELF_PR_BRANCH"},
+}
+
+// Command builds the shell command for a configured command line. Values are
+// never pasted into the script: each placeholder becomes a reference to an
+// environment variable, quoted for where it stands, so a title such as
+// "$(rm -rf ~)" reaches the command as literal text. A placeholder inside
+// single quotes closes and reopens them around the reference, and one inside
+// double quotes becomes a plain ${...} reference, so placeholders expand
+// whether or not the line quotes them.
+func Command(line string, pr PullRequest) *exec.Cmd {
+	values := map[string]string{
+		"GELF_PR_URL":    pr.URL,
+		"GELF_PR_NUMBER": strconv.Itoa(pr.Number),
+		"GELF_PR_TITLE":  pr.Title,
+		"GELF_PR_BRANCH": pr.Branch,
+	}
+
+	env := os.Environ()
+	for _, placeholder := range placeholders {
+		env = append(env, placeholder.Env+"="+values[placeholder.Env])
+	}
+
+	cmd := exec.Command("sh", "-c", substitute(line))
+	cmd.Env = env
+	return cmd
+}
+
+// substitute replaces the placeholders of line with references to their
+// environment variables, following the sh quoting of line to know whether
+// each stands unquoted, in single quotes or in double quotes.
+func substitute(line string) string {
+	var script strings.Builder
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		if env, name := placeholderAt(line[i:]); env != "" {
+			switch quote {
+			case '\'':
+				script.WriteString(`'"$` + env + `"'`)
+			case '"':
+				script.WriteString("${" + env + "}")
+			default:
+				script.WriteString(`"$` + env + `"`)
+			}
+			i += len(name) - 1
+			continue
+		}
+
+		c := line[i]
+		script.WriteByte(c)
+		switch {
+		case c == '\\' && quote != '\'' && i+1 < len(line):
+			// An escaped character, quote or not, is copied as it is.
+			i++
+			script.WriteByte(line[i])
+		case (c == '\'' || c == '"') && quote == 0:
+			quote = c
+		case c == quote:
+			quote = 0
+		}
+	}
+	return script.String()
+}
+
+// placeholderAt returns the environment variable and the name of the
+// placeholder text starts with, or "" if it starts with none.
+func placeholderAt(text string) (string, string) {
+	for _, placeholder := range placeholders {
+		if strings.HasPrefix(text, placeholder.Name) {
+			return placeholder.Env, placeholder.Name
+		}
+	}
+	return "", ""
+}
+
+// Run runs each command in order, writing their output to out, and returns
+// one error per command that failed. A failing command does not stop the
+// ones after it.
+func Run(commands []string, pr PullRequest, out io.Writer) []error {
+	var errs []error
+	for _, line := range commands {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		cmd := Command(line, pr)
+		cmd.Stdout = out
+		cmd.Stderr = out
+		stop := timing.Track("post-create")
+		err := cmd.Run()
+		stop()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("post_create %q failed: %w", line, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,49 @@
+package postcreate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommandQuoting(t *testing.T) {
+	pr := PullRequest{
+		URL:    "https://github.com/owner/repo/pull/7",
+		Number: 7,
+		Title:  `Fix "it's" $(echo injected) $HOME`,
+		Branch: "feat/#7-fix",
+	}
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"unquoted", "printf '%s|' {{title}} {{number}}", `Fix "it's" $(echo injected) $HOME|7|`},
+		{"single quotes", "printf '%s|' 'PR {{number}}: {{title}}'", `PR 7: Fix "it's" $(echo injected) $HOME|`},
+		{"double quotes", `printf '%s|' "PR {{number}} on {{branch}}: {{title}}"`, `PR 7 on feat/#7-fix: Fix "it's" $(echo injected) $HOME|`},
+		{"escaped quote", `printf '%s|' \"{{number}}\"`, `"7"|`},
+		{"quote inside the other quotes", `printf '%s|' "it's {{number}}" 'say "{{number}}"'`, `it's 7|say "7"|`},
+		{"environment", `printf '%s|' "$GELF_PR_URL"`, "https://github.com/owner/repo/pull/7|"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := Command(tt.line, pr).Output()
+			if err != nil {
+				t.Fatalf("%s: %v", tt.line, err)
+			}
+			if got := string(output); got != tt.want {
+				t.Errorf("%s printed %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunContinuesAfterFailure(t *testing.T) {
+	var out strings.Builder
+	errs := Run([]string{"exit 3", "", "echo {{number}}"}, PullRequest{Number: 12}, &out)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `"exit 3"`) {
+		t.Errorf("Run() errors = %v, want one for the failing command", errs)
+	}
+	if out.String() != "12\n" {
+		t.Errorf("output = %q, want the later command to run", out.String())
+	}
+}
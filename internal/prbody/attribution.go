@@ -0,0 +1,50 @@
+package prbody
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// attributionLinePattern matches the visible footer line written by
+	// SetAttribution, with the blank lines before it like headMarkerPattern.
+	attributionLinePattern = regexp.MustCompile(`(?m)\n*^[ \t]*<sub>Generated by gelf\b[^\n]*</sub>[ \t]*$`)
+	// promptMarkerPattern matches the comment recording the hash of the
+	// prompt a description was generated from, e.g. <!-- gelf:prompt 1a2b3c4d5e6f -->.
+	promptMarkerPattern = regexp.MustCompile(`(?m)\n*^[ \t]*<!--\s*gelf:prompt\s+([0-9a-f]{12,64})\s*-->[ \t]*$`)
+)
+
+// HashPrompt returns the short hash SetAttribution records for prompt.
+func HashPrompt(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// SetAttribution appends a footer saying the body was generated by gelf
+// version with model, and a comment with the prompt hash, replacing any
+// footer already there.
+func SetAttribution(body, version, model, promptHash string) string {
+	footer := fmt.Sprintf("<sub>Generated by gelf %s (%s)</sub>\n<!-- gelf:prompt %s -->", version, model, promptHash)
+	return StripAttribution(body) + "\n\n" + footer
+}
+
+// StripAttribution removes the footer written by SetAttribution, so a
+// description can be shown to the model again without it.
+func StripAttribution(body string) string {
+	body = attributionLinePattern.ReplaceAllString(body, "")
+	return strings.TrimSpace(promptMarkerPattern.ReplaceAllString(body, ""))
+}
+
+// PromptMarker returns the prompt hash recorded by SetAttribution, or "" if
+// body was not generated by gelf with pr.attribution on. The last marker
+// wins.
+func PromptMarker(body string) string {
+	matches := promptMarkerPattern.FindAllStringSubmatch(body, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	return matches[len(matches)-1][1]
+}
@@ -0,0 +1,126 @@
+package prbody
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// GitHub's limits on pull request titles and bodies, in characters.
+const (
+	MaxTitleLength = 256
+	MaxBodyLength  = 65536
+)
+
+// TruncationMarker replaces content removed to fit MaxBodyLength.
+const TruncationMarker = "…truncated by gelf…"
+
+// ClampTitle shortens title to MaxTitleLength characters and moves the
+// overflow to the first line of body. That line is held to MaxTitleLength
+// characters as well, so a runaway title does not become a runaway first
+// line. It reports whether the title changed.
+func ClampTitle(title, body string) (string, string, bool) {
+	runes := []rune(title)
+	if len(runes) <= MaxTitleLength {
+		return title, body, false
+	}
+
+	cut := MaxTitleLength - 1
+	clamped := strings.TrimRight(string(runes[:cut]), " ") + "…"
+	rest := []rune(strings.TrimSpace(string(runes[cut:])))
+	if len(rest) > MaxTitleLength-2 {
+		rest = append([]rune(strings.TrimRight(string(rest[:MaxTitleLength-2]), " ")), '…')
+	}
+	overflow := "…" + string(rest)
+	if strings.TrimSpace(body) == "" {
+		return clamped, overflow, true
+	}
+	return clamped, overflow + "\n\n" + body, true
+}
+
+// FitBody trims body to at most limit characters. The largest section (text
+// under a markdown heading) is shortened first and marked with
+// TruncationMarker, repeating until the body fits. It reports whether the
+// body was trimmed.
+func FitBody(body string, limit int) (string, bool) {
+	if utf8.RuneCountInString(body) <= limit {
+		return body, false
+	}
+
+	sections := splitSections(body)
+	for {
+		total := 0
+		for _, section := range sections {
+			total += utf8.RuneCountInString(section)
+		}
+		excess := total - limit
+		if excess <= 0 {
+			return strings.Join(sections, ""), true
+		}
+
+		largest := 0
+		for i, section := range sections {
+			if utf8.RuneCountInString(section) > utf8.RuneCountInString(sections[largest]) {
+				largest = i
+			}
+		}
+
+		trimmed := truncateSection(sections[largest], excess)
+		if utf8.RuneCountInString(trimmed) >= utf8.RuneCountInString(sections[largest]) {
+			// Nothing left to trim section by section.
+			return truncateRunes(strings.Join(sections, ""), limit), true
+		}
+		sections[largest] = trimmed
+	}
+}
+
+// truncateSection removes at least excess characters from the end of
+// section, keeping its heading line and cutting at a line boundary.
+func truncateSection(section string, excess int) string {
+	heading, content := "", section
+	if loc := headingRegex.FindStringIndex(section); loc != nil && loc[0] == 0 {
+		if idx := strings.Index(section, "\n"); idx != -1 {
+			heading, content = section[:idx+1], section[idx+1:]
+		} else {
+			heading, content = section, ""
+		}
+	}
+
+	markerLine := "\n" + TruncationMarker + "\n\n"
+	keep := utf8.RuneCountInString(content) - excess - utf8.RuneCountInString(markerLine)
+	if keep < 0 {
+		keep = 0
+	}
+
+	kept := truncateRunes(content, keep)
+	if idx := strings.LastIndex(kept, "\n"); idx != -1 {
+		kept = kept[:idx+1]
+	}
+	return heading + strings.TrimRight(kept, "\n") + markerLine
+}
+
+// splitSections splits body before each markdown heading outside code fences.
+// Concatenating the result yields body.
+func splitSections(body string) []string {
+	var sections []string
+	var current strings.Builder
+	var fence codeFence
+	for _, line := range strings.SplitAfter(body, "\n") {
+		if !fence.line(strings.TrimSpace(line)) && headingRegex.MatchString(line) && current.Len() > 0 {
+			sections = append(sections, current.String())
+			current.Reset()
+		}
+		current.WriteString(line)
+	}
+	if current.Len() > 0 {
+		sections = append(sections, current.String())
+	}
+	return sections
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
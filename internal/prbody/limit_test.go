@@ -0,0 +1,157 @@
+package prbody
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestClampTitle(t *testing.T) {
+	tests := []struct {
+		name        string
+		title       string
+		body        string
+		wantTitle   string
+		wantBody    string
+		wantClamped bool
+	}{
+		{
+			name:      "multibyte title at the limit",
+			title:     strings.Repeat("再", MaxTitleLength),
+			body:      "本文",
+			wantTitle: strings.Repeat("再", MaxTitleLength),
+			wantBody:  "本文",
+		},
+		{
+			name:        "multibyte title one over the limit",
+			title:       strings.Repeat("再", MaxTitleLength+1),
+			body:        "本文",
+			wantTitle:   strings.Repeat("再", MaxTitleLength-1) + "…",
+			wantBody:    "…再再\n\n本文",
+			wantClamped: true,
+		},
+		{
+			name:        "overflow longer than two titles",
+			title:       strings.Repeat("a", 3*MaxTitleLength),
+			body:        "Body.",
+			wantTitle:   strings.Repeat("a", MaxTitleLength-1) + "…",
+			wantBody:    "…" + strings.Repeat("a", MaxTitleLength-2) + "…\n\nBody.",
+			wantClamped: true,
+		},
+		{
+			name:        "empty body",
+			title:       strings.Repeat("word ", 60),
+			body:        "  \n",
+			wantTitle:   strings.TrimRight(strings.Repeat("word ", 51), " ") + "…",
+			wantBody:    "…" + strings.TrimSpace(strings.Repeat("word ", 60)[255:]),
+			wantClamped: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, body, clamped := ClampTitle(tt.title, tt.body)
+			if title != tt.wantTitle || body != tt.wantBody || clamped != tt.wantClamped {
+				t.Errorf("ClampTitle() = %q, %q, %v, want %q, %q, %v", title, body, clamped, tt.wantTitle, tt.wantBody, tt.wantClamped)
+			}
+			if n := utf8.RuneCountInString(title); n > MaxTitleLength {
+				t.Errorf("title is %d characters", n)
+			}
+			if first, _, _ := strings.Cut(body, "\n"); utf8.RuneCountInString(first) > MaxTitleLength {
+				t.Errorf("first body line is %d characters", utf8.RuneCountInString(first))
+			}
+		})
+	}
+}
+
+func TestFitBody(t *testing.T) {
+	paragraph := strings.Repeat("Retries model calls on rate limits.\n", 20) // 720 characters
+	tests := []struct {
+		name  string
+		body  string
+		limit int
+		// want is the exact result, or "" to only check the limit and
+		// that keep are kept.
+		want    string
+		keep    []string
+		trimmed bool
+	}{
+		{
+			name:  "empty body",
+			body:  "",
+			limit: 100,
+			want:  "",
+		},
+		{
+			name:  "within the limit",
+			body:  "## Summary\n" + paragraph,
+			limit: 1000,
+			want:  "## Summary\n" + paragraph,
+		},
+		{
+			name:    "no headings",
+			body:    paragraph,
+			limit:   300,
+			keep:    []string{"Retries model calls on rate limits.\n", TruncationMarker},
+			trimmed: true,
+		},
+		{
+			name:    "largest section is cut first",
+			body:    "## Summary\nShort.\n\n## Details\n" + paragraph + "\n## Testing\nRan go test.\n",
+			limit:   400,
+			keep:    []string{"## Summary\nShort.\n", "## Details\nRetries", TruncationMarker, "## Testing\nRan go test.\n"},
+			trimmed: true,
+		},
+		{
+			name:    "heading inside a code fence stays in its section",
+			body:    "## Logs\n```\n## not a heading\n" + paragraph + "```\n\n## Testing\nRan go test.\n",
+			limit:   400,
+			keep:    []string{"## Logs\n```\n## not a heading\n", TruncationMarker, "## Testing\nRan go test.\n"},
+			trimmed: true,
+		},
+		{
+			name:    "limit smaller than the marker",
+			body:    "## Details\n" + paragraph,
+			limit:   utf8.RuneCountInString(TruncationMarker) - 5,
+			trimmed: true,
+		},
+		{
+			name:    "sections that cannot shrink are cut by characters",
+			body:    "## A\n## B\n## C\n## D\n",
+			limit:   9,
+			want:    "## A\n## B",
+			trimmed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, trimmed := FitBody(tt.body, tt.limit)
+			if trimmed != tt.trimmed {
+				t.Errorf("trimmed = %v, want %v", trimmed, tt.trimmed)
+			}
+			if n := utf8.RuneCountInString(got); n > tt.limit {
+				t.Errorf("FitBody() is %d characters, over the limit of %d: %q", n, tt.limit, got)
+			}
+			if tt.want != "" || (!tt.trimmed && tt.keep == nil) {
+				if got != tt.want {
+					t.Errorf("FitBody() = %q, want %q", got, tt.want)
+				}
+			}
+			for _, keep := range tt.keep {
+				if !strings.Contains(got, keep) {
+					t.Errorf("FitBody() = %q, want it to keep %q", got, keep)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitSectionsSkipsFencedHeadings(t *testing.T) {
+	body := "Intro\n## One\n```md\n## fenced\n```\n~~~\n# also fenced\n~~~\n## Two\n"
+	sections := splitSections(body)
+	if strings.Join(sections, "") != body {
+		t.Fatalf("sections do not add up to the body: %q", sections)
+	}
+	if len(sections) != 3 || !strings.HasPrefix(sections[1], "## One\n") || sections[2] != "## Two\n" {
+		t.Errorf("splitSections() = %q", sections)
+	}
+}
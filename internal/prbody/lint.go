@@ -0,0 +1,121 @@
+package prbody
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Warning kinds reported by Lint.
+const (
+	WarningUnchecked   = "unchecked"
+	WarningComment     = "comment"
+	WarningPlaceholder = "placeholder"
+)
+
+// Warning describes template boilerplate left untouched in a generated body.
+type Warning struct {
+	Kind string
+	Text string
+	// Required is set for unchecked checkboxes listed in pr.required_checkboxes.
+	Required bool
+}
+
+func (w Warning) String() string {
+	switch w.Kind {
+	case WarningUnchecked:
+		if w.Required {
+			return fmt.Sprintf("required checkbox is not checked: %s", w.Text)
+		}
+		return fmt.Sprintf("unchecked checkbox: %s", w.Text)
+	case WarningComment:
+		return fmt.Sprintf("section contains only a template comment: %s", w.Text)
+	default:
+		return fmt.Sprintf("placeholder text left in body: %q", w.Text)
+	}
+}
+
+var (
+	uncheckedRegex = regexp.MustCompile(`^\s*[-*+]\s+\[ \]\s+(.+)$`)
+	commentRegex   = regexp.MustCompile(`(?s)<!--(.*?)-->`)
+	headingRegex   = regexp.MustCompile(`(?m)^#{1,6}\s`)
+)
+
+// placeholders are phrases commonly found in templates that should have been
+// replaced with real content.
+var placeholders = []string{
+	"Describe your changes here",
+	"Add a description",
+	"Fixes # (issue)",
+	"Lorem ipsum",
+}
+
+// Lint reports checkboxes, instruction comments and placeholder text that the
+// generated body left untouched.
+func Lint(body string, requiredCheckboxes []string) []Warning {
+	var warnings []Warning
+
+	// Boxes in code fences are examples, not checkboxes.
+	var fence codeFence
+	for _, line := range strings.Split(body, "\n") {
+		if fence.line(strings.TrimSpace(line)) {
+			continue
+		}
+		match := uncheckedRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		label := strings.TrimSpace(match[1])
+		warnings = append(warnings, Warning{
+			Kind:     WarningUnchecked,
+			Text:     label,
+			Required: matchesAny(label, requiredCheckboxes),
+		})
+	}
+
+	for _, section := range headingRegex.Split(body, -1) {
+		lines := strings.SplitN(section, "\n", 2)
+		if len(lines) < 2 {
+			continue
+		}
+		content := strings.TrimSpace(lines[1])
+		if content == "" {
+			continue
+		}
+		if strings.TrimSpace(commentRegex.ReplaceAllString(content, "")) == "" {
+			comment := strings.Join(strings.Fields(commentRegex.FindStringSubmatch(content)[1]), " ")
+			warnings = append(warnings, Warning{Kind: WarningComment, Text: comment})
+		}
+	}
+
+	lowerBody := strings.ToLower(commentRegex.ReplaceAllString(body, ""))
+	for _, placeholder := range placeholders {
+		if strings.Contains(lowerBody, strings.ToLower(placeholder)) {
+			warnings = append(warnings, Warning{Kind: WarningPlaceholder, Text: placeholder})
+		}
+	}
+
+	return warnings
+}
+
+// MissingRequired returns the labels of required checkboxes left unchecked.
+func MissingRequired(warnings []Warning) []string {
+	var missing []string
+	for _, warning := range warnings {
+		if warning.Required {
+			missing = append(missing, warning.Text)
+		}
+	}
+	return missing
+}
+
+func matchesAny(label string, required []string) bool {
+	label = strings.ToLower(label)
+	for _, item := range required {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" && strings.Contains(label, item) {
+			return true
+		}
+	}
+	return false
+}
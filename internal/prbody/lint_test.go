@@ -0,0 +1,111 @@
+package prbody
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLintCheckboxes(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		// want is the label of each unchecked box, with "!" in front of
+		// required ones.
+		want []string
+	}{
+		{
+			name: "dash",
+			body: "## Checklist\n- [ ] Tests added\n",
+			want: []string{"!Tests added"},
+		},
+		{
+			name: "star and plus",
+			body: "* [ ] Docs updated\n+ [ ] Changelog entry\n",
+			want: []string{"Docs updated", "Changelog entry"},
+		},
+		{
+			name: "checked boxes",
+			body: "- [x] Tests added\n* [x] Docs updated\n- [X] Changelog entry\n",
+		},
+		{
+			name: "nested lists",
+			body: "- [x] Release\n  - [ ] Tests added\n    * [ ] Migration notes\n\t- [x] Docs updated\n",
+			want: []string{"!Tests added", "Migration notes"},
+		},
+		{
+			name: "boxes in code fences",
+			body: "## Example\n```md\n- [ ] Tests added\n```\n~~~\n* [ ] Docs updated\n~~~\n- [ ] Changelog entry\n",
+			want: []string{"Changelog entry"},
+		},
+		{
+			name: "box after a longer fence",
+			body: "````\n```\n- [ ] Tests added\n```\n````\n- [ ] Docs updated\n",
+			want: []string{"Docs updated"},
+		},
+		{
+			name: "not list items",
+			body: "[ ] Tests added\n-[ ] Docs updated\n- [ ]\n- [] Changelog entry\n",
+		},
+		{
+			name: "CRLF line endings",
+			body: "- [ ] Tests added\r\n- [x] Docs updated\r\n",
+			want: []string{"!Tests added"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, warning := range Lint(tt.body, []string{"tests added", "  "}) {
+				if warning.Kind != WarningUnchecked {
+					continue
+				}
+				label := warning.Text
+				if warning.Required {
+					label = "!" + label
+				}
+				got = append(got, label)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("unchecked boxes = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMissingRequired(t *testing.T) {
+	body := "## Checklist\n- [ ] I have added tests\n  * [ ] Docs\n- [x] CHANGELOG updated\n```\n- [ ] Tests in a fence\n```\n"
+	tests := []struct {
+		name     string
+		required []string
+		want     []string
+	}{
+		{"none required", nil, nil},
+		{"matched case-insensitively by substring", []string{"ADDED TESTS", "docs"}, []string{"I have added tests", "Docs"}},
+		{"checked boxes are not missing", []string{"changelog"}, nil},
+		{"boxes in fences are not missing", []string{"in a fence"}, nil},
+		{"blank entries match nothing", []string{"", " "}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MissingRequired(Lint(body, tt.required))
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("MissingRequired() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintCommentsAndPlaceholders(t *testing.T) {
+	body := "## Summary\n<!-- Describe   the change -->\n\n## Details\nDescribe your changes here.\n"
+	var got []string
+	for _, warning := range Lint(body, nil) {
+		got = append(got, warning.String())
+	}
+	want := []string{
+		"section contains only a template comment: Describe the change",
+		`placeholder text left in body: "Describe your changes here"`,
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Lint() = %q, want %q", got, want)
+	}
+}
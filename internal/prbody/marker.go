@@ -0,0 +1,33 @@
+package prbody
+
+import (
+	"regexp"
+	"strings"
+)
+
+// headMarkerPattern matches the comment recording the commit a description
+// covers, e.g. <!-- gelf:head 1a2b3c4 -->, with the blank lines before it so
+// that removing it leaves no gap when other text follows.
+var headMarkerPattern = regexp.MustCompile(`(?m)\n*^[ \t]*<!--\s*gelf:head\s+([0-9a-fA-F]{7,40})\s*-->[ \t]*$`)
+
+// HeadMarker returns the commit recorded in body by SetHeadMarker, or ""
+// if there is none. The last marker wins.
+func HeadMarker(body string) string {
+	matches := headMarkerPattern.FindAllStringSubmatch(body, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	return matches[len(matches)-1][1]
+}
+
+// StripHeadMarker removes every head marker from body.
+func StripHeadMarker(body string) string {
+	return strings.TrimSpace(headMarkerPattern.ReplaceAllString(body, ""))
+}
+
+// SetHeadMarker records sha as the commit body covers, replacing any
+// existing marker. The marker is an HTML comment, so GitHub does not show
+// it.
+func SetHeadMarker(body, sha string) string {
+	return StripHeadMarker(body) + "\n\n<!-- gelf:head " + sha + " -->"
+}
@@ -0,0 +1,58 @@
+package prbody
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// escapeSequenceRegex matches terminal escape sequences: CSI (colors,
+	// cursor movement), OSC (titles, hyperlinks) and the short ones such as
+	// ESC c, which resets the terminal, and ESC ( B, which picks a charset.
+	escapeSequenceRegex = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[ -/]*[0-~]`)
+	spaceRunRegex       = regexp.MustCompile(` {2,}`)
+)
+
+// SanitizeTitle makes a generated title safe to print, copy and substitute
+// into templates: escape sequences and control characters are removed, line
+// breaks and tabs become spaces, and runs of spaces are collapsed, so the
+// title is a single line.
+func SanitizeTitle(title string) string {
+	title = stripControls(title, false)
+	return strings.TrimSpace(spaceRunRegex.ReplaceAllString(title, " "))
+}
+
+// SanitizeBody removes escape sequences and control characters other than
+// line breaks and tabs from a generated body. CRLF and CR line endings
+// become LF.
+func SanitizeBody(body string) string {
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	body = strings.ReplaceAll(body, "\r", "\n")
+	return stripControls(body, true)
+}
+
+// stripControls removes escape sequences, control characters and the
+// Unicode bidirectional overrides and isolates, which can make printed text
+// read differently from what it is. Line breaks and tabs are kept with
+// keepLines and turned into spaces otherwise.
+func stripControls(text string, keepLines bool) string {
+	text = escapeSequenceRegex.ReplaceAllString(text, "")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t':
+			if keepLines {
+				return r
+			}
+			return ' '
+		case r == '\n' || r == '\u2028' || r == '\u2029':
+			if keepLines {
+				return '\n'
+			}
+			return ' '
+		case unicode.IsControl(r), r >= '\u202a' && r <= '\u202e', r >= '\u2066' && r <= '\u2069':
+			return -1
+		}
+		return r
+	}, text)
+}
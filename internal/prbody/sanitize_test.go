@@ -0,0 +1,70 @@
+package prbody
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeTitle(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"plain", "Add search", "Add search"},
+		{"color", "\x1b[31mAdd\x1b[0m search", "Add search"},
+		{"cursor movement", "Add\x1b[2K\x1b[1A search", "Add search"},
+		{"window title", "\x1b]0;pwned\x07Add search", "Add search"},
+		{"hyperlink", "\x1b]8;;https://evil.example\x1b\\Add search\x1b]8;;\x1b\\", "Add search"},
+		{"two-byte escape", "Add\x1bc search", "Add search"},
+		{"unterminated escape", "Add search\x1b[", "Add search"},
+		{"charset", "Add\x1b(B search", "Add search"},
+		{"nul", "Add\x00 search", "Add search"},
+		{"c1 control", "Add\u009b31m search", "Add31m search"},
+		{"bidi override", "Add ‮search‬", "Add search"},
+		{"bidi isolate", "Add ⁦search⁩", "Add search"},
+		{"newline", "Add search\nand filters", "Add search and filters"},
+		{"crlf", "Add search\r\nand filters", "Add search and filters"},
+		{"line separator", "Add search and filters", "Add search and filters"},
+		{"tabs and space runs", "Add\t\tsearch   now ", "Add search now"},
+		{"only controls", "\x1b[0m\x00\n", ""},
+		{"non-ascii kept", "検索を追加 ✨", "検索を追加 ✨"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SanitizeTitle(tt.title)
+			if got != tt.want {
+				t.Errorf("SanitizeTitle(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+			if strings.ContainsAny(got, "\x1b\x00\n\r\t") {
+				t.Errorf("SanitizeTitle(%q) = %q, which still holds a control character", tt.title, got)
+			}
+		})
+	}
+}
+
+func TestSanitizeBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"lines and tabs kept", "## Summary\n\n- item\n\tcode", "## Summary\n\n- item\n\tcode"},
+		{"crlf", "line one\r\nline two\r\n", "line one\nline two\n"},
+		{"lone cr", "line one\rline two", "line one\nline two"},
+		{"line and paragraph separators", "one two three", "one\ntwo\nthree"},
+		{"color", "\x1b[1mBold\x1b[0m text", "Bold text"},
+		{"clear screen", "\x1b[2J\x1b[HBody", "Body"},
+		{"window title", "\x1b]2;title\x1b\\Body", "Body"},
+		{"nul and bell", "Bo\x00dy\x07", "Body"},
+		{"bidi override", "`if admin ‮{ }‬`", "`if admin { }`"},
+		{"markdown kept", "<!-- gelf:head abc1234 -->\n[link](https://example.com)", "<!-- gelf:head abc1234 -->\n[link](https://example.com)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeBody(tt.body); got != tt.want {
+				t.Errorf("SanitizeBody(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
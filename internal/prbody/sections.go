@@ -0,0 +1,171 @@
+package prbody
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Section is a part of a body that starts at a markdown heading and runs up
+// to the next one. Text before the first heading is a section with Level 0
+// and no Title.
+type Section struct {
+	Title string
+	Level int
+	// Text is the section as it appears in the body, heading included.
+	Text string
+}
+
+var (
+	atxHeadingRegex = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextRegex     = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	listItemRegex   = regexp.MustCompile(`^ {0,3}(?:[-*+]|\d{1,9}[.)])(?:[ \t]|$)`)
+)
+
+// heading marks the line at which a section starts.
+type heading struct {
+	line  int
+	level int
+	title string
+}
+
+// Sections splits body at ATX ("## Testing") and setext ("Testing" underlined
+// with "=" or "-") headings outside code fences. Joining the Text of every
+// section in order yields body.
+func Sections(body string) []Section {
+	if body == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(body, "\n")
+	headings := findHeadings(lines)
+
+	var sections []Section
+	if len(headings) == 0 || headings[0].line > 0 {
+		end := len(lines)
+		if len(headings) > 0 {
+			end = headings[0].line
+		}
+		sections = append(sections, Section{Text: strings.Join(lines[:end], "")})
+	}
+	for i, h := range headings {
+		end := len(lines)
+		if i+1 < len(headings) {
+			end = headings[i+1].line
+		}
+		sections = append(sections, Section{
+			Title: h.title,
+			Level: h.level,
+			Text:  strings.Join(lines[h.line:end], ""),
+		})
+	}
+	return sections
+}
+
+// findHeadings returns the headings in lines, in order. A setext heading
+// starts at the first line of the paragraph its underline belongs to.
+func findHeadings(lines []string) []heading {
+	var headings []heading
+	var fence codeFence
+	// paragraph is the first line of the paragraph being read, -1 outside a
+	// paragraph and -2 inside a block (list item, quote) whose following
+	// lines cannot be a setext heading.
+	paragraph := -1
+	for i, raw := range lines {
+		line := strings.TrimRight(raw, "\r\n")
+		trimmed := strings.TrimSpace(line)
+
+		if fence.line(trimmed) {
+			paragraph = -1
+			continue
+		}
+
+		if match := atxHeadingRegex.FindStringSubmatch(line); match != nil {
+			headings = append(headings, heading{line: i, level: len(match[1]), title: strings.TrimSpace(match[2])})
+			paragraph = -1
+			continue
+		}
+		if match := setextRegex.FindStringSubmatch(line); match != nil && paragraph >= 0 {
+			level := 1
+			if strings.HasPrefix(match[1], "-") {
+				level = 2
+			}
+			var title []string
+			for _, l := range lines[paragraph:i] {
+				title = append(title, strings.TrimSpace(l))
+			}
+			headings = append(headings, heading{line: paragraph, level: level, title: strings.Join(title, " ")})
+			paragraph = -1
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			paragraph = -1
+		case listItemRegex.MatchString(line) || strings.HasPrefix(trimmed, ">"):
+			paragraph = -2
+		case paragraph == -1 && (strings.HasPrefix(trimmed, "<!--") || strings.HasPrefix(trimmed, "|")):
+			paragraph = -2
+		case paragraph == -1:
+			paragraph = i
+		}
+	}
+	return headings
+}
+
+// codeFence follows the fenced code blocks of a document line by line.
+type codeFence struct {
+	// marker is the run of backticks or tildes that opened the block being
+	// read, or "" outside a block.
+	marker string
+}
+
+// line reports whether trimmed, the next line with its indentation and line
+// break removed, opens, belongs to or closes a fenced code block. A block
+// only closes at a run of its own character at least as long as the one
+// that opened it, so "```" inside a "~~~" or "````" block is code.
+func (f *codeFence) line(trimmed string) bool {
+	run := fenceRun(trimmed)
+	if f.marker == "" {
+		f.marker = run
+		return run != ""
+	}
+	if run != "" && run[0] == f.marker[0] && len(run) >= len(f.marker) && strings.TrimSpace(trimmed[len(run):]) == "" {
+		f.marker = ""
+	}
+	return true
+}
+
+// fenceRun returns the run of three or more backticks or tildes trimmed
+// starts with, or "".
+func fenceRun(trimmed string) string {
+	if trimmed == "" || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return ""
+	}
+	n := len(trimmed) - len(strings.TrimLeft(trimmed, trimmed[:1]))
+	if n < 3 {
+		return ""
+	}
+	return trimmed[:n]
+}
+
+// JoinSections returns the body made of the sections whose keep entry is
+// true. Dropping a section also drops the sections nested under it, that is
+// the deeper headings that follow it up to the next heading of its level or
+// higher. The section before the first heading is always kept.
+func JoinSections(sections []Section, keep []bool) string {
+	var b strings.Builder
+	dropBelow := 0
+	for i, section := range sections {
+		if dropBelow > 0 {
+			if section.Level > dropBelow {
+				continue
+			}
+			dropBelow = 0
+		}
+		if section.Level > 0 && i < len(keep) && !keep[i] {
+			dropBelow = section.Level
+			continue
+		}
+		b.WriteString(section.Text)
+	}
+	return b.String()
+}
@@ -0,0 +1,120 @@
+package prbody
+
+import (
+	"strings"
+	"testing"
+)
+
+// sectionTitles returns the level and title of every section, "0:" for the
+// text before the first heading.
+func sectionTitles(sections []Section) []string {
+	var titles []string
+	for _, s := range sections {
+		titles = append(titles, strings.Repeat("#", s.Level)+":"+s.Title)
+	}
+	return titles
+}
+
+func TestSections(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "default layout",
+			body: "## Summary\nRetries model calls.\n\n## Changes\n- Retry 429\n- Retry 503\n\n## Testing\nRan go test ./...\n",
+			want: []string{"##:Summary", "##:Changes", "##:Testing"},
+		},
+		{
+			name: "template",
+			body: "<!-- Describe your change. -->\n\n## What\nAdds retries.\n\n## Why\nRate limits.\n\n### Screenshots\nNone.\n\n## Checklist\n- [x] Tests\n- [ ] Docs\n",
+			want: []string{":", "##:What", "##:Why", "###:Screenshots", "##:Checklist"},
+		},
+		{
+			name: "setext headings",
+			body: "Summary\n=======\nRetries model calls\nwith backoff.\n\nTesting\n-------\nRan go test.\n",
+			want: []string{"#:Summary", "##:Testing"},
+		},
+		{
+			name: "multi-line setext heading",
+			body: "Retries and\nbackoff\n-------\nDetails.\n",
+			want: []string{"##:Retries and backoff"},
+		},
+		{
+			name: "hash in a fence",
+			body: "## Testing\n```sh\n# run the tests\ngo test ./...\n```\n\n## Notes\nNone.\n",
+			want: []string{"##:Testing", "##:Notes"},
+		},
+		{
+			name: "backticks in a tilde fence",
+			body: "## Example\n~~~md\n```\n# not a heading\n~~~\n\n## Notes\nNone.\n",
+			want: []string{"##:Example", "##:Notes"},
+		},
+		{
+			name: "shorter closing fence",
+			body: "## Example\n````md\n```go\n```\n# not a heading\n````\n\n## Notes\nNone.\n",
+			want: []string{"##:Example", "##:Notes"},
+		},
+		{
+			name: "rule after a list item",
+			body: "## Changes\n- Retry 429\n---\n## Testing\nRan go test.\n",
+			want: []string{"##:Changes", "##:Testing"},
+		},
+		{
+			name: "rule after a quote",
+			body: "## Notes\n> quoted\n---\nMore.\n",
+			want: []string{"##:Notes"},
+		},
+		{
+			name: "rule after a blank line",
+			body: "## Notes\nText.\n\n---\n\nMore.\n",
+			want: []string{"##:Notes"},
+		},
+		{
+			name: "closing hashes",
+			body: "## Summary ##\nText.\n",
+			want: []string{"##:Summary"},
+		},
+		{
+			name: "no headings",
+			body: "Just text.\n",
+			want: []string{":"},
+		},
+		{
+			name: "crlf",
+			body: "## Summary\r\nText.\r\n\r\n## Testing\r\nNone.\r\n",
+			want: []string{"##:Summary", "##:Testing"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sections := Sections(tt.body)
+			if got := sectionTitles(sections); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Sections() = %q, want %q", got, tt.want)
+			}
+			var joined strings.Builder
+			for _, s := range sections {
+				joined.WriteString(s.Text)
+			}
+			if joined.String() != tt.body {
+				t.Errorf("joined sections = %q, want the body %q", joined.String(), tt.body)
+			}
+			keep := make([]bool, len(sections))
+			for i := range keep {
+				keep[i] = true
+			}
+			if got := JoinSections(sections, keep); got != tt.body {
+				t.Errorf("JoinSections(all) = %q, want the body %q", got, tt.body)
+			}
+		})
+	}
+}
+
+func TestJoinSectionsDropsNested(t *testing.T) {
+	body := "Intro.\n## What\nAdds retries.\n### Details\nBackoff.\n## Testing\nRan go test.\n"
+	sections := Sections(body)
+	if got := JoinSections(sections, []bool{false, false, true, true}); got != "Intro.\n## Testing\nRan go test.\n" {
+		t.Errorf("JoinSections() = %q", got)
+	}
+}
@@ -0,0 +1,98 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Kind identifies the type of a progress event.
+type Kind int
+
+const (
+	// PhaseStart marks the beginning of a phase such as "generate".
+	PhaseStart Kind = iota
+	// PhaseEnd marks the end of a phase; Err is set when it failed.
+	PhaseEnd
+	// Item reports that item Current of Total within a phase is done.
+	Item
+	// Tokens reports token usage for a phase.
+	Tokens
+	// Status reports how far a streamed response got: Message describes it
+	// and Current counts the characters received so far.
+	Status
+)
+
+// Event is a typed progress notification.
+type Event struct {
+	Kind    Kind
+	Phase   string
+	Message string
+	Current int
+	Total   int
+
+	InputTokens  int
+	OutputTokens int
+
+	Err error
+}
+
+// Reporter receives progress events from long running operations.
+type Reporter interface {
+	Report(event Event)
+}
+
+// ReporterFunc adapts a function to the Reporter interface.
+type ReporterFunc func(event Event)
+
+func (f ReporterFunc) Report(event Event) {
+	f(event)
+}
+
+// Nop returns a Reporter that discards all events.
+func Nop() Reporter {
+	return ReporterFunc(func(Event) {})
+}
+
+// NewPlain returns a Reporter that prints one line per phase and item to out,
+// suitable for logs and non-interactive terminals.
+func NewPlain(out io.Writer) Reporter {
+	return &plainReporter{out: out, started: map[string]time.Time{}}
+}
+
+type plainReporter struct {
+	mu      sync.Mutex
+	out     io.Writer
+	started map[string]time.Time
+}
+
+func (r *plainReporter) Report(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch event.Kind {
+	case PhaseStart:
+		r.started[event.Phase] = time.Now()
+		message := event.Message
+		if message == "" {
+			message = event.Phase
+		}
+		fmt.Fprintf(r.out, "%s\n", message)
+	case PhaseEnd:
+		elapsed := time.Duration(0)
+		if start, ok := r.started[event.Phase]; ok {
+			elapsed = time.Since(start).Round(time.Millisecond)
+			delete(r.started, event.Phase)
+		}
+		if event.Err != nil {
+			fmt.Fprintf(r.out, "%s: failed after %s: %v\n", event.Phase, elapsed, event.Err)
+			return
+		}
+		fmt.Fprintf(r.out, "%s: done in %s\n", event.Phase, elapsed)
+	case Item:
+		fmt.Fprintf(r.out, "%s: %d/%d\n", event.Phase, event.Current, event.Total)
+	case Tokens:
+		fmt.Fprintf(r.out, "%s: %d input tokens, %d output tokens\n", event.Phase, event.InputTokens, event.OutputTokens)
+	}
+}
@@ -0,0 +1,95 @@
+package progress
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestPlainReporter(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []Event
+		// want holds a pattern per line printed.
+		want []string
+	}{
+		{
+			name: "phase with message",
+			events: []Event{
+				{Kind: PhaseStart, Phase: "generate", Message: "Generating commit message..."},
+				{Kind: PhaseEnd, Phase: "generate"},
+			},
+			want: []string{`Generating commit message\.\.\.`, `generate: done in \d+(\.\d+)?[mµn]?s`},
+		},
+		{
+			name: "phase without message",
+			events: []Event{
+				{Kind: PhaseStart, Phase: "review"},
+				{Kind: PhaseEnd, Phase: "review", Err: errors.New("quota exceeded")},
+			},
+			want: []string{`review`, `review: failed after \S+: quota exceeded`},
+		},
+		{
+			name: "items",
+			events: []Event{
+				{Kind: Item, Phase: "split", Current: 1, Total: 3},
+				{Kind: Item, Phase: "split", Current: 3, Total: 3},
+			},
+			want: []string{`split: 1/3`, `split: 3/3`},
+		},
+		{
+			name:   "tokens",
+			events: []Event{{Kind: Tokens, Phase: "generate", InputTokens: 1200, OutputTokens: 300}},
+			want:   []string{`generate: 1200 input tokens, 300 output tokens`},
+		},
+		{
+			name:   "end without start",
+			events: []Event{{Kind: PhaseEnd, Phase: "push"}},
+			want:   []string{`push: done in 0s`},
+		},
+		{
+			// Streaming status is for spinners; a log gets no line per chunk.
+			name:   "status",
+			events: []Event{{Kind: Status, Phase: "generate", Message: "receiving", Current: 512}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			reporter := NewPlain(&out)
+			for _, event := range tt.events {
+				reporter.Report(event)
+			}
+
+			var lines []string
+			if text := strings.TrimSuffix(out.String(), "\n"); text != "" {
+				lines = strings.Split(text, "\n")
+			}
+			if len(lines) != len(tt.want) {
+				t.Fatalf("output = %q, want %d lines", out.String(), len(tt.want))
+			}
+			for i, pattern := range tt.want {
+				if !regexp.MustCompile(`^` + pattern + `$`).MatchString(lines[i]) {
+					t.Errorf("line %d = %q, want %s", i+1, lines[i], pattern)
+				}
+			}
+		})
+	}
+}
+
+func TestNop(t *testing.T) {
+	reporter := Nop()
+	for kind := PhaseStart; kind <= Status; kind++ {
+		reporter.Report(Event{Kind: kind, Phase: "generate", Err: errors.New("ignored")})
+	}
+}
+
+func TestReporterFunc(t *testing.T) {
+	var got []Event
+	reporter := ReporterFunc(func(event Event) { got = append(got, event) })
+	reporter.Report(Event{Kind: Item, Phase: "split", Current: 2, Total: 5})
+	if len(got) != 1 || got[0].Current != 2 || got[0].Total != 5 {
+		t.Errorf("ReporterFunc received %+v", got)
+	}
+}
@@ -0,0 +1,45 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/EkeMinusYou/gelf/internal/config"
+)
+
+// Rule replaces every match of Pattern with Replace.
+type Rule struct {
+	Pattern *regexp.Regexp
+	Replace string
+}
+
+// Redactor applies redaction rules to text sent to the model. Redaction is
+// one-way: nothing is ever restored in the model's output.
+type Redactor struct {
+	rules []Rule
+}
+
+// New compiles the configured redaction rules into a Redactor.
+func New(configured []config.RedactRule) (*Redactor, error) {
+	rules := make([]Rule, 0, len(configured))
+	for _, rule := range configured {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", rule.Pattern, err)
+		}
+		rules = append(rules, Rule{Pattern: re, Replace: rule.Replace})
+	}
+
+	return &Redactor{rules: rules}, nil
+}
+
+// Apply returns text with all rules applied in order.
+func (r *Redactor) Apply(text string) string {
+	if r == nil {
+		return text
+	}
+	for _, rule := range r.rules {
+		text = rule.Pattern.ReplaceAllString(text, rule.Replace)
+	}
+	return text
+}
@@ -0,0 +1,84 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/EkeMinusYou/gelf/internal/config"
+)
+
+const diff = `diff --git a/deploy.yml b/deploy.yml
+--- a/deploy.yml
++++ b/deploy.yml
+@@ -1,2 +1,2 @@
+-host: db01.corp.example.com
++host: db02.corp.example.com
+ customer: ACME-48213
+`
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []config.RedactRule
+		text  string
+		want  string
+	}{
+		{
+			name:  "hostnames",
+			rules: []config.RedactRule{{Pattern: `[a-z0-9]+\.corp\.example\.com`, Replace: "<HOST>"}},
+			text:  diff,
+			want:  strings.NewReplacer("db01.corp.example.com", "<HOST>", "db02.corp.example.com", "<HOST>").Replace(diff),
+		},
+		{
+			name:  "customer identifiers keep a group",
+			rules: []config.RedactRule{{Pattern: `([A-Z]+)-\d{5}`, Replace: "$1-<ID>"}},
+			text:  diff,
+			want:  strings.Replace(diff, "ACME-48213", "ACME-<ID>", 1),
+		},
+		{
+			name: "rules apply in order",
+			rules: []config.RedactRule{
+				{Pattern: `db\d+\.corp`, Replace: "<DB>.corp"},
+				{Pattern: `<DB>\.corp\.example\.com`, Replace: "<HOST>"},
+			},
+			text: "host: db01.corp.example.com",
+			want: "host: <HOST>",
+		},
+		{
+			name:  "empty replacement removes the match",
+			rules: []config.RedactRule{{Pattern: ` customer: \S+\n`}},
+			text:  diff,
+			want:  strings.Replace(diff, " customer: ACME-48213\n", "", 1),
+		},
+		{
+			name: "no rules",
+			text: diff,
+			want: diff,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redactor, err := New(tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := redactor.Apply(tt.text); got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyNil(t *testing.T) {
+	var redactor *Redactor
+	if got := redactor.Apply(diff); got != diff {
+		t.Errorf("nil Redactor changed the text: %q", got)
+	}
+}
+
+func TestNewInvalidPattern(t *testing.T) {
+	_, err := New([]config.RedactRule{{Pattern: `db(\d+`, Replace: "<HOST>"}})
+	if err == nil || !strings.Contains(err.Error(), `db(\d+`) {
+		t.Errorf("New() error = %v, want it to name the pattern", err)
+	}
+}
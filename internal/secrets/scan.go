@@ -0,0 +1,166 @@
+package secrets
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Rule is a named pattern that identifies a secret.
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// Finding is a secret detected on an added line of a diff.
+type Finding struct {
+	Rule  string
+	File  string
+	Line  string
+	Match string
+}
+
+// Masked returns the line with the matched secret masked.
+func (f Finding) Masked() string {
+	return strings.Replace(f.Line, f.Match, Mask(f.Match), 1)
+}
+
+var defaultRules = []Rule{
+	{Name: "aws-access-key-id", Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "aws-secret-access-key", Pattern: regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}`)},
+	{Name: "gcp-service-account", Pattern: regexp.MustCompile(`"type"\s*:\s*"service_account"`)},
+	{Name: "private-key", Pattern: regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )?PRIVATE KEY-----`)},
+	{Name: "generic-api-key", Pattern: regexp.MustCompile(`(?i)\b(?:api[_-]?key|secret[_-]?key|access[_-]?token|auth[_-]?token|client[_-]?secret|password)["']?\s*[:=]\s*["']?[A-Za-z0-9_\-./+=]{16,}`)},
+}
+
+var (
+	entropyTokenRegex = regexp.MustCompile(`[A-Za-z0-9_\-+/=]{24,}`)
+	// hashRegex matches base64 hashes labelled with their algorithm, as in
+	// subresource integrity ("sha384-...") and Go module sums ("h1:...").
+	hashRegex       = regexp.MustCompile(`\b(?:sha(?:1|256|384|512)-|h1:)[A-Za-z0-9+/]+=*`)
+	fileHeaderRegex = regexp.MustCompile(`^diff --git a/(.*) b/(.*)$`)
+)
+
+// checksumFiles hold hashes by design, so the entropy heuristic skips them.
+var checksumFiles = map[string]bool{
+	"go.sum":            true,
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"Cargo.lock":        true,
+	"Gemfile.lock":      true,
+	"poetry.lock":       true,
+	"composer.lock":     true,
+}
+
+// entropyThreshold is the Shannon entropy (bits per character) above which a
+// long token is considered random enough to be a credential.
+const entropyThreshold = 4.5
+
+// Scanner looks for secrets on the added lines of a unified diff.
+type Scanner struct {
+	rules   []Rule
+	entropy bool
+}
+
+// NewScanner creates a scanner with the built-in rules plus extra patterns
+// keyed by rule name.
+func NewScanner(extra map[string]string, entropy bool) (*Scanner, error) {
+	rules := append([]Rule{}, defaultRules...)
+	for name, pattern := range extra {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret pattern %q: %w", name, err)
+		}
+		rules = append(rules, Rule{Name: name, Pattern: re})
+	}
+	return &Scanner{rules: rules, entropy: entropy}, nil
+}
+
+// Scan returns the secrets found on lines added by diff.
+func (s *Scanner) Scan(diff string) []Finding {
+	var findings []Finding
+	currentFile := ""
+	for _, line := range strings.Split(diff, "\n") {
+		if matches := fileHeaderRegex.FindStringSubmatch(line); matches != nil {
+			currentFile = matches[2]
+			continue
+		}
+		if !strings.HasPrefix(line, "+") || strings.HasPrefix(line, "+++") {
+			continue
+		}
+		added := strings.TrimPrefix(line, "+")
+
+		if finding, ok := s.scanLine(currentFile, added); ok {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+func (s *Scanner) scanLine(file, line string) (Finding, bool) {
+	for _, rule := range s.rules {
+		if match := rule.Pattern.FindString(line); match != "" {
+			return Finding{Rule: rule.Name, File: file, Line: line, Match: match}, true
+		}
+	}
+
+	if !s.entropy || checksumFiles[path.Base(file)] {
+		return Finding{}, false
+	}
+	for _, token := range entropyTokenRegex.FindAllString(hashRegex.ReplaceAllString(line, " "), -1) {
+		if looksRandom(token) {
+			return Finding{Rule: "high-entropy-string", File: file, Line: line, Match: token}, true
+		}
+	}
+	return Finding{}, false
+}
+
+// looksRandom reports whether token has high entropy and mixes character
+// classes, which filters out long identifiers and hex hashes.
+func looksRandom(token string) bool {
+	var hasUpper, hasLower, hasDigit bool
+	for _, r := range token {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			hasUpper = true
+		case r >= 'a' && r <= 'z':
+			hasLower = true
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		}
+	}
+	if !(hasUpper && hasLower && hasDigit) {
+		return false
+	}
+	return shannonEntropy(token) >= entropyThreshold
+}
+
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// Mask keeps the first four characters of a secret and hides the rest.
+func Mask(secret string) string {
+	runes := []rune(secret)
+	if len(runes) <= 4 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:4]) + strings.Repeat("*", len(runes)-4)
+}
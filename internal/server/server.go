@@ -0,0 +1,243 @@
+// Package server exposes commit message, pull request and review generation
+// over a small JSON HTTP API for editor integrations (gelf serve).
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/pkg/gelf"
+)
+
+// MaxRequestBytes limits the size of a request body.
+const MaxRequestBytes = 4 << 20
+
+// Generator is the part of gelf.Generator the handlers use.
+type Generator interface {
+	CommitMessage(ctx context.Context, req gelf.CommitRequest) (*gelf.CommitResult, error)
+	PullRequest(ctx context.Context, req gelf.PRRequest) (*gelf.PRResult, error)
+	Review(ctx context.Context, req gelf.ReviewRequest, stream func(chunk string)) (*gelf.ReviewResult, error)
+}
+
+// Options configure the handler.
+type Options struct {
+	// Commit generates commit messages; PR generates pull request content
+	// and reviews. They usually differ only in the model.
+	Commit Generator
+	PR     Generator
+	// Token must be sent as "Authorization: Bearer <token>".
+	Token string
+	// Redact is applied to diffs, commit logs and messages before they are
+	// sent to the model. Optional.
+	Redact func(string) string
+}
+
+type usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+func toUsage(u gelf.Usage) usage {
+	return usage{InputTokens: u.InputTokens, OutputTokens: u.OutputTokens}
+}
+
+type commitRequest struct {
+	Diff     string `json:"diff"`
+	Language string `json:"language"`
+	Template string `json:"template"`
+	Scope    string `json:"scope"`
+}
+
+type commitResponse struct {
+	Message string `json:"message"`
+	Usage   usage  `json:"usage"`
+}
+
+type prRequest struct {
+	BaseBranch    string `json:"base_branch"`
+	HeadBranch    string `json:"head_branch"`
+	CommitLog     string `json:"commit_log"`
+	DiffStat      string `json:"diff_stat"`
+	Diff          string `json:"diff"`
+	Template      string `json:"template"`
+	Language      string `json:"language"`
+	TitleLanguage string `json:"title_language"`
+	BodyLanguage  string `json:"body_language"`
+	Scope         string `json:"scope"`
+}
+
+type prResponse struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Usage usage  `json:"usage"`
+}
+
+type reviewRequest struct {
+	Diff          string `json:"diff"`
+	CommitMessage string `json:"commit_message"`
+	Language      string `json:"language"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// New returns the API handler:
+//
+//	POST /v1/commit-message  {"diff"} -> {"message", "usage"}
+//	POST /v1/pr-content      {"diff", "commit_log", ...} -> {"title", "body", "usage"}
+//	POST /v1/review          {"diff"} -> text/event-stream of "chunk" events,
+//	                         ending with "done" ({"usage"}) or "error"
+func New(opts Options) http.Handler {
+	if opts.Redact == nil {
+		opts.Redact = func(text string) string { return text }
+	}
+	s := &server{opts: opts}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/commit-message", s.commitMessage)
+	mux.HandleFunc("POST /v1/pr-content", s.prContent)
+	mux.HandleFunc("POST /v1/review", s.review)
+	return s.authenticate(mux)
+}
+
+type server struct {
+	opts Options
+}
+
+func (s *server) authenticate(next http.Handler) http.Handler {
+	expected := []byte("Bearer " + s.opts.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, MaxRequestBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *server) commitMessage(w http.ResponseWriter, r *http.Request) {
+	var req commitRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	result, err := s.opts.Commit.CommitMessage(r.Context(), gelf.CommitRequest{
+		Diff:     s.opts.Redact(req.Diff),
+		Language: req.Language,
+		Template: req.Template,
+		Scope:    req.Scope,
+	})
+	if err != nil {
+		writeGenerationError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, commitResponse{Message: result.Message, Usage: toUsage(result.Usage)})
+}
+
+func (s *server) prContent(w http.ResponseWriter, r *http.Request) {
+	var req prRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	result, err := s.opts.PR.PullRequest(r.Context(), gelf.PRRequest{
+		BaseBranch:    req.BaseBranch,
+		HeadBranch:    req.HeadBranch,
+		CommitLog:     s.opts.Redact(req.CommitLog),
+		DiffStat:      s.opts.Redact(req.DiffStat),
+		Diff:          s.opts.Redact(req.Diff),
+		Template:      s.opts.Redact(req.Template),
+		Language:      req.Language,
+		TitleLanguage: req.TitleLanguage,
+		BodyLanguage:  req.BodyLanguage,
+		Scope:         req.Scope,
+	})
+	if err != nil {
+		writeGenerationError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, prResponse{Title: result.Title, Body: result.Body, Usage: toUsage(result.Usage)})
+}
+
+func (s *server) review(w http.ResponseWriter, r *http.Request) {
+	var req reviewRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	result, err := s.opts.PR.Review(r.Context(), gelf.ReviewRequest{
+		Diff:          s.opts.Redact(req.Diff),
+		CommitMessage: s.opts.Redact(req.CommitMessage),
+		Language:      req.Language,
+	}, func(chunk string) {
+		writeEvent(w, "chunk", chunk)
+		flusher.Flush()
+	})
+	if err != nil {
+		writeEvent(w, "error", errorResponse{Error: err.Error()})
+	} else {
+		writeEvent(w, "done", struct {
+			Usage usage `json:"usage"`
+		}{toUsage(result.Usage)})
+	}
+	flusher.Flush()
+}
+
+// decode reads the JSON request body into v, writing an error response and
+// returning false when it cannot.
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body exceeds %d bytes", tooLarge.Limit))
+			return false
+		}
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return false
+	}
+	return true
+}
+
+// writeGenerationError reports a failed generation. The gelf package
+// prefixes errors in the request with "gelf:".
+func writeGenerationError(w http.ResponseWriter, err error) {
+	status := http.StatusBadGateway
+	if strings.HasPrefix(err.Error(), "gelf:") {
+		status = http.StatusBadRequest
+	}
+	writeError(w, status, err)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeEvent writes one server-sent event whose data is v as JSON, so chunks
+// with newlines stay on one data line.
+func writeEvent(w http.ResponseWriter, event string, v any) {
+	data, _ := json.Marshal(v)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EkeMinusYou/gelf/pkg/gelf"
+)
+
+const testToken = "secret"
+
+// fakeGenerator records the requests it gets and answers with fixed text.
+type fakeGenerator struct {
+	commit gelf.CommitRequest
+	review gelf.ReviewRequest
+	// chunks are streamed by Review; err fails it after the chunks.
+	chunks []string
+	err    error
+}
+
+func (f *fakeGenerator) CommitMessage(ctx context.Context, req gelf.CommitRequest) (*gelf.CommitResult, error) {
+	f.commit = req
+	return &gelf.CommitResult{Message: "feat: add retries", Usage: gelf.Usage{InputTokens: 10, OutputTokens: 3}}, nil
+}
+
+func (f *fakeGenerator) PullRequest(ctx context.Context, req gelf.PRRequest) (*gelf.PRResult, error) {
+	return &gelf.PRResult{Title: "Add retries", Body: "## Summary"}, nil
+}
+
+func (f *fakeGenerator) Review(ctx context.Context, req gelf.ReviewRequest, stream func(chunk string)) (*gelf.ReviewResult, error) {
+	f.review = req
+	for _, chunk := range f.chunks {
+		stream(chunk)
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &gelf.ReviewResult{Review: strings.Join(f.chunks, ""), Usage: gelf.Usage{InputTokens: 7, OutputTokens: 2}}, nil
+}
+
+// newTestServer serves New with generator behind both models and a redactor
+// that hides "hunter2".
+func newTestServer(t *testing.T, generator *fakeGenerator) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(New(Options{
+		Commit: generator,
+		PR:     generator,
+		Token:  testToken,
+		Redact: func(text string) string { return strings.ReplaceAll(text, "hunter2", "[REDACTED]") },
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func post(t *testing.T, server *httptest.Server, path, authorization, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestAuthentication(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"missing token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer wrong", http.StatusUnauthorized},
+		{"token without Bearer", testToken, http.StatusUnauthorized},
+		{"valid token", "Bearer " + testToken, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator := &fakeGenerator{}
+			resp := post(t, newTestServer(t, generator), "/v1/commit-message", tt.authorization, `{"diff":"+retry"}`)
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && generator.commit.Diff != "" {
+				t.Error("the model was called without a valid token")
+			}
+		})
+	}
+}
+
+func TestCommitMessage(t *testing.T) {
+	generator := &fakeGenerator{}
+	resp := post(t, newTestServer(t, generator), "/v1/commit-message", "Bearer "+testToken, `{"diff":"+password = hunter2","scope":"ai"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	var got commitResponse
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Message != "feat: add retries" || got.Usage.InputTokens != 10 || got.Usage.OutputTokens != 3 {
+		t.Errorf("response = %+v", got)
+	}
+	if generator.commit.Diff != "+password = [REDACTED]" || generator.commit.Scope != "ai" {
+		t.Errorf("model got %+v, want the redacted diff and the scope", generator.commit)
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{"oversized body", "/v1/commit-message", `{"diff":"` + strings.Repeat("a", MaxRequestBytes) + `"}`, http.StatusRequestEntityTooLarge},
+		{"oversized review body", "/v1/review", `{"diff":"` + strings.Repeat("a", MaxRequestBytes) + `"}`, http.StatusRequestEntityTooLarge},
+		{"invalid JSON", "/v1/commit-message", `{"diff":`, http.StatusBadRequest},
+		{"unknown field", "/v1/pr-content", `{"diff":"+x","title":"x"}`, http.StatusBadRequest},
+		{"unknown path", "/v1/commit", `{}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := post(t, newTestServer(t, &fakeGenerator{}), tt.path, "Bearer "+testToken, tt.body)
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+// event is one server-sent event.
+type event struct {
+	name, data string
+}
+
+// readEvents parses the server-sent events of resp.
+func readEvents(t *testing.T, resp *http.Response) []event {
+	t.Helper()
+	var events []event
+	var current event
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			events = append(events, current)
+			current = event{}
+		case strings.HasPrefix(line, "event: "):
+			current.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if current.data != "" {
+				t.Errorf("event %q has more than one data line", current.name)
+			}
+			current.data = strings.TrimPrefix(line, "data: ")
+		default:
+			t.Errorf("unexpected line %q", line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestReviewStream(t *testing.T) {
+	tests := []struct {
+		name      string
+		generator *fakeGenerator
+		want      []event
+	}{
+		{
+			name:      "chunks then done",
+			generator: &fakeGenerator{chunks: []string{"- **low** retry.go:1:\n", "missing doc comment\n"}},
+			want: []event{
+				{"chunk", `"- **low** retry.go:1:\n"`},
+				{"chunk", `"missing doc comment\n"`},
+				{"done", `{"usage":{"input_tokens":7,"output_tokens":2}}`},
+			},
+		},
+		{
+			name:      "error after a chunk",
+			generator: &fakeGenerator{chunks: []string{"- **high**"}, err: errors.New("quota exceeded")},
+			want: []event{
+				{"chunk", `"- **high**"`},
+				{"error", `{"error":"quota exceeded"}`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := post(t, newTestServer(t, tt.generator), "/v1/review", "Bearer "+testToken, `{"diff":"+hunter2"}`)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want 200", resp.StatusCode)
+			}
+			if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+				t.Errorf("Content-Type = %q, want text/event-stream", got)
+			}
+			got := readEvents(t, resp)
+			if len(got) != len(tt.want) {
+				t.Fatalf("events = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("event %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+			if tt.generator.review.Diff != "+[REDACTED]" {
+				t.Errorf("model got diff %q, want it redacted", tt.generator.review.Diff)
+			}
+		})
+	}
+}
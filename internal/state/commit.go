@@ -0,0 +1,37 @@
+package state
+
+import "time"
+
+const lastCommitFile = "last-commit.json"
+
+// SavedCommit is a generated commit message that could not be committed.
+type SavedCommit struct {
+	DiffHash string    `json:"diff_hash"`
+	Message  string    `json:"message"`
+	SavedAt  time.Time `json:"saved_at"`
+}
+
+// SaveCommitMessage stores message together with a hash of the staged diff
+// it was generated from and returns the file it was written to.
+func SaveCommitMessage(repoRoot, diff, message string) (string, error) {
+	return writeJSON(repoRoot, lastCommitFile, SavedCommit{
+		DiffHash: Hash(diff),
+		Message:  message,
+		SavedAt:  time.Now(),
+	})
+}
+
+// LoadCommitMessage returns the saved commit message, or nil if there is none.
+func LoadCommitMessage(repoRoot string) (*SavedCommit, error) {
+	var saved SavedCommit
+	found, err := readJSON(repoRoot, lastCommitFile, &saved)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// ClearCommitMessage removes the saved commit message.
+func ClearCommitMessage(repoRoot string) error {
+	return remove(repoRoot, lastCommitFile)
+}
@@ -0,0 +1,47 @@
+package state
+
+import (
+	"sort"
+	"time"
+)
+
+const historyFile = "pull-requests.json"
+
+// PullRequestRecord is the last thing gelf did to a pull request.
+type PullRequestRecord struct {
+	Number int       `json:"number"`
+	Action string    `json:"action"`
+	At     time.Time `json:"at"`
+}
+
+// RecordPullRequest remembers that gelf created or updated pull request
+// number in the repository.
+func RecordPullRequest(repoRoot string, number int, action string) error {
+	history, err := LoadPullRequestHistory(repoRoot)
+	if err != nil {
+		return err
+	}
+	history[number] = PullRequestRecord{Number: number, Action: action, At: time.Now()}
+
+	records := make([]PullRequestRecord, 0, len(history))
+	for _, record := range history {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Number < records[j].Number })
+	_, err = writeJSON(repoRoot, historyFile, records)
+	return err
+}
+
+// LoadPullRequestHistory returns the recorded pull requests keyed by number.
+func LoadPullRequestHistory(repoRoot string) (map[int]PullRequestRecord, error) {
+	var records []PullRequestRecord
+	if _, err := readJSON(repoRoot, historyFile, &records); err != nil {
+		return nil, err
+	}
+
+	history := make(map[int]PullRequestRecord, len(records))
+	for _, record := range records {
+		history[record.Number] = record
+	}
+	return history, nil
+}
@@ -0,0 +1,35 @@
+package state
+
+import "time"
+
+const learnedDefaultsFile = "pr-defaults.json"
+
+// PRDefaults are the draft state, labels and reviewers learned from the
+// user's recent pull requests (pr.learn_defaults).
+type PRDefaults struct {
+	Draft     bool      `json:"draft"`
+	Labels    []string  `json:"labels,omitempty"`
+	Reviewers []string  `json:"reviewers,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// SavePRDefaults caches defaults for the repository.
+func SavePRDefaults(repoRoot string, defaults PRDefaults) error {
+	defaults.At = time.Now()
+	_, err := writeJSON(repoRoot, learnedDefaultsFile, defaults)
+	return err
+}
+
+// LoadPRDefaults returns the cached defaults if they are younger than maxAge,
+// or nil.
+func LoadPRDefaults(repoRoot string, maxAge time.Duration) (*PRDefaults, error) {
+	var defaults PRDefaults
+	found, err := readJSON(repoRoot, learnedDefaultsFile, &defaults)
+	if err != nil || !found {
+		return nil, err
+	}
+	if time.Since(defaults.At) > maxAge {
+		return nil, nil
+	}
+	return &defaults, nil
+}
@@ -0,0 +1,38 @@
+package state
+
+import "time"
+
+const lastPullRequestFile = "last-pr.json"
+
+// SavedPullRequest is generated pull request content that was declined at
+// the confirmation prompt or that gh failed to submit.
+type SavedPullRequest struct {
+	Branch  string    `json:"branch"`
+	HeadSHA string    `json:"head_sha"`
+	Title   string    `json:"title"`
+	Body    string    `json:"body"`
+	SavedAt time.Time `json:"saved_at"`
+}
+
+// SavePullRequestContent stores saved and returns the file it was written
+// to.
+func SavePullRequestContent(repoRoot string, saved SavedPullRequest) (string, error) {
+	saved.SavedAt = time.Now()
+	return writeJSON(repoRoot, lastPullRequestFile, saved)
+}
+
+// LoadPullRequestContent returns the saved pull request content, or nil if
+// there is none.
+func LoadPullRequestContent(repoRoot string) (*SavedPullRequest, error) {
+	var saved SavedPullRequest
+	found, err := readJSON(repoRoot, lastPullRequestFile, &saved)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// ClearPullRequestContent removes the saved pull request content.
+func ClearPullRequestContent(repoRoot string) error {
+	return remove(repoRoot, lastPullRequestFile)
+}
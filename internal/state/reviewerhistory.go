@@ -0,0 +1,35 @@
+package state
+
+import "time"
+
+const reviewerHistoryFile = "reviewer-history.json"
+
+// ReviewerHistory are the users who approved the user's recently merged
+// pull requests, most likely reviewer first, without those who already
+// have too many of the user's pull requests to review
+// (pr create --reviewer-from-history).
+type ReviewerHistory struct {
+	Reviewers []string  `json:"reviewers,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// SaveReviewerHistory caches history for the repository.
+func SaveReviewerHistory(repoRoot string, history ReviewerHistory) error {
+	history.At = time.Now()
+	_, err := writeJSON(repoRoot, reviewerHistoryFile, history)
+	return err
+}
+
+// LoadReviewerHistory returns the cached history if it is younger than
+// maxAge, or nil.
+func LoadReviewerHistory(repoRoot string, maxAge time.Duration) (*ReviewerHistory, error) {
+	var history ReviewerHistory
+	found, err := readJSON(repoRoot, reviewerHistoryFile, &history)
+	if err != nil || !found {
+		return nil, err
+	}
+	if time.Since(history.At) > maxAge {
+		return nil, nil
+	}
+	return &history, nil
+}
@@ -0,0 +1,32 @@
+package state
+
+import "time"
+
+const reviewerCandidatesFile = "reviewers.json"
+
+// ReviewerCandidates are the collaborators of a repository, cached so that
+// completing --reviewer never waits for gh.
+type ReviewerCandidates struct {
+	Logins []string  `json:"logins,omitempty"`
+	At     time.Time `json:"at"`
+	// Attempted is when a refresh was last started, so that pressing Tab
+	// repeatedly starts one refresh, not one per key press.
+	Attempted time.Time `json:"attempted"`
+}
+
+// SaveReviewerCandidates caches candidates for the repository as they are.
+func SaveReviewerCandidates(repoRoot string, candidates ReviewerCandidates) error {
+	_, err := writeJSON(repoRoot, reviewerCandidatesFile, candidates)
+	return err
+}
+
+// LoadReviewerCandidates returns the cached candidates, or nil when there
+// are none.
+func LoadReviewerCandidates(repoRoot string) (*ReviewerCandidates, error) {
+	var candidates ReviewerCandidates
+	found, err := readJSON(repoRoot, reviewerCandidatesFile, &candidates)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &candidates, nil
+}
@@ -0,0 +1,118 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Dir returns gelf's state directory ($XDG_STATE_HOME/gelf, falling back to
+// ~/.local/state/gelf).
+func Dir() (string, error) {
+	if xdgStateHome := os.Getenv("XDG_STATE_HOME"); xdgStateHome != "" {
+		return filepath.Join(xdgStateHome, "gelf"), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".local", "state", "gelf"), nil
+}
+
+// RepoDir returns the state directory for the repository rooted at repoRoot.
+func RepoDir(repoRoot string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(repoRoot))
+	return filepath.Join(dir, "repos", hex.EncodeToString(sum[:8])), nil
+}
+
+// Hash returns a stable hash of content, used to detect stale saved state.
+func Hash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// writeJSON writes value to name in the repository's state directory and
+// returns the file path.
+func writeJSON(repoRoot, name string, value any) (string, error) {
+	dir, err := RepoDir(repoRoot)
+	if err != nil {
+		return "", err
+	}
+	return writeJSONIn(dir, name, value)
+}
+
+// writeJSONIn writes value to name in dir. The file is written under a
+// temporary name and renamed into place, so readers never see it half
+// written.
+func writeJSONIn(dir, name string, value any) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, name)
+	file, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// readJSON reads name from the repository's state directory into value. It
+// reports false when the file does not exist.
+func readJSON(repoRoot, name string, value any) (bool, error) {
+	dir, err := RepoDir(repoRoot)
+	if err != nil {
+		return false, err
+	}
+	return readJSONIn(dir, name, value)
+}
+
+func readJSONIn(dir, name string, value any) (bool, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err := json.Unmarshal(data, value); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return true, nil
+}
+
+func remove(repoRoot, name string) error {
+	dir, err := RepoDir(repoRoot)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
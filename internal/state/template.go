@@ -0,0 +1,62 @@
+package state
+
+import "time"
+
+const templateLookupFile = "template-lookup.json"
+
+// templateLookup records when the org pull request template lookup for
+// Owner last timed out.
+type templateLookup struct {
+	Owner string    `json:"owner"`
+	At    time.Time `json:"at"`
+}
+
+// SaveTemplateTimeout records that the org template lookup for owner timed
+// out, so the next runs can skip it.
+func SaveTemplateTimeout(repoRoot, owner string) error {
+	_, err := writeJSON(repoRoot, templateLookupFile, templateLookup{Owner: owner, At: time.Now()})
+	return err
+}
+
+// TemplateTimedOut reports whether the org template lookup for owner timed
+// out within maxAge.
+func TemplateTimedOut(repoRoot, owner string, maxAge time.Duration) (bool, error) {
+	var lookup templateLookup
+	found, err := readJSON(repoRoot, templateLookupFile, &lookup)
+	if err != nil || !found {
+		return false, err
+	}
+	return lookup.Owner == owner && time.Since(lookup.At) <= maxAge, nil
+}
+
+const remoteTemplateFile = "remote-template.json"
+
+// RemoteTemplate is the pull request template found on the default branch
+// of Repo ("owner/name"). Path is empty when Repo has none.
+type RemoteTemplate struct {
+	Repo    string    `json:"repo"`
+	Path    string    `json:"path,omitempty"`
+	Content string    `json:"content,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// SaveRemoteTemplate caches the result of a default branch template lookup.
+func SaveRemoteTemplate(repoRoot string, template RemoteTemplate) error {
+	template.At = time.Now()
+	_, err := writeJSON(repoRoot, remoteTemplateFile, template)
+	return err
+}
+
+// LoadRemoteTemplate returns the cached default branch template lookup for
+// repo, or nil when there is none younger than maxAge.
+func LoadRemoteTemplate(repoRoot, repo string, maxAge time.Duration) (*RemoteTemplate, error) {
+	var template RemoteTemplate
+	found, err := readJSON(repoRoot, remoteTemplateFile, &template)
+	if err != nil || !found {
+		return nil, err
+	}
+	if template.Repo != repo || time.Since(template.At) > maxAge {
+		return nil, nil
+	}
+	return &template, nil
+}
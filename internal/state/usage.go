@@ -0,0 +1,128 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/lock"
+)
+
+const (
+	usageFile = "usage.json"
+	// usageLockFile serializes the updates of usageFile by concurrent runs.
+	usageLockFile = "usage.lock"
+	// usageRetention is how long usage is kept; it covers the current week
+	// with room to spare.
+	usageRetention = 30 * 24 * time.Hour
+)
+
+// UsageEntry is the model use of one command with one model on one day.
+type UsageEntry struct {
+	// Day is the local date, as 2006-01-02.
+	Day     string `json:"day"`
+	Command string `json:"command"`
+	// Provider is the provider the model was called through; it is empty
+	// in entries recorded before it was kept.
+	Provider     string `json:"provider,omitempty"`
+	Model        string `json:"model"`
+	Calls        int    `json:"calls"`
+	InputTokens  int    `json:"input_tokens"`
+	OutputTokens int    `json:"output_tokens"`
+}
+
+// Date returns the local midnight starting e's day.
+func (e UsageEntry) Date() time.Time {
+	day, err := time.ParseInLocation(time.DateOnly, e.Day, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return day
+}
+
+// RecordUsage adds one model call by command through provider at now to the
+// usage kept across repositories, and drops entries older than
+// usageRetention. Concurrent runs update the file one at a time, and a file
+// that cannot be parsed is started over rather than left to fail every run.
+func RecordUsage(now time.Time, command, provider, model string, inputTokens, outputTokens int) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	l, err := lock.Wait(filepath.Join(dir, usageLockFile), nil)
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+
+	entries, err := LoadUsage()
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		entries, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	day := now.Format(time.DateOnly)
+	found := false
+	kept := entries[:0]
+	for _, entry := range entries {
+		if now.Sub(entry.Date()) > usageRetention {
+			continue
+		}
+		if entry.Day == day && entry.Command == command && entry.Provider == provider && entry.Model == model {
+			entry.Calls++
+			entry.InputTokens += inputTokens
+			entry.OutputTokens += outputTokens
+			found = true
+		}
+		kept = append(kept, entry)
+	}
+	if !found {
+		kept = append(kept, UsageEntry{
+			Day:          day,
+			Command:      command,
+			Provider:     provider,
+			Model:        model,
+			Calls:        1,
+			InputTokens:  inputTokens,
+			OutputTokens: outputTokens,
+		})
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		if kept[i].Day != kept[j].Day {
+			return kept[i].Day < kept[j].Day
+		}
+		if kept[i].Command != kept[j].Command {
+			return kept[i].Command < kept[j].Command
+		}
+		if kept[i].Provider != kept[j].Provider {
+			return kept[i].Provider < kept[j].Provider
+		}
+		return kept[i].Model < kept[j].Model
+	})
+
+	_, err = writeJSONIn(dir, usageFile, kept)
+	return err
+}
+
+// LoadUsage returns the recorded model use, oldest day first.
+func LoadUsage() ([]UsageEntry, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	var entries []UsageEntry
+	if _, err := readJSONIn(dir, usageFile, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,69 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordUsage(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.Local)
+
+	for _, call := range []struct{ provider, model string }{
+		{"vertex", "gemini-2.5-flash"},
+		{"vertex", "gemini-2.5-flash"},
+		{"openai", "gpt-4.1-mini"},
+	} {
+		if err := RecordUsage(now, "commit", call.provider, call.model, 100, 10); err != nil {
+			t.Fatalf("RecordUsage: %v", err)
+		}
+	}
+
+	entries, err := LoadUsage()
+	if err != nil {
+		t.Fatalf("LoadUsage: %v", err)
+	}
+	want := []UsageEntry{
+		{Day: "2026-03-02", Command: "commit", Provider: "openai", Model: "gpt-4.1-mini", Calls: 1, InputTokens: 100, OutputTokens: 10},
+		{Day: "2026-03-02", Command: "commit", Provider: "vertex", Model: "gemini-2.5-flash", Calls: 2, InputTokens: 200, OutputTokens: 20},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("LoadUsage() = %+v, want %+v", entries, want)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
+
+func TestRecordUsageResetsUnreadableFile(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir, err := Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	// A write cut short leaves a truncated file behind.
+	if err := os.WriteFile(filepath.Join(dir, usageFile), []byte(`[{"day": "2026-03-`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadUsage(); err == nil {
+		t.Fatal("LoadUsage() of a truncated file succeeded")
+	}
+
+	if err := RecordUsage(time.Now(), "commit", "vertex", "gemini-2.5-flash", 1, 1); err != nil {
+		t.Fatalf("RecordUsage: %v", err)
+	}
+	entries, err := LoadUsage()
+	if err != nil {
+		t.Fatalf("LoadUsage after reset: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Calls != 1 {
+		t.Errorf("LoadUsage() = %+v, want one call", entries)
+	}
+}
@@ -0,0 +1,22 @@
+// Package textwidth measures text the way a terminal or a git log shows it:
+// in columns, where CJK and other full-width characters and most emoji take
+// two, combining marks take none, and a grapheme cluster such as a flag or a
+// ZWJ emoji sequence is counted once.
+package textwidth
+
+import "github.com/clipperhouse/displaywidth"
+
+// options counts characters of ambiguous width as one column regardless of
+// the locale, so a check gives the same result on every machine.
+var options = displaywidth.Options{EastAsianWidth: false}
+
+// Width returns the number of columns s takes.
+func Width(s string) int {
+	return options.String(s)
+}
+
+// Truncate shortens s to at most width columns, ending it with tail when
+// anything was cut. It never splits a grapheme cluster.
+func Truncate(s string, width int, tail string) string {
+	return options.TruncateString(s, width, tail)
+}
@@ -0,0 +1,59 @@
+package textwidth
+
+import "testing"
+
+func TestWidth(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want int
+	}{
+		{"ascii", "fix: handle empty diffs", 23},
+		{"empty", "", 0},
+		{"kana and kanji", "空の差分を扱う", 14},
+		{"mixed", "fix: 空の差分", 13},
+		{"full-width letters", "ｆｅａｔ", 8},
+		{"hangul", "한국어", 6},
+		{"emoji", "🐛", 2},
+		{"emoji with variation selector", "⚠️", 2},
+		{"zwj sequence", "👩‍💻", 2},
+		{"flag", "🇯🇵", 2},
+		{"skin tone", "👍🏽", 2},
+		{"combining acute", "é", 1},
+		{"combining dakuten", "が", 2},
+		{"ambiguous width", "±×÷", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Width(tt.s); got != tt.want {
+				t.Errorf("Width(%q) = %d, want %d", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     string
+		width int
+		want  string
+	}{
+		{"fits", "short", 10, "short"},
+		{"ascii", "fix: handle empty diffs", 10, "fix: hand…"},
+		{"does not split a wide character", "空の差分を扱う", 7, "空の差…"},
+		{"does not split a zwj sequence", "ab👩‍💻cd", 4, "ab…"},
+		{"keeps a combining mark", "éééé", 3, "éé…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Truncate(tt.s, tt.width, "…")
+			if got != tt.want {
+				t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+			}
+			if Width(got) > tt.width {
+				t.Errorf("Truncate(%q, %d) = %q is %d columns wide", tt.s, tt.width, got, Width(got))
+			}
+		})
+	}
+}
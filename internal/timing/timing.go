@@ -0,0 +1,146 @@
+package timing
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is the total time spent in calls with the same name.
+type Entry struct {
+	Name  string
+	Count int
+	Total time.Duration
+}
+
+var (
+	mu      sync.Mutex
+	entries = map[string]*Entry{}
+	notes   []string
+)
+
+// Track starts timing a call named name, such as "vertex generate", and
+// returns a function that stops it. Timings stay in memory and are only
+// printed locally.
+func Track(name string) func() {
+	return StartCall(name).End
+}
+
+func record(name string, elapsed time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	entry, ok := entries[name]
+	if !ok {
+		entry = &Entry{Name: name}
+		entries[name] = entry
+	}
+	entry.Count++
+	entry.Total += elapsed
+}
+
+// Note records a line that Write prints after the timings, such as which
+// path parsed a model response.
+func Note(format string, args ...any) {
+	mu.Lock()
+	defer mu.Unlock()
+	notes = append(notes, fmt.Sprintf(format, args...))
+}
+
+// Output runs cmd like cmd.Output, timing it under CommandName.
+func Output(cmd *exec.Cmd) ([]byte, error) {
+	defer Track(CommandName(cmd))()
+	return cmd.Output()
+}
+
+// CombinedOutput runs cmd like cmd.CombinedOutput, timing it under CommandName.
+func CombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	defer Track(CommandName(cmd))()
+	return cmd.CombinedOutput()
+}
+
+// Run runs cmd like cmd.Run, timing it under CommandName.
+func Run(cmd *exec.Cmd) error {
+	defer Track(CommandName(cmd))()
+	return cmd.Run()
+}
+
+// CommandName names a command by its program and first subcommand words,
+// e.g. "git diff" or "gh pr list", skipping flags and git's -c/-C values.
+func CommandName(cmd *exec.Cmd) string {
+	name := filepath.Base(cmd.Path)
+	if len(cmd.Args) > 0 {
+		name = filepath.Base(cmd.Args[0])
+	}
+
+	words := []string{name}
+	skipValue := false
+	for _, arg := range cmd.Args[1:] {
+		if skipValue {
+			skipValue = false
+			continue
+		}
+		if strings.HasPrefix(arg, "-") {
+			skipValue = name == "git" && (arg == "-c" || arg == "-C")
+			continue
+		}
+		words = append(words, arg)
+		// gh uses two-word subcommands ("pr create"), git uses one.
+		if name != "gh" || len(words) == 3 {
+			break
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Summary returns the recorded timings, slowest first.
+func Summary() []Entry {
+	mu.Lock()
+	defer mu.Unlock()
+
+	summary := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		summary = append(summary, *entry)
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		if summary[i].Total != summary[j].Total {
+			return summary[i].Total > summary[j].Total
+		}
+		return summary[i].Name < summary[j].Name
+	})
+	return summary
+}
+
+// Write prints the summary as an aligned breakdown.
+func Write(out io.Writer) {
+	summary := Summary()
+	if len(summary) == 0 {
+		return
+	}
+
+	width := 0
+	for _, entry := range summary {
+		if len(entry.Name)+1 > width {
+			width = len(entry.Name) + 1
+		}
+	}
+
+	fmt.Fprintln(out, "Timings:")
+	for _, entry := range summary {
+		line := fmt.Sprintf("  %-*s  %5.1fs", width, entry.Name+":", entry.Total.Seconds())
+		if entry.Count > 1 {
+			line += fmt.Sprintf(" (%d calls)", entry.Count)
+		}
+		fmt.Fprintln(out, line)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, note := range notes {
+		fmt.Fprintf(out, "  %s\n", note)
+	}
+}
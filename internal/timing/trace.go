@@ -0,0 +1,156 @@
+package timing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// traceEnv names the file that spans are written to, in Chrome trace-event
+// JSON, so a run can be inspected in Perfetto or chrome://tracing.
+const traceEnv = "GELF_TRACE"
+
+var (
+	tracePath = os.Getenv(traceEnv)
+	epoch     = time.Now()
+	traceMu   sync.Mutex
+	events    []traceEvent
+)
+
+// traceEvent is a complete ("X") event of the trace-event format. Times are
+// in microseconds since the process started.
+type traceEvent struct {
+	Name  string         `json:"name"`
+	Phase string         `json:"ph"`
+	TS    int64          `json:"ts"`
+	Dur   int64          `json:"dur"`
+	PID   int            `json:"pid"`
+	TID   int            `json:"tid"`
+	Args  map[string]any `json:"args,omitempty"`
+}
+
+// Span is a timed operation. Each goroutine gets its own track in the
+// trace, in which the spans it started nest by time, so work running
+// concurrently shows side by side instead of overlapping on one track.
+// Calls started with StartCall also count towards the timing summary;
+// phases started with StartSpan only appear in the trace.
+type Span struct {
+	name      string
+	start     time.Time
+	summarize bool
+	// tid is the ID of the goroutine that started the span.
+	tid int
+
+	mu    sync.Mutex
+	attrs map[string]any
+	ended bool
+}
+
+// StartSpan starts a phase such as "pr context". It is recorded only when
+// GELF_TRACE is set.
+func StartSpan(name string) *Span {
+	return &Span{name: name, start: time.Now(), tid: goroutineID()}
+}
+
+// StartCall starts an external call such as "vertex generate", which is also
+// added to the timing summary.
+func StartCall(name string) *Span {
+	return &Span{name: name, start: time.Now(), summarize: true, tid: goroutineID()}
+}
+
+// goroutineID returns the ID of the calling goroutine, read from the header
+// of its stack trace, or 1 when tracing is off or the header cannot be read.
+func goroutineID() int {
+	if tracePath == "" {
+		return 1
+	}
+	var buf [64]byte
+	header := buf[:runtime.Stack(buf[:], false)]
+	header, ok := bytes.CutPrefix(header, []byte("goroutine "))
+	if !ok {
+		return 1
+	}
+	if i := bytes.IndexByte(header, ' '); i >= 0 {
+		header = header[:i]
+	}
+	id, err := strconv.Atoi(string(header))
+	if err != nil {
+		return 1
+	}
+	return id
+}
+
+// SetAttr attaches an attribute, such as the model or a token count, to the
+// span in the trace.
+func (s *Span) SetAttr(key string, value any) {
+	if tracePath == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attrs == nil {
+		s.attrs = map[string]any{}
+	}
+	s.attrs[key] = value
+}
+
+// End stops the span. Only the first call has an effect, so End can be both
+// deferred and called early.
+func (s *Span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	attrs := s.attrs
+	s.mu.Unlock()
+
+	elapsed := time.Since(s.start)
+	if s.summarize {
+		record(s.name, elapsed)
+	}
+	if tracePath == "" {
+		return
+	}
+
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	events = append(events, traceEvent{
+		Name:  s.name,
+		Phase: "X",
+		TS:    s.start.Sub(epoch).Microseconds(),
+		Dur:   elapsed.Microseconds(),
+		PID:   os.Getpid(),
+		TID:   s.tid,
+		Args:  attrs,
+	})
+}
+
+// WriteTrace writes the recorded spans to the file named by GELF_TRACE. It
+// does nothing when tracing is off.
+func WriteTrace() error {
+	if tracePath == "" {
+		return nil
+	}
+
+	traceMu.Lock()
+	data, err := json.MarshalIndent(map[string]any{
+		"traceEvents":     events,
+		"displayTimeUnit": "ms",
+	}, "", "  ")
+	traceMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(tracePath, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write trace %s: %w", tracePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package timing
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestTraceTracksPerGoroutine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.json")
+	oldPath, oldEvents := tracePath, events
+	tracePath, events = path, nil
+	t.Cleanup(func() { tracePath, events = oldPath, oldEvents })
+
+	root := StartSpan("root")
+	nested := StartSpan("nested")
+	var wg sync.WaitGroup
+	for _, name := range []string{"worker a", "worker b"} {
+		wg.Go(func() {
+			StartSpan(name).End()
+		})
+	}
+	wg.Wait()
+	nested.End()
+	root.End()
+
+	if err := WriteTrace(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var trace struct {
+		TraceEvents []traceEvent `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(data, &trace); err != nil {
+		t.Fatal(err)
+	}
+	tids := map[string]int{}
+	for _, event := range trace.TraceEvents {
+		tids[event.Name] = event.TID
+	}
+	if len(tids) != 4 {
+		t.Fatalf("trace events = %+v, want four spans", trace.TraceEvents)
+	}
+
+	if tids["root"] != tids["nested"] {
+		t.Errorf("nested span is on track %d, root on %d; want the same track", tids["nested"], tids["root"])
+	}
+	if tids["worker a"] == tids["root"] || tids["worker b"] == tids["root"] || tids["worker a"] == tids["worker b"] {
+		t.Errorf("tracks = %v, want a separate track for each goroutine", tids)
+	}
+}
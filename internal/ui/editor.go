@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// EditText opens the user's editor ($VISUAL, then $EDITOR, then vi) on a
+// temporary file containing initial and returns the saved content.
+func EditText(initial string, pattern string) (string, error) {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	path := file.Name()
+	defer os.Remove(path)
+
+	if _, err := file.WriteString(initial); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	editor, cmd := editorCommand(path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited file: %w", err)
+	}
+
+	return string(content), nil
+}
+
+// editorCommand returns the user's editor ($VISUAL, then $EDITOR, then vi)
+// and a command that opens path in it.
+func editorCommand(path string) (string, *exec.Cmd) {
+	editor := os.Getenv("VISUAL")
+	if strings.TrimSpace(editor) == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if strings.TrimSpace(editor) == "" {
+		editor = "vi"
+	}
+
+	args := strings.Fields(editor)
+	return editor, exec.Command(args[0], append(args[1:], path)...)
+}
+
+// commitEditorBuffer places message above the comment lines of a commit
+// template, the way git fills the editor for git commit.
+func commitEditorBuffer(message, template string) string {
+	var comments []string
+	for _, line := range strings.Split(template, "\n") {
+		if strings.HasPrefix(line, "#") {
+			comments = append(comments, line)
+		}
+	}
+	if len(comments) == 0 {
+		return message + "\n"
+	}
+	return message + "\n\n" + strings.Join(comments, "\n") + "\n"
+}
+
+// cleanupCommitMessage drops comment lines and surrounding blank lines, like
+// git's default commit.cleanup mode.
+func cleanupCommitMessage(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, strings.TrimRight(line, " \t"))
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
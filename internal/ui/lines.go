@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// wrappedLines holds rendered text as lines wrapped to a width, so that
+// showing a screenful of it costs the same however long the text is. Text is
+// wrapped once when it is added, and all of it again only when the width
+// changes.
+type wrappedLines struct {
+	width int
+	// source holds the text added so far split at newlines. When the text
+	// does not end with a newline, open is set and the next text continues
+	// the last source line.
+	source []string
+	open   bool
+	// lines holds the source lines wrapped to width; starts holds the
+	// index in lines of the first line of each source line.
+	lines  []string
+	starts []int
+}
+
+// Append adds rendered text, wrapping only the lines it adds or continues.
+func (w *wrappedLines) Append(text string) {
+	if text == "" {
+		return
+	}
+	parts := strings.Split(text, "\n")
+	if w.open {
+		last := len(w.source) - 1
+		parts[0] = w.source[last] + parts[0]
+		w.lines = w.lines[:w.starts[last]]
+		w.source = w.source[:last]
+		w.starts = w.starts[:last]
+	}
+	w.open = !strings.HasSuffix(text, "\n")
+	if !w.open {
+		parts = parts[:len(parts)-1]
+	}
+	for _, line := range parts {
+		w.add(line)
+	}
+}
+
+// add adds a source line and its wrapped lines.
+func (w *wrappedLines) add(line string) {
+	w.source = append(w.source, line)
+	w.starts = append(w.starts, len(w.lines))
+	if w.width <= 0 || ansi.StringWidth(line) <= w.width {
+		w.lines = append(w.lines, line)
+		return
+	}
+	w.lines = append(w.lines, strings.Split(ansi.Wrap(line, w.width, ""), "\n")...)
+}
+
+// SetWidth wraps the text to width. A width of 0 or less leaves lines
+// unwrapped.
+func (w *wrappedLines) SetWidth(width int) {
+	if width == w.width {
+		return
+	}
+	w.width = width
+	source := w.source
+	w.source = make([]string, 0, len(source))
+	w.lines = w.lines[:0]
+	w.starts = w.starts[:0]
+	for _, line := range source {
+		w.add(line)
+	}
+}
+
+// Len returns the number of wrapped lines.
+func (w *wrappedLines) Len() int {
+	return len(w.lines)
+}
+
+// Slice returns at most height wrapped lines from offset on. The result
+// shares memory with w and is only valid until the next change.
+func (w *wrappedLines) Slice(offset, height int) []string {
+	offset = max(0, min(offset, len(w.lines)))
+	end := max(offset, min(offset+height, len(w.lines)))
+	return w.lines[offset:end]
+}
@@ -1,8 +1,68 @@
 package ui
 
-import "charm.land/glamour/v2"
+import (
+	"errors"
+	"sync"
+
+	"charm.land/glamour/v2"
+)
+
+// MaxStyledMarkdown is the largest markdown, in bytes, styled with glamour.
+// Styling a body of a few thousand lines takes long enough to make the
+// terminal lag, so larger input is shown as plain markdown instead.
+const MaxStyledMarkdown = 64 * 1024
+
+// ErrTooLargeToStyle is returned by RenderMarkdown for input larger than
+// MaxStyledMarkdown; callers show the markdown unstyled.
+var ErrTooLargeToStyle = errors.New("body too large to style — showing plain markdown")
+
+type renderKey struct {
+	markdown string
+	useColor bool
+}
+
+// renderCache keeps recent renderings so that showing the same body again,
+// e.g. when toggling the template diff, does not style it a second time.
+var renderCache = struct {
+	sync.Mutex
+	entries map[renderKey]string
+}{entries: map[renderKey]string{}}
+
+const maxRenderCacheEntries = 8
 
 func RenderMarkdown(markdown string, useColor bool) (string, error) {
+	if len(markdown) > MaxStyledMarkdown {
+		return "", ErrTooLargeToStyle
+	}
+
+	key := renderKey{markdown: markdown, useColor: useColor}
+	renderCache.Lock()
+	rendered, ok := renderCache.entries[key]
+	renderCache.Unlock()
+	if ok {
+		return rendered, nil
+	}
+
+	renderer, err := newMarkdownRenderer(useColor)
+	if err != nil {
+		return "", err
+	}
+
+	rendered, err = renderer.Render(markdown)
+	if err != nil {
+		return "", err
+	}
+
+	renderCache.Lock()
+	if len(renderCache.entries) >= maxRenderCacheEntries {
+		clear(renderCache.entries)
+	}
+	renderCache.entries[key] = rendered
+	renderCache.Unlock()
+	return rendered, nil
+}
+
+func newMarkdownRenderer(useColor bool) (*glamour.TermRenderer, error) {
 	opts := []glamour.TermRendererOption{
 		glamour.WithWordWrap(0),
 	}
@@ -13,10 +73,5 @@ func RenderMarkdown(markdown string, useColor bool) (string, error) {
 		opts = append(opts, glamour.WithStandardStyle("ascii"))
 	}
 
-	renderer, err := glamour.NewTermRenderer(opts...)
-	if err != nil {
-		return "", err
-	}
-
-	return renderer.Render(markdown)
+	return glamour.NewTermRenderer(opts...)
 }
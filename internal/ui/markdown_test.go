@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// generatedBody returns a pull request body of about lines lines, made of
+// sections with paragraphs, lists and code.
+func generatedBody(lines int) string {
+	var builder strings.Builder
+	for section := 0; builder.Len() == 0 || strings.Count(builder.String(), "\n") < lines; section++ {
+		fmt.Fprintf(&builder, "## Section %d\n\n", section)
+		fmt.Fprintf(&builder, "Section %d retries model calls on **rate limits** and `5xx` answers.\n\n", section)
+		for item := range 5 {
+			fmt.Fprintf(&builder, "- item %d of section %d\n", item, section)
+		}
+		builder.WriteString("\n```go\nfor attempt := range 3 {\n\tcall()\n}\n```\n\n")
+	}
+	return builder.String()
+}
+
+func TestRenderMarkdownTooLarge(t *testing.T) {
+	_, err := RenderMarkdown(strings.Repeat("word ", MaxStyledMarkdown/5+1), false)
+	if !errors.Is(err, ErrTooLargeToStyle) {
+		t.Errorf("RenderMarkdown() error = %v, want ErrTooLargeToStyle", err)
+	}
+}
+
+// BenchmarkRenderMarkdown measures a viewport refresh of a 2,000-line body:
+// uncached is the cost of styling it on every refresh, as before the cache,
+// and cached the cost of a refresh that finds it in the cache.
+func BenchmarkRenderMarkdown(b *testing.B) {
+	body := generatedBody(2000)
+	if len(body) > MaxStyledMarkdown {
+		b.Fatalf("body is %d bytes, over MaxStyledMarkdown", len(body))
+	}
+
+	b.Run("uncached", func(b *testing.B) {
+		for b.Loop() {
+			renderCache.Lock()
+			clear(renderCache.entries)
+			renderCache.Unlock()
+			if _, err := RenderMarkdown(body, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		if _, err := RenderMarkdown(body, false); err != nil {
+			b.Fatal(err)
+		}
+		for b.Loop() {
+			if _, err := RenderMarkdown(body, false); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
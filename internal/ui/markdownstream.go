@@ -0,0 +1,198 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+
+	"charm.land/glamour/v2"
+)
+
+var (
+	// listItemRegex matches the first line of a list item.
+	listItemRegex = regexp.MustCompile(`^\s*(?:[-*+]|\d{1,9}[.)])(?:[ \t]|$)`)
+	// referenceDefinitionRegex matches a link reference definition, such as
+	// "[docs]: https://example.com".
+	referenceDefinitionRegex = regexp.MustCompile(`(?m)^ {0,3}\[([^\]]+)\]:[ \t]*\S.*$`)
+	// referenceLinkRegex matches a full or collapsed reference link, such as
+	// "[the docs][docs]" or "[docs][]".
+	referenceLinkRegex = regexp.MustCompile(`\[([^\]]+)\]\[([^\]]*)\]`)
+)
+
+// MarkdownStream renders markdown that arrives in chunks. Each top-level
+// block is rendered once, when it is complete, so the cost stays linear in
+// the length of the stream instead of re-rendering everything received so
+// far on every chunk.
+//
+// A block ends at a blank line followed by a line that starts a new block:
+// blank lines inside a code fence, between the items of a list or before an
+// indented continuation do not end one, since rendering the parts apart
+// would break them up. Reference definitions seen so far are rendered along
+// with every block, and a block using a reference that is not defined yet
+// is held back, with everything after it, until the definition arrives or
+// the stream ends.
+type MarkdownStream struct {
+	renderer *glamour.TermRenderer
+	pending  strings.Builder
+	// scanned is how far pending has been checked for block ends.
+	scanned int
+	// fence is the marker of the code fence open at scanned, or "".
+	fence string
+	// inList is whether the block open at scanned is a list.
+	inList bool
+	// afterBlank is whether the line before scanned is blank.
+	afterBlank bool
+	// held is the completed blocks waiting for a reference definition.
+	held string
+	// definitions maps the normalized labels of the reference definitions
+	// seen so far to their lines.
+	definitions map[string]string
+	labels      []string
+}
+
+// NewMarkdownStream returns a stream rendering with the same style as
+// RenderMarkdown.
+func NewMarkdownStream(useColor bool) (*MarkdownStream, error) {
+	renderer, err := newMarkdownRenderer(useColor)
+	if err != nil {
+		return nil, err
+	}
+	return &MarkdownStream{renderer: renderer, definitions: map[string]string{}}, nil
+}
+
+// Write adds a chunk and returns the rendering of the blocks it completed,
+// or "" if the trailing block is still open.
+func (s *MarkdownStream) Write(chunk string) (string, error) {
+	s.pending.WriteString(chunk)
+	text := s.pending.String()
+
+	end := -1
+	for {
+		newline := strings.IndexByte(text[s.scanned:], '\n')
+		if newline < 0 {
+			break
+		}
+		lineStart := s.scanned
+		s.scanned += newline + 1
+		if s.scanLine(text[lineStart : s.scanned-1]) {
+			end = lineStart
+		}
+	}
+	if end <= 0 {
+		return "", nil
+	}
+
+	s.pending.Reset()
+	s.pending.WriteString(text[end:])
+	s.scanned -= end
+
+	blocks := s.held + text[:end]
+	s.addDefinitions(blocks)
+	if s.unresolved(blocks) {
+		s.held = blocks
+		return "", nil
+	}
+	s.held = ""
+	return s.render(blocks)
+}
+
+// scanLine updates the state of the stream for the next line and reports
+// whether a new top-level block starts at it.
+func (s *MarkdownStream) scanLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if s.fence != "" {
+		if run := fenceRun(trimmed); run != "" && run[0] == s.fence[0] && len(run) >= len(s.fence) && run == trimmed {
+			s.fence = ""
+		}
+		return false
+	}
+	if trimmed == "" {
+		s.afterBlank = true
+		return false
+	}
+
+	starts := false
+	indented := line[0] == ' ' || line[0] == '\t'
+	if s.afterBlank && !indented && !(s.inList && listItemRegex.MatchString(line)) {
+		starts = true
+		s.inList = false
+	}
+	s.afterBlank = false
+	if !indented && listItemRegex.MatchString(line) {
+		s.inList = true
+	}
+	s.fence = fenceRun(trimmed)
+	return starts
+}
+
+// fenceRun returns the run of backticks or tildes that opens or closes a
+// code fence on trimmed, or "" if trimmed is not a fence line.
+func fenceRun(trimmed string) string {
+	if trimmed == "" || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return ""
+	}
+	n := len(trimmed) - len(strings.TrimLeft(trimmed, trimmed[:1]))
+	if n < 3 {
+		return ""
+	}
+	return trimmed[:n]
+}
+
+// addDefinitions records the reference definitions of text.
+func (s *MarkdownStream) addDefinitions(text string) {
+	for _, match := range referenceDefinitionRegex.FindAllStringSubmatch(text, -1) {
+		label := referenceLabel(match[1])
+		if _, ok := s.definitions[label]; !ok {
+			s.definitions[label] = strings.TrimSpace(match[0])
+			s.labels = append(s.labels, label)
+		}
+	}
+}
+
+// unresolved reports whether text uses a reference that is not defined.
+func (s *MarkdownStream) unresolved(text string) bool {
+	for _, match := range referenceLinkRegex.FindAllStringSubmatch(text, -1) {
+		label := match[2]
+		if label == "" {
+			label = match[1]
+		}
+		if _, ok := s.definitions[referenceLabel(label)]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// referenceLabel normalizes a reference label, which matches regardless of
+// case and of how its words are spaced.
+func referenceLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// Flush renders whatever is left once the stream has ended.
+func (s *MarkdownStream) Flush() (string, error) {
+	text := s.held + s.pending.String()
+	s.addDefinitions(text)
+	s.pending.Reset()
+	s.held = ""
+	s.scanned = 0
+	s.fence = ""
+	s.inList = false
+	s.afterBlank = false
+	return s.render(text)
+}
+
+// render renders blocks with the reference definitions seen so far, which
+// render as nothing but resolve the links of blocks that only use them.
+func (s *MarkdownStream) render(blocks string) (string, error) {
+	if strings.TrimSpace(blocks) == "" {
+		return "", nil
+	}
+	if len(s.labels) > 0 {
+		definitions := make([]string, len(s.labels))
+		for i, label := range s.labels {
+			definitions[i] = s.definitions[label]
+		}
+		blocks = strings.TrimRight(blocks, "\n") + "\n\n" + strings.Join(definitions, "\n") + "\n"
+	}
+	return s.renderer.Render(blocks)
+}
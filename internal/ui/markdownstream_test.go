@@ -0,0 +1,163 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+// streamMarkdown writes markdown to a stream a byte at a time and returns
+// what the writes rendered, in order, and what Flush rendered.
+func streamMarkdown(t *testing.T, markdown string) ([]string, string) {
+	t.Helper()
+	stream, err := NewMarkdownStream(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written []string
+	for i := range len(markdown) {
+		rendered, err := stream.Write(markdown[i : i+1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rendered != "" {
+			written = append(written, rendered)
+		}
+	}
+	flushed, err := stream.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return written, flushed
+}
+
+func TestMarkdownStreamBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		// first holds text the first rendered block must show, and notFirst
+		// text it must not, having been left for a later block.
+		first    []string
+		notFirst []string
+	}{
+		{
+			name:     "paragraphs",
+			markdown: "First paragraph.\n\nSecond paragraph.\n\nThird paragraph.\n",
+			first:    []string{"First paragraph."},
+			notFirst: []string{"Second paragraph."},
+		},
+		{
+			name:     "loose list",
+			markdown: "1. one\n\n2. two\n\n3. three\n\nAfter the list.\n",
+			first:    []string{"one", "two", "three"},
+			notFirst: []string{"After the list."},
+		},
+		{
+			name:     "list item continuation",
+			markdown: "- item\n\n  more of the item\n\n- next item\n\nAfter the list.\n",
+			first:    []string{"item", "more of the item", "next item"},
+			notFirst: []string{"After the list."},
+		},
+		{
+			name:     "blank line in a fence",
+			markdown: "```go\na := 1\n\nb := 2\n```\n\nAfter the code.\n",
+			first:    []string{"a := 1", "b := 2"},
+			notFirst: []string{"After the code."},
+		},
+		{
+			name:     "shorter run in a fence",
+			markdown: "````\n```\n\nstill code\n````\n\nAfter the code.\n",
+			first:    []string{"still code"},
+			notFirst: []string{"After the code."},
+		},
+		{
+			name:     "indented code",
+			markdown: "Intro.\n\n    code line\n\n    more code\n\nAfter the code.\n",
+			first:    []string{"code line", "more code"},
+			notFirst: []string{"After the code."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			written, flushed := streamMarkdown(t, tt.markdown)
+			if len(written) == 0 {
+				t.Fatalf("nothing was rendered before Flush; Flush rendered %q", flushed)
+			}
+			for _, want := range tt.first {
+				if !strings.Contains(written[0], want) {
+					t.Errorf("first block %q does not show %q", written[0], want)
+				}
+			}
+			for _, notWant := range tt.notFirst {
+				if strings.Contains(written[0], notWant) {
+					t.Errorf("first block %q shows %q", written[0], notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestMarkdownStreamReferenceDefinedLater(t *testing.T) {
+	written, flushed := streamMarkdown(t, "See [the docs][docs].\n\nMore text.\n\n[docs]: https://example.com/docs\n")
+	if len(written) != 0 {
+		t.Errorf("blocks using an undefined reference were rendered early: %q", written)
+	}
+	if !strings.Contains(flushed, "https://example.com/docs") || !strings.Contains(flushed, "More text.") {
+		t.Errorf("Flush() = %q, want the link resolved and the held blocks", flushed)
+	}
+}
+
+func TestMarkdownStreamReferenceDefinedEarlier(t *testing.T) {
+	written, _ := streamMarkdown(t, "[docs]: https://example.com/docs\n\nSee [the docs][docs].\n\nMore text.\n")
+	var link string
+	for _, rendered := range written {
+		if strings.Contains(rendered, "the docs") {
+			link = rendered
+		}
+	}
+	if !strings.Contains(link, "https://example.com/docs") {
+		t.Errorf("rendered blocks = %q, want the link resolved", written)
+	}
+}
+
+// BenchmarkMarkdownStream measures rendering a streamed review in 64-byte
+// chunks: rerender styles everything received so far on every chunk, as
+// before the stream, and stream renders each block once.
+func BenchmarkMarkdownStream(b *testing.B) {
+	review := generatedBody(200)
+	var chunks []string
+	for start := 0; start < len(review); start += 64 {
+		chunks = append(chunks, review[start:min(start+64, len(review))])
+	}
+
+	b.Run("rerender", func(b *testing.B) {
+		renderer, err := newMarkdownRenderer(false)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for b.Loop() {
+			var received strings.Builder
+			for _, chunk := range chunks {
+				received.WriteString(chunk)
+				if _, err := renderer.Render(received.String()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("stream", func(b *testing.B) {
+		for b.Loop() {
+			stream, err := NewMarkdownStream(false)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for _, chunk := range chunks {
+				if _, err := stream.Write(chunk); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if _, err := stream.Flush(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// PromptMultiSelectWithWriter shows items as a checklist with every item
+// checked. Space toggles the item under the cursor, "a" toggles all and Enter
+// confirms. It returns which items are checked and false when the user
+// cancelled with q, Esc or Ctrl+C. It needs an interactive terminal.
+func PromptMultiSelectWithWriter(prompt string, items []string, out io.Writer) ([]bool, bool, error) {
+	checked := make([]bool, len(items))
+	for i := range checked {
+		checked[i] = true
+	}
+	return PromptChecklistWithWriter(prompt, items, checked, out)
+}
+
+// PromptChecklistWithWriter is PromptMultiSelectWithWriter with the items
+// in checked checked at first and the others not, for items that are only
+// suggestions.
+func PromptChecklistWithWriter(prompt string, items []string, checked []bool, out io.Writer) ([]bool, bool, error) {
+	if out == nil {
+		out = os.Stdout
+	}
+	if !IsInteractive() {
+		return nil, false, fmt.Errorf("selecting items requires an interactive terminal")
+	}
+
+	checked = append([]bool(nil), checked...)
+	m := &multiSelectModel{prompt: promptStyle.Render(prompt), items: items, checked: checked}
+	if err := runProgram(m, tea.WithOutput(out)); err != nil {
+		return nil, false, err
+	}
+	return m.checked, m.confirmed, nil
+}
+
+type multiSelectModel struct {
+	prompt    string
+	items     []string
+	checked   []bool
+	cursor    int
+	confirmed bool
+	done      bool
+}
+
+func (m *multiSelectModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *multiSelectModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.items)-1 {
+				m.cursor++
+			}
+		case " ", "x":
+			m.checked[m.cursor] = !m.checked[m.cursor]
+		case "a":
+			all := true
+			for _, checked := range m.checked {
+				all = all && checked
+			}
+			for i := range m.checked {
+				m.checked[i] = !all
+			}
+		case "enter":
+			m.confirmed = true
+			m.done = true
+			return m, tea.Quit
+		case "q", "esc", "ctrl+c", "ctrl+d":
+			m.done = true
+			return m, tea.Quit
+		}
+	}
+
+	return m, nil
+}
+
+func (m *multiSelectModel) View() string {
+	if m.done {
+		return ""
+	}
+
+	lines := []string{m.prompt, editPromptStyle.Render(i18n.T("select.hint"))}
+	for i, item := range m.items {
+		cursor := "  "
+		if i == m.cursor {
+			cursor = "> "
+		}
+		box := "[ ]"
+		if m.checked[i] {
+			box = addedStyle.Render("[x]")
+		}
+		lines = append(lines, fmt.Sprintf("%s%s %s", cursor, box, item))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
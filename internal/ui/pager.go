@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/term"
+)
+
+// Pager shows rendered text in a full-screen view that scrolls with
+// up/down (or j/k), PgUp/PgDn (or b/f and space) and Home/End (or g/G), and
+// closes with q, Esc or Ctrl+C. Text can be added while it is shown, such as
+// the blocks of a MarkdownStream as a review streams in. Scrolling only
+// looks at the lines on screen, so it does not slow down as the text grows.
+type Pager struct {
+	program *tea.Program
+	done    chan struct{}
+	err     error
+}
+
+// StartPager shows an empty pager titled title. It needs an interactive
+// terminal.
+func StartPager(title string) (*Pager, error) {
+	if !IsInteractive() {
+		return nil, fmt.Errorf("the pager requires an interactive terminal")
+	}
+	program, run := newSafeProgram(&pagerModel{title: title}, tea.WithAltScreen())
+	p := &Pager{program: program, done: make(chan struct{})}
+	go func() {
+		p.err = run()
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// Page shows text in a pager titled title until the user closes it.
+func Page(title, text string) error {
+	p, err := StartPager(title)
+	if err != nil {
+		return err
+	}
+	p.Append(text)
+	return p.Wait()
+}
+
+// Append adds rendered text to the end of the pager. It does nothing once
+// the pager is closed.
+func (p *Pager) Append(text string) {
+	p.program.Send(pagerAppendMsg(text))
+}
+
+// Done is closed when the user closes the pager.
+func (p *Pager) Done() <-chan struct{} {
+	return p.done
+}
+
+// Wait marks the text as complete and waits for the user to close the
+// pager.
+func (p *Pager) Wait() error {
+	p.program.Send(pagerCompleteMsg{})
+	<-p.done
+	return p.err
+}
+
+// Close closes the pager without waiting for the user, such as when the
+// text could not be produced, and restores the terminal.
+func (p *Pager) Close() {
+	p.program.Quit()
+	<-p.done
+}
+
+// tallerThanTerminal reports whether text has more lines than the terminal
+// on stdout has rows, so that it scrolls out of view when printed.
+func tallerThanTerminal(text string) bool {
+	if !isTerminalWriter(os.Stdout) {
+		return false
+	}
+	_, height, err := term.GetSize(int(os.Stdout.Fd()))
+	return err == nil && strings.Count(text, "\n")+1 > height
+}
+
+// pagerAppendMsg adds text to a pagerModel.
+type pagerAppendMsg string
+
+// pagerCompleteMsg tells a pagerModel that no more text will be added.
+type pagerCompleteMsg struct{}
+
+type pagerModel struct {
+	title  string
+	lines  wrappedLines
+	offset int
+	height int
+	// complete is whether all of the text has been added.
+	complete bool
+}
+
+func (m *pagerModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *pagerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+		m.lines.SetWidth(msg.Width)
+	case pagerAppendMsg:
+		m.lines.Append(string(msg))
+	case pagerCompleteMsg:
+		m.complete = true
+	case tea.KeyMsg:
+		page := max(1, m.pageHeight())
+		switch msg.String() {
+		case "up", "k":
+			m.offset--
+		case "down", "j", "enter":
+			m.offset++
+		case "pgup", "b":
+			m.offset -= page
+		case "pgdown", "f", " ":
+			m.offset += page
+		case "home", "g":
+			m.offset = 0
+		case "end", "G":
+			m.offset = m.lines.Len()
+		case "q", "esc", "ctrl+c":
+			return m, tea.Quit
+		}
+	}
+	m.offset = max(0, min(m.offset, m.lines.Len()-m.pageHeight()))
+	return m, nil
+}
+
+// pageHeight returns how many lines of text fit above the status line.
+func (m *pagerModel) pageHeight() int {
+	return max(0, m.height-1)
+}
+
+func (m *pagerModel) View() string {
+	if m.height == 0 {
+		return ""
+	}
+	page := m.pageHeight()
+	lines := m.lines.Slice(m.offset, page)
+
+	var view strings.Builder
+	for _, line := range lines {
+		view.WriteString(line)
+		view.WriteByte('\n')
+	}
+	for range page - len(lines) {
+		view.WriteByte('\n')
+	}
+	status := i18n.T("pager.status", m.title, min(m.offset+1, m.lines.Len()), m.offset+len(lines), m.lines.Len())
+	if !m.complete {
+		status += i18n.T("pager.loading")
+	}
+	view.WriteString(editPromptStyle.Render(status))
+	return view.String()
+}
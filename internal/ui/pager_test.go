@@ -0,0 +1,206 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestWrappedLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		width  int
+		want   []string
+	}{
+		{
+			name:   "whole lines",
+			chunks: []string{"one\ntwo\n", "three\n"},
+			want:   []string{"one", "two", "three"},
+		},
+		{
+			name:   "line continued by the next chunk",
+			chunks: []string{"retries on ", "rate limits\nand 5xx"},
+			want:   []string{"retries on rate limits", "and 5xx"},
+		},
+		{
+			name:   "wrapped at words",
+			chunks: []string{"retries model calls on rate limits\n"},
+			width:  12,
+			want:   []string{"retries", "model calls", "on rate", "limits"},
+		},
+		{
+			name:   "continued line wrapped again",
+			chunks: []string{"retries model ", "calls on rate limits\n", "done\n"},
+			width:  12,
+			want:   []string{"retries", "model calls", "on rate", "limits", "done"},
+		},
+		{
+			name:   "styles do not count toward the width",
+			chunks: []string{"\x1b[1mretries\x1b[0m model\n"},
+			width:  13,
+			want:   []string{"\x1b[1mretries\x1b[0m model"},
+		},
+		{
+			name:   "empty lines are kept",
+			chunks: []string{"\n\nend\n"},
+			width:  10,
+			want:   []string{"", "", "end"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines wrappedLines
+			lines.SetWidth(tt.width)
+			for _, chunk := range tt.chunks {
+				lines.Append(chunk)
+			}
+			if got := lines.Slice(0, 100); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrappedLinesSetWidth(t *testing.T) {
+	var lines wrappedLines
+	lines.Append("retries model calls on rate limits\nand server errors\n")
+	if lines.Len() != 2 {
+		t.Fatalf("unwrapped Len() = %d, want 2", lines.Len())
+	}
+
+	lines.SetWidth(12)
+	if got := strings.Join(lines.Slice(0, 100), "|"); got != "retries|model calls|on rate|limits|and server|errors" {
+		t.Errorf("at 12 columns: %q", got)
+	}
+	lines.Append("again\n")
+	lines.SetWidth(80)
+	if got := strings.Join(lines.Slice(0, 100), "|"); got != "retries model calls on rate limits|and server errors|again" {
+		t.Errorf("at 80 columns: %q", got)
+	}
+}
+
+func TestWrappedLinesSlice(t *testing.T) {
+	var lines wrappedLines
+	lines.Append("a\nb\nc\n")
+	tests := []struct {
+		offset, height int
+		want           string
+	}{
+		{0, 2, "a|b"},
+		{1, 5, "b|c"},
+		{3, 2, ""},
+		{7, 2, ""},
+		{-1, 1, "a"},
+		{0, 0, ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(lines.Slice(tt.offset, tt.height), "|"); got != tt.want {
+			t.Errorf("Slice(%d, %d) = %q, want %q", tt.offset, tt.height, got, tt.want)
+		}
+	}
+}
+
+// pagerKey returns the key message of key.
+func pagerKey(key string) tea.KeyMsg {
+	switch key {
+	case "pgdown":
+		return tea.KeyMsg{Type: tea.KeyPgDown}
+	case "end":
+		return tea.KeyMsg{Type: tea.KeyEnd}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+}
+
+func TestPagerScroll(t *testing.T) {
+	m := &pagerModel{title: "review"}
+	m.Update(tea.WindowSizeMsg{Width: 40, Height: 6})
+	for i := range 20 {
+		m.Update(pagerAppendMsg(fmt.Sprintf("line %d\n", i)))
+	}
+
+	tests := []struct {
+		key    string
+		offset int
+	}{
+		{"k", 0},
+		{"j", 1},
+		{"pgdown", 6},
+		{"end", 15},
+		{"j", 15},
+		{"b", 10},
+		{"g", 0},
+	}
+	for _, tt := range tests {
+		m.Update(pagerKey(tt.key))
+		if m.offset != tt.offset {
+			t.Errorf("after %s offset = %d, want %d", tt.key, m.offset, tt.offset)
+		}
+	}
+
+	m.Update(pagerKey("j"))
+	view := m.View()
+	if got := strings.Count(view, "\n"); got != 5 {
+		t.Errorf("view has %d lines above the status line, want 5", got)
+	}
+	if !strings.HasPrefix(view, "line 1\n") || !strings.Contains(view, "line 5\n") {
+		t.Errorf("view = %q, want lines 1 to 5", view)
+	}
+	if !strings.Contains(view, "2-6") || !strings.Contains(view, "20") {
+		t.Errorf("status line of %q does not show lines 2-6 of 20", view)
+	}
+
+	if _, cmd := m.Update(pagerKey("q")); cmd == nil {
+		t.Error("q did not quit")
+	}
+}
+
+func TestPagerShortText(t *testing.T) {
+	m := &pagerModel{title: "review"}
+	m.Update(tea.WindowSizeMsg{Width: 40, Height: 10})
+	m.Update(pagerAppendMsg("only line\n"))
+	m.Update(pagerKey("end"))
+	if m.offset != 0 {
+		t.Errorf("offset = %d, want 0 for text shorter than the screen", m.offset)
+	}
+	if view := m.View(); strings.Count(view, "\n") != 9 {
+		t.Errorf("view = %q, want the status line on the last row", view)
+	}
+}
+
+// reviewLines returns rendered review text of about size bytes: styled
+// headings and paragraphs longer than the benchmark width.
+func reviewLines(size int) string {
+	var builder strings.Builder
+	for i := 0; builder.Len() < size; i++ {
+		fmt.Fprintf(&builder, "\x1b[1m## Finding %d\x1b[0m\n\n", i)
+		fmt.Fprintf(&builder, "  - **medium** internal/ai/retry.go:%d: the retry loop waits on a timer that is not stopped when the context ends, so a cancelled call holds the timer until it fires; stop it before returning.\n\n", i)
+	}
+	return builder.String()
+}
+
+// BenchmarkPagerScroll measures a key press and the redraw it causes in
+// pagers holding reviews of growing size. The time per scroll stays the
+// same: only the lines on screen are looked at, and none is wrapped again.
+func BenchmarkPagerScroll(b *testing.B) {
+	for _, size := range []int{20 << 10, 200 << 10, 2 << 20} {
+		b.Run(fmt.Sprintf("%dKB", size>>10), func(b *testing.B) {
+			m := &pagerModel{title: "review", complete: true}
+			m.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+			m.Update(pagerAppendMsg(reviewLines(size)))
+			down, up := pagerKey("j"), pagerKey("k")
+
+			b.ResetTimer()
+			for i := range b.N {
+				if i%2000 < 1000 {
+					m.Update(down)
+				} else {
+					m.Update(up)
+				}
+				_ = m.View()
+			}
+		})
+	}
+}
@@ -2,71 +2,378 @@ package ui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/EkeMinusYou/gelf/internal/ai"
 	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	"github.com/EkeMinusYou/gelf/internal/prbody"
+	"github.com/EkeMinusYou/gelf/pkg/gelf"
 )
 
 type prModel struct {
-	aiClient       *ai.VertexAIClient
-	input          ai.PullRequestInput
+	generator      *gelf.Generator
+	request        gelf.PRRequest
 	diffSummary    git.DiffSummary
 	commitLines    []string
+	commitNote     string
+	size           string
 	render         bool
 	useColor       bool
 	renderedBody   string
+	tooLarge       bool
 	content        *ai.PullRequestContent
 	printedContext bool
+	fullContext    bool
 	confirmPrompt  string
+	pending        func() (*ai.PullRequestContent, error)
+	warnings       func(*ai.PullRequestContent) []string
+	fit            func(*ai.PullRequestContent) []string
+	// fitNotes holds what fit reported for each attempt, so the notes stay
+	// with it when it is shown again.
+	fitNotes map[*ai.PullRequestContent][]string
+	// attempts holds every generation of this session, oldest first;
+	// content is the one being shown.
+	attempts []*ai.PullRequestContent
 }
 
-func NewPRTUI(aiClient *ai.VertexAIClient, input ai.PullRequestInput, render bool, useColor bool, confirmPrompt string) *prModel {
-	diffSummary := git.ParseDiffSummary(input.Diff)
-	commitLines := parseCommitLines(input.CommitLog)
+func NewPRTUI(generator *gelf.Generator, request gelf.PRRequest, render bool, useColor bool, confirmPrompt string) *prModel {
+	diffSummary := git.ParseDiffSummary(request.Diff)
+	commitLines := parseCommitLines(request.CommitLog)
 
 	return &prModel{
-		aiClient:    aiClient,
-		input:       input,
+		generator:   generator,
+		request:     request,
 		diffSummary: diffSummary,
 		commitLines: commitLines,
 		render:      render,
 		useColor:    useColor,
 		confirmPrompt: func() string {
 			if strings.TrimSpace(confirmPrompt) == "" {
-				return "Create this pull request? (y)es / (n)o"
+				return i18n.T("pr.confirm_create")
 			}
 			return confirmPrompt
 		}(),
 	}
 }
 
+// SetWarnings sets a function whose result is listed under the generated
+// content, just above the confirmation prompt.
+func (m *prModel) SetWarnings(warnings func(*ai.PullRequestContent) []string) {
+	m.warnings = warnings
+}
+
+// SetFit sets a function that fits each generated attempt to the limits of
+// the pull request before it is shown, so what is confirmed is what gets
+// posted. The notes it returns are listed with the warnings.
+func (m *prModel) SetFit(fit func(*ai.PullRequestContent) []string) {
+	m.fit = fit
+	m.fitNotes = make(map[*ai.PullRequestContent][]string)
+}
+
+// SetCommitSelection notes in the context header that only considered of
+// total commits inform the description (--select-commits).
+func (m *prModel) SetCommitSelection(considered, total int) {
+	m.commitNote = fmt.Sprintf("%d of %d commits considered", considered, total)
+}
+
+// SetSize shows the size label the pull request will get under its title
+// (--label-from-diff).
+func (m *prModel) SetSize(size string) {
+	m.size = size
+}
+
+// UsePending makes Run wait for content generated elsewhere instead of
+// calling the AI itself.
+func (m *prModel) UsePending(wait func() (*ai.PullRequestContent, error)) {
+	m.pending = wait
+}
+
+// SetFullContext lists every changed file and commit in the context header
+// instead of the largest ones (--full-context).
+func (m *prModel) SetFullContext(full bool) {
+	m.fullContext = full
+}
+
 func (m *prModel) Run() (*ai.PullRequestContent, bool, error) {
 	ctx := context.Background()
-	loadingContext := formatPRContext(m.diffSummary, m.commitLines)
+	loadingContext := formatPRContext(m.diffSummary, m.commitLines, m.commitNote, m.fullContext)
 	stopSpinner := m.startLoadingIndicator(loadingContext)
-	content, err := m.aiClient.GeneratePullRequestContent(ctx, m.input)
+	var content *ai.PullRequestContent
+	var err error
+	if m.pending != nil {
+		content, err = m.pending()
+	} else {
+		content, err = m.generate(ctx)
+	}
 	stopSpinner()
 	if err != nil {
 		return nil, false, err
 	}
+	if content == nil {
+		return nil, false, fmt.Errorf("no pull request content was generated")
+	}
 
-	m.content = content
+	m.attempts = []*ai.PullRequestContent{content}
+	m.show(content)
+
+	hasTemplate := strings.TrimSpace(m.request.Template) != ""
+	capped := !m.fullContext && contextCapped(m.diffSummary, m.commitLines)
+	if !hasTemplate && !capped && !m.sectioned() && m.generator == nil && !m.tall() {
+		confirmed, err := PromptYesNoStyled(m.confirmPrompt)
+		return m.content, confirmed, err
+	}
+
+	// With a template, "d" toggles between the body and a word diff of the
+	// template against the body, so untouched boilerplate stands out. When
+	// the context header was capped, "v" prints all of it. When the body has
+	// headings, "s" lists its sections so unwanted ones can be removed. "r"
+	// generates another attempt; once there are two, "D" toggles a word diff
+	// of the last two and "u" goes back to the previous one. When the
+	// description is taller than the terminal, "p" shows it in a pager. The
+	// prompt is asked again after each of them.
+	showingDiff := false
+	for {
+		prompt := m.confirmPrompt
+		choices := []string{"y", "n"}
+		if hasTemplate {
+			prompt += i18n.T("pr.diff_choice")
+			choices = append(choices, "d")
+		}
+		if capped {
+			prompt += i18n.T("pr.context_choice")
+			choices = append(choices, "v")
+		}
+		if m.sectioned() {
+			prompt += i18n.T("pr.sections_choice")
+			choices = append(choices, "s")
+		}
+		if m.generator != nil {
+			prompt += i18n.T("pr.regenerate_choice")
+			choices = append(choices, "r")
+		}
+		if len(m.attempts) > 1 {
+			prompt += i18n.T("pr.attempt_diff_choice")
+			choices = append(choices, "D")
+		}
+		if m.attemptIndex() > 0 {
+			prompt += i18n.T("pr.undo_choice")
+			choices = append(choices, "u")
+		}
+		if m.tall() {
+			prompt += i18n.T("pr.pager_choice")
+			choices = append(choices, "p")
+		}
 
-	if m.render {
-		rendered, err := RenderMarkdown(content.Body, m.useColor)
-		if err == nil {
-			m.renderedBody = strings.TrimRight(rendered, "\n")
+		choice, err := PromptChoiceStyledWithWriter(prompt, choices, os.Stdout)
+		if err != nil {
+			return m.content, false, err
+		}
+		switch choice {
+		case "y":
+			return m.content, true, nil
+		case "v":
+			fmt.Print("\n\n")
+			fmt.Println(formatPRContext(m.diffSummary, m.commitLines, m.commitNote, true))
+			fmt.Println()
+		case "s":
+			fmt.Print("\n\n")
+			if err := m.selectSections(); err != nil {
+				return m.content, false, err
+			}
+			showingDiff = false
+			fmt.Println(m.buildBody())
+			fmt.Println()
+			m.printWarnings()
+		case "d":
+			showingDiff = !showingDiff
+			fmt.Print("\n\n")
+			if showingDiff {
+				fmt.Println(m.buildTemplateDiff())
+			} else {
+				fmt.Println(m.buildBody())
+			}
+			fmt.Println()
+		case "r":
+			fmt.Print("\n\n")
+			stopSpinner := m.startLoadingIndicator("")
+			content, err := m.generate(ctx)
+			stopSpinner()
+			if err != nil {
+				// The attempts so far are still there to choose from.
+				fmt.Printf("%s\n\n", FormatWarnings([]string{err.Error()}))
+				continue
+			}
+			m.attempts = append(m.attempts, content)
+			showingDiff = false
+			m.show(content)
+		case "D":
+			showingDiff = !showingDiff
+			fmt.Print("\n\n")
+			if showingDiff {
+				fmt.Println(m.buildAttemptDiff())
+			} else {
+				fmt.Println(m.buildPRContent())
+			}
+			fmt.Println()
+		case "u":
+			fmt.Print("\n\n")
+			showingDiff = false
+			m.show(m.attempts[m.attemptIndex()-1])
+		case "p":
+			fmt.Print("\n\n")
+			if err := Page(i18n.T("pr.pager_title"), m.buildPRContent()); err != nil {
+				return m.content, false, err
+			}
+		default:
+			return m.content, false, nil
 		}
 	}
+}
+
+// generate generates another description with m.generator.
+func (m *prModel) generate(ctx context.Context) (*ai.PullRequestContent, error) {
+	result, err := m.generator.PullRequest(ctx, m.request)
+	if err != nil {
+		return nil, err
+	}
+	return &ai.PullRequestContent{Title: result.Title, Body: result.Body}, nil
+}
+
+// show makes content the attempt being shown and prints it.
+func (m *prModel) show(content *ai.PullRequestContent) {
+	if m.fit != nil {
+		if _, ok := m.fitNotes[content]; !ok {
+			m.fitNotes[content] = m.fit(content)
+		}
+	}
+	m.content = content
+	m.renderBody()
 
 	fmt.Printf("%s\n", m.buildPRContent())
 	fmt.Println()
+	m.printWarnings()
+	// Later attempts are shown without repeating the context.
+	m.printedContext = true
+}
+
+// sectioned reports whether the body has more than one headed section to
+// pick from.
+func (m *prModel) sectioned() bool {
+	return len(headedSections(prbody.Sections(m.content.Body))) > 1
+}
+
+// tall reports whether the description is taller than the terminal.
+func (m *prModel) tall() bool {
+	return IsInteractive() && tallerThanTerminal(m.buildPRContent())
+}
+
+// attemptIndex returns the position of the shown content in m.attempts.
+func (m *prModel) attemptIndex() int {
+	for i, attempt := range m.attempts {
+		if attempt == m.content {
+			return i
+		}
+	}
+	return len(m.attempts) - 1
+}
 
-	confirmed, err := PromptYesNoStyled(m.confirmPrompt)
-	return content, confirmed, err
+// buildAttemptDiff renders a word diff of the title and body of the last two
+// attempts.
+func (m *prModel) buildAttemptDiff() string {
+	n := len(m.attempts)
+	previous, latest := m.attempts[n-2], m.attempts[n-1]
+	header := titleStyle.Render(i18n.T("pr.attempt_diff", n-1, n))
+	title := renderWordDiff(wordDiff(previous.Title, latest.Title))
+	body := renderWordDiff(wordDiff(previous.Body, latest.Body))
+	return header + "\n\n" + title + "\n\n" + body
+}
+
+// renderBody renders the body of m.content for display when rendering is on.
+func (m *prModel) renderBody() {
+	m.renderedBody = ""
+	m.tooLarge = false
+	if !m.render || m.content == nil {
+		return
+	}
+	rendered, err := RenderMarkdown(m.content.Body, m.useColor)
+	if err == nil {
+		m.renderedBody = strings.TrimRight(rendered, "\n")
+	}
+	m.tooLarge = errors.Is(err, ErrTooLargeToStyle)
+}
+
+func (m *prModel) printWarnings() {
+	warnings := m.fitNotes[m.content]
+	if m.warnings != nil {
+		warnings = append(slices.Clip(warnings), m.warnings(m.content)...)
+	}
+	if warnings := FormatWarnings(warnings); warnings != "" {
+		fmt.Printf("%s\n\n", warnings)
+	}
+}
+
+// selectSections lists the headed sections of the body, all checked, and
+// removes the ones the user unchecks. Cancelling leaves the body as it is.
+func (m *prModel) selectSections() error {
+	sections := prbody.Sections(m.content.Body)
+	indexes := headedSections(sections)
+	items := make([]string, len(indexes))
+	for i, index := range indexes {
+		section := sections[index]
+		items[i] = strings.Repeat("  ", section.Level-1) + section.Title
+	}
+
+	checked, confirmed, err := PromptMultiSelectWithWriter(i18n.T("pr.select_sections"), items, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return nil
+	}
+	keep := make([]bool, len(sections))
+	for i := range keep {
+		keep[i] = true
+	}
+	for i, index := range indexes {
+		keep[index] = checked[i]
+	}
+	m.content.Body = strings.TrimRight(prbody.JoinSections(sections, keep), "\n")
+	m.renderBody()
+	return nil
+}
+
+// headedSections returns the indexes of the sections that start at a
+// heading.
+func headedSections(sections []prbody.Section) []int {
+	var indexes []int
+	for i, section := range sections {
+		if section.Level > 0 {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+func (m *prModel) buildBody() string {
+	if m.render && m.renderedBody != "" {
+		return m.renderedBody
+	}
+	if m.content == nil {
+		return ""
+	}
+	return m.content.Body
+}
+
+func (m *prModel) buildTemplateDiff() string {
+	header := titleStyle.Render(i18n.T("pr.template_diff"))
+	diff := renderWordDiff(wordDiff(strings.TrimSpace(m.request.Template), m.content.Body))
+	return header + "\n\n" + diff
 }
 
 func (m *prModel) startLoadingIndicator(context string) func() {
@@ -80,38 +387,81 @@ func (m *prModel) startLoadingIndicator(context string) func() {
 		m.printedContext = true
 	}
 
-	return StartSpinner("Generating pull request message...", os.Stderr)
+	return StartSpinner(i18n.T("pr.generating"), os.Stderr)
 }
 
 func (m *prModel) buildPRContent() string {
-	header := titleStyle.Render("📝 Generated Pull Request:")
+	if m.content == nil {
+		return ""
+	}
+	header := titleStyle.Render(i18n.T("pr.generated"))
 	title := messageStyle.Render(m.content.Title)
-	body := m.content.Body
-	if m.render && m.renderedBody != "" {
-		body = m.renderedBody
+	if m.request.Title != "" && m.content.Title == m.request.Title {
+		title += " " + editPromptStyle.Render(i18n.T("pr.title_from_commit"))
 	}
+	body := m.buildBody()
 
 	sections := []string{}
 	if !m.printedContext {
-		context := formatPRContext(m.diffSummary, m.commitLines)
+		context := formatPRContext(m.diffSummary, m.commitLines, m.commitNote, m.fullContext)
 		if context != "" {
 			sections = append(sections, context)
 		}
 	}
-	sections = append(sections, header, title, body)
+	sections = append(sections, header, title)
+	if len(m.attempts) > 1 {
+		sections = append(sections, editPromptStyle.Render(i18n.T("pr.attempt", m.attemptIndex()+1, len(m.attempts))))
+	}
+	if m.size != "" {
+		sections = append(sections, editPromptStyle.Render(i18n.T("pr.size", m.size)))
+	}
+	if m.tooLarge {
+		sections = append(sections, editPromptStyle.Render(i18n.T("markdown.too_large")))
+	}
+	sections = append(sections, body)
 
 	return strings.Join(sections, "\n\n")
 }
 
-func formatPRDiffSummary(summary git.DiffSummary) string {
+// FormatWarnings renders one warning per line.
+func FormatWarnings(warnings []string) string {
+	lines := make([]string, 0, len(warnings))
+	for _, warning := range warnings {
+		lines = append(lines, editPromptStyle.Render("⚠ "+warning))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Without --full-context the context header lists at most this many files
+// (the ones with the most changed lines) and commits (the newest).
+const (
+	contextFileLimit   = 10
+	contextCommitLimit = 10
+)
+
+// contextCapped reports whether formatPRContext leaves files or commits out.
+func contextCapped(summary git.DiffSummary, commitLines []string) bool {
+	return len(summary.Files) > contextFileLimit || len(commitLines) > contextCommitLimit
+}
+
+func formatPRDiffSummary(summary git.DiffSummary, full bool) string {
 	if len(summary.Files) == 0 {
 		return ""
 	}
 
+	files := summary.Files
+	if !full && len(files) > contextFileLimit {
+		files = append([]git.FileDiff(nil), files...)
+		sort.SliceStable(files, func(i, j int) bool {
+			return files[i].AddedLines+files[i].DeletedLines > files[j].AddedLines+files[j].DeletedLines
+		})
+		files = files[:contextFileLimit]
+	}
+
 	var parts []string
-	parts = append(parts, diffStyle.Render("📄 Changed Files:"))
+	parts = append(parts, diffStyle.Render(i18n.T("changed_files")))
 
-	for _, file := range summary.Files {
+	for _, file := range files {
 		fileName := fileStyle.Render(file.Name)
 
 		var changes []string
@@ -129,27 +479,46 @@ func formatPRDiffSummary(summary git.DiffSummary) string {
 		}
 	}
 
+	if hidden := len(summary.Files) - len(files); hidden > 0 {
+		added, deleted := 0, 0
+		for _, file := range summary.Files {
+			added += file.AddedLines
+			deleted += file.DeletedLines
+		}
+		parts = append(parts, editPromptStyle.Render(i18n.T("pr.more_files", hidden, len(summary.Files), added, deleted)))
+	}
+
 	return strings.Join(parts, "\n")
 }
 
-func formatPRContext(summary git.DiffSummary, commitLines []string) string {
+func formatPRContext(summary git.DiffSummary, commitLines []string, commitNote string, full bool) string {
 	sections := []string{}
 
-	diffSummary := formatPRDiffSummary(summary)
+	diffSummary := formatPRDiffSummary(summary, full)
 	if diffSummary != "" {
 		sections = append(sections, diffSummary)
 	}
 
 	if len(commitLines) > 0 {
-		sections = append(sections, formatPRCommitLog(commitLines))
+		sections = append(sections, formatPRCommitLog(commitLines, commitNote, full))
 	}
 
 	return strings.Join(sections, "\n\n")
 }
 
-func formatPRCommitLog(commitLines []string) string {
-	parts := []string{diffStyle.Render("🧾 Commits:")}
-	for _, line := range commitLines {
+func formatPRCommitLog(commitLines []string, note string, full bool) string {
+	header := i18n.T("pr.commits")
+	if note != "" {
+		header = i18n.T("pr.commits_note", note)
+	}
+	parts := []string{diffStyle.Render(header)}
+
+	shown := commitLines
+	if !full && len(shown) > contextCommitLimit {
+		shown = shown[len(shown)-contextCommitLimit:]
+		parts = append(parts, editPromptStyle.Render(i18n.T("pr.more_commits", len(commitLines)-len(shown))))
+	}
+	for _, line := range shown {
 		parts = append(parts, fmt.Sprintf(" • %s", line))
 	}
 	return strings.Join(parts, "\n")
@@ -170,5 +539,5 @@ func parseCommitLines(log string) []string {
 func FormatPRContext(diff string, commitLog string) string {
 	diffSummary := git.ParseDiffSummary(diff)
 	commitLines := parseCommitLines(commitLog)
-	return formatPRContext(diffSummary, commitLines)
+	return formatPRContext(diffSummary, commitLines, "", false)
 }
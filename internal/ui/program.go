@@ -0,0 +1,128 @@
+package ui
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// PanicError is returned when a terminal UI panicked. By the time it is
+// returned the terminal has been restored; Stack is the goroutine stack at
+// the panic.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("the terminal UI crashed: %v", e.Value)
+}
+
+// runProgram runs model like tea.Program.Run. A panic in Init, Update, View
+// or a command the model returned quits the program the normal way, so raw
+// mode, the alternate screen and mouse reporting are undone, and is then
+// returned as a *PanicError. Bubble Tea's own panic handling stays in place
+// for commands nested in tea.Batch or tea.Sequence.
+func runProgram(model tea.Model, opts ...tea.ProgramOption) error {
+	_, run := newSafeProgram(model, opts...)
+	return run()
+}
+
+// newSafeProgram returns a program for model and a function that runs it
+// like runProgram, for callers that send messages to the program while it
+// runs.
+func newSafeProgram(model tea.Model, opts ...tea.ProgramOption) (*tea.Program, func() error) {
+	safe := &safeModel{model: model}
+	safe.program = tea.NewProgram(safe, opts...)
+	return safe.program, func() error {
+		_, err := safe.program.Run()
+		if safe.panic != nil {
+			return safe.panic
+		}
+		return err
+	}
+}
+
+// panicMsg carries a panic out of a command into the event loop.
+type panicMsg struct {
+	err *PanicError
+}
+
+// safeModel recovers panics from the model it wraps. Init, Update and View
+// all run on the event loop goroutine, so panic needs no locking.
+type safeModel struct {
+	model   tea.Model
+	program *tea.Program
+	panic   *PanicError
+}
+
+func (s *safeModel) Init() (cmd tea.Cmd) {
+	defer func() {
+		if s.caught(recover()) {
+			cmd = tea.Quit
+		}
+	}()
+	return s.wrap(s.model.Init())
+}
+
+func (s *safeModel) Update(msg tea.Msg) (model tea.Model, cmd tea.Cmd) {
+	if msg, ok := msg.(panicMsg); ok {
+		if s.panic == nil {
+			s.panic = msg.err
+		}
+		return s, tea.Quit
+	}
+	if s.panic != nil {
+		return s, nil
+	}
+	defer func() {
+		if s.caught(recover()) {
+			model, cmd = s, tea.Quit
+		}
+	}()
+	next, cmd := s.model.Update(msg)
+	s.model = next
+	return s, s.wrap(cmd)
+}
+
+func (s *safeModel) View() (view string) {
+	if s.panic != nil {
+		return ""
+	}
+	defer func() {
+		if s.caught(recover()) {
+			view = ""
+			// View cannot return a command, and Quit blocks until the
+			// event loop that is calling View takes the message.
+			go s.program.Quit()
+		}
+	}()
+	return s.model.View()
+}
+
+// caught records r when it is a panic and reports whether it was.
+func (s *safeModel) caught(r any) bool {
+	if r == nil {
+		return false
+	}
+	if s.panic == nil {
+		s.panic = &PanicError{Value: r, Stack: debug.Stack()}
+	}
+	return true
+}
+
+// wrap turns a panic in cmd into a panicMsg.
+func (s *safeModel) wrap(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() (msg tea.Msg) {
+		defer func() {
+			if r := recover(); r != nil {
+				msg = panicMsg{err: &PanicError{Value: r, Stack: debug.Stack()}}
+			}
+		}()
+		return cmd()
+	}
+}
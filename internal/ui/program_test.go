@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// panicModel panics in the method named by where.
+type panicModel struct {
+	where string
+}
+
+type startMsg struct{}
+
+func (m panicModel) Init() tea.Cmd {
+	if m.where == "cmd" {
+		return func() tea.Msg { panic("boom in cmd") }
+	}
+	return func() tea.Msg { return startMsg{} }
+}
+
+func (m panicModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if _, ok := msg.(startMsg); ok && m.where == "update" {
+		panic("boom in update")
+	}
+	return m, nil
+}
+
+func (m panicModel) View() string {
+	if m.where == "view" {
+		panic("boom in view")
+	}
+	return "ok"
+}
+
+func TestRunProgramPanics(t *testing.T) {
+	tests := []struct {
+		where string
+		value string
+		frame string
+	}{
+		{"update", "boom in update", "panicModel.Update"},
+		{"view", "boom in view", "panicModel.View"},
+		{"cmd", "boom in cmd", "panicModel.Init.func1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			done := make(chan error, 1)
+			go func() {
+				done <- runProgram(panicModel{where: tt.where}, tea.WithInput(nil), tea.WithOutput(&bytes.Buffer{}))
+			}()
+
+			var err error
+			select {
+			case err = <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatal("the program did not quit after the panic")
+			}
+			var panicErr *PanicError
+			if !errors.As(err, &panicErr) {
+				t.Fatalf("runProgram() = %v, want a *PanicError", err)
+			}
+			if panicErr.Value != tt.value {
+				t.Errorf("Value = %v, want %q", panicErr.Value, tt.value)
+			}
+			if !strings.Contains(string(panicErr.Stack), tt.frame) {
+				t.Errorf("Stack does not show %s:\n%s", tt.frame, panicErr.Stack)
+			}
+		})
+	}
+}
+
+func TestRunProgramWithoutPanic(t *testing.T) {
+	model := quitModel{}
+	if err := runProgram(model, tea.WithInput(nil), tea.WithOutput(&bytes.Buffer{})); err != nil {
+		t.Fatalf("runProgram() = %v, want nil", err)
+	}
+}
+
+// quitModel quits as soon as it starts.
+type quitModel struct{}
+
+func (quitModel) Init() tea.Cmd                         { return tea.Quit }
+func (m quitModel) Update(tea.Msg) (tea.Model, tea.Cmd) { return m, nil }
+func (quitModel) View() string                          { return "" }
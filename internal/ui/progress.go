@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/EkeMinusYou/gelf/internal/progress"
+)
+
+// NewReporter returns the progress.Reporter for out: a spinner reporter when
+// out is a terminal, and otherwise progress.NewPlain, so that CI logs and
+// redirected output still show each phase.
+func NewReporter(out io.Writer) progress.Reporter {
+	if isTerminalWriter(out) {
+		return NewSpinnerReporter(out)
+	}
+	return progress.NewPlain(out)
+}
+
+// NewSpinnerReporter returns a progress.Reporter that shows a spinner with the
+// running phase's message on out. Item events update the message with m/n.
+func NewSpinnerReporter(out io.Writer) progress.Reporter {
+	return &spinnerReporter{out: out}
+}
+
+type spinnerReporter struct {
+	mu      sync.Mutex
+	out     io.Writer
+	message string
+	stop    func()
+}
+
+func (r *spinnerReporter) Report(event progress.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch event.Kind {
+	case progress.PhaseStart:
+		r.stopSpinner()
+		r.message = event.Message
+		if r.message == "" {
+			r.message = event.Phase
+		}
+		r.stop = StartSpinnerInline(r.message, r.out)
+	case progress.Item:
+		if r.stop == nil {
+			return
+		}
+		r.stopSpinner()
+		r.stop = StartSpinnerInline(fmt.Sprintf("%s (%d/%d)", r.message, event.Current, event.Total), r.out)
+	case progress.PhaseEnd:
+		r.stopSpinner()
+	}
+}
+
+func (r *spinnerReporter) stopSpinner() {
+	if r.stop != nil {
+		r.stop()
+		r.stop = nil
+	}
+}
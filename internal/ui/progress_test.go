@@ -0,0 +1,32 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/EkeMinusYou/gelf/internal/progress"
+)
+
+func TestNewReporterPlainWithoutTerminal(t *testing.T) {
+	var out bytes.Buffer
+	reporter := NewReporter(&out)
+	if _, spinner := reporter.(*spinnerReporter); spinner {
+		t.Fatal("NewReporter() drew a spinner on a buffer")
+	}
+
+	reporter.Report(progress.Event{Kind: progress.PhaseStart, Phase: "generate", Message: "Generating pull request..."})
+	reporter.Report(progress.Event{Kind: progress.Tokens, Phase: "generate", InputTokens: 1200, OutputTokens: 300})
+	reporter.Report(progress.Event{Kind: progress.PhaseEnd, Phase: "generate"})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("output = %q, want a line per event", out.String())
+	}
+	if lines[0] != "Generating pull request..." || lines[1] != "generate: 1200 input tokens, 300 output tokens" || !strings.HasPrefix(lines[2], "generate: done in ") {
+		t.Errorf("output = %q", lines)
+	}
+	if strings.Contains(out.String(), "\r") || strings.Contains(out.String(), "\033") {
+		t.Errorf("output has terminal control sequences: %q", out.String())
+	}
+}
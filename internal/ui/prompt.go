@@ -11,6 +11,22 @@ import (
 	"golang.org/x/term"
 )
 
+// headless is set by SetHeadless.
+var headless bool
+
+// SetHeadless makes gelf treat stdin and every output as something other
+// than a terminal, whatever they are, so that a runner that allocates a
+// pseudo-terminal behaves like one that does not: prompts fail and spinners
+// are not drawn.
+func SetHeadless() {
+	headless = true
+}
+
+// IsInteractive reports whether stdin is a terminal that can answer prompts.
+func IsInteractive() bool {
+	return !headless && term.IsTerminal(int(os.Stdin.Fd()))
+}
+
 func PromptYesNoStyled(prompt string) (bool, error) {
 	return PromptYesNoStyledWithWriter(prompt, os.Stdout)
 }
@@ -27,10 +43,9 @@ func PromptYesNoWithWriter(prompt string, out io.Writer) (bool, error) {
 	if out == nil {
 		out = os.Stdout
 	}
-	if term.IsTerminal(int(os.Stdin.Fd())) {
+	if IsInteractive() {
 		m := &yesNoModel{prompt: prompt}
-		p := tea.NewProgram(m, tea.WithOutput(out))
-		if _, err := p.Run(); err != nil {
+		if err := runProgram(m, tea.WithOutput(out)); err != nil {
 			return false, err
 		}
 		return m.confirmed, nil
@@ -51,6 +66,88 @@ func PromptYesNoWithWriter(prompt string, out io.Writer) (bool, error) {
 	return false, nil
 }
 
+// PromptChoiceStyledWithWriter asks prompt and returns the pressed key out of
+// choices (single characters). A lowercase choice is picked by either case,
+// an uppercase one only with shift. "n", "q", Esc and Ctrl+C always answer
+// "n".
+func PromptChoiceStyledWithWriter(prompt string, choices []string, out io.Writer) (string, error) {
+	if out == nil {
+		out = os.Stdout
+	}
+	styled := promptStyle.Render(prompt)
+	if IsInteractive() {
+		m := &choiceModel{prompt: styled, choices: choices, choice: "n"}
+		if err := runProgram(m, tea.WithOutput(out)); err != nil {
+			return "n", err
+		}
+		return m.choice, nil
+	}
+
+	fmt.Fprintf(out, "%s ", styled)
+
+	reader := bufio.NewReader(os.Stdin)
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "n", err
+	}
+
+	line = strings.TrimSpace(line)
+	for _, key := range line {
+		if choice, ok := matchChoice(string(key), choices); ok {
+			return choice, nil
+		}
+		break
+	}
+	return "n", nil
+}
+
+// matchChoice returns the choice key picks: the one typed exactly, else the
+// one typed in the other case.
+func matchChoice(key string, choices []string) (string, bool) {
+	for _, choice := range choices {
+		if key == choice {
+			return choice, true
+		}
+	}
+	lower := strings.ToLower(key)
+	for _, choice := range choices {
+		if lower == choice {
+			return choice, true
+		}
+	}
+	return "", false
+}
+
+type choiceModel struct {
+	prompt  string
+	choices []string
+	choice  string
+}
+
+func (m *choiceModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *choiceModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch strings.ToLower(msg.String()) {
+		case "q", "ctrl+c", "ctrl+d", "esc":
+			m.choice = "n"
+			return m, tea.Quit
+		}
+		if choice, ok := matchChoice(msg.String(), m.choices); ok {
+			m.choice = choice
+			return m, tea.Quit
+		}
+	}
+
+	return m, nil
+}
+
+func (m *choiceModel) View() string {
+	return fmt.Sprintf("%s ", m.prompt)
+}
+
 type yesNoModel struct {
 	prompt    string
 	confirmed bool
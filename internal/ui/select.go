@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// PromptSelectWithWriter shows items as a list to pick one from. Up/down (or
+// j/k) move the cursor and Enter picks the item under it. It returns the
+// index of the picked item and false when the user cancelled with q, Esc or
+// Ctrl+C. It needs an interactive terminal.
+func PromptSelectWithWriter(prompt string, items []string, out io.Writer) (int, bool, error) {
+	if out == nil {
+		out = os.Stdout
+	}
+	if !IsInteractive() {
+		return 0, false, fmt.Errorf("selecting an item requires an interactive terminal")
+	}
+	if len(items) == 0 {
+		return 0, false, nil
+	}
+
+	m := &selectModel{prompt: promptStyle.Render(prompt), items: items}
+	if err := runProgram(m, tea.WithOutput(out)); err != nil {
+		return 0, false, err
+	}
+	return m.cursor, m.confirmed, nil
+}
+
+type selectModel struct {
+	prompt    string
+	items     []string
+	cursor    int
+	confirmed bool
+	done      bool
+}
+
+func (m *selectModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *selectModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.items)-1 {
+				m.cursor++
+			}
+		case "home", "g":
+			m.cursor = 0
+		case "end", "G":
+			m.cursor = len(m.items) - 1
+		case "enter":
+			m.confirmed = true
+			m.done = true
+			return m, tea.Quit
+		case "q", "esc", "ctrl+c", "ctrl+d":
+			m.done = true
+			return m, tea.Quit
+		}
+	}
+
+	return m, nil
+}
+
+func (m *selectModel) View() string {
+	if m.done {
+		return ""
+	}
+
+	lines := []string{m.prompt, editPromptStyle.Render(i18n.T("select.single_hint"))}
+	for i, item := range m.items {
+		if i == m.cursor {
+			lines = append(lines, addedStyle.Render("> ")+item)
+		} else {
+			lines = append(lines, "  "+item)
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -69,6 +69,9 @@ func startSpinner(message string, out io.Writer, newline bool) func() {
 }
 
 func isTerminalWriter(w io.Writer) bool {
+	if headless {
+		return false
+	}
 	f, ok := w.(*os.File)
 	if !ok {
 		return false
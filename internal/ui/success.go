@@ -9,3 +9,8 @@ func RenderSuccessHeader(text string) string {
 func RenderSuccessMessage(text string) string {
 	return messageStyle.Render(text)
 }
+
+// RenderSuccessStats applies muted styling to a stats footer line.
+func RenderSuccessStats(text string) string {
+	return diffStyle.Render(text)
+}
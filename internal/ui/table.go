@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/EkeMinusYou/gelf/internal/textwidth"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// RenderTable lays out rows in columns under bold headers. Cells may contain
+// ANSI styling; widths are measured on the visible text.
+func RenderTable(headers []string, rows [][]string) string {
+	widths := make([]int, len(headers))
+	for i, header := range headers {
+		widths[i] = lipgloss.Width(header)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if w := lipgloss.Width(cell); i < len(widths) && w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	var builder strings.Builder
+	writeRow := func(cells []string, style func(string) string) {
+		for i, cell := range cells {
+			if i > 0 {
+				builder.WriteString("  ")
+			}
+			builder.WriteString(style(cell))
+			if i < len(cells)-1 {
+				builder.WriteString(strings.Repeat(" ", widths[i]-lipgloss.Width(cell)))
+			}
+		}
+		builder.WriteString("\n")
+	}
+
+	writeRow(headers, func(cell string) string { return titleStyle.Render(cell) })
+	for _, row := range rows {
+		writeRow(row, func(cell string) string { return cell })
+	}
+	return builder.String()
+}
+
+// RenderPRState colors a pull request state the way GitHub does.
+func RenderPRState(state string) string {
+	switch {
+	case strings.EqualFold(state, "OPEN"):
+		return successStyle.Render(state)
+	case strings.EqualFold(state, "MERGED"):
+		return fileStyle.Render(state)
+	default:
+		return errorStyle.Render(state)
+	}
+}
+
+// Truncate shortens text to width display cells, ending it with "…".
+func Truncate(text string, width int) string {
+	return textwidth.Truncate(text, width, "…")
+}
@@ -3,10 +3,13 @@ package ui
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
-	"github.com/EkeMinusYou/gelf/internal/ai"
 	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/i18n"
+	"github.com/EkeMinusYou/gelf/pkg/gelf"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -18,6 +21,7 @@ type state int
 const (
 	stateLoading state = iota
 	stateConfirm
+	stateFallback
 	stateEditing
 	stateCommitting
 	stateSuccess
@@ -25,8 +29,10 @@ const (
 )
 
 type model struct {
-	aiClient        *ai.VertexAIClient
-	diff            string
+	generator *gelf.Generator
+	// request is what the message is generated from; its Diff is what is
+	// committed.
+	request         gelf.CommitRequest
 	diffSummary     git.DiffSummary
 	commitMessage   string
 	originalMessage string
@@ -34,7 +40,19 @@ type model struct {
 	state           state
 	spinner         spinner.Model
 	textInput       textinput.Model
-	commitLanguage  string
+	declined        bool
+	commitFailed    bool
+	normalize       func(string) (string, []string)
+	warnings        []string
+	commitOptions   git.CommitOptions
+	lockCommit      func() (func(), error)
+	note            string
+	fallback        string
+	// started is when generation began; received counts the characters
+	// streamed so far, which arrive on streamed.
+	started  time.Time
+	received int
+	streamed chan int
 }
 
 type msgCommitGenerated struct {
@@ -42,35 +60,90 @@ type msgCommitGenerated struct {
 	err     error
 }
 
+type msgStreamed struct {
+	received int
+}
+
 type msgCommitDone struct {
 	err error
 }
 
-func NewTUI(aiClient *ai.VertexAIClient, diff string, commitLanguage string) *model {
+type msgEditorDone struct {
+	message string
+	err     error
+}
+
+// NewTUI returns the commit TUI, which generates a message for request with
+// generator and commits it with options once it is approved.
+func NewTUI(generator *gelf.Generator, request gelf.CommitRequest, options git.CommitOptions) *model {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = loadingStyle
 
 	ti := textinput.New()
-	ti.Placeholder = "Enter your commit message..."
+	ti.Placeholder = i18n.T("commit.placeholder")
 	ti.CharLimit = 0
 	ti.Width = 60
 
-	diffSummary := git.ParseDiffSummary(diff)
+	diffSummary := git.ParseDiffSummary(request.Diff)
 
 	return &model{
-		aiClient:       aiClient,
-		diff:           diff,
-		diffSummary:    diffSummary,
-		state:          stateLoading,
-		spinner:        s,
-		textInput:      ti,
-		commitLanguage: commitLanguage,
+		generator:     generator,
+		request:       request,
+		diffSummary:   diffSummary,
+		state:         stateLoading,
+		spinner:       s,
+		textInput:     ti,
+		commitOptions: options,
+	}
+}
+
+// SetNormalizer sets a function applied to generated and edited messages.
+// Its warnings are shown with the message.
+func (m *model) SetNormalizer(normalize func(string) (string, []string)) {
+	m.normalize = normalize
+}
+
+// SetCommitLock sets a function that takes the repository lock around the
+// commit and returns its release.
+func (m *model) SetCommitLock(lock func() (func(), error)) {
+	m.lockCommit = lock
+}
+
+// SetNote sets a note shown next to the message header, e.g. the trivial
+// rule that produced the message.
+func (m *model) SetNote(note string) {
+	m.note = note
+}
+
+// SetFallback sets the message offered, labelled as an offline fallback,
+// when generation fails. It still has to be confirmed like a generated one.
+func (m *model) SetFallback(message string) {
+	m.fallback = message
+}
+
+// setMessage stores message after normalizing it.
+func (m *model) setMessage(message string) {
+	m.warnings = nil
+	if m.normalize != nil {
+		message, m.warnings = m.normalize(message)
 	}
+	m.commitMessage = message
+}
+
+// UseMessage skips generation and starts at the confirm step with message.
+func (m *model) UseMessage(message string) {
+	m.setMessage(message)
+	m.state = stateConfirm
 }
 
 func (m *model) Init() tea.Cmd {
-	return tea.Batch(m.spinner.Tick, m.generateCommitMessage())
+	if m.state != stateLoading {
+		return nil
+	}
+	m.started = time.Now()
+	m.streamed = make(chan int, 16)
+	return tea.Batch(m.spinner.Tick, m.generateCommitMessage(), m.waitForStream())
 }
 
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -82,6 +155,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case stateLoading:
 			switch msg.String() {
 			case "q", "ctrl+c":
+				m.declined = true
 				return m, tea.Quit
 			}
 		case stateConfirm:
@@ -95,14 +169,28 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.textInput.Focus()
 				m.state = stateEditing
 				return m, textinput.Blink
+			case "v", "V":
+				return m, m.editInEditor()
 			case "n", "N", "q", "ctrl+c":
+				m.declined = true
+				return m, tea.Quit
+			}
+		case stateFallback:
+			switch msg.String() {
+			case "y", "Y":
+				m.setMessage(m.fallback)
+				m.note = i18n.T("commit.offline_fallback")
+				m.state = stateConfirm
+			case "n", "N", "q", "ctrl+c":
+				m.state = stateError
 				return m, tea.Quit
 			}
 		case stateEditing:
 			switch msg.String() {
 			case "enter":
-				m.commitMessage = strings.TrimSpace(m.textInput.Value())
-				if m.commitMessage == "" {
+				if edited := strings.TrimSpace(m.textInput.Value()); edited != "" {
+					m.setMessage(edited)
+				} else {
 					m.commitMessage = m.originalMessage
 				}
 				m.textInput.Blur()
@@ -123,14 +211,32 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.err != nil {
 			m.err = msg.err
 			m.state = stateError
+			if m.fallback != "" {
+				m.state = stateFallback
+			}
 		} else {
-			m.commitMessage = msg.message
+			m.setMessage(msg.message)
 			m.state = stateConfirm
 		}
 
+	case msgStreamed:
+		m.received = msg.received
+		return m, m.waitForStream()
+
+	case msgEditorDone:
+		if msg.err != nil {
+			m.err = msg.err
+			m.state = stateError
+			return m, tea.Quit
+		}
+		if msg.message != "" {
+			m.setMessage(msg.message)
+		}
+
 	case msgCommitDone:
 		if msg.err != nil {
 			m.err = msg.err
+			m.commitFailed = true
 			m.state = stateError
 		} else {
 			m.state = stateSuccess
@@ -152,7 +258,10 @@ func (m *model) View() string {
 	case stateLoading:
 		loadingText := fmt.Sprintf("%s %s",
 			m.spinner.View(),
-			loadingStyle.Render("Generating commit message..."))
+			loadingStyle.Render(i18n.T("commit.generating")))
+		if status := m.streamStatus(); status != "" {
+			loadingText += " " + editPromptStyle.Render(status)
+		}
 
 		diffSummary := m.formatDiffSummary()
 		if diffSummary != "" {
@@ -162,20 +271,33 @@ func (m *model) View() string {
 
 	case stateConfirm:
 		diffSummary := m.formatDiffSummary()
-		header := titleStyle.Render("📝 Generated Commit Message:")
+		header := titleStyle.Render(i18n.T("commit.generated"))
+		if m.note != "" {
+			header += " " + editPromptStyle.Render(m.note)
+		}
 		message := messageStyle.Render(m.commitMessage)
-		prompt := promptStyle.Render("Commit this message? (y)es / (e)dit / (n)o")
+		if len(m.warnings) > 0 {
+			message += "\n\n" + FormatWarnings(m.warnings)
+		}
+		prompt := promptStyle.Render(i18n.T("commit.confirm"))
 
 		if diffSummary != "" {
 			return fmt.Sprintf("%s\n\n%s\n\n%s\n\n%s", diffSummary, header, message, prompt)
 		}
 		return fmt.Sprintf("%s\n\n%s\n\n%s", header, message, prompt)
 
+	case stateFallback:
+		errorText := errorStyle.Render(i18n.T("error", m.err))
+		header := titleStyle.Render(i18n.T("commit.fallback_header")) + " " + editPromptStyle.Render(i18n.T("commit.offline_fallback"))
+		message := messageStyle.Render(m.fallback)
+		prompt := promptStyle.Render(i18n.T("commit.fallback_confirm"))
+		return fmt.Sprintf("%s\n\n%s\n\n%s\n\n%s", errorText, header, message, prompt)
+
 	case stateEditing:
 		diffSummary := m.formatDiffSummary()
-		header := titleStyle.Render("✏️  Edit Commit Message:")
+		header := titleStyle.Render(i18n.T("commit.edit"))
 		inputView := m.textInput.View()
-		prompt := editPromptStyle.Render("Press Enter to confirm, Esc to cancel")
+		prompt := editPromptStyle.Render(i18n.T("commit.edit_hint"))
 
 		if diffSummary != "" {
 			return fmt.Sprintf("%s\n\n%s\n\n%s\n\n%s", diffSummary, header, inputView, prompt)
@@ -185,32 +307,107 @@ func (m *model) View() string {
 	case stateCommitting:
 		return fmt.Sprintf("%s %s",
 			m.spinner.View(),
-			loadingStyle.Render("Committing changes..."))
+			loadingStyle.Render(i18n.T("commit.committing")))
 
 	case stateSuccess:
 		return ""
 
 	case stateError:
-		return errorStyle.Render(fmt.Sprintf("✗ Error: %v", m.err))
+		return errorStyle.Render(i18n.T("error", m.err))
 	}
 
 	return ""
 }
 
+// streamStatus says how long generation has been running and, once the
+// response streams in, how much of it arrived. It is empty for the first
+// seconds, when there is nothing worth showing yet.
+func (m *model) streamStatus() string {
+	elapsed := time.Since(m.started).Truncate(time.Second)
+	switch {
+	case m.started.IsZero():
+		return ""
+	case m.received > 0:
+		return i18n.T("commit.streaming", elapsed, m.received)
+	case elapsed >= 3*time.Second:
+		return i18n.T("commit.waiting", elapsed)
+	}
+	return ""
+}
+
+// waitForStream delivers the next streamed character count as a message.
+func (m *model) waitForStream() tea.Cmd {
+	streamed := m.streamed
+	return func() tea.Msg {
+		received, ok := <-streamed
+		if !ok {
+			return nil
+		}
+		return msgStreamed{received: received}
+	}
+}
+
 func (m *model) generateCommitMessage() tea.Cmd {
+	streamed := m.streamed
+	generator, request := m.generator, m.request
 	return tea.Cmd(func() tea.Msg {
+		defer close(streamed)
 		ctx := context.Background()
-		message, err := m.aiClient.GenerateCommitMessage(ctx, m.diff, m.commitLanguage)
-		return msgCommitGenerated{
-			message: strings.TrimSpace(message),
-			err:     err,
+		result, err := generator.CommitMessageStream(ctx, request, func(received int) {
+			select {
+			case streamed <- received:
+			default:
+				// The view only needs the latest count; skip when it lags.
+			}
+		})
+		if err != nil {
+			return msgCommitGenerated{err: err}
 		}
+		return msgCommitGenerated{message: result.Message}
+	})
+}
+
+// editInEditor suspends the TUI and opens the message in $EDITOR, below
+// which the commit template's comment lines are shown as in git commit.
+func (m *model) editInEditor() tea.Cmd {
+	file, err := os.CreateTemp("", "gelf-commit-*.txt")
+	if err != nil {
+		return func() tea.Msg { return msgEditorDone{err: fmt.Errorf("failed to create temporary file: %w", err)} }
+	}
+	path := file.Name()
+	_, err = file.WriteString(commitEditorBuffer(m.commitMessage, m.request.Template))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return func() tea.Msg { return msgEditorDone{err: fmt.Errorf("failed to write temporary file: %w", err)} }
+	}
+
+	editor, cmd := editorCommand(path)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		defer os.Remove(path)
+		if err != nil {
+			return msgEditorDone{err: fmt.Errorf("editor %q failed: %w", editor, err)}
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return msgEditorDone{err: fmt.Errorf("failed to read edited file: %w", err)}
+		}
+		return msgEditorDone{message: cleanupCommitMessage(string(content))}
 	})
 }
 
 func (m *model) commitChanges() tea.Cmd {
 	return tea.Cmd(func() tea.Msg {
-		err := git.CommitChanges(m.commitMessage)
+		if m.lockCommit != nil {
+			release, err := m.lockCommit()
+			if err != nil {
+				return msgCommitDone{err: err}
+			}
+			defer release()
+		}
+		err := git.CommitChanges(m.commitMessage, m.commitOptions)
 		return msgCommitDone{err: err}
 	})
 }
@@ -221,7 +418,7 @@ func (m *model) formatDiffSummary() string {
 	}
 
 	var parts []string
-	parts = append(parts, diffStyle.Render("📄 Changed Files:"))
+	parts = append(parts, diffStyle.Render(i18n.T("changed_files")))
 
 	for _, file := range m.diffSummary.Files {
 		fileName := fileStyle.Render(file.Name)
@@ -245,12 +442,11 @@ func (m *model) formatDiffSummary() string {
 }
 
 func (m *model) Run() error {
-	p := tea.NewProgram(m)
-	_, err := p.Run()
+	err := runProgram(m)
 
 	// Print success message after TUI exits so it remains visible
 	if m.state == stateSuccess {
-		header := successStyle.Render("✓ Commit successful")
+		header := successStyle.Render(i18n.T("commit.success"))
 		message := messageStyle.Render(m.commitMessage)
 
 		fmt.Printf("%s\n%s\n", header, message)
@@ -259,6 +455,27 @@ func (m *model) Run() error {
 	return err
 }
 
+// Declined reports whether the user cancelled instead of committing.
+func (m *model) Declined() bool {
+	return m.declined
+}
+
+// FailedMessage returns the message that git failed to commit, if any.
+func (m *model) FailedMessage() string {
+	if !m.commitFailed {
+		return ""
+	}
+	return m.commitMessage
+}
+
+// Err returns the error shown by the TUI, if any.
+func (m *model) Err() error {
+	if m.state != stateError {
+		return nil
+	}
+	return m.err
+}
+
 // TUI styles shared across commands.
 var (
 	titleStyle = lipgloss.NewStyle().
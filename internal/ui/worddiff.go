@@ -0,0 +1,168 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+)
+
+type diffKind int
+
+const (
+	diffEqual diffKind = iota
+	diffInsert
+	diffDelete
+)
+
+type diffOp struct {
+	kind diffKind
+	text string
+}
+
+var wordTokenRegex = regexp.MustCompile(`\S+\s*|\s+`)
+
+// maxWordDiffCells bounds the LCS table; larger inputs are diffed by line,
+// and when even the lines are too many the changed middle is replaced as a
+// whole.
+const maxWordDiffCells = 4_000_000
+
+// wordDiff returns the word-level edit script turning a into b. Tokens keep
+// their trailing whitespace so the output can be concatenated back into text.
+func wordDiff(a, b string) []diffOp {
+	aTokens := wordTokenRegex.FindAllString(a, -1)
+	bTokens := wordTokenRegex.FindAllString(b, -1)
+	if len(aTokens)*len(bTokens) > maxWordDiffCells {
+		aTokens = splitKeepNewline(a)
+		bTokens = splitKeepNewline(b)
+	}
+	return lcsDiff(aTokens, bTokens)
+}
+
+func splitKeepNewline(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffBuilder collects an edit script, merging runs of the same kind.
+type diffBuilder struct {
+	ops  []diffOp
+	kind diffKind
+	text strings.Builder
+}
+
+func (d *diffBuilder) add(kind diffKind, text string) {
+	if d.text.Len() > 0 && d.kind != kind {
+		d.flush()
+	}
+	d.kind = kind
+	d.text.WriteString(text)
+}
+
+func (d *diffBuilder) flush() {
+	if d.text.Len() > 0 {
+		d.ops = append(d.ops, diffOp{kind: d.kind, text: d.text.String()})
+		d.text.Reset()
+	}
+}
+
+func lcsDiff(a, b []string) []diffOp {
+	key := func(token string) string {
+		return strings.TrimSpace(token)
+	}
+
+	var builder diffBuilder
+
+	// The common prefix and suffix need no table; for a filled-in template
+	// they are most of the text.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && key(a[prefix]) == key(b[prefix]) {
+		builder.add(diffEqual, b[prefix])
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && key(a[len(a)-1-suffix]) == key(b[len(b)-1-suffix]) {
+		suffix++
+	}
+	a, b, tail := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], b[len(b)-suffix:]
+
+	if (len(a)+1)*(len(b)+1) > maxWordDiffCells {
+		for _, token := range a {
+			builder.add(diffDelete, token)
+		}
+		for _, token := range b {
+			builder.add(diffInsert, token)
+		}
+	} else {
+		// lengths[i*width+j] is the LCS length of a[i:] and b[j:].
+		width := len(b) + 1
+		lengths := make([]int, (len(a)+1)*width)
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if key(a[i]) == key(b[j]) {
+					lengths[i*width+j] = lengths[(i+1)*width+j+1] + 1
+				} else {
+					lengths[i*width+j] = max(lengths[(i+1)*width+j], lengths[i*width+j+1])
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < len(a) && j < len(b) {
+			switch {
+			case key(a[i]) == key(b[j]):
+				builder.add(diffEqual, b[j])
+				i++
+				j++
+			case lengths[(i+1)*width+j] >= lengths[i*width+j+1]:
+				builder.add(diffDelete, a[i])
+				i++
+			default:
+				builder.add(diffInsert, b[j])
+				j++
+			}
+		}
+		for ; i < len(a); i++ {
+			builder.add(diffDelete, a[i])
+		}
+		for ; j < len(b); j++ {
+			builder.add(diffInsert, b[j])
+		}
+	}
+
+	for _, token := range tail {
+		builder.add(diffEqual, token)
+	}
+	builder.flush()
+	return builder.ops
+}
+
+// renderWordDiff colors insertions green and deletions red and struck
+// through. Styling is applied per line so that ANSI codes never span lines.
+func renderWordDiff(ops []diffOp) string {
+	var builder strings.Builder
+	for _, op := range ops {
+		style := diffStyle
+		switch op.kind {
+		case diffInsert:
+			style = addedStyle
+		case diffDelete:
+			style = deletedStyle.Strikethrough(true)
+		}
+		if op.kind == diffEqual {
+			builder.WriteString(op.text)
+			continue
+		}
+		lines := strings.Split(op.text, "\n")
+		for i, line := range lines {
+			if i > 0 {
+				builder.WriteString("\n")
+			}
+			if line != "" {
+				builder.WriteString(style.Render(line))
+			}
+		}
+	}
+	return builder.String()
+}
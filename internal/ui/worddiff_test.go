@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// joinOps concatenates the text of the ops whose kind is not skip.
+func joinOps(ops []diffOp, skip diffKind) string {
+	var builder strings.Builder
+	for _, op := range ops {
+		if op.kind != skip {
+			builder.WriteString(op.text)
+		}
+	}
+	return builder.String()
+}
+
+// numberedLines returns count lines reading "<prefix> line <n>".
+func numberedLines(prefix string, count int) string {
+	var builder strings.Builder
+	for i := range count {
+		fmt.Fprintf(&builder, "%s line %d\n", prefix, i)
+	}
+	return builder.String()
+}
+
+const prTemplate = `## Summary
+
+<!-- Describe the change. -->
+
+## Testing
+
+- [ ] Unit tests
+- [ ] Manual testing
+`
+
+func TestWordDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		body     string
+		// inserted and deleted are the text of all insert and delete ops.
+		inserted string
+		deleted  string
+		ops      int
+	}{
+		{
+			name:     "untouched placeholder",
+			template: prTemplate,
+			body:     prTemplate,
+			ops:      1,
+		},
+		{
+			name:     "filled section",
+			template: prTemplate,
+			body:     strings.Replace(prTemplate, "<!-- Describe the change. -->", "Retry model calls on rate limits.", 1),
+			inserted: "Retry model calls on rate limits.",
+			deleted:  "<!-- Describe the change. -->",
+			ops:      4,
+		},
+		{
+			name:     "ticked checkbox",
+			template: prTemplate,
+			body:     strings.Replace(prTemplate, "- [ ] Unit", "- [x] Unit", 1),
+			inserted: "[x] ",
+			deleted:  "[ ] ",
+			ops:      4,
+		},
+		{
+			name:     "empty body",
+			template: prTemplate,
+			body:     "",
+			deleted:  prTemplate,
+			ops:      1,
+		},
+		{
+			name:     "empty template",
+			template: "",
+			body:     prTemplate,
+			inserted: prTemplate,
+			ops:      1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops := wordDiff(tt.template, tt.body)
+			if len(ops) != tt.ops {
+				t.Errorf("got %d ops, want %d: %+v", len(ops), tt.ops, ops)
+			}
+			if got := joinOps(ops, diffDelete); got != tt.body {
+				t.Errorf("equal and inserted text = %q, want the body %q", got, tt.body)
+			}
+			var inserted, deleted string
+			for _, op := range ops {
+				switch op.kind {
+				case diffInsert:
+					inserted += op.text
+				case diffDelete:
+					deleted += op.text
+				}
+			}
+			if strings.TrimSpace(inserted) != strings.TrimSpace(tt.inserted) {
+				t.Errorf("inserted %q, want %q", inserted, tt.inserted)
+			}
+			if strings.TrimSpace(deleted) != strings.TrimSpace(tt.deleted) {
+				t.Errorf("deleted %q, want %q", deleted, tt.deleted)
+			}
+		})
+	}
+}
+
+func TestWordDiffOverCellLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		body     string
+	}{
+		// Too many words for a word table, few enough lines for a line one.
+		{"line fallback", numberedLines("old", 800), numberedLines("new", 800)},
+		// Too many lines as well: the changed middle is replaced whole.
+		{"replaced middle", numberedLines("old", 2100), numberedLines("new", 2100)},
+		{"replaced middle with shared ends", prTemplate + numberedLines("old", 2100) + prTemplate, prTemplate + numberedLines("new", 2100) + prTemplate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops := wordDiff(tt.template, tt.body)
+			if got := joinOps(ops, diffDelete); got != tt.body {
+				t.Error("equal and inserted text is not the body")
+			}
+			if got := joinOps(ops, diffInsert); got != tt.template {
+				t.Error("equal and deleted text is not the template")
+			}
+		})
+	}
+}
+
+func TestRenderWordDiffKeepsLines(t *testing.T) {
+	ops := wordDiff(prTemplate, strings.Replace(prTemplate, "- [ ] Manual testing\n", "", 1))
+	rendered := renderWordDiff(ops)
+	if got, want := strings.Count(rendered, "\n"), strings.Count(prTemplate, "\n"); got != want {
+		t.Errorf("rendered %d lines, want %d", got, want)
+	}
+}
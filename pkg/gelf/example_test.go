@@ -0,0 +1,103 @@
+package gelf_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+
+	"github.com/EkeMinusYou/gelf/pkg/gelf"
+)
+
+// The examples call a model, so they are compiled but not run.
+
+func ExampleGenerator_CommitMessage() {
+	ctx := context.Background()
+	gen, err := gelf.New(ctx, gelf.Config{ProjectID: "my-project"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer gen.Close()
+
+	diff, err := exec.Command("git", "diff", "--cached").Output()
+	if err != nil {
+		log.Fatal(err)
+	}
+	result, err := gen.CommitMessage(ctx, gelf.CommitRequest{Diff: string(diff), Scope: "api"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(result.Message)
+	fmt.Fprintf(os.Stderr, "%d input tokens, %d output tokens\n", result.Usage.InputTokens, result.Usage.OutputTokens)
+}
+
+func ExampleGenerator_PullRequest() {
+	ctx := context.Background()
+	cfg, err := gelf.LoadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	gen, err := gelf.New(ctx, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer gen.Close()
+
+	diff, err := exec.Command("git", "diff", "main...HEAD").Output()
+	if err != nil {
+		log.Fatal(err)
+	}
+	commits, err := exec.Command("git", "log", "--reverse", "--format=%h %s", "main..HEAD").Output()
+	if err != nil {
+		log.Fatal(err)
+	}
+	result, err := gen.PullRequest(ctx, gelf.PRRequest{
+		BaseBranch: "main",
+		HeadBranch: "feature",
+		CommitLog:  string(commits),
+		Diff:       string(diff),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s\n\n%s\n", result.Title, result.Body)
+}
+
+func ExampleGenerator_Review() {
+	ctx := context.Background()
+	gen, err := gelf.New(ctx, gelf.Config{Provider: "openai", Model: "gpt-4o"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer gen.Close()
+
+	diff, err := exec.Command("git", "diff", "HEAD~1").Output()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Print the review as it streams in.
+	_, err = gen.Review(ctx, gelf.ReviewRequest{Diff: string(diff)}, func(chunk string) {
+		fmt.Print(chunk)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ExampleGenerator_WithInstructions() {
+	ctx := context.Background()
+	gen, err := gelf.New(ctx, gelf.Config{ProjectID: "my-project"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer gen.Close()
+
+	// Both generators share one connection.
+	strict := gen.WithInstructions("Reference the Jira ticket in the branch name.")
+	result, err := strict.CommitMessage(ctx, gelf.CommitRequest{Diff: "..."})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(result.Message)
+}
@@ -0,0 +1,554 @@
+// Package gelf generates commit messages, pull request descriptions and
+// reviews the way the gelf command does, for programs that want to embed
+// the generation instead of running the binary.
+//
+// The package is a compatibility commitment: exported names only change in
+// backwards compatible ways. Which model provider is used and how prompts
+// are worded are implementation details and may change.
+//
+// A Generator is created once and is safe for concurrent use:
+//
+//	gen, err := gelf.New(ctx, gelf.Config{ProjectID: "my-project"})
+//	if err != nil {
+//		return err
+//	}
+//	defer gen.Close()
+//
+//	result, err := gen.CommitMessage(ctx, gelf.CommitRequest{Diff: diff})
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Println(result.Message, result.Usage.OutputTokens)
+package gelf
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EkeMinusYou/gelf/internal/ai"
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/git"
+	"github.com/EkeMinusYou/gelf/internal/progress"
+)
+
+// Defaults used for zero Config fields.
+const (
+	DefaultLocation = "global"
+	DefaultModel    = "gemini-3.1-pro-preview"
+	DefaultLanguage = "english"
+)
+
+// Config configures a Generator.
+type Config struct {
+	// Provider is the model provider: "vertex_ai" (the default),
+	// "openai", which reads its API key from OPENAI_API_KEY, "bedrock", or
+	// "openai-compatible".
+	Provider string
+	// ProjectID is the Google Cloud project used for Vertex AI. Required
+	// with the vertex_ai provider.
+	ProjectID string
+	// Location is the Vertex AI location (default: DefaultLocation).
+	Location string
+	// BedrockRegion is the AWS region used for Amazon Bedrock. Required
+	// with the bedrock provider.
+	BedrockRegion string
+	// BedrockProfile is the profile of the AWS shared credentials file used
+	// when AWS_ACCESS_KEY_ID is not set (default: "default").
+	BedrockProfile string
+	// OpenAICompatibleBaseURL is the server of the openai-compatible
+	// provider, which also requires Model.
+	OpenAICompatibleBaseURL string
+	// Model is the model used for every request (default: DefaultModel,
+	// or the provider's pro model with another provider).
+	Model string
+	// Language is the output language when a request does not set one
+	// (default: DefaultLanguage).
+	Language string
+	// Instructions are standing instructions added to every prompt, such as
+	// the content of a GELF.md file.
+	Instructions string
+	// RetryMaxAttempts is how many times a request failing with a rate limit
+	// or a server error is tried (default: 3). RetryBaseDelay is the wait
+	// before the first retry, doubled for each further one (default: 1s).
+	RetryMaxAttempts int
+	RetryBaseDelay   time.Duration
+	// FileOrder is the order of a diff's files in the prompt:
+	// "significance" puts source files and larger changes first, and "" or
+	// "path" keeps the order of the diff.
+	FileOrder string
+}
+
+// LoadConfig returns the configuration the gelf command would use: gelf.yml
+// and the VERTEXAI_* environment variables, with the pull request model and
+// language.
+func LoadConfig() (Config, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return Config{}, err
+	}
+	return Config{
+		ProjectID:               cfg.ProjectID,
+		Location:                cfg.Location,
+		Provider:                cfg.Provider,
+		BedrockRegion:           cfg.BedrockRegion,
+		BedrockProfile:          cfg.BedrockProfile,
+		OpenAICompatibleBaseURL: cfg.OpenAICompatibleBaseURL,
+		Model:                   cfg.ResolveModel(cfg.PRModel),
+		Language:                cfg.PRLanguage,
+		FileOrder:               cfg.PromptFileOrder,
+		RetryMaxAttempts:        cfg.RetryMaxAttempts,
+		RetryBaseDelay:          cfg.RetryBaseDelay,
+	}, nil
+}
+
+// Usage is the number of tokens a request used.
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+}
+
+// Generator generates text with one model configuration.
+type Generator struct {
+	client   ai.Provider
+	config   Config
+	reporter progress.Reporter
+}
+
+// New connects to the model provider. Credentials are taken from
+// GELF_CREDENTIALS or GOOGLE_APPLICATION_CREDENTIALS, as for the command.
+func New(ctx context.Context, cfg Config) (*Generator, error) {
+	vertexAI := cfg.Provider == "" || cfg.Provider == ai.DefaultProvider
+	if vertexAI && strings.TrimSpace(cfg.ProjectID) == "" {
+		return nil, fmt.Errorf("gelf: ProjectID is required")
+	}
+	if cfg.Location == "" {
+		cfg.Location = DefaultLocation
+	}
+	if cfg.BedrockProfile == "" {
+		cfg.BedrockProfile = "default"
+	}
+	if cfg.Model == "" {
+		cfg.Model = DefaultModel
+		if !vertexAI {
+			_, cfg.Model = config.DefaultModels(cfg.Provider)
+		}
+	}
+	if cfg.Language == "" {
+		cfg.Language = DefaultLanguage
+	}
+
+	return newGenerator(ctx, &config.Config{
+		Provider:                cfg.Provider,
+		ProjectID:               cfg.ProjectID,
+		Location:                cfg.Location,
+		FlashModel:              cfg.Model,
+		ProModel:                cfg.Model,
+		BedrockRegion:           cfg.BedrockRegion,
+		BedrockProfile:          cfg.BedrockProfile,
+		OpenAICompatibleBaseURL: cfg.OpenAICompatibleBaseURL,
+		RetryMaxAttempts:        cfg.RetryMaxAttempts,
+		RetryBaseDelay:          cfg.RetryBaseDelay,
+	}, cfg)
+}
+
+// NewFromConfig connects to the model provider of the command's
+// configuration, as loaded from gelf.yml, and generates with model (default:
+// the configuration's flash model) in language (default: DefaultLanguage).
+// It is how the gelf command itself builds a Generator; use New elsewhere.
+func NewFromConfig(ctx context.Context, cfg *config.Config, model, language string) (*Generator, error) {
+	if model == "" {
+		model = cfg.FlashModel
+	}
+	if language == "" {
+		language = DefaultLanguage
+	}
+	providerConfig := *cfg
+	providerConfig.FlashModel = model
+	providerConfig.ProModel = model
+	return newGenerator(ctx, &providerConfig, Config{
+		Provider:                cfg.Provider,
+		ProjectID:               cfg.ProjectID,
+		Location:                cfg.Location,
+		BedrockRegion:           cfg.BedrockRegion,
+		BedrockProfile:          cfg.BedrockProfile,
+		OpenAICompatibleBaseURL: cfg.OpenAICompatibleBaseURL,
+		Model:                   model,
+		Language:                language,
+		FileOrder:               cfg.PromptFileOrder,
+		RetryMaxAttempts:        cfg.RetryMaxAttempts,
+		RetryBaseDelay:          cfg.RetryBaseDelay,
+	})
+}
+
+func newGenerator(ctx context.Context, providerConfig *config.Config, cfg Config) (*Generator, error) {
+	client, err := ai.NewProvider(ctx, providerConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &Generator{client: client, config: cfg}, nil
+}
+
+// WithInstructions returns a Generator sharing g's connection that adds
+// instructions to every prompt instead of Config.Instructions. Closing
+// either closes both.
+func (g *Generator) WithInstructions(instructions string) *Generator {
+	config := g.config
+	config.Instructions = instructions
+	return &Generator{client: g.client, config: config, reporter: g.reporter}
+}
+
+// WithReporter returns a Generator sharing g's connection that sends the
+// progress events of its requests to reporter. The gelf command draws its
+// spinners with it. Closing either closes both.
+func (g *Generator) WithReporter(reporter progress.Reporter) *Generator {
+	return &Generator{client: g.client, config: g.config, reporter: reporter}
+}
+
+// Close releases the connection.
+func (g *Generator) Close() error {
+	return g.client.Close()
+}
+
+// CommitRequest describes the staged changes to write a commit message for.
+type CommitRequest struct {
+	// Diff is the staged diff (git diff --cached). Required.
+	Diff string
+	// Language overrides Config.Language.
+	Language string
+	// Template is the repository's commit.template content, if any.
+	Template string
+	// Scope is the Conventional Commits scope the message must use, if any.
+	Scope string
+	// SubjectLanguage and BodyLanguage override Language for the subject
+	// line or the body only.
+	SubjectLanguage string
+	BodyLanguage    string
+	// Merge, when set, is the merge being committed, which the message then
+	// describes.
+	Merge *MergeRequest
+	// History holds the recent commit subjects of the most changed files,
+	// for a message in the style those files are committed in.
+	History []FileHistory
+}
+
+// MergeRequest describes a merge in progress.
+type MergeRequest struct {
+	// Message is the message git prepared for the merge.
+	Message string
+	// Commits lists the commits the merge brings in.
+	Commits string
+	// Conflicts names the files that had conflicts, and Resolution is the
+	// diff of how they were resolved.
+	Conflicts  []string
+	Resolution string
+}
+
+// FileHistory is the recent commit subjects of one file, newest first.
+type FileHistory struct {
+	Path     string
+	Subjects []string
+}
+
+// CommitResult is a generated commit message.
+type CommitResult struct {
+	Message string
+	Usage   Usage
+}
+
+// CommitMessage generates a commit message for the request's diff.
+func (g *Generator) CommitMessage(ctx context.Context, req CommitRequest) (*CommitResult, error) {
+	if strings.TrimSpace(req.Diff) == "" {
+		return nil, fmt.Errorf("gelf: Diff is required")
+	}
+	client, usage := g.tracked(nil)
+	message, err := client.GenerateCommitMessage(ctx, g.commitInput(req))
+	if err != nil {
+		return nil, err
+	}
+	return &CommitResult{Message: strings.TrimSpace(message), Usage: usage.total()}, nil
+}
+
+// CommitMessageStream is CommitMessage with the message streamed. When
+// received is not nil it is called with the number of characters received
+// so far as they arrive.
+func (g *Generator) CommitMessageStream(ctx context.Context, req CommitRequest, received func(chars int)) (*CommitResult, error) {
+	if strings.TrimSpace(req.Diff) == "" {
+		return nil, fmt.Errorf("gelf: Diff is required")
+	}
+	client, usage := g.tracked(func(event progress.Event) {
+		if event.Kind == progress.Status && received != nil {
+			received(event.Current)
+		}
+	})
+	message, err := client.GenerateCommitMessageStream(ctx, g.commitInput(req))
+	if err != nil {
+		return nil, err
+	}
+	return &CommitResult{Message: strings.TrimSpace(message), Usage: usage.total()}, nil
+}
+
+func (g *Generator) commitInput(req CommitRequest) ai.CommitInput {
+	input := ai.CommitInput{
+		Diff:            req.Diff,
+		Language:        g.language(req.Language),
+		SubjectLanguage: req.SubjectLanguage,
+		BodyLanguage:    req.BodyLanguage,
+		Template:        req.Template,
+		Scope:           req.Scope,
+		Instructions:    g.config.Instructions,
+		FileOrder:       g.config.FileOrder,
+	}
+	if req.Merge != nil {
+		input.Merge = &ai.MergeInput{
+			Message:    req.Merge.Message,
+			Commits:    req.Merge.Commits,
+			Conflicts:  req.Merge.Conflicts,
+			Resolution: req.Merge.Resolution,
+		}
+	}
+	for _, history := range req.History {
+		input.PathHistory = append(input.PathHistory, ai.PathHistory{Path: history.Path, Subjects: history.Subjects})
+	}
+	return input
+}
+
+// PRRequest describes a branch to write a pull request description for.
+type PRRequest struct {
+	BaseBranch string
+	HeadBranch string
+	// CommitLog lists the branch's commits, one "<sha> <subject>" per line,
+	// oldest first.
+	CommitLog string
+	// DiffStat is the output of git diff --stat against the base.
+	DiffStat string
+	// Diff is the branch's diff against the base. Required. What kinds of
+	// files it changes, such as tests or docs only, shapes the description.
+	Diff string
+	// Template is the pull request template to fill in, if any.
+	Template string
+	// Language overrides Config.Language; TitleLanguage and BodyLanguage
+	// override it for the title or body only.
+	Language      string
+	TitleLanguage string
+	BodyLanguage  string
+	// Scope is the Conventional Commits scope the title must use, if any.
+	Scope string
+	// Context is extra background, such as companion pull requests in
+	// other repositories.
+	Context string
+	// UncommittedDiff is work that is not committed yet. It is described as
+	// upcoming work, not as part of the pull request.
+	UncommittedDiff string
+	// DependencyUpdates lists the bumped dependencies of a branch that only
+	// updates dependencies, with their release notes. DependencySection is
+	// appended to the body as it is.
+	DependencyUpdates string
+	DependencySection string
+	// Title, when set, is used as the title and only the body is generated.
+	Title string
+	// Previous, when set, is the description of the pull request being
+	// extended. Only an addendum describing CommitLog and Diff is
+	// generated, and the body returned is Previous's with the addendum
+	// appended under UpdateHeading.
+	Previous      *Description
+	UpdateHeading string
+	// Prompt, when set, is sent as it is instead of the prompt built from
+	// the other fields.
+	Prompt string
+}
+
+// Description is the title and body of a pull request.
+type Description struct {
+	Title string
+	Body  string
+}
+
+// PRResult is a generated pull request title and body.
+type PRResult struct {
+	Title string
+	Body  string
+	Usage Usage
+}
+
+// PullRequest generates a pull request title and body.
+func (g *Generator) PullRequest(ctx context.Context, req PRRequest) (*PRResult, error) {
+	if strings.TrimSpace(req.Diff) == "" {
+		return nil, fmt.Errorf("gelf: Diff is required")
+	}
+	client, usage := g.tracked(nil)
+	content, err := client.GeneratePullRequestContent(ctx, g.prInput(req))
+	if err != nil {
+		return nil, err
+	}
+	return &PRResult{Title: content.Title, Body: content.Body, Usage: usage.total()}, nil
+}
+
+func (g *Generator) prInput(req PRRequest) ai.PullRequestInput {
+	classification := git.ClassifyDiff(req.Diff)
+	input := ai.PullRequestInput{
+		BaseBranch:        req.BaseBranch,
+		HeadBranch:        req.HeadBranch,
+		CommitLog:         req.CommitLog,
+		DiffStat:          req.DiffStat,
+		Diff:              req.Diff,
+		Template:          req.Template,
+		Language:          g.language(req.Language),
+		TitleLanguage:     req.TitleLanguage,
+		BodyLanguage:      req.BodyLanguage,
+		FileCategories:    classification.String(),
+		DominantCategory:  string(classification.Dominant),
+		DocsOnly:          classification.Only(git.CategoryDocs),
+		UncommittedDiff:   req.UncommittedDiff,
+		DependencyUpdates: req.DependencyUpdates,
+		DependencySection: req.DependencySection,
+		Scope:             req.Scope,
+		Instructions:      g.config.Instructions,
+		Context:           req.Context,
+		UpdateHeading:     req.UpdateHeading,
+		Title:             req.Title,
+		FileOrder:         g.config.FileOrder,
+		Prompt:            req.Prompt,
+	}
+	if req.Previous != nil {
+		input.Previous = &ai.PullRequestContent{Title: req.Previous.Title, Body: req.Previous.Body}
+	}
+	return input
+}
+
+// ReviewRequest describes a change to review.
+type ReviewRequest struct {
+	// Diff is the change to review. Required.
+	Diff string
+	// CommitMessage is the message of the commit being reviewed, if the
+	// diff is a single commit.
+	CommitMessage string
+	// Language overrides Config.Language.
+	Language string
+	// Template is the pull request template the change will be opened
+	// with, if any. The review then ends with a "Template readiness"
+	// section listing the template's requirements the diff does not meet.
+	Template string
+}
+
+// ReviewResult is a generated review in markdown.
+type ReviewResult struct {
+	Review string
+	Usage  Usage
+}
+
+// Review reviews the request's diff. When stream is not nil it receives the
+// review in chunks as they are generated; the result still holds all of it.
+func (g *Generator) Review(ctx context.Context, req ReviewRequest, stream func(chunk string)) (*ReviewResult, error) {
+	if strings.TrimSpace(req.Diff) == "" {
+		return nil, fmt.Errorf("gelf: Diff is required")
+	}
+	client, usage := g.tracked(nil)
+	review, err := client.GenerateReview(ctx, ai.ReviewInput{
+		Diff:          req.Diff,
+		CommitMessage: req.CommitMessage,
+		Language:      g.language(req.Language),
+		Instructions:  g.config.Instructions,
+		Template:      req.Template,
+		FileOrder:     g.config.FileOrder,
+	}, stream)
+	if err != nil {
+		return nil, err
+	}
+	return &ReviewResult{Review: review, Usage: usage.total()}, nil
+}
+
+// CompareRequest describes two branches implementing the same change, to
+// compare their approaches.
+type CompareRequest struct {
+	// BranchA and BranchB name the branches; the comparison refers to them
+	// by these names.
+	BranchA string
+	BranchB string
+	// DiffA and DiffB are the branches' diffs against MergeBase. Required.
+	DiffA string
+	DiffB string
+	// MergeBase is the commit both branches fork from, if known.
+	MergeBase string
+	// Language overrides Config.Language.
+	Language string
+}
+
+// Compare compares the approaches of the request's branches on
+// correctness, complexity and risk. When stream is not nil it receives the
+// comparison in chunks as they are generated; the result still holds all of
+// it.
+func (g *Generator) Compare(ctx context.Context, req CompareRequest, stream func(chunk string)) (*ReviewResult, error) {
+	if strings.TrimSpace(req.DiffA) == "" || strings.TrimSpace(req.DiffB) == "" {
+		return nil, fmt.Errorf("gelf: DiffA and DiffB are required")
+	}
+	branchA, branchB := req.BranchA, req.BranchB
+	if branchA == "" {
+		branchA = "A"
+	}
+	if branchB == "" {
+		branchB = "B"
+	}
+	client, usage := g.tracked(nil)
+	review, err := client.GenerateComparison(ctx, ai.CompareInput{
+		BranchA:      branchA,
+		DiffA:        req.DiffA,
+		BranchB:      branchB,
+		DiffB:        req.DiffB,
+		MergeBase:    req.MergeBase,
+		Language:     g.language(req.Language),
+		Instructions: g.config.Instructions,
+		FileOrder:    g.config.FileOrder,
+	}, stream)
+	if err != nil {
+		return nil, err
+	}
+	return &ReviewResult{Review: review, Usage: usage.total()}, nil
+}
+
+func (g *Generator) language(language string) string {
+	if language != "" {
+		return language
+	}
+	return g.config.Language
+}
+
+// tracked returns a client for one request and the usage it reports. The
+// request's events also go to report, when it is not nil, and to g's
+// reporter.
+func (g *Generator) tracked(report func(progress.Event)) (ai.Provider, *usageCounter) {
+	counter := &usageCounter{report: report, reporter: g.reporter}
+	return g.client.WithReporter(counter), counter
+}
+
+type usageCounter struct {
+	report   func(progress.Event)
+	reporter progress.Reporter
+
+	mu    sync.Mutex
+	usage Usage
+}
+
+func (c *usageCounter) Report(event progress.Event) {
+	if c.report != nil {
+		c.report(event)
+	}
+	if c.reporter != nil {
+		c.reporter.Report(event)
+	}
+	if event.Kind != progress.Tokens {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.usage.InputTokens += event.InputTokens
+	c.usage.OutputTokens += event.OutputTokens
+}
+
+func (c *usageCounter) total() Usage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.usage
+}
@@ -0,0 +1,338 @@
+package gelf
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/EkeMinusYou/gelf/internal/ai"
+	"github.com/EkeMinusYou/gelf/internal/config"
+	"github.com/EkeMinusYou/gelf/internal/progress"
+)
+
+// testProvider is the provider name fakeProvider is registered under.
+const testProvider = "gelf-test"
+
+func init() {
+	ai.RegisterProvider(testProvider, func(ctx context.Context, cfg *config.Config) (ai.Provider, error) {
+		return &fakeProvider{calls: &fakeCalls{model: cfg.FlashModel}}, nil
+	})
+}
+
+// fakeCalls records what a fakeProvider and its copies were asked for.
+type fakeCalls struct {
+	mu      sync.Mutex
+	model   string
+	commits []ai.CommitInput
+	prs     []ai.PullRequestInput
+	reviews []ai.ReviewInput
+	closed  bool
+}
+
+// fakeProvider answers with fixed text and reports 100 input and 20 output
+// tokens per call. Methods the package does not use are left to the nil
+// embedded Provider.
+type fakeProvider struct {
+	ai.Provider
+	calls    *fakeCalls
+	reporter progress.Reporter
+}
+
+func (f *fakeProvider) report(event progress.Event) {
+	if f.reporter != nil {
+		f.reporter.Report(event)
+	}
+}
+
+func (f *fakeProvider) tokens() {
+	f.report(progress.Event{Kind: progress.Tokens, InputTokens: 100, OutputTokens: 20})
+}
+
+func (f *fakeProvider) GenerateCommitMessage(ctx context.Context, input ai.CommitInput) (string, error) {
+	f.calls.mu.Lock()
+	f.calls.commits = append(f.calls.commits, input)
+	f.calls.mu.Unlock()
+	f.tokens()
+	return "  feat: add retries\n\n", nil
+}
+
+func (f *fakeProvider) GenerateCommitMessageStream(ctx context.Context, input ai.CommitInput) (string, error) {
+	f.report(progress.Event{Kind: progress.Status, Current: 5})
+	f.report(progress.Event{Kind: progress.Status, Current: 18})
+	return f.GenerateCommitMessage(ctx, input)
+}
+
+func (f *fakeProvider) GeneratePullRequestContent(ctx context.Context, input ai.PullRequestInput) (*ai.PullRequestContent, error) {
+	f.calls.mu.Lock()
+	f.calls.prs = append(f.calls.prs, input)
+	f.calls.mu.Unlock()
+	f.tokens()
+	return &ai.PullRequestContent{Title: "Add retries", Body: "## Summary\nRetries model calls."}, nil
+}
+
+func (f *fakeProvider) GenerateReview(ctx context.Context, input ai.ReviewInput, onChunk func(string)) (string, error) {
+	f.calls.mu.Lock()
+	f.calls.reviews = append(f.calls.reviews, input)
+	f.calls.mu.Unlock()
+	f.tokens()
+	for _, chunk := range []string{"Looks ", "good."} {
+		if onChunk != nil {
+			onChunk(chunk)
+		}
+	}
+	return "Looks good.", nil
+}
+
+func (f *fakeProvider) GenerateComparison(ctx context.Context, input ai.CompareInput, onChunk func(string)) (string, error) {
+	f.tokens()
+	return "### Recommendation\n" + input.BranchA + " over " + input.BranchB, nil
+}
+
+func (f *fakeProvider) WithReporter(reporter progress.Reporter) ai.Provider {
+	clone := *f
+	clone.reporter = reporter
+	return &clone
+}
+
+func (f *fakeProvider) Close() error {
+	f.calls.mu.Lock()
+	defer f.calls.mu.Unlock()
+	f.calls.closed = true
+	return nil
+}
+
+// newTestGenerator returns a Generator on a fakeProvider and what the
+// provider records.
+func newTestGenerator(t *testing.T, cfg Config) (*Generator, *fakeCalls) {
+	t.Helper()
+	cfg.Provider = testProvider
+	g, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	t.Cleanup(func() { g.Close() })
+	return g, g.client.(*fakeProvider).calls
+}
+
+const readmeDiff = "diff --git a/README.md b/README.md\n--- a/README.md\n+++ b/README.md\n@@ -1 +1 @@\n-old\n+new\n"
+
+func TestNewRequiresProjectID(t *testing.T) {
+	for _, provider := range []string{"", "vertex_ai"} {
+		if _, err := New(context.Background(), Config{Provider: provider}); err == nil || !strings.Contains(err.Error(), "ProjectID") {
+			t.Errorf("New(Provider: %q) error = %v, want ProjectID is required", provider, err)
+		}
+	}
+}
+
+func TestNewDefaults(t *testing.T) {
+	g, calls := newTestGenerator(t, Config{})
+	if g.config.Language != DefaultLanguage {
+		t.Errorf("Language = %q, want %q", g.config.Language, DefaultLanguage)
+	}
+	if _, pro := config.DefaultModels(testProvider); calls.model != pro {
+		t.Errorf("model = %q, want the provider's pro model %q", calls.model, pro)
+	}
+
+	g, calls = newTestGenerator(t, Config{Model: "my-model", Language: "japanese"})
+	if calls.model != "my-model" || g.config.Language != "japanese" {
+		t.Errorf("model, language = %q, %q, want my-model, japanese", calls.model, g.config.Language)
+	}
+}
+
+func TestCommitMessage(t *testing.T) {
+	g, calls := newTestGenerator(t, Config{Language: "japanese", Instructions: "Mention tickets.", FileOrder: "significance"})
+
+	result, err := g.CommitMessage(context.Background(), CommitRequest{
+		Diff:    readmeDiff,
+		Scope:   "docs",
+		Merge:   &MergeRequest{Message: "Merge branch 'feature'", Conflicts: []string{"README.md"}},
+		History: []FileHistory{{Path: "README.md", Subjects: []string{"docs: fix typo"}}},
+	})
+	if err != nil {
+		t.Fatalf("CommitMessage() error: %v", err)
+	}
+	if result.Message != "feat: add retries" {
+		t.Errorf("Message = %q, want the trimmed message", result.Message)
+	}
+	if result.Usage != (Usage{InputTokens: 100, OutputTokens: 20}) {
+		t.Errorf("Usage = %+v", result.Usage)
+	}
+
+	input := calls.commits[0]
+	if input.Diff != readmeDiff || input.Scope != "docs" || input.Language != "japanese" {
+		t.Errorf("input = %+v, want the request's diff and scope in the config's language", input)
+	}
+	if input.Instructions != "Mention tickets." || input.FileOrder != "significance" {
+		t.Errorf("input instructions, file order = %q, %q", input.Instructions, input.FileOrder)
+	}
+	if input.Merge == nil || input.Merge.Message != "Merge branch 'feature'" || len(input.Merge.Conflicts) != 1 {
+		t.Errorf("input.Merge = %+v", input.Merge)
+	}
+	if len(input.PathHistory) != 1 || input.PathHistory[0].Subjects[0] != "docs: fix typo" {
+		t.Errorf("input.PathHistory = %+v", input.PathHistory)
+	}
+
+	if _, err := g.CommitMessage(context.Background(), CommitRequest{Diff: readmeDiff, Language: "french"}); err != nil {
+		t.Fatal(err)
+	}
+	if calls.commits[1].Language != "french" {
+		t.Errorf("Language = %q, want the request's french", calls.commits[1].Language)
+	}
+
+	if _, err := g.CommitMessage(context.Background(), CommitRequest{Diff: " \n"}); err == nil {
+		t.Error("CommitMessage() with an empty diff succeeded")
+	}
+}
+
+func TestCommitMessageStream(t *testing.T) {
+	g, _ := newTestGenerator(t, Config{})
+	var received []int
+	result, err := g.CommitMessageStream(context.Background(), CommitRequest{Diff: readmeDiff}, func(chars int) {
+		received = append(received, chars)
+	})
+	if err != nil {
+		t.Fatalf("CommitMessageStream() error: %v", err)
+	}
+	if result.Message != "feat: add retries" {
+		t.Errorf("Message = %q", result.Message)
+	}
+	if len(received) != 2 || received[1] != 18 {
+		t.Errorf("received = %v, want [5 18]", received)
+	}
+	if _, err := g.CommitMessageStream(context.Background(), CommitRequest{Diff: readmeDiff}, nil); err != nil {
+		t.Errorf("CommitMessageStream() without a callback error: %v", err)
+	}
+}
+
+func TestPullRequest(t *testing.T) {
+	g, calls := newTestGenerator(t, Config{})
+	result, err := g.PullRequest(context.Background(), PRRequest{
+		BaseBranch:    "main",
+		HeadBranch:    "docs",
+		Diff:          readmeDiff,
+		Title:         "docs: refresh the README",
+		Previous:      &Description{Title: "Docs", Body: "Earlier work."},
+		UpdateHeading: "Update",
+		Prompt:        "edited prompt",
+	})
+	if err != nil {
+		t.Fatalf("PullRequest() error: %v", err)
+	}
+	if result.Title != "Add retries" || !strings.HasPrefix(result.Body, "## Summary") {
+		t.Errorf("result = %+v", result)
+	}
+	if result.Usage.OutputTokens != 20 {
+		t.Errorf("Usage = %+v", result.Usage)
+	}
+
+	input := calls.prs[0]
+	if !input.DocsOnly || input.DominantCategory != "docs" {
+		t.Errorf("input categories = %q, %q, DocsOnly %v; want a docs-only diff", input.FileCategories, input.DominantCategory, input.DocsOnly)
+	}
+	if input.Previous == nil || input.Previous.Body != "Earlier work." || input.UpdateHeading != "Update" {
+		t.Errorf("input.Previous = %+v, heading %q", input.Previous, input.UpdateHeading)
+	}
+	if input.Title != "docs: refresh the README" || input.Prompt != "edited prompt" || input.Language != DefaultLanguage {
+		t.Errorf("input title, prompt, language = %q, %q, %q", input.Title, input.Prompt, input.Language)
+	}
+
+	if _, err := g.PullRequest(context.Background(), PRRequest{}); err == nil {
+		t.Error("PullRequest() with an empty diff succeeded")
+	}
+}
+
+func TestReviewStreams(t *testing.T) {
+	g, calls := newTestGenerator(t, Config{})
+	var chunks []string
+	result, err := g.Review(context.Background(), ReviewRequest{Diff: readmeDiff, CommitMessage: "docs: refresh"}, func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("Review() error: %v", err)
+	}
+	if strings.Join(chunks, "") != result.Review || result.Review != "Looks good." {
+		t.Errorf("chunks %q, review %q", chunks, result.Review)
+	}
+	if calls.reviews[0].CommitMessage != "docs: refresh" {
+		t.Errorf("input.CommitMessage = %q", calls.reviews[0].CommitMessage)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	g, _ := newTestGenerator(t, Config{})
+	result, err := g.Compare(context.Background(), CompareRequest{DiffA: readmeDiff, DiffB: readmeDiff}, nil)
+	if err != nil {
+		t.Fatalf("Compare() error: %v", err)
+	}
+	if !strings.Contains(result.Review, "A over B") {
+		t.Errorf("Review = %q, want the default branch names", result.Review)
+	}
+	if _, err := g.Compare(context.Background(), CompareRequest{DiffA: readmeDiff}, nil); err == nil {
+		t.Error("Compare() without DiffB succeeded")
+	}
+}
+
+func TestWithInstructionsAndReporter(t *testing.T) {
+	g, calls := newTestGenerator(t, Config{Instructions: "standing"})
+
+	var mu sync.Mutex
+	var events []progress.Event
+	reporting := g.WithReporter(progress.ReporterFunc(func(event progress.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}))
+	instructed := reporting.WithInstructions("per run")
+
+	result, err := instructed.CommitMessage(context.Background(), CommitRequest{Diff: readmeDiff})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.commits[0].Instructions != "per run" {
+		t.Errorf("Instructions = %q, want per run", calls.commits[0].Instructions)
+	}
+	if len(events) != 1 || events[0].Kind != progress.Tokens {
+		t.Errorf("reporter got %+v, want the token event", events)
+	}
+	// Usage is counted per request even with a reporter.
+	if result.Usage.InputTokens != 100 {
+		t.Errorf("Usage = %+v", result.Usage)
+	}
+
+	if _, err := g.CommitMessage(context.Background(), CommitRequest{Diff: readmeDiff}); err != nil {
+		t.Fatal(err)
+	}
+	if calls.commits[1].Instructions != "standing" || len(events) != 1 {
+		t.Errorf("the original generator changed: instructions %q, %d events", calls.commits[1].Instructions, len(events))
+	}
+
+	instructed.Close()
+	if !calls.closed {
+		t.Error("closing a derived generator did not close the connection")
+	}
+}
+
+func TestUsageIsPerRequest(t *testing.T) {
+	g, _ := newTestGenerator(t, Config{})
+	var wg sync.WaitGroup
+	results := make([]*CommitResult, 8)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := g.CommitMessage(context.Background(), CommitRequest{Diff: readmeDiff})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+	for i, result := range results {
+		if result != nil && result.Usage != (Usage{InputTokens: 100, OutputTokens: 20}) {
+			t.Errorf("request %d Usage = %+v, want one call's", i, result.Usage)
+		}
+	}
+}